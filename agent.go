@@ -8,9 +8,12 @@ import (
 	"errors"
 	"fmt"
 	"maps"
+	"reflect"
+	"regexp"
 	"slices"
 	"strings"
 	"sync"
+	"time"
 
 	"charm.land/fantasy/jsonrepair"
 	"charm.land/fantasy/schema"
@@ -21,6 +24,9 @@ import (
 type StepResult struct {
 	Response
 	Messages []Message
+	// InputBreakdown estimates which parts of the prompt the step's input
+	// tokens went to.
+	InputBreakdown TokenBreakdown
 }
 
 // stepExecutionResult encapsulates the result of executing a step with stream processing.
@@ -83,6 +89,17 @@ func FinishReasonIs(reason FinishReason) StopCondition {
 	}
 }
 
+// TextMatches returns a stop condition that stops when the text of the last step matches the pattern.
+func TextMatches(pattern *regexp.Regexp) StopCondition {
+	return func(steps []StepResult) bool {
+		if len(steps) == 0 {
+			return false
+		}
+		lastStep := steps[len(steps)-1]
+		return pattern.MatchString(lastStep.Content.Text())
+	}
+}
+
 // MaxTokensUsed returns a stop condition that stops when total token usage exceeds the specified limit.
 func MaxTokensUsed(maxTokens int64) StopCondition {
 	return func(steps []StepResult) bool {
@@ -111,6 +128,9 @@ type PrepareStepResult struct {
 	ActiveTools     []string
 	DisableAllTools bool
 	Tools           []AgentTool
+	// Sources are added to the step's content, for example to attribute
+	// documents injected into Messages.
+	Sources []SourceContent
 }
 
 // ToolCallRepairOptions contains the options for repairing a tool call.
@@ -131,10 +151,15 @@ type (
 
 	// RepairToolCallFunction defines a function that repairs a tool call.
 	RepairToolCallFunction = func(ctx context.Context, options ToolCallRepairOptions) (*ToolCallContent, error)
+
+	// SystemPromptFunction defines a function that computes the system prompt
+	// for a call.
+	SystemPromptFunction = func(ctx context.Context, call AgentCall) (string, error)
 )
 
 type agentSettings struct {
 	systemPrompt     string
+	systemPromptFunc SystemPromptFunction
 	maxOutputTokens  *int64
 	temperature      *float64
 	topP             *float64
@@ -142,6 +167,7 @@ type agentSettings struct {
 	presencePenalty  *float64
 	frequencyPenalty *float64
 	headers          map[string]string
+	extraBody        map[string]any
 	userAgent        string
 	providerOptions  ProviderOptions
 
@@ -157,6 +183,38 @@ type agentSettings struct {
 	prepareStep    PrepareStepFunction
 	repairToolCall RepairToolCallFunction
 	onRetry        OnRetryCallback
+
+	toolResultLimit *toolResultLimit
+	tokenizer       Tokenizer
+	artifacts       *artifactSettings
+	guardrails      []Guardrail
+
+	automaticCacheBreakpoints bool
+	requestGroup              *RequestGroup
+	spendLimit                *spendLimit
+	spendStore                SpendStore
+	costFunction              CostFunction
+	auditSink                 AuditSink
+	debugger                  *Debugger
+	toolRetry                 *ToolRetryPolicy
+	deferredMode              DeferredMode
+	deferredJobs              *deferredJobs
+	deferredPollInterval      time.Duration
+
+	disablePanicRecovery   bool
+	streamIdleTimeout      time.Duration
+	maxContinuations       int
+	truncatedToolCalls     TruncatedToolCallMode
+	numChoices             int
+	scoreChoice            ChoiceScoreFunction
+	reflection             *reflectionSettings
+	reasoningHistory       ReasoningHistoryMode
+	serviceTier            ServiceTier
+	pdfFallback            *PDFFallback
+	maxFileDownloadBytes   int64
+	warningHandler         func(CallWarning)
+	warningsAsErrors       bool
+	strictPromptConversion bool
 }
 
 // AgentCall represents a call to an agent.
@@ -173,11 +231,18 @@ type AgentCall struct {
 	ActiveTools      []string    `json:"active_tools"`
 	ToolChoice       *ToolChoice `json:"tool_choice"`
 	Headers          map[string]string
+	ExtraQuery       map[string]string
+	ExtraBody        map[string]any
 	ProviderOptions  ProviderOptions
 	OnRetry          OnRetryCallback
 	OnAuthRefresh    OnAuthRefreshFunc
 	MaxRetries       *int
 
+	// Model, when non-nil, replaces the agent's model for this run, e.g. to
+	// escalate to a bigger model after a small one failed. Tools, system
+	// prompt and settings stay the same.
+	Model LanguageModel `json:"-"`
+
 	// ModelProvider, when non-nil, is called on each retry attempt to
 	// obtain the language model. This allows callers to swap in a
 	// refreshed model after OnAuthRefresh rebuilds credentials. When
@@ -187,6 +252,11 @@ type AgentCall struct {
 	StopWhen       []StopCondition
 	PrepareStep    PrepareStepFunction
 	RepairToolCall RepairToolCallFunction
+
+	// OnToolError decides what the agent does when a tool returns an
+	// error or panics: abort the run, report the error to the model or
+	// retry the tool.
+	OnToolError OnToolErrorFunc
 }
 
 // Agent-level callbacks.
@@ -261,13 +331,194 @@ type (
 	// OnToolResultFunc is called when tool execution completes.
 	OnToolResultFunc func(result ToolResultContent) error
 
+	// OnToolExecutionDeltaFunc is called for output a tool writes to
+	// ToolCall.Output while running.
+	OnToolExecutionDeltaFunc func(id, delta string) error
+
 	// OnSourceFunc is called for source references.
 	OnSourceFunc func(source SourceContent) error
 
 	// OnStreamFinishFunc is called when stream finishes.
 	OnStreamFinishFunc func(usage Usage, finishReason FinishReason, providerMetadata ProviderMetadata) error
+
+	// OnProgressFunc is called periodically while a step is streaming.
+	OnProgressFunc func(progress Progress) error
 )
 
+// DefaultProgressInterval is the minimum time between two progress
+// reports when AgentStreamCall.ProgressInterval is not set.
+const DefaultProgressInterval = time.Second
+
+// Progress is a heartbeat emitted during streaming so callers can show
+// progress without waiting for the step to finish.
+type Progress struct {
+	// StepNumber is the zero-based index of the step being streamed.
+	StepNumber int `json:"step_number"`
+	// Elapsed is the time since the agent run started.
+	Elapsed time.Duration `json:"elapsed"`
+	// StepElapsed is the time since the current step started streaming.
+	StepElapsed time.Duration `json:"step_elapsed"`
+	// Usage is the total usage reported by the steps finished so far.
+	// Providers usually only report usage at the end of a step, so the
+	// current step is not included until it finishes.
+	Usage Usage `json:"usage"`
+	// StepOutputChars is the number of text, reasoning and tool input
+	// characters received so far in the current step.
+	StepOutputChars int `json:"step_output_chars"`
+}
+
+// progressTracker throttles progress reports for a streaming agent run.
+// While a step streams, a ticker reports progress even when no part
+// arrives, such as before the first token or when the stream stalls.
+type progressTracker struct {
+	fn       OnProgressFunc
+	interval time.Duration
+	now      func() time.Time
+
+	mu         sync.Mutex
+	start      time.Time
+	stepStart  time.Time
+	lastReport time.Time
+	stepNumber int
+	usage      Usage
+	stepChars  int
+	// err is the error of a report made by the ticker.
+	err  error
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newProgressTracker(fn OnProgressFunc, interval time.Duration) *progressTracker {
+	if fn == nil {
+		return nil
+	}
+	if interval <= 0 {
+		interval = DefaultProgressInterval
+	}
+	p := &progressTracker{fn: fn, interval: interval, now: time.Now}
+	p.start = p.now()
+	return p
+}
+
+// startStep resets the per-step counters. It is also called when a step is
+// retried so the counters never include output from a failed attempt.
+func (p *progressTracker) startStep(stepNumber int) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stepNumber = stepNumber
+	p.stepStart = p.now()
+	p.lastReport = p.stepStart
+	p.stepChars = 0
+	p.err = nil
+}
+
+// watch starts reporting progress every interval until stop is called. The
+// returned context is canceled if a report fails, to end the stream.
+func (p *progressTracker) watch(ctx context.Context) context.Context {
+	if p == nil {
+		return ctx
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	done := make(chan struct{})
+	p.mu.Lock()
+	p.done = done
+	p.mu.Unlock()
+	p.wg.Go(func() {
+		ticker := time.NewTicker(p.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := p.tick(); err != nil {
+					cancel(err)
+					return
+				}
+			case <-done:
+				cancel(nil)
+				return
+			}
+		}
+	})
+	return ctx
+}
+
+func (p *progressTracker) tick() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.now().Sub(p.lastReport) < p.interval {
+		return nil
+	}
+	p.err = p.report()
+	return p.err
+}
+
+// stop stops the reports started by watch, and returns the error of the
+// report that failed, if any.
+func (p *progressTracker) stop() error {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	if p.done != nil {
+		close(p.done)
+		p.done = nil
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// observe records a stream part and reports progress if the interval has
+// elapsed since the last report.
+func (p *progressTracker) observe(part StreamPart) error {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	switch part.Type {
+	case StreamPartTypeTextDelta, StreamPartTypeReasoningDelta, StreamPartTypeToolInputDelta:
+		p.stepChars += len(part.Delta)
+	}
+	if p.now().Sub(p.lastReport) < p.interval {
+		return nil
+	}
+	return p.report()
+}
+
+// finishStep adds the step usage to the running total and reports progress
+// unconditionally.
+func (p *progressTracker) finishStep(usage Usage) error {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.usage = addUsage(p.usage, usage)
+	return p.report()
+}
+
+// report calls the callback. Callers must hold the lock.
+func (p *progressTracker) report() error {
+	now := p.now()
+	p.lastReport = now
+	return p.fn(Progress{
+		StepNumber:      p.stepNumber,
+		Elapsed:         now.Sub(p.start),
+		StepElapsed:     now.Sub(p.stepStart),
+		Usage:           p.usage,
+		StepOutputChars: p.stepChars,
+	})
+}
+
 // AgentStreamCall represents a streaming call to an agent.
 type AgentStreamCall struct {
 	Prompt           string     `json:"prompt"`
@@ -282,11 +533,18 @@ type AgentStreamCall struct {
 	ActiveTools      []string    `json:"active_tools"`
 	ToolChoice       *ToolChoice `json:"tool_choice"`
 	Headers          map[string]string
+	ExtraQuery       map[string]string
+	ExtraBody        map[string]any
 	ProviderOptions  ProviderOptions
 	OnRetry          OnRetryCallback
 	OnAuthRefresh    OnAuthRefreshFunc
 	MaxRetries       *int
 
+	// Model, when non-nil, replaces the agent's model for this run, e.g. to
+	// escalate to a bigger model after a small one failed. Tools, system
+	// prompt and settings stay the same.
+	Model LanguageModel `json:"-"`
+
 	// ModelProvider, when non-nil, is called on each retry attempt to
 	// obtain the language model. This allows callers to swap in a
 	// refreshed model after OnAuthRefresh rebuilds credentials. When
@@ -321,6 +579,27 @@ type AgentStreamCall struct {
 	OnToolResult     OnToolResultFunc     // Called when tool execution completes
 	OnSource         OnSourceFunc         // Called for source references
 	OnStreamFinish   OnStreamFinishFunc   // Called when stream finishes
+
+	// OnToolExecutionDelta is called for output a tool writes to
+	// ToolCall.Output or reports with ReportToolOutput while running, such
+	// as the live output of a command. The output is also passed to OnChunk
+	// as a StreamPartTypeToolExecutionDelta part.
+	OnToolExecutionDelta OnToolExecutionDeltaFunc
+
+	// OnToolError decides what the agent does when a tool returns an
+	// error or panics: abort the run, report the error to the model or
+	// retry the tool.
+	OnToolError OnToolErrorFunc
+
+	// OnProgress, when set, receives periodic progress reports while a
+	// step is streaming, including while waiting for its first part, plus
+	// one report after each step finishes. It may be called from another
+	// goroutine than the other callbacks, but never concurrently with
+	// itself.
+	OnProgress OnProgressFunc
+	// ProgressInterval is the minimum time between two progress reports.
+	// Defaults to DefaultProgressInterval.
+	ProgressInterval time.Duration
 }
 
 // AgentResult represents the result of an agent execution.
@@ -331,6 +610,9 @@ type AgentResult struct {
 	// so callers always see meaningful output without walking Steps manually.
 	Response   Response
 	TotalUsage Usage
+	// Reflections has a record per critique round when the agent was
+	// created WithReflection. Response is the last revision.
+	Reflections []Reflection
 }
 
 // finalResponse picks the best Response from a slice of steps. It walks
@@ -364,6 +646,61 @@ func hasNonBlankText(content ResponseContent) bool {
 	return false
 }
 
+// FinalAnswerToolName is the name of the tool whose input DecodeFinal reads
+// the final structured output from.
+const FinalAnswerToolName = "final_answer"
+
+// DecodeFinal decodes the agent's final structured output into v, which must
+// be a non-nil pointer. The output is the input of the last call to the
+// FinalAnswerToolName tool or, when there is none, the JSON in the final
+// response text. Malformed JSON is repaired and the result is validated
+// against the schema of v before decoding. It returns a
+// *NoObjectGeneratedError when no valid output is found.
+func (r *AgentResult) DecodeFinal(v any) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() {
+		return fmt.Errorf("decode final: expected a non-nil pointer, got %T", v)
+	}
+
+	rawText := r.finalOutput()
+	if strings.TrimSpace(rawText) == "" {
+		return &NoObjectGeneratedError{
+			ParseError:   errors.New("no final output found"),
+			Usage:        r.TotalUsage,
+			FinishReason: r.Response.FinishReason,
+		}
+	}
+
+	obj, err := schema.ParseAndValidate(rawText, schema.Generate(rv.Type().Elem()))
+	if err != nil {
+		if parseErr, ok := err.(*schema.ParseError); ok {
+			return &NoObjectGeneratedError{
+				RawText:         parseErr.RawText,
+				ParseError:      parseErr.ParseError,
+				ValidationError: parseErr.ValidationError,
+				Usage:           r.TotalUsage,
+				FinishReason:    r.Response.FinishReason,
+			}
+		}
+		return err
+	}
+	return unmarshalObject(obj, v)
+}
+
+// finalOutput returns the input of the last final answer tool call, or the
+// final response text.
+func (r *AgentResult) finalOutput() string {
+	for i := len(r.Steps) - 1; i >= 0; i-- {
+		toolCalls := r.Steps[i].Content.ToolCalls()
+		for j := len(toolCalls) - 1; j >= 0; j-- {
+			if toolCalls[j].ToolName == FinalAnswerToolName {
+				return toolCalls[j].Input
+			}
+		}
+	}
+	return r.Response.Content.Text()
+}
+
 // Agent represents an AI agent that can generate responses and stream responses.
 type Agent interface {
 	Generate(context.Context, AgentCall) (*AgentResult, error)
@@ -385,6 +722,9 @@ func NewAgent(model LanguageModel, opts ...AgentOption) Agent {
 	for _, o := range opts {
 		o(&settings)
 	}
+	if settings.spendLimit != nil && settings.spendStore == nil {
+		settings.spendStore = NewMemorySpendStore()
+	}
 	return &agent{
 		settings: settings,
 	}
@@ -399,6 +739,9 @@ func (a *agent) prepareCall(call AgentCall) AgentCall {
 	call.FrequencyPenalty = cmp.Or(call.FrequencyPenalty, a.settings.frequencyPenalty)
 	call.MaxRetries = cmp.Or(call.MaxRetries, a.settings.maxRetries)
 	call.ToolChoice = cmp.Or(call.ToolChoice, a.settings.toolChoice)
+	if call.Model == nil {
+		call.Model = a.settings.model
+	}
 
 	if len(call.StopWhen) == 0 && len(a.settings.stopWhen) > 0 {
 		call.StopWhen = a.settings.stopWhen
@@ -413,14 +756,7 @@ func (a *agent) prepareCall(call AgentCall) AgentCall {
 		call.OnRetry = a.settings.onRetry
 	}
 
-	providerOptions := ProviderOptions{}
-	if a.settings.providerOptions != nil {
-		maps.Copy(providerOptions, a.settings.providerOptions)
-	}
-	if call.ProviderOptions != nil {
-		maps.Copy(providerOptions, call.ProviderOptions)
-	}
-	call.ProviderOptions = providerOptions
+	call.ProviderOptions = MergeProviderOptions(a.settings.providerOptions, call.ProviderOptions)
 
 	headers := map[string]string{}
 
@@ -432,23 +768,83 @@ func (a *agent) prepareCall(call AgentCall) AgentCall {
 	}
 	call.Headers = headers
 
+	if a.settings.extraBody != nil {
+		extraBody := maps.Clone(a.settings.extraBody)
+		maps.Copy(extraBody, call.ExtraBody)
+		call.ExtraBody = extraBody
+	}
+
 	return call
 }
 
 // Generate implements Agent.
 func (a *agent) Generate(ctx context.Context, opts AgentCall) (*AgentResult, error) {
+	ctx = withRunPIIRedactions(ctx)
 	opts = a.prepareCall(opts)
-	initialPrompt, err := a.createPrompt(a.settings.systemPrompt, opts.Prompt, opts.Messages, opts.Files...)
+	ctx = withToolErrorHandler(ctx, opts.OnToolError)
+	prepared, err := a.preparePrompt(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	result, err := a.generateSteps(ctx, opts, prepared)
+	if err != nil || a.settings.reflection == nil {
+		return result, err
+	}
+	return a.reflect(ctx, opts, prepared, result)
+}
+
+// preparedPrompt is the prompt of a run once the system prompt, the input
+// guardrails, and the file URL and PDF fallbacks were applied.
+type preparedPrompt struct {
+	system      string
+	prompt      Prompt
+	warnings    []CallWarning
+	toolCallIDs *toolCallIDs
+}
+
+func (a *agent) preparePrompt(ctx context.Context, opts AgentCall) (*preparedPrompt, error) {
+	systemPrompt, err := a.resolveSystemPrompt(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	initialPrompt, err := a.createPrompt(systemPrompt, opts.Prompt, opts.Messages, opts.Files...)
+	if err != nil {
+		return nil, err
+	}
+	initialPrompt, err = a.guardInput(ctx, initialPrompt)
+	if err != nil {
+		return nil, err
+	}
+	initialPrompt, err = ResolveFileURLs(ctx, opts.Model, initialPrompt, a.maxFileDownloadBytes())
 	if err != nil {
 		return nil, err
 	}
+	initialPrompt, pdfWarnings, err := a.fallBackFromPDFs(ctx, opts.Model, initialPrompt)
+	if err != nil {
+		return nil, err
+	}
+	return &preparedPrompt{
+		system:      systemPrompt,
+		prompt:      initialPrompt,
+		warnings:    pdfWarnings,
+		toolCallIDs: newToolCallIDs(initialPrompt),
+	}, nil
+}
+
+// generateSteps runs the steps of a call, starting from its prepared
+// prompt.
+func (a *agent) generateSteps(ctx context.Context, opts AgentCall, prepared *preparedPrompt) (*AgentResult, error) {
+	systemPrompt := prepared.system
+	initialPrompt := prepared.prompt
+	pdfWarnings := prepared.warnings
+	toolCallIDs := prepared.toolCallIDs
 	var responseMessages []Message
 	var steps []StepResult
 
 	for {
-		stepInputMessages := append(initialPrompt, responseMessages...)
-		stepModel := a.settings.model
-		stepSystemPrompt := a.settings.systemPrompt
+		stepInputMessages := a.filterReasoningHistory(append(initialPrompt, responseMessages...))
+		stepModel := opts.Model
+		stepSystemPrompt := systemPrompt
 		stepActiveTools := opts.ActiveTools
 		stepToolChoice := ToolChoiceAuto
 		if opts.ToolChoice != nil {
@@ -456,6 +852,7 @@ func (a *agent) Generate(ctx context.Context, opts AgentCall) (*AgentResult, err
 		}
 		disableAllTools := false
 		stepTools := a.settings.tools
+		var stepSources []SourceContent
 		if opts.PrepareStep != nil {
 			updatedCtx, prepared, err := opts.PrepareStep(ctx, PrepareStepFunctionOptions{
 				Model:      stepModel,
@@ -489,10 +886,11 @@ func (a *agent) Generate(ctx context.Context, opts AgentCall) (*AgentResult, err
 			if prepared.Tools != nil {
 				stepTools = prepared.Tools
 			}
+			stepSources = prepared.Sources
 		}
 
 		// Recreate prompt with potentially modified system prompt
-		if stepSystemPrompt != a.settings.systemPrompt {
+		if stepSystemPrompt != systemPrompt {
 			stepPrompt, err := a.createPrompt(stepSystemPrompt, opts.Prompt, opts.Messages, opts.Files...)
 			if err != nil {
 				return nil, err
@@ -503,6 +901,7 @@ func (a *agent) Generate(ctx context.Context, opts AgentCall) (*AgentResult, err
 			}
 		}
 
+		stepInputMessages = a.applyCacheBreakpoints(stepModel, stepInputMessages)
 		preparedTools := a.prepareTools(stepTools, a.settings.providerDefinedTools, stepActiveTools, disableAllTools)
 
 		// Filter executable provider tools by activeTools at the
@@ -525,26 +924,43 @@ func (a *agent) Generate(ctx context.Context, opts AgentCall) (*AgentResult, err
 				retryModel = opts.ModelProvider()
 			}
 
-			return retryModel.Generate(ctx, Call{
-				Prompt:           stepInputMessages,
-				MaxOutputTokens:  opts.MaxOutputTokens,
-				Temperature:      opts.Temperature,
-				TopP:             opts.TopP,
-				TopK:             opts.TopK,
-				PresencePenalty:  opts.PresencePenalty,
-				FrequencyPenalty: opts.FrequencyPenalty,
-				Tools:            preparedTools,
-				ToolChoice:       &stepToolChoice,
-				UserAgent:        a.settings.userAgent,
-				Headers:          opts.Headers,
-				ProviderOptions:  opts.ProviderOptions,
+			return a.generate(ctx, retryModel, Call{
+				Prompt:                 toolCallIDs.restore(stepInputMessages),
+				MaxOutputTokens:        opts.MaxOutputTokens,
+				Temperature:            opts.Temperature,
+				TopP:                   opts.TopP,
+				TopK:                   opts.TopK,
+				PresencePenalty:        opts.PresencePenalty,
+				FrequencyPenalty:       opts.FrequencyPenalty,
+				Tools:                  preparedTools,
+				ToolChoice:             &stepToolChoice,
+				ServiceTier:            a.settings.serviceTier,
+				StrictPromptConversion: a.settings.strictPromptConversion,
+				UserAgent:              a.settings.userAgent,
+				Headers:                opts.Headers,
+				ExtraQuery:             opts.ExtraQuery,
+				ExtraBody:              opts.ExtraBody,
+				Metadata:               CallMetadata(ctx),
+				ProviderOptions:        opts.ProviderOptions,
 			})
 		})
 		if err != nil {
 			return nil, err
 		}
+		result.Content = toolCallIDs.uniqueContent(result.Content)
+		if len(steps) == 0 && len(pdfWarnings) > 0 {
+			result.Warnings = slices.Concat(pdfWarnings, result.Warnings)
+		}
+		if err := a.handleWarnings(result.Warnings); err != nil {
+			return nil, err
+		}
+		result.Content, err = a.guardOutput(ctx, result.Content)
+		if err != nil {
+			return nil, err
+		}
 
 		var stepToolCalls []ToolCallContent
+		truncatedToolCall := false
 		for _, content := range result.Content {
 			if content.GetType() == ContentTypeToolCall {
 				toolCall, ok := AsContentType[ToolCallContent](content)
@@ -557,6 +973,11 @@ func (a *agent) Generate(ctx context.Context, opts AgentCall) (*AgentResult, err
 				if toolCall.ProviderExecuted {
 					continue
 				}
+				if isTruncatedToolCall(toolCall, result.FinishReason) {
+					truncatedToolCall = true
+					stepToolCalls = append(stepToolCalls, a.recoverTruncatedToolCall(toolCall, stepTools, stepExecProviderTools))
+					continue
+				}
 				// Validate and potentially repair the tool call
 				validatedToolCall := a.validateAndRepairToolCall(ctx, toolCall, stepTools, stepExecProviderTools, stepSystemPrompt, stepInputMessages, a.settings.repairToolCall)
 				stepToolCalls = append(stepToolCalls, validatedToolCall)
@@ -564,6 +985,10 @@ func (a *agent) Generate(ctx context.Context, opts AgentCall) (*AgentResult, err
 		}
 
 		toolResults, err := a.executeTools(ctx, stepTools, stepExecProviderTools, stepToolCalls, nil)
+		var fatalErr *ToolFatalError
+		if errors.As(err, &fatalErr) {
+			return nil, err
+		}
 
 		// If any tool result requested a stop, deliver all results but don't
 		// request another completion from the model.
@@ -572,6 +997,9 @@ func (a *agent) Generate(ctx context.Context, opts AgentCall) (*AgentResult, err
 		// Build step content with validated tool calls and tool results.
 		// Provider-executed tool calls are kept as-is.
 		stepContent := []Content{}
+		for _, source := range stepSources {
+			stepContent = append(stepContent, source)
+		}
 		toolCallIndex := 0
 		for _, content := range result.Content {
 			if content.GetType() == ContentTypeToolCall {
@@ -592,7 +1020,11 @@ func (a *agent) Generate(ctx context.Context, opts AgentCall) (*AgentResult, err
 		for _, result := range toolResults {
 			stepContent = append(stepContent, result)
 		}
-		currentStepMessages := toResponseMessages(stepContent)
+		currentStepMessages, guardErr := a.guardToolResults(ctx, toResponseMessages(stepContent))
+		if guardErr != nil {
+			return nil, guardErr
+		}
+		currentStepMessages = a.limitToolResults(ctx, stepModel, a.storeArtifacts(ctx, currentStepMessages))
 		responseMessages = append(responseMessages, currentStepMessages...)
 
 		stepResult := StepResult{
@@ -601,14 +1033,17 @@ func (a *agent) Generate(ctx context.Context, opts AgentCall) (*AgentResult, err
 				FinishReason:     result.FinishReason,
 				Usage:            result.Usage,
 				Warnings:         result.Warnings,
+				Safety:           result.Safety,
+				Alternatives:     result.Alternatives,
 				ProviderMetadata: result.ProviderMetadata,
 			},
-			Messages: currentStepMessages,
+			Messages:       currentStepMessages,
+			InputBreakdown: EstimateInputBreakdown(stepInputMessages, preparedTools, result.Usage.InputTokens),
 		}
 		steps = append(steps, stepResult)
 		shouldStop := isStopConditionMet(opts.StopWhen, steps)
 
-		if shouldStop || err != nil || stopTurnRequested || len(stepToolCalls) == 0 || result.FinishReason != FinishReasonToolCalls {
+		if shouldStop || err != nil || stopTurnRequested || len(stepToolCalls) == 0 || (result.FinishReason != FinishReasonToolCalls && !truncatedToolCall) {
 			break
 		}
 	}
@@ -715,6 +1150,7 @@ func toResponseMessages(content []Content) []Message {
 				Output:           result.Result,
 				ProviderExecuted: result.ProviderExecuted,
 				ProviderOptions:  ProviderOptions(result.ProviderMetadata),
+				Sensitive:        result.Sensitive,
 			}
 			if result.ProviderExecuted {
 				// Provider-executed tool results (e.g. web search)
@@ -777,6 +1213,27 @@ func (a *agent) executeTools(ctx context.Context, allTools []AgentTool, execProv
 
 // executeSingleTool executes a single tool and returns its result and a critical error flag.
 func (a *agent) executeSingleTool(ctx context.Context, toolMap map[string]AgentTool, execProviderToolMap map[string]ExecutableProviderTool, toolCall ToolCallContent, toolResultCallback func(result ToolResultContent) error) (ToolResultContent, bool) {
+	toolCall, err := a.debugToolCall(ctx, toolCall)
+	if err != nil {
+		return ToolResultContent{
+			ToolCallID: toolCall.ToolCallID,
+			ToolName:   toolCall.ToolName,
+			Result:     ToolResultOutputContentError{Error: err},
+		}, true
+	}
+	result, isCriticalError := a.runSingleTool(ctx, toolMap, execProviderToolMap, toolCall, toolResultCallback)
+	_, known := toolMap[toolCall.ToolName]
+	if !known {
+		_, known = execProviderToolMap[toolCall.ToolName]
+	}
+	if err := a.auditToolCall(ctx, result, known && !toolCall.Invalid); err != nil {
+		result.Result = ToolResultOutputContentError{Error: err}
+		return result, true
+	}
+	return result, isCriticalError
+}
+
+func (a *agent) runSingleTool(ctx context.Context, toolMap map[string]AgentTool, execProviderToolMap map[string]ExecutableProviderTool, toolCall ToolCallContent, toolResultCallback func(result ToolResultContent) error) (ToolResultContent, bool) {
 	result := ToolResultContent{
 		ToolCallID:       toolCall.ToolCallID,
 		ToolName:         toolCall.ToolName,
@@ -813,17 +1270,28 @@ func (a *agent) executeSingleTool(ctx context.Context, toolMap map[string]AgentT
 	}
 
 	// Execute the tool
-	toolResult, err := runTool(ctx, ToolCall{
-		ID:    toolCall.ToolCallID,
-		Name:  toolCall.ToolName,
-		Input: toolCall.Input,
-	})
+	start := time.Now()
+	call := ToolCall{
+		ID:     toolCall.ToolCallID,
+		Name:   toolCall.ToolName,
+		Input:  toolCall.Input,
+		Output: toolOutputWriter{ctx: ctx},
+	}
+	toolResult, retries, err := a.runTool(ctx, runTool, call)
+	if err != nil {
+		var handlerRetries int
+		toolResult, handlerRetries, err = a.handleToolError(ctx, runTool, call, toolResult, err)
+		retries += handlerRetries
+	}
+	result.Duration = time.Since(start)
+	result.Retries = retries
 	if err != nil {
 		result.Result = ToolResultOutputContentError{
 			Error: err,
 		}
 		result.ClientMetadata = toolResult.Metadata
 		result.StopTurn = toolResult.StopTurn
+		result.Sensitive = toolResult.Sensitive
 		if toolResultCallback != nil {
 			_ = toolResultCallback(result)
 		}
@@ -832,9 +1300,27 @@ func (a *agent) executeSingleTool(ctx context.Context, toolMap map[string]AgentT
 
 	result.ClientMetadata = toolResult.Metadata
 	result.StopTurn = toolResult.StopTurn
+	result.Sensitive = toolResult.Sensitive
+	if toolResult.Fatal {
+		err := toolResult.Err
+		if err == nil {
+			err = errors.New(toolResult.Content)
+		}
+		result.Result = ToolResultOutputContentError{
+			Error: &ToolFatalError{ToolCallID: toolCall.ToolCallID, ToolName: toolCall.ToolName, Err: err},
+		}
+		if toolResultCallback != nil {
+			_ = toolResultCallback(result)
+		}
+		return result, true
+	}
 	if toolResult.IsError {
+		err := toolResult.Err
+		if err == nil {
+			err = errors.New(toolResult.Content)
+		}
 		result.Result = ToolResultOutputContentError{
-			Error: errors.New(toolResult.Content),
+			Error: err,
 		}
 	} else if toolResult.Type == "image" || toolResult.Type == "media" {
 		result.Result = ToolResultOutputContentMedia{
@@ -855,6 +1341,10 @@ func (a *agent) executeSingleTool(ctx context.Context, toolMap map[string]AgentT
 
 // Stream implements Agent.
 func (a *agent) Stream(ctx context.Context, opts AgentStreamCall) (*AgentResult, error) {
+	if a.settings.reflection != nil {
+		return nil, ErrReflectionUnsupported
+	}
+	ctx = withRunPIIRedactions(ctx)
 	// Convert AgentStreamCall to AgentCall for preparation
 	call := AgentCall{
 		Prompt:           opts.Prompt,
@@ -869,26 +1359,49 @@ func (a *agent) Stream(ctx context.Context, opts AgentStreamCall) (*AgentResult,
 		ActiveTools:      opts.ActiveTools,
 		ToolChoice:       opts.ToolChoice,
 		Headers:          opts.Headers,
+		ExtraQuery:       opts.ExtraQuery,
+		ExtraBody:        opts.ExtraBody,
 		ProviderOptions:  opts.ProviderOptions,
 		MaxRetries:       opts.MaxRetries,
 		OnRetry:          opts.OnRetry,
 		OnAuthRefresh:    opts.OnAuthRefresh,
+		Model:            opts.Model,
 		ModelProvider:    opts.ModelProvider,
 		StopWhen:         opts.StopWhen,
 		PrepareStep:      opts.PrepareStep,
 		RepairToolCall:   opts.RepairToolCall,
+		OnToolError:      opts.OnToolError,
 	}
 
 	call = a.prepareCall(call)
+	ctx = withToolErrorHandler(ctx, call.OnToolError)
 
-	initialPrompt, err := a.createPrompt(a.settings.systemPrompt, call.Prompt, call.Messages, call.Files...)
+	systemPrompt, err := a.resolveSystemPrompt(ctx, call)
+	if err != nil {
+		return nil, err
+	}
+	initialPrompt, err := a.createPrompt(systemPrompt, call.Prompt, call.Messages, call.Files...)
+	if err != nil {
+		return nil, err
+	}
+	initialPrompt, err = a.guardInput(ctx, initialPrompt)
 	if err != nil {
 		return nil, err
 	}
+	initialPrompt, err = ResolveFileURLs(ctx, call.Model, initialPrompt, a.maxFileDownloadBytes())
+	if err != nil {
+		return nil, err
+	}
+	initialPrompt, pdfWarnings, err := a.fallBackFromPDFs(ctx, call.Model, initialPrompt)
+	if err != nil {
+		return nil, err
+	}
+	toolCallIDs := newToolCallIDs(initialPrompt)
 
 	var responseMessages []Message
 	var steps []StepResult
 	var totalUsage Usage
+	progress := newProgressTracker(opts.OnProgress, opts.ProgressInterval)
 
 	// Start agent stream
 	if opts.OnAgentStart != nil {
@@ -896,9 +1409,9 @@ func (a *agent) Stream(ctx context.Context, opts AgentStreamCall) (*AgentResult,
 	}
 
 	for stepNumber := 0; ; stepNumber++ {
-		stepInputMessages := append(initialPrompt, responseMessages...)
-		stepModel := a.settings.model
-		stepSystemPrompt := a.settings.systemPrompt
+		stepInputMessages := a.filterReasoningHistory(append(initialPrompt, responseMessages...))
+		stepModel := call.Model
+		stepSystemPrompt := systemPrompt
 		stepActiveTools := call.ActiveTools
 		stepToolChoice := ToolChoiceAuto
 		if call.ToolChoice != nil {
@@ -906,6 +1419,7 @@ func (a *agent) Stream(ctx context.Context, opts AgentStreamCall) (*AgentResult,
 		}
 		disableAllTools := false
 		stepTools := a.settings.tools
+		var stepSources []SourceContent
 		// Apply step preparation if provided
 		if call.PrepareStep != nil {
 			updatedCtx, prepared, err := call.PrepareStep(ctx, PrepareStepFunctionOptions{
@@ -939,10 +1453,11 @@ func (a *agent) Stream(ctx context.Context, opts AgentStreamCall) (*AgentResult,
 			if prepared.Tools != nil {
 				stepTools = prepared.Tools
 			}
+			stepSources = prepared.Sources
 		}
 
 		// Recreate prompt with potentially modified system prompt
-		if stepSystemPrompt != a.settings.systemPrompt {
+		if stepSystemPrompt != systemPrompt {
 			stepPrompt, err := a.createPrompt(stepSystemPrompt, call.Prompt, call.Messages, call.Files...)
 			if err != nil {
 				return nil, err
@@ -952,6 +1467,7 @@ func (a *agent) Stream(ctx context.Context, opts AgentStreamCall) (*AgentResult,
 			}
 		}
 
+		stepInputMessages = a.applyCacheBreakpoints(stepModel, stepInputMessages)
 		preparedTools := a.prepareTools(stepTools, a.settings.providerDefinedTools, stepActiveTools, disableAllTools)
 
 		// Filter executable provider tools by activeTools at the
@@ -963,20 +1479,32 @@ func (a *agent) Stream(ctx context.Context, opts AgentStreamCall) (*AgentResult,
 		if opts.OnStepStart != nil {
 			_ = opts.OnStepStart(stepNumber)
 		}
+		for _, source := range stepSources {
+			if opts.OnSource != nil {
+				if err := opts.OnSource(source); err != nil {
+					return nil, err
+				}
+			}
+		}
 		// Create streaming call
 		streamCall := Call{
-			Prompt:           stepInputMessages,
-			MaxOutputTokens:  call.MaxOutputTokens,
-			Temperature:      call.Temperature,
-			TopP:             call.TopP,
-			TopK:             call.TopK,
-			PresencePenalty:  call.PresencePenalty,
-			FrequencyPenalty: call.FrequencyPenalty,
-			Tools:            preparedTools,
-			ToolChoice:       &stepToolChoice,
-			UserAgent:        a.settings.userAgent,
-			Headers:          call.Headers,
-			ProviderOptions:  call.ProviderOptions,
+			Prompt:                 toolCallIDs.restore(stepInputMessages),
+			MaxOutputTokens:        call.MaxOutputTokens,
+			Temperature:            call.Temperature,
+			TopP:                   call.TopP,
+			TopK:                   call.TopK,
+			PresencePenalty:        call.PresencePenalty,
+			FrequencyPenalty:       call.FrequencyPenalty,
+			Tools:                  preparedTools,
+			ToolChoice:             &stepToolChoice,
+			ServiceTier:            a.settings.serviceTier,
+			StrictPromptConversion: a.settings.strictPromptConversion,
+			UserAgent:              a.settings.userAgent,
+			Headers:                call.Headers,
+			ExtraQuery:             call.ExtraQuery,
+			ExtraBody:              call.ExtraBody,
+			Metadata:               CallMetadata(ctx),
+			ProviderOptions:        call.ProviderOptions,
 		}
 
 		// Execute step with retry logic wrapping both stream creation and processing
@@ -996,14 +1524,35 @@ func (a *agent) Stream(ctx context.Context, opts AgentStreamCall) (*AgentResult,
 				retryModel = call.ModelProvider()
 			}
 
-			// Create the stream
-			stream, err := retryModel.Stream(ctx, streamCall)
-			if err != nil {
-				return stepExecutionResult{}, err
-			}
+			run := func() (result stepExecutionResult, err error) {
+				// Report progress from the request on, as the first token
+				// can take a while.
+				progress.startStep(stepNumber)
+				streamCtx := progress.watch(ctx)
+				defer func() {
+					if progressErr := progress.stop(); progressErr != nil {
+						result, err = stepExecutionResult{}, progressErr
+					}
+				}()
 
-			// Process the stream
-			result, err := a.processStepStream(ctx, stream, opts, steps, stepTools, stepExecProviderTools)
+				// Create the stream
+				stream, err := a.stream(streamCtx, retryModel, streamCall)
+				if err != nil {
+					return stepExecutionResult{}, err
+				}
+				stream = toolCallIDs.uniqueStream(stream)
+
+				// Process the stream
+				defer a.recoverPanic(&err)
+				return a.processStepStream(ctx, stream, opts, progress, stepSystemPrompt, stepTools, stepExecProviderTools)
+			}
+			result, err := run()
+			if fallBackFromFlex(&streamCall, err) {
+				result, err = run()
+				if err == nil {
+					result.StepResult.Warnings = append(result.StepResult.Warnings, flexFallbackWarning)
+				}
+			}
 			if err != nil {
 				return stepExecutionResult{}, err
 			}
@@ -1015,18 +1564,51 @@ func (a *agent) Stream(ctx context.Context, opts AgentStreamCall) (*AgentResult,
 			}
 			return nil, err
 		}
+		if stepNumber == 0 && len(pdfWarnings) > 0 {
+			result.StepResult.Warnings = slices.Concat(pdfWarnings, result.StepResult.Warnings)
+		}
+		if err := a.handleWarnings(result.StepResult.Warnings); err != nil {
+			if opts.OnError != nil {
+				opts.OnError(err)
+			}
+			return nil, err
+		}
+		if len(a.settings.guardrails) > 0 {
+			result.StepResult.Messages, err = a.guardToolResults(ctx, result.StepResult.Messages)
+			if err != nil {
+				if opts.OnError != nil {
+					opts.OnError(err)
+				}
+				return nil, err
+			}
+		}
 
+		if len(stepSources) > 0 {
+			content := make(ResponseContent, 0, len(stepSources)+len(result.StepResult.Content))
+			for _, source := range stepSources {
+				content = append(content, source)
+			}
+			result.StepResult.Content = append(content, result.StepResult.Content...)
+		}
+		result.StepResult.Messages = a.limitToolResults(ctx, stepModel, a.storeArtifacts(ctx, result.StepResult.Messages))
+		result.StepResult.InputBreakdown = EstimateInputBreakdown(stepInputMessages, preparedTools, result.StepResult.Usage.InputTokens)
 		steps = append(steps, result.StepResult)
 		totalUsage = addUsage(totalUsage, result.StepResult.Usage)
 
+		if err := progress.finishStep(result.StepResult.Usage); err != nil {
+			if opts.OnError != nil {
+				opts.OnError(err)
+			}
+			return nil, err
+		}
+
 		// Call step finished callback
 		if opts.OnStepFinish != nil {
 			_ = opts.OnStepFinish(result.StepResult)
 		}
 
 		// Add step messages to response messages
-		stepMessages := toResponseMessages(result.StepResult.Content)
-		responseMessages = append(responseMessages, stepMessages...)
+		responseMessages = append(responseMessages, result.StepResult.Messages...)
 
 		// Check stop conditions
 		shouldStop := isStopConditionMet(call.StopWhen, steps)
@@ -1199,6 +1781,14 @@ func (a *agent) validateToolCall(toolCall ToolCallContent, availableTools []Agen
 	return nil
 }
 
+// resolveSystemPrompt returns the system prompt for the call.
+func (a *agent) resolveSystemPrompt(ctx context.Context, call AgentCall) (string, error) {
+	if a.settings.systemPromptFunc == nil {
+		return a.settings.systemPrompt, nil
+	}
+	return a.settings.systemPromptFunc(ctx, call)
+}
+
 func (a *agent) createPrompt(system, prompt string, messages []Message, files ...FilePart) (Prompt, error) {
 	// Validation: empty prompt is only allowed when there are messages,
 	// no files to attach, and the last message is a user or tool message.
@@ -1248,6 +1838,16 @@ func WithSystemPrompt(prompt string) AgentOption {
 	}
 }
 
+// WithSystemPromptFunc sets a function that computes the system prompt at the
+// start of every call, so it can include values such as the current time, the
+// user's profile or retrieved memory. It takes precedence over
+// WithSystemPrompt, and an error from it aborts the call.
+func WithSystemPromptFunc(fn SystemPromptFunction) AgentOption {
+	return func(s *agentSettings) {
+		s.systemPromptFunc = fn
+	}
+}
+
 // WithMaxOutputTokens sets the maximum output tokens for the agent.
 func WithMaxOutputTokens(tokens int64) AgentOption {
 	return func(s *agentSettings) {
@@ -1341,6 +1941,22 @@ func WithPrepareStep(fn PrepareStepFunction) AgentOption {
 	}
 }
 
+// PrepareStepMessages adapts a function that transforms the step's input
+// messages into a PrepareStepFunction, for use with WithPrepareStep or
+// AgentCall.PrepareStep. The function runs before every model call and can
+// prune history, inject retrieved context or rewrite tool results. The
+// returned messages are sent for that step only; the agent keeps its
+// accumulated history unchanged.
+func PrepareStepMessages(fn func(ctx context.Context, stepNumber int, prompt Prompt) (Prompt, error)) PrepareStepFunction {
+	return func(ctx context.Context, options PrepareStepFunctionOptions) (context.Context, PrepareStepResult, error) {
+		prompt, err := fn(ctx, options.StepNumber, options.Messages)
+		if err != nil {
+			return ctx, PrepareStepResult{}, err
+		}
+		return ctx, PrepareStepResult{Messages: prompt}, nil
+	}
+}
+
 // WithRepairToolCall sets the repair tool call function for the agent.
 func WithRepairToolCall(fn RepairToolCallFunction) AgentOption {
 	return func(s *agentSettings) {
@@ -1363,12 +1979,13 @@ func WithOnRetry(callback OnRetryCallback) AgentOption {
 }
 
 // processStepStream processes a single step's stream and returns the step result.
-func (a *agent) processStepStream(ctx context.Context, stream StreamResponse, opts AgentStreamCall, _ []StepResult, stepTools []AgentTool, execProviderTools []ExecutableProviderTool) (stepExecutionResult, error) {
+func (a *agent) processStepStream(ctx context.Context, stream StreamResponse, opts AgentStreamCall, progress *progressTracker, systemPrompt string, stepTools []AgentTool, execProviderTools []ExecutableProviderTool) (stepExecutionResult, error) {
 	var stepContent []Content
 	var stepToolCalls []ToolCallContent
 	var stepUsage Usage
 	stepFinishReason := FinishReasonUnknown
 	var stepWarnings []CallWarning
+	var stepSafety *SafetyInfo
 	var stepProviderMetadata ProviderMetadata
 
 	activeToolCalls := make(map[string]*ToolCallContent)
@@ -1385,6 +2002,9 @@ func (a *agent) processStepStream(ctx context.Context, stream StreamResponse, op
 		parallel bool
 	}
 	var pendingDispatches []toolExecutionRequest
+	// malformedToolCalls are the tool calls whose input isn't valid JSON,
+	// handled once the stream's finish reason is known.
+	var malformedToolCalls []ToolCallContent
 
 	// Create a map for quick tool lookup
 	toolMap := make(map[string]AgentTool)
@@ -1397,6 +2017,29 @@ func (a *agent) processStepStream(ctx context.Context, stream StreamResponse, op
 		execProviderToolMap[ept.GetName()] = ept
 	}
 
+	addToolCall := func(toolCall ToolCallContent) error {
+		stepToolCalls = append(stepToolCalls, toolCall)
+		stepContent = append(stepContent, toolCall)
+
+		if opts.OnToolCall != nil {
+			err := opts.OnToolCall(toolCall)
+			if err != nil {
+				return err
+			}
+		}
+
+		// Determine if tool can run in parallel
+		isParallel := false
+		if tool, exists := toolMap[toolCall.ToolName]; exists {
+			isParallel = tool.Info().Parallel
+		}
+
+		// Buffer dispatch until stream is fully consumed so that all
+		// OnToolCall callbacks complete before any tool result is written.
+		pendingDispatches = append(pendingDispatches, toolExecutionRequest{toolCall: toolCall, parallel: isParallel})
+		return nil
+	}
+
 	// Process stream parts
 	for part := range stream {
 		// Forward all parts to chunk callback
@@ -1407,9 +2050,13 @@ func (a *agent) processStepStream(ctx context.Context, stream StreamResponse, op
 			}
 		}
 
+		if err := progress.observe(part); err != nil {
+			return stepExecutionResult{}, err
+		}
+
 		switch part.Type {
 		case StreamPartTypeWarnings:
-			stepWarnings = part.Warnings
+			stepWarnings = append(stepWarnings, part.Warnings...)
 			if opts.OnWarnings != nil {
 				err := opts.OnWarnings(part.Warnings)
 				if err != nil {
@@ -1552,29 +2199,18 @@ func (a *agent) processStepStream(ctx context.Context, stream StreamResponse, op
 					}
 				}
 				delete(activeToolCalls, part.ID)
+			} else if !json.Valid([]byte(toolCall.Input)) {
+				// The input may have been cut off by the output token
+				// limit, which is only known once the stream finishes.
+				malformedToolCalls = append(malformedToolCalls, toolCall)
+				delete(activeToolCalls, part.ID)
 			} else {
 				// Validate and potentially repair the tool call
-				validatedToolCall := a.validateAndRepairToolCall(ctx, toolCall, stepTools, execProviderTools, a.settings.systemPrompt, nil, opts.RepairToolCall)
-				stepToolCalls = append(stepToolCalls, validatedToolCall)
-				stepContent = append(stepContent, validatedToolCall)
-
-				if opts.OnToolCall != nil {
-					err := opts.OnToolCall(validatedToolCall)
-					if err != nil {
-						return stepExecutionResult{}, err
-					}
-				}
-
-				// Determine if tool can run in parallel
-				isParallel := false
-				if tool, exists := toolMap[validatedToolCall.ToolName]; exists {
-					isParallel = tool.Info().Parallel
+				validatedToolCall := a.validateAndRepairToolCall(ctx, toolCall, stepTools, execProviderTools, systemPrompt, nil, opts.RepairToolCall)
+				if err := addToolCall(validatedToolCall); err != nil {
+					return stepExecutionResult{}, err
 				}
 
-				// Buffer dispatch until stream is fully consumed so that all
-				// OnToolCall callbacks complete before any tool result is written.
-				pendingDispatches = append(pendingDispatches, toolExecutionRequest{toolCall: validatedToolCall, parallel: isParallel})
-
 				// Clean up active tool call
 				delete(activeToolCalls, part.ID)
 			}
@@ -1605,6 +2241,7 @@ func (a *agent) processStepStream(ctx context.Context, stream StreamResponse, op
 				ID:               part.ID,
 				URL:              part.URL,
 				Title:            part.Title,
+				Citations:        part.Citations,
 				ProviderMetadata: part.ProviderMetadata,
 			}
 			stepContent = append(stepContent, sourceContent)
@@ -1618,6 +2255,7 @@ func (a *agent) processStepStream(ctx context.Context, stream StreamResponse, op
 		case StreamPartTypeFinish:
 			stepUsage = part.Usage
 			stepFinishReason = part.FinishReason
+			stepSafety = part.Safety
 			stepProviderMetadata = part.ProviderMetadata
 			if opts.OnStreamFinish != nil {
 				err := opts.OnStreamFinish(part.Usage, part.FinishReason, part.ProviderMetadata)
@@ -1631,8 +2269,43 @@ func (a *agent) processStepStream(ctx context.Context, stream StreamResponse, op
 		}
 	}
 
+	// The stream has ended, so stop the progress reports of its step.
+	if err := progress.stop(); err != nil {
+		return stepExecutionResult{}, err
+	}
+
+	// A response cut off by the output token limit may end in the middle
+	// of a tool call's input, before the provider emitted the call.
+	if stepFinishReason == FinishReasonLength {
+		for _, id := range slices.Sorted(maps.Keys(activeToolCalls)) {
+			if toolCall := activeToolCalls[id]; !toolCall.ProviderExecuted {
+				malformedToolCalls = append(malformedToolCalls, *toolCall)
+			}
+		}
+	}
+	truncatedToolCall := false
+	for _, toolCall := range malformedToolCalls {
+		if isTruncatedToolCall(toolCall, stepFinishReason) {
+			truncatedToolCall = true
+			toolCall = a.recoverTruncatedToolCall(toolCall, stepTools, execProviderTools)
+		} else {
+			toolCall = a.validateAndRepairToolCall(ctx, toolCall, stepTools, execProviderTools, systemPrompt, nil, opts.RepairToolCall)
+		}
+		if err := addToolCall(toolCall); err != nil {
+			return stepExecutionResult{}, err
+		}
+	}
+
+	// Check the step's output before any tool runs, so a blocked step has
+	// no side effects.
+	stepContent, err := a.guardOutput(ctx, stepContent)
+	if err != nil {
+		return stepExecutionResult{}, err
+	}
+
 	// All tool calls are now collected. Create the execution channel sized to
 	// avoid blocking during dispatch, start the coordinator, then flush the batch.
+	onToolExecutionDelta := toolExecutionDeltaFunc(opts)
 	toolChan := make(chan toolExecutionRequest, len(pendingDispatches))
 	var toolExecutionWg sync.WaitGroup
 	var toolStateMu sync.Mutex
@@ -1650,7 +2323,8 @@ func (a *agent) processStepStream(ctx context.Context, stream StreamResponse, op
 				parallelSem <- struct{}{}
 				toolExecutionWg.Go(func() {
 					defer func() { <-parallelSem }()
-					result, isCriticalError := a.executeSingleTool(ctx, toolMap, execProviderToolMap, req.toolCall, opts.OnToolResult)
+					toolCtx := withToolOutputReporter(ctx, req.toolCall.ToolCallID, onToolExecutionDelta)
+					result, isCriticalError := a.executeSingleTool(toolCtx, toolMap, execProviderToolMap, req.toolCall, opts.OnToolResult)
 					toolStateMu.Lock()
 					toolResults = append(toolResults, result)
 					if isCriticalError && toolExecutionErr == nil {
@@ -1662,7 +2336,8 @@ func (a *agent) processStepStream(ctx context.Context, stream StreamResponse, op
 				})
 			} else {
 				sequentialMu.Lock()
-				result, isCriticalError := a.executeSingleTool(ctx, toolMap, execProviderToolMap, req.toolCall, opts.OnToolResult)
+				toolCtx := withToolOutputReporter(ctx, req.toolCall.ToolCallID, onToolExecutionDelta)
+				result, isCriticalError := a.executeSingleTool(toolCtx, toolMap, execProviderToolMap, req.toolCall, opts.OnToolResult)
 				toolStateMu.Lock()
 				toolResults = append(toolResults, result)
 				if isCriticalError && toolExecutionErr == nil {
@@ -1704,13 +2379,14 @@ func (a *agent) processStepStream(ctx context.Context, stream StreamResponse, op
 			FinishReason:     stepFinishReason,
 			Usage:            stepUsage,
 			Warnings:         stepWarnings,
+			Safety:           stepSafety,
 			ProviderMetadata: stepProviderMetadata,
 		},
 		Messages: toResponseMessages(stepContent),
 	}
 
 	// Determine if we should continue (has tool calls and not stopped)
-	shouldContinue := len(stepToolCalls) > 0 && stepFinishReason == FinishReasonToolCalls && !hasStopTurn(toolResults)
+	shouldContinue := len(stepToolCalls) > 0 && (stepFinishReason == FinishReasonToolCalls || truncatedToolCall) && !hasStopTurn(toolResults)
 
 	return stepExecutionResult{
 		StepResult:     stepResult,
@@ -1718,6 +2394,25 @@ func (a *agent) processStepStream(ctx context.Context, stream StreamResponse, op
 	}, nil
 }
 
+// toolExecutionDeltaFunc returns the function passing the output of
+// running tools to the stream's callbacks, or nil if none listens.
+func toolExecutionDeltaFunc(opts AgentStreamCall) OnToolExecutionDeltaFunc {
+	if opts.OnChunk == nil && opts.OnToolExecutionDelta == nil {
+		return nil
+	}
+	return func(id, delta string) error {
+		if opts.OnChunk != nil {
+			if err := opts.OnChunk(StreamPart{Type: StreamPartTypeToolExecutionDelta, ID: id, Delta: delta}); err != nil {
+				return err
+			}
+		}
+		if opts.OnToolExecutionDelta != nil {
+			return opts.OnToolExecutionDelta(id, delta)
+		}
+		return nil
+	}
+}
+
 func addUsage(a, b Usage) Usage {
 	return Usage{
 		InputTokens:         a.InputTokens + b.InputTokens,
@@ -1736,6 +2431,15 @@ func WithHeaders(headers map[string]string) AgentOption {
 	}
 }
 
+// WithExtraBody sets fields to add to the JSON body of every request of the
+// agent. The ExtraBody of a call replaces matching fields. See
+// Call.ExtraBody.
+func WithExtraBody(body map[string]any) AgentOption {
+	return func(s *agentSettings) {
+		s.extraBody = body
+	}
+}
+
 // WithUserAgent sets the User-Agent header for the agent. This overrides any
 // provider-level User-Agent setting.
 func WithUserAgent(ua string) AgentOption {
@@ -1744,7 +2448,9 @@ func WithUserAgent(ua string) AgentOption {
 	}
 }
 
-// WithProviderOptions sets the provider options for the agent.
+// WithProviderOptions sets the default provider options for the agent.
+// The options of each call are merged onto them, as described in
+// MergeProviderOptions.
 func WithProviderOptions(providerOptions ProviderOptions) AgentOption {
 	return func(s *agentSettings) {
 		s.providerOptions = providerOptions
@@ -7,6 +7,7 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -761,3 +762,261 @@ func TestStreamingAgent_StopTurn(t *testing.T) {
 	require.Len(t, responseResults, 1)
 	require.True(t, responseResults[0].StopTurn)
 }
+
+func TestStreamingAgent_OnProgress(t *testing.T) {
+	t.Parallel()
+
+	mockModel := &mockLanguageModel{
+		streamFunc: func(ctx context.Context, call Call) (StreamResponse, error) {
+			return func(yield func(StreamPart) bool) {
+				if !yield(StreamPart{Type: StreamPartTypeTextStart, ID: "text-1"}) {
+					return
+				}
+				if !yield(StreamPart{Type: StreamPartTypeTextDelta, ID: "text-1", Delta: "Hello"}) {
+					return
+				}
+				if !yield(StreamPart{Type: StreamPartTypeTextDelta, ID: "text-1", Delta: ", world!"}) {
+					return
+				}
+				if !yield(StreamPart{Type: StreamPartTypeTextEnd, ID: "text-1"}) {
+					return
+				}
+				yield(StreamPart{
+					Type:         StreamPartTypeFinish,
+					Usage:        Usage{InputTokens: 3, OutputTokens: 10, TotalTokens: 13},
+					FinishReason: FinishReasonStop,
+				})
+			}, nil
+		},
+	}
+
+	agent := NewAgent(mockModel)
+
+	var reports []Progress
+	_, err := agent.Stream(context.Background(), AgentStreamCall{
+		Prompt:           "Say hello",
+		ProgressInterval: time.Nanosecond,
+		OnProgress: func(p Progress) error {
+			reports = append(reports, p)
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, reports)
+
+	// Character counts only grow during the step.
+	for i := 1; i < len(reports); i++ {
+		require.GreaterOrEqual(t, reports[i].StepOutputChars, reports[i-1].StepOutputChars)
+	}
+
+	// The last report is emitted after the step finishes and includes its usage.
+	last := reports[len(reports)-1]
+	require.Equal(t, 0, last.StepNumber)
+	require.Equal(t, len("Hello, world!"), last.StepOutputChars)
+	require.Equal(t, int64(13), last.Usage.TotalTokens)
+	require.GreaterOrEqual(t, last.Elapsed, last.StepElapsed)
+
+	// Usage is not reported until the step finishes.
+	require.Zero(t, reports[0].Usage.TotalTokens)
+}
+
+func TestStreamingAgent_OnProgressError(t *testing.T) {
+	t.Parallel()
+
+	mockModel := &mockLanguageModel{
+		streamFunc: func(ctx context.Context, call Call) (StreamResponse, error) {
+			return func(yield func(StreamPart) bool) {
+				if !yield(StreamPart{Type: StreamPartTypeTextStart, ID: "text-1"}) {
+					return
+				}
+				yield(StreamPart{Type: StreamPartTypeFinish, FinishReason: FinishReasonStop})
+			}, nil
+		},
+	}
+
+	progressErr := fmt.Errorf("stop")
+	agent := NewAgent(mockModel)
+	_, err := agent.Stream(context.Background(), AgentStreamCall{
+		Prompt:           "Say hello",
+		MaxRetries:       new(int),
+		ProgressInterval: time.Nanosecond,
+		OnProgress: func(Progress) error {
+			return progressErr
+		},
+	})
+	require.ErrorIs(t, err, progressErr)
+}
+
+func TestStreamingAgent_OnProgressBeforeFirstPart(t *testing.T) {
+	t.Parallel()
+
+	// firstPart is released once the agent has reported progress twice
+	// while the model waits.
+	slowModel := func(firstPart <-chan struct{}) *mockLanguageModel {
+		return &mockLanguageModel{
+			streamFunc: func(ctx context.Context, call Call) (StreamResponse, error) {
+				return func(yield func(StreamPart) bool) {
+					select {
+					case <-firstPart:
+					case <-ctx.Done():
+						yield(StreamPart{Type: StreamPartTypeError, Error: ctx.Err()})
+						return
+					}
+					_ = yield(StreamPart{Type: StreamPartTypeTextStart, ID: "text-1"}) &&
+						yield(StreamPart{Type: StreamPartTypeTextDelta, ID: "text-1", Delta: "Hi"}) &&
+						yield(StreamPart{Type: StreamPartTypeTextEnd, ID: "text-1"}) &&
+						yield(StreamPart{Type: StreamPartTypeFinish, FinishReason: FinishReasonStop})
+				}, nil
+			},
+		}
+	}
+
+	t.Run("reports while waiting", func(t *testing.T) {
+		t.Parallel()
+		firstPart := make(chan struct{})
+		var mu sync.Mutex
+		var reports []Progress
+		_, err := NewAgent(slowModel(firstPart)).Stream(t.Context(), AgentStreamCall{
+			Prompt:           "Say hi",
+			ProgressInterval: time.Millisecond,
+			OnProgress: func(p Progress) error {
+				mu.Lock()
+				defer mu.Unlock()
+				reports = append(reports, p)
+				if len(reports) == 2 {
+					close(firstPart)
+				}
+				return nil
+			},
+		})
+		require.NoError(t, err)
+
+		mu.Lock()
+		defer mu.Unlock()
+		require.Greater(t, len(reports), 2)
+		require.Zero(t, reports[0].StepOutputChars)
+		require.Positive(t, reports[1].StepElapsed)
+		require.Equal(t, len("Hi"), reports[len(reports)-1].StepOutputChars)
+	})
+
+	t.Run("a failed report ends the stream", func(t *testing.T) {
+		t.Parallel()
+		progressErr := fmt.Errorf("stop")
+		_, err := NewAgent(slowModel(make(chan struct{}))).Stream(t.Context(), AgentStreamCall{
+			Prompt:           "Say hi",
+			MaxRetries:       new(int),
+			ProgressInterval: time.Millisecond,
+			OnProgress: func(Progress) error {
+				return progressErr
+			},
+		})
+		require.ErrorIs(t, err, progressErr)
+	})
+}
+
+func TestStreamingAgentReportToolOutput(t *testing.T) {
+	t.Parallel()
+
+	progressTool := &mockTool{
+		name: "build",
+		executeFunc: func(ctx context.Context, call ToolCall) (ToolResponse, error) {
+			require.NoError(t, ReportToolOutput(ctx, "compiling\n"))
+			require.NoError(t, ReportToolOutput(ctx, "linking\n"))
+			return NewTextResponse("built"), nil
+		},
+	}
+
+	step := 0
+	mockModel := &mockLanguageModel{
+		streamFunc: func(ctx context.Context, call Call) (StreamResponse, error) {
+			step++
+			return func(yield func(StreamPart) bool) {
+				if step == 1 {
+					if !yield(StreamPart{Type: StreamPartTypeToolCall, ID: "tool-1", ToolCallName: "build", ToolCallInput: `{}`}) {
+						return
+					}
+					yield(StreamPart{Type: StreamPartTypeFinish, FinishReason: FinishReasonToolCalls})
+					return
+				}
+				yield(StreamPart{Type: StreamPartTypeFinish, FinishReason: FinishReasonStop})
+			}, nil
+		},
+	}
+
+	var deltas []string
+	agent := NewAgent(mockModel, WithTools(progressTool))
+	_, err := agent.Stream(t.Context(), AgentStreamCall{
+		Prompt: "build it",
+		OnToolExecutionDelta: func(id, delta string) error {
+			require.Equal(t, "tool-1", id)
+			deltas = append(deltas, delta)
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"compiling\n", "linking\n"}, deltas)
+
+	// Outside of Stream reported output is dropped.
+	require.NoError(t, ReportToolOutput(t.Context(), "ignored"))
+}
+
+func TestStreamingAgentToolExecutionDelta(t *testing.T) {
+	t.Parallel()
+
+	progressTool := &mockTool{
+		name: "build",
+		executeFunc: func(ctx context.Context, call ToolCall) (ToolResponse, error) {
+			_, err := fmt.Fprint(call.Output, "compiling\n")
+			require.NoError(t, err)
+			_, err = fmt.Fprint(call.Output, "linking\n")
+			require.NoError(t, err)
+			return NewTextResponse("built"), nil
+		},
+	}
+
+	step := 0
+	mockModel := &mockLanguageModel{
+		streamFunc: func(ctx context.Context, call Call) (StreamResponse, error) {
+			step++
+			return func(yield func(StreamPart) bool) {
+				if step == 1 {
+					if !yield(StreamPart{Type: StreamPartTypeToolCall, ID: "tool-1", ToolCallName: "build", ToolCallInput: `{}`}) {
+						return
+					}
+					yield(StreamPart{Type: StreamPartTypeFinish, FinishReason: FinishReasonToolCalls})
+					return
+				}
+				yield(StreamPart{Type: StreamPartTypeFinish, FinishReason: FinishReasonStop})
+			}, nil
+		},
+	}
+
+	var deltas []string
+	var parts []StreamPart
+	agent := NewAgent(mockModel, WithTools(progressTool))
+	_, err := agent.Stream(t.Context(), AgentStreamCall{
+		Prompt: "build it",
+		OnToolExecutionDelta: func(id, delta string) error {
+			require.Equal(t, "tool-1", id)
+			deltas = append(deltas, delta)
+			return nil
+		},
+		OnChunk: func(part StreamPart) error {
+			if part.Type == StreamPartTypeToolExecutionDelta {
+				parts = append(parts, part)
+			}
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"compiling\n", "linking\n"}, deltas)
+	require.Equal(t, []StreamPart{
+		{Type: StreamPartTypeToolExecutionDelta, ID: "tool-1", Delta: "compiling\n"},
+		{Type: StreamPartTypeToolExecutionDelta, ID: "tool-1", Delta: "linking\n"},
+	}, parts)
+
+	// Generate drops the output.
+	_, err = NewAgent(toolCallingModel(ToolCallContent{ToolCallID: "tool-1", ToolName: "build", Input: `{}`}), WithTools(progressTool)).
+		Generate(t.Context(), AgentCall{Prompt: "build it"})
+	require.NoError(t, err)
+}
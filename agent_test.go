@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"regexp"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -1130,6 +1131,23 @@ func TestStopConditions(t *testing.T) {
 		// Should not stop with empty steps
 		require.False(t, condition([]StepResult{}))
 	})
+
+	t.Run("TextMatches", func(t *testing.T) {
+		t.Parallel()
+		condition := TextMatches(regexp.MustCompile(`(?i)^world$`))
+
+		// Should not stop when the last step's text doesn't match
+		require.False(t, condition([]StepResult{step1}))
+
+		// Should stop when the last step's text matches
+		require.True(t, condition([]StepResult{step1, step2}))
+
+		// Should not stop when the match is in an earlier step
+		require.False(t, condition([]StepResult{step1, step2, step3}))
+
+		// Should not stop with empty steps
+		require.False(t, condition([]StepResult{}))
+	})
 }
 
 func TestStopConditions_Integration(t *testing.T) {
@@ -2719,3 +2737,226 @@ func TestAgent_Generate_StopTurn_NotSet(t *testing.T) {
 	require.Len(t, toolResults, 1)
 	require.False(t, toolResults[0].StopTurn)
 }
+
+func TestAgent_ModelOverride(t *testing.T) {
+	t.Parallel()
+
+	var seen []string
+	newModel := func(name string) *mockLanguageModel {
+		return &mockLanguageModel{
+			generateFunc: func(_ context.Context, call Call) (*Response, error) {
+				require.Equal(t, MessageRoleSystem, call.Prompt[0].Role)
+				require.Len(t, call.Tools, 1)
+				seen = append(seen, name)
+				return &Response{Content: []Content{TextContent{Text: name}}, FinishReason: FinishReasonStop}, nil
+			},
+			streamFunc: func(_ context.Context, call Call) (StreamResponse, error) {
+				require.Len(t, call.Tools, 1)
+				seen = append(seen, name)
+				return func(yield func(StreamPart) bool) {
+					yield(StreamPart{Type: StreamPartTypeFinish, FinishReason: FinishReasonStop})
+				}, nil
+			},
+		}
+	}
+	small, large := newModel("small"), newModel("large")
+	agent := NewAgent(small, WithSystemPrompt("Be brief."), WithTools(&mockTool{name: "tool"}))
+
+	_, err := agent.Generate(t.Context(), AgentCall{Prompt: "Hi", Model: large})
+	require.NoError(t, err)
+	_, err = agent.Generate(t.Context(), AgentCall{Prompt: "Hi"})
+	require.NoError(t, err)
+	_, err = agent.Stream(t.Context(), AgentStreamCall{Prompt: "Hi", Model: large})
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"large", "small", "large"}, seen)
+}
+
+func TestAgent_SystemPromptFunc(t *testing.T) {
+	t.Parallel()
+
+	var prompts []string
+	model := &mockLanguageModel{
+		generateFunc: func(_ context.Context, call Call) (*Response, error) {
+			prompts = append(prompts, call.Prompt[0].Content[0].(TextPart).Text)
+			return &Response{Content: []Content{TextContent{Text: "ok"}}, FinishReason: FinishReasonStop}, nil
+		},
+		streamFunc: func(_ context.Context, call Call) (StreamResponse, error) {
+			prompts = append(prompts, call.Prompt[0].Content[0].(TextPart).Text)
+			return func(yield func(StreamPart) bool) {
+				yield(StreamPart{Type: StreamPartTypeFinish, FinishReason: FinishReasonStop})
+			}, nil
+		},
+	}
+
+	calls := 0
+	agent := NewAgent(model, WithSystemPrompt("static"), WithSystemPromptFunc(func(_ context.Context, call AgentCall) (string, error) {
+		calls++
+		return fmt.Sprintf("call %d: %s", calls, call.Prompt), nil
+	}))
+
+	_, err := agent.Generate(t.Context(), AgentCall{Prompt: "first"})
+	require.NoError(t, err)
+	_, err = agent.Stream(t.Context(), AgentStreamCall{Prompt: "second"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"call 1: first", "call 2: second"}, prompts)
+
+	failing := NewAgent(model, WithSystemPromptFunc(func(context.Context, AgentCall) (string, error) {
+		return "", errors.New("memory unavailable")
+	}))
+	_, err = failing.Generate(t.Context(), AgentCall{Prompt: "Hi"})
+	require.EqualError(t, err, "memory unavailable")
+	_, err = failing.Stream(t.Context(), AgentStreamCall{Prompt: "Hi"})
+	require.EqualError(t, err, "memory unavailable")
+	require.Len(t, prompts, 2)
+}
+
+func TestPrepareStepMessages(t *testing.T) {
+	t.Parallel()
+
+	var sent []int
+	model := &mockLanguageModel{
+		generateFunc: func(_ context.Context, call Call) (*Response, error) {
+			sent = append(sent, len(call.Prompt))
+			if len(sent) == 1 {
+				return &Response{
+					Content:      []Content{ToolCallContent{ToolCallID: "call-1", ToolName: "tool", Input: `{}`}},
+					FinishReason: FinishReasonToolCalls,
+				}, nil
+			}
+			return &Response{Content: []Content{TextContent{Text: "done"}}, FinishReason: FinishReasonStop}, nil
+		},
+	}
+
+	var steps []int
+	keepLast := PrepareStepMessages(func(_ context.Context, stepNumber int, prompt Prompt) (Prompt, error) {
+		steps = append(steps, stepNumber)
+		return Prompt{prompt[0], prompt[len(prompt)-1]}, nil
+	})
+	agent := NewAgent(model, WithSystemPrompt("system"), WithTools(&mockTool{name: "tool"}), WithPrepareStep(keepLast))
+
+	result, err := agent.Generate(t.Context(), AgentCall{Prompt: "Hi"})
+	require.NoError(t, err)
+	require.Equal(t, []int{0, 1}, steps)
+	// The second step would send system, user, assistant and tool messages
+	// without pruning.
+	require.Equal(t, []int{2, 2}, sent)
+	require.Len(t, result.Steps, 2)
+
+	failing := NewAgent(model, WithPrepareStep(PrepareStepMessages(func(context.Context, int, Prompt) (Prompt, error) {
+		return nil, errors.New("retrieval failed")
+	})))
+	_, err = failing.Generate(t.Context(), AgentCall{Prompt: "Hi"})
+	require.EqualError(t, err, "retrieval failed")
+}
+
+func TestAgentResult_DecodeFinal(t *testing.T) {
+	t.Parallel()
+
+	type answer struct {
+		City        string `json:"city"`
+		Temperature int    `json:"temperature"`
+	}
+
+	t.Run("final answer tool", func(t *testing.T) {
+		t.Parallel()
+		result := &AgentResult{
+			Steps: []StepResult{
+				{Response: Response{Content: ResponseContent{
+					ToolCallContent{ToolCallID: "1", ToolName: FinalAnswerToolName, Input: `{"city": "Lisbon", "temperature": 18}`},
+				}}},
+				{Response: Response{Content: ResponseContent{
+					ToolCallContent{ToolCallID: "2", ToolName: "weather", Input: `{"city": "Porto"}`},
+					ToolCallContent{ToolCallID: "3", ToolName: FinalAnswerToolName, Input: `{"city": "Porto", "temperature": 15`},
+				}}},
+			},
+			Response: Response{Content: ResponseContent{TextContent{Text: "Done."}}},
+		}
+
+		var got answer
+		require.NoError(t, result.DecodeFinal(&got))
+		require.Equal(t, answer{City: "Porto", Temperature: 15}, got)
+	})
+
+	t.Run("response text", func(t *testing.T) {
+		t.Parallel()
+		result := &AgentResult{
+			Response: Response{Content: ResponseContent{TextContent{Text: "```json\n{\"city\": \"Oslo\", \"temperature\": 4}\n```"}}},
+		}
+
+		var got answer
+		require.NoError(t, result.DecodeFinal(&got))
+		require.Equal(t, answer{City: "Oslo", Temperature: 4}, got)
+	})
+
+	t.Run("invalid output", func(t *testing.T) {
+		t.Parallel()
+		result := &AgentResult{
+			Response: Response{
+				Content:      ResponseContent{TextContent{Text: `{"city": "Oslo", "temperature": "cold"}`}},
+				FinishReason: FinishReasonStop,
+			},
+		}
+
+		var got answer
+		err := result.DecodeFinal(&got)
+		var noObject *NoObjectGeneratedError
+		require.ErrorAs(t, err, &noObject)
+		require.Error(t, noObject.ValidationError)
+		require.Equal(t, FinishReasonStop, noObject.FinishReason)
+
+		require.True(t, IsNoObjectGeneratedError((&AgentResult{}).DecodeFinal(&got)))
+		require.Error(t, result.DecodeFinal(got))
+	})
+}
+
+func TestAgent_Generate_SensitiveToolResult(t *testing.T) {
+	t.Parallel()
+
+	type TestInput struct {
+		Value string `json:"value" description:"Test value"`
+	}
+
+	tool1 := NewAgentTool(
+		"tool1",
+		"Test tool",
+		func(ctx context.Context, input TestInput, _ ToolCall) (ToolResponse, error) {
+			return SensitiveToolResult(NewTextResponse("ssn: 123-45-6789")), nil
+		},
+	)
+
+	var secondPrompt Prompt
+	callCount := 0
+	model := &mockLanguageModel{
+		generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+			callCount++
+			if callCount == 1 {
+				return &Response{
+					Content: []Content{
+						ToolCallContent{ToolCallID: "call-1", ToolName: "tool1", Input: `{"value":"test"}`},
+					},
+					FinishReason: FinishReasonToolCalls,
+				}, nil
+			}
+			secondPrompt = call.Prompt
+			return &Response{
+				Content:      []Content{TextContent{Text: "done"}},
+				FinishReason: FinishReasonStop,
+			}, nil
+		},
+	}
+
+	agent := NewAgent(model, WithTools(tool1))
+	result, err := agent.Generate(context.Background(), AgentCall{Prompt: "test-input"})
+	require.NoError(t, err)
+
+	toolResults := result.Steps[0].Content.ToolResults()
+	require.Len(t, toolResults, 1)
+	require.True(t, toolResults[0].Sensitive)
+
+	// The model still receives the result as is.
+	part, ok := AsMessagePart[ToolResultPart](secondPrompt[len(secondPrompt)-1].Content[0])
+	require.True(t, ok)
+	require.True(t, part.Sensitive)
+	require.Equal(t, ToolResultOutputContentText{Text: "ssn: 123-45-6789"}, part.Output)
+}
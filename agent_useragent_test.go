@@ -69,3 +69,28 @@ func TestAgent_NoUA_OmitsCallLevelFields(t *testing.T) {
 	require.NoError(t, err)
 	assert.Empty(t, capturedCall.UserAgent)
 }
+
+func TestAgent_WithExtraBody_MergesCallExtraBody(t *testing.T) {
+	t.Parallel()
+
+	var capturedCall Call
+	model := &mockLanguageModel{
+		generateFunc: func(_ context.Context, call Call) (*Response, error) {
+			capturedCall = call
+			return &Response{
+				Content:      []Content{TextContent{Text: "ok"}},
+				FinishReason: FinishReasonStop,
+			}, nil
+		},
+	}
+
+	defaults := map[string]any{"a": 1, "b": 1}
+	agent := NewAgent(model, WithExtraBody(defaults))
+	_, err := agent.Generate(context.Background(), AgentCall{
+		Prompt:    "hi",
+		ExtraBody: map[string]any{"b": 2},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"a": 1, "b": 2}, capturedCall.ExtraBody)
+	assert.Equal(t, map[string]any{"a": 1, "b": 1}, defaults)
+}
@@ -0,0 +1,220 @@
+package fantasy
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ReadArtifactToolName is the name of the tool added by WithArtifactStore.
+const ReadArtifactToolName = "read_artifact"
+
+const (
+	artifactPreviewChars   = 500
+	artifactDefaultLimit   = 200
+	artifactTextMediaType  = "text/plain"
+	readArtifactToolPrompt = "Read an artifact, a large tool output stored outside the conversation. " +
+		"Text artifacts are read in pages of lines; images and other media are returned whole."
+)
+
+// ErrArtifactNotFound is returned by an ArtifactStore when there is no
+// artifact with the requested ID.
+var ErrArtifactNotFound = errors.New("artifact not found")
+
+// Artifact is a tool output kept outside the conversation.
+type Artifact struct {
+	ToolCallID string `json:"tool_call_id"`
+	ToolName   string `json:"tool_name"`
+	// MediaType is "text/plain" for text outputs.
+	MediaType string `json:"media_type"`
+	Data      []byte `json:"data"`
+}
+
+// ArtifactStore stores large tool outputs so that the conversation only
+// holds a short reference to them. See WithArtifactStore.
+type ArtifactStore interface {
+	// Put stores the artifact and returns its ID.
+	Put(ctx context.Context, artifact Artifact) (string, error)
+	// Get returns the artifact with the ID, or ErrArtifactNotFound.
+	Get(ctx context.Context, id string) (Artifact, error)
+}
+
+// MemoryArtifactStore is an ArtifactStore that keeps artifacts in memory.
+// It is safe for concurrent use.
+type MemoryArtifactStore struct {
+	mu        sync.Mutex
+	artifacts map[string]Artifact
+}
+
+// NewMemoryArtifactStore creates an empty in-memory artifact store.
+func NewMemoryArtifactStore() *MemoryArtifactStore {
+	return &MemoryArtifactStore{artifacts: map[string]Artifact{}}
+}
+
+// Put implements ArtifactStore.
+func (s *MemoryArtifactStore) Put(_ context.Context, artifact Artifact) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := fmt.Sprintf("artifact-%d", len(s.artifacts)+1)
+	s.artifacts[id] = artifact
+	return id, nil
+}
+
+// Get implements ArtifactStore.
+func (s *MemoryArtifactStore) Get(_ context.Context, id string) (Artifact, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	artifact, ok := s.artifacts[id]
+	if !ok {
+		return Artifact{}, ErrArtifactNotFound
+	}
+	return artifact, nil
+}
+
+type artifactSettings struct {
+	store           ArtifactStore
+	thresholdTokens int64
+}
+
+// WithArtifactStore keeps tool outputs larger than about thresholdTokens
+// tokens out of the conversation. They are saved in the store and replaced
+// by a short reference with a preview, and the agent gets a read_artifact
+// tool so the model can read them on demand. As with WithToolResultLimit,
+// StepResult.Content and the OnToolResult callback still receive the full
+// output.
+func WithArtifactStore(store ArtifactStore, thresholdTokens int64) AgentOption {
+	return func(s *agentSettings) {
+		s.artifacts = &artifactSettings{store: store, thresholdTokens: thresholdTokens}
+		s.tools = append(s.tools, NewReadArtifactTool(store))
+	}
+}
+
+type readArtifactInput struct {
+	ID     string `json:"id" description:"The artifact ID"`
+	Offset int    `json:"offset,omitempty" description:"The first line to read, starting at 0"`
+	Limit  int    `json:"limit,omitempty" description:"The maximum number of lines to read, 200 by default"`
+}
+
+// NewReadArtifactTool creates the read_artifact tool for the store.
+// WithArtifactStore adds it to the agent automatically.
+func NewReadArtifactTool(store ArtifactStore) AgentTool {
+	return NewParallelAgentTool(ReadArtifactToolName, readArtifactToolPrompt,
+		func(ctx context.Context, input readArtifactInput, _ ToolCall) (ToolResponse, error) {
+			artifact, err := store.Get(ctx, input.ID)
+			if errors.Is(err, ErrArtifactNotFound) {
+				return NewTextErrorResponse(fmt.Sprintf("artifact %q not found", input.ID)), nil
+			}
+			if err != nil {
+				return ToolResponse{}, err
+			}
+			if artifact.MediaType != artifactTextMediaType {
+				if strings.HasPrefix(artifact.MediaType, "image/") {
+					return NewImageResponse(artifact.Data, artifact.MediaType), nil
+				}
+				return NewMediaResponse(artifact.Data, artifact.MediaType), nil
+			}
+
+			lines := strings.Split(string(artifact.Data), "\n")
+			offset := min(max(input.Offset, 0), len(lines))
+			limit := input.Limit
+			if limit <= 0 {
+				limit = artifactDefaultLimit
+			}
+			end := min(offset+limit, len(lines))
+			return NewTextResponse(fmt.Sprintf("Lines %d-%d of %d:\n%s",
+				offset, end, len(lines), strings.Join(lines[offset:end], "\n"))), nil
+		})
+}
+
+// storeArtifacts returns the messages with large tool outputs replaced by
+// references to artifacts saved in the agent's artifact store.
+func (a *agent) storeArtifacts(ctx context.Context, messages []Message) []Message {
+	settings := a.settings.artifacts
+	if settings == nil || settings.thresholdTokens <= 0 {
+		return messages
+	}
+	maxChars := int(settings.thresholdTokens * charsPerToken)
+
+	toolNames := map[string]string{}
+	result := make([]Message, len(messages))
+	for i, msg := range messages {
+		result[i] = msg
+		if msg.Role != MessageRoleTool {
+			for _, part := range msg.Content {
+				if call, ok := AsMessagePart[ToolCallPart](part); ok {
+					toolNames[call.ToolCallID] = call.ToolName
+				}
+			}
+			continue
+		}
+		result[i].Content = make([]MessagePart, len(msg.Content))
+		for j, part := range msg.Content {
+			result[i].Content[j] = part
+			toolResult, ok := AsMessagePart[ToolResultPart](part)
+			toolName := toolNames[toolResult.ToolCallID]
+			if !ok || toolResult.ProviderExecuted || toolName == ReadArtifactToolName {
+				continue
+			}
+			artifact, preview, ok := artifactFromOutput(toolResult.Output, maxChars)
+			if !ok {
+				continue
+			}
+			artifact.ToolCallID = toolResult.ToolCallID
+			artifact.ToolName = toolName
+			id, err := settings.store.Put(ctx, artifact)
+			if err != nil {
+				continue
+			}
+			toolResult.Output = ToolResultOutputContentText{Text: artifactReference(id, artifact, preview)}
+			result[i].Content[j] = toolResult
+		}
+	}
+	return result
+}
+
+// artifactFromOutput returns the artifact for a tool output larger than
+// maxChars, along with a preview of text outputs.
+func artifactFromOutput(output ToolResultOutputContent, maxChars int) (Artifact, string, bool) {
+	if output == nil {
+		return Artifact{}, "", false
+	}
+	switch output.GetType() {
+	case ToolResultContentTypeText:
+		text, _ := AsToolResultOutputType[ToolResultOutputContentText](output)
+		if len([]rune(text.Text)) <= maxChars {
+			return Artifact{}, "", false
+		}
+		preview := []rune(text.Text)[:min(artifactPreviewChars, maxChars)]
+		return Artifact{MediaType: artifactTextMediaType, Data: []byte(text.Text)}, string(preview), true
+	case ToolResultContentTypeMedia:
+		media, _ := AsToolResultOutputType[ToolResultOutputContentMedia](output)
+		if len(media.Data) <= maxChars {
+			return Artifact{}, "", false
+		}
+		data, err := base64.StdEncoding.DecodeString(media.Data)
+		if err != nil {
+			return Artifact{}, "", false
+		}
+		return Artifact{MediaType: media.MediaType, Data: data}, media.Text, true
+	}
+	return Artifact{}, "", false
+}
+
+func artifactReference(id string, artifact Artifact, preview string) string {
+	var sb strings.Builder
+	if artifact.MediaType == artifactTextMediaType {
+		text := string(artifact.Data)
+		fmt.Fprintf(&sb, "[The output was stored as artifact %q: %d lines, %d characters. It starts with:]\n%s\n",
+			id, strings.Count(text, "\n")+1, len([]rune(text)), preview)
+	} else {
+		fmt.Fprintf(&sb, "[The output was stored as artifact %q: %s, %d bytes.]\n", id, artifact.MediaType, len(artifact.Data))
+		if preview != "" {
+			sb.WriteString(preview + "\n")
+		}
+	}
+	fmt.Fprintf(&sb, "[Call %s with this ID to read it.]", ReadArtifactToolName)
+	return sb.String()
+}
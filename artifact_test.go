@@ -0,0 +1,94 @@
+package fantasy
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithArtifactStore(t *testing.T) {
+	t.Parallel()
+
+	lines := make([]string, 50)
+	for i := range lines {
+		lines[i] = strings.Repeat("x", 10)
+	}
+	output := strings.Join(lines, "\n")
+	dump := &mockTool{
+		name: "dump",
+		executeFunc: func(ctx context.Context, call ToolCall) (ToolResponse, error) {
+			return NewTextResponse(output), nil
+		},
+	}
+
+	store := NewMemoryArtifactStore()
+	var reference, read string
+	model := &mockLanguageModel{
+		generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+			last := call.Prompt[len(call.Prompt)-1]
+			switch len(call.Prompt) {
+			case 1:
+				return &Response{
+					Content:      []Content{ToolCallContent{ToolCallID: "call-1", ToolName: "dump", Input: `{}`}},
+					FinishReason: FinishReasonToolCalls,
+				}, nil
+			case 3:
+				result, _ := AsMessagePart[ToolResultPart](last.Content[0])
+				text, _ := AsToolResultOutputType[ToolResultOutputContentText](result.Output)
+				reference = text.Text
+				input, _ := json.Marshal(readArtifactInput{ID: "artifact-1", Offset: 48})
+				return &Response{
+					Content:      []Content{ToolCallContent{ToolCallID: "call-2", ToolName: ReadArtifactToolName, Input: string(input)}},
+					FinishReason: FinishReasonToolCalls,
+				}, nil
+			}
+			result, _ := AsMessagePart[ToolResultPart](last.Content[0])
+			text, _ := AsToolResultOutputType[ToolResultOutputContentText](result.Output)
+			read = text.Text
+			return &Response{Content: []Content{TextContent{Text: "done"}}, FinishReason: FinishReasonStop}, nil
+		},
+	}
+
+	agent := NewAgent(model, WithTools(dump), WithArtifactStore(store, 25))
+	result, err := agent.Generate(t.Context(), AgentCall{Prompt: "dump it"})
+	require.NoError(t, err)
+	require.Len(t, result.Steps, 3)
+
+	require.Contains(t, reference, `artifact "artifact-1": 50 lines, 549 characters`)
+	require.Contains(t, reference, ReadArtifactToolName)
+	require.Less(t, len(reference), len(output))
+	require.Equal(t, "Lines 48-50 of 50:\nxxxxxxxxxx\nxxxxxxxxxx", read)
+
+	artifact, err := store.Get(t.Context(), "artifact-1")
+	require.NoError(t, err)
+	require.Equal(t, "dump", artifact.ToolName)
+	require.Equal(t, "call-1", artifact.ToolCallID)
+	require.Equal(t, output, string(artifact.Data))
+
+	// The full output is kept in the step content.
+	toolResults := result.Steps[0].Content.ToolResults()
+	full, ok := AsToolResultOutputType[ToolResultOutputContentText](toolResults[0].Result)
+	require.True(t, ok)
+	require.Equal(t, output, full.Text)
+}
+
+func TestReadArtifactTool(t *testing.T) {
+	t.Parallel()
+
+	store := NewMemoryArtifactStore()
+	id, err := store.Put(t.Context(), Artifact{MediaType: "image/png", Data: []byte{1, 2, 3}})
+	require.NoError(t, err)
+
+	tool := NewReadArtifactTool(store)
+	resp, err := tool.Run(t.Context(), ToolCall{ID: "1", Name: ReadArtifactToolName, Input: `{"id":"` + id + `"}`})
+	require.NoError(t, err)
+	require.Equal(t, "image", resp.Type)
+	require.Equal(t, []byte{1, 2, 3}, resp.Data)
+
+	resp, err = tool.Run(t.Context(), ToolCall{ID: "2", Name: ReadArtifactToolName, Input: `{"id":"missing"}`})
+	require.NoError(t, err)
+	require.True(t, resp.IsError)
+}
@@ -0,0 +1,223 @@
+package fantasy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// AuditEventType is the kind of action an AuditEvent records.
+type AuditEventType string
+
+const (
+	// AuditEventModelCall records a call to a language model.
+	AuditEventModelCall AuditEventType = "model_call"
+	// AuditEventToolCall records a tool call the model asked for.
+	AuditEventToolCall AuditEventType = "tool_call"
+)
+
+// AuditDecision is the outcome of an audited action.
+type AuditDecision string
+
+const (
+	// AuditDecisionAllowed means the action ran and succeeded.
+	AuditDecisionAllowed AuditDecision = "allowed"
+	// AuditDecisionBlocked means the action was refused before it ran, e.g.
+	// by a spend limit, or because the tool call was invalid.
+	AuditDecisionBlocked AuditDecision = "blocked"
+	// AuditDecisionFailed means the action ran and failed.
+	AuditDecisionFailed AuditDecision = "failed"
+)
+
+// AuditEvent records one model call or tool execution of an agent.
+type AuditEvent struct {
+	Time time.Time      `json:"time"`
+	Type AuditEventType `json:"type"`
+
+	// TenantID and Metadata identify who the action was made for; see
+	// WithTenantID and WithCallMetadata.
+	TenantID string            `json:"tenant_id,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// Provider and Model are set for model calls.
+	Provider string `json:"provider,omitempty"`
+	Model    string `json:"model,omitempty"`
+
+	// ToolName and ToolCallID are set for tool calls.
+	ToolName   string `json:"tool_name,omitempty"`
+	ToolCallID string `json:"tool_call_id,omitempty"`
+
+	// Usage, Cost and FinishReason are set for model calls. Cost is only
+	// calculated with WithCostFunction.
+	Usage        Usage        `json:"usage,omitzero"`
+	Cost         float64      `json:"cost,omitempty"`
+	FinishReason FinishReason `json:"finish_reason,omitempty"`
+
+	Duration time.Duration `json:"duration"`
+	Decision AuditDecision `json:"decision"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// AuditSink receives the audit events of agents. Record is called
+// synchronously, once per event; an error fails the agent call, so that no
+// action goes unrecorded. Sinks may be called concurrently.
+type AuditSink interface {
+	Record(ctx context.Context, event AuditEvent) error
+}
+
+// AuditSinkFunc is a function that implements AuditSink.
+type AuditSinkFunc func(ctx context.Context, event AuditEvent) error
+
+// Record implements AuditSink.
+func (f AuditSinkFunc) Record(ctx context.Context, event AuditEvent) error {
+	return f(ctx, event)
+}
+
+// NewJSONLAuditSink returns an AuditSink appending each event to w as a line
+// of JSON, for append-only files and log shippers.
+func NewJSONLAuditSink(w io.Writer) AuditSink {
+	return &jsonlAuditSink{w: w}
+}
+
+type jsonlAuditSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// Record implements AuditSink.
+func (s *jsonlAuditSink) Record(_ context.Context, event AuditEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(data, '\n'))
+	return err
+}
+
+// WithAuditSink makes the agent record an AuditEvent in the sink for every
+// model call and tool execution.
+func WithAuditSink(sink AuditSink) AgentOption {
+	return func(s *agentSettings) {
+		s.auditSink = sink
+	}
+}
+
+// audit fills in who the event is for and records it.
+func (a *agent) audit(ctx context.Context, event AuditEvent) error {
+	if a.settings.auditSink == nil {
+		return nil
+	}
+	event.Time = time.Now()
+	event.TenantID, _ = TenantID(ctx)
+	event.Metadata = CallMetadata(ctx)
+	if err := a.settings.auditSink.Record(ctx, event); err != nil {
+		return fmt.Errorf("recording audit event: %w", err)
+	}
+	return nil
+}
+
+// auditModelCall records a model call that started at start.
+func (a *agent) auditModelCall(ctx context.Context, model LanguageModel, start time.Time, usage Usage, finishReason FinishReason, err error) error {
+	if a.settings.auditSink == nil {
+		return nil
+	}
+	event := AuditEvent{
+		Type:         AuditEventModelCall,
+		Provider:     model.Provider(),
+		Model:        model.Model(),
+		Usage:        usage,
+		FinishReason: finishReason,
+		Duration:     time.Since(start),
+		Decision:     AuditDecisionAllowed,
+	}
+	if a.settings.costFunction != nil {
+		event.Cost = a.settings.costFunction(model, usage)
+	}
+	if err != nil {
+		event.Error = err.Error()
+		event.Decision = AuditDecisionFailed
+		var limitErr *SpendLimitError
+		if errors.As(err, &limitErr) {
+			event.Decision = AuditDecisionBlocked
+		}
+	}
+	return a.audit(ctx, event)
+}
+
+// auditStream records the stream's model call when it finishes, fails or
+// is abandoned.
+func (a *agent) auditStream(ctx context.Context, model LanguageModel, start time.Time, stream StreamResponse) StreamResponse {
+	if a.settings.auditSink == nil {
+		return stream
+	}
+	return func(yield func(StreamPart) bool) {
+		var (
+			usage        Usage
+			finishReason FinishReason
+			streamErr    error
+			audited      bool
+		)
+		auditOnce := func() error {
+			if audited {
+				return nil
+			}
+			audited = true
+			return a.auditModelCall(ctx, model, start, usage, finishReason, streamErr)
+		}
+		for part := range stream {
+			switch part.Type {
+			case StreamPartTypeFinish:
+				usage, finishReason = part.Usage, part.FinishReason
+			case StreamPartTypeError:
+				streamErr = part.Error
+			}
+			if part.Type == StreamPartTypeFinish || part.Type == StreamPartTypeError {
+				if err := auditOnce(); err != nil {
+					yield(StreamPart{Type: StreamPartTypeError, Error: err})
+					return
+				}
+			}
+			if !yield(part) {
+				// The consumer can't be told about a failure anymore.
+				_ = auditOnce()
+				return
+			}
+		}
+		if err := auditOnce(); err != nil {
+			yield(StreamPart{Type: StreamPartTypeError, Error: err})
+		}
+	}
+}
+
+// auditToolCall records the execution of a tool call. ran is false when the
+// call was refused without running the tool.
+func (a *agent) auditToolCall(ctx context.Context, result ToolResultContent, ran bool) error {
+	if a.settings.auditSink == nil {
+		return nil
+	}
+	event := AuditEvent{
+		Type:       AuditEventToolCall,
+		ToolName:   result.ToolName,
+		ToolCallID: result.ToolCallID,
+		Duration:   result.Duration,
+		Decision:   AuditDecisionAllowed,
+	}
+	if !ran {
+		event.Decision = AuditDecisionBlocked
+	}
+	if errResult, ok := result.Result.(ToolResultOutputContentError); ok {
+		if errResult.Error != nil {
+			event.Error = errResult.Error.Error()
+		}
+		if ran {
+			event.Decision = AuditDecisionFailed
+		}
+	}
+	return a.audit(ctx, event)
+}
@@ -0,0 +1,172 @@
+package fantasy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// toolCallingModel asks for the tool calls in its first response and
+// answers in the second.
+func toolCallingModel(toolCalls ...ToolCallContent) *mockLanguageModel {
+	var calls int
+	return &mockLanguageModel{
+		generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+			calls++
+			if calls == 1 {
+				content := make([]Content, len(toolCalls))
+				for i, toolCall := range toolCalls {
+					content[i] = toolCall
+				}
+				return &Response{Content: content, FinishReason: FinishReasonToolCalls, Usage: Usage{InputTokens: 10, OutputTokens: 5}}, nil
+			}
+			return &Response{Content: []Content{TextContent{Text: "done"}}, FinishReason: FinishReasonStop, Usage: Usage{InputTokens: 20, OutputTokens: 2}}, nil
+		},
+	}
+}
+
+func TestWithAuditSink(t *testing.T) {
+	t.Parallel()
+
+	model := toolCallingModel(
+		ToolCallContent{ToolCallID: "call-1", ToolName: "lookup", Input: `{}`},
+		ToolCallContent{ToolCallID: "call-2", ToolName: "missing", Input: `{}`},
+	)
+	tool := &mockTool{
+		name: "lookup",
+		executeFunc: func(ctx context.Context, call ToolCall) (ToolResponse, error) {
+			return NewTextResponse("found"), nil
+		},
+	}
+	var buf bytes.Buffer
+	agent := NewAgent(model,
+		WithTools(tool),
+		WithAuditSink(NewJSONLAuditSink(&buf)),
+		WithCostFunction(PricingTable(map[string]Pricing{"mock-model": {Input: 1e6, Output: 1e6}})),
+	)
+	ctx := WithCallMetadata(WithTenantID(t.Context(), "acme"), map[string]string{MetadataUserID: "user-1"})
+	_, err := agent.Generate(ctx, AgentCall{Prompt: "hello"})
+	require.NoError(t, err)
+
+	var events []AuditEvent
+	for line := range strings.Lines(buf.String()) {
+		var event AuditEvent
+		require.NoError(t, json.Unmarshal([]byte(line), &event))
+		require.Equal(t, "acme", event.TenantID)
+		require.Equal(t, "user-1", event.Metadata[MetadataUserID])
+		require.False(t, event.Time.IsZero())
+		events = append(events, event)
+	}
+	require.Len(t, events, 4)
+
+	require.Equal(t, AuditEventModelCall, events[0].Type)
+	require.Equal(t, "mock-provider", events[0].Provider)
+	require.Equal(t, "mock-model", events[0].Model)
+	require.Equal(t, AuditDecisionAllowed, events[0].Decision)
+	require.Equal(t, FinishReasonToolCalls, events[0].FinishReason)
+	require.Equal(t, int64(10), events[0].Usage.InputTokens)
+	require.Equal(t, 15.0, events[0].Cost)
+
+	require.Equal(t, AuditEventToolCall, events[1].Type)
+	require.Equal(t, "lookup", events[1].ToolName)
+	require.Equal(t, "call-1", events[1].ToolCallID)
+	require.Equal(t, AuditDecisionAllowed, events[1].Decision)
+
+	require.Equal(t, "missing", events[2].ToolName)
+	require.Equal(t, AuditDecisionBlocked, events[2].Decision)
+	require.Contains(t, events[2].Error, "tool not found")
+
+	require.Equal(t, AuditEventModelCall, events[3].Type)
+	require.Equal(t, 22.0, events[3].Cost)
+}
+
+func TestWithAuditSink_Decisions(t *testing.T) {
+	t.Parallel()
+
+	t.Run("failed tool", func(t *testing.T) {
+		t.Parallel()
+		var events []AuditEvent
+		agent := NewAgent(
+			toolCallingModel(ToolCallContent{ToolCallID: "call-1", ToolName: "lookup", Input: `{}`}),
+			WithTools(&mockTool{
+				name: "lookup",
+				executeFunc: func(ctx context.Context, call ToolCall) (ToolResponse, error) {
+					return NewTextErrorResponse("not found"), nil
+				},
+			}),
+			WithAuditSink(AuditSinkFunc(func(ctx context.Context, event AuditEvent) error {
+				events = append(events, event)
+				return nil
+			})),
+		)
+		_, err := agent.Generate(t.Context(), AgentCall{Prompt: "hello"})
+		require.NoError(t, err)
+		require.Equal(t, AuditDecisionFailed, events[1].Decision)
+		require.Equal(t, "not found", events[1].Error)
+	})
+
+	t.Run("blocked model call", func(t *testing.T) {
+		t.Parallel()
+		var events []AuditEvent
+		store := NewMemorySpendStore()
+		_, err := store.AddSpend(t.Context(), "", time.Time{}, 1)
+		require.NoError(t, err)
+		agent := NewAgent(
+			toolCallingModel(),
+			WithSpendLimit(1, 0, nil),
+			WithSpendStore(store),
+			WithCostFunction(PricingTable(nil)),
+			WithAuditSink(AuditSinkFunc(func(ctx context.Context, event AuditEvent) error {
+				events = append(events, event)
+				return nil
+			})),
+		)
+		_, err = agent.Generate(t.Context(), AgentCall{Prompt: "hello"})
+		require.Error(t, err)
+		require.Len(t, events, 1)
+		require.Equal(t, AuditDecisionBlocked, events[0].Decision)
+	})
+
+	t.Run("sink error fails the call", func(t *testing.T) {
+		t.Parallel()
+		agent := NewAgent(toolCallingModel(),
+			WithAuditSink(AuditSinkFunc(func(ctx context.Context, event AuditEvent) error {
+				return errors.New("disk full")
+			})),
+		)
+		_, err := agent.Generate(t.Context(), AgentCall{Prompt: "hello"})
+		require.ErrorContains(t, err, "recording audit event: disk full")
+	})
+}
+
+func TestWithAuditSink_Stream(t *testing.T) {
+	t.Parallel()
+
+	model := &mockLanguageModel{
+		streamFunc: func(ctx context.Context, call Call) (StreamResponse, error) {
+			return func(yield func(StreamPart) bool) {
+				_ = yield(StreamPart{Type: StreamPartTypeTextStart, ID: "1"}) &&
+					yield(StreamPart{Type: StreamPartTypeTextDelta, ID: "1", Delta: "done"}) &&
+					yield(StreamPart{Type: StreamPartTypeTextEnd, ID: "1"}) &&
+					yield(StreamPart{Type: StreamPartTypeFinish, FinishReason: FinishReasonStop, Usage: Usage{OutputTokens: 7}})
+			}, nil
+		},
+	}
+	var events []AuditEvent
+	agent := NewAgent(model, WithAuditSink(AuditSinkFunc(func(ctx context.Context, event AuditEvent) error {
+		events = append(events, event)
+		return nil
+	})))
+	_, err := agent.Stream(t.Context(), AgentStreamCall{Prompt: "hello"})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, AuditEventModelCall, events[0].Type)
+	require.Equal(t, FinishReasonStop, events[0].FinishReason)
+	require.Equal(t, int64(7), events[0].Usage.OutputTokens)
+}
@@ -0,0 +1,340 @@
+package fantasy
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	continueTextPrompt      = "Your previous response was cut off. Continue exactly where it stopped, without repeating anything."
+	continueToolInputPrompt = "Your previous response was cut off while writing the JSON input of a call to the %s tool. Reply with only the rest of the JSON, starting exactly where it stopped. The input so far is:\n\n%s"
+)
+
+// WithAutoContinue makes the agent continue responses that stop because
+// they hit the output token limit, up to maxContinuations times per step.
+// Each continuation is a new request that sends the partial response back:
+// as a prefill if the model implements PrefillModel, otherwise followed by
+// a user message asking the model to go on. A tool call cut off in the
+// middle of its JSON input is completed by asking for the rest of the
+// JSON. The pieces are stitched together, so the step result and the
+// stream callbacks see one response with the summed usage.
+func WithAutoContinue(maxContinuations int) AgentOption {
+	return func(s *agentSettings) {
+		s.maxContinuations = maxContinuations
+	}
+}
+
+func (a *agent) generate(ctx context.Context, model LanguageModel, call Call) (*Response, error) {
+	resp, err := a.generateChoices(ctx, model, call)
+	fellBack := fallBackFromFlex(&call, err)
+	if fellBack {
+		resp, err = a.generateChoices(ctx, model, call)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if fellBack {
+		resp.Warnings = append(resp.Warnings, flexFallbackWarning)
+	}
+	for range a.settings.maxContinuations {
+		if resp.FinishReason != FinishReasonLength {
+			break
+		}
+		text, truncated, ok := truncatedContent(resp.Content)
+		if !ok {
+			break
+		}
+		next, err := a.safeGenerate(ctx, model, continuationCall(model, call, text, truncated))
+		if err != nil {
+			return nil, err
+		}
+		resp = mergeContinuation(resp, next, truncated != nil)
+	}
+	return resp, nil
+}
+
+// truncatedContent returns the text of the response and, if the response
+// ends with a tool call whose input isn't valid JSON, that tool call. It
+// reports false if the response doesn't end in text or such a tool call,
+// so there is nothing to continue.
+func truncatedContent(content ResponseContent) (string, *ToolCallContent, bool) {
+	var text strings.Builder
+	for _, c := range content {
+		if tc, ok := AsContentType[TextContent](c); ok {
+			text.WriteString(tc.Text)
+		}
+	}
+	if len(content) == 0 {
+		return "", nil, false
+	}
+	last := content[len(content)-1]
+	if toolCall, ok := AsContentType[ToolCallContent](last); ok && !json.Valid([]byte(toolCall.Input)) {
+		return text.String(), &toolCall, true
+	}
+	return text.String(), nil, last.GetType() == ContentTypeText
+}
+
+// continuationCall returns the call that continues the partial response
+// made of text and, if it was cut off in a tool call, that tool call.
+func continuationCall(model LanguageModel, call Call, text string, truncated *ToolCallContent) Call {
+	prompt := slices.Clone(call.Prompt)
+	prefill := truncated == nil && supportsPrefill(model)
+	if prefill {
+		text = strings.TrimRight(text, " \t\r\n")
+	}
+	if text != "" {
+		prompt = append(prompt, Message{
+			Role:    MessageRoleAssistant,
+			Content: []MessagePart{TextPart{Text: text}},
+		})
+	}
+	switch {
+	case truncated != nil:
+		prompt = append(prompt, NewUserMessage(fmt.Sprintf(continueToolInputPrompt, truncated.ToolName, truncated.Input)))
+		call.Tools = nil
+		call.ToolChoice = nil
+	case !prefill || text == "":
+		prompt = append(prompt, NewUserMessage(continueTextPrompt))
+	}
+	call.Prompt = prompt
+	call.NumChoices = nil
+	return call
+}
+
+// mergeContinuation appends the continuation to the response. If the
+// response was cut off in a tool call, the continuation's text completes
+// that tool call's input.
+func mergeContinuation(resp, next *Response, toolInput bool) *Response {
+	merged := *resp
+	merged.Content = slices.Clone(resp.Content)
+	merged.FinishReason = next.FinishReason
+	merged.Usage = addUsage(resp.Usage, next.Usage)
+	merged.Warnings = append(slices.Clone(resp.Warnings), next.Warnings...)
+	if next.ProviderMetadata != nil {
+		merged.ProviderMetadata = next.ProviderMetadata
+	}
+
+	if toolInput {
+		last := len(merged.Content) - 1
+		toolCall, _ := AsContentType[ToolCallContent](merged.Content[last])
+		toolCall.Input += continuedToolInput(next.Content.Text())
+		merged.Content[last] = toolCall
+		if json.Valid([]byte(toolCall.Input)) {
+			merged.FinishReason = FinishReasonToolCalls
+		}
+		return &merged
+	}
+
+	for i, c := range next.Content {
+		text, ok := AsContentType[TextContent](c)
+		if i == 0 && ok && len(merged.Content) > 0 {
+			if prev, ok := AsContentType[TextContent](merged.Content[len(merged.Content)-1]); ok {
+				prev.Text += text.Text
+				merged.Content[len(merged.Content)-1] = prev
+				continue
+			}
+		}
+		merged.Content = append(merged.Content, c)
+	}
+	return &merged
+}
+
+// continuedToolInput strips the code fence models tend to put around the
+// rest of the JSON.
+func continuedToolInput(text string) string {
+	trimmed := strings.TrimSpace(text)
+	if !strings.HasPrefix(trimmed, "```") {
+		return text
+	}
+	_, trimmed, _ = strings.Cut(trimmed, "\n")
+	return strings.TrimRight(strings.TrimSuffix(trimmed, "```"), " \t\r\n")
+}
+
+func (a *agent) stream(ctx context.Context, model LanguageModel, call Call) (StreamResponse, error) {
+	stream, err := a.idleStream(ctx, model, call)
+	if err != nil || a.settings.maxContinuations <= 0 {
+		return stream, err
+	}
+
+	return func(yield func(StreamPart) bool) {
+		var (
+			usage Usage
+			text  strings.Builder
+			// held are the parts closing a block, which are only passed on
+			// once it's clear the block isn't continued.
+			held      []StreamPart
+			lastText  string
+			lastTool  string
+			toolNames = map[string]string{}
+			toolInput = map[string]*strings.Builder{}
+			// continueID is the ID of the text block the continuation
+			// continues, and remap maps the continuation's IDs for it.
+			continueID string
+			remap      = map[string]string{}
+			// truncated is the tool call whose input the continuation
+			// completes, with the rest of the input collected in rest.
+			truncated *ToolCallContent
+			rest      strings.Builder
+		)
+		flush := func() bool {
+			for _, part := range held {
+				if !yield(part) {
+					return false
+				}
+			}
+			held = held[:0]
+			return true
+		}
+
+		for continuations := 0; ; continuations++ {
+			var finish *StreamPart
+			for part := range stream {
+				if part.Type == StreamPartTypeFinish {
+					finish = &part
+					continue
+				}
+				if truncated != nil {
+					switch part.Type {
+					case StreamPartTypeTextDelta:
+						rest.WriteString(part.Delta)
+					case StreamPartTypeWarnings, StreamPartTypeError:
+						if !yield(part) {
+							return
+						}
+					}
+					continue
+				}
+				if continueID != "" && (part.Type == StreamPartTypeTextStart || part.Type == StreamPartTypeTextDelta) {
+					remap[part.ID] = continueID
+					continueID = ""
+					if part.Type == StreamPartTypeTextStart {
+						continue
+					}
+				}
+				if id, ok := remap[part.ID]; ok {
+					part.ID = id
+				}
+				switch part.Type {
+				case StreamPartTypeTextEnd, StreamPartTypeToolInputEnd, StreamPartTypeToolCall:
+					if part.Type == StreamPartTypeToolCall {
+						toolNames[part.ID] = part.ToolCallName
+						lastTool, lastText = part.ID, ""
+					}
+					held = append(held, part)
+					continue
+				case StreamPartTypeTextStart:
+					lastText, lastTool = part.ID, ""
+				case StreamPartTypeTextDelta:
+					lastText, lastTool = part.ID, ""
+					text.WriteString(part.Delta)
+				case StreamPartTypeToolInputStart:
+					lastTool, lastText = part.ID, ""
+					toolNames[part.ID] = part.ToolCallName
+					toolInput[part.ID] = &strings.Builder{}
+				case StreamPartTypeToolInputDelta:
+					if input, ok := toolInput[part.ID]; ok {
+						input.WriteString(part.Delta)
+					}
+				case StreamPartTypeReasoningStart, StreamPartTypeSource:
+					lastText, lastTool = "", ""
+				}
+				if !flush() || !yield(part) {
+					return
+				}
+			}
+			if continueID != "" {
+				// The continuation had no text, so the block ends here.
+				held = append([]StreamPart{{Type: StreamPartTypeTextEnd, ID: continueID}}, held...)
+				continueID = ""
+			}
+			if finish == nil {
+				flush()
+				return
+			}
+			usage = addUsage(usage, finish.Usage)
+
+			if truncated != nil {
+				delta := continuedToolInput(rest.String())
+				truncated.Input += delta
+				rest.Reset()
+				if finish.FinishReason == FinishReasonLength && !json.Valid([]byte(truncated.Input)) && continuations < a.settings.maxContinuations {
+					if !yield(StreamPart{Type: StreamPartTypeToolInputDelta, ID: truncated.ToolCallID, Delta: delta}) {
+						return
+					}
+					stream, err = a.idleStream(ctx, model, continuationCall(model, call, text.String(), truncated))
+					if err != nil {
+						yield(StreamPart{Type: StreamPartTypeError, Error: err})
+						return
+					}
+					continue
+				}
+				if json.Valid([]byte(truncated.Input)) {
+					finish.FinishReason = FinishReasonToolCalls
+				}
+				for _, part := range []StreamPart{
+					{Type: StreamPartTypeToolInputDelta, ID: truncated.ToolCallID, Delta: delta},
+					{Type: StreamPartTypeToolInputEnd, ID: truncated.ToolCallID},
+					{Type: StreamPartTypeToolCall, ID: truncated.ToolCallID, ToolCallName: truncated.ToolName, ToolCallInput: truncated.Input},
+				} {
+					if !yield(part) {
+						return
+					}
+				}
+				truncated = nil
+			} else if finish.FinishReason == FinishReasonLength && continuations < a.settings.maxContinuations {
+				truncated = truncatedToolCall(held, lastTool, toolNames, toolInput)
+				if truncated != nil {
+					held = slices.DeleteFunc(held, func(part StreamPart) bool { return part.ID == lastTool })
+				} else if lastText != "" {
+					held = slices.DeleteFunc(held, func(part StreamPart) bool {
+						return part.Type == StreamPartTypeTextEnd && part.ID == lastText
+					})
+					continueID = lastText
+				}
+				if truncated != nil || continueID != "" {
+					if !flush() {
+						return
+					}
+					clear(remap)
+					stream, err = a.idleStream(ctx, model, continuationCall(model, call, text.String(), truncated))
+					if err != nil {
+						yield(StreamPart{Type: StreamPartTypeError, Error: err})
+						return
+					}
+					continue
+				}
+			}
+
+			if !flush() {
+				return
+			}
+			finish.Usage = usage
+			yield(*finish)
+			return
+		}
+	}, nil
+}
+
+// truncatedToolCall returns the last tool call of the stream if its input
+// was cut off: it either never completed, or its input isn't valid JSON.
+func truncatedToolCall(held []StreamPart, id string, names map[string]string, inputs map[string]*strings.Builder) *ToolCallContent {
+	if id == "" {
+		return nil
+	}
+	toolCall := &ToolCallContent{ToolCallID: id, ToolName: names[id]}
+	if input, ok := inputs[id]; ok {
+		toolCall.Input = input.String()
+	}
+	for _, part := range held {
+		if part.Type == StreamPartTypeToolCall && part.ID == id {
+			toolCall.Input = part.ToolCallInput
+		}
+	}
+	if json.Valid([]byte(toolCall.Input)) {
+		return nil
+	}
+	return toolCall
+}
@@ -0,0 +1,231 @@
+package fantasy
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func partsStream(parts ...StreamPart) StreamResponse {
+	return func(yield func(StreamPart) bool) {
+		for _, part := range parts {
+			if !yield(part) {
+				return
+			}
+		}
+	}
+}
+
+func TestAutoContinue(t *testing.T) {
+	t.Parallel()
+
+	t.Run("generate continues text", func(t *testing.T) {
+		t.Parallel()
+		var calls []Call
+		model := &mockLanguageModel{
+			generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+				calls = append(calls, call)
+				if len(calls) == 1 {
+					return &Response{
+						Content:      ResponseContent{TextContent{Text: "Once upon "}},
+						FinishReason: FinishReasonLength,
+						Usage:        Usage{OutputTokens: 10},
+					}, nil
+				}
+				return &Response{
+					Content:      ResponseContent{TextContent{Text: "a time."}},
+					FinishReason: FinishReasonStop,
+					Usage:        Usage{OutputTokens: 3},
+				}, nil
+			},
+		}
+		result, err := NewAgent(model, WithAutoContinue(2)).Generate(t.Context(), AgentCall{Prompt: "tell a story"})
+		require.NoError(t, err)
+		require.Len(t, calls, 2)
+		require.Equal(t, "Once upon a time.", result.Response.Content.Text())
+		require.Len(t, result.Response.Content, 1)
+		require.Equal(t, FinishReasonStop, result.Response.FinishReason)
+		require.Equal(t, int64(13), result.Response.Usage.OutputTokens)
+
+		prompt := calls[1].Prompt
+		require.Equal(t, Message{Role: MessageRoleAssistant, Content: []MessagePart{TextPart{Text: "Once upon "}}}, prompt[len(prompt)-2])
+		require.Equal(t, NewUserMessage(continueTextPrompt), prompt[len(prompt)-1])
+	})
+
+	t.Run("generate stops at the limit", func(t *testing.T) {
+		t.Parallel()
+		var calls atomic.Int32
+		model := &mockLanguageModel{
+			generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+				calls.Add(1)
+				return &Response{
+					Content:      ResponseContent{TextContent{Text: "more "}},
+					FinishReason: FinishReasonLength,
+				}, nil
+			},
+		}
+		result, err := NewAgent(model, WithAutoContinue(2)).Generate(t.Context(), AgentCall{Prompt: "hi"})
+		require.NoError(t, err)
+		require.Equal(t, int32(3), calls.Load())
+		require.Equal(t, "more more more ", result.Response.Content.Text())
+		require.Equal(t, FinishReasonLength, result.Response.FinishReason)
+	})
+
+	t.Run("generate completes tool input", func(t *testing.T) {
+		t.Parallel()
+		var calls []Call
+		var input string
+		tool := &mockTool{
+			name: "write_file",
+			executeFunc: func(ctx context.Context, call ToolCall) (ToolResponse, error) {
+				input = call.Input
+				return NewTextResponse("ok"), nil
+			},
+		}
+		model := &mockLanguageModel{
+			generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+				calls = append(calls, call)
+				switch len(calls) {
+				case 1:
+					return &Response{
+						Content: ResponseContent{ToolCallContent{
+							ToolCallID: "call-1",
+							ToolName:   "write_file",
+							Input:      `{"path":"a.txt","content":"hel`,
+						}},
+						FinishReason: FinishReasonLength,
+					}, nil
+				case 2:
+					return &Response{
+						Content:      ResponseContent{TextContent{Text: "```json\nlo\"}\n```"}},
+						FinishReason: FinishReasonStop,
+					}, nil
+				}
+				return &Response{Content: ResponseContent{TextContent{Text: "done"}}, FinishReason: FinishReasonStop}, nil
+			},
+		}
+		result, err := NewAgent(model, WithTools(tool), WithAutoContinue(1)).Generate(t.Context(), AgentCall{Prompt: "write"})
+		require.NoError(t, err)
+		require.Equal(t, `{"path":"a.txt","content":"hello"}`, input)
+		require.Equal(t, "done", result.Response.Content.Text())
+		require.Empty(t, calls[1].Tools)
+		require.Contains(t, calls[1].Prompt[len(calls[1].Prompt)-1].Content[0].(TextPart).Text, `{"path":"a.txt","content":"hel`)
+	})
+
+	t.Run("stream stitches text with prefill", func(t *testing.T) {
+		t.Parallel()
+		var calls []Call
+		model := prefillMockModel{&mockLanguageModel{
+			streamFunc: func(ctx context.Context, call Call) (StreamResponse, error) {
+				calls = append(calls, call)
+				if len(calls) == 1 {
+					return partsStream(
+						StreamPart{Type: StreamPartTypeTextStart, ID: "a"},
+						StreamPart{Type: StreamPartTypeTextDelta, ID: "a", Delta: "Hello, "},
+						StreamPart{Type: StreamPartTypeTextEnd, ID: "a"},
+						StreamPart{Type: StreamPartTypeFinish, FinishReason: FinishReasonLength, Usage: Usage{OutputTokens: 2}},
+					), nil
+				}
+				return partsStream(
+					StreamPart{Type: StreamPartTypeTextStart, ID: "b"},
+					StreamPart{Type: StreamPartTypeTextDelta, ID: "b", Delta: "world!"},
+					StreamPart{Type: StreamPartTypeTextEnd, ID: "b"},
+					StreamPart{Type: StreamPartTypeFinish, FinishReason: FinishReasonStop, Usage: Usage{OutputTokens: 1}},
+				), nil
+			},
+		}}
+
+		var events []string
+		var finishes int
+		result, err := NewAgent(model, WithAutoContinue(1)).Stream(t.Context(), AgentStreamCall{
+			Prompt: "hi",
+			OnTextStart: func(id string) error {
+				events = append(events, "start "+id)
+				return nil
+			},
+			OnTextDelta: func(id, text string) error {
+				events = append(events, "delta "+id+" "+text)
+				return nil
+			},
+			OnTextEnd: func(id string) error {
+				events = append(events, "end "+id)
+				return nil
+			},
+			OnStreamFinish: func(usage Usage, reason FinishReason, _ ProviderMetadata) error {
+				finishes++
+				require.Equal(t, int64(3), usage.OutputTokens)
+				require.Equal(t, FinishReasonStop, reason)
+				return nil
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{"start a", "delta a Hello, ", "delta a world!", "end a"}, events)
+		require.Equal(t, 1, finishes)
+		require.Equal(t, "Hello, world!", result.Response.Content.Text())
+
+		last := calls[1].Prompt[len(calls[1].Prompt)-1]
+		require.Equal(t, Message{Role: MessageRoleAssistant, Content: []MessagePart{TextPart{Text: "Hello,"}}}, last)
+	})
+
+	t.Run("stream completes tool input", func(t *testing.T) {
+		t.Parallel()
+		var (
+			mu    sync.Mutex
+			calls int
+			input string
+		)
+		tool := &mockTool{
+			name: "lookup",
+			executeFunc: func(ctx context.Context, call ToolCall) (ToolResponse, error) {
+				input = call.Input
+				return NewTextResponse("ok"), nil
+			},
+		}
+		model := &mockLanguageModel{
+			streamFunc: func(ctx context.Context, call Call) (StreamResponse, error) {
+				mu.Lock()
+				calls++
+				n := calls
+				mu.Unlock()
+				switch n {
+				case 1:
+					return partsStream(
+						StreamPart{Type: StreamPartTypeToolInputStart, ID: "call-1", ToolCallName: "lookup"},
+						StreamPart{Type: StreamPartTypeToolInputDelta, ID: "call-1", Delta: `{"query":"fan`},
+						StreamPart{Type: StreamPartTypeFinish, FinishReason: FinishReasonLength},
+					), nil
+				case 2:
+					return partsStream(
+						StreamPart{Type: StreamPartTypeTextStart, ID: "0"},
+						StreamPart{Type: StreamPartTypeTextDelta, ID: "0", Delta: `tasy"}`},
+						StreamPart{Type: StreamPartTypeTextEnd, ID: "0"},
+						StreamPart{Type: StreamPartTypeFinish, FinishReason: FinishReasonStop},
+					), nil
+				}
+				return partsStream(StreamPart{Type: StreamPartTypeFinish, FinishReason: FinishReasonStop}), nil
+			},
+		}
+
+		var deltas []string
+		var textStarts int
+		_, err := NewAgent(model, WithTools(tool), WithAutoContinue(1)).Stream(t.Context(), AgentStreamCall{
+			Prompt: "search",
+			OnToolInputDelta: func(id, delta string) error {
+				deltas = append(deltas, delta)
+				return nil
+			},
+			OnTextStart: func(id string) error {
+				textStarts++
+				return nil
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, `{"query":"fantasy"}`, input)
+		require.Equal(t, []string{`{"query":"fan`, `tasy"}`}, deltas)
+		require.Zero(t, textStarts)
+		require.Equal(t, 3, calls)
+	})
+}
@@ -0,0 +1,145 @@
+package fantasy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Judge scores the candidate responses of BestOfN, returning one score per
+// candidate. Higher is better.
+type Judge interface {
+	Score(ctx context.Context, call Call, candidates []ResponseContent) ([]float64, error)
+}
+
+// JudgeFunc is a function that implements Judge.
+type JudgeFunc func(ctx context.Context, call Call, candidates []ResponseContent) ([]float64, error)
+
+// Score implements Judge.
+func (f JudgeFunc) Score(ctx context.Context, call Call, candidates []ResponseContent) ([]float64, error) {
+	return f(ctx, call, candidates)
+}
+
+// ScoreJudge returns a Judge that scores each candidate on its own.
+func ScoreJudge(score ChoiceScoreFunction) Judge {
+	return JudgeFunc(func(ctx context.Context, _ Call, candidates []ResponseContent) ([]float64, error) {
+		scores := make([]float64, len(candidates))
+		for i, candidate := range candidates {
+			var err error
+			if scores[i], err = score(ctx, candidate); err != nil {
+				return nil, fmt.Errorf("scoring candidate %d: %w", i, err)
+			}
+		}
+		return scores, nil
+	})
+}
+
+const judgePrompt = `Rate how well each candidate response below answers the conversation, from 0 (useless) to 10 (perfect).%s
+
+Reply with only a JSON array holding one score per candidate, in order.`
+
+// ModelJudge returns a Judge that asks model to rate the candidates from 0
+// to 10 side by side. criteria, if not empty, is added to the instructions.
+func ModelJudge(model LanguageModel, criteria string) Judge {
+	return JudgeFunc(func(ctx context.Context, call Call, candidates []ResponseContent) ([]float64, error) {
+		var transcript strings.Builder
+		for _, msg := range call.Prompt {
+			if msg.Role == MessageRoleSystem {
+				continue
+			}
+			for _, part := range msg.Content {
+				if text, ok := AsMessagePart[TextPart](part); ok {
+					fmt.Fprintf(&transcript, "%s: %s\n", msg.Role, text.Text)
+				}
+			}
+		}
+		for i, candidate := range candidates {
+			fmt.Fprintf(&transcript, "\n<candidate %d>\n%s\n</candidate %d>\n", i+1, candidate.Text(), i+1)
+		}
+		if criteria != "" {
+			criteria = "\n\nCriteria: " + criteria
+		}
+
+		resp, err := model.Generate(ctx, Call{
+			Prompt: Prompt{
+				{Role: MessageRoleSystem, Content: []MessagePart{TextPart{Text: fmt.Sprintf(judgePrompt, criteria)}}},
+				NewUserMessage(transcript.String()),
+			},
+			Temperature: new(0.0),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("judging candidates: %w", err)
+		}
+		text := resp.Content.Text()
+		start, end := strings.Index(text, "["), strings.LastIndex(text, "]")
+		if start < 0 || end < start {
+			return nil, fmt.Errorf("judge reply has no scores: %q", text)
+		}
+		var scores []float64
+		if err := json.Unmarshal([]byte(text[start:end+1]), &scores); err != nil {
+			return nil, fmt.Errorf("parsing judge scores: %w", err)
+		}
+		return scores, nil
+	})
+}
+
+// BestOfNResult is the result of BestOfN.
+type BestOfNResult struct {
+	// Best is the index of the selected candidate.
+	Best int
+	// Response is the selected candidate.
+	Response *Response
+	// Candidates are all the generated responses, in the order of Scores.
+	Candidates []*Response
+	// Scores are the judge's scores of the candidates.
+	Scores []float64
+	// Usage is the summed usage of the candidates.
+	Usage Usage
+}
+
+// BestOfN generates n candidate responses to the call concurrently and
+// returns the one the judge scores highest, along with all the candidates
+// and their scores. Ties go to the earlier candidate.
+func BestOfN(ctx context.Context, model LanguageModel, call Call, n int, judge Judge) (*BestOfNResult, error) {
+	if n < 1 {
+		return nil, errors.New("best of n needs at least one candidate")
+	}
+
+	candidates := make([]*Response, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Go(func() {
+			candidates[i], errs[i] = model.Generate(ctx, call)
+		})
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	result := &BestOfNResult{Candidates: candidates}
+	contents := make([]ResponseContent, n)
+	for i, candidate := range candidates {
+		contents[i] = candidate.Content
+		result.Usage = addUsage(result.Usage, candidate.Usage)
+	}
+	scores, err := judge.Score(ctx, call, contents)
+	if err != nil {
+		return nil, err
+	}
+	if len(scores) != n {
+		return nil, fmt.Errorf("judge returned %d scores for %d candidates", len(scores), n)
+	}
+	result.Scores = scores
+	for i, score := range scores {
+		if score > scores[result.Best] {
+			result.Best = i
+		}
+	}
+	result.Response = candidates[result.Best]
+	return result, nil
+}
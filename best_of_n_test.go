@@ -0,0 +1,85 @@
+package fantasy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBestOfN(t *testing.T) {
+	t.Parallel()
+
+	model := &mockLanguageModel{
+		generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+			return &Response{
+				Content: ResponseContent{TextContent{Text: "candidate"}},
+				Usage:   Usage{OutputTokens: 4},
+			}, nil
+		},
+	}
+
+	t.Run("score func", func(t *testing.T) {
+		t.Parallel()
+		var calls atomic.Int32
+		model := &mockLanguageModel{
+			generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+				n := calls.Add(1)
+				return &Response{Content: ResponseContent{TextContent{Text: strings.Repeat("x", int(n))}}}, nil
+			},
+		}
+		judge := ScoreJudge(func(ctx context.Context, content ResponseContent) (float64, error) {
+			return float64(len(content.Text())), nil
+		})
+		result, err := BestOfN(t.Context(), model, Call{}, 3, judge)
+		require.NoError(t, err)
+		require.Len(t, result.Candidates, 3)
+		require.Equal(t, "xxx", result.Response.Content.Text())
+		require.Equal(t, float64(3), result.Scores[result.Best])
+	})
+
+	t.Run("judge model", func(t *testing.T) {
+		t.Parallel()
+		var judgeCall Call
+		judgeModel := &mockLanguageModel{
+			generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+				judgeCall = call
+				return &Response{Content: ResponseContent{TextContent{Text: "Scores: [2, 9]"}}}, nil
+			},
+		}
+		result, err := BestOfN(t.Context(), model, Call{Prompt: Prompt{NewUserMessage("write a haiku")}}, 2, ModelJudge(judgeModel, "prefer brevity"))
+		require.NoError(t, err)
+		require.Equal(t, 1, result.Best)
+		require.Equal(t, []float64{2, 9}, result.Scores)
+		require.Same(t, result.Candidates[1], result.Response)
+		require.Equal(t, int64(8), result.Usage.OutputTokens)
+
+		require.Contains(t, judgeCall.Prompt[0].Content[0].(TextPart).Text, "Criteria: prefer brevity")
+		transcript := judgeCall.Prompt[1].Content[0].(TextPart).Text
+		require.Contains(t, transcript, "user: write a haiku")
+		require.Contains(t, transcript, "<candidate 2>")
+	})
+
+	t.Run("score count mismatch", func(t *testing.T) {
+		t.Parallel()
+		judge := JudgeFunc(func(ctx context.Context, call Call, candidates []ResponseContent) ([]float64, error) {
+			return []float64{1}, nil
+		})
+		_, err := BestOfN(t.Context(), model, Call{}, 2, judge)
+		require.EqualError(t, err, "judge returned 1 scores for 2 candidates")
+	})
+
+	t.Run("candidate error", func(t *testing.T) {
+		t.Parallel()
+		failing := &mockLanguageModel{
+			generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+				return nil, fmt.Errorf("rate limited")
+			},
+		}
+		_, err := BestOfN(t.Context(), failing, Call{}, 2, ScoreJudge(nil))
+		require.ErrorContains(t, err, "rate limited")
+	})
+}
@@ -0,0 +1,35 @@
+package fantasy
+
+import (
+	"context"
+	"maps"
+)
+
+// MetadataUserID is the call metadata key identifying the end user a call is
+// made for. Providers that attribute abuse to end users, such as Anthropic
+// with metadata.user_id, send it in the field they reserve for it.
+const MetadataUserID = "user_id"
+
+type callMetadataContextKey struct{}
+
+// WithCallMetadata returns a context carrying the metadata, such as the end
+// user or a trace ID, merged over any metadata ctx already carries. Agents
+// send the metadata of their context with every call they make, tools read it
+// with CallMetadata, and providers map it to their request metadata: OpenAI
+// sends it as metadata and Anthropic sends MetadataUserID as
+// metadata.user_id.
+func WithCallMetadata(ctx context.Context, metadata map[string]string) context.Context {
+	merged := maps.Clone(CallMetadata(ctx))
+	if merged == nil {
+		merged = make(map[string]string, len(metadata))
+	}
+	maps.Copy(merged, metadata)
+	return context.WithValue(ctx, callMetadataContextKey{}, merged)
+}
+
+// CallMetadata returns the metadata ctx carries, or nil. The map must not be
+// modified.
+func CallMetadata(ctx context.Context) map[string]string {
+	metadata, _ := ctx.Value(callMetadataContextKey{}).(map[string]string)
+	return metadata
+}
@@ -0,0 +1,56 @@
+package fantasy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithCallMetadata(t *testing.T) {
+	t.Parallel()
+
+	require.Nil(t, CallMetadata(t.Context()))
+
+	ctx := WithCallMetadata(t.Context(), map[string]string{MetadataUserID: "user-1", "trace_id": "abc"})
+	child := WithCallMetadata(ctx, map[string]string{"trace_id": "def"})
+	require.Equal(t, map[string]string{MetadataUserID: "user-1", "trace_id": "def"}, CallMetadata(child))
+	require.Equal(t, "abc", CallMetadata(ctx)["trace_id"], "the parent metadata must not change")
+}
+
+func TestAgent_CallMetadata(t *testing.T) {
+	t.Parallel()
+
+	var calls []Call
+	model := &mockLanguageModel{
+		generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+			calls = append(calls, call)
+			if len(calls) == 1 {
+				return &Response{
+					Content:      []Content{ToolCallContent{ToolCallID: "call-1", ToolName: "lookup", Input: `{}`}},
+					FinishReason: FinishReasonToolCalls,
+				}, nil
+			}
+			return &Response{Content: []Content{TextContent{Text: "done"}}, FinishReason: FinishReasonStop}, nil
+		},
+	}
+	var toolMetadata map[string]string
+	tool := &mockTool{
+		name: "lookup",
+		executeFunc: func(ctx context.Context, call ToolCall) (ToolResponse, error) {
+			toolMetadata = CallMetadata(ctx)
+			return NewTextResponse("found"), nil
+		},
+	}
+
+	metadata := map[string]string{MetadataUserID: "user-1"}
+	agent := NewAgent(model, WithTools(tool))
+	_, err := agent.Generate(WithCallMetadata(t.Context(), metadata), AgentCall{Prompt: "hello"})
+	require.NoError(t, err)
+
+	require.Len(t, calls, 2)
+	for _, call := range calls {
+		require.Equal(t, metadata, call.Metadata)
+	}
+	require.Equal(t, metadata, toolMetadata)
+}
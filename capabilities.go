@@ -0,0 +1,100 @@
+package fantasy
+
+import (
+	"context"
+	"errors"
+)
+
+// WithCapabilities returns wrapper with the optional capabilities of inner,
+// the model it wraps: PrefillModel, PDFModel, FileURLModel,
+// MultiChoiceModel and TokenCounter. Models that wrap another one use it
+// so that what the inner model supports is still detected.
+func WithCapabilities(wrapper, inner LanguageModel) LanguageModel {
+	m := capabilityModel{LanguageModel: wrapper, capabilities: staticCapabilities{inner}}
+	if _, ok := inner.(TokenCounter); ok {
+		return tokenCountingModel{m}
+	}
+	return m
+}
+
+// capabilities resolves the model whose capabilities a wrapper forwards.
+type capabilities interface {
+	// capable returns the model, or false if it isn't known.
+	capable() (LanguageModel, bool)
+	// counter returns the model to count the tokens of a call with.
+	counter(ctx context.Context) (LanguageModel, error)
+}
+
+type staticCapabilities struct {
+	model LanguageModel
+}
+
+func (c staticCapabilities) capable() (LanguageModel, bool) {
+	return c.model, true
+}
+
+func (c staticCapabilities) counter(context.Context) (LanguageModel, error) {
+	return c.model, nil
+}
+
+type capabilityModel struct {
+	LanguageModel
+	capabilities capabilities
+}
+
+// SupportsPrefill implements PrefillModel.
+func (m capabilityModel) SupportsPrefill() bool {
+	model, ok := m.capabilities.capable()
+	if !ok {
+		return false
+	}
+	prefill, ok := model.(PrefillModel)
+	return ok && prefill.SupportsPrefill()
+}
+
+// SupportsPDF implements PDFModel.
+func (m capabilityModel) SupportsPDF() bool {
+	model, ok := m.capabilities.capable()
+	if !ok {
+		return false
+	}
+	pdf, ok := model.(PDFModel)
+	return ok && pdf.SupportsPDF()
+}
+
+// SupportsFileURL implements FileURLModel.
+func (m capabilityModel) SupportsFileURL(mediaType string) bool {
+	model, ok := m.capabilities.capable()
+	if !ok {
+		return false
+	}
+	url, ok := model.(FileURLModel)
+	return ok && url.SupportsFileURL(mediaType)
+}
+
+// SupportsNumChoices implements MultiChoiceModel.
+func (m capabilityModel) SupportsNumChoices() bool {
+	model, ok := m.capabilities.capable()
+	if !ok {
+		return false
+	}
+	multi, ok := model.(MultiChoiceModel)
+	return ok && multi.SupportsNumChoices()
+}
+
+type tokenCountingModel struct {
+	capabilityModel
+}
+
+// CountTokens implements TokenCounter.
+func (m tokenCountingModel) CountTokens(ctx context.Context, call Call) (int64, error) {
+	model, err := m.capabilities.counter(ctx)
+	if err != nil {
+		return 0, err
+	}
+	counter, ok := model.(TokenCounter)
+	if !ok {
+		return 0, errors.New("token counting is not supported")
+	}
+	return counter.CountTokens(ctx, call)
+}
@@ -0,0 +1,49 @@
+package fantasy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// capableMockModel reads PDFs natively, continues prefilled responses and
+// counts tokens.
+type capableMockModel struct {
+	mockLanguageModel
+}
+
+func (*capableMockModel) SupportsPrefill() bool { return true }
+func (*capableMockModel) SupportsPDF() bool     { return true }
+
+func (*capableMockModel) CountTokens(context.Context, Call) (int64, error) { return 42, nil }
+
+// requireCapabilities fails the test unless model has the capabilities of
+// capableMockModel.
+func requireCapabilities(t *testing.T, model LanguageModel) {
+	t.Helper()
+	prefill, ok := model.(PrefillModel)
+	require.True(t, ok)
+	require.True(t, prefill.SupportsPrefill())
+	pdf, ok := model.(PDFModel)
+	require.True(t, ok)
+	require.True(t, pdf.SupportsPDF())
+	counter, ok := model.(TokenCounter)
+	require.True(t, ok)
+	count, err := counter.CountTokens(t.Context(), Call{})
+	require.NoError(t, err)
+	require.Equal(t, int64(42), count)
+}
+
+func TestWithCapabilities(t *testing.T) {
+	t.Parallel()
+
+	requireCapabilities(t, WithCapabilities(&mockLanguageModel{}, &capableMockModel{}))
+
+	model := WithCapabilities(&mockLanguageModel{}, &mockLanguageModel{})
+	_, ok := model.(TokenCounter)
+	require.False(t, ok, "models that can't count tokens shouldn't seem to")
+	pdf, ok := model.(PDFModel)
+	require.True(t, ok)
+	require.False(t, pdf.SupportsPDF())
+}
@@ -0,0 +1,115 @@
+package fantasy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// GenerateChoices generates a response with call.NumChoices choices. Models
+// implementing MultiChoiceModel are called once; other models are called
+// that many times in parallel, and the responses are combined into one
+// with the summed usage and the later responses' content as alternatives.
+func GenerateChoices(ctx context.Context, model LanguageModel, call Call) (*Response, error) {
+	if call.NumChoices == nil || *call.NumChoices <= 1 {
+		return model.Generate(ctx, call)
+	}
+	if multi, ok := model.(MultiChoiceModel); ok && multi.SupportsNumChoices() {
+		return model.Generate(ctx, call)
+	}
+
+	n := int(*call.NumChoices)
+	call.NumChoices = nil
+	responses := make([]*Response, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Go(func() {
+			responses[i], errs[i] = model.Generate(ctx, call)
+		})
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	resp := *responses[0]
+	for _, other := range responses[1:] {
+		resp.Alternatives = append(resp.Alternatives, other.Content)
+		resp.Usage = addUsage(resp.Usage, other.Usage)
+		resp.Warnings = append(resp.Warnings, other.Warnings...)
+	}
+	return &resp, nil
+}
+
+// ChoiceScoreFunction scores one choice of a response. Higher is better.
+type ChoiceScoreFunction func(ctx context.Context, content ResponseContent) (float64, error)
+
+// WithChoices makes the agent ask for n choices in each generated step and
+// go on with the one score rates best, or the first one if score is nil.
+// The other choices are kept in the step's Response.Alternatives. Streamed
+// steps produce a single choice.
+func WithChoices(n int, score ChoiceScoreFunction) AgentOption {
+	return func(s *agentSettings) {
+		s.numChoices = n
+		s.scoreChoice = score
+	}
+}
+
+func (a *agent) generateChoices(ctx context.Context, model LanguageModel, call Call) (*Response, error) {
+	if a.settings.numChoices <= 1 {
+		return a.safeGenerate(ctx, model, call)
+	}
+	n := int64(a.settings.numChoices)
+	call.NumChoices = &n
+	resp, err := GenerateChoices(ctx, safeModel{model, a}, call)
+	if err != nil || a.settings.scoreChoice == nil || len(resp.Alternatives) == 0 {
+		return resp, err
+	}
+
+	choices := append([]ResponseContent{resp.Content}, resp.Alternatives...)
+	best, bestScore := 0, 0.0
+	for i, choice := range choices {
+		score, err := a.settings.scoreChoice(ctx, choice)
+		if err != nil {
+			return nil, fmt.Errorf("scoring choice %d: %w", i, err)
+		}
+		if i == 0 || score > bestScore {
+			best, bestScore = i, score
+		}
+	}
+	if best == 0 {
+		return resp, nil
+	}
+
+	picked := *resp
+	picked.Content = choices[best]
+	picked.Alternatives = append(choices[:best:best], choices[best+1:]...)
+	// The finish reason is the first choice's, so it only says whether
+	// the picked choice calls tools if it's adjusted.
+	switch {
+	case len(picked.Content.ToolCalls()) > 0:
+		picked.FinishReason = FinishReasonToolCalls
+	case picked.FinishReason == FinishReasonToolCalls:
+		picked.FinishReason = FinishReasonStop
+	}
+	return &picked, nil
+}
+
+// safeModel recovers panics in Generate, which GenerateChoices may call
+// from other goroutines than the agent's.
+type safeModel struct {
+	LanguageModel
+	agent *agent
+}
+
+func (m safeModel) Generate(ctx context.Context, call Call) (*Response, error) {
+	return m.agent.safeGenerate(ctx, m.LanguageModel, call)
+}
+
+func (m safeModel) SupportsNumChoices() bool {
+	multi, ok := m.LanguageModel.(MultiChoiceModel)
+	return ok && multi.SupportsNumChoices()
+}
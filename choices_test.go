@@ -0,0 +1,114 @@
+package fantasy
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type multiChoiceMockModel struct {
+	*mockLanguageModel
+}
+
+func (multiChoiceMockModel) SupportsNumChoices() bool { return true }
+
+func TestGenerateChoices(t *testing.T) {
+	t.Parallel()
+
+	t.Run("emulates with parallel calls", func(t *testing.T) {
+		t.Parallel()
+		var calls atomic.Int32
+		model := &mockLanguageModel{
+			generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+				require.Nil(t, call.NumChoices)
+				n := calls.Add(1)
+				return &Response{
+					Content:      ResponseContent{TextContent{Text: fmt.Sprintf("choice %d", n)}},
+					FinishReason: FinishReasonStop,
+					Usage:        Usage{OutputTokens: 5},
+				}, nil
+			},
+		}
+		resp, err := GenerateChoices(t.Context(), model, Call{NumChoices: new(int64(3))})
+		require.NoError(t, err)
+		require.Equal(t, int32(3), calls.Load())
+		require.Len(t, resp.Alternatives, 2)
+		require.Equal(t, int64(15), resp.Usage.OutputTokens)
+
+		texts := []string{resp.Content.Text()}
+		for _, alternative := range resp.Alternatives {
+			texts = append(texts, alternative.Text())
+		}
+		require.ElementsMatch(t, []string{"choice 1", "choice 2", "choice 3"}, texts)
+	})
+
+	t.Run("uses native support", func(t *testing.T) {
+		t.Parallel()
+		var calls atomic.Int32
+		model := multiChoiceMockModel{&mockLanguageModel{
+			generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+				calls.Add(1)
+				require.Equal(t, int64(2), *call.NumChoices)
+				return &Response{
+					Content:      ResponseContent{TextContent{Text: "a"}},
+					Alternatives: []ResponseContent{{TextContent{Text: "b"}}},
+				}, nil
+			},
+		}}
+		resp, err := GenerateChoices(t.Context(), model, Call{NumChoices: new(int64(2))})
+		require.NoError(t, err)
+		require.Equal(t, int32(1), calls.Load())
+		require.Equal(t, "b", resp.Alternatives[0].Text())
+	})
+
+	t.Run("agent picks the best choice", func(t *testing.T) {
+		t.Parallel()
+		var calls atomic.Int32
+		tool := &mockTool{name: "lookup"}
+		model := multiChoiceMockModel{&mockLanguageModel{
+			generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+				if calls.Add(1) > 1 {
+					return &Response{Content: ResponseContent{TextContent{Text: "done"}}, FinishReason: FinishReasonStop}, nil
+				}
+				require.Equal(t, int64(3), *call.NumChoices)
+				return &Response{
+					Content: ResponseContent{TextContent{Text: "short"}},
+					Alternatives: []ResponseContent{
+						{TextContent{Text: "a longer answer"}, ToolCallContent{ToolCallID: "1", ToolName: "lookup", Input: `{}`}},
+						{TextContent{Text: "medium"}},
+					},
+					FinishReason: FinishReasonStop,
+				}, nil
+			},
+		}}
+		score := func(ctx context.Context, content ResponseContent) (float64, error) {
+			return float64(len(content.Text())), nil
+		}
+		result, err := NewAgent(model, WithTools(tool), WithChoices(3, score)).Generate(t.Context(), AgentCall{Prompt: "hi"})
+		require.NoError(t, err)
+
+		step := result.Steps[0]
+		require.Equal(t, "a longer answer", step.Content.Text())
+		require.Equal(t, FinishReasonToolCalls, step.FinishReason)
+		require.Len(t, step.Alternatives, 2)
+		require.Equal(t, "short", step.Alternatives[0].Text())
+		require.Len(t, result.Steps, 2)
+	})
+
+	t.Run("agent fails on score errors", func(t *testing.T) {
+		t.Parallel()
+		model := &mockLanguageModel{
+			generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+				return &Response{Content: ResponseContent{TextContent{Text: "x"}}, FinishReason: FinishReasonStop}, nil
+			},
+		}
+		score := func(ctx context.Context, content ResponseContent) (float64, error) {
+			return 0, fmt.Errorf("judge unavailable")
+		}
+		_, err := NewAgent(model, WithChoices(2, score), WithMaxRetries(0)).Generate(t.Context(), AgentCall{Prompt: "hi"})
+		require.ErrorContains(t, err, "judge unavailable")
+	})
+}
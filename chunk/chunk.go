@@ -0,0 +1,235 @@
+// Package chunk splits documents into chunks for indexing, for example in a
+// vectorstore. Chunks are limited in length, measured in characters or
+// tokens, and can overlap so that text cut at a boundary keeps some context.
+//
+// Recursive splits at the largest natural boundary that fits, from
+// paragraphs down to words. Sentences keeps sentences whole. Markdown splits
+// at headings and records the heading path of each chunk.
+//
+// Example:
+//
+//	splitter := chunk.Markdown(chunk.Options{Size: 500, Overlap: 50, Length: chunk.EstimatedTokens})
+//	for _, c := range splitter.Split(doc) {
+//	    docs = append(docs, vectorstore.Document{
+//	        ID:       fmt.Sprintf("%s#%d", name, c.Start),
+//	        Text:     c.Text,
+//	        Metadata: map[string]string{"title": c.Heading},
+//	    })
+//	}
+package chunk
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"charm.land/fantasy"
+)
+
+// Chunk is a piece of a document.
+type Chunk struct {
+	Text string `json:"text"`
+	// Start and End are the byte offsets of the chunk in the document.
+	Start int `json:"start"`
+	End   int `json:"end"`
+	// Heading is the path of markdown headings the chunk is under, such as
+	// "Install > Linux". It is only set by Markdown.
+	Heading string `json:"heading,omitempty"`
+}
+
+// Splitter splits a document into chunks.
+type Splitter interface {
+	Split(text string) []Chunk
+}
+
+// LengthFunc measures the length of a text, in characters or tokens.
+type LengthFunc func(text string) int
+
+// Characters measures text in characters.
+func Characters(text string) int {
+	return utf8.RuneCountInString(text)
+}
+
+// EstimatedTokens measures text in tokens, estimated at about four
+// characters per token.
+func EstimatedTokens(text string) int {
+	return int(fantasy.EstimateTokens(text))
+}
+
+// TokenizedTokens measures text in tokens as counted by a local tokenizer,
+// such as one from the tokenizer package, for exact limits without requests.
+func TokenizedTokens(tokenizer fantasy.Tokenizer) LengthFunc {
+	return tokenizer.Count
+}
+
+// CountedTokens measures text in tokens as counted by the model, for exact
+// limits. Counting usually makes a request per text, so results are cached.
+// If counting fails the length is estimated.
+func CountedTokens(ctx context.Context, counter fantasy.TokenCounter) LengthFunc {
+	var cache sync.Map
+	return func(text string) int {
+		if n, ok := cache.Load(text); ok {
+			return n.(int)
+		}
+		n, err := counter.CountTokens(ctx, fantasy.Call{Prompt: fantasy.Prompt{fantasy.NewUserMessage(text)}})
+		if err != nil {
+			return EstimatedTokens(text)
+		}
+		cache.Store(text, int(n))
+		return int(n)
+	}
+}
+
+// Options configures a splitter.
+type Options struct {
+	// Size is the maximum length of a chunk. Defaults to 1000.
+	Size int
+	// Overlap is how much of the end of a chunk is repeated at the start of
+	// the next one. It must be less than Size.
+	Overlap int
+	// Length measures text. Defaults to Characters.
+	Length LengthFunc
+}
+
+const defaultSize = 1000
+
+func (o Options) withDefaults() Options {
+	if o.Size <= 0 {
+		o.Size = defaultSize
+	}
+	if o.Overlap < 0 || o.Overlap >= o.Size {
+		o.Overlap = 0
+	}
+	if o.Length == nil {
+		o.Length = Characters
+	}
+	return o
+}
+
+// span is a range of bytes of the document.
+type span struct {
+	start, end int
+}
+
+// splitter holds the state of splitting one document.
+type splitter struct {
+	Options
+	doc string
+}
+
+func (s *splitter) length(sp span) int {
+	return s.Length(s.doc[sp.start:sp.end])
+}
+
+// trimmedLength measures the span without its trailing whitespace, which is
+// dropped when the span ends a chunk.
+func (s *splitter) trimmedLength(sp span) int {
+	return s.Length(strings.TrimRightFunc(s.doc[sp.start:sp.end], unicode.IsSpace))
+}
+
+// recursive splits the span at the first separator it contains, splitting
+// pieces that are still too long with the next separators, and merges the
+// pieces back into chunks. The empty separator splits between characters.
+func (s *splitter) recursive(sp span, separators []string) []span {
+	if s.length(sp) <= s.Size || len(separators) == 0 {
+		return []span{sp}
+	}
+	text := s.doc[sp.start:sp.end]
+	i := 0
+	for i < len(separators)-1 && separators[i] != "" && !strings.Contains(text, separators[i]) {
+		i++
+	}
+	return s.mergeOrSplit(splitAfter(sp, text, separators[i]), separators[i+1:])
+}
+
+// mergeOrSplit merges the pieces into chunks, splitting the pieces that are
+// too long on their own with the separators.
+func (s *splitter) mergeOrSplit(pieces []span, separators []string) []span {
+	var chunks, small []span
+	for _, piece := range pieces {
+		if s.trimmedLength(piece) <= s.Size {
+			small = append(small, piece)
+			continue
+		}
+		chunks = append(chunks, s.merge(small)...)
+		small = nil
+		chunks = append(chunks, s.recursive(piece, separators)...)
+	}
+	return append(chunks, s.merge(small)...)
+}
+
+// merge combines consecutive pieces into chunks of up to Size, starting each
+// chunk with up to Overlap of the end of the previous one.
+func (s *splitter) merge(pieces []span) []span {
+	var chunks []span
+	var current []span
+	total := 0
+	for _, piece := range pieces {
+		n, trimmed := s.length(piece), s.trimmedLength(piece)
+		if len(current) > 0 && total+trimmed > s.Size {
+			chunks = append(chunks, span{current[0].start, current[len(current)-1].end})
+			for len(current) > 0 && (total > s.Overlap || total+trimmed > s.Size) {
+				total -= s.length(current[0])
+				current = current[1:]
+			}
+		}
+		current = append(current, piece)
+		total += n
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, span{current[0].start, current[len(current)-1].end})
+	}
+	return chunks
+}
+
+// chunks turns spans into chunks, trimming surrounding whitespace and
+// dropping empty ones.
+func (s *splitter) chunks(spans []span, heading string) []Chunk {
+	var chunks []Chunk
+	for _, sp := range spans {
+		for sp.start < sp.end && isSpace(s.doc[sp.start]) {
+			sp.start++
+		}
+		for sp.end > sp.start && isSpace(s.doc[sp.end-1]) {
+			sp.end--
+		}
+		if sp.start < sp.end {
+			chunks = append(chunks, Chunk{Text: s.doc[sp.start:sp.end], Start: sp.start, End: sp.end, Heading: heading})
+		}
+	}
+	return chunks
+}
+
+// splitAfter splits the span after each occurrence of the separator, so no
+// text is lost. The empty separator splits between characters.
+func splitAfter(sp span, text, separator string) []span {
+	var pieces []span
+	start := 0
+	if separator == "" {
+		for i, r := range text {
+			end := i + utf8.RuneLen(r)
+			pieces = append(pieces, span{sp.start + start, sp.start + end})
+			start = end
+		}
+		return pieces
+	}
+	for {
+		i := strings.Index(text[start:], separator)
+		if i < 0 {
+			break
+		}
+		end := start + i + len(separator)
+		pieces = append(pieces, span{sp.start + start, sp.start + end})
+		start = end
+	}
+	if start < len(text) {
+		pieces = append(pieces, span{sp.start + start, sp.start + len(text)})
+	}
+	return pieces
+}
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\n' || b == '\t' || b == '\r'
+}
@@ -0,0 +1,197 @@
+package chunk
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/require"
+)
+
+func texts(chunks []Chunk) []string {
+	out := make([]string, len(chunks))
+	for i, c := range chunks {
+		out[i] = c.Text
+	}
+	return out
+}
+
+func requireSpans(t *testing.T, doc string, chunks []Chunk) {
+	t.Helper()
+	for _, c := range chunks {
+		require.Equal(t, c.Text, doc[c.Start:c.End])
+	}
+}
+
+func TestRecursive(t *testing.T) {
+	t.Parallel()
+
+	t.Run("short text is one chunk", func(t *testing.T) {
+		t.Parallel()
+		chunks := Recursive(Options{Size: 100}).Split("  Hello, world.\n")
+		require.Equal(t, []Chunk{{Text: "Hello, world.", Start: 2, End: 15}}, chunks)
+	})
+
+	t.Run("splits at paragraphs first", func(t *testing.T) {
+		t.Parallel()
+		doc := "First paragraph here.\n\nSecond paragraph here.\n\nThird one."
+		chunks := Recursive(Options{Size: 30}).Split(doc)
+		require.Equal(t, []string{"First paragraph here.", "Second paragraph here.", "Third one."}, texts(chunks))
+		requireSpans(t, doc, chunks)
+	})
+
+	t.Run("merges small pieces", func(t *testing.T) {
+		t.Parallel()
+		doc := "a b c d e f g h"
+		chunks := Recursive(Options{Size: 7}).Split(doc)
+		require.Equal(t, []string{"a b c d", "e f g h"}, texts(chunks))
+		requireSpans(t, doc, chunks)
+	})
+
+	t.Run("overlap", func(t *testing.T) {
+		t.Parallel()
+		doc := "one two three four five six"
+		chunks := Recursive(Options{Size: 14, Overlap: 6}).Split(doc)
+		require.Equal(t, []string{"one two three", "three four", "four five six"}, texts(chunks))
+		requireSpans(t, doc, chunks)
+	})
+
+	t.Run("splits long words at characters", func(t *testing.T) {
+		t.Parallel()
+		chunks := Recursive(Options{Size: 4}).Split("abcdefghij")
+		require.Equal(t, []string{"abcd", "efgh", "ij"}, texts(chunks))
+	})
+
+	t.Run("counts characters not bytes", func(t *testing.T) {
+		t.Parallel()
+		doc := "héllo wörld"
+		chunks := Recursive(Options{Size: 5}).Split(doc)
+		require.Equal(t, []string{"héllo", "wörld"}, texts(chunks))
+		requireSpans(t, doc, chunks)
+	})
+
+	t.Run("custom separators", func(t *testing.T) {
+		t.Parallel()
+		chunks := Recursive(Options{Size: 5}, ";", "").Split("abc;de;fghijk")
+		require.Equal(t, []string{"abc;", "de;", "fghij", "k"}, texts(chunks))
+	})
+
+	t.Run("chunks never exceed size", func(t *testing.T) {
+		t.Parallel()
+		doc := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 50)
+		for _, c := range Recursive(Options{Size: 60, Overlap: 10}).Split(doc) {
+			require.LessOrEqual(t, Characters(c.Text), 60)
+		}
+	})
+}
+
+func TestSentences(t *testing.T) {
+	t.Parallel()
+
+	doc := "Go is fun. Is it fast? Yes! It compiles quickly."
+	chunks := Sentences(Options{Size: 25}).Split(doc)
+	require.Equal(t, []string{"Go is fun. Is it fast?", "Yes! It compiles quickly."}, texts(chunks))
+	requireSpans(t, doc, chunks)
+
+	t.Run("long sentences are split at words", func(t *testing.T) {
+		t.Parallel()
+		chunks := Sentences(Options{Size: 10}).Split("This sentence is too long. Short.")
+		require.Equal(t, []string{"This", "sentence", "is too", "long.", "Short."}, texts(chunks))
+	})
+}
+
+func TestMarkdown(t *testing.T) {
+	t.Parallel()
+
+	doc := `Intro text.
+
+# Install
+
+Run the installer.
+
+## Linux
+
+Use the package.
+
+` + "```sh\n# not a heading\napt install x\n```" + `
+
+## macOS ##
+
+Use brew.
+
+# Usage
+
+Call it.
+`
+	chunks := Markdown(Options{Size: 200}).Split(doc)
+	requireSpans(t, doc, chunks)
+	require.Equal(t, []string{"", "Install", "Install > Linux", "Install > macOS", "Usage"}, headings(chunks))
+	require.Equal(t, "Intro text.", chunks[0].Text)
+	require.Contains(t, chunks[2].Text, "# not a heading")
+	require.Equal(t, "# Usage\n\nCall it.", chunks[4].Text)
+
+	t.Run("long sections are split", func(t *testing.T) {
+		t.Parallel()
+		doc := "# Title\n\n" + strings.Repeat("word ", 40)
+		chunks := Markdown(Options{Size: 50}).Split(doc)
+		require.Greater(t, len(chunks), 2)
+		for _, c := range chunks {
+			require.Equal(t, "Title", c.Heading)
+			require.LessOrEqual(t, Characters(c.Text), 50)
+		}
+	})
+}
+
+func headings(chunks []Chunk) []string {
+	out := make([]string, len(chunks))
+	for i, c := range chunks {
+		out[i] = c.Heading
+	}
+	return out
+}
+
+func TestTokens(t *testing.T) {
+	t.Parallel()
+
+	doc := strings.Repeat("abcd ", 100)
+	chunks := Tokens(20, 0).Split(doc)
+	require.Greater(t, len(chunks), 1)
+	for _, c := range chunks {
+		require.LessOrEqual(t, EstimatedTokens(c.Text), 20)
+	}
+}
+
+type countingModel struct {
+	calls int
+	err   error
+}
+
+func (m *countingModel) CountTokens(_ context.Context, call fantasy.Call) (int64, error) {
+	m.calls++
+	if m.err != nil {
+		return 0, m.err
+	}
+	text, _ := fantasy.AsMessagePart[fantasy.TextPart](call.Prompt[0].Content[0])
+	return int64(len(strings.Fields(text.Text))), nil
+}
+
+func TestCountedTokens(t *testing.T) {
+	t.Parallel()
+
+	t.Run("counts and caches", func(t *testing.T) {
+		t.Parallel()
+		model := &countingModel{}
+		length := CountedTokens(t.Context(), model)
+		require.Equal(t, 3, length("one two three"))
+		require.Equal(t, 3, length("one two three"))
+		require.Equal(t, 1, model.calls)
+	})
+
+	t.Run("falls back to the estimate", func(t *testing.T) {
+		t.Parallel()
+		length := CountedTokens(t.Context(), &countingModel{err: errors.New("boom")})
+		require.Equal(t, EstimatedTokens("one two three"), length("one two three"))
+	})
+}
@@ -0,0 +1,152 @@
+package chunk
+
+import (
+	"regexp"
+	"strings"
+)
+
+// DefaultSeparators are the separators Recursive uses when none are given:
+// paragraphs, lines, sentences, words and characters.
+var DefaultSeparators = []string{"\n\n", "\n", ". ", " ", ""}
+
+type recursiveSplitter struct {
+	opts       Options
+	separators []string
+}
+
+// Recursive creates a splitter that splits text at the first of the
+// separators it contains, splits the pieces that are still too long at the
+// next separators, and merges small pieces back into chunks of up to
+// opts.Size. Separators default to DefaultSeparators; end them with "" to
+// guarantee no chunk is longer than opts.Size.
+func Recursive(opts Options, separators ...string) Splitter {
+	if len(separators) == 0 {
+		separators = DefaultSeparators
+	}
+	return &recursiveSplitter{opts: opts.withDefaults(), separators: separators}
+}
+
+// Split implements Splitter.
+func (r *recursiveSplitter) Split(text string) []Chunk {
+	s := &splitter{Options: r.opts, doc: text}
+	return s.chunks(s.recursive(span{0, len(text)}, r.separators), "")
+}
+
+// Tokens creates a recursive splitter that limits chunks to size estimated
+// tokens. Use Recursive with CountedTokens to count them exactly.
+func Tokens(size, overlap int) Splitter {
+	return Recursive(Options{Size: size, Overlap: overlap, Length: EstimatedTokens})
+}
+
+// sentenceEnd matches the end of a sentence: its punctuation, any closing
+// quotes or brackets, and the whitespace after them.
+var sentenceEnd = regexp.MustCompile(`[.!?]+["'”’)\]]*\s+`)
+
+type sentenceSplitter struct {
+	opts Options
+}
+
+// Sentences creates a splitter that keeps sentences whole, merging them into
+// chunks of up to opts.Size. Sentences longer than opts.Size are split at
+// words.
+func Sentences(opts Options) Splitter {
+	return &sentenceSplitter{opts: opts.withDefaults()}
+}
+
+// Split implements Splitter.
+func (ss *sentenceSplitter) Split(text string) []Chunk {
+	s := &splitter{Options: ss.opts, doc: text}
+	return s.chunks(s.mergeOrSplit(sentences(span{0, len(text)}, text), []string{" ", ""}), "")
+}
+
+func sentences(sp span, text string) []span {
+	var pieces []span
+	start := 0
+	for _, m := range sentenceEnd.FindAllStringIndex(text, -1) {
+		pieces = append(pieces, span{sp.start + start, sp.start + m[1]})
+		start = m[1]
+	}
+	if start < len(text) {
+		pieces = append(pieces, span{sp.start + start, sp.start + len(text)})
+	}
+	return pieces
+}
+
+var (
+	atxHeading = regexp.MustCompile(`^ {0,3}(#{1,6})[ \t]+(.*?)(?:[ \t]+#+)?[ \t]*$`)
+	codeFence  = regexp.MustCompile("^ {0,3}(```|~~~)")
+)
+
+type markdownSplitter struct {
+	opts Options
+}
+
+// Markdown creates a splitter that splits markdown into sections at its
+// headings, ignoring lines in code blocks, and splits sections longer than
+// opts.Size recursively. Each chunk's Heading is the path of headings it is
+// under, joined with " > ".
+func Markdown(opts Options) Splitter {
+	return &markdownSplitter{opts: opts.withDefaults()}
+}
+
+type section struct {
+	span
+	heading string
+}
+
+// Split implements Splitter.
+func (m *markdownSplitter) Split(text string) []Chunk {
+	s := &splitter{Options: m.opts, doc: text}
+	var chunks []Chunk
+	for _, sec := range markdownSections(text) {
+		chunks = append(chunks, s.chunks(s.recursive(sec.span, DefaultSeparators), sec.heading)...)
+	}
+	return chunks
+}
+
+// markdownSections splits the text before each heading, and returns the
+// sections with their heading paths.
+func markdownSections(text string) []section {
+	var (
+		sections []section
+		headings [6]string
+		path     string
+		start    int
+		fence    string
+	)
+	for offset := 0; offset < len(text); {
+		end := strings.IndexByte(text[offset:], '\n') + offset + 1
+		if end == offset {
+			end = len(text)
+		}
+		line := strings.TrimRight(text[offset:end], "\r\n")
+		if m := codeFence.FindStringSubmatch(line); m != nil {
+			switch fence {
+			case "":
+				fence = m[1]
+			case m[1]:
+				fence = ""
+			}
+		} else if m := atxHeading.FindStringSubmatch(line); m != nil && fence == "" {
+			if offset > start {
+				sections = append(sections, section{span{start, offset}, path})
+			}
+			start = offset
+			level := len(m[1]) - 1
+			headings[level] = m[2]
+			clear(headings[level+1:])
+			var parts []string
+			for _, h := range headings {
+				if h != "" {
+					parts = append(parts, h)
+				}
+			}
+			path = strings.Join(parts, " > ")
+		}
+		offset = end
+	}
+	if start < len(text) {
+		sections = append(sections, section{span{start, len(text)}, path})
+	}
+	return sections
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/anthropic"
+	"charm.land/fantasy/providers/google"
+	"charm.land/fantasy/providers/openai"
+	"charm.land/fantasy/providers/openaicompat"
+	"charm.land/fantasy/providers/openrouter"
+	"charm.land/fantasy/tools/fetch"
+	"charm.land/fantasy/tools/fs"
+	"charm.land/fantasy/tools/shell"
+)
+
+// providerConfig describes how to create a provider from the environment.
+type providerConfig struct {
+	name   string
+	keyEnv string
+	new    func(apiKey, baseURL string) (fantasy.Provider, error)
+}
+
+var providers = []providerConfig{
+	{
+		name:   "anthropic",
+		keyEnv: "ANTHROPIC_API_KEY",
+		new: func(apiKey, baseURL string) (fantasy.Provider, error) {
+			opts := []anthropic.Option{anthropic.WithAPIKey(apiKey)}
+			if baseURL != "" {
+				opts = append(opts, anthropic.WithBaseURL(baseURL))
+			}
+			return anthropic.New(opts...)
+		},
+	},
+	{
+		name:   "openai",
+		keyEnv: "OPENAI_API_KEY",
+		new: func(apiKey, baseURL string) (fantasy.Provider, error) {
+			opts := []openai.Option{openai.WithAPIKey(apiKey)}
+			if baseURL != "" {
+				opts = append(opts, openai.WithBaseURL(baseURL))
+			}
+			return openai.New(opts...)
+		},
+	},
+	{
+		name:   "google",
+		keyEnv: "GEMINI_API_KEY",
+		new: func(apiKey, baseURL string) (fantasy.Provider, error) {
+			opts := []google.Option{google.WithGeminiAPIKey(apiKey)}
+			if baseURL != "" {
+				opts = append(opts, google.WithBaseURL(baseURL))
+			}
+			return google.New(opts...)
+		},
+	},
+	{
+		name:   "openrouter",
+		keyEnv: "OPENROUTER_API_KEY",
+		new: func(apiKey, baseURL string) (fantasy.Provider, error) {
+			if baseURL != "" {
+				return nil, errors.New("openrouter doesn't support -base-url")
+			}
+			return openrouter.New(openrouter.WithAPIKey(apiKey))
+		},
+	},
+	{
+		name:   "openaicompat",
+		keyEnv: "OPENAI_COMPAT_API_KEY",
+		new: func(apiKey, baseURL string) (fantasy.Provider, error) {
+			if baseURL == "" {
+				return nil, errors.New("openaicompat needs -base-url")
+			}
+			return openaicompat.New(openaicompat.WithAPIKey(apiKey), openaicompat.WithBaseURL(baseURL))
+		},
+	},
+}
+
+// agentFlags are the flags shared by the commands that run an agent.
+type agentFlags struct {
+	provider string
+	model    string
+	baseURL  string
+	system   string
+	tools    string
+	maxSteps int
+}
+
+func (f *agentFlags) register(flags *flag.FlagSet) {
+	names := make([]string, len(providers))
+	for i, p := range providers {
+		names[i] = p.name
+	}
+	flags.StringVar(&f.provider, "provider", os.Getenv("FANTASY_PROVIDER"), "provider, one of "+strings.Join(names, ", "))
+	flags.StringVar(&f.model, "model", os.Getenv("FANTASY_MODEL"), "model ID")
+	flags.StringVar(&f.baseURL, "base-url", "", "base URL of the provider API")
+	flags.StringVar(&f.system, "system", "", "system prompt")
+	flags.StringVar(&f.tools, "tools", "", "comma-separated tools to enable: shell, fs (read-only) and fetch")
+	flags.IntVar(&f.maxSteps, "max-steps", 0, "maximum number of steps, 0 for the agent default")
+}
+
+// agent creates the agent described by the flags.
+func (f *agentFlags) agent(ctx context.Context) (fantasy.Agent, error) {
+	provider, err := f.newProvider()
+	if err != nil {
+		return nil, err
+	}
+	if f.model == "" {
+		return nil, errors.New("no model: set -model or FANTASY_MODEL")
+	}
+	model, err := provider.LanguageModel(ctx, f.model)
+	if err != nil {
+		return nil, err
+	}
+	tools, err := newTools(f.tools)
+	if err != nil {
+		return nil, err
+	}
+	opts := []fantasy.AgentOption{fantasy.WithTools(tools...)}
+	if f.system != "" {
+		opts = append(opts, fantasy.WithSystemPrompt(f.system))
+	}
+	if f.maxSteps > 0 {
+		opts = append(opts, fantasy.WithStopConditions(fantasy.StepCountIs(f.maxSteps)))
+	}
+	return fantasy.NewAgent(model, opts...), nil
+}
+
+func (f *agentFlags) newProvider() (fantasy.Provider, error) {
+	if f.provider == "" {
+		for _, p := range providers {
+			if os.Getenv(p.keyEnv) != "" {
+				return p.new(os.Getenv(p.keyEnv), f.baseURL)
+			}
+		}
+		return nil, errors.New("no provider: set -provider or FANTASY_PROVIDER, or an API key such as ANTHROPIC_API_KEY")
+	}
+	i := slices.IndexFunc(providers, func(p providerConfig) bool { return p.name == f.provider })
+	if i < 0 {
+		return nil, fmt.Errorf("unknown provider %q", f.provider)
+	}
+	return providers[i].new(os.Getenv(providers[i].keyEnv), f.baseURL)
+}
+
+// newTools creates the tools named in the comma-separated list. They work
+// in the current directory.
+func newTools(list string) ([]fantasy.AgentTool, error) {
+	var tools []fantasy.AgentTool
+	for name := range strings.SplitSeq(list, ",") {
+		switch strings.TrimSpace(name) {
+		case "":
+		case "shell":
+			tools = append(tools, shell.New(shell.Config{}))
+		case "fs":
+			fsTools, err := fs.Tools(fs.Policy{Roots: []string{"."}, ReadOnly: true})
+			if err != nil {
+				return nil, err
+			}
+			tools = append(tools, fsTools...)
+		case "fetch":
+			tools = append(tools, fetch.New(fetch.Config{}))
+		default:
+			return nil, fmt.Errorf("unknown tool %q", name)
+		}
+	}
+	return tools, nil
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"charm.land/fantasy"
+)
+
+func inspectCommand(args []string) error {
+	flags := flag.NewFlagSet("inspect", flag.ExitOnError)
+	format := flags.String("format", "text", "output format: text, mermaid or dot")
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		return fmt.Errorf("inspect takes one file, got %d", flags.NArg())
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+	if path := flags.Arg(0); path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return err
+	}
+	var result fantasy.AgentResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return fmt.Errorf("decoding AgentResult: %w", err)
+	}
+	return inspect(os.Stdout, &result, *format)
+}
+
+// inspect writes the result in the format.
+func inspect(w io.Writer, result *fantasy.AgentResult, format string) error {
+	switch format {
+	case "mermaid":
+		_, err := io.WriteString(w, result.ToMermaid())
+		return err
+	case "dot":
+		_, err := io.WriteString(w, result.ToDOT())
+		return err
+	case "text":
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+
+	for i, step := range result.Steps {
+		fmt.Fprintf(w, "Step %d (%s) · %s\n", i+1, step.FinishReason, usageText(step.Usage))
+		for _, content := range step.Content {
+			switch c := content.(type) {
+			case fantasy.ReasoningContent:
+				fmt.Fprintf(w, "  reasoning: %s\n", truncate(c.Text, 200))
+			case fantasy.TextContent:
+				fmt.Fprintf(w, "  text: %s\n", indent(c.Text))
+			case fantasy.ToolCallContent:
+				fmt.Fprintf(w, "  → %s [%s] %s\n", c.ToolName, c.ToolCallID, c.Input)
+			case fantasy.ToolResultContent:
+				marker := "←"
+				if c.Result != nil && c.Result.GetType() == fantasy.ToolResultContentTypeError {
+					marker = "✗"
+				}
+				fmt.Fprintf(w, "  %s %s [%s] %s", marker, c.ToolName, c.ToolCallID, truncate(toolResultText(c.Result), 200))
+				if c.Duration > 0 {
+					fmt.Fprintf(w, " (%s)", c.Duration)
+				}
+				fmt.Fprintln(w)
+			}
+		}
+		for _, warning := range step.Warnings {
+			fmt.Fprintf(w, "  warning: %s\n", warning.Message)
+		}
+	}
+	fmt.Fprintf(w, "Total · %s\n", usageText(result.TotalUsage))
+	return nil
+}
+
+func usageText(usage fantasy.Usage) string {
+	text := fmt.Sprintf("%d in / %d out tokens", usage.InputTokens, usage.OutputTokens)
+	if usage.CacheReadTokens > 0 {
+		text += fmt.Sprintf(", %d cached", usage.CacheReadTokens)
+	}
+	return text
+}
+
+func toolResultText(output fantasy.ToolResultOutputContent) string {
+	switch o := output.(type) {
+	case fantasy.ToolResultOutputContentText:
+		return o.Text
+	case fantasy.ToolResultOutputContentError:
+		if o.Error != nil {
+			return o.Error.Error()
+		}
+	case fantasy.ToolResultOutputContentMedia:
+		return fmt.Sprintf("<%s>", o.MediaType)
+	}
+	return ""
+}
+
+// truncate shortens text to a single line of at most n runes.
+func truncate(text string, n int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > n {
+		return string(runes[:n]) + "…"
+	}
+	return text
+}
+
+func indent(text string) string {
+	return strings.ReplaceAll(strings.TrimSpace(text), "\n", "\n        ")
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspect(t *testing.T) {
+	t.Parallel()
+
+	recorded := &fantasy.AgentResult{
+		Steps: []fantasy.StepResult{
+			{Response: fantasy.Response{
+				Content: fantasy.ResponseContent{
+					fantasy.ToolCallContent{ToolCallID: "call-1", ToolName: "lookup", Input: `{"q":"dogs"}`},
+					fantasy.ToolResultContent{ToolCallID: "call-1", ToolName: "lookup", Result: fantasy.ToolResultOutputContentText{Text: "found\nmany dogs"}, Duration: time.Second},
+					fantasy.ToolCallContent{ToolCallID: "call-2", ToolName: "fetch", Input: `{}`},
+					fantasy.ToolResultContent{ToolCallID: "call-2", ToolName: "fetch", Result: fantasy.ToolResultOutputContentError{Error: errors.New("404")}},
+				},
+				FinishReason: fantasy.FinishReasonToolCalls,
+				Usage:        fantasy.Usage{InputTokens: 10, OutputTokens: 5},
+			}},
+			{Response: fantasy.Response{
+				Content:      fantasy.ResponseContent{fantasy.TextContent{Text: "Dogs\neverywhere."}},
+				FinishReason: fantasy.FinishReasonStop,
+				Usage:        fantasy.Usage{InputTokens: 20, OutputTokens: 2, CacheReadTokens: 8},
+			}},
+		},
+		TotalUsage: fantasy.Usage{InputTokens: 30, OutputTokens: 7, CacheReadTokens: 8},
+	}
+	data, err := json.Marshal(recorded)
+	require.NoError(t, err)
+	var result fantasy.AgentResult
+	require.NoError(t, json.Unmarshal(data, &result))
+
+	var b strings.Builder
+	require.NoError(t, inspect(&b, &result, "text"))
+	require.Equal(t, `Step 1 (tool-calls) · 10 in / 5 out tokens
+  → lookup [call-1] {"q":"dogs"}
+  ← lookup [call-1] found many dogs (1s)
+  → fetch [call-2] {}
+  ✗ fetch [call-2] 404
+Step 2 (stop) · 20 in / 2 out tokens, 8 cached
+  text: Dogs
+        everywhere.
+Total · 30 in / 7 out tokens, 8 cached
+`, b.String())
+
+	b.Reset()
+	require.NoError(t, inspect(&b, &result, "mermaid"))
+	require.Equal(t, result.ToMermaid(), b.String())
+
+	require.ErrorContains(t, inspect(&b, &result, "yaml"), `unknown format "yaml"`)
+}
+
+func TestNewTools(t *testing.T) {
+	t.Parallel()
+
+	tools, err := newTools("shell, fetch")
+	require.NoError(t, err)
+	require.Len(t, tools, 2)
+	require.Equal(t, "shell", tools[0].Info().Name)
+
+	tools, err = newTools("")
+	require.NoError(t, err)
+	require.Empty(t, tools)
+
+	_, err = newTools("shell,rm")
+	require.ErrorContains(t, err, `unknown tool "rm"`)
+}
@@ -0,0 +1,58 @@
+// Command fantasy runs agents from the command line. It is a debugging aid
+// and an example of the fantasy API.
+//
+// Usage:
+//
+//	fantasy run [flags] <prompt>    run a one-shot prompt
+//	fantasy chat [flags]            start an interactive streaming chat
+//	fantasy inspect [flags] <file>  pretty-print a recorded AgentResult
+//
+// The provider is picked with -provider or FANTASY_PROVIDER, defaulting to
+// the first provider with an API key in the environment, and the model with
+// -model or FANTASY_MODEL. Tools are enabled with -tools, for example
+// -tools shell,fs,fetch.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+)
+
+const usage = `Usage:
+  fantasy run [flags] <prompt>    run a one-shot prompt; reads stdin without a prompt
+  fantasy chat [flags]            start an interactive streaming chat
+  fantasy inspect [flags] <file>  pretty-print a recorded AgentResult; - reads stdin
+
+Run "fantasy <command> -h" for the flags of a command.
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var err error
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "run":
+		err = runCommand(ctx, args)
+	case "chat":
+		err = chatCommand(ctx, args)
+	case "inspect":
+		err = inspectCommand(args)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(os.Stdout, usage)
+	default:
+		fmt.Fprintf(os.Stderr, "fantasy: unknown command %q\n\n%s", cmd, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "fantasy:", err)
+		os.Exit(1)
+	}
+}
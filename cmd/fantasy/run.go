@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"charm.land/fantasy"
+)
+
+func runCommand(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	var (
+		agentFlags agentFlags
+		record     string
+		verbose    bool
+	)
+	agentFlags.register(flags)
+	flags.StringVar(&record, "record", "", "write the AgentResult as JSON to this file, for fantasy inspect")
+	flags.BoolVar(&verbose, "v", false, "print tool calls and usage to stderr")
+	_ = flags.Parse(args)
+
+	prompt := strings.Join(flags.Args(), " ")
+	if prompt == "" {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		prompt = strings.TrimSpace(string(data))
+	}
+	if prompt == "" {
+		return errors.New("no prompt")
+	}
+
+	agent, err := agentFlags.agent(ctx)
+	if err != nil {
+		return err
+	}
+	call := fantasy.AgentStreamCall{Prompt: prompt}
+	if verbose {
+		call.OnToolCall = printToolCall
+		call.OnToolResult = printToolResult
+	}
+	result, err := agent.Stream(ctx, call)
+	if err != nil {
+		return err
+	}
+	fmt.Println(result.Response.Content.Text())
+	if verbose {
+		printUsage(result.TotalUsage)
+	}
+	if record != "" {
+		return writeResult(record, result)
+	}
+	return nil
+}
+
+func chatCommand(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("chat", flag.ExitOnError)
+	var (
+		agentFlags agentFlags
+		record     string
+	)
+	agentFlags.register(flags)
+	flags.StringVar(&record, "record", "", "write the AgentResult of each turn as JSON to this file, overwriting the previous turn")
+	_ = flags.Parse(args)
+
+	agent, err := agentFlags.agent(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(os.Stderr, "Type a message, /reset to start over or /exit to quit.")
+	var history []fantasy.Message
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Fprint(os.Stderr, "> ")
+		if !scanner.Scan() {
+			fmt.Fprintln(os.Stderr)
+			return scanner.Err()
+		}
+		line := strings.TrimSpace(scanner.Text())
+		switch line {
+		case "":
+			continue
+		case "/exit", "/quit":
+			return nil
+		case "/reset":
+			history = nil
+			continue
+		}
+
+		result, err := agent.Stream(ctx, fantasy.AgentStreamCall{
+			Prompt:   line,
+			Messages: history,
+			OnTextDelta: func(id, text string) error {
+				_, err := fmt.Print(text)
+				return err
+			},
+			OnToolCall:   printToolCall,
+			OnToolResult: printToolResult,
+		})
+		fmt.Println()
+		if errors.Is(err, context.Canceled) {
+			return nil
+		}
+		if err != nil {
+			// Keep the conversation going; the turn is dropped from the
+			// history.
+			fmt.Fprintln(os.Stderr, "error:", err)
+			continue
+		}
+		history = append(history, fantasy.NewUserMessage(line))
+		for _, step := range result.Steps {
+			history = append(history, step.Messages...)
+		}
+		if record != "" {
+			if err := writeResult(record, result); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func printToolCall(call fantasy.ToolCallContent) error {
+	fmt.Fprintf(os.Stderr, "\n→ %s %s\n", call.ToolName, call.Input)
+	return nil
+}
+
+func printToolResult(result fantasy.ToolResultContent) error {
+	marker := "←"
+	if result.Result != nil && result.Result.GetType() == fantasy.ToolResultContentTypeError {
+		marker = "✗"
+	}
+	fmt.Fprintf(os.Stderr, "%s %s %s\n", marker, result.ToolName, truncate(toolResultText(result.Result), 200))
+	return nil
+}
+
+func printUsage(usage fantasy.Usage) {
+	fmt.Fprintf(os.Stderr, "tokens: %d in, %d out, %d cached\n", usage.InputTokens, usage.OutputTokens, usage.CacheReadTokens)
+}
+
+func writeResult(path string, result *fantasy.AgentResult) error {
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
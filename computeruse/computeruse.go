@@ -0,0 +1,406 @@
+// Package computeruse drives a screen with a model's computer use tool. The
+// harness runs the actions the model asks for on a Screen, such as a virtual
+// machine or a remote desktop, and answers each of them with a fresh
+// screenshot. Screenshots are downscaled to the size the model works best
+// with and the model's coordinates are mapped back to the screen. Actions
+// are checked before they run, so keys or actions can be denied.
+//
+// Example:
+//
+//	harness := computeruse.New(screen, computeruse.Config{
+//	    SettleDelay: 500 * time.Millisecond,
+//	    DenyKeys:    []string{"ctrl+alt+delete"},
+//	})
+//	agent := fantasy.NewAgent(model,
+//	    fantasy.WithProviderDefinedTools(harness.Tool()),
+//	)
+package computeruse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/anthropic"
+)
+
+// Defaults for the zero values of Config. Anthropic downscales larger
+// images, which makes the coordinates the model answers with drift.
+const (
+	DefaultMaxLongEdge = 1568
+	DefaultMaxPixels   = 1_150_000
+	DefaultWait        = time.Second
+)
+
+// Button is a mouse button.
+type Button int
+
+const (
+	ButtonLeft Button = iota
+	ButtonRight
+	ButtonMiddle
+)
+
+// Screen is the display the harness drives. Points are in screen pixels.
+type Screen interface {
+	// Size returns the size of the screen.
+	Size() image.Point
+	// Screenshot captures the whole screen.
+	Screenshot(ctx context.Context) (image.Image, error)
+	// Click clicks button count times at p, holding the modifier keys, such
+	// as "shift" or "ctrl", if there are any.
+	Click(ctx context.Context, p image.Point, button Button, count int, modifiers string) error
+	// Move moves the pointer to p.
+	Move(ctx context.Context, p image.Point) error
+	// Type types text.
+	Type(ctx context.Context, text string) error
+	// Key presses a key combination, such as "ctrl+c" or "Return".
+	Key(ctx context.Context, keys string) error
+	// Scroll scrolls amount clicks in direction, one of "up", "down",
+	// "left" or "right", at p.
+	Scroll(ctx context.Context, p image.Point, direction string, amount int, modifiers string) error
+}
+
+// Pointer is implemented by screens that can press and release the left
+// mouse button separately. It's needed for dragging.
+type Pointer interface {
+	MouseDown(ctx context.Context, p image.Point) error
+	MouseUp(ctx context.Context, p image.Point) error
+}
+
+// KeyHolder is implemented by screens that can hold a key down.
+type KeyHolder interface {
+	HoldKey(ctx context.Context, key string, d time.Duration) error
+}
+
+// Config configures the harness.
+type Config struct {
+	// ToolVersion is the version of the Anthropic computer use tool.
+	// Defaults to anthropic.ComputerUse20251124, with zoom enabled.
+	ToolVersion anthropic.ComputerUseToolVersion
+	// MaxLongEdge and MaxPixels bound the size of the screenshots sent to
+	// the model. Defaults to DefaultMaxLongEdge and DefaultMaxPixels.
+	MaxLongEdge int
+	MaxPixels   int
+	// SettleDelay is how long to wait after an action before taking the
+	// screenshot, so the screen has time to update. Zero takes it right
+	// away.
+	SettleDelay time.Duration
+	// Actions lists the actions the model may take. When it's empty all
+	// actions are allowed.
+	Actions []anthropic.ComputerAction
+	// DenyKeys lists key combinations the model may not press or hold,
+	// such as "ctrl+alt+delete". The order of the keys and their case
+	// don't matter. Text typed with the type action is checked too: its
+	// control characters count as the keys that produce them, such as
+	// ctrl+c for "\x03" or escape for "\x1b".
+	DenyKeys []string
+	// Check is called before each action runs, with the coordinates in
+	// screen pixels. Returning an error rejects the action, and the model
+	// is told why.
+	Check func(ctx context.Context, input anthropic.ComputerUseInput) error
+}
+
+// Harness runs computer use actions on a Screen.
+type Harness struct {
+	screen Screen
+	config Config
+	// scale is the ratio of the model's display to the screen.
+	scale   float64
+	display image.Point
+
+	mu sync.Mutex
+}
+
+// New returns a harness driving screen.
+func New(screen Screen, config Config) *Harness {
+	if config.ToolVersion == "" {
+		config.ToolVersion = anthropic.ComputerUse20251124
+	}
+	if config.MaxLongEdge <= 0 {
+		config.MaxLongEdge = DefaultMaxLongEdge
+	}
+	if config.MaxPixels <= 0 {
+		config.MaxPixels = DefaultMaxPixels
+	}
+	size := screen.Size()
+	scale := fitScale(size, config.MaxLongEdge, config.MaxPixels)
+	return &Harness{
+		screen:  screen,
+		config:  config,
+		scale:   scale,
+		display: scaleSize(size, scale),
+	}
+}
+
+// DisplaySize returns the size of the display as the model sees it.
+func (h *Harness) DisplaySize() image.Point {
+	return h.display
+}
+
+// Tool returns the computer use tool to give the agent.
+func (h *Harness) Tool() fantasy.ExecutableProviderTool {
+	opts := anthropic.ComputerUseToolOptions{
+		DisplayWidthPx:  int64(h.display.X),
+		DisplayHeightPx: int64(h.display.Y),
+		ToolVersion:     h.config.ToolVersion,
+	}
+	if h.config.ToolVersion == anthropic.ComputerUse20251124 {
+		opts.EnableZoom = new(true)
+	}
+	return anthropic.NewComputerUseTool(opts, h.Run)
+}
+
+// Run runs the action of a computer use tool call and responds with a
+// screenshot of the screen afterwards. Actions that are rejected or fail
+// are reported to the model as error responses. Actions run one at a time.
+func (h *Harness) Run(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+	input, err := anthropic.ParseComputerUseInput(call.Input)
+	if err != nil {
+		return fantasy.NewTextErrorResponse("invalid input: " + err.Error()), nil
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if input, err = h.toScreen(input); err != nil {
+		return fantasy.NewTextErrorResponse(err.Error()), nil
+	}
+	if err := h.check(ctx, input); err != nil {
+		return fantasy.NewTextErrorResponse(fmt.Sprintf("action %s rejected: %v", input.Action, err)), nil
+	}
+	if input.Action == anthropic.ActionZoom {
+		return h.zoom(ctx, input)
+	}
+	if err := h.do(ctx, input); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return fantasy.ToolResponse{}, ctxErr
+		}
+		return fantasy.NewTextErrorResponse(fmt.Sprintf("action %s failed: %v", input.Action, err)), nil
+	}
+	if input.Action != anthropic.ActionScreenshot && h.config.SettleDelay > 0 {
+		if err := sleep(ctx, h.config.SettleDelay); err != nil {
+			return fantasy.ToolResponse{}, err
+		}
+	}
+	return h.screenshot(ctx, image.Rectangle{})
+}
+
+// toScreen maps the coordinates of input from the model's display to the
+// screen, rejecting those outside of the display.
+func (h *Harness) toScreen(input anthropic.ComputerUseInput) (anthropic.ComputerUseInput, error) {
+	point := func(c [2]int64) ([2]int64, error) {
+		if c[0] < 0 || c[1] < 0 || c[0] >= int64(h.display.X) || c[1] >= int64(h.display.Y) {
+			return c, fmt.Errorf("coordinate (%d, %d) is outside of the %dx%d display", c[0], c[1], h.display.X, h.display.Y)
+		}
+		return [2]int64{h.unscale(c[0]), h.unscale(c[1])}, nil
+	}
+	var err error
+	if input.Coordinate, err = point(input.Coordinate); err != nil {
+		return input, err
+	}
+	if input.StartCoordinate, err = point(input.StartCoordinate); err != nil {
+		return input, err
+	}
+	if input.Action == anthropic.ActionZoom {
+		r := input.Region
+		if r[2] <= r[0] || r[3] <= r[1] {
+			return input, fmt.Errorf("region %v is empty", r)
+		}
+		if r[0] < 0 || r[1] < 0 || r[2] > int64(h.display.X) || r[3] > int64(h.display.Y) {
+			return input, fmt.Errorf("region %v is outside of the %dx%d display", r, h.display.X, h.display.Y)
+		}
+		for i := range r {
+			input.Region[i] = h.unscale(r[i])
+		}
+	}
+	return input, nil
+}
+
+func (h *Harness) unscale(v int64) int64 {
+	return int64(float64(v) / h.scale)
+}
+
+// check runs the safety checks of the config on input.
+func (h *Harness) check(ctx context.Context, input anthropic.ComputerUseInput) error {
+	if len(h.config.Actions) > 0 && !slices.Contains(h.config.Actions, input.Action) {
+		return errors.New("action not allowed")
+	}
+	switch input.Action {
+	case anthropic.ActionKey, anthropic.ActionHoldKey:
+		if h.deniedKeys(input.Text) {
+			return fmt.Errorf("key %q not allowed", input.Text)
+		}
+	case anthropic.ActionType:
+		for _, keys := range typedKeys(input.Text) {
+			if h.deniedKeys(keys) {
+				return fmt.Errorf("key %q not allowed in typed text", keys)
+			}
+		}
+	case anthropic.ActionLeftClick, anthropic.ActionRightClick, anthropic.ActionMiddleClick,
+		anthropic.ActionDoubleClick, anthropic.ActionTripleClick, anthropic.ActionScroll:
+		if input.Text != "" && h.deniedKeys(input.Text) {
+			return fmt.Errorf("modifier %q not allowed", input.Text)
+		}
+	}
+	if h.config.Check != nil {
+		return h.config.Check(ctx, input)
+	}
+	return nil
+}
+
+func (h *Harness) deniedKeys(keys string) bool {
+	combo := normalizeKeys(keys)
+	for _, denied := range h.config.DenyKeys {
+		if normalizeKeys(denied) == combo {
+			return true
+		}
+	}
+	return false
+}
+
+// controlKeys are the keys that type control characters other than
+// ctrl+letter.
+var controlKeys = map[rune][]string{
+	'\b':   {"backspace"},
+	'\t':   {"tab"},
+	'\n':   {"return", "enter"},
+	'\r':   {"return", "enter"},
+	'\x1b': {"escape", "esc"},
+	'\x7f': {"delete"},
+}
+
+// typedKeys returns the key combinations that type the control characters
+// of text.
+func typedKeys(text string) []string {
+	var keys []string
+	for _, r := range text {
+		switch {
+		case controlKeys[r] != nil:
+			keys = append(keys, controlKeys[r]...)
+		case r >= 1 && r <= 26:
+			keys = append(keys, "ctrl+"+string('a'+r-1))
+		}
+	}
+	return keys
+}
+
+// normalizeKeys returns a key combination with its keys lowercased and
+// sorted, so equivalent combinations compare equal.
+func normalizeKeys(keys string) string {
+	parts := strings.Split(strings.ToLower(keys), "+")
+	for i, part := range parts {
+		parts[i] = strings.TrimSpace(part)
+	}
+	slices.Sort(parts)
+	return strings.Join(parts, "+")
+}
+
+// do runs input on the screen.
+func (h *Harness) do(ctx context.Context, input anthropic.ComputerUseInput) error {
+	p := image.Pt(int(input.Coordinate[0]), int(input.Coordinate[1]))
+	switch input.Action {
+	case anthropic.ActionScreenshot:
+		return nil
+	case anthropic.ActionLeftClick:
+		return h.screen.Click(ctx, p, ButtonLeft, 1, input.Text)
+	case anthropic.ActionRightClick:
+		return h.screen.Click(ctx, p, ButtonRight, 1, input.Text)
+	case anthropic.ActionMiddleClick:
+		return h.screen.Click(ctx, p, ButtonMiddle, 1, input.Text)
+	case anthropic.ActionDoubleClick:
+		return h.screen.Click(ctx, p, ButtonLeft, 2, input.Text)
+	case anthropic.ActionTripleClick:
+		return h.screen.Click(ctx, p, ButtonLeft, 3, input.Text)
+	case anthropic.ActionMouseMove:
+		return h.screen.Move(ctx, p)
+	case anthropic.ActionType:
+		return h.screen.Type(ctx, input.Text)
+	case anthropic.ActionKey:
+		return h.screen.Key(ctx, input.Text)
+	case anthropic.ActionScroll:
+		return h.screen.Scroll(ctx, p, input.ScrollDirection, int(max(input.ScrollAmount, 1)), input.Text)
+	case anthropic.ActionLeftClickDrag:
+		pointer, ok := h.screen.(Pointer)
+		if !ok {
+			return errors.New("not supported by the screen")
+		}
+		start := image.Pt(int(input.StartCoordinate[0]), int(input.StartCoordinate[1]))
+		if err := h.screen.Move(ctx, start); err != nil {
+			return err
+		}
+		if err := pointer.MouseDown(ctx, start); err != nil {
+			return err
+		}
+		if err := h.screen.Move(ctx, p); err != nil {
+			return errors.Join(err, pointer.MouseUp(ctx, start))
+		}
+		return pointer.MouseUp(ctx, p)
+	case anthropic.ActionLeftMouseDown, anthropic.ActionLeftMouseUp:
+		pointer, ok := h.screen.(Pointer)
+		if !ok {
+			return errors.New("not supported by the screen")
+		}
+		if input.Action == anthropic.ActionLeftMouseDown {
+			return pointer.MouseDown(ctx, p)
+		}
+		return pointer.MouseUp(ctx, p)
+	case anthropic.ActionHoldKey:
+		holder, ok := h.screen.(KeyHolder)
+		if !ok {
+			return errors.New("not supported by the screen")
+		}
+		return holder.HoldKey(ctx, input.Text, time.Duration(input.Duration)*time.Second)
+	case anthropic.ActionWait:
+		wait := DefaultWait
+		if input.Duration > 0 {
+			wait = time.Duration(input.Duration) * time.Second
+		}
+		return sleep(ctx, wait)
+	default:
+		return errors.New("unknown action")
+	}
+}
+
+// zoom responds with the region of input at up to full resolution.
+func (h *Harness) zoom(ctx context.Context, input anthropic.ComputerUseInput) (fantasy.ToolResponse, error) {
+	r := input.Region
+	return h.screenshot(ctx, image.Rect(int(r[0]), int(r[1]), int(r[2]), int(r[3])))
+}
+
+// screenshot responds with a screenshot of region, or the whole screen when
+// region is empty, downscaled to the limits of the config.
+func (h *Harness) screenshot(ctx context.Context, region image.Rectangle) (fantasy.ToolResponse, error) {
+	img, err := h.screen.Screenshot(ctx)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return fantasy.ToolResponse{}, ctxErr
+		}
+		return fantasy.NewTextErrorResponse("screenshot failed: " + err.Error()), nil
+	}
+	if !region.Empty() {
+		img = crop(img, region)
+	}
+	data, err := encode(img, h.config.MaxLongEdge, h.config.MaxPixels)
+	if err != nil {
+		return fantasy.NewTextErrorResponse("screenshot failed: " + err.Error()), nil
+	}
+	return fantasy.NewImageResponse(data, "image/png"), nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,191 @@
+package computeruse
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"testing"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/anthropic"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeScreen struct {
+	size    image.Point
+	actions []string
+}
+
+func (s *fakeScreen) Size() image.Point { return s.size }
+
+func (s *fakeScreen) Screenshot(context.Context) (image.Image, error) {
+	return image.NewRGBA(image.Rectangle{Max: s.size}), nil
+}
+
+func (s *fakeScreen) Click(_ context.Context, p image.Point, button Button, count int, modifiers string) error {
+	s.actions = append(s.actions, fmt.Sprintf("click %v %d %d %s", p, button, count, modifiers))
+	return nil
+}
+
+func (s *fakeScreen) Move(_ context.Context, p image.Point) error {
+	s.actions = append(s.actions, fmt.Sprintf("move %v", p))
+	return nil
+}
+
+func (s *fakeScreen) Type(_ context.Context, text string) error {
+	s.actions = append(s.actions, "type "+text)
+	return nil
+}
+
+func (s *fakeScreen) Key(_ context.Context, keys string) error {
+	s.actions = append(s.actions, "key "+keys)
+	return nil
+}
+
+func (s *fakeScreen) Scroll(_ context.Context, p image.Point, direction string, amount int, _ string) error {
+	s.actions = append(s.actions, fmt.Sprintf("scroll %v %s %d", p, direction, amount))
+	return nil
+}
+
+func run(t *testing.T, h *Harness, input string) fantasy.ToolResponse {
+	t.Helper()
+	resp, err := h.Run(t.Context(), fantasy.ToolCall{ID: "call-1", Name: "computer", Input: input})
+	require.NoError(t, err)
+	return resp
+}
+
+func imageSize(t *testing.T, resp fantasy.ToolResponse) image.Point {
+	t.Helper()
+	require.Equal(t, "image", resp.Type)
+	require.Equal(t, "image/png", resp.MediaType)
+	cfg, err := png.DecodeConfig(bytes.NewReader(resp.Data))
+	require.NoError(t, err)
+	return image.Pt(cfg.Width, cfg.Height)
+}
+
+func TestHarness(t *testing.T) {
+	t.Parallel()
+
+	t.Run("downscales the display", func(t *testing.T) {
+		t.Parallel()
+		h := New(&fakeScreen{size: image.Pt(2560, 1600)}, Config{})
+		display := h.DisplaySize()
+		require.LessOrEqual(t, display.X, DefaultMaxLongEdge)
+		require.LessOrEqual(t, display.X*display.Y, DefaultMaxPixels)
+		require.InDelta(t, 2560.0/1600.0, float64(display.X)/float64(display.Y), 0.01)
+
+		pdt := h.Tool().Definition()
+		require.Equal(t, int64(display.X), pdt.Args["display_width_px"])
+		require.Equal(t, int64(display.Y), pdt.Args["display_height_px"])
+		require.Equal(t, true, pdt.Args["enable_zoom"])
+
+		require.Equal(t, display, imageSize(t, run(t, h, `{"action":"screenshot"}`)))
+	})
+
+	t.Run("keeps small displays", func(t *testing.T) {
+		t.Parallel()
+		h := New(&fakeScreen{size: image.Pt(1024, 768)}, Config{})
+		require.Equal(t, image.Pt(1024, 768), h.DisplaySize())
+	})
+
+	t.Run("maps coordinates to the screen and takes a screenshot", func(t *testing.T) {
+		t.Parallel()
+		screen := &fakeScreen{size: image.Pt(2048, 1536)}
+		h := New(screen, Config{MaxLongEdge: 1024})
+		require.Equal(t, image.Pt(1024, 768), h.DisplaySize())
+
+		resp := run(t, h, `{"action":"left_click","coordinate":[100,50],"text":"shift"}`)
+		require.Equal(t, image.Pt(1024, 768), imageSize(t, resp))
+		run(t, h, `{"action":"scroll","coordinate":[10,10],"scroll_direction":"down","scroll_amount":3}`)
+		run(t, h, `{"action":"type","text":"hello"}`)
+		require.Equal(t, []string{
+			"click (200,100) 0 1 shift",
+			"scroll (20,20) down 3",
+			"type hello",
+		}, screen.actions)
+	})
+
+	t.Run("rejects coordinates outside of the display", func(t *testing.T) {
+		t.Parallel()
+		screen := &fakeScreen{size: image.Pt(800, 600)}
+		h := New(screen, Config{})
+		resp := run(t, h, `{"action":"left_click","coordinate":[800,10]}`)
+		require.True(t, resp.IsError)
+		require.Contains(t, resp.Content, "outside of the 800x600 display")
+		require.Empty(t, screen.actions)
+	})
+
+	t.Run("denies keys and actions", func(t *testing.T) {
+		t.Parallel()
+		screen := &fakeScreen{size: image.Pt(800, 600)}
+		h := New(screen, Config{
+			Actions:  []anthropic.ComputerAction{anthropic.ActionScreenshot, anthropic.ActionKey},
+			DenyKeys: []string{"ctrl+alt+delete"},
+		})
+		resp := run(t, h, `{"action":"key","text":"Delete+ctrl+alt"}`)
+		require.True(t, resp.IsError)
+		require.Contains(t, resp.Content, "not allowed")
+
+		resp = run(t, h, `{"action":"type","text":"rm -rf /"}`)
+		require.True(t, resp.IsError)
+		require.Contains(t, resp.Content, "action type rejected")
+
+		run(t, h, `{"action":"key","text":"Return"}`)
+		require.Equal(t, []string{"key Return"}, screen.actions)
+	})
+
+	t.Run("denies keys in typed text", func(t *testing.T) {
+		t.Parallel()
+		screen := &fakeScreen{size: image.Pt(800, 600)}
+		h := New(screen, Config{DenyKeys: []string{"ctrl+c", "Escape"}})
+		resp := run(t, h, `{"action":"type","text":"stop\u0003"}`)
+		require.True(t, resp.IsError)
+		require.Contains(t, resp.Content, `key "ctrl+c" not allowed`)
+
+		resp = run(t, h, `{"action":"type","text":"\u001b:q!"}`)
+		require.True(t, resp.IsError)
+		require.Contains(t, resp.Content, `key "escape" not allowed`)
+
+		run(t, h, `{"action":"type","text":"ctrl+c\n"}`)
+		require.Equal(t, []string{"type ctrl+c\n"}, screen.actions)
+	})
+
+	t.Run("runs the check", func(t *testing.T) {
+		t.Parallel()
+		screen := &fakeScreen{size: image.Pt(2048, 1536)}
+		var checked anthropic.ComputerUseInput
+		h := New(screen, Config{
+			MaxLongEdge: 1024,
+			Check: func(_ context.Context, input anthropic.ComputerUseInput) error {
+				checked = input
+				return errors.New("needs confirmation")
+			},
+		})
+		resp := run(t, h, `{"action":"left_click","coordinate":[1,2]}`)
+		require.True(t, resp.IsError)
+		require.Contains(t, resp.Content, "needs confirmation")
+		require.Equal(t, [2]int64{2, 4}, checked.Coordinate)
+		require.Empty(t, screen.actions)
+	})
+
+	t.Run("reports unsupported actions", func(t *testing.T) {
+		t.Parallel()
+		h := New(&fakeScreen{size: image.Pt(800, 600)}, Config{})
+		resp := run(t, h, `{"action":"left_click_drag","start_coordinate":[1,1],"coordinate":[5,5]}`)
+		require.True(t, resp.IsError)
+		require.Contains(t, resp.Content, "not supported by the screen")
+	})
+
+	t.Run("zooms into a region", func(t *testing.T) {
+		t.Parallel()
+		h := New(&fakeScreen{size: image.Pt(2048, 1536)}, Config{MaxLongEdge: 1024})
+		resp := run(t, h, `{"action":"zoom","region":[0,0,256,128]}`)
+		require.Equal(t, image.Pt(512, 256), imageSize(t, resp))
+
+		resp = run(t, h, `{"action":"zoom","region":[10,10,5,20]}`)
+		require.True(t, resp.IsError)
+	})
+}
@@ -0,0 +1,54 @@
+package computeruse
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"math"
+
+	"golang.org/x/image/draw"
+)
+
+// fitScale returns the factor, at most 1, that scales size down to fit
+// within maxLongEdge and maxPixels.
+func fitScale(size image.Point, maxLongEdge, maxPixels int) float64 {
+	if size.X <= 0 || size.Y <= 0 {
+		return 1
+	}
+	scale := math.Min(1, float64(maxLongEdge)/float64(max(size.X, size.Y)))
+	if pixels := float64(size.X) * float64(size.Y) * scale * scale; pixels > float64(maxPixels) {
+		scale *= math.Sqrt(float64(maxPixels) / pixels)
+	}
+	return scale
+}
+
+func scaleSize(size image.Point, scale float64) image.Point {
+	return image.Pt(
+		max(1, int(float64(size.X)*scale)),
+		max(1, int(float64(size.Y)*scale)),
+	)
+}
+
+// crop returns the part of img within r.
+func crop(img image.Image, r image.Rectangle) image.Image {
+	r = r.Add(img.Bounds().Min).Intersect(img.Bounds())
+	dst := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	draw.Copy(dst, image.Point{}, img, r, draw.Src, nil)
+	return dst
+}
+
+// encode encodes img as PNG, downscaled to fit within maxLongEdge and
+// maxPixels.
+func encode(img image.Image, maxLongEdge, maxPixels int) ([]byte, error) {
+	size := img.Bounds().Size()
+	if scale := fitScale(size, maxLongEdge, maxPixels); scale < 1 {
+		dst := image.NewRGBA(image.Rectangle{Max: scaleSize(size, scale)})
+		draw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Src, nil)
+		img = dst
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -3,6 +3,7 @@ package fantasy
 import (
 	"context"
 	"encoding/json"
+	"time"
 )
 
 // ProviderOptionsData is an interface for provider-specific options data.
@@ -73,6 +74,25 @@ type ProviderOptionsData interface {
 //	}
 type ProviderMetadata map[string]ProviderOptionsData
 
+// ProviderMetadataOf returns the metadata of type T, such as
+// *openai.ProviderMetadata, and whether there is one. It's looked up under
+// the provider name first, then under the other names, as providers built on
+// another provider's client store their metadata under that provider's name.
+//
+// Providers have typed accessors built on it, such as openai.MetadataFrom.
+func ProviderMetadataOf[T ProviderOptionsData](metadata ProviderMetadata, name string) (T, bool) {
+	if v, ok := metadata[name].(T); ok {
+		return v, true
+	}
+	for _, data := range metadata {
+		if v, ok := data.(T); ok {
+			return v, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
 // ProviderOptions represents additional provider-specific options.
 // Options are additional input to the provider. They are passed through
 // to the provider from the AI SDK and enable provider-specific functionality
@@ -218,8 +238,13 @@ func (r ReasoningPart) Options() ProviderOptions {
 
 // FilePart represents file content in a message.
 type FilePart struct {
-	Filename        string          `json:"filename"`
-	Data            []byte          `json:"data"`
+	Filename string `json:"filename"`
+	Data     []byte `json:"data"`
+	// URL is the location of the file, used instead of Data: an http(s)
+	// URL or a data URL. Models that accept URLs for the media type pass it
+	// to the provider, for others the agent downloads the file. See
+	// ResolveFileURLs.
+	URL             string          `json:"url,omitempty"`
 	MediaType       string          `json:"media_type"`
 	ProviderOptions ProviderOptions `json:"provider_options"`
 }
@@ -259,6 +284,9 @@ type ToolResultPart struct {
 	Output           ToolResultOutputContent `json:"output"`
 	ProviderExecuted bool                    `json:"provider_executed"`
 	ProviderOptions  ProviderOptions         `json:"provider_options"`
+	// Sensitive marks the result as one transcripts redact when they're
+	// exported or persisted. It's sent to the model as is.
+	Sensitive bool `json:"sensitive,omitempty"`
 }
 
 // GetType returns the type of the tool result part.
@@ -409,6 +437,30 @@ const (
 	SourceTypeDocument SourceType = "document"
 )
 
+// Citation is a span of the generated text that is backed by a source.
+//
+// StartIndex and EndIndex are byte offsets into the concatenated text
+// content of the response, so text[StartIndex:EndIndex] yields the cited
+// span. Providers that only report a citation position (rather than a span)
+// set both indexes to that position.
+//
+// Citations are set by the anthropic provider (including on Bedrock and
+// Vertex), the google provider and the openai provider, whose url_citation
+// annotations are also mapped for the providers built on it, such as azure,
+// openaicompat, openrouter, together and vercel, when the backend returns
+// them. Perplexity sets the positions of its numbered markers, such as
+// "[1]", in generated responses but not in streams. The Bedrock Converse
+// API, huggingface, kronk, llamacpp and vllm don't set any.
+type Citation struct {
+	StartIndex int `json:"start_index"`
+	EndIndex   int `json:"end_index"`
+	// Text is the span of the generated text, when known.
+	Text string `json:"text,omitempty"`
+	// CitedText is the passage quoted from the source, when the provider
+	// returns it.
+	CitedText string `json:"cited_text,omitempty"`
+}
+
 // SourceContent represents a source that has been used as input to generate the response.
 type SourceContent struct {
 	SourceType       SourceType       `json:"source_type"` // "url" or "document"
@@ -417,6 +469,7 @@ type SourceContent struct {
 	Title            string           `json:"title"`
 	MediaType        string           `json:"media_type"` // for document sources (IANA media type)
 	Filename         string           `json:"filename"`   // for document sources
+	Citations        []Citation       `json:"citations"`  // spans of the response text that cite this source
 	ProviderMetadata ProviderMetadata `json:"provider_metadata"`
 }
 
@@ -467,6 +520,15 @@ type ToolResultContent struct {
 	// The tool result is still delivered to the model's context, but the model
 	// does not get another chance to make tool calls in the same turn.
 	StopTurn bool `json:"stop_turn,omitempty"`
+	// Duration is how long the agent took to run the tool. It is zero for
+	// provider-executed tools and for calls that never reached a tool.
+	Duration time.Duration `json:"duration,omitempty"`
+	// Sensitive is set when the tool marked its response with
+	// SensitiveToolResult.
+	Sensitive bool `json:"sensitive,omitempty"`
+	// Retries is how many times the agent retried the tool after a
+	// transient error. See WithToolRetry.
+	Retries int `json:"retries,omitempty"`
 }
 
 // GetType returns the type of the tool result content.
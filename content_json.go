@@ -1,9 +1,11 @@
 package fantasy
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 )
 
 // contentJSON is a helper type for JSON serialization of Content in Response.
@@ -30,6 +32,57 @@ type toolJSON struct {
 	Data json.RawMessage `json:"data"`
 }
 
+// PromptFormatVersion is the version of the document written by
+// MarshalPrompt. It changes only when the format changes incompatibly.
+const PromptFormatVersion = 1
+
+// promptJSON is the versioned document written by MarshalPrompt.
+type promptJSON struct {
+	Version  int    `json:"version"`
+	Messages Prompt `json:"messages"`
+}
+
+// MarshalPrompt serializes a prompt into a versioned document for
+// persistence. Provider options, such as reasoning signatures and cache
+// control markers, are kept; their provider packages must be imported when
+// the document is read back so their types are registered.
+func MarshalPrompt(prompt Prompt) ([]byte, error) {
+	if prompt == nil {
+		prompt = Prompt{}
+	}
+	return json.Marshal(promptJSON{
+		Version:  PromptFormatVersion,
+		Messages: prompt,
+	})
+}
+
+// UnmarshalPrompt reads a document written by MarshalPrompt. A plain JSON
+// array of messages is accepted as well.
+func UnmarshalPrompt(data []byte) (Prompt, error) {
+	var prompt Prompt
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &prompt); err != nil {
+			return nil, err
+		}
+		return prompt, nil
+	}
+
+	var doc struct {
+		Version  int             `json:"version"`
+		Messages json.RawMessage `json:"messages"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Version < 1 || doc.Version > PromptFormatVersion {
+		return nil, fmt.Errorf("unsupported prompt format version: %d", doc.Version)
+	}
+	if err := json.Unmarshal(doc.Messages, &prompt); err != nil {
+		return nil, err
+	}
+	return prompt, nil
+}
+
 // MarshalJSON implements json.Marshaler for TextContent.
 func (t TextContent) MarshalJSON() ([]byte, error) {
 	dataBytes, err := json.Marshal(struct {
@@ -187,6 +240,7 @@ func (s SourceContent) MarshalJSON() ([]byte, error) {
 		Title            string           `json:"title,omitempty"`
 		MediaType        string           `json:"media_type,omitempty"`
 		Filename         string           `json:"filename,omitempty"`
+		Citations        []Citation       `json:"citations,omitempty"`
 		ProviderMetadata ProviderMetadata `json:"provider_metadata,omitempty"`
 	}{
 		SourceType:       s.SourceType,
@@ -195,6 +249,7 @@ func (s SourceContent) MarshalJSON() ([]byte, error) {
 		Title:            s.Title,
 		MediaType:        s.MediaType,
 		Filename:         s.Filename,
+		Citations:        s.Citations,
 		ProviderMetadata: s.ProviderMetadata,
 	})
 	if err != nil {
@@ -221,6 +276,7 @@ func (s *SourceContent) UnmarshalJSON(data []byte) error {
 		Title            string                     `json:"title,omitempty"`
 		MediaType        string                     `json:"media_type,omitempty"`
 		Filename         string                     `json:"filename,omitempty"`
+		Citations        []Citation                 `json:"citations,omitempty"`
 		ProviderMetadata map[string]json.RawMessage `json:"provider_metadata,omitempty"`
 	}
 
@@ -234,6 +290,7 @@ func (s *SourceContent) UnmarshalJSON(data []byte) error {
 	s.Title = aux.Title
 	s.MediaType = aux.MediaType
 	s.Filename = aux.Filename
+	s.Citations = aux.Citations
 
 	if len(aux.ProviderMetadata) > 0 {
 		metadata, err := UnmarshalProviderMetadata(aux.ProviderMetadata)
@@ -330,6 +387,9 @@ func (t ToolResultContent) MarshalJSON() ([]byte, error) {
 		ClientMetadata   string                  `json:"client_metadata,omitempty"`
 		ProviderExecuted bool                    `json:"provider_executed"`
 		ProviderMetadata ProviderMetadata        `json:"provider_metadata,omitempty"`
+		Duration         time.Duration           `json:"duration,omitempty"`
+		Sensitive        bool                    `json:"sensitive,omitempty"`
+		Retries          int                     `json:"retries,omitempty"`
 	}{
 		ToolCallID:       t.ToolCallID,
 		ToolName:         t.ToolName,
@@ -337,6 +397,9 @@ func (t ToolResultContent) MarshalJSON() ([]byte, error) {
 		ClientMetadata:   t.ClientMetadata,
 		ProviderExecuted: t.ProviderExecuted,
 		ProviderMetadata: t.ProviderMetadata,
+		Duration:         t.Duration,
+		Sensitive:        t.Sensitive,
+		Retries:          t.Retries,
 	})
 	if err != nil {
 		return nil, err
@@ -362,6 +425,9 @@ func (t *ToolResultContent) UnmarshalJSON(data []byte) error {
 		ClientMetadata   string                     `json:"client_metadata,omitempty"`
 		ProviderExecuted bool                       `json:"provider_executed"`
 		ProviderMetadata map[string]json.RawMessage `json:"provider_metadata,omitempty"`
+		Duration         time.Duration              `json:"duration,omitempty"`
+		Sensitive        bool                       `json:"sensitive,omitempty"`
+		Retries          int                        `json:"retries,omitempty"`
 	}
 
 	if err := json.Unmarshal(cj.Data, &aux); err != nil {
@@ -372,6 +438,9 @@ func (t *ToolResultContent) UnmarshalJSON(data []byte) error {
 	t.ToolName = aux.ToolName
 	t.ClientMetadata = aux.ClientMetadata
 	t.ProviderExecuted = aux.ProviderExecuted
+	t.Duration = aux.Duration
+	t.Sensitive = aux.Sensitive
+	t.Retries = aux.Retries
 
 	// Unmarshal the Result field
 	result, err := UnmarshalToolResultOutputContent(aux.Result)
@@ -597,11 +666,13 @@ func (f FilePart) MarshalJSON() ([]byte, error) {
 	dataBytes, err := json.Marshal(struct {
 		Filename        string          `json:"filename"`
 		Data            []byte          `json:"data"`
+		URL             string          `json:"url,omitempty"`
 		MediaType       string          `json:"media_type"`
 		ProviderOptions ProviderOptions `json:"provider_options,omitempty"`
 	}{
 		Filename:        f.Filename,
 		Data:            f.Data,
+		URL:             f.URL,
 		MediaType:       f.MediaType,
 		ProviderOptions: f.ProviderOptions,
 	})
@@ -625,6 +696,7 @@ func (f *FilePart) UnmarshalJSON(data []byte) error {
 	var aux struct {
 		Filename        string                     `json:"filename"`
 		Data            []byte                     `json:"data"`
+		URL             string                     `json:"url,omitempty"`
 		MediaType       string                     `json:"media_type"`
 		ProviderOptions map[string]json.RawMessage `json:"provider_options,omitempty"`
 	}
@@ -635,6 +707,7 @@ func (f *FilePart) UnmarshalJSON(data []byte) error {
 
 	f.Filename = aux.Filename
 	f.Data = aux.Data
+	f.URL = aux.URL
 	f.MediaType = aux.MediaType
 
 	if len(aux.ProviderOptions) > 0 {
@@ -715,11 +788,13 @@ func (t ToolResultPart) MarshalJSON() ([]byte, error) {
 		Output           ToolResultOutputContent `json:"output"`
 		ProviderExecuted bool                    `json:"provider_executed"`
 		ProviderOptions  ProviderOptions         `json:"provider_options,omitempty"`
+		Sensitive        bool                    `json:"sensitive,omitempty"`
 	}{
 		ToolCallID:       t.ToolCallID,
 		Output:           t.Output,
 		ProviderExecuted: t.ProviderExecuted,
 		ProviderOptions:  t.ProviderOptions,
+		Sensitive:        t.Sensitive,
 	})
 	if err != nil {
 		return nil, err
@@ -743,6 +818,7 @@ func (t *ToolResultPart) UnmarshalJSON(data []byte) error {
 		Output           json.RawMessage            `json:"output"`
 		ProviderExecuted bool                       `json:"provider_executed"`
 		ProviderOptions  map[string]json.RawMessage `json:"provider_options,omitempty"`
+		Sensitive        bool                       `json:"sensitive,omitempty"`
 	}
 
 	if err := json.Unmarshal(mpj.Data, &aux); err != nil {
@@ -751,6 +827,7 @@ func (t *ToolResultPart) UnmarshalJSON(data []byte) error {
 
 	t.ToolCallID = aux.ToolCallID
 	t.ProviderExecuted = aux.ProviderExecuted
+	t.Sensitive = aux.Sensitive
 
 	// Unmarshal the Output field
 	output, err := UnmarshalToolResultOutputContent(aux.Output)
@@ -0,0 +1,168 @@
+package fantasy
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// ErrDebugAborted is returned by agent calls that a debugger controller
+// aborted.
+var ErrDebugAborted = errors.New("aborted by debugger")
+
+// DebugPauseKind is what an agent paused before.
+type DebugPauseKind string
+
+const (
+	// DebugPauseModelCall pauses before a call to the model.
+	DebugPauseModelCall DebugPauseKind = "model_call"
+	// DebugPauseToolCall pauses before the execution of a tool.
+	DebugPauseToolCall DebugPauseKind = "tool_call"
+)
+
+type debugAction int
+
+const (
+	debugStep debugAction = iota
+	debugContinue
+	debugAbort
+)
+
+// DebugPause is an agent paused by a Debugger. The controller may change
+// Call, such as its prompt, or the input of ToolCall, and must then resume
+// the agent with exactly one of Step, Continue or Abort.
+type DebugPause struct {
+	Kind DebugPauseKind
+	// Provider, Model and Call describe model calls. The model is called
+	// with Call as it is when the agent resumes.
+	Provider string
+	Model    string
+	Call     Call
+	// ToolCall describes tool executions. The tool runs with its Input as
+	// it is when the agent resumes.
+	ToolCall ToolCallContent
+
+	resume chan debugAction
+}
+
+// Step resumes the agent and pauses it again before the next model call or
+// tool execution.
+func (p *DebugPause) Step() {
+	p.resume <- debugStep
+}
+
+// Continue resumes the agent without pausing it again until Debugger.Break
+// is called.
+func (p *DebugPause) Continue() {
+	p.resume <- debugContinue
+}
+
+// Abort stops the agent run. At a model call the agent call fails with
+// ErrDebugAborted. At a tool execution the tool doesn't run and the abort
+// is a critical tool error: it ends the run after the current step, and
+// Stream fails with ErrDebugAborted.
+func (p *DebugPause) Abort() {
+	p.resume <- debugAbort
+}
+
+// Debugger pauses agents before each model call and tool execution so that
+// a controller, such as a TUI or an HTTP handler, can inspect and modify
+// what is about to run:
+//
+//	debugger := fantasy.NewDebugger()
+//	agent := fantasy.NewAgent(model, fantasy.WithDebugger(debugger))
+//	go func() {
+//	    for pause := range debugger.Pauses() {
+//	        // Show pause.Call or pause.ToolCall, then:
+//	        pause.Step()
+//	    }
+//	}()
+//
+// A paused agent waits until the controller receives the pause and resumes
+// it, or until the context of the agent call is done. Parallel tools may be
+// paused at the same time.
+type Debugger struct {
+	pauses  chan *DebugPause
+	running atomic.Bool
+}
+
+// NewDebugger creates a debugger that pauses at the first model call.
+func NewDebugger() *Debugger {
+	return &Debugger{pauses: make(chan *DebugPause)}
+}
+
+// Pauses returns the channel the controller receives pauses from.
+func (d *Debugger) Pauses() <-chan *DebugPause {
+	return d.pauses
+}
+
+// Break makes the agent pause again at the next model call or tool
+// execution after Continue.
+func (d *Debugger) Break() {
+	d.running.Store(false)
+}
+
+// WithDebugger makes the agent pause before each model call and tool
+// execution until the debugger's controller resumes it.
+func WithDebugger(debugger *Debugger) AgentOption {
+	return func(s *agentSettings) {
+		s.debugger = debugger
+	}
+}
+
+// pause hands the pause to the controller and waits for it to resume the
+// agent.
+func (d *Debugger) pause(ctx context.Context, pause *DebugPause) error {
+	if d.running.Load() {
+		return nil
+	}
+	// Buffered so that resuming never blocks the controller, even after
+	// the agent stopped waiting.
+	pause.resume = make(chan debugAction, 1)
+	select {
+	case d.pauses <- pause:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case action := <-pause.resume:
+		switch action {
+		case debugContinue:
+			d.running.Store(true)
+		case debugAbort:
+			return ErrDebugAborted
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// debugModelCall pauses before a model call and returns the call to make.
+func (a *agent) debugModelCall(ctx context.Context, model LanguageModel, call Call) (Call, error) {
+	if a.settings.debugger == nil {
+		return call, nil
+	}
+	pause := &DebugPause{
+		Kind:     DebugPauseModelCall,
+		Provider: model.Provider(),
+		Model:    model.Model(),
+		Call:     call,
+	}
+	if err := a.settings.debugger.pause(ctx, pause); err != nil {
+		return call, err
+	}
+	return pause.Call, nil
+}
+
+// debugToolCall pauses before a tool execution and returns the call to run.
+func (a *agent) debugToolCall(ctx context.Context, toolCall ToolCallContent) (ToolCallContent, error) {
+	if a.settings.debugger == nil || toolCall.Invalid {
+		return toolCall, nil
+	}
+	pause := &DebugPause{Kind: DebugPauseToolCall, ToolCall: toolCall}
+	if err := a.settings.debugger.pause(ctx, pause); err != nil {
+		return toolCall, err
+	}
+	return pause.ToolCall, nil
+}
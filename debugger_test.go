@@ -0,0 +1,140 @@
+package fantasy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithDebugger(t *testing.T) {
+	t.Parallel()
+
+	var prompts []Prompt
+	model := toolCallingModel(ToolCallContent{ToolCallID: "call-1", ToolName: "lookup", Input: `{"q":"cats"}`})
+	generate := model.generateFunc
+	model.generateFunc = func(ctx context.Context, call Call) (*Response, error) {
+		prompts = append(prompts, call.Prompt)
+		return generate(ctx, call)
+	}
+	var toolInput string
+	tool := &mockTool{
+		name: "lookup",
+		executeFunc: func(ctx context.Context, call ToolCall) (ToolResponse, error) {
+			toolInput = call.Input
+			return NewTextResponse("found"), nil
+		},
+	}
+	debugger := NewDebugger()
+	agent := NewAgent(model, WithTools(tool), WithDebugger(debugger))
+
+	var pauses []DebugPause
+	go func() {
+		for pause := range debugger.Pauses() {
+			pauses = append(pauses, *pause)
+			switch len(pauses) {
+			case 1:
+				pause.Call.Prompt = Prompt{NewUserMessage("edited")}
+				pause.Step()
+			case 2:
+				pause.ToolCall.Input = `{"q":"dogs"}`
+				pause.Step()
+			default:
+				pause.Continue()
+			}
+		}
+	}()
+
+	result, err := agent.Generate(t.Context(), AgentCall{Prompt: "hello"})
+	require.NoError(t, err)
+	require.Equal(t, "done", result.Response.Content.Text())
+	require.Len(t, pauses, 3)
+	require.Equal(t, DebugPauseModelCall, pauses[0].Kind)
+	require.Equal(t, "mock-provider", pauses[0].Provider)
+	require.Equal(t, "mock-model", pauses[0].Model)
+	require.Equal(t, DebugPauseToolCall, pauses[1].Kind)
+	require.Equal(t, "call-1", pauses[1].ToolCall.ToolCallID)
+	require.Equal(t, DebugPauseModelCall, pauses[2].Kind)
+	require.Equal(t, Prompt{NewUserMessage("edited")}, prompts[0])
+	require.Equal(t, `{"q":"dogs"}`, toolInput)
+
+	// Continued: the next run doesn't pause until Break.
+	model = toolCallingModel()
+	agent = NewAgent(model, WithDebugger(debugger))
+	_, err = agent.Generate(t.Context(), AgentCall{Prompt: "hello"})
+	require.NoError(t, err)
+	require.Len(t, pauses, 3)
+}
+
+func TestWithDebugger_Abort(t *testing.T) {
+	t.Parallel()
+
+	t.Run("model call", func(t *testing.T) {
+		t.Parallel()
+		debugger := NewDebugger()
+		agent := NewAgent(toolCallingModel(), WithDebugger(debugger))
+		go func() {
+			pause := <-debugger.Pauses()
+			pause.Abort()
+		}()
+		_, err := agent.Generate(t.Context(), AgentCall{Prompt: "hello"})
+		require.ErrorIs(t, err, ErrDebugAborted)
+	})
+
+	t.Run("tool call", func(t *testing.T) {
+		t.Parallel()
+		debugger := NewDebugger()
+		ran := false
+		agent := NewAgent(
+			toolCallingModel(ToolCallContent{ToolCallID: "call-1", ToolName: "lookup", Input: `{}`}),
+			WithTools(&mockTool{
+				name: "lookup",
+				executeFunc: func(ctx context.Context, call ToolCall) (ToolResponse, error) {
+					ran = true
+					return NewTextResponse("found"), nil
+				},
+			}),
+			WithDebugger(debugger),
+		)
+		go func() {
+			(<-debugger.Pauses()).Step()
+			(<-debugger.Pauses()).Abort()
+		}()
+		result, err := agent.Generate(t.Context(), AgentCall{Prompt: "hello"})
+		require.NoError(t, err)
+		require.False(t, ran)
+		// Like other critical tool errors, the abort ends Generate after
+		// the step.
+		require.Len(t, result.Steps, 1)
+	})
+}
+
+func TestWithDebugger_Break(t *testing.T) {
+	t.Parallel()
+
+	debugger := NewDebugger()
+	pauses := 0
+	go func() {
+		for pause := range debugger.Pauses() {
+			pauses++
+			pause.Continue()
+		}
+	}()
+	for range 2 {
+		debugger.Break()
+		_, err := NewAgent(toolCallingModel(), WithDebugger(debugger)).Generate(t.Context(), AgentCall{Prompt: "hello"})
+		require.NoError(t, err)
+	}
+	require.Equal(t, 2, pauses)
+}
+
+func TestWithDebugger_ContextDone(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithTimeout(t.Context(), 50*time.Millisecond)
+	defer cancel()
+	agent := NewAgent(toolCallingModel(), WithDebugger(NewDebugger()))
+	_, err := agent.Generate(ctx, AgentCall{Prompt: "hello"})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,156 @@
+package fantasy
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+	"sync"
+)
+
+// RequestGroup coalesces identical concurrent Generate calls into a single
+// upstream request whose response is shared, for servers where many users
+// trigger the same completion at once. Calls are identical when they go to
+// the same provider and model with the same Call, headers included, for the
+// same tenant (see WithTenantID). Only
+// calls in flight at the same time are coalesced; nothing is cached.
+//
+// Every caller receives the usage of the shared request, although it is
+// only billed once. It is safe for concurrent use.
+type RequestGroup struct {
+	mu    sync.Mutex
+	calls map[string]*sharedCall
+}
+
+type sharedCall struct {
+	done    chan struct{}
+	cancel  context.CancelFunc
+	waiters int
+	resp    *Response
+	err     error
+}
+
+// NewRequestGroup creates an empty request group.
+func NewRequestGroup() *RequestGroup {
+	return &RequestGroup{calls: map[string]*sharedCall{}}
+}
+
+// WithRequestDeduplication makes the agent send its Generate calls through
+// the group, so identical calls made at the same time by agents sharing it
+// result in one request. Streams aren't coalesced.
+func WithRequestDeduplication(group *RequestGroup) AgentOption {
+	return func(s *agentSettings) {
+		s.requestGroup = group
+	}
+}
+
+// Generate calls model.Generate, or waits for an identical call already in
+// flight and returns a copy of its response. The upstream request is
+// canceled once every caller waiting for it has given up; a caller whose
+// context is done returns its error without affecting the others.
+func (g *RequestGroup) Generate(ctx context.Context, model LanguageModel, call Call) (*Response, error) {
+	key, err := requestKey(ctx, model, call)
+	if err != nil {
+		// Calls that can't be hashed are never coalesced.
+		return model.Generate(ctx, call)
+	}
+
+	g.mu.Lock()
+	shared, ok := g.calls[key]
+	if !ok {
+		// The request outlives the caller that started it as long as others
+		// are waiting for it.
+		upstreamCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		shared = &sharedCall{done: make(chan struct{}), cancel: cancel}
+		g.calls[key] = shared
+		go g.run(upstreamCtx, key, shared, model, call)
+	}
+	shared.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-shared.done:
+		if shared.err != nil {
+			return nil, shared.err
+		}
+		return copyResponse(shared.resp), nil
+	case <-ctx.Done():
+		g.mu.Lock()
+		shared.waiters--
+		if shared.waiters == 0 {
+			shared.cancel()
+			if g.calls[key] == shared {
+				delete(g.calls, key)
+			}
+		}
+		g.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+func (g *RequestGroup) run(ctx context.Context, key string, shared *sharedCall, model LanguageModel, call Call) {
+	defer shared.cancel()
+	defer close(shared.done)
+	defer func() {
+		if r := recover(); r != nil {
+			shared.err = newPanicError(r)
+		}
+		g.mu.Lock()
+		if g.calls[key] == shared {
+			delete(g.calls, key)
+		}
+		g.mu.Unlock()
+	}()
+	shared.resp, shared.err = model.Generate(ctx, call)
+}
+
+// Model returns a language model whose Generate calls go through the group.
+// Its other methods call model directly, and it has the optional
+// capabilities of model.
+func (g *RequestGroup) Model(model LanguageModel) LanguageModel {
+	return WithCapabilities(&dedupModel{LanguageModel: model, group: g}, model)
+}
+
+type dedupModel struct {
+	LanguageModel
+	group *RequestGroup
+}
+
+// Generate implements LanguageModel.
+func (m *dedupModel) Generate(ctx context.Context, call Call) (*Response, error) {
+	return m.group.Generate(ctx, m.LanguageModel, call)
+}
+
+// requestKey hashes everything that is sent upstream for the call, and the
+// tenant it is sent for, whose model and credentials may differ.
+func requestKey(ctx context.Context, model LanguageModel, call Call) (string, error) {
+	tenant, _ := TenantID(ctx)
+	data, err := json.Marshal(struct {
+		Tenant     string            `json:"tenant"`
+		Provider   string            `json:"provider"`
+		Model      string            `json:"model"`
+		Call       Call              `json:"call"`
+		UserAgent  string            `json:"user_agent"`
+		Headers    map[string]string `json:"headers"`
+		ExtraQuery map[string]string `json:"extra_query"`
+		ExtraBody  map[string]any    `json:"extra_body"`
+	}{tenant, model.Provider(), model.Model(), call, call.UserAgent, call.Headers, call.ExtraQuery, call.ExtraBody})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// copyResponse copies the response so that callers sharing it can modify
+// their content without affecting each other.
+func copyResponse(resp *Response) *Response {
+	if resp == nil {
+		return nil
+	}
+	copied := *resp
+	copied.Content = slices.Clone(resp.Content)
+	copied.Warnings = slices.Clone(resp.Warnings)
+	return &copied
+}
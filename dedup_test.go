@@ -0,0 +1,204 @@
+package fantasy
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestGroup(t *testing.T) {
+	t.Parallel()
+
+	t.Run("coalesces identical calls", func(t *testing.T) {
+		t.Parallel()
+		var calls atomic.Int32
+		release := make(chan struct{})
+		model := &mockLanguageModel{
+			generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+				calls.Add(1)
+				<-release
+				return &Response{Content: []Content{TextContent{Text: call.Prompt[0].Content[0].(TextPart).Text}}}, nil
+			},
+		}
+		group := NewRequestGroup()
+		deduped := group.Model(model)
+
+		const n = 5
+		responses := make([]*Response, n)
+		var wg sync.WaitGroup
+		for i := range n {
+			wg.Go(func() {
+				resp, err := deduped.Generate(t.Context(), Call{Prompt: Prompt{NewUserMessage("hello")}})
+				require.NoError(t, err)
+				responses[i] = resp
+			})
+		}
+		require.Eventually(t, func() bool { return waiters(group) == n }, time.Second, time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		require.Equal(t, int32(1), calls.Load())
+		for _, resp := range responses {
+			require.Equal(t, "hello", resp.Content.Text())
+		}
+		responses[0].Content[0] = TextContent{Text: "changed"}
+		require.Equal(t, "hello", responses[1].Content.Text())
+
+		// Later calls are sent again.
+		_, err := deduped.Generate(t.Context(), Call{Prompt: Prompt{NewUserMessage("hello")}})
+		require.NoError(t, err)
+		require.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("different calls are not coalesced", func(t *testing.T) {
+		t.Parallel()
+		var calls atomic.Int32
+		model := &mockLanguageModel{
+			generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+				calls.Add(1)
+				return &Response{}, nil
+			},
+		}
+		group := NewRequestGroup()
+		_, err := group.Generate(t.Context(), model, Call{Prompt: Prompt{NewUserMessage("hello")}})
+		require.NoError(t, err)
+		_, err = group.Generate(t.Context(), model, Call{Prompt: Prompt{NewUserMessage("hello")}, Headers: map[string]string{"X-User": "1"}})
+		require.NoError(t, err)
+		require.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("different tenants are not coalesced", func(t *testing.T) {
+		t.Parallel()
+		release := make(chan struct{})
+		model := &mockLanguageModel{
+			generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+				<-release
+				tenant, _ := TenantID(ctx)
+				return &Response{Content: []Content{TextContent{Text: tenant}}}, nil
+			},
+		}
+		group := NewRequestGroup()
+
+		tenants := []string{"acme", "globex"}
+		responses := make([]*Response, len(tenants))
+		var wg sync.WaitGroup
+		for i, tenant := range tenants {
+			wg.Go(func() {
+				resp, err := group.Generate(WithTenantID(t.Context(), tenant), model, Call{Prompt: Prompt{NewUserMessage("hello")}})
+				require.NoError(t, err)
+				responses[i] = resp
+			})
+		}
+		require.Eventually(t, func() bool { return waiters(group) == 2 }, time.Second, time.Millisecond)
+		group.mu.Lock()
+		require.Len(t, group.calls, 2)
+		group.mu.Unlock()
+		close(release)
+		wg.Wait()
+
+		require.Equal(t, "acme", responses[0].Content.Text())
+		require.Equal(t, "globex", responses[1].Content.Text())
+	})
+
+	t.Run("canceled caller", func(t *testing.T) {
+		t.Parallel()
+		upstreamCanceled := make(chan struct{})
+		release := make(chan struct{})
+		model := &mockLanguageModel{
+			generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+				select {
+				case <-release:
+					return &Response{Content: []Content{TextContent{Text: "done"}}}, nil
+				case <-ctx.Done():
+					close(upstreamCanceled)
+					return nil, ctx.Err()
+				}
+			},
+		}
+		group := NewRequestGroup()
+		call := Call{Prompt: Prompt{NewUserMessage("hello")}}
+
+		// The caller that started the request gives up, another one gets
+		// the response.
+		ctx, cancel := context.WithCancel(t.Context())
+		first := make(chan error)
+		go func() {
+			_, err := group.Generate(ctx, model, call)
+			first <- err
+		}()
+		require.Eventually(t, func() bool { return waiters(group) == 1 }, time.Second, time.Millisecond)
+		second := make(chan *Response)
+		go func() {
+			resp, err := group.Generate(t.Context(), model, call)
+			require.NoError(t, err)
+			second <- resp
+		}()
+		require.Eventually(t, func() bool { return waiters(group) == 2 }, time.Second, time.Millisecond)
+		cancel()
+		require.ErrorIs(t, <-first, context.Canceled)
+		close(release)
+		require.Equal(t, "done", (<-second).Content.Text())
+
+		// The request is canceled when nobody waits for it anymore.
+		release = make(chan struct{})
+		ctx, cancel = context.WithCancel(t.Context())
+		go func() {
+			_, err := group.Generate(ctx, model, call)
+			first <- err
+		}()
+		require.Eventually(t, func() bool { return waiters(group) == 1 }, time.Second, time.Millisecond)
+		cancel()
+		require.ErrorIs(t, <-first, context.Canceled)
+		<-upstreamCanceled
+	})
+}
+
+func TestWithRequestDeduplication(t *testing.T) {
+	t.Parallel()
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	model := &mockLanguageModel{
+		generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+			calls.Add(1)
+			<-release
+			return &Response{Content: []Content{TextContent{Text: "shared"}}, FinishReason: FinishReasonStop}, nil
+		},
+	}
+	group := NewRequestGroup()
+
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Go(func() {
+			agent := NewAgent(model, WithRequestDeduplication(group))
+			result, err := agent.Generate(t.Context(), AgentCall{Prompt: "hello"})
+			require.NoError(t, err)
+			require.Equal(t, "shared", result.Response.Content.Text())
+		})
+	}
+	require.Eventually(t, func() bool { return waiters(group) == 3 }, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+	require.Equal(t, int32(1), calls.Load())
+}
+
+// waiters returns how many callers wait for requests of the group.
+func waiters(group *RequestGroup) int {
+	group.mu.Lock()
+	defer group.mu.Unlock()
+	var n int
+	for _, shared := range group.calls {
+		n += shared.waiters
+	}
+	return n
+}
+
+func TestRequestGroup_ModelCapabilities(t *testing.T) {
+	t.Parallel()
+
+	requireCapabilities(t, NewRequestGroup().Model(&capableMockModel{}))
+}
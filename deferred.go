@@ -0,0 +1,179 @@
+package fantasy
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CheckStatusToolName is the name of the tool the agent gives the model to
+// check on deferred tool results with DeferredPoll.
+const CheckStatusToolName = "check_status"
+
+// DeferredHandle is a job a tool started that finishes later, such as a CI
+// run or a render. Tools return it with Deferred.
+type DeferredHandle interface {
+	// Poll reports whether the job is done and, if so, its response. While
+	// it isn't, the response may hold a progress message for the model.
+	Poll(ctx context.Context) (resp ToolResponse, done bool, err error)
+}
+
+// Deferred creates a response for a tool whose result comes later, from
+// the job of the handle. What the agent does meanwhile depends on the
+// agent's DeferredMode.
+func Deferred(handle DeferredHandle) ToolResponse {
+	return ToolResponse{Type: "text", Deferred: handle}
+}
+
+// Background runs fn in a goroutine and returns a handle to its result, for
+// tools that return Deferred. fn's context isn't canceled when the run that
+// called the tool ends, so the job can outlive it with DeferredPoll.
+func Background(ctx context.Context, fn func(ctx context.Context) (ToolResponse, error)) DeferredHandle {
+	job := &backgroundJob{done: make(chan struct{})}
+	go func() {
+		defer close(job.done)
+		job.resp, job.err = fn(context.WithoutCancel(ctx))
+	}()
+	return job
+}
+
+type backgroundJob struct {
+	done chan struct{}
+	resp ToolResponse
+	err  error
+}
+
+func (j *backgroundJob) Poll(context.Context) (ToolResponse, bool, error) {
+	select {
+	case <-j.done:
+		return j.resp, true, j.err
+	default:
+		return ToolResponse{}, false, nil
+	}
+}
+
+// Wait waits for the job, so the agent doesn't need to poll it.
+func (j *backgroundJob) Wait(ctx context.Context) (ToolResponse, error) {
+	select {
+	case <-j.done:
+		return j.resp, j.err
+	case <-ctx.Done():
+		return ToolResponse{}, ctx.Err()
+	}
+}
+
+// DeferredMode is what the agent does with the deferred results of tools.
+type DeferredMode int
+
+const (
+	// DeferredWait waits for the result and sends it to the model as the
+	// tool's result. This is the default.
+	DeferredWait DeferredMode = iota
+	// DeferredPoll tells the model the job started and gives it the
+	// check_status tool to get the result later, so it can go on with other
+	// work meanwhile. Jobs are kept by the agent across runs until their
+	// result is checked.
+	DeferredPoll
+)
+
+// DefaultDeferredPollInterval is how often the agent polls a deferred
+// result it waits for.
+const DefaultDeferredPollInterval = time.Second
+
+// WithDeferredTools sets what the agent does with the deferred results of
+// tools. See Deferred.
+func WithDeferredTools(mode DeferredMode) AgentOption {
+	return func(s *agentSettings) {
+		s.deferredMode = mode
+		if mode == DeferredPoll && s.deferredJobs == nil {
+			s.deferredJobs = &deferredJobs{jobs: map[string]DeferredHandle{}}
+			s.tools = append(s.tools, checkStatusTool(s.deferredJobs))
+		}
+	}
+}
+
+// WithDeferredPollInterval sets how often the agent polls a deferred result
+// it waits for. Handles with a Wait(ctx) (ToolResponse, error) method, like
+// the ones of Background, are waited for without polling.
+func WithDeferredPollInterval(interval time.Duration) AgentOption {
+	return func(s *agentSettings) {
+		s.deferredPollInterval = interval
+	}
+}
+
+// deferredJobs are the jobs started by the tools of an agent with
+// DeferredPoll, by ID.
+type deferredJobs struct {
+	mu   sync.Mutex
+	jobs map[string]DeferredHandle
+}
+
+// resolveDeferred returns the response of a tool that returned Deferred:
+// the job's result with DeferredWait, or a message telling the model how to
+// check on the job with DeferredPoll.
+func (a *agent) resolveDeferred(ctx context.Context, call ToolCall, resp ToolResponse) (ToolResponse, error) {
+	handle := resp.Deferred
+	if a.settings.deferredMode == DeferredPoll {
+		jobs := a.settings.deferredJobs
+		jobs.mu.Lock()
+		jobs.jobs[call.ID] = handle
+		jobs.mu.Unlock()
+		return NewTextResponse(fmt.Sprintf("Started job %s, which runs in the background. Go on with other work meanwhile, and call %s with its job_id to get its result.", call.ID, CheckStatusToolName)), nil
+	}
+
+	if waiter, ok := handle.(interface {
+		Wait(ctx context.Context) (ToolResponse, error)
+	}); ok {
+		return waiter.Wait(ctx)
+	}
+	interval := a.settings.deferredPollInterval
+	if interval <= 0 {
+		interval = DefaultDeferredPollInterval
+	}
+	for {
+		resp, done, err := handle.Poll(ctx)
+		if err != nil || done {
+			return resp, err
+		}
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return ToolResponse{}, ctx.Err()
+		}
+	}
+}
+
+type checkStatusInput struct {
+	JobID string `json:"job_id" description:"The ID of the job"`
+}
+
+func checkStatusTool(jobs *deferredJobs) AgentTool {
+	return NewAgentTool(
+		CheckStatusToolName,
+		"Checks on a job started in the background by another tool, and returns its result when it's done.",
+		func(ctx context.Context, input checkStatusInput, _ ToolCall) (ToolResponse, error) {
+			jobs.mu.Lock()
+			handle, ok := jobs.jobs[input.JobID]
+			jobs.mu.Unlock()
+			if !ok {
+				return NewTextErrorResponse(fmt.Sprintf("Unknown job %q.", input.JobID)), nil
+			}
+			resp, done, err := handle.Poll(ctx)
+			if err == nil && !done {
+				if resp.Content == "" {
+					resp.Content = fmt.Sprintf("Job %s is still running.", input.JobID)
+				}
+				resp.Type = "text"
+				return resp, nil
+			}
+			jobs.mu.Lock()
+			delete(jobs.jobs, input.JobID)
+			jobs.mu.Unlock()
+			if err != nil {
+				return NewRetryableErrorResponse(err), nil
+			}
+			return resp, nil
+		},
+	)
+}
@@ -0,0 +1,109 @@
+package fantasy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// pollHandle is done after the given number of polls.
+type pollHandle struct {
+	polls int
+}
+
+func (h *pollHandle) Poll(context.Context) (ToolResponse, bool, error) {
+	h.polls--
+	if h.polls > 0 {
+		return NewTextResponse("rendering"), false, nil
+	}
+	return NewTextResponse("rendered"), true, nil
+}
+
+func TestDeferred_Wait(t *testing.T) {
+	t.Parallel()
+
+	for name, handle := range map[string]func(ctx context.Context) DeferredHandle{
+		"background": func(ctx context.Context) DeferredHandle {
+			return Background(ctx, func(ctx context.Context) (ToolResponse, error) {
+				time.Sleep(10 * time.Millisecond)
+				return NewTextResponse("rendered"), nil
+			})
+		},
+		"poll": func(context.Context) DeferredHandle {
+			return &pollHandle{polls: 3}
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			tool := &mockTool{
+				name: "render",
+				executeFunc: func(ctx context.Context, call ToolCall) (ToolResponse, error) {
+					return Deferred(handle(ctx)), nil
+				},
+			}
+			agent := NewAgent(
+				toolCallingModel(ToolCallContent{ToolCallID: "call-1", ToolName: "render", Input: `{}`}),
+				WithTools(tool),
+				WithDeferredPollInterval(time.Millisecond),
+			)
+			result, err := agent.Generate(t.Context(), AgentCall{Prompt: "render it"})
+			require.NoError(t, err)
+			toolResult := result.Steps[0].Content.ToolResults()[0]
+			require.Equal(t, ToolResultOutputContentText{Text: "rendered"}, toolResult.Result)
+		})
+	}
+}
+
+func TestDeferred_Poll(t *testing.T) {
+	t.Parallel()
+
+	tool := &mockTool{
+		name: "render",
+		executeFunc: func(ctx context.Context, call ToolCall) (ToolResponse, error) {
+			return Deferred(&pollHandle{polls: 2}), nil
+		},
+	}
+	var prompts []Prompt
+	model := &mockLanguageModel{
+		generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+			prompts = append(prompts, call.Prompt)
+			var toolCall ToolCallContent
+			switch len(prompts) {
+			case 1:
+				require.Contains(t, toolNames(call.Tools), CheckStatusToolName)
+				toolCall = ToolCallContent{ToolCallID: "call-1", ToolName: "render", Input: `{}`}
+			case 2, 3:
+				toolCall = ToolCallContent{ToolCallID: NewID(), ToolName: CheckStatusToolName, Input: `{"job_id":"call-1"}`}
+			default:
+				return &Response{Content: ResponseContent{TextContent{Text: "done"}}, FinishReason: FinishReasonStop}, nil
+			}
+			return &Response{Content: ResponseContent{toolCall}, FinishReason: FinishReasonToolCalls}, nil
+		},
+	}
+	a := NewAgent(model, WithTools(tool), WithDeferredTools(DeferredPoll))
+	result, err := a.Generate(t.Context(), AgentCall{Prompt: "render it"})
+	require.NoError(t, err)
+	require.Len(t, result.Steps, 4)
+
+	resultText := func(step int) string {
+		return result.Steps[step].Content.ToolResults()[0].Result.(ToolResultOutputContentText).Text
+	}
+	require.Contains(t, resultText(0), "Started job call-1")
+	require.Equal(t, "rendering", resultText(1))
+	require.Equal(t, "rendered", resultText(2))
+
+	// The job is forgotten once its result was checked.
+	resp, err := a.(*agent).settings.tools[1].Run(t.Context(), ToolCall{Input: `{"job_id":"call-1"}`})
+	require.NoError(t, err)
+	require.True(t, resp.IsError)
+}
+
+func toolNames(tools []Tool) []string {
+	names := make([]string, len(tools))
+	for i, tool := range tools {
+		names[i] = tool.GetName()
+	}
+	return names
+}
@@ -0,0 +1,37 @@
+package fantasy
+
+import "context"
+
+// Embedding is the vector representation of a single input value.
+type Embedding []float32
+
+// EmbeddingCall represents a call to an embedding model.
+type EmbeddingCall struct {
+	// Values are the texts to embed. The response contains one embedding
+	// per value, in the same order.
+	Values []string `json:"values"`
+	// Dimensions truncates the embeddings to the given number of
+	// dimensions, for models that support it.
+	Dimensions *int64 `json:"dimensions"`
+
+	// for provider specific options, the key is the provider id
+	ProviderOptions ProviderOptions `json:"provider_options"`
+}
+
+// EmbeddingResponse represents a response from an embedding model.
+type EmbeddingResponse struct {
+	Embeddings []Embedding   `json:"embeddings"`
+	Usage      Usage         `json:"usage"`
+	Warnings   []CallWarning `json:"warnings"`
+
+	// for provider specific response metadata, the key is the provider id
+	ProviderMetadata ProviderMetadata `json:"provider_metadata"`
+}
+
+// EmbeddingModel represents a model that turns text into embeddings.
+type EmbeddingModel interface {
+	Embed(context.Context, EmbeddingCall) (*EmbeddingResponse, error)
+
+	Provider() string
+	Model() string
+}
@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/x/exp/slice"
 	"golang.org/x/net/http2"
@@ -255,6 +256,33 @@ func (e RetryError) Unwrap() error {
 	return nil
 }
 
+// ToolFatalError is the error of an agent run aborted by a tool with
+// NewFatalErrorResponse.
+type ToolFatalError struct {
+	ToolCallID string
+	ToolName   string
+	Err        error
+}
+
+func (e *ToolFatalError) Error() string {
+	return fmt.Sprintf("tool %s: %v", e.ToolName, e.Err)
+}
+
+// Unwrap returns the tool's error.
+func (e *ToolFatalError) Unwrap() error {
+	return e.Err
+}
+
+// StreamStalledError is returned when a stream produces no part for longer
+// than the idle timeout set with WithStreamIdleTimeout. It is retryable.
+type StreamStalledError struct {
+	Timeout time.Duration
+}
+
+func (e *StreamStalledError) Error() string {
+	return fmt.Sprintf("stream stalled: no data for %s", e.Timeout)
+}
+
 // ErrorTitleForStatusCode returns a human-readable title for a given HTTP status code.
 func ErrorTitleForStatusCode(statusCode int) string {
 	return strings.ToLower(http.StatusText(statusCode))
@@ -0,0 +1,247 @@
+// Package eval runs a dataset of prompts against an agent or a language
+// model, scores the outputs with pluggable graders and reports the results as
+// JSON or JUnit XML.
+//
+// Example:
+//
+//	runner := eval.NewRunner(
+//	    eval.AgentTarget(agent),
+//	    []eval.Grader{eval.Regex(regexp.MustCompile(`(?i)paris`))},
+//	    eval.WithConcurrency(4),
+//	    eval.WithRateLimit(200*time.Millisecond),
+//	)
+//	report, err := runner.Run(ctx, dataset)
+package eval
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"charm.land/fantasy"
+)
+
+// Case is a single evaluation case.
+type Case struct {
+	Name     string            `json:"name"`
+	Prompt   string            `json:"prompt"`
+	Messages []fantasy.Message `json:"messages,omitempty"`
+	// Expected is the reference answer graders compare the output with.
+	Expected string         `json:"expected,omitempty"`
+	Metadata map[string]any `json:"metadata,omitempty"`
+}
+
+// Dataset is an ordered list of evaluation cases.
+type Dataset []Case
+
+// LoadDataset reads a dataset in JSON Lines format, one Case per line. Blank
+// lines are skipped and cases without a name are named after their line.
+func LoadDataset(r io.Reader) (Dataset, error) {
+	var dataset Dataset
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var c Case
+		if err := json.Unmarshal(scanner.Bytes(), &c); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if c.Name == "" {
+			c.Name = fmt.Sprintf("case-%d", line)
+		}
+		dataset = append(dataset, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return dataset, nil
+}
+
+// Output is what a target produced for a case.
+type Output struct {
+	Text  string        `json:"text"`
+	Usage fantasy.Usage `json:"usage"`
+}
+
+// Target produces the output for a case.
+type Target func(ctx context.Context, c Case) (Output, error)
+
+// AgentTarget returns a target that runs each case through the agent.
+func AgentTarget(agent fantasy.Agent) Target {
+	return func(ctx context.Context, c Case) (Output, error) {
+		result, err := agent.Generate(ctx, fantasy.AgentCall{
+			Prompt:   c.Prompt,
+			Messages: c.Messages,
+		})
+		if err != nil {
+			return Output{}, err
+		}
+		return Output{Text: result.Response.Content.Text(), Usage: result.TotalUsage}, nil
+	}
+}
+
+// ModelTarget returns a target that sends each case to the model in a single
+// call.
+func ModelTarget(model fantasy.LanguageModel) Target {
+	return func(ctx context.Context, c Case) (Output, error) {
+		prompt := append(fantasy.Prompt{}, c.Messages...)
+		if c.Prompt != "" {
+			prompt = append(prompt, fantasy.NewUserMessage(c.Prompt))
+		}
+		resp, err := model.Generate(ctx, fantasy.Call{Prompt: prompt})
+		if err != nil {
+			return Output{}, err
+		}
+		return Output{Text: resp.Content.Text(), Usage: resp.Usage}, nil
+	}
+}
+
+// Result is the outcome of a single case.
+type Result struct {
+	Case     Case          `json:"case"`
+	Output   Output        `json:"output"`
+	Grades   []Grade       `json:"grades"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Passed reports whether the target succeeded and every grader passed.
+func (r Result) Passed() bool {
+	if r.Error != "" {
+		return false
+	}
+	for _, g := range r.Grades {
+		if !g.Pass {
+			return false
+		}
+	}
+	return true
+}
+
+// Option configures a Runner.
+type Option = func(*Runner)
+
+// WithConcurrency sets how many cases run at the same time. Defaults to 1.
+func WithConcurrency(n int) Option {
+	return func(r *Runner) {
+		r.concurrency = max(n, 1)
+	}
+}
+
+// WithRateLimit sets the minimum time between the start of two cases,
+// regardless of concurrency.
+func WithRateLimit(interval time.Duration) Option {
+	return func(r *Runner) {
+		r.interval = interval
+	}
+}
+
+// WithOnResult sets a function called as each case finishes, for progress
+// reporting. It may be called concurrently.
+func WithOnResult(fn func(Result)) Option {
+	return func(r *Runner) {
+		r.onResult = fn
+	}
+}
+
+// Runner executes a dataset against a target and grades the outputs.
+type Runner struct {
+	target      Target
+	graders     []Grader
+	concurrency int
+	interval    time.Duration
+	onResult    func(Result)
+}
+
+// NewRunner creates a runner for the target and graders.
+func NewRunner(target Target, graders []Grader, opts ...Option) *Runner {
+	r := &Runner{
+		target:      target,
+		graders:     graders,
+		concurrency: 1,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Run executes every case in the dataset and returns the report. Target and
+// grader failures are recorded on the case rather than aborting the run; Run
+// only returns an error when the context is canceled.
+func (r *Runner) Run(ctx context.Context, dataset Dataset) (*Report, error) {
+	start := time.Now()
+	results := make([]Result, len(dataset))
+
+	var ticker *time.Ticker
+	if r.interval > 0 {
+		ticker = time.NewTicker(r.interval)
+		defer ticker.Stop()
+	}
+
+	sem := make(chan struct{}, r.concurrency)
+	var wg sync.WaitGroup
+	var err error
+dispatch:
+	for i, c := range dataset {
+		if err = ctx.Err(); err != nil {
+			break
+		}
+		if ticker != nil && i > 0 {
+			select {
+			case <-ctx.Done():
+				err = ctx.Err()
+				break dispatch
+			case <-ticker.C:
+			}
+		}
+		select {
+		case <-ctx.Done():
+			err = ctx.Err()
+			break dispatch
+		case sem <- struct{}{}:
+		}
+		wg.Go(func() {
+			defer func() { <-sem }()
+			results[i] = r.runCase(ctx, c)
+			if r.onResult != nil {
+				r.onResult(results[i])
+			}
+		})
+	}
+	wg.Wait()
+	if err != nil {
+		return nil, err
+	}
+	return newReport(results, time.Since(start)), nil
+}
+
+func (r *Runner) runCase(ctx context.Context, c Case) Result {
+	start := time.Now()
+	result := Result{Case: c}
+	output, err := r.target(ctx, c)
+	if err != nil {
+		result.Error = err.Error()
+		result.Duration = time.Since(start)
+		return result
+	}
+	result.Output = output
+	for _, grader := range r.graders {
+		grade, err := grader.Grade(ctx, c, output.Text)
+		if err != nil {
+			grade = Grade{Grader: grader.Name(), Reason: err.Error()}
+		}
+		if grade.Grader == "" {
+			grade.Grader = grader.Name()
+		}
+		result.Grades = append(result.Grades, grade)
+	}
+	result.Duration = time.Since(start)
+	return result
+}
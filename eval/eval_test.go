@@ -0,0 +1,163 @@
+package eval
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/require"
+)
+
+type mockModel struct {
+	generate       func(ctx context.Context, call fantasy.Call) (*fantasy.Response, error)
+	generateObject func(ctx context.Context, call fantasy.ObjectCall) (*fantasy.ObjectResponse, error)
+}
+
+func (m *mockModel) Generate(ctx context.Context, call fantasy.Call) (*fantasy.Response, error) {
+	return m.generate(ctx, call)
+}
+
+func (m *mockModel) Stream(context.Context, fantasy.Call) (fantasy.StreamResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockModel) GenerateObject(ctx context.Context, call fantasy.ObjectCall) (*fantasy.ObjectResponse, error) {
+	return m.generateObject(ctx, call)
+}
+
+func (m *mockModel) StreamObject(context.Context, fantasy.ObjectCall) (fantasy.ObjectStreamResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockModel) Provider() string { return "mock" }
+
+func (m *mockModel) Model() string { return "mock" }
+
+func echoModel() *mockModel {
+	return &mockModel{
+		generate: func(_ context.Context, call fantasy.Call) (*fantasy.Response, error) {
+			prompt := call.Prompt[len(call.Prompt)-1].Content[0].(fantasy.TextPart).Text
+			if prompt == "fail" {
+				return nil, errors.New("boom")
+			}
+			return &fantasy.Response{
+				Content: fantasy.ResponseContent{fantasy.TextContent{Text: strings.ToUpper(prompt)}},
+				Usage:   fantasy.Usage{InputTokens: 1, OutputTokens: 2, TotalTokens: 3},
+			}, nil
+		},
+	}
+}
+
+func TestLoadDataset(t *testing.T) {
+	t.Parallel()
+
+	dataset, err := LoadDataset(strings.NewReader(`{"name": "greeting", "prompt": "hi", "expected": "HI"}
+
+{"prompt": "bye"}
+`))
+	require.NoError(t, err)
+	require.Equal(t, Dataset{
+		{Name: "greeting", Prompt: "hi", Expected: "HI"},
+		{Name: "case-3", Prompt: "bye"},
+	}, dataset)
+
+	_, err = LoadDataset(strings.NewReader("{"))
+	require.ErrorContains(t, err, "line 1")
+}
+
+func TestRunner_Run(t *testing.T) {
+	t.Parallel()
+
+	dataset := Dataset{
+		{Name: "pass", Prompt: "hello", Expected: "HELLO"},
+		{Name: "mismatch", Prompt: "world", Expected: "planet"},
+		{Name: "error", Prompt: "fail"},
+	}
+	var finished atomic.Int32
+	runner := NewRunner(
+		ModelTarget(echoModel()),
+		[]Grader{ExactMatch(), Regex(regexp.MustCompile(`^[A-Z]+$`))},
+		WithConcurrency(3),
+		WithOnResult(func(Result) { finished.Add(1) }),
+	)
+
+	report, err := runner.Run(t.Context(), dataset)
+	require.NoError(t, err)
+	require.Equal(t, int32(3), finished.Load())
+	require.Equal(t, 3, report.Total)
+	require.Equal(t, 1, report.Passed)
+	require.Equal(t, 1, report.Failed)
+	require.Equal(t, 1, report.Errored)
+	require.Equal(t, int64(6), report.Usage.TotalTokens)
+	require.InDelta(t, 1.0/3, report.PassRate(), 0.001)
+
+	require.Equal(t, "pass", report.Results[0].Case.Name)
+	require.True(t, report.Results[0].Passed())
+	require.Equal(t, "WORLD", report.Results[1].Output.Text)
+	require.False(t, report.Results[1].Grades[0].Pass)
+	require.True(t, report.Results[1].Grades[1].Pass)
+	require.Equal(t, "boom", report.Results[2].Error)
+
+	var buf bytes.Buffer
+	require.NoError(t, report.WriteJSON(&buf))
+	var decoded Report
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	require.Equal(t, report.Passed, decoded.Passed)
+
+	buf.Reset()
+	require.NoError(t, report.WriteJUnit(&buf, "echo"))
+	junit := buf.String()
+	require.Contains(t, junit, `<testsuite name="echo" tests="3" failures="1" errors="1"`)
+	require.Contains(t, junit, `<failure message="grading failed">exact-match: expected &#34;planet&#34;, got &#34;WORLD&#34;</failure>`)
+	require.Contains(t, junit, `<error message="boom"></error>`)
+}
+
+func TestRunner_RateLimit(t *testing.T) {
+	t.Parallel()
+
+	var starts []time.Time
+	target := func(_ context.Context, c Case) (Output, error) {
+		starts = append(starts, time.Now())
+		return Output{Text: c.Prompt}, nil
+	}
+	runner := NewRunner(target, nil, WithRateLimit(20*time.Millisecond))
+	_, err := runner.Run(t.Context(), Dataset{{Name: "a"}, {Name: "b"}, {Name: "c"}})
+	require.NoError(t, err)
+	require.Len(t, starts, 3)
+	require.GreaterOrEqual(t, starts[2].Sub(starts[0]), 30*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+	_, err = runner.Run(ctx, Dataset{{Name: "a"}})
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestJudge(t *testing.T) {
+	t.Parallel()
+
+	judgeModel := &mockModel{
+		generateObject: func(_ context.Context, call fantasy.ObjectCall) (*fantasy.ObjectResponse, error) {
+			prompt := call.Prompt[1].Content[0].(fantasy.TextPart).Text
+			require.Contains(t, prompt, "Reference answer:\nParis")
+			require.Contains(t, prompt, "Answer to grade:\nIt is Paris.")
+			require.Equal(t, "object", call.Schema.Type)
+			return &fantasy.ObjectResponse{
+				Object: map[string]any{"pass": true, "score": 1.5, "reason": "correct"},
+			}, nil
+		},
+	}
+
+	grade, err := Judge(judgeModel, "The answer names the right city.").Grade(t.Context(), Case{
+		Prompt:   "What is the capital of France?",
+		Expected: "Paris",
+	}, "It is Paris.")
+	require.NoError(t, err)
+	require.Equal(t, Grade{Pass: true, Score: 1, Reason: "correct"}, grade)
+}
@@ -0,0 +1,100 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/object"
+)
+
+// Grade is a grader's verdict on a case's output.
+type Grade struct {
+	Grader string `json:"grader"`
+	Pass   bool   `json:"pass"`
+	// Score ranges from 0 to 1.
+	Score  float64 `json:"score"`
+	Reason string  `json:"reason,omitempty"`
+}
+
+// Grader scores the output of a case.
+type Grader interface {
+	Name() string
+	Grade(ctx context.Context, c Case, output string) (Grade, error)
+}
+
+type graderFunc struct {
+	name string
+	fn   func(ctx context.Context, c Case, output string) (Grade, error)
+}
+
+// GraderFunc creates a grader from a function.
+func GraderFunc(name string, fn func(ctx context.Context, c Case, output string) (Grade, error)) Grader {
+	return graderFunc{name: name, fn: fn}
+}
+
+func (g graderFunc) Name() string {
+	return g.name
+}
+
+func (g graderFunc) Grade(ctx context.Context, c Case, output string) (Grade, error) {
+	return g.fn(ctx, c, output)
+}
+
+// ExactMatch returns a grader that passes when the output equals the case's
+// expected answer, ignoring surrounding whitespace.
+func ExactMatch() Grader {
+	return GraderFunc("exact-match", func(_ context.Context, c Case, output string) (Grade, error) {
+		if strings.TrimSpace(output) == strings.TrimSpace(c.Expected) {
+			return Grade{Pass: true, Score: 1}, nil
+		}
+		return Grade{Reason: fmt.Sprintf("expected %q, got %q", c.Expected, output)}, nil
+	})
+}
+
+// Regex returns a grader that passes when the output matches the pattern.
+func Regex(pattern *regexp.Regexp) Grader {
+	return GraderFunc("regex", func(_ context.Context, _ Case, output string) (Grade, error) {
+		if pattern.MatchString(output) {
+			return Grade{Pass: true, Score: 1}, nil
+		}
+		return Grade{Reason: fmt.Sprintf("output does not match %s", pattern)}, nil
+	})
+}
+
+type judgement struct {
+	Pass   bool    `json:"pass" description:"Whether the answer satisfies the rubric"`
+	Score  float64 `json:"score" description:"How well the answer satisfies the rubric, from 0 to 1"`
+	Reason string  `json:"reason" description:"A short justification of the verdict"`
+}
+
+// Judge returns a grader that asks the model to grade the output against
+// the rubric, and the case's expected answer when it has one.
+func Judge(model fantasy.LanguageModel, rubric string) Grader {
+	return GraderFunc("judge", func(ctx context.Context, c Case, output string) (Grade, error) {
+		var prompt strings.Builder
+		fmt.Fprintf(&prompt, "Rubric:\n%s\n\nQuestion:\n%s\n\n", rubric, c.Prompt)
+		if c.Expected != "" {
+			fmt.Fprintf(&prompt, "Reference answer:\n%s\n\n", c.Expected)
+		}
+		fmt.Fprintf(&prompt, "Answer to grade:\n%s", output)
+
+		result, err := object.Generate[judgement](ctx, model, fantasy.ObjectCall{
+			Prompt: fantasy.Prompt{
+				fantasy.NewSystemMessage("You are an impartial grader. Grade the answer against the rubric and reply with your verdict."),
+				fantasy.NewUserMessage(prompt.String()),
+			},
+			SchemaName: "verdict",
+		})
+		if err != nil {
+			return Grade{}, err
+		}
+		return Grade{
+			Pass:   result.Object.Pass,
+			Score:  min(max(result.Object.Score, 0), 1),
+			Reason: result.Object.Reason,
+		}, nil
+	})
+}
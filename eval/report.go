@@ -0,0 +1,142 @@
+package eval
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"charm.land/fantasy"
+)
+
+// Report summarizes an evaluation run.
+type Report struct {
+	Results  []Result      `json:"results"`
+	Total    int           `json:"total"`
+	Passed   int           `json:"passed"`
+	Failed   int           `json:"failed"`
+	Errored  int           `json:"errored"`
+	Usage    fantasy.Usage `json:"usage"`
+	Duration time.Duration `json:"duration"`
+}
+
+func newReport(results []Result, duration time.Duration) *Report {
+	report := &Report{
+		Results:  results,
+		Total:    len(results),
+		Duration: duration,
+	}
+	for _, r := range results {
+		switch {
+		case r.Error != "":
+			report.Errored++
+		case r.Passed():
+			report.Passed++
+		default:
+			report.Failed++
+		}
+		report.Usage.InputTokens += r.Output.Usage.InputTokens
+		report.Usage.OutputTokens += r.Output.Usage.OutputTokens
+		report.Usage.TotalTokens += r.Output.Usage.TotalTokens
+		report.Usage.ReasoningTokens += r.Output.Usage.ReasoningTokens
+		report.Usage.CacheCreationTokens += r.Output.Usage.CacheCreationTokens
+		report.Usage.CacheReadTokens += r.Output.Usage.CacheReadTokens
+	}
+	return report
+}
+
+// PassRate returns the fraction of cases that passed.
+func (r *Report) PassRate() float64 {
+	if r.Total == 0 {
+		return 0
+	}
+	return float64(r.Passed) / float64(r.Total)
+}
+
+// WriteJSON writes the report as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Errors   int             `xml:"errors,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the report as JUnit XML, with one test case per
+// evaluation case, so CI systems can display the results.
+func (r *Report) WriteJUnit(w io.Writer, suiteName string) error {
+	suite := junitTestSuite{
+		Name:     suiteName,
+		Tests:    r.Total,
+		Failures: r.Failed,
+		Errors:   r.Errored,
+		Time:     junitTime(r.Duration),
+	}
+	for _, result := range r.Results {
+		tc := junitTestCase{
+			Name:      result.Case.Name,
+			ClassName: suiteName,
+			Time:      junitTime(result.Duration),
+			SystemOut: result.Output.Text,
+		}
+		switch {
+		case result.Error != "":
+			tc.Error = &junitMessage{Message: result.Error}
+		case !result.Passed():
+			var reasons []string
+			for _, g := range result.Grades {
+				if !g.Pass {
+					reasons = append(reasons, fmt.Sprintf("%s: %s", g.Grader, g.Reason))
+				}
+			}
+			tc.Failure = &junitMessage{
+				Message: "grading failed",
+				Body:    strings.Join(reasons, "\n"),
+			}
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func junitTime(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
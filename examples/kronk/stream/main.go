@@ -70,8 +70,8 @@ func run() error {
 	// Clean up when done.
 	defer func() {
 		fmt.Println("\nUnloading Kronk")
-		if closer, ok := provider.(interface{ Close(context.Context) error }); ok {
-			if err := closer.Close(context.Background()); err != nil {
+		if manager, ok := provider.(kronk.Manager); ok {
+			if err := manager.Close(context.Background()); err != nil {
 				fmt.Printf("failed to close provider: %v\n", err)
 			}
 		}
@@ -0,0 +1,160 @@
+// Package extract turns web pages and documents into clean text for
+// prompts. HTML is converted to markdown with its main content kept and page
+// chrome removed, other text is decoded to UTF-8, and formats such as PDF are
+// handled by extractors registered by the application.
+//
+// Example:
+//
+//	doc, err := extract.HTMLToMarkdown(resp.Body, resp.Request.URL)
+//
+//	extractors := extract.NewRegistry()
+//	extractors.Register("application/pdf", extract.ExtractorFunc(pdfToText))
+//	doc, err = extractors.Extract(ctx, r, "application/pdf", nil)
+package extract
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html/charset"
+)
+
+// Document is content extracted as text.
+type Document struct {
+	// Title is the document title, if it has one.
+	Title string `json:"title,omitempty"`
+	// Text is the content, as markdown for HTML.
+	Text string `json:"text"`
+}
+
+// Extractor extracts the text of content of a media type, such as a PDF.
+type Extractor interface {
+	Extract(ctx context.Context, r io.Reader) (Document, error)
+}
+
+// ExtractorFunc adapts a function to an Extractor.
+type ExtractorFunc func(ctx context.Context, r io.Reader) (Document, error)
+
+// Extract implements Extractor.
+func (f ExtractorFunc) Extract(ctx context.Context, r io.Reader) (Document, error) {
+	return f(ctx, r)
+}
+
+// UnsupportedTypeError is returned by Registry.Extract for a media type it
+// has no extractor for.
+type UnsupportedTypeError struct {
+	MediaType string
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return fmt.Sprintf("no extractor for media type %q", e.MediaType)
+}
+
+// Registry maps media types to extractors. HTML and text are supported
+// without registering anything. It is safe for concurrent use.
+type Registry struct {
+	mu         sync.RWMutex
+	extractors map[string]Extractor
+}
+
+// NewRegistry creates a registry with only the built-in HTML and text
+// support.
+func NewRegistry() *Registry {
+	return &Registry{extractors: map[string]Extractor{}}
+}
+
+// Register registers the extractor for the media type, replacing the
+// built-in support or any extractor previously registered for it.
+func (r *Registry) Register(mediaType string, extractor Extractor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.extractors[strings.ToLower(mediaType)] = extractor
+}
+
+// Supports reports whether the registry can extract the media type.
+func (r *Registry) Supports(mediaType string) bool {
+	mediaType = baseMediaType(mediaType)
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.extractors[mediaType]
+	return ok || IsHTML(mediaType) || IsText(mediaType)
+}
+
+// Extract extracts the text of r. contentType is its media type, optionally
+// with a charset parameter as in a Content-Type header; text without a
+// charset is detected. Relative links in HTML are resolved against base,
+// which may be nil. It returns an *UnsupportedTypeError when no extractor
+// handles the media type.
+func (r *Registry) Extract(ctx context.Context, rd io.Reader, contentType string, base *url.URL) (Document, error) {
+	mediaType := baseMediaType(contentType)
+	r.mu.RLock()
+	extractor, ok := r.extractors[mediaType]
+	r.mu.RUnlock()
+	if ok {
+		return extractor.Extract(ctx, rd)
+	}
+
+	switch {
+	case IsHTML(mediaType):
+		text, err := decode(rd, contentType)
+		if err != nil {
+			return Document{}, err
+		}
+		return HTMLToMarkdown(strings.NewReader(text), base)
+	case IsText(mediaType):
+		text, err := decode(rd, contentType)
+		if err != nil {
+			return Document{}, err
+		}
+		return Document{Text: text}, nil
+	}
+	return Document{}, &UnsupportedTypeError{MediaType: mediaType}
+}
+
+// IsHTML reports whether the media type is HTML.
+func IsHTML(mediaType string) bool {
+	mediaType = baseMediaType(mediaType)
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
+
+// IsText reports whether the media type is text that can be used as is,
+// such as plain text, markdown, JSON or XML.
+func IsText(mediaType string) bool {
+	mediaType = baseMediaType(mediaType)
+	return strings.HasPrefix(mediaType, "text/") ||
+		strings.HasSuffix(mediaType, "+json") ||
+		strings.HasSuffix(mediaType, "+xml") ||
+		slices.Contains([]string{"application/json", "application/xml", "application/javascript", "application/x-ndjson", "application/yaml"}, mediaType)
+}
+
+func baseMediaType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(contentType))
+	}
+	return mediaType
+}
+
+// decode reads r as UTF-8 text according to the charset of contentType,
+// detecting it if there is none.
+func decode(r io.Reader, contentType string) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	decoder, err := charset.NewReader(strings.NewReader(string(data)), contentType)
+	if err != nil {
+		return string(data), nil
+	}
+	text, err := io.ReadAll(decoder)
+	if err != nil {
+		return "", err
+	}
+	return string(text), nil
+}
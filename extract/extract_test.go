@@ -0,0 +1,76 @@
+package extract
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testPage = `<!DOCTYPE html>
+<html>
+<head><title>Fantasy  docs</title><style>body { color: red }</style></head>
+<body>
+<nav><a href="/">Home</a></nav>
+<main>
+<h1>Getting started</h1>
+<p>Fantasy is a <strong>Go</strong> library for <a href="/agents">building agents</a>.</p>
+<ul><li>Fast</li><li>Simple<ul><li>Nested</li></ul></li></ul>
+<pre><code>go get charm.land/fantasy
+</code></pre>
+<blockquote><p>Quoted</p></blockquote>
+<table><tr><th>Name</th><th>Value</th></tr><tr><td>a</td><td>1</td></tr></table>
+<script>alert("hi")</script>
+</main>
+<footer>Copyright</footer>
+</body>
+</html>`
+
+func TestHTMLToMarkdown(t *testing.T) {
+	t.Parallel()
+
+	base, _ := url.Parse("https://example.com/docs/")
+	doc, err := HTMLToMarkdown(strings.NewReader(testPage), base)
+	require.NoError(t, err)
+	require.Equal(t, "Fantasy docs", doc.Title)
+	require.Equal(t, "# Getting started\n\n"+
+		"Fantasy is a **Go** library for [building agents](https://example.com/agents).\n\n"+
+		"- Fast\n- Simple\n\n  - Nested\n\n"+
+		"```\ngo get charm.land/fantasy\n```\n\n"+
+		"> Quoted\n\n"+
+		"| Name | Value |\n| --- | --- |\n| a | 1 |", doc.Text)
+}
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	r := NewRegistry()
+	require.True(t, r.Supports("text/html; charset=utf-8"))
+	require.True(t, r.Supports("application/vnd.api+json"))
+	require.False(t, r.Supports("application/pdf"))
+
+	doc, err := r.Extract(t.Context(), strings.NewReader("<p>caf\xe9</p>"), "text/html; charset=iso-8859-1", nil)
+	require.NoError(t, err)
+	require.Equal(t, "café", doc.Text)
+
+	doc, err = r.Extract(t.Context(), strings.NewReader(`{"a":1}`), "application/json", nil)
+	require.NoError(t, err)
+	require.Equal(t, `{"a":1}`, doc.Text)
+
+	_, err = r.Extract(t.Context(), strings.NewReader("%PDF"), "application/pdf", nil)
+	var unsupported *UnsupportedTypeError
+	require.True(t, errors.As(err, &unsupported))
+	require.Equal(t, "application/pdf", unsupported.MediaType)
+
+	r.Register("application/pdf", ExtractorFunc(func(ctx context.Context, r io.Reader) (Document, error) {
+		return Document{Title: "Report", Text: "page one"}, nil
+	}))
+	require.True(t, r.Supports("application/pdf"))
+	doc, err = r.Extract(t.Context(), strings.NewReader("%PDF"), "application/pdf", nil)
+	require.NoError(t, err)
+	require.Equal(t, Document{Title: "Report", Text: "page one"}, doc)
+}
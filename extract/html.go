@@ -0,0 +1,306 @@
+package extract
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+var (
+	whitespace    = regexp.MustCompile(`\s+`)
+	extraNewlines = regexp.MustCompile(`\n{3,}`)
+)
+
+// skippedElements hold no readable content, or only page chrome such as
+// navigation.
+var skippedElements = map[atom.Atom]bool{
+	atom.Head: true, atom.Script: true, atom.Style: true, atom.Noscript: true,
+	atom.Template: true, atom.Svg: true, atom.Canvas: true, atom.Iframe: true,
+	atom.Nav: true, atom.Header: true, atom.Footer: true, atom.Aside: true,
+	atom.Form: true, atom.Button: true, atom.Input: true, atom.Select: true,
+	atom.Textarea: true, atom.Dialog: true,
+}
+
+var blockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true,
+	atom.Main: true, atom.Body: true, atom.Figure: true, atom.Figcaption: true,
+	atom.Dl: true, atom.Dt: true, atom.Dd: true, atom.Address: true,
+	atom.Details: true, atom.Summary: true, atom.Center: true,
+}
+
+// HTMLToMarkdown extracts the title and the main content of an HTML page as
+// markdown. Scripts, styles, navigation, headers, footers and forms are left
+// out, and when the page has a main or article element only its content is
+// kept. Relative links are resolved against base, which may be nil.
+func HTMLToMarkdown(r io.Reader, base *url.URL) (Document, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return Document{}, err
+	}
+	var title string
+	if n := findElement(doc, atom.Title); n != nil {
+		title = strings.TrimSpace(whitespace.ReplaceAllString(textContent(n), " "))
+	}
+	root := findElement(doc, atom.Main)
+	if root == nil {
+		root = findElement(doc, atom.Article)
+	}
+	if root == nil {
+		root = doc
+	}
+
+	c := &converter{base: base}
+	c.node(root)
+	return Document{Title: title, Text: c.String()}, nil
+}
+
+type converter struct {
+	sb       strings.Builder
+	base     *url.URL
+	newlines int
+}
+
+func (c *converter) String() string {
+	lines := strings.Split(c.sb.String(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.TrimSpace(extraNewlines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+func (c *converter) write(s string) {
+	if s == "" {
+		return
+	}
+	c.sb.WriteString(s)
+	if trimmed := strings.TrimRight(s, "\n"); trimmed == "" {
+		c.newlines += len(s)
+	} else {
+		c.newlines = len(s) - len(trimmed)
+	}
+}
+
+func (c *converter) atLineStart() bool {
+	return c.sb.Len() == 0 || c.newlines > 0
+}
+
+// blankLine ends the current paragraph.
+func (c *converter) blankLine() {
+	if c.sb.Len() == 0 {
+		return
+	}
+	for c.newlines < 2 {
+		c.write("\n")
+	}
+}
+
+func (c *converter) block(s string) {
+	if s == "" {
+		return
+	}
+	c.blankLine()
+	c.write(s)
+	c.blankLine()
+}
+
+func (c *converter) text(s string) {
+	s = whitespace.ReplaceAllString(s, " ")
+	if c.atLineStart() || strings.HasSuffix(c.sb.String(), " ") {
+		s = strings.TrimLeft(s, " ")
+	}
+	c.write(s)
+}
+
+// render converts the children of n on their own.
+func (c *converter) render(n *html.Node) string {
+	sub := &converter{base: c.base}
+	sub.children(n)
+	return sub.String()
+}
+
+// inline converts the children of n to a single line.
+func (c *converter) inline(n *html.Node) string {
+	return strings.TrimSpace(whitespace.ReplaceAllString(c.render(n), " "))
+}
+
+func (c *converter) children(n *html.Node) {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		c.node(child)
+	}
+}
+
+func (c *converter) node(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		c.text(n.Data)
+		return
+	case html.ElementNode:
+	case html.DocumentNode:
+		c.children(n)
+		return
+	default:
+		return
+	}
+	if skippedElements[n.DataAtom] {
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		if text := c.inline(n); text != "" {
+			c.block(strings.Repeat("#", level) + " " + text)
+		}
+	case atom.Br:
+		c.write("\n")
+	case atom.Hr:
+		c.block("---")
+	case atom.Pre:
+		c.block("```\n" + strings.Trim(textContent(n), "\n") + "\n```")
+	case atom.Code, atom.Kbd, atom.Samp:
+		if text := textContent(n); text != "" {
+			c.text("`" + text + "`")
+		}
+	case atom.Strong, atom.B:
+		c.wrap(n, "**")
+	case atom.Em, atom.I:
+		c.wrap(n, "*")
+	case atom.A:
+		text := c.inline(n)
+		href := c.resolve(attr(n, "href"))
+		if href == "" || text == "" {
+			c.text(text)
+			return
+		}
+		c.text(fmt.Sprintf("[%s](%s)", text, href))
+	case atom.Img:
+		if src := c.resolve(attr(n, "src")); src != "" {
+			c.text(fmt.Sprintf("![%s](%s)", attr(n, "alt"), src))
+		}
+	case atom.Ul, atom.Ol:
+		c.list(n)
+	case atom.Blockquote:
+		lines := strings.Split(c.render(n), "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimRight("> "+line, " ")
+		}
+		c.block(strings.Join(lines, "\n"))
+	case atom.Table:
+		c.table(n)
+	default:
+		if blockElements[n.DataAtom] {
+			c.blankLine()
+			c.children(n)
+			c.blankLine()
+			return
+		}
+		c.children(n)
+	}
+}
+
+func (c *converter) wrap(n *html.Node, marker string) {
+	if text := c.inline(n); text != "" {
+		c.text(marker + text + marker)
+	}
+}
+
+func (c *converter) list(n *html.Node) {
+	var items []string
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.ElementNode || child.DataAtom != atom.Li {
+			continue
+		}
+		marker := "- "
+		if n.DataAtom == atom.Ol {
+			marker = fmt.Sprintf("%d. ", len(items)+1)
+		}
+		lines := strings.Split(c.render(child), "\n")
+		for i := 1; i < len(lines); i++ {
+			if lines[i] != "" {
+				lines[i] = strings.Repeat(" ", len(marker)) + lines[i]
+			}
+		}
+		items = append(items, marker+strings.Join(lines, "\n"))
+	}
+	c.block(strings.Join(items, "\n"))
+}
+
+func (c *converter) table(n *html.Node) {
+	var rows []string
+	var visit func(*html.Node)
+	visit = func(n *html.Node) {
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if child.Type != html.ElementNode {
+				continue
+			}
+			if child.DataAtom != atom.Tr {
+				visit(child)
+				continue
+			}
+			var cells []string
+			for cell := child.FirstChild; cell != nil; cell = cell.NextSibling {
+				if cell.Type == html.ElementNode && (cell.DataAtom == atom.Td || cell.DataAtom == atom.Th) {
+					cells = append(cells, strings.ReplaceAll(c.inline(cell), "|", `\|`))
+				}
+			}
+			rows = append(rows, "| "+strings.Join(cells, " | ")+" |")
+			if len(rows) == 1 {
+				rows = append(rows, strings.Repeat("| --- ", len(cells))+"|")
+			}
+		}
+	}
+	visit(n)
+	c.block(strings.Join(rows, "\n"))
+}
+
+func (c *converter) resolve(ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.HasPrefix(ref, "#") || strings.HasPrefix(strings.ToLower(ref), "javascript:") {
+		return ""
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	if c.base != nil {
+		u = c.base.ResolveReference(u)
+	}
+	return u.String()
+}
+
+func findElement(n *html.Node, a atom.Atom) *html.Node {
+	if n.Type == html.ElementNode && n.DataAtom == a {
+		return n
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if found := findElement(child, a); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var sb strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		sb.WriteString(textContent(child))
+	}
+	return sb.String()
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,140 @@
+package fantasytest
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"charm.land/fantasy"
+)
+
+// RequireTextsPreserved fails the test unless every text of the prompt is
+// found in the converted prompt: the text of system, user and assistant
+// messages and of tool results, and the message of tool errors. Reasoning is
+// not checked, as providers drop reasoning they can't send back.
+//
+// The converted prompt can be any value, typically the messages in the
+// provider's API types. Its strings are collected recursively, including
+// unexported fields.
+func RequireTextsPreserved(t testing.TB, prompt fantasy.Prompt, converted any) {
+	t.Helper()
+	strs := Strings(converted)
+	for _, text := range Texts(prompt) {
+		if !containsSubstring(strs, text) {
+			t.Fatalf("fantasytest: text %q is missing from the converted prompt", text)
+		}
+	}
+}
+
+// RequireToolCallIDsPreserved fails the test unless the ID of every tool
+// call and tool result of the prompt is found in the converted prompt.
+func RequireToolCallIDsPreserved(t testing.TB, prompt fantasy.Prompt, converted any) {
+	t.Helper()
+	strs := Strings(converted)
+	for _, id := range ToolCallIDs(prompt) {
+		if !containsSubstring(strs, id) {
+			t.Fatalf("fantasytest: tool call ID %q is missing from the converted prompt", id)
+		}
+	}
+}
+
+// Texts returns the texts of the prompt that a conversion must keep, as
+// checked by RequireTextsPreserved.
+func Texts(prompt fantasy.Prompt) []string {
+	var texts []string
+	for _, msg := range prompt {
+		for _, part := range msg.Content {
+			switch part := part.(type) {
+			case fantasy.TextPart:
+				texts = append(texts, part.Text)
+			case fantasy.ToolResultPart:
+				switch output := part.Output.(type) {
+				case fantasy.ToolResultOutputContentText:
+					texts = append(texts, output.Text)
+				case fantasy.ToolResultOutputContentError:
+					if output.Error != nil {
+						texts = append(texts, output.Error.Error())
+					}
+				case fantasy.ToolResultOutputContentMedia:
+					texts = append(texts, output.Text)
+				}
+			}
+		}
+	}
+	return nonEmpty(texts)
+}
+
+// ToolCallIDs returns the IDs of the tool calls of the prompt, in order.
+func ToolCallIDs(prompt fantasy.Prompt) []string {
+	var ids []string
+	for _, msg := range prompt {
+		for _, part := range msg.Content {
+			if call, ok := fantasy.AsMessagePart[fantasy.ToolCallPart](part); ok {
+				ids = append(ids, call.ToolCallID)
+			}
+		}
+	}
+	return ids
+}
+
+// Strings returns the strings found in v, walking structs, pointers,
+// interfaces, slices and maps.
+func Strings(v any) []string {
+	var strs []string
+	seen := map[uintptr]bool{}
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		switch v.Kind() {
+		case reflect.String:
+			strs = append(strs, v.String())
+		case reflect.Pointer:
+			if v.IsNil() || seen[v.Pointer()] {
+				return
+			}
+			seen[v.Pointer()] = true
+			walk(v.Elem())
+		case reflect.Interface:
+			if !v.IsNil() {
+				walk(v.Elem())
+			}
+		case reflect.Struct:
+			for i := range v.NumField() {
+				walk(v.Field(i))
+			}
+		case reflect.Slice, reflect.Array:
+			if v.Type().Elem().Kind() == reflect.Uint8 {
+				return
+			}
+			for i := range v.Len() {
+				walk(v.Index(i))
+			}
+		case reflect.Map:
+			iter := v.MapRange()
+			for iter.Next() {
+				walk(iter.Key())
+				walk(iter.Value())
+			}
+		}
+	}
+	walk(reflect.ValueOf(v))
+	return strs
+}
+
+func containsSubstring(strs []string, substr string) bool {
+	for _, s := range strs {
+		if strings.Contains(s, substr) {
+			return true
+		}
+	}
+	return false
+}
+
+func nonEmpty(strs []string) []string {
+	out := strs[:0]
+	for _, s := range strs {
+		if s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
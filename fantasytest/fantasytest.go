@@ -0,0 +1,300 @@
+// Package fantasytest generates random, valid calls for property-based tests
+// of providers, and checks that a provider's conversion of a prompt to its
+// API format keeps the content of the prompt.
+//
+// A provider tests its prompt conversion against many generated prompts:
+//
+//	func TestToPrompt_Properties(t *testing.T) {
+//	    fantasytest.ForAll(t, 200, func(t *testing.T, call fantasy.Call) {
+//	        messages, _ := toPrompt(call.Prompt)
+//	        fantasytest.RequireTextsPreserved(t, call.Prompt, messages)
+//	        fantasytest.RequireToolCallIDsPreserved(t, call.Prompt, messages)
+//	    })
+//	}
+//
+// The calls are random but reproducible: a failing case logs the seed, and
+// setting FANTASYTEST_SEED to it generates the same calls again.
+package fantasytest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"charm.land/fantasy"
+)
+
+// SeedEnv is the environment variable that sets the seed of the generated
+// calls.
+const SeedEnv = "FANTASYTEST_SEED"
+
+// Generator generates random, valid calls. The prompts start with an
+// optional system message and alternate user and assistant messages, each
+// tool call followed by a tool message with its result, and end with a user
+// or tool message so they are ready for a new generation.
+type Generator struct {
+	rng *rand.Rand
+	// ids numbers the texts and IDs so they are unique within a call.
+	ids int
+}
+
+// NewGenerator creates a generator. Generators with the same seed generate
+// the same calls.
+func NewGenerator(seed uint64) *Generator {
+	return &Generator{rng: rand.New(rand.NewPCG(seed, seed))}
+}
+
+// GenerateCall returns a random call, logging the seed to reproduce it if
+// the test fails.
+func GenerateCall(t testing.TB) fantasy.Call {
+	t.Helper()
+	seed := Seed(t)
+	return NewGenerator(seed).Call()
+}
+
+// ForAll runs check as a subtest for n random calls. Each failing case logs
+// how to reproduce it.
+func ForAll(t *testing.T, n int, check func(t *testing.T, call fantasy.Call)) {
+	t.Helper()
+	base := Seed(t)
+	for i := range n {
+		seed := base + uint64(i)
+		t.Run(fmt.Sprintf("case-%d", i), func(t *testing.T) {
+			call := NewGenerator(seed).Call()
+			t.Cleanup(func() {
+				if t.Failed() {
+					data, _ := json.MarshalIndent(call, "", "  ")
+					t.Logf("call:\n%s", data)
+				}
+			})
+			check(t, call)
+		})
+	}
+}
+
+// Seed returns the seed set with FANTASYTEST_SEED, or a random one. It logs
+// the seed if the test fails.
+func Seed(t testing.TB) uint64 {
+	t.Helper()
+	seed := rand.Uint64()
+	if env := os.Getenv(SeedEnv); env != "" {
+		parsed, err := strconv.ParseUint(env, 10, 64)
+		if err != nil {
+			t.Fatalf("fantasytest: invalid %s: %v", SeedEnv, err)
+		}
+		seed = parsed
+	}
+	t.Cleanup(func() {
+		if t.Failed() {
+			t.Logf("fantasytest: reproduce with %s=%d", SeedEnv, seed)
+		}
+	})
+	return seed
+}
+
+// Call returns a random call with a prompt, tools for the tool calls of the
+// prompt and random settings.
+func (g *Generator) Call() fantasy.Call {
+	prompt := g.Prompt()
+	call := fantasy.Call{Prompt: prompt, Tools: g.tools(prompt)}
+	if g.chance(0.5) {
+		n := int64(g.rng.IntN(4096) + 1)
+		call.MaxOutputTokens = &n
+	}
+	if g.chance(0.5) {
+		temperature := float64(g.rng.IntN(11)) / 10
+		call.Temperature = &temperature
+	}
+	if g.chance(0.3) {
+		topP := float64(g.rng.IntN(10)+1) / 10
+		call.TopP = &topP
+	}
+	if len(call.Tools) > 0 && g.chance(0.5) {
+		choices := []fantasy.ToolChoice{fantasy.ToolChoiceAuto, fantasy.ToolChoiceRequired, fantasy.ToolChoiceNone, fantasy.SpecificToolChoice(call.Tools[0].GetName())}
+		choice := choices[g.rng.IntN(len(choices))]
+		call.ToolChoice = &choice
+	}
+	return call
+}
+
+// Prompt returns a random prompt.
+func (g *Generator) Prompt() fantasy.Prompt {
+	var prompt fantasy.Prompt
+	if g.chance(0.6) {
+		prompt = append(prompt, fantasy.NewSystemMessage(g.text()))
+	}
+	turns := g.rng.IntN(4) + 1
+	for turn := range turns {
+		prompt = append(prompt, g.userMessage())
+		last := turn == turns-1
+		rounds := g.rng.IntN(3)
+		for range rounds {
+			calls := g.rng.IntN(2) + 1
+			assistant, ids := g.toolCallMessage(calls)
+			prompt = append(prompt, assistant, g.toolMessage(ids))
+		}
+		if !last || (rounds > 0 && g.chance(0.5)) {
+			prompt = append(prompt, g.assistantMessage())
+			if last {
+				prompt = append(prompt, g.userMessage())
+			}
+		}
+	}
+	return prompt
+}
+
+func (g *Generator) userMessage() fantasy.Message {
+	msg := fantasy.Message{Role: fantasy.MessageRoleUser}
+	for range g.rng.IntN(3) + 1 {
+		if g.chance(0.2) {
+			msg.Content = append(msg.Content, fantasy.FilePart{
+				Filename:  g.id("image") + ".png",
+				Data:      pixel,
+				MediaType: "image/png",
+			})
+			continue
+		}
+		msg.Content = append(msg.Content, fantasy.TextPart{Text: g.text()})
+	}
+	if !hasText(msg) {
+		msg.Content = append(msg.Content, fantasy.TextPart{Text: g.text()})
+	}
+	return msg
+}
+
+func (g *Generator) assistantMessage() fantasy.Message {
+	msg := fantasy.Message{Role: fantasy.MessageRoleAssistant}
+	if g.chance(0.2) {
+		msg.Content = append(msg.Content, fantasy.ReasoningPart{Text: g.text()})
+	}
+	msg.Content = append(msg.Content, fantasy.TextPart{Text: g.text()})
+	return msg
+}
+
+func (g *Generator) toolCallMessage(n int) (fantasy.Message, []toolCall) {
+	msg := fantasy.Message{Role: fantasy.MessageRoleAssistant}
+	if g.chance(0.4) {
+		msg.Content = append(msg.Content, fantasy.TextPart{Text: g.text()})
+	}
+	calls := make([]toolCall, n)
+	for i := range calls {
+		calls[i] = toolCall{id: g.id("call"), name: toolNames[g.rng.IntN(len(toolNames))]}
+		input, _ := json.Marshal(map[string]any{"query": g.text(), "limit": g.rng.IntN(100)})
+		msg.Content = append(msg.Content, fantasy.ToolCallPart{
+			ToolCallID: calls[i].id,
+			ToolName:   calls[i].name,
+			Input:      string(input),
+		})
+	}
+	return msg, calls
+}
+
+func (g *Generator) toolMessage(calls []toolCall) fantasy.Message {
+	msg := fantasy.Message{Role: fantasy.MessageRoleTool}
+	for _, call := range calls {
+		var output fantasy.ToolResultOutputContent
+		switch n := g.rng.IntN(10); {
+		case n < 7:
+			output = fantasy.ToolResultOutputContentText{Text: g.text()}
+		case n < 9:
+			output = fantasy.ToolResultOutputContentError{Error: fmt.Errorf("%s", g.text())}
+		default:
+			output = fantasy.ToolResultOutputContentMedia{Data: pixelBase64, MediaType: "image/png"}
+		}
+		msg.Content = append(msg.Content, fantasy.ToolResultPart{ToolCallID: call.id, Output: output})
+	}
+	return msg
+}
+
+// tools returns a function tool for every tool called in the prompt, and
+// sometimes one more.
+func (g *Generator) tools(prompt fantasy.Prompt) []fantasy.Tool {
+	names := map[string]bool{}
+	for _, msg := range prompt {
+		for _, part := range msg.Content {
+			if call, ok := fantasy.AsMessagePart[fantasy.ToolCallPart](part); ok {
+				names[call.ToolName] = true
+			}
+		}
+	}
+	if g.chance(0.5) {
+		names[toolNames[g.rng.IntN(len(toolNames))]] = true
+	}
+	var tools []fantasy.Tool
+	for _, name := range toolNames {
+		if !names[name] {
+			continue
+		}
+		tools = append(tools, fantasy.FunctionTool{
+			Name:        name,
+			Description: g.text(),
+			InputSchema: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"query": map[string]any{"type": "string", "description": g.text()},
+					"limit": map[string]any{"type": "integer"},
+				},
+				"required": []string{"query"},
+			},
+		})
+	}
+	return tools
+}
+
+// text returns a unique, non-empty text mixing plain words with characters
+// that need escaping.
+func (g *Generator) text() string {
+	words := make([]string, g.rng.IntN(6)+1)
+	for i := range words {
+		words[i] = vocabulary[g.rng.IntN(len(vocabulary))]
+	}
+	return g.id("t") + " " + strings.Join(words, " ")
+}
+
+func (g *Generator) id(prefix string) string {
+	g.ids++
+	return fmt.Sprintf("%s%d", prefix, g.ids)
+}
+
+func (g *Generator) chance(p float64) bool {
+	return g.rng.Float64() < p
+}
+
+type toolCall struct {
+	id, name string
+}
+
+var toolNames = []string{"search", "get_weather", "read_file"}
+
+var vocabulary = []string{
+	"hello", "world", "the", "quick", "brown", "fox",
+	`"quoted"`, "it's", "back\\slash", "<tag>", "a&b", "tab\there",
+	"line\nbreak", "naïve", "日本語", "🙂", "{json: true}", "100%",
+}
+
+// pixel is a 1x1 PNG image.
+var pixel, pixelBase64 = func() ([]byte, string) {
+	img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, img)
+	return buf.Bytes(), base64.StdEncoding.EncodeToString(buf.Bytes())
+}()
+
+func hasText(msg fantasy.Message) bool {
+	for _, part := range msg.Content {
+		if _, ok := fantasy.AsMessagePart[fantasy.TextPart](part); ok {
+			return true
+		}
+	}
+	return false
+}
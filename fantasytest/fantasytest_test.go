@@ -0,0 +1,112 @@
+package fantasytest
+
+import (
+	"fmt"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator_Deterministic(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, NewGenerator(42).Call(), NewGenerator(42).Call())
+	require.NotEqual(t, NewGenerator(42).Call(), NewGenerator(43).Call())
+}
+
+func TestForAll_ValidPrompts(t *testing.T) {
+	t.Parallel()
+
+	ForAll(t, 200, func(t *testing.T, call fantasy.Call) {
+		prompt := call.Prompt
+		require.NotEmpty(t, prompt)
+		last := prompt[len(prompt)-1].Role
+		require.Contains(t, []fantasy.MessageRole{fantasy.MessageRoleUser, fantasy.MessageRoleTool}, last)
+
+		tools := map[string]bool{}
+		for _, tool := range call.Tools {
+			tools[tool.GetName()] = true
+		}
+		for i, msg := range prompt {
+			require.NotEmpty(t, msg.Content)
+			if msg.Role == fantasy.MessageRoleSystem {
+				require.Zero(t, i, "system message after the start")
+			}
+			// Every tool call is answered by the next message, which only
+			// answers the tool calls.
+			var calls []string
+			for _, part := range msg.Content {
+				if toolCall, ok := fantasy.AsMessagePart[fantasy.ToolCallPart](part); ok {
+					require.True(t, tools[toolCall.ToolName], "tool %s is missing", toolCall.ToolName)
+					calls = append(calls, toolCall.ToolCallID)
+				}
+			}
+			if len(calls) == 0 {
+				continue
+			}
+			require.Equal(t, fantasy.MessageRoleTool, prompt[i+1].Role)
+			var results []string
+			for _, part := range prompt[i+1].Content {
+				result, ok := fantasy.AsMessagePart[fantasy.ToolResultPart](part)
+				require.True(t, ok)
+				results = append(results, result.ToolCallID)
+			}
+			require.Equal(t, calls, results)
+		}
+
+		texts := Texts(prompt)
+		require.NotEmpty(t, texts)
+		seen := map[string]bool{}
+		for _, text := range texts {
+			require.False(t, seen[text], "text %q is not unique", text)
+			seen[text] = true
+		}
+	})
+}
+
+type fatalRecorder struct {
+	testing.TB
+	failure string
+}
+
+func (r *fatalRecorder) Helper() {}
+
+func (r *fatalRecorder) Fatalf(format string, args ...any) {
+	r.failure = fmt.Sprintf(format, args...)
+}
+
+func TestRequireTextsPreserved(t *testing.T) {
+	t.Parallel()
+
+	prompt := fantasy.Prompt{
+		fantasy.NewSystemMessage("be brief"),
+		fantasy.NewUserMessage("hello"),
+		{Role: fantasy.MessageRoleAssistant, Content: []fantasy.MessagePart{
+			fantasy.ReasoningPart{Text: "dropped"},
+			fantasy.ToolCallPart{ToolCallID: "call-1", ToolName: "search", Input: "{}"},
+		}},
+		{Role: fantasy.MessageRoleTool, Content: []fantasy.MessagePart{
+			fantasy.ToolResultPart{ToolCallID: "call-1", Output: fantasy.ToolResultOutputContentError{Error: fmt.Errorf("not found")}},
+		}},
+	}
+	type block struct {
+		text string
+	}
+	converted := map[string]any{
+		"system":   []string{"be brief"},
+		"messages": []*block{{text: "hello"}, {text: "error: not found (call-1)"}},
+	}
+
+	r := &fatalRecorder{TB: t}
+	RequireTextsPreserved(r, prompt, converted)
+	RequireToolCallIDsPreserved(r, prompt, converted)
+	require.Empty(t, r.failure)
+
+	RequireTextsPreserved(r, prompt, converted["messages"])
+	require.Equal(t, `fantasytest: text "be brief" is missing from the converted prompt`, r.failure)
+
+	r.failure = ""
+	RequireToolCallIDsPreserved(r, prompt, converted["system"])
+	require.Equal(t, `fantasytest: tool call ID "call-1" is missing from the converted prompt`, r.failure)
+}
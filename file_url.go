@@ -0,0 +1,167 @@
+package fantasy
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+)
+
+// DefaultMaxFileDownloadBytes is the largest file the agent downloads for
+// a file part's URL unless WithFileDownloadLimit is set.
+const DefaultMaxFileDownloadBytes = 20 << 20
+
+// WithFileDownloadLimit sets the largest file the agent downloads for the
+// URL of a file part the model can't pass to its provider. Larger files
+// fail the call.
+func WithFileDownloadLimit(maxBytes int64) AgentOption {
+	return func(s *agentSettings) {
+		s.maxFileDownloadBytes = maxBytes
+	}
+}
+
+func (a *agent) maxFileDownloadBytes() int64 {
+	if a.settings.maxFileDownloadBytes > 0 {
+		return a.settings.maxFileDownloadBytes
+	}
+	return DefaultMaxFileDownloadBytes
+}
+
+// ResolveFileURLs replaces the URLs of the file parts of prompt that model
+// can't pass to its provider with their content: data URLs are decoded and
+// other URLs downloaded, up to maxBytes. Parts without a media type are
+// always resolved, so they get the one of the content. The prompt is left
+// untouched.
+func ResolveFileURLs(ctx context.Context, model LanguageModel, prompt Prompt, maxBytes int64) (Prompt, error) {
+	var resolved Prompt
+	for i, msg := range prompt {
+		var content []MessagePart
+		for j, part := range msg.Content {
+			file, ok := AsMessagePart[FilePart](part)
+			if !ok || file.URL == "" || passesFileURL(model, file) {
+				continue
+			}
+			file, err := LoadFileURL(ctx, file, maxBytes)
+			if err != nil {
+				return nil, err
+			}
+			if content == nil {
+				content = slices.Clone(msg.Content)
+			}
+			content[j] = file
+		}
+		if content == nil {
+			continue
+		}
+		if resolved == nil {
+			resolved = slices.Clone(prompt)
+		}
+		resolved[i].Content = content
+	}
+	if resolved == nil {
+		return prompt, nil
+	}
+	return resolved, nil
+}
+
+// LoadFileURL returns file with the content of its URL, decoded from a data
+// URL or downloaded, in Data and its URL cleared. The media type and file
+// name are taken from the content when file has none. Downloads larger
+// than maxBytes fail.
+func LoadFileURL(ctx context.Context, file FilePart, maxBytes int64) (FilePart, error) {
+	if strings.HasPrefix(file.URL, "data:") {
+		data, mediaType, err := decodeDataURL(file.URL)
+		if err != nil {
+			return file, fmt.Errorf("decoding file data URL: %w", err)
+		}
+		file.Data = data
+		file.MediaType = cmp.Or(file.MediaType, mediaType)
+		file.URL = ""
+		return file, nil
+	}
+	data, mediaType, err := download(ctx, file.URL, maxBytes)
+	if err != nil {
+		return file, fmt.Errorf("downloading file %s: %w", file.URL, err)
+	}
+	if file.Filename == "" {
+		if u, err := url.Parse(file.URL); err == nil && path.Base(u.Path) != "/" && path.Base(u.Path) != "." {
+			file.Filename = path.Base(u.Path)
+		}
+	}
+	file.Data = data
+	file.MediaType = cmp.Or(file.MediaType, mediaType)
+	file.URL = ""
+	return file, nil
+}
+
+// passesFileURL reports whether model passes the URL of file to its
+// provider as is.
+func passesFileURL(model LanguageModel, file FilePart) bool {
+	if file.MediaType == "" || strings.HasPrefix(file.URL, "data:") {
+		return false
+	}
+	urlModel, ok := model.(FileURLModel)
+	return ok && urlModel.SupportsFileURL(file.MediaType)
+}
+
+// decodeDataURL decodes a data URL of the form
+// data:[<media type>][;base64],<data>.
+func decodeDataURL(dataURL string) ([]byte, string, error) {
+	header, payload, ok := strings.Cut(strings.TrimPrefix(dataURL, "data:"), ",")
+	if !ok {
+		return nil, "", errors.New("missing comma")
+	}
+	mediaType, isBase64 := strings.CutSuffix(header, ";base64")
+	mediaType, _, _ = strings.Cut(mediaType, ";")
+	mediaType = cmp.Or(mediaType, "text/plain")
+	if isBase64 {
+		data, err := base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			return nil, "", err
+		}
+		return data, mediaType, nil
+	}
+	data, err := url.PathUnescape(payload)
+	if err != nil {
+		return nil, "", err
+	}
+	return []byte(data), mediaType, nil
+}
+
+func download(ctx context.Context, rawURL string, maxBytes int64) ([]byte, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	if resp.ContentLength > maxBytes {
+		return nil, "", fmt.Errorf("file of %d bytes is larger than the limit of %d bytes", resp.ContentLength, maxBytes)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, "", fmt.Errorf("file is larger than the limit of %d bytes", maxBytes)
+	}
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || mediaType == "application/octet-stream" {
+		mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	}
+	return data, mediaType, nil
+}
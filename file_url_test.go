@@ -0,0 +1,110 @@
+package fantasy
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type urlMockModel struct {
+	mockLanguageModel
+}
+
+func (*urlMockModel) SupportsFileURL(mediaType string) bool {
+	return mediaType == "image/png"
+}
+
+func TestResolveFileURLs(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/report.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			_, _ = w.Write([]byte("%PDF-1.7 document"))
+		case "/missing":
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	resolve := func(t *testing.T, model LanguageModel, file FilePart, maxBytes int64) (FilePart, error) {
+		t.Helper()
+		prompt := Prompt{NewUserMessage("look", file)}
+		resolved, err := ResolveFileURLs(t.Context(), model, prompt, maxBytes)
+		if err != nil {
+			return FilePart{}, err
+		}
+		original, _ := AsMessagePart[FilePart](prompt[0].Content[1])
+		require.Equal(t, file, original)
+		part, ok := AsMessagePart[FilePart](resolved[0].Content[1])
+		require.True(t, ok)
+		return part, nil
+	}
+
+	t.Run("downloads files", func(t *testing.T) {
+		t.Parallel()
+		file, err := resolve(t, &mockLanguageModel{}, FilePart{URL: server.URL + "/report.pdf"}, DefaultMaxFileDownloadBytes)
+		require.NoError(t, err)
+		require.Equal(t, FilePart{Filename: "report.pdf", Data: []byte("%PDF-1.7 document"), MediaType: "application/pdf"}, file)
+	})
+
+	t.Run("decodes data URLs", func(t *testing.T) {
+		t.Parallel()
+		file, err := resolve(t, &urlMockModel{}, FilePart{URL: "data:image/png;base64,iVBORw==", MediaType: "image/png"}, DefaultMaxFileDownloadBytes)
+		require.NoError(t, err)
+		require.Equal(t, []byte{0x89, 'P', 'N', 'G'}, file.Data)
+		require.Empty(t, file.URL)
+
+		file, err = resolve(t, &mockLanguageModel{}, FilePart{URL: "data:,hello%20world"}, DefaultMaxFileDownloadBytes)
+		require.NoError(t, err)
+		require.Equal(t, FilePart{Data: []byte("hello world"), MediaType: "text/plain"}, file)
+	})
+
+	t.Run("passes URLs the model accepts", func(t *testing.T) {
+		t.Parallel()
+		image := FilePart{URL: "https://example.com/cat.png", MediaType: "image/png"}
+		file, err := resolve(t, &urlMockModel{}, image, DefaultMaxFileDownloadBytes)
+		require.NoError(t, err)
+		require.Equal(t, image, file)
+	})
+
+	t.Run("limits the size", func(t *testing.T) {
+		t.Parallel()
+		_, err := resolve(t, &mockLanguageModel{}, FilePart{URL: server.URL + "/report.pdf"}, 4)
+		require.ErrorContains(t, err, "larger than the limit of 4 bytes")
+	})
+
+	t.Run("fails on errors", func(t *testing.T) {
+		t.Parallel()
+		_, err := resolve(t, &mockLanguageModel{}, FilePart{URL: server.URL + "/missing"}, DefaultMaxFileDownloadBytes)
+		require.ErrorContains(t, err, "404")
+	})
+
+	t.Run("agent", func(t *testing.T) {
+		t.Parallel()
+		var prompt Prompt
+		model := &mockLanguageModel{generateFunc: func(_ context.Context, call Call) (*Response, error) {
+			prompt = call.Prompt
+			return &Response{Content: ResponseContent{TextContent{Text: "ok"}}, FinishReason: FinishReasonStop}, nil
+		}}
+		agent := NewAgent(model, WithFileDownloadLimit(4))
+		_, err := agent.Generate(t.Context(), AgentCall{
+			Prompt: "summarize",
+			Files:  []FilePart{{URL: "data:text/plain,hi"}},
+		})
+		require.NoError(t, err)
+		file, ok := AsMessagePart[FilePart](prompt[0].Content[1])
+		require.True(t, ok)
+		require.Equal(t, []byte("hi"), file.Data)
+
+		_, err = agent.Generate(t.Context(), AgentCall{
+			Prompt: "summarize",
+			Files:  []FilePart{{URL: server.URL + "/report.pdf"}},
+		})
+		require.Error(t, err)
+	})
+}
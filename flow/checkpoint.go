@@ -0,0 +1,109 @@
+package flow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Checkpoint is the saved progress of a run.
+type Checkpoint[S any] struct {
+	RunID string `json:"run_id"`
+	// Node is the node to run next, or End if the run finished.
+	Node string `json:"node"`
+	Step int    `json:"step"`
+	// State is the state Node runs on.
+	State S `json:"state"`
+	// Interrupt is set if Node paused the run.
+	Interrupt *Interrupt `json:"interrupt,omitempty"`
+}
+
+// Checkpointer stores the checkpoints of runs. Load returns nil if the run
+// has no checkpoint.
+type Checkpointer[S any] interface {
+	Save(ctx context.Context, checkpoint Checkpoint[S]) error
+	Load(ctx context.Context, runID string) (*Checkpoint[S], error)
+}
+
+// MemoryCheckpointer keeps checkpoints in memory.
+type MemoryCheckpointer[S any] struct {
+	mu          sync.Mutex
+	checkpoints map[string]Checkpoint[S]
+}
+
+// NewMemoryCheckpointer returns an empty MemoryCheckpointer.
+func NewMemoryCheckpointer[S any]() *MemoryCheckpointer[S] {
+	return &MemoryCheckpointer[S]{checkpoints: map[string]Checkpoint[S]{}}
+}
+
+// Save implements Checkpointer.
+func (m *MemoryCheckpointer[S]) Save(_ context.Context, checkpoint Checkpoint[S]) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.checkpoints[checkpoint.RunID] = checkpoint
+	return nil
+}
+
+// Load implements Checkpointer.
+func (m *MemoryCheckpointer[S]) Load(_ context.Context, runID string) (*Checkpoint[S], error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	checkpoint, ok := m.checkpoints[runID]
+	if !ok {
+		return nil, nil
+	}
+	return &checkpoint, nil
+}
+
+// FileCheckpointer keeps checkpoints as JSON files in a directory, so runs
+// can be resumed by another process. The state must marshal to JSON.
+type FileCheckpointer[S any] struct {
+	dir string
+}
+
+// NewFileCheckpointer returns a FileCheckpointer that writes to dir,
+// creating it if needed.
+func NewFileCheckpointer[S any](dir string) *FileCheckpointer[S] {
+	return &FileCheckpointer[S]{dir: dir}
+}
+
+func (f *FileCheckpointer[S]) path(runID string) string {
+	return filepath.Join(f.dir, filepath.Base(runID)+".json")
+}
+
+// Save implements Checkpointer.
+func (f *FileCheckpointer[S]) Save(_ context.Context, checkpoint Checkpoint[S]) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(f.dir, 0o755); err != nil {
+		return err
+	}
+	// Write to a temporary file first so a crash can't leave a partial
+	// checkpoint behind.
+	tmp := f.path(checkpoint.RunID) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.path(checkpoint.RunID))
+}
+
+// Load implements Checkpointer.
+func (f *FileCheckpointer[S]) Load(_ context.Context, runID string) (*Checkpoint[S], error) {
+	data, err := os.ReadFile(f.path(runID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var checkpoint Checkpoint[S]
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
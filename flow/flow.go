@@ -0,0 +1,297 @@
+// Package flow orchestrates agents, tools and people as a graph of nodes
+// that share a typed state. Edges, optionally conditional, decide which
+// node runs next. Runs stream their progress as events, save a checkpoint
+// after each node, and can pause at a human gate and resume later.
+//
+// Example:
+//
+//	type State struct{ Topic, Draft string; Approved bool }
+//
+//	g := flow.New[State]("write").
+//	    AddNode("write", flow.Agent(writer, func(s State) fantasy.AgentCall {
+//	        return fantasy.AgentCall{Prompt: "Write about " + s.Topic}
+//	    }, func(s State, r *fantasy.AgentResult) State {
+//	        s.Draft = r.Response.Content.Text()
+//	        return s
+//	    })).
+//	    AddNode("review", flow.HumanGate(func(s State) string {
+//	        return "Publish this draft?\n\n" + s.Draft
+//	    }, func(s State, answer string) (State, error) {
+//	        s.Approved = answer == "yes"
+//	        return s, nil
+//	    })).
+//	    AddEdge("write", "review").
+//	    AddConditionalEdge("review", flow.End, func(s State) bool { return s.Approved }).
+//	    AddEdge("review", "write").
+//	    WithCheckpointer(flow.NewMemoryCheckpointer[State]())
+//
+//	state, err := g.Run(ctx, "run-1", State{Topic: "otters"})
+//	var interrupt *flow.Interrupt
+//	if errors.As(err, &interrupt) {
+//	    state, err = g.Resume(ctx, "run-1", askUser(interrupt.Prompt))
+//	}
+package flow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+)
+
+// End is the name of the implicit node that ends a run.
+const End = "end"
+
+// DefaultMaxSteps is the number of nodes a run executes before failing,
+// which stops cycles that never reach End.
+const DefaultMaxSteps = 100
+
+// Condition decides whether a conditional edge is taken.
+type Condition[S any] func(state S) bool
+
+type edge[S any] struct {
+	to   string
+	when Condition[S]
+}
+
+// Graph is a flow of nodes over the state S.
+type Graph[S any] struct {
+	start        string
+	nodes        map[string]Node[S]
+	edges        map[string][]edge[S]
+	checkpointer Checkpointer[S]
+	maxSteps     int
+}
+
+// New returns an empty graph whose runs begin at the start node.
+func New[S any](start string) *Graph[S] {
+	return &Graph[S]{
+		start:    start,
+		nodes:    map[string]Node[S]{},
+		edges:    map[string][]edge[S]{},
+		maxSteps: DefaultMaxSteps,
+	}
+}
+
+// AddNode adds a node under name, replacing any node with that name.
+func (g *Graph[S]) AddNode(name string, node Node[S]) *Graph[S] {
+	g.nodes[name] = node
+	return g
+}
+
+// AddEdge makes to run after from, unless an earlier conditional edge out
+// of from is taken.
+func (g *Graph[S]) AddEdge(from, to string) *Graph[S] {
+	g.edges[from] = append(g.edges[from], edge[S]{to: to})
+	return g
+}
+
+// AddConditionalEdge makes to run after from if when reports true for the
+// state from produced. Edges out of a node are tried in the order they
+// were added.
+func (g *Graph[S]) AddConditionalEdge(from, to string, when Condition[S]) *Graph[S] {
+	g.edges[from] = append(g.edges[from], edge[S]{to: to, when: when})
+	return g
+}
+
+// WithCheckpointer saves a checkpoint of runs after each node, which
+// Resume continues from.
+func (g *Graph[S]) WithCheckpointer(checkpointer Checkpointer[S]) *Graph[S] {
+	g.checkpointer = checkpointer
+	return g
+}
+
+// WithMaxSteps sets how many nodes a run executes before it fails.
+func (g *Graph[S]) WithMaxSteps(maxSteps int) *Graph[S] {
+	g.maxSteps = maxSteps
+	return g
+}
+
+// Validate checks that the start node and the edges refer to added nodes.
+func (g *Graph[S]) Validate() error {
+	if _, ok := g.nodes[g.start]; !ok {
+		return fmt.Errorf("flow: unknown start node %q", g.start)
+	}
+	for from, edges := range g.edges {
+		if _, ok := g.nodes[from]; !ok {
+			return fmt.Errorf("flow: edge from unknown node %q", from)
+		}
+		for _, e := range edges {
+			if _, ok := g.nodes[e.to]; !ok && e.to != End {
+				return fmt.Errorf("flow: edge from %q to unknown node %q", from, e.to)
+			}
+		}
+	}
+	return nil
+}
+
+// EventType is the kind of an Event.
+type EventType string
+
+const (
+	// EventNodeStart is sent before a node runs.
+	EventNodeStart EventType = "node_start"
+	// EventNodeFinish is sent after a node ran, with the state it produced.
+	EventNodeFinish EventType = "node_finish"
+	// EventInterrupt is sent when a node pauses the run, e.g. a human gate.
+	EventInterrupt EventType = "interrupt"
+	// EventFinish is sent when the run reaches End, with the final state.
+	EventFinish EventType = "finish"
+	// EventError is sent when the run fails.
+	EventError EventType = "error"
+)
+
+// Event reports the progress of a run.
+type Event[S any] struct {
+	Type EventType
+	// Node is the node the event is about.
+	Node string
+	// Step counts the nodes the run has executed.
+	Step  int
+	State S
+	// Interrupt is set for EventInterrupt.
+	Interrupt *Interrupt
+	// Error is set for EventError.
+	Error error
+}
+
+// Run runs the graph from its start node with the initial state and
+// returns the final state. If a node pauses the run, Run returns the
+// state so far and an *Interrupt error.
+func (g *Graph[S]) Run(ctx context.Context, runID string, state S) (S, error) {
+	return collect(g.Stream(ctx, runID, state))
+}
+
+// Stream runs the graph like Run, yielding its progress as events.
+func (g *Graph[S]) Stream(ctx context.Context, runID string, state S) iter.Seq[Event[S]] {
+	return g.run(ctx, Checkpoint[S]{RunID: runID, Node: g.start, State: state}, nil)
+}
+
+// Resume continues the run saved by the graph's checkpointer, e.g. after a
+// failure or an interrupt. input is the answer to a pending interrupt and
+// is ignored otherwise.
+func (g *Graph[S]) Resume(ctx context.Context, runID string, input string) (S, error) {
+	return collect(g.ResumeStream(ctx, runID, input))
+}
+
+// ResumeStream resumes the run like Resume, yielding its progress as
+// events.
+func (g *Graph[S]) ResumeStream(ctx context.Context, runID string, input string) iter.Seq[Event[S]] {
+	return func(yield func(Event[S]) bool) {
+		if g.checkpointer == nil {
+			yield(Event[S]{Type: EventError, Error: errors.New("flow: resuming needs a checkpointer")})
+			return
+		}
+		checkpoint, err := g.checkpointer.Load(ctx, runID)
+		if err == nil && checkpoint == nil {
+			err = fmt.Errorf("flow: no checkpoint for run %q", runID)
+		}
+		if err != nil {
+			yield(Event[S]{Type: EventError, Error: err})
+			return
+		}
+		var answer *string
+		if checkpoint.Interrupt != nil {
+			answer = &input
+		}
+		g.run(ctx, *checkpoint, answer)(yield)
+	}
+}
+
+func (g *Graph[S]) run(ctx context.Context, checkpoint Checkpoint[S], answer *string) iter.Seq[Event[S]] {
+	return func(yield func(Event[S]) bool) {
+		fail := func(node string, err error) {
+			yield(Event[S]{Type: EventError, Node: node, Step: checkpoint.Step, State: checkpoint.State, Error: err})
+		}
+		if err := g.Validate(); err != nil {
+			fail("", err)
+			return
+		}
+
+		for checkpoint.Node != End {
+			if checkpoint.Step >= g.maxSteps {
+				fail(checkpoint.Node, fmt.Errorf("flow: run exceeded %d steps", g.maxSteps))
+				return
+			}
+			name := checkpoint.Node
+			if !yield(Event[S]{Type: EventNodeStart, Node: name, Step: checkpoint.Step, State: checkpoint.State}) {
+				return
+			}
+
+			nodeCtx := ctx
+			if answer != nil {
+				nodeCtx = context.WithValue(ctx, answerKey{}, *answer)
+				answer = nil
+			}
+			state, err := g.nodes[name].Run(nodeCtx, checkpoint.State)
+			var interrupt *Interrupt
+			if errors.As(err, &interrupt) {
+				interrupt.Node = name
+				checkpoint.Interrupt = interrupt
+				if err := g.save(ctx, checkpoint); err != nil {
+					fail(name, err)
+					return
+				}
+				yield(Event[S]{Type: EventInterrupt, Node: name, Step: checkpoint.Step, State: checkpoint.State, Interrupt: interrupt})
+				return
+			}
+			if err != nil {
+				fail(name, fmt.Errorf("flow: node %q: %w", name, err))
+				return
+			}
+
+			next, err := g.next(name, state)
+			if err != nil {
+				fail(name, err)
+				return
+			}
+			checkpoint = Checkpoint[S]{RunID: checkpoint.RunID, Node: next, Step: checkpoint.Step + 1, State: state}
+			if err := g.save(ctx, checkpoint); err != nil {
+				fail(name, err)
+				return
+			}
+			if !yield(Event[S]{Type: EventNodeFinish, Node: name, Step: checkpoint.Step, State: state}) {
+				return
+			}
+		}
+		yield(Event[S]{Type: EventFinish, Node: End, Step: checkpoint.Step, State: checkpoint.State})
+	}
+}
+
+// next returns the node to run after from.
+func (g *Graph[S]) next(from string, state S) (string, error) {
+	edges := g.edges[from]
+	if len(edges) == 0 {
+		return End, nil
+	}
+	for _, e := range edges {
+		if e.when == nil || e.when(state) {
+			return e.to, nil
+		}
+	}
+	return "", fmt.Errorf("flow: no edge out of %q matches the state", from)
+}
+
+func (g *Graph[S]) save(ctx context.Context, checkpoint Checkpoint[S]) error {
+	if g.checkpointer == nil || checkpoint.RunID == "" {
+		return nil
+	}
+	if err := g.checkpointer.Save(ctx, checkpoint); err != nil {
+		return fmt.Errorf("flow: saving checkpoint: %w", err)
+	}
+	return nil
+}
+
+func collect[S any](events iter.Seq[Event[S]]) (S, error) {
+	var state S
+	for event := range events {
+		state = event.State
+		switch event.Type {
+		case EventError:
+			return state, event.Error
+		case EventInterrupt:
+			return state, event.Interrupt
+		}
+	}
+	return state, nil
+}
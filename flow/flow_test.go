@@ -0,0 +1,236 @@
+package flow
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/require"
+)
+
+type mockModel struct {
+	generate func(ctx context.Context, call fantasy.Call) (*fantasy.Response, error)
+}
+
+func (m *mockModel) Generate(ctx context.Context, call fantasy.Call) (*fantasy.Response, error) {
+	return m.generate(ctx, call)
+}
+
+func (m *mockModel) Stream(context.Context, fantasy.Call) (fantasy.StreamResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockModel) GenerateObject(context.Context, fantasy.ObjectCall) (*fantasy.ObjectResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockModel) StreamObject(context.Context, fantasy.ObjectCall) (fantasy.ObjectStreamResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockModel) Provider() string { return "mock" }
+
+func (m *mockModel) Model() string { return "mock" }
+
+type state struct {
+	Count    int      `json:"count"`
+	Log      []string `json:"log"`
+	Approved bool     `json:"approved"`
+}
+
+func appendLog(entry string) Node[state] {
+	return NodeFunc[state](func(_ context.Context, s state) (state, error) {
+		s.Log = append(s.Log, entry)
+		return s, nil
+	})
+}
+
+func TestGraph(t *testing.T) {
+	t.Parallel()
+
+	t.Run("conditional loop", func(t *testing.T) {
+		t.Parallel()
+		g := New[state]("count").
+			AddNode("count", NodeFunc[state](func(_ context.Context, s state) (state, error) {
+				s.Count++
+				return s, nil
+			})).
+			AddNode("done", appendLog("done")).
+			AddConditionalEdge("count", "done", func(s state) bool { return s.Count == 3 }).
+			AddEdge("count", "count")
+
+		var events []string
+		for event := range g.Stream(t.Context(), "", state{}) {
+			events = append(events, string(event.Type)+" "+event.Node)
+		}
+		require.Equal(t, []string{
+			"node_start count", "node_finish count",
+			"node_start count", "node_finish count",
+			"node_start count", "node_finish count",
+			"node_start done", "node_finish done",
+			"finish end",
+		}, events)
+
+		final, err := g.Run(t.Context(), "", state{})
+		require.NoError(t, err)
+		require.Equal(t, state{Count: 3, Log: []string{"done"}}, final)
+	})
+
+	t.Run("max steps", func(t *testing.T) {
+		t.Parallel()
+		g := New[state]("loop").AddNode("loop", appendLog("x")).AddEdge("loop", "loop").WithMaxSteps(5)
+		final, err := g.Run(t.Context(), "", state{})
+		require.EqualError(t, err, "flow: run exceeded 5 steps")
+		require.Len(t, final.Log, 5)
+	})
+
+	t.Run("invalid graph", func(t *testing.T) {
+		t.Parallel()
+		g := New[state]("a").AddNode("a", appendLog("a")).AddEdge("a", "missing")
+		_, err := g.Run(t.Context(), "", state{})
+		require.EqualError(t, err, `flow: edge from "a" to unknown node "missing"`)
+	})
+
+	t.Run("no matching edge", func(t *testing.T) {
+		t.Parallel()
+		g := New[state]("a").
+			AddNode("a", appendLog("a")).
+			AddConditionalEdge("a", End, func(s state) bool { return s.Approved })
+		_, err := g.Run(t.Context(), "", state{})
+		require.EqualError(t, err, `flow: no edge out of "a" matches the state`)
+	})
+
+	t.Run("parallel", func(t *testing.T) {
+		t.Parallel()
+		g := New[state]("fan").AddNode("fan", Parallel(func(s state, results []state) state {
+			for _, r := range results {
+				s.Log = append(s.Log, r.Log...)
+			}
+			return s
+		}, appendLog("a"), appendLog("b"), appendLog("c")))
+		final, err := g.Run(t.Context(), "", state{})
+		require.NoError(t, err)
+		require.Equal(t, []string{"a", "b", "c"}, final.Log)
+	})
+
+	t.Run("agent and tool", func(t *testing.T) {
+		t.Parallel()
+		model := &mockModel{generate: func(ctx context.Context, call fantasy.Call) (*fantasy.Response, error) {
+			prompt := call.Prompt[len(call.Prompt)-1].Content[0].(fantasy.TextPart).Text
+			return &fantasy.Response{
+				Content:      fantasy.ResponseContent{fantasy.TextContent{Text: strings.ToUpper(prompt)}},
+				FinishReason: fantasy.FinishReasonStop,
+			}, nil
+		}}
+		type shoutInput struct {
+			Text string `json:"text"`
+		}
+		shout := fantasy.NewAgentTool("shout", "Shouts", func(ctx context.Context, input shoutInput, _ fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			return fantasy.NewTextResponse(input.Text + "!"), nil
+		})
+
+		g := New[state]("agent").
+			AddNode("agent", Agent(fantasy.NewAgent(model), func(s state) fantasy.AgentCall {
+				return fantasy.AgentCall{Prompt: "hello"}
+			}, func(s state, result *fantasy.AgentResult) state {
+				s.Log = append(s.Log, result.Response.Content.Text())
+				return s
+			})).
+			AddNode("tool", Tool(shout, func(s state) any {
+				return shoutInput{Text: s.Log[0]}
+			}, func(s state, response fantasy.ToolResponse) (state, error) {
+				s.Log = append(s.Log, response.Content)
+				return s, nil
+			})).
+			AddEdge("agent", "tool")
+
+		final, err := g.Run(t.Context(), "", state{})
+		require.NoError(t, err)
+		require.Equal(t, []string{"HELLO", "HELLO!"}, final.Log)
+	})
+}
+
+func TestHumanGate(t *testing.T) {
+	t.Parallel()
+
+	newGraph := func(checkpointer Checkpointer[state]) *Graph[state] {
+		return New[state]("draft").
+			AddNode("draft", NodeFunc[state](func(_ context.Context, s state) (state, error) {
+				s.Count++
+				return s, nil
+			})).
+			AddNode("review", HumanGate(func(s state) string {
+				return "approve draft?"
+			}, func(s state, answer string) (state, error) {
+				s.Approved = answer == "yes"
+				return s, nil
+			})).
+			AddEdge("draft", "review").
+			AddConditionalEdge("review", End, func(s state) bool { return s.Approved }).
+			AddEdge("review", "draft").
+			WithCheckpointer(checkpointer)
+	}
+
+	for name, checkpointer := range map[string]Checkpointer[state]{
+		"memory": NewMemoryCheckpointer[state](),
+		"file":   NewFileCheckpointer[state](t.TempDir()),
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			g := newGraph(checkpointer)
+
+			_, err := g.Run(t.Context(), "run-1", state{})
+			var interrupt *Interrupt
+			require.ErrorAs(t, err, &interrupt)
+			require.Equal(t, "review", interrupt.Node)
+			require.Equal(t, "approve draft?", interrupt.Prompt)
+
+			// Another graph, as in another process, picks the run up.
+			_, err = newGraph(checkpointer).Resume(t.Context(), "run-1", "no")
+			require.ErrorAs(t, err, &interrupt)
+
+			final, err := g.Resume(t.Context(), "run-1", "yes")
+			require.NoError(t, err)
+			require.Equal(t, state{Count: 2, Approved: true}, final)
+
+			checkpoint, err := checkpointer.Load(t.Context(), "run-1")
+			require.NoError(t, err)
+			require.Equal(t, End, checkpoint.Node)
+			require.Nil(t, checkpoint.Interrupt)
+		})
+	}
+
+	t.Run("resume needs a checkpoint", func(t *testing.T) {
+		t.Parallel()
+		_, err := newGraph(NewMemoryCheckpointer[state]()).Resume(t.Context(), "missing", "")
+		require.EqualError(t, err, `flow: no checkpoint for run "missing"`)
+	})
+}
+
+func TestResumeAfterFailure(t *testing.T) {
+	t.Parallel()
+
+	var failures atomic.Int32
+	failures.Store(1)
+	g := New[state]("a").
+		AddNode("a", appendLog("a")).
+		AddNode("flaky", NodeFunc[state](func(_ context.Context, s state) (state, error) {
+			if failures.Add(-1) >= 0 {
+				return s, errors.New("unavailable")
+			}
+			s.Log = append(s.Log, "flaky")
+			return s, nil
+		})).
+		AddEdge("a", "flaky").
+		WithCheckpointer(NewMemoryCheckpointer[state]())
+
+	_, err := g.Run(t.Context(), "run", state{})
+	require.EqualError(t, err, `flow: node "flaky": unavailable`)
+
+	final, err := g.Resume(t.Context(), "run", "")
+	require.NoError(t, err)
+	require.Equal(t, []string{"a", "flaky"}, final.Log)
+}
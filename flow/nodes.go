@@ -0,0 +1,112 @@
+package flow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"charm.land/fantasy"
+)
+
+// Node is a step of a graph. It gets the current state and returns the
+// next one.
+type Node[S any] interface {
+	Run(ctx context.Context, state S) (S, error)
+}
+
+// NodeFunc is a function that implements Node.
+type NodeFunc[S any] func(ctx context.Context, state S) (S, error)
+
+// Run implements Node.
+func (f NodeFunc[S]) Run(ctx context.Context, state S) (S, error) {
+	return f(ctx, state)
+}
+
+// Agent returns a node that runs the agent with the call built from the
+// state, and folds the result back into the state with apply.
+func Agent[S any](agent fantasy.Agent, call func(state S) fantasy.AgentCall, apply func(state S, result *fantasy.AgentResult) S) Node[S] {
+	return NodeFunc[S](func(ctx context.Context, state S) (S, error) {
+		result, err := agent.Generate(ctx, call(state))
+		if err != nil {
+			return state, err
+		}
+		return apply(state, result), nil
+	})
+}
+
+// Tool returns a node that runs the tool with input built from the state,
+// marshaled to JSON, and folds the response back into the state with
+// apply. Error responses are returned to apply like any other.
+func Tool[S any](tool fantasy.AgentTool, input func(state S) any, apply func(state S, response fantasy.ToolResponse) (S, error)) Node[S] {
+	return NodeFunc[S](func(ctx context.Context, state S) (S, error) {
+		data, err := json.Marshal(input(state))
+		if err != nil {
+			return state, fmt.Errorf("marshaling %s input: %w", tool.Info().Name, err)
+		}
+		response, err := tool.Run(ctx, fantasy.ToolCall{
+			ID:    fantasy.NewID(),
+			Name:  tool.Info().Name,
+			Input: string(data),
+		})
+		if err != nil {
+			return state, err
+		}
+		return apply(state, response)
+	})
+}
+
+// Parallel returns a node that runs the nodes concurrently on the same
+// state and combines the states they return, in the order of nodes, with
+// merge. The nodes must not pause the run.
+func Parallel[S any](merge func(state S, results []S) S, nodes ...Node[S]) Node[S] {
+	return NodeFunc[S](func(ctx context.Context, state S) (S, error) {
+		results := make([]S, len(nodes))
+		errs := make([]error, len(nodes))
+		var wg sync.WaitGroup
+		for i, node := range nodes {
+			wg.Go(func() {
+				results[i], errs[i] = node.Run(ctx, state)
+			})
+		}
+		wg.Wait()
+		for _, err := range errs {
+			var interrupt *Interrupt
+			if errors.As(err, &interrupt) {
+				return state, errors.New("parallel nodes can't pause the run")
+			}
+		}
+		if err := errors.Join(errs...); err != nil {
+			return state, err
+		}
+		return merge(state, results), nil
+	})
+}
+
+// Interrupt pauses a run until it's resumed with an answer to Prompt. Runs
+// return it as an error, and Resume continues them.
+type Interrupt struct {
+	// Node is the node that paused the run.
+	Node   string `json:"node"`
+	Prompt string `json:"prompt"`
+}
+
+func (i *Interrupt) Error() string {
+	return fmt.Sprintf("flow: paused at %q: %s", i.Node, i.Prompt)
+}
+
+type answerKey struct{}
+
+// HumanGate returns a node that pauses the run with the prompt built from
+// the state. When the run is resumed, apply folds the answer into the
+// state.
+func HumanGate[S any](prompt func(state S) string, apply func(state S, answer string) (S, error)) Node[S] {
+	return NodeFunc[S](func(ctx context.Context, state S) (S, error) {
+		answer, ok := ctx.Value(answerKey{}).(string)
+		if !ok {
+			return state, &Interrupt{Prompt: prompt(state)}
+		}
+		return apply(state, answer)
+	})
+}
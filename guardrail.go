@@ -0,0 +1,174 @@
+package fantasy
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// GuardrailStage is the point of an agent run at which a guardrail checks
+// text.
+type GuardrailStage string
+
+const (
+	// GuardrailStageInput checks the text of the user messages that start
+	// a run, before the first step.
+	GuardrailStageInput GuardrailStage = "input"
+	// GuardrailStageOutput checks the text the model generates in each
+	// step, and the input of its tool calls, before the tools run. Changes
+	// to tool call inputs are ignored, so they can only be blocked. When
+	// streaming, the text has already been streamed by the time it is
+	// checked, but a blocked step still fails the run without running its
+	// tools.
+	GuardrailStageOutput GuardrailStage = "output"
+	// GuardrailStageToolResult checks the text results of tools before
+	// they are sent to the model. The step's content keeps the results as
+	// the tools returned them.
+	GuardrailStageToolResult GuardrailStage = "tool_result"
+)
+
+// Guardrail screens the text going into and coming out of an agent.
+type Guardrail interface {
+	// Check returns the text to use in place of text, which is usually the
+	// text itself, or an error to stop the run. Return a *GuardrailError to
+	// report that the text is not allowed.
+	Check(ctx context.Context, stage GuardrailStage, text string) (string, error)
+}
+
+// GuardrailFunc adapts a function to a Guardrail.
+type GuardrailFunc func(ctx context.Context, stage GuardrailStage, text string) (string, error)
+
+// Check implements Guardrail.
+func (f GuardrailFunc) Check(ctx context.Context, stage GuardrailStage, text string) (string, error) {
+	return f(ctx, stage, text)
+}
+
+// GuardrailError is returned by an agent run when a guardrail blocks its
+// input or output.
+type GuardrailError struct {
+	Stage  GuardrailStage
+	Reason string
+	// Categories lists the categories of content that were found, if the
+	// guardrail reports them.
+	Categories []string
+}
+
+func (e *GuardrailError) Error() string {
+	msg := fmt.Sprintf("guardrail blocked %s: %s", e.Stage, e.Reason)
+	if len(e.Categories) > 0 {
+		msg += " (" + strings.Join(e.Categories, ", ") + ")"
+	}
+	return msg
+}
+
+// WithGuardrails adds guardrails that check the input, output and tool
+// results of every run of the agent, in order. Each guardrail sees the text
+// returned by the previous one.
+func WithGuardrails(guardrails ...Guardrail) AgentOption {
+	return func(s *agentSettings) {
+		s.guardrails = append(s.guardrails, guardrails...)
+	}
+}
+
+func (a *agent) checkGuardrails(ctx context.Context, stage GuardrailStage, text string) (string, error) {
+	for _, guardrail := range a.settings.guardrails {
+		var err error
+		text, err = guardrail.Check(ctx, stage, text)
+		if err != nil {
+			return "", err
+		}
+	}
+	return text, nil
+}
+
+// guardInput checks the text of the user messages at the end of the prompt,
+// which are the input of the run.
+func (a *agent) guardInput(ctx context.Context, prompt Prompt) (Prompt, error) {
+	if len(a.settings.guardrails) == 0 {
+		return prompt, nil
+	}
+	prompt = slices.Clone(prompt)
+	for i := len(prompt) - 1; i >= 0 && prompt[i].Role == MessageRoleUser; i-- {
+		msg := prompt[i]
+		msg.Content = slices.Clone(msg.Content)
+		for j, part := range msg.Content {
+			text, ok := AsMessagePart[TextPart](part)
+			if !ok {
+				continue
+			}
+			checked, err := a.checkGuardrails(ctx, GuardrailStageInput, text.Text)
+			if err != nil {
+				return nil, err
+			}
+			text.Text = checked
+			msg.Content[j] = text
+		}
+		prompt[i] = msg
+	}
+	return prompt, nil
+}
+
+// guardOutput checks the text the model generated in a step, and the input
+// of the tool calls it made, before the tools run. Tool call inputs can only
+// be blocked: the tools get the input the model sent.
+func (a *agent) guardOutput(ctx context.Context, content ResponseContent) (ResponseContent, error) {
+	if len(a.settings.guardrails) == 0 {
+		return content, nil
+	}
+	content = slices.Clone(content)
+	for i, c := range content {
+		if toolCall, ok := AsContentType[ToolCallContent](c); ok {
+			if toolCall.ProviderExecuted {
+				continue
+			}
+			if _, err := a.checkGuardrails(ctx, GuardrailStageOutput, toolCall.Input); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		text, ok := AsContentType[TextContent](c)
+		if !ok {
+			continue
+		}
+		checked, err := a.checkGuardrails(ctx, GuardrailStageOutput, text.Text)
+		if err != nil {
+			return nil, err
+		}
+		text.Text = checked
+		content[i] = text
+	}
+	return content, nil
+}
+
+// guardToolResults checks the text results of tools in the step messages.
+func (a *agent) guardToolResults(ctx context.Context, messages []Message) ([]Message, error) {
+	if len(a.settings.guardrails) == 0 {
+		return messages, nil
+	}
+	messages = slices.Clone(messages)
+	for i, msg := range messages {
+		if msg.Role != MessageRoleTool {
+			continue
+		}
+		msg.Content = slices.Clone(msg.Content)
+		for j, part := range msg.Content {
+			toolResult, ok := AsMessagePart[ToolResultPart](part)
+			if !ok {
+				continue
+			}
+			output, ok := AsToolResultOutputType[ToolResultOutputContentText](toolResult.Output)
+			if !ok {
+				continue
+			}
+			checked, err := a.checkGuardrails(ctx, GuardrailStageToolResult, output.Text)
+			if err != nil {
+				return nil, err
+			}
+			toolResult.Output = ToolResultOutputContentText{Text: checked}
+			msg.Content[j] = toolResult
+		}
+		messages[i] = msg
+	}
+	return messages, nil
+}
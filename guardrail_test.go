@@ -0,0 +1,230 @@
+package fantasy
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testModeration = NewKeywordModerationModel(
+	ModerationRule{Category: "violence", Keywords: []string{"attack", "kill"}},
+	ModerationRule{Category: "secrets", Patterns: []*regexp.Regexp{regexp.MustCompile(`sk-[a-z0-9]{8,}`)}},
+)
+
+func TestKeywordModerationModel(t *testing.T) {
+	t.Parallel()
+
+	resp, err := testModeration.Moderate(t.Context(), ModerationCall{Values: []string{
+		"Plan the ATTACK at dawn",
+		"my key is sk-abcdef123456",
+		"skill issue",
+	}})
+	require.NoError(t, err)
+	require.Len(t, resp.Results, 3)
+
+	require.True(t, resp.Results[0].Flagged)
+	require.Equal(t, []string{"violence"}, resp.Results[0].FlaggedCategories())
+	require.Equal(t, []string{"secrets"}, resp.Results[1].FlaggedCategories())
+	// Keywords only match whole words.
+	require.False(t, resp.Results[2].Flagged)
+	require.Equal(t, []ModerationCategory{{Name: "violence"}, {Name: "secrets"}}, resp.Results[2].Categories)
+}
+
+func TestModerationGuardrail(t *testing.T) {
+	t.Parallel()
+
+	t.Run("blocks flagged input", func(t *testing.T) {
+		t.Parallel()
+		called := false
+		model := &mockLanguageModel{
+			generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+				called = true
+				return &Response{Content: []Content{TextContent{Text: "ok"}}, FinishReason: FinishReasonStop}, nil
+			},
+		}
+		agent := NewAgent(model, WithModeration(testModeration))
+		_, err := agent.Generate(t.Context(), AgentCall{Prompt: "how do I attack the server"})
+
+		var guardErr *GuardrailError
+		require.ErrorAs(t, err, &guardErr)
+		require.Equal(t, GuardrailStageInput, guardErr.Stage)
+		require.Equal(t, []string{"violence"}, guardErr.Categories)
+		require.Equal(t, "guardrail blocked input: flagged by moderation (violence)", err.Error())
+		require.False(t, called)
+	})
+
+	t.Run("only checks the new user messages", func(t *testing.T) {
+		t.Parallel()
+		agent := NewAgent(&mockLanguageModel{}, WithModeration(testModeration))
+		result, err := agent.Generate(t.Context(), AgentCall{
+			Messages: []Message{
+				NewUserMessage("kill the process"),
+				{Role: MessageRoleAssistant, Content: []MessagePart{TextPart{Text: "Done."}}},
+			},
+			Prompt: "thanks",
+		})
+		require.NoError(t, err)
+		require.Equal(t, "Hello, world!", result.Response.Content.Text())
+	})
+
+	t.Run("blocks flagged output", func(t *testing.T) {
+		t.Parallel()
+		model := &mockLanguageModel{
+			generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+				return &Response{Content: []Content{TextContent{Text: "Use sk-abcdef123456"}}, FinishReason: FinishReasonStop}, nil
+			},
+		}
+		agent := NewAgent(model, WithModeration(testModeration))
+		_, err := agent.Generate(t.Context(), AgentCall{Prompt: "what's the key?"})
+
+		var guardErr *GuardrailError
+		require.ErrorAs(t, err, &guardErr)
+		require.Equal(t, GuardrailStageOutput, guardErr.Stage)
+	})
+
+	t.Run("moderation errors stop the run", func(t *testing.T) {
+		t.Parallel()
+		agent := NewAgent(&mockLanguageModel{}, WithModeration(failingModeration{}))
+		_, err := agent.Generate(t.Context(), AgentCall{Prompt: "hi"})
+		require.ErrorContains(t, err, "moderating input: unavailable")
+	})
+}
+
+type failingModeration struct{}
+
+func (failingModeration) Moderate(context.Context, ModerationCall) (*ModerationResponse, error) {
+	return nil, errors.New("unavailable")
+}
+func (failingModeration) Provider() string { return "test" }
+func (failingModeration) Model() string    { return "failing" }
+
+func TestGuardrailRewritesText(t *testing.T) {
+	t.Parallel()
+
+	upper := GuardrailFunc(func(_ context.Context, stage GuardrailStage, text string) (string, error) {
+		return string(stage) + ":" + strings.ToUpper(text), nil
+	})
+
+	t.Run("generate", func(t *testing.T) {
+		t.Parallel()
+		var prompt Prompt
+		model := &mockLanguageModel{
+			generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+				prompt = call.Prompt
+				return &Response{Content: []Content{TextContent{Text: "reply"}}, FinishReason: FinishReasonStop}, nil
+			},
+		}
+		agent := NewAgent(model, WithGuardrails(upper))
+		result, err := agent.Generate(t.Context(), AgentCall{Prompt: "hello"})
+		require.NoError(t, err)
+
+		require.Equal(t, TextPart{Text: "input:HELLO"}, prompt[len(prompt)-1].Content[0])
+		require.Equal(t, "output:REPLY", result.Response.Content.Text())
+		require.Equal(t, TextPart{Text: "output:REPLY"}, result.Steps[0].Messages[0].Content[0])
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		t.Parallel()
+		model := &mockLanguageModel{
+			streamFunc: func(ctx context.Context, call Call) (StreamResponse, error) {
+				return func(yield func(StreamPart) bool) {
+					for _, part := range []StreamPart{
+						{Type: StreamPartTypeTextStart, ID: "text-1"},
+						{Type: StreamPartTypeTextDelta, ID: "text-1", Delta: "reply"},
+						{Type: StreamPartTypeTextEnd, ID: "text-1"},
+						{Type: StreamPartTypeFinish, FinishReason: FinishReasonStop},
+					} {
+						if !yield(part) {
+							return
+						}
+					}
+				}, nil
+			},
+		}
+		agent := NewAgent(model, WithGuardrails(upper))
+		result, err := agent.Stream(t.Context(), AgentStreamCall{Prompt: "hello"})
+		require.NoError(t, err)
+		require.Equal(t, "output:REPLY", result.Response.Content.Text())
+		require.Equal(t, TextPart{Text: "output:REPLY"}, result.Steps[0].Messages[0].Content[0])
+	})
+}
+
+func TestGuardrailBlocksToolCalls(t *testing.T) {
+	t.Parallel()
+
+	toolCallParts := []StreamPart{
+		{Type: StreamPartTypeTextStart, ID: "text-1"},
+		{Type: StreamPartTypeTextDelta, ID: "text-1", Delta: "Deleting everything"},
+		{Type: StreamPartTypeTextEnd, ID: "text-1"},
+		{Type: StreamPartTypeToolCall, ID: "call-1", ToolCallName: "shell", ToolCallInput: `{"cmd":"ls"}`},
+		{Type: StreamPartTypeFinish, FinishReason: FinishReasonToolCalls},
+	}
+	streamModel := func(parts []StreamPart) *mockLanguageModel {
+		return &mockLanguageModel{
+			streamFunc: func(ctx context.Context, call Call) (StreamResponse, error) {
+				return func(yield func(StreamPart) bool) {
+					for _, part := range parts {
+						if !yield(part) {
+							return
+						}
+					}
+				}, nil
+			},
+		}
+	}
+	block := func(word string) Guardrail {
+		return GuardrailFunc(func(_ context.Context, stage GuardrailStage, text string) (string, error) {
+			if stage == GuardrailStageOutput && strings.Contains(text, word) {
+				return "", &GuardrailError{Stage: stage, Reason: "not allowed"}
+			}
+			return text, nil
+		})
+	}
+
+	t.Run("stream doesn't run the tools of a blocked step", func(t *testing.T) {
+		t.Parallel()
+		executed := false
+		shell := &mockTool{name: "shell", executeFunc: func(ctx context.Context, call ToolCall) (ToolResponse, error) {
+			executed = true
+			return NewTextResponse("ok"), nil
+		}}
+		agent := NewAgent(streamModel(toolCallParts), WithTools(shell), WithGuardrails(block("Deleting")))
+		_, err := agent.Stream(t.Context(), AgentStreamCall{Prompt: "clean up"})
+
+		var guardErr *GuardrailError
+		require.ErrorAs(t, err, &guardErr)
+		require.Equal(t, GuardrailStageOutput, guardErr.Stage)
+		require.False(t, executed)
+	})
+
+	t.Run("tool call inputs are checked", func(t *testing.T) {
+		t.Parallel()
+		executed := false
+		shell := &mockTool{name: "shell", executeFunc: func(ctx context.Context, call ToolCall) (ToolResponse, error) {
+			executed = true
+			return NewTextResponse("ok"), nil
+		}}
+		agent := NewAgent(streamModel(toolCallParts), WithTools(shell), WithGuardrails(block(`"ls"`)))
+		_, err := agent.Stream(t.Context(), AgentStreamCall{Prompt: "clean up"})
+		var guardErr *GuardrailError
+		require.ErrorAs(t, err, &guardErr)
+		require.False(t, executed)
+
+		model := &mockLanguageModel{
+			generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+				return &Response{
+					Content:      []Content{ToolCallContent{ToolCallID: "call-1", ToolName: "shell", Input: `{"cmd":"ls"}`}},
+					FinishReason: FinishReasonToolCalls,
+				}, nil
+			},
+		}
+		agent = NewAgent(model, WithTools(shell), WithGuardrails(block(`"ls"`)))
+		_, err = agent.Generate(t.Context(), AgentCall{Prompt: "clean up"})
+		require.ErrorAs(t, err, &guardErr)
+		require.False(t, executed)
+	})
+}
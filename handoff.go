@@ -0,0 +1,176 @@
+package fantasy
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// maxToolCallIDLength is the longest tool call ID every provider accepts.
+const maxToolCallIDLength = 40
+
+// providerOptionAliases lists the provider option keys read by providers
+// that reuse another provider's options.
+var providerOptionAliases = map[string][]string{
+	"azure":   {"openai"},
+	"bedrock": {"anthropic"},
+}
+
+// NormalizePromptFor prepares a conversation produced by one provider to be
+// continued with the given model, such as when a session switches from
+// Claude to GPT. It returns a new prompt where:
+//
+//   - provider options of other providers are removed, including reasoning
+//     signatures and cache control markers;
+//   - reasoning and provider-executed tool calls and results are dropped
+//     unless they carry metadata for the model's provider, since they can't
+//     be replayed elsewhere;
+//   - tool call IDs are rewritten to letters, digits, '_' and '-' within 40
+//     characters, and kept unique, with their results updated to match;
+//   - system messages are merged into a single leading system message.
+//
+// The input prompt is not modified.
+func NormalizePromptFor(model LanguageModel, prompt Prompt) Prompt {
+	keys := append([]string{model.Provider()}, providerOptionAliases[model.Provider()]...)
+	n := &promptNormalizer{
+		keep:    func(key string) bool { return slices.Contains(keys, key) },
+		ids:     map[string]string{},
+		usedIDs: map[string]bool{},
+	}
+
+	var system *Message
+	var result Prompt
+	for _, msg := range prompt {
+		parts := n.parts(msg.Content)
+		if msg.Role == MessageRoleSystem {
+			if system == nil {
+				system = &Message{Role: MessageRoleSystem}
+			}
+			system.Content = append(system.Content, parts...)
+			system.ProviderOptions = mergeProviderOptions(system.ProviderOptions, n.options(msg.ProviderOptions))
+			continue
+		}
+		if len(parts) == 0 {
+			continue
+		}
+		result = append(result, Message{
+			Role:            msg.Role,
+			Content:         parts,
+			ProviderOptions: n.options(msg.ProviderOptions),
+		})
+	}
+	if system != nil && len(system.Content) > 0 {
+		result = append(Prompt{*system}, result...)
+	}
+	return result
+}
+
+// promptNormalizer holds the state of NormalizePromptFor.
+type promptNormalizer struct {
+	keep    func(key string) bool
+	ids     map[string]string
+	usedIDs map[string]bool
+}
+
+func (n *promptNormalizer) parts(parts []MessagePart) []MessagePart {
+	result := make([]MessagePart, 0, len(parts))
+	for _, part := range parts {
+		switch part.GetType() {
+		case ContentTypeText:
+			text, _ := AsMessagePart[TextPart](part)
+			text.ProviderOptions = n.options(text.ProviderOptions)
+			result = append(result, text)
+		case ContentTypeReasoning:
+			reasoning, _ := AsMessagePart[ReasoningPart](part)
+			if reasoning.ProviderOptions = n.options(reasoning.ProviderOptions); reasoning.ProviderOptions == nil {
+				continue
+			}
+			result = append(result, reasoning)
+		case ContentTypeFile:
+			file, _ := AsMessagePart[FilePart](part)
+			file.ProviderOptions = n.options(file.ProviderOptions)
+			result = append(result, file)
+		case ContentTypeToolCall:
+			call, _ := AsMessagePart[ToolCallPart](part)
+			call.ProviderOptions = n.options(call.ProviderOptions)
+			if call.ProviderExecuted && call.ProviderOptions == nil {
+				continue
+			}
+			call.ToolCallID = n.toolCallID(call.ToolCallID)
+			result = append(result, call)
+		case ContentTypeToolResult:
+			toolResult, _ := AsMessagePart[ToolResultPart](part)
+			toolResult.ProviderOptions = n.options(toolResult.ProviderOptions)
+			if toolResult.ProviderExecuted && toolResult.ProviderOptions == nil {
+				continue
+			}
+			toolResult.ToolCallID = n.toolCallID(toolResult.ToolCallID)
+			result = append(result, toolResult)
+		default:
+			result = append(result, part)
+		}
+	}
+	return result
+}
+
+// options returns the options the model's provider reads, or nil if there
+// are none.
+func (n *promptNormalizer) options(options ProviderOptions) ProviderOptions {
+	var result ProviderOptions
+	for key, data := range options {
+		if !n.keep(key) {
+			continue
+		}
+		if result == nil {
+			result = ProviderOptions{}
+		}
+		result[key] = data
+	}
+	return result
+}
+
+// toolCallID returns the normalized ID for a tool call, assigning a new one
+// the first time the ID is seen so calls and results stay paired.
+func (n *promptNormalizer) toolCallID(id string) string {
+	if normalized, ok := n.ids[id]; ok {
+		return normalized
+	}
+	base := cmp.Or(sanitizeToolCallID(id), "call")
+	normalized := base
+	for i := 1; n.usedIDs[normalized]; i++ {
+		suffix := fmt.Sprintf("_%d", i)
+		normalized = base[:min(len(base), maxToolCallIDLength-len(suffix))] + suffix
+	}
+	n.ids[id] = normalized
+	n.usedIDs[normalized] = true
+	return normalized
+}
+
+// sanitizeToolCallID replaces characters providers reject with '_' and
+// truncates the ID.
+func sanitizeToolCallID(id string) string {
+	var sb strings.Builder
+	for _, r := range id {
+		if sb.Len() == maxToolCallIDLength {
+			break
+		}
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			sb.WriteRune(r)
+		default:
+			sb.WriteByte('_')
+		}
+	}
+	return sb.String()
+}
+
+func mergeProviderOptions(dst, src ProviderOptions) ProviderOptions {
+	for key, data := range src {
+		if dst == nil {
+			dst = ProviderOptions{}
+		}
+		dst[key] = data
+	}
+	return dst
+}
@@ -0,0 +1,92 @@
+package fantasy
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type namedProviderModel struct {
+	mockLanguageModel
+	provider string
+}
+
+func (m *namedProviderModel) Provider() string { return m.provider }
+
+func TestNormalizePromptFor(t *testing.T) {
+	t.Parallel()
+
+	signature := ProviderOptions{"anthropic": &mockProviderData{Key: "signature"}}
+	longID := "toolu_" + strings.Repeat("x", 50)
+	prompt := Prompt{
+		NewSystemMessage("You are helpful."),
+		{
+			Role:            MessageRoleUser,
+			Content:         []MessagePart{TextPart{Text: "Search", ProviderOptions: signature}},
+			ProviderOptions: signature,
+		},
+		{
+			Role: MessageRoleAssistant,
+			Content: []MessagePart{
+				ReasoningPart{Text: "Thinking", ProviderOptions: signature},
+				ToolCallPart{ToolCallID: "srvtoolu_1", ToolName: "web_search", ProviderExecuted: true, ProviderOptions: signature},
+				ToolResultPart{ToolCallID: "srvtoolu_1", ProviderExecuted: true, ProviderOptions: signature},
+				ToolCallPart{ToolCallID: "fc.1", ToolName: "lookup", Input: "{}"},
+				ToolCallPart{ToolCallID: "fc:1", ToolName: "lookup", Input: "{}"},
+				ToolCallPart{ToolCallID: longID, ToolName: "lookup", Input: "{}"},
+			},
+		},
+		{
+			Role: MessageRoleTool,
+			Content: []MessagePart{
+				ToolResultPart{ToolCallID: "fc.1", Output: ToolResultOutputContentText{Text: "a"}},
+				ToolResultPart{ToolCallID: "fc:1", Output: ToolResultOutputContentText{Text: "b"}},
+				ToolResultPart{ToolCallID: longID, Output: ToolResultOutputContentText{Text: "c"}},
+			},
+		},
+		NewSystemMessage("Be brief."),
+	}
+
+	t.Run("to another provider", func(t *testing.T) {
+		t.Parallel()
+
+		normalized := NormalizePromptFor(&namedProviderModel{provider: "openai"}, prompt)
+		require.Equal(t, Prompt{
+			{Role: MessageRoleSystem, Content: []MessagePart{TextPart{Text: "You are helpful."}, TextPart{Text: "Be brief."}}},
+			NewUserMessage("Search"),
+			{
+				Role: MessageRoleAssistant,
+				Content: []MessagePart{
+					ToolCallPart{ToolCallID: "fc_1", ToolName: "lookup", Input: "{}"},
+					ToolCallPart{ToolCallID: "fc_1_1", ToolName: "lookup", Input: "{}"},
+					ToolCallPart{ToolCallID: longID[:40], ToolName: "lookup", Input: "{}"},
+				},
+			},
+			{
+				Role: MessageRoleTool,
+				Content: []MessagePart{
+					ToolResultPart{ToolCallID: "fc_1", Output: ToolResultOutputContentText{Text: "a"}},
+					ToolResultPart{ToolCallID: "fc_1_1", Output: ToolResultOutputContentText{Text: "b"}},
+					ToolResultPart{ToolCallID: longID[:40], Output: ToolResultOutputContentText{Text: "c"}},
+				},
+			},
+		}, normalized)
+
+		// The input is left untouched.
+		require.Len(t, prompt, 5)
+		require.Len(t, prompt[2].Content, 6)
+	})
+
+	t.Run("to the same provider family", func(t *testing.T) {
+		t.Parallel()
+
+		normalized := NormalizePromptFor(&namedProviderModel{provider: "bedrock"}, prompt)
+		require.Len(t, normalized, 4)
+		require.Equal(t, signature, normalized[1].ProviderOptions)
+		require.Len(t, normalized[2].Content, 6)
+		reasoning, ok := AsMessagePart[ReasoningPart](normalized[2].Content[0])
+		require.True(t, ok)
+		require.Equal(t, signature, reasoning.ProviderOptions)
+	})
+}
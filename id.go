@@ -0,0 +1,47 @@
+package fantasy
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+)
+
+// IDGenerator generates identifiers for tool calls, sources and other
+// content that the provider returns without an ID.
+type IDGenerator = func() string
+
+var idGenerator atomic.Pointer[IDGenerator]
+
+// SetIDGenerator sets the generator used by NewID for the whole process, so
+// tests and replays can produce stable transcripts. Passing nil restores the
+// default, which returns random UUIDs.
+func SetIDGenerator(generator IDGenerator) {
+	if generator == nil {
+		idGenerator.Store(nil)
+		return
+	}
+	idGenerator.Store(&generator)
+}
+
+// NewID returns a new identifier from the current ID generator.
+func NewID() string {
+	if generator := idGenerator.Load(); generator != nil {
+		return (*generator)()
+	}
+	return uuid.NewString()
+}
+
+// SequentialIDGenerator returns a generator that yields prefix-1, prefix-2
+// and so on. It is safe for concurrent use.
+func SequentialIDGenerator(prefix string) IDGenerator {
+	var mu sync.Mutex
+	var n int
+	return func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		n++
+		return fmt.Sprintf("%s-%d", prefix, n)
+	}
+}
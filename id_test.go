@@ -0,0 +1,21 @@
+package fantasy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Not parallel: the ID generator is process-wide.
+func TestSetIDGenerator(t *testing.T) {
+	t.Cleanup(func() { SetIDGenerator(nil) })
+
+	require.Len(t, NewID(), 36)
+
+	SetIDGenerator(SequentialIDGenerator("call"))
+	require.Equal(t, "call-1", NewID())
+	require.Equal(t, "call-2", NewID())
+
+	SetIDGenerator(nil)
+	require.NotEqual(t, NewID(), NewID())
+}
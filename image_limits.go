@@ -0,0 +1,222 @@
+package fantasy
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	_ "image/gif" // Register the GIF decoder.
+	"image/jpeg"
+	"image/png"
+	"math"
+	"slices"
+	"strings"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // Register the WebP decoder.
+)
+
+// ImageLimits are the limits a provider puts on input images. Images
+// exceeding them are downscaled and recompressed before they're sent, since
+// providers otherwise reject the whole call. The zero value has no limits.
+type ImageLimits struct {
+	// MaxBytes is the largest encoded size of an image.
+	MaxBytes int
+	// MaxEdge is the largest width and height of an image.
+	MaxEdge int
+	// MaxShortEdge is the largest length of the shorter side of an image.
+	MaxShortEdge int
+	// MediaTypes lists the image types the provider accepts. Images of
+	// other types are converted to PNG or JPEG. When it's empty all types
+	// are accepted.
+	MediaTypes []string
+}
+
+// jpegQualities are the qualities JPEG images are tried with, from the
+// best, before they're downscaled further to fit MaxBytes.
+var jpegQualities = []int{90, 75, 60}
+
+// PrepareImage downscales and recompresses an image to fit within the
+// limits. The part is returned as is when it's not an image, already fits
+// or can't be decoded. The warning describes the conversion, or why the
+// image couldn't be made to fit.
+func (l ImageLimits) PrepareImage(part FilePart) (FilePart, *CallWarning) {
+	if !strings.HasPrefix(part.MediaType, "image/") {
+		return part, nil
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(part.Data))
+	if err != nil {
+		return part, nil
+	}
+	size := image.Pt(cfg.Width, cfg.Height)
+	target := l.fit(size)
+	if target == size && l.accepts(part.MediaType) && (l.MaxBytes <= 0 || len(part.Data) <= l.MaxBytes) {
+		return part, nil
+	}
+	img, _, err := image.Decode(bytes.NewReader(part.Data))
+	if err != nil {
+		return part, nil
+	}
+
+	useJPEG := l.accepts("image/jpeg") && (format == "jpeg" || !l.accepts("image/png") ||
+		(isOpaque(img) && l.MaxBytes > 0 && len(part.Data) > l.MaxBytes))
+	if !useJPEG && !l.accepts("image/png") {
+		return part, &CallWarning{
+			Type:    CallWarningTypeOther,
+			Message: fmt.Sprintf("image of type %s can't be converted to a type the provider accepts", part.MediaType),
+		}
+	}
+	for range 8 {
+		scaled := resizeImage(img, target)
+		var data []byte
+		var mediaType string
+		qualities := []int{0}
+		if useJPEG {
+			qualities = jpegQualities
+		}
+		for _, quality := range qualities {
+			data, mediaType, err = encodeImage(scaled, useJPEG, quality)
+			if err != nil {
+				return part, nil
+			}
+			if l.MaxBytes <= 0 || len(data) <= l.MaxBytes {
+				prepared := part
+				prepared.Data = data
+				prepared.MediaType = mediaType
+				return prepared, &CallWarning{
+					Type: CallWarningTypeOther,
+					Message: fmt.Sprintf(
+						"image converted from %s %dx%d (%d bytes) to %s %dx%d (%d bytes) to fit the provider's limits",
+						part.MediaType, size.X, size.Y, len(part.Data), mediaType, target.X, target.Y, len(data),
+					),
+				}
+			}
+		}
+		target = image.Pt(max(1, target.X*3/4), max(1, target.Y*3/4))
+	}
+	return part, &CallWarning{
+		Type:    CallWarningTypeOther,
+		Message: fmt.Sprintf("image of %d bytes couldn't be reduced below the provider's limit of %d bytes", len(part.Data), l.MaxBytes),
+	}
+}
+
+// PrepareImages prepares the images of the prompt, in file parts and tool
+// results, with the limits returned for each of them. The prompt is left
+// untouched; the returned prompt shares the messages that didn't change.
+func PrepareImages(prompt Prompt, limits func(FilePart) ImageLimits) (Prompt, []CallWarning) {
+	var warnings []CallWarning
+	var prepared Prompt
+	for i, msg := range prompt {
+		var content []MessagePart
+		for j, part := range msg.Content {
+			newPart, warning := prepareImagePart(part, limits)
+			if warning != nil {
+				warnings = append(warnings, *warning)
+			}
+			if newPart == nil {
+				continue
+			}
+			if content == nil {
+				content = slices.Clone(msg.Content)
+			}
+			content[j] = newPart
+		}
+		if content == nil {
+			continue
+		}
+		if prepared == nil {
+			prepared = slices.Clone(prompt)
+		}
+		prepared[i].Content = content
+	}
+	if prepared == nil {
+		return prompt, warnings
+	}
+	return prepared, warnings
+}
+
+// prepareImagePart prepares the image of part, if it holds one, returning
+// the part to replace it with if it changed.
+func prepareImagePart(part MessagePart, limits func(FilePart) ImageLimits) (MessagePart, *CallWarning) {
+	if file, ok := AsMessagePart[FilePart](part); ok {
+		prepared, warning := limits(file).PrepareImage(file)
+		if warning == nil || bytes.Equal(prepared.Data, file.Data) {
+			return nil, warning
+		}
+		return prepared, warning
+	}
+	result, ok := AsMessagePart[ToolResultPart](part)
+	if !ok {
+		return nil, nil
+	}
+	media, ok := AsToolResultOutputType[ToolResultOutputContentMedia](result.Output)
+	if !ok || !strings.HasPrefix(media.MediaType, "image/") {
+		return nil, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(media.Data)
+	if err != nil {
+		return nil, nil
+	}
+	file := FilePart{Data: data, MediaType: media.MediaType}
+	prepared, warning := limits(file).PrepareImage(file)
+	if warning == nil || bytes.Equal(prepared.Data, data) {
+		return nil, warning
+	}
+	media.Data = base64.StdEncoding.EncodeToString(prepared.Data)
+	media.MediaType = prepared.MediaType
+	result.Output = media
+	return result, warning
+}
+
+func (l ImageLimits) accepts(mediaType string) bool {
+	return len(l.MediaTypes) == 0 || slices.Contains(l.MediaTypes, mediaType)
+}
+
+// fit returns the size to downscale size to so it fits MaxEdge and
+// MaxShortEdge, keeping its aspect ratio.
+func (l ImageLimits) fit(size image.Point) image.Point {
+	scale := 1.0
+	if l.MaxEdge > 0 {
+		scale = math.Min(scale, float64(l.MaxEdge)/float64(max(size.X, size.Y)))
+	}
+	if l.MaxShortEdge > 0 {
+		scale = math.Min(scale, float64(l.MaxShortEdge)/float64(min(size.X, size.Y)))
+	}
+	if scale >= 1 {
+		return size
+	}
+	return image.Pt(
+		max(1, int(float64(size.X)*scale)),
+		max(1, int(float64(size.Y)*scale)),
+	)
+}
+
+func resizeImage(img image.Image, size image.Point) image.Image {
+	if img.Bounds().Size() == size {
+		return img
+	}
+	dst := image.NewRGBA(image.Rectangle{Max: size})
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), draw.Src, nil)
+	return dst
+}
+
+func encodeImage(img image.Image, useJPEG bool, quality int) ([]byte, string, error) {
+	var buf bytes.Buffer
+	if useJPEG {
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/jpeg", nil
+	}
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), "image/png", nil
+}
+
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package fantasy
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testPNG(t *testing.T, width, height int, noisy bool) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := range height {
+		for x := range width {
+			c := color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255}
+			if noisy {
+				c.B = uint8((x*7919 + y*104729) % 251)
+			}
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func imageConfig(t *testing.T, data []byte) (image.Config, string) {
+	t.Helper()
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	require.NoError(t, err)
+	return cfg, format
+}
+
+func TestImageLimitsPrepareImage(t *testing.T) {
+	t.Parallel()
+
+	t.Run("leaves fitting images alone", func(t *testing.T) {
+		t.Parallel()
+		part := FilePart{Data: testPNG(t, 100, 50, false), MediaType: "image/png"}
+		prepared, warning := ImageLimits{MaxEdge: 100, MaxBytes: len(part.Data)}.PrepareImage(part)
+		require.Nil(t, warning)
+		require.Equal(t, part, prepared)
+	})
+
+	t.Run("leaves other files alone", func(t *testing.T) {
+		t.Parallel()
+		part := FilePart{Data: []byte("%PDF"), MediaType: "application/pdf"}
+		prepared, warning := ImageLimits{MaxBytes: 1}.PrepareImage(part)
+		require.Nil(t, warning)
+		require.Equal(t, part, prepared)
+	})
+
+	t.Run("downscales to the edges", func(t *testing.T) {
+		t.Parallel()
+		part := FilePart{Data: testPNG(t, 400, 200, false), MediaType: "image/png"}
+		prepared, warning := ImageLimits{MaxEdge: 300, MaxShortEdge: 100}.PrepareImage(part)
+		require.NotNil(t, warning)
+		require.Contains(t, warning.Message, "400x200")
+		cfg, format := imageConfig(t, prepared.Data)
+		require.Equal(t, "png", format)
+		require.Equal(t, "image/png", prepared.MediaType)
+		require.Equal(t, 200, cfg.Width)
+		require.Equal(t, 100, cfg.Height)
+	})
+
+	t.Run("recompresses opaque images to fit the size", func(t *testing.T) {
+		t.Parallel()
+		part := FilePart{Data: testPNG(t, 300, 300, true), MediaType: "image/png"}
+		limits := ImageLimits{MaxBytes: len(part.Data) / 4, MediaTypes: []string{"image/png", "image/jpeg"}}
+		prepared, warning := limits.PrepareImage(part)
+		require.NotNil(t, warning)
+		require.LessOrEqual(t, len(prepared.Data), limits.MaxBytes)
+		_, format := imageConfig(t, prepared.Data)
+		require.Equal(t, "jpeg", format)
+		require.Equal(t, "image/jpeg", prepared.MediaType)
+	})
+
+	t.Run("converts unaccepted types", func(t *testing.T) {
+		t.Parallel()
+		part := FilePart{Data: testPNG(t, 10, 10, false), MediaType: "image/png"}
+		prepared, warning := ImageLimits{MediaTypes: []string{"image/jpeg"}}.PrepareImage(part)
+		require.NotNil(t, warning)
+		require.Equal(t, "image/jpeg", prepared.MediaType)
+	})
+}
+
+func TestPrepareImages(t *testing.T) {
+	t.Parallel()
+
+	big := testPNG(t, 400, 400, false)
+	prompt := Prompt{
+		NewUserMessage("look", FilePart{Data: big, MediaType: "image/png"}),
+		{Role: MessageRoleTool, Content: []MessagePart{
+			ToolResultPart{ToolCallID: "call-1", Output: ToolResultOutputContentMedia{
+				Data:      base64.StdEncoding.EncodeToString(big),
+				MediaType: "image/png",
+			}},
+		}},
+	}
+	prepared, warnings := PrepareImages(prompt, func(FilePart) ImageLimits {
+		return ImageLimits{MaxEdge: 100}
+	})
+	require.Len(t, warnings, 2)
+
+	file, ok := AsMessagePart[FilePart](prepared[0].Content[1])
+	require.True(t, ok)
+	cfg, _ := imageConfig(t, file.Data)
+	require.Equal(t, 100, cfg.Width)
+
+	result, ok := AsMessagePart[ToolResultPart](prepared[1].Content[0])
+	require.True(t, ok)
+	media, ok := AsToolResultOutputType[ToolResultOutputContentMedia](result.Output)
+	require.True(t, ok)
+	data, err := base64.StdEncoding.DecodeString(media.Data)
+	require.NoError(t, err)
+	cfg, _ = imageConfig(t, data)
+	require.Equal(t, 100, cfg.Width)
+
+	original, ok := AsMessagePart[FilePart](prompt[0].Content[1])
+	require.True(t, ok)
+	require.Equal(t, big, original.Data)
+
+	unchanged, warnings := PrepareImages(prompt, func(FilePart) ImageLimits { return ImageLimits{} })
+	require.Empty(t, warnings)
+	require.Equal(t, prompt, unchanged)
+}
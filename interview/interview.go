@@ -0,0 +1,307 @@
+// Package interview drives an agent to fill a typed struct by asking the
+// user questions, one turn at a time, until every required field holds a
+// value that validates against the struct's schema. The progress can be
+// saved and resumed, e.g. across the requests of a chat bot.
+//
+// Example:
+//
+//	type Signup struct {
+//	    Name  string `json:"name" description:"Full name"`
+//	    Email string `json:"email" format:"email"`
+//	    Team  string `json:"team,omitempty"`
+//	}
+//
+//	iv := interview.New[Signup](model)
+//	turn, err := iv.Start(ctx)
+//	for err == nil && !turn.Done {
+//	    turn, err = iv.Answer(ctx, ask(turn.Message))
+//	}
+//	signup, err := iv.Result()
+package interview
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/schema"
+)
+
+const recordToolName = "record_answers"
+
+const systemPrompt = `You are interviewing the user to fill in a form. Ask for the missing fields conversationally, one or a few related ones at a time. Whenever the user gives values, call the %s tool with them right away, then ask about what is still missing. Don't invent values the user didn't give. When nothing required is missing, thank the user and stop asking.%s
+
+Fields:
+%s
+Recorded so far:
+%s
+Still required: %s`
+
+// State is the progress of an interview, which Resume continues from. It
+// marshals to JSON.
+type State struct {
+	Messages []fantasy.Message `json:"messages"`
+	// Values holds the fields recorded so far, by JSON name.
+	Values map[string]any `json:"values"`
+}
+
+// Turn is the outcome of a step of the interview.
+type Turn struct {
+	// Message is the agent's reply to show the user: the next question, or
+	// a closing message when Done.
+	Message string
+	// Remaining lists the required fields still missing.
+	Remaining []string
+	// Errors holds the reasons values given for fields were rejected in
+	// this turn, by field.
+	Errors map[string]string
+	// Done reports whether the struct is complete and valid.
+	Done  bool
+	Usage fantasy.Usage
+}
+
+// Option configures an Interview.
+type Option func(*options)
+
+type options struct {
+	instructions string
+	agentOptions []fantasy.AgentOption
+}
+
+// WithInstructions adds context to the agent's instructions, such as who
+// the user is or the tone to use.
+func WithInstructions(instructions string) Option {
+	return func(o *options) {
+		o.instructions = instructions
+	}
+}
+
+// WithAgentOptions configures the agent that conducts the interview.
+func WithAgentOptions(agentOptions ...fantasy.AgentOption) Option {
+	return func(o *options) {
+		o.agentOptions = append(o.agentOptions, agentOptions...)
+	}
+}
+
+// Interview fills a T by asking the user questions.
+type Interview[T any] struct {
+	model   fantasy.LanguageModel
+	schema  schema.Schema
+	options options
+
+	mu    sync.Mutex
+	state State
+	// errors collects the values rejected during a turn.
+	errors map[string]string
+}
+
+// New returns an interview that fills a T with the help of the model.
+func New[T any](model fantasy.LanguageModel, opts ...Option) *Interview[T] {
+	return Resume[T](model, State{}, opts...)
+}
+
+// Resume returns an interview that continues from state.
+func Resume[T any](model fantasy.LanguageModel, state State, opts ...Option) *Interview[T] {
+	iv := &Interview[T]{
+		model:  model,
+		schema: schema.Generate(reflect.TypeFor[T]()),
+		state:  State{Messages: slices.Clone(state.Messages), Values: maps.Clone(state.Values)},
+	}
+	if iv.state.Values == nil {
+		iv.state.Values = map[string]any{}
+	}
+	for _, opt := range opts {
+		opt(&iv.options)
+	}
+	return iv
+}
+
+// Start asks the first question.
+func (iv *Interview[T]) Start(ctx context.Context) (Turn, error) {
+	return iv.Answer(ctx, "Hello.")
+}
+
+// Answer records the user's answer and returns the agent's reply.
+func (iv *Interview[T]) Answer(ctx context.Context, answer string) (Turn, error) {
+	iv.mu.Lock()
+	messages := append(slices.Clone(iv.state.Messages), fantasy.NewUserMessage(answer))
+	iv.errors = map[string]string{}
+	prompt := iv.systemPrompt()
+	iv.mu.Unlock()
+
+	agentOptions := append([]fantasy.AgentOption{
+		fantasy.WithSystemPrompt(prompt),
+		fantasy.WithTools(&recordTool[T]{interview: iv}),
+	}, iv.options.agentOptions...)
+	result, err := fantasy.NewAgent(iv.model, agentOptions...).Generate(ctx, fantasy.AgentCall{Messages: messages})
+	if err != nil {
+		return Turn{}, err
+	}
+
+	iv.mu.Lock()
+	defer iv.mu.Unlock()
+	for _, step := range result.Steps {
+		messages = append(messages, step.Messages...)
+	}
+	iv.state.Messages = messages
+	remaining := iv.remaining()
+	turn := Turn{
+		Message:   result.Response.Content.Text(),
+		Remaining: remaining,
+		Done:      len(remaining) == 0,
+		Usage:     result.TotalUsage,
+	}
+	if len(iv.errors) > 0 {
+		turn.Errors = iv.errors
+	}
+	if turn.Done {
+		_, err := iv.result()
+		turn.Done = err == nil
+	}
+	return turn, nil
+}
+
+// Remaining lists the required fields still missing.
+func (iv *Interview[T]) Remaining() []string {
+	iv.mu.Lock()
+	defer iv.mu.Unlock()
+	return iv.remaining()
+}
+
+// State returns the progress of the interview, to continue it later with
+// Resume.
+func (iv *Interview[T]) State() State {
+	iv.mu.Lock()
+	defer iv.mu.Unlock()
+	return State{Messages: slices.Clone(iv.state.Messages), Values: maps.Clone(iv.state.Values)}
+}
+
+// Result decodes the recorded values into a T, validating it against its
+// schema.
+func (iv *Interview[T]) Result() (T, error) {
+	iv.mu.Lock()
+	defer iv.mu.Unlock()
+	return iv.result()
+}
+
+func (iv *Interview[T]) result() (T, error) {
+	data, err := json.Marshal(iv.state.Values)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return schema.ParseAndValidateAs[T](string(data))
+}
+
+func (iv *Interview[T]) remaining() []string {
+	var remaining []string
+	for _, name := range iv.schema.Required {
+		if _, ok := iv.state.Values[name]; !ok {
+			remaining = append(remaining, name)
+		}
+	}
+	return remaining
+}
+
+// record validates the values given for each field and keeps the valid
+// ones.
+func (iv *Interview[T]) record(values map[string]any) (recorded, rejected []string) {
+	iv.mu.Lock()
+	defer iv.mu.Unlock()
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		field, ok := iv.schema.Properties[name]
+		if !ok {
+			iv.errors[name] = "unknown field"
+			rejected = append(rejected, fmt.Sprintf("%s: unknown field", name))
+			continue
+		}
+		if err := schema.ValidateAgainstSchema(values[name], *field); err != nil {
+			iv.errors[name] = err.Error()
+			rejected = append(rejected, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		iv.state.Values[name] = values[name]
+		recorded = append(recorded, name)
+	}
+	return recorded, rejected
+}
+
+func (iv *Interview[T]) systemPrompt() string {
+	var fields strings.Builder
+	for _, name := range slices.Sorted(maps.Keys(iv.schema.Properties)) {
+		field := iv.schema.Properties[name]
+		fmt.Fprintf(&fields, "- %s (%s", name, field.Type)
+		if slices.Contains(iv.schema.Required, name) {
+			fields.WriteString(", required")
+		}
+		fields.WriteString(")")
+		if field.Description != "" {
+			fmt.Fprintf(&fields, ": %s", field.Description)
+		}
+		fields.WriteString("\n")
+	}
+	recorded, _ := json.Marshal(iv.state.Values)
+	remaining := strings.Join(iv.remaining(), ", ")
+	if remaining == "" {
+		remaining = "nothing"
+	}
+	instructions := ""
+	if iv.options.instructions != "" {
+		instructions = "\n\n" + iv.options.instructions
+	}
+	return fmt.Sprintf(systemPrompt, recordToolName, instructions, fields.String(), recorded, remaining)
+}
+
+// recordTool is the tool the agent records the user's answers with.
+type recordTool[T any] struct {
+	interview       *Interview[T]
+	providerOptions fantasy.ProviderOptions
+}
+
+func (t *recordTool[T]) Info() fantasy.ToolInfo {
+	parameters := map[string]any{}
+	for name, field := range t.interview.schema.Properties {
+		parameters[name] = schema.ToMap(*field)
+	}
+	return fantasy.ToolInfo{
+		Name:        recordToolName,
+		Description: "Records the form field values the user gave.",
+		Parameters:  parameters,
+	}
+}
+
+func (t *recordTool[T]) Run(_ context.Context, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+	var values map[string]any
+	if err := json.Unmarshal([]byte(call.Input), &values); err != nil {
+		return fantasy.NewTextErrorResponse("invalid input: " + err.Error()), nil
+	}
+	recorded, rejected := t.interview.record(values)
+
+	var reply strings.Builder
+	if len(recorded) > 0 {
+		fmt.Fprintf(&reply, "Recorded: %s.", strings.Join(recorded, ", "))
+	}
+	for _, reason := range rejected {
+		fmt.Fprintf(&reply, "\nRejected %s. Ask the user again.", reason)
+	}
+	if remaining := t.interview.Remaining(); len(remaining) > 0 {
+		fmt.Fprintf(&reply, "\nStill required: %s.", strings.Join(remaining, ", "))
+	} else {
+		reply.WriteString("\nAll required fields are filled in.")
+	}
+	return fantasy.NewTextResponse(strings.TrimSpace(reply.String())), nil
+}
+
+func (t *recordTool[T]) ProviderOptions() fantasy.ProviderOptions {
+	return t.providerOptions
+}
+
+func (t *recordTool[T]) SetProviderOptions(opts fantasy.ProviderOptions) {
+	t.providerOptions = opts
+}
@@ -0,0 +1,130 @@
+package interview
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/require"
+)
+
+type mockModel struct {
+	generate func(ctx context.Context, call fantasy.Call) (*fantasy.Response, error)
+}
+
+func (m *mockModel) Generate(ctx context.Context, call fantasy.Call) (*fantasy.Response, error) {
+	return m.generate(ctx, call)
+}
+
+func (m *mockModel) Stream(context.Context, fantasy.Call) (fantasy.StreamResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockModel) GenerateObject(context.Context, fantasy.ObjectCall) (*fantasy.ObjectResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockModel) StreamObject(context.Context, fantasy.ObjectCall) (fantasy.ObjectStreamResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockModel) Provider() string { return "mock" }
+
+func (m *mockModel) Model() string { return "mock" }
+
+type signup struct {
+	Name string `json:"name" description:"Full name"`
+	Age  int    `json:"age" minimum:"18"`
+	Team string `json:"team,omitempty"`
+}
+
+// scriptedModel records the values given for an answer with the tool, and
+// replies to a tool result with the reply whose key it contains.
+func scriptedModel(t *testing.T, values map[string]map[string]any, replies map[string]string) (*mockModel, *[]fantasy.Call) {
+	var calls []fantasy.Call
+	return &mockModel{generate: func(ctx context.Context, call fantasy.Call) (*fantasy.Response, error) {
+		calls = append(calls, call)
+		last := call.Prompt[len(call.Prompt)-1]
+		if last.Role == fantasy.MessageRoleTool {
+			result := last.Content[0].(fantasy.ToolResultPart).Output.(fantasy.ToolResultOutputContentText).Text
+			for key, reply := range replies {
+				if strings.Contains(result, key) {
+					return &fantasy.Response{
+						Content:      fantasy.ResponseContent{fantasy.TextContent{Text: reply}},
+						FinishReason: fantasy.FinishReasonStop,
+					}, nil
+				}
+			}
+			t.Fatalf("unexpected tool result %q", result)
+		}
+		answer := last.Content[0].(fantasy.TextPart).Text
+		input, ok := values[answer]
+		if !ok {
+			return &fantasy.Response{
+				Content:      fantasy.ResponseContent{fantasy.TextContent{Text: "What's your name?"}},
+				FinishReason: fantasy.FinishReasonStop,
+			}, nil
+		}
+		data, err := json.Marshal(input)
+		require.NoError(t, err)
+		return &fantasy.Response{
+			Content: fantasy.ResponseContent{fantasy.ToolCallContent{
+				ToolCallID: "call-" + answer,
+				ToolName:   recordToolName,
+				Input:      string(data),
+			}},
+			FinishReason: fantasy.FinishReasonToolCalls,
+		}, nil
+	}}, &calls
+}
+
+func TestInterview(t *testing.T) {
+	t.Parallel()
+
+	model, calls := scriptedModel(t, map[string]map[string]any{
+		"I'm Ada, 12":    {"name": "Ada", "age": 12},
+		"Sorry, I'm 36.": {"age": 36},
+	}, map[string]string{
+		"Rejected age":                      "You must be 18. How old are you?",
+		"All required fields are filled in": "Thanks, you're all set!",
+	})
+
+	iv := New[signup](model, WithInstructions("You're onboarding new members."))
+	turn, err := iv.Start(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, "What's your name?", turn.Message)
+	require.Equal(t, []string{"name", "age"}, turn.Remaining)
+	require.False(t, turn.Done)
+
+	system := (*calls)[0].Prompt[0].Content[0].(fantasy.TextPart).Text
+	require.Contains(t, system, "- name (string, required): Full name")
+	require.Contains(t, system, "- team (string)")
+	require.Contains(t, system, "You're onboarding new members.")
+
+	turn, err = iv.Answer(t.Context(), "I'm Ada, 12")
+	require.NoError(t, err)
+	require.Equal(t, []string{"age"}, turn.Remaining)
+	require.Contains(t, turn.Errors, "age")
+	require.Equal(t, "You must be 18. How old are you?", turn.Message)
+
+	// Resume from the saved state, as a chat bot would on the next request.
+	data, err := json.Marshal(iv.State())
+	require.NoError(t, err)
+	var state State
+	require.NoError(t, json.Unmarshal(data, &state))
+	iv = Resume[signup](model, state)
+
+	turn, err = iv.Answer(t.Context(), "Sorry, I'm 36.")
+	require.NoError(t, err)
+	require.True(t, turn.Done)
+	require.Empty(t, turn.Remaining)
+	require.Equal(t, "Thanks, you're all set!", turn.Message)
+
+	result, err := iv.Result()
+	require.NoError(t, err)
+	require.Equal(t, signup{Name: "Ada", Age: 36}, result)
+	require.Greater(t, len(iv.State().Messages), 6)
+}
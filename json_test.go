@@ -646,6 +646,56 @@ func TestPromptSerialization(t *testing.T) {
 	})
 }
 
+func TestMarshalPrompt(t *testing.T) {
+	prompt := Prompt{
+		NewSystemMessage("You are helpful"),
+		NewUserMessage("Hello"),
+	}
+
+	data, err := MarshalPrompt(prompt)
+	if err != nil {
+		t.Fatalf("failed to marshal prompt: %v", err)
+	}
+
+	var doc struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("failed to read document: %v", err)
+	}
+	if doc.Version != PromptFormatVersion {
+		t.Errorf("version mismatch: got %d, want %d", doc.Version, PromptFormatVersion)
+	}
+
+	decoded, err := UnmarshalPrompt(data)
+	if err != nil {
+		t.Fatalf("failed to unmarshal prompt: %v", err)
+	}
+	if !reflect.DeepEqual(prompt, decoded) {
+		t.Errorf("prompt mismatch after round-trip:\ngot:  %#v\nwant: %#v", decoded, prompt)
+	}
+
+	t.Run("plain message array", func(t *testing.T) {
+		data, err := json.Marshal(prompt)
+		if err != nil {
+			t.Fatalf("failed to marshal prompt: %v", err)
+		}
+		decoded, err := UnmarshalPrompt(data)
+		if err != nil {
+			t.Fatalf("failed to unmarshal prompt: %v", err)
+		}
+		if len(decoded) != 2 {
+			t.Fatalf("expected 2 messages, got %d", len(decoded))
+		}
+	})
+
+	t.Run("unsupported version", func(t *testing.T) {
+		if _, err := UnmarshalPrompt([]byte(`{"version": 99, "messages": []}`)); err == nil {
+			t.Fatal("expected an error for an unsupported version")
+		}
+	})
+}
+
 func TestStreamPartErrorSerialization(t *testing.T) {
 	t.Run("stream part with ProviderError containing OpenAI API error", func(t *testing.T) {
 		// Create a mock OpenAI API error
@@ -750,3 +800,36 @@ func TestStreamPartErrorSerialization(t *testing.T) {
 		}
 	})
 }
+
+func TestAgentResultJSONRoundTrip(t *testing.T) {
+	result := AgentResult{
+		Steps: []StepResult{{
+			Response: Response{
+				Content: ResponseContent{
+					ToolCallContent{ToolCallID: "call-1", ToolName: "lookup", Input: `{}`},
+					ToolResultContent{ToolCallID: "call-1", ToolName: "lookup", Result: ToolResultOutputContentText{Text: "found"}},
+				},
+				FinishReason: FinishReasonToolCalls,
+				Usage:        Usage{InputTokens: 10, OutputTokens: 5},
+			},
+			Messages: []Message{
+				{Role: MessageRoleAssistant, Content: []MessagePart{ToolCallPart{ToolCallID: "call-1", ToolName: "lookup", Input: `{}`}}},
+			},
+			InputBreakdown: TokenBreakdown{User: 3},
+		}},
+		Response:   Response{Content: ResponseContent{TextContent{Text: "done"}}, FinishReason: FinishReasonStop},
+		TotalUsage: Usage{InputTokens: 10, OutputTokens: 5},
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	var decoded AgentResult
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("failed to unmarshal: %v", err)
+	}
+	if !reflect.DeepEqual(result, decoded) {
+		t.Errorf("round trip mismatch:\noriginal: %#v\ndecoded:  %#v", result, decoded)
+	}
+}
@@ -0,0 +1,182 @@
+package jsonrepair
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// WithSchema resolves ambiguous repairs using a JSON schema, given in the map
+// form used for tool parameters. Values whose type doesn't match the schema
+// are converted when the conversion is lossless: "30" becomes 30 for an
+// integer, "true" becomes true for a boolean, 42 becomes "42" for a string
+// and a single value becomes a one-element array. Strings matching an enum
+// value case-insensitively are replaced with that value.
+//
+// Use schema.ToMap to pass a fantasy.Schema.
+func WithSchema(schema map[string]any) Option {
+	return func(o *options) {
+		o.schema = schema
+	}
+}
+
+func coerceToSchema(value any, schema map[string]any) any {
+	if schema == nil {
+		return value
+	}
+
+	types := schemaTypes(schema)
+	if len(types) > 0 && !matchesAnyType(value, types) {
+		for _, t := range types {
+			if coerced, ok := coerceType(value, t); ok {
+				value = coerced
+				break
+			}
+		}
+	}
+
+	switch v := value.(type) {
+	case *orderedObject:
+		properties, _ := schema["properties"].(map[string]any)
+		additional, _ := schema["additionalProperties"].(map[string]any)
+		for i, entry := range v.entries {
+			propertySchema, ok := properties[entry.key].(map[string]any)
+			if !ok {
+				propertySchema = additional
+			}
+			v.entries[i].value = coerceToSchema(entry.value, propertySchema)
+		}
+	case []any:
+		items, _ := schema["items"].(map[string]any)
+		for i := range v {
+			v[i] = coerceToSchema(v[i], items)
+		}
+	case string:
+		enum, _ := schema["enum"].([]any)
+		for _, option := range enum {
+			if s, ok := option.(string); ok && s != v && strings.EqualFold(s, v) {
+				return s
+			}
+		}
+	}
+	return value
+}
+
+func schemaTypes(schema map[string]any) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	case []string:
+		return t
+	case []any:
+		types := make([]string, 0, len(t))
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+func matchesAnyType(value any, types []string) bool {
+	for _, t := range types {
+		if matchesType(value, t) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesType(value any, t string) bool {
+	switch t {
+	case "string":
+		_, ok := value.(string)
+		return ok
+	case "integer":
+		f, ok := numberFloat(value)
+		return ok && f == math.Trunc(f)
+	case "number":
+		_, ok := numberFloat(value)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	case "array":
+		_, ok := value.([]any)
+		return ok
+	case "object":
+		_, ok := value.(*orderedObject)
+		return ok
+	}
+	return true
+}
+
+func coerceType(value any, t string) (any, bool) {
+	switch t {
+	case "integer":
+		s, ok := value.(string)
+		if !ok {
+			return nil, false
+		}
+		s = strings.TrimSpace(s)
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return numberValue{raw: strconv.FormatInt(i, 10)}, true
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil && f == math.Trunc(f) && !math.IsInf(f, 0) {
+			return numberValue{raw: strconv.FormatFloat(f, 'f', -1, 64)}, true
+		}
+	case "number":
+		s, ok := value.(string)
+		if !ok {
+			return nil, false
+		}
+		if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+			return numberValue{raw: strconv.FormatFloat(f, 'g', -1, 64)}, true
+		}
+	case "boolean":
+		s, ok := value.(string)
+		if !ok {
+			return nil, false
+		}
+		if b, err := strconv.ParseBool(strings.ToLower(strings.TrimSpace(s))); err == nil {
+			return b, true
+		}
+	case "string":
+		switch v := value.(type) {
+		case numberValue:
+			return v.raw, true
+		case json.Number:
+			return v.String(), true
+		case bool:
+			return strconv.FormatBool(v), true
+		}
+	case "null":
+		if s, ok := value.(string); ok && strings.EqualFold(strings.TrimSpace(s), "null") {
+			return nil, true
+		}
+	case "array":
+		if value != nil && value != "" {
+			return []any{value}, true
+		}
+	}
+	return nil, false
+}
+
+func numberFloat(value any) (float64, bool) {
+	var raw string
+	switch v := value.(type) {
+	case numberValue:
+		raw = v.raw
+	case json.Number:
+		raw = v.String()
+	default:
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(raw, 64)
+	return f, err == nil
+}
@@ -0,0 +1,71 @@
+package jsonrepair
+
+import "testing"
+
+func TestRepairJSONWithSchema(t *testing.T) {
+	schema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"age":     map[string]any{"type": "integer"},
+			"delta":   map[string]any{"type": "integer"},
+			"ratio":   map[string]any{"type": "number"},
+			"active":  map[string]any{"type": "boolean"},
+			"zip":     map[string]any{"type": "string"},
+			"tags":    map[string]any{"type": "array", "items": map[string]any{"type": "string"}},
+			"color":   map[string]any{"type": "string", "enum": []any{"Red", "Green"}},
+			"comment": map[string]any{"type": []any{"string", "null"}},
+			"nested": map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"count": map[string]any{"type": "integer"},
+				},
+			},
+		},
+	}
+
+	cases := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "quoted_numbers",
+			input: `{"age": "30", "ratio": "0.5", "delta": -3}`,
+			want:  `{"age": 30, "ratio": 0.5, "delta": -3}`,
+		},
+		{
+			name:  "bare_words",
+			input: `{active: True, zip: 02134, color: red}`,
+			want:  `{"active": true, "zip": "02134", "color": "Red"}`,
+		},
+		{
+			name:  "single_value_array",
+			input: `{"tags": "solo", "comment": null}`,
+			want:  `{"tags": ["solo"], "comment": null}`,
+		},
+		{
+			name:  "nested_and_lossy_untouched",
+			input: `{"nested": {"count": "7"}, "age": "thirty", "delta": "1.5"}`,
+			want:  `{"nested": {"count": 7}, "age": "thirty", "delta": "1.5"}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := RepairJSON(tc.input, WithSchema(schema))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("got %s want %s", got, tc.want)
+			}
+		})
+	}
+
+	var decoded struct {
+		Age int `json:"age"`
+	}
+	if err := Unmarshal(`{"age": "42"}`, &decoded, WithSchema(schema)); err != nil || decoded.Age != 42 {
+		t.Fatalf("Unmarshal() = %+v, %v", decoded, err)
+	}
+}
@@ -21,6 +21,8 @@ type options struct {
 	skipJSONLoads bool
 	streamStable  bool
 	strict        bool
+	useNumber     bool
+	schema        map[string]any
 }
 
 // LogEntry represents a log entry with context and text.
@@ -1449,6 +1451,36 @@ func WithStrict() Option {
 	}
 }
 
+// WithUseNumber makes Unmarshal decode numbers into interface values as
+// json.Number instead of float64.
+func WithUseNumber() Option {
+	return func(o *options) {
+		o.useNumber = true
+	}
+}
+
+// Unmarshal repairs the input if needed and decodes it into v, following the
+// rules of json.Unmarshal, including json tags. Valid JSON is decoded as-is
+// unless WithSchema is given.
+func Unmarshal(input string, v any, opts ...Option) error {
+	cfg := applyOptions(opts)
+	if cfg.schema != nil || !json.Valid([]byte(input)) {
+		repaired, err := RepairJSON(input, opts...)
+		if err != nil {
+			return err
+		}
+		if repaired == "" {
+			return errors.New("no JSON value to decode")
+		}
+		input = repaired
+	}
+	dec := json.NewDecoder(strings.NewReader(input))
+	if cfg.useNumber {
+		dec.UseNumber()
+	}
+	return dec.Decode(v)
+}
+
 // RepairJSON takes a potentially malformed JSON string output from LLMs and
 // attempts to repair it into a valid JSON string. It returns the repaired JSON
 // string or an error if the input cannot be repaired.
@@ -1459,6 +1491,7 @@ func RepairJSON(input string, opts ...Option) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	value = coerceToSchema(value, cfg.schema)
 	if str, ok := value.(string); ok {
 		trimmed := strings.TrimSpace(str)
 		if str == "" || trimmed == "" {
@@ -1481,6 +1514,7 @@ func Loads(input string, opts ...Option) (any, error) {
 	if err != nil {
 		return nil, err
 	}
+	value = coerceToSchema(value, cfg.schema)
 	if value == "" {
 		return "", nil
 	}
@@ -1497,6 +1531,7 @@ func RepairJSONWithLog(input string, opts ...Option) (any, []LogEntry, error) {
 	if err != nil {
 		return nil, nil, err
 	}
+	value = coerceToSchema(value, cfg.schema)
 	if logs == nil {
 		logs = []LogEntry{}
 	}
@@ -273,6 +273,94 @@ func TestLoads(t *testing.T) {
 	}
 }
 
+func TestUnmarshal(t *testing.T) {
+	type person struct {
+		Name  string      `json:"name"`
+		Age   int         `json:"age"`
+		Score json.Number `json:"score"`
+		Tags  []string    `json:"tags,omitempty"`
+		Extra any         `json:"extra"`
+	}
+
+	cases := []struct {
+		name  string
+		input string
+		opts  []Option
+		want  person
+	}{
+		{
+			name:  "valid",
+			input: `{"name": "John", "age": -30, "score": 1.5, "extra": 2}`,
+			want:  person{Name: "John", Age: -30, Score: "1.5", Extra: float64(2)},
+		},
+		{
+			name:  "repaired",
+			input: "```json\n{'name': 'Anna', \"age\": 25, \"tags\": [\"a\", \"b\"",
+			want:  person{Name: "Anna", Age: 25, Tags: []string{"a", "b"}},
+		},
+		{
+			name:  "use_number",
+			input: `{"name": "Peter", "extra": 12345678901234567890}`,
+			opts:  []Option{WithUseNumber()},
+			want:  person{Name: "Peter", Extra: json.Number("12345678901234567890")},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got person
+			if err := Unmarshal(tc.input, &got, tc.opts...); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("got %#v want %#v", got, tc.want)
+			}
+		})
+	}
+
+	var got person
+	if err := Unmarshal("no json here", &got); err == nil {
+		t.Fatalf("expected an error for input without JSON")
+	}
+	if err := Unmarshal(`{"name": ["not", "a", "string"]}`, &got); err == nil {
+		t.Fatalf("expected a type error")
+	}
+}
+
+func TestLoadsOrdered(t *testing.T) {
+	got, err := LoadsOrdered(`{"zeta": 1, "alpha": {"b": [true, {"y": null, "x": "<"}], "a": 2}, "mid": "v"`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	obj, ok := got.(*OrderedObject)
+	if !ok {
+		t.Fatalf("got %T want *OrderedObject", got)
+	}
+	if want := []string{"zeta", "alpha", "mid"}; !reflect.DeepEqual(obj.Keys(), want) {
+		t.Fatalf("got keys %v want %v", obj.Keys(), want)
+	}
+	if value, _ := obj.Get("zeta"); value != json.Number("1") {
+		t.Fatalf("got zeta %#v want json.Number(1)", value)
+	}
+
+	var keys []string
+	for key := range obj.All() {
+		keys = append(keys, key)
+	}
+	if !reflect.DeepEqual(keys, obj.Keys()) || obj.Len() != 3 {
+		t.Fatalf("got keys %v from All", keys)
+	}
+
+	data, err := json.Marshal(obj)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `{"zeta":1,"alpha":{"b":[true,{"y":null,"x":"\u003c"}],"a":2},"mid":"v"}`
+	if string(data) != want {
+		t.Fatalf("got %s want %s", data, want)
+	}
+}
+
 func TestRepairJSONSkipJSONLoads(t *testing.T) {
 	cases := []struct {
 		name  string
@@ -0,0 +1,106 @@
+package jsonrepair
+
+import (
+	"bytes"
+	"encoding/json"
+	"iter"
+)
+
+// OrderedObject is a JSON object that keeps its keys in the order they
+// appeared in the input. It is returned by LoadsOrdered.
+type OrderedObject struct {
+	keys   []string
+	values map[string]any
+}
+
+// Len returns the number of keys.
+func (o *OrderedObject) Len() int {
+	return len(o.keys)
+}
+
+// Keys returns the keys in input order.
+func (o *OrderedObject) Keys() []string {
+	return o.keys
+}
+
+// Get returns the value for the key.
+func (o *OrderedObject) Get(key string) (any, bool) {
+	value, ok := o.values[key]
+	return value, ok
+}
+
+// All iterates over the keys and values in input order.
+func (o *OrderedObject) All() iter.Seq2[string, any] {
+	return func(yield func(string, any) bool) {
+		for _, key := range o.keys {
+			if !yield(key, o.values[key]) {
+				return
+			}
+		}
+	}
+}
+
+// MarshalJSON implements json.Marshaler, writing the keys in input order.
+func (o *OrderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// LoadsOrdered is like Loads, but returns objects as *OrderedObject so the
+// key order of the input is preserved.
+func LoadsOrdered(input string, opts ...Option) (any, error) {
+	cfg := applyOptions(opts)
+	p := newParser(input, false, cfg.streamStable, cfg.strict)
+	value, _, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	value = coerceToSchema(value, cfg.schema)
+	if value == "" {
+		return "", nil
+	}
+	return orderedValue(value), nil
+}
+
+func orderedValue(value any) any {
+	switch v := value.(type) {
+	case *orderedObject:
+		result := &OrderedObject{
+			keys:   make([]string, 0, len(v.entries)),
+			values: make(map[string]any, len(v.entries)),
+		}
+		for _, entry := range v.entries {
+			result.keys = append(result.keys, entry.key)
+			result.values[entry.key] = orderedValue(entry.value)
+		}
+		return result
+	case []any:
+		items := make([]any, 0, len(v))
+		for _, item := range v {
+			items = append(items, orderedValue(item))
+		}
+		return items
+	case numberValue:
+		return json.Number(v.raw)
+	default:
+		return v
+	}
+}
@@ -0,0 +1,338 @@
+package jsonrepair
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode/utf8"
+)
+
+type streamPhase int
+
+const (
+	// phaseValue expects a value: at the top level, after '[', ',' in an
+	// array, or ':' in an object.
+	phaseValue streamPhase = iota
+	// phaseKey expects an object key, after '{' or ','.
+	phaseKey
+	// phaseColon expects the ':' after an object key.
+	phaseColon
+	// phaseAfter follows a complete value and expects ',' or a closer.
+	phaseAfter
+)
+
+type streamToken int
+
+const (
+	tokenNone streamToken = iota
+	tokenString
+	tokenKey
+	tokenNumber
+	tokenKeyword
+)
+
+// streamFrame is an open container. The bottom frame, with kind 0, stands
+// for the top level.
+type streamFrame struct {
+	kind  byte
+	phase streamPhase
+	empty bool
+	// commit is the buffer offset just after the last complete member, where
+	// the buffer can be cut to drop an incomplete one.
+	commit int
+}
+
+var keywords = []string{"true", "false", "null"}
+
+// StreamRepairer repairs JSON that arrives in chunks, such as streamed tool
+// call arguments. It keeps the parser state between writes, so each write
+// costs time proportional to the chunk rather than to everything received so
+// far.
+//
+// As long as the input is well-formed JSON, truncated at any point, Current
+// completes it by closing open strings and containers and dropping an
+// incomplete trailing member. Once the input strays from strict JSON, for
+// example with single quotes, comments or code fences, the repairer falls
+// back to RepairJSON on the whole buffer.
+type StreamRepairer struct {
+	opts []Option
+	buf  []byte
+
+	frames  []streamFrame
+	token   streamToken
+	start   int
+	escape  bool
+	unicode int
+	failed  bool
+}
+
+// NewStreamRepairer creates a StreamRepairer. The options apply when it
+// falls back to RepairJSON.
+func NewStreamRepairer(opts ...Option) *StreamRepairer {
+	return &StreamRepairer{
+		opts:   opts,
+		frames: []streamFrame{{phase: phaseValue, empty: true}},
+	}
+}
+
+// Write appends a chunk. It never fails.
+func (r *StreamRepairer) Write(chunk []byte) (int, error) {
+	for _, b := range chunk {
+		r.buf = append(r.buf, b)
+		if !r.failed {
+			r.scan(b, len(r.buf)-1)
+		}
+	}
+	return len(chunk), nil
+}
+
+// WriteString appends a chunk. It never fails.
+func (r *StreamRepairer) WriteString(chunk string) (int, error) {
+	for i := 0; i < len(chunk); i++ {
+		r.buf = append(r.buf, chunk[i])
+		if !r.failed {
+			r.scan(chunk[i], len(r.buf)-1)
+		}
+	}
+	return len(chunk), nil
+}
+
+// String returns the raw input received so far.
+func (r *StreamRepairer) String() string {
+	return string(r.buf)
+}
+
+// Reset discards the input and state, keeping the options.
+func (r *StreamRepairer) Reset() {
+	*r = *NewStreamRepairer(r.opts...)
+}
+
+// Current returns the input received so far, repaired into valid JSON. It
+// returns an empty string while there is no value yet.
+func (r *StreamRepairer) Current() (string, error) {
+	if r.failed {
+		return RepairJSON(string(r.buf), r.opts...)
+	}
+
+	top := &r.frames[len(r.frames)-1]
+	var out []byte
+	switch r.token {
+	case tokenString:
+		out = r.closeString()
+	case tokenKey:
+		out = r.buf[:top.commit]
+	case tokenNumber:
+		number := strings.TrimRight(string(r.buf[r.start:]), ".eE+-")
+		if number != "" && json.Valid([]byte(number)) {
+			out = append(r.buf[:r.start:r.start], number...)
+		} else {
+			out = r.buf[:top.commit]
+		}
+	case tokenKeyword:
+		out = r.buf
+		partial := string(r.buf[r.start:])
+		for _, keyword := range keywords {
+			if strings.HasPrefix(keyword, partial) {
+				out = append(r.buf[:len(r.buf):len(r.buf)], keyword[len(partial):]...)
+				break
+			}
+		}
+	default:
+		if top.phase == phaseAfter {
+			out = r.buf
+		} else {
+			out = r.buf[:top.commit]
+		}
+	}
+
+	if len(r.frames) == 1 && len(strings.TrimSpace(string(out))) == 0 {
+		return "", nil
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(out) + len(r.frames))
+	sb.Write(out)
+	for i := len(r.frames) - 1; i > 0; i-- {
+		if r.frames[i].kind == '{' {
+			sb.WriteByte('}')
+		} else {
+			sb.WriteByte(']')
+		}
+	}
+	return sb.String(), nil
+}
+
+// closeString returns the buffer with the open string closed, dropping a
+// trailing partial escape or UTF-8 sequence.
+func (r *StreamRepairer) closeString() []byte {
+	end := len(r.buf)
+	switch {
+	case r.escape:
+		end--
+	case r.unicode > 0:
+		end -= 6 - r.unicode
+	}
+	for i := end - 1; i >= r.start && i >= end-utf8.UTFMax; i-- {
+		if utf8.RuneStart(r.buf[i]) {
+			if !utf8.FullRune(r.buf[i:end]) {
+				end = i
+			}
+			break
+		}
+	}
+	out := make([]byte, 0, end+1)
+	out = append(out, r.buf[:end]...)
+	return append(out, '"')
+}
+
+func (r *StreamRepairer) scan(b byte, pos int) {
+	switch r.token {
+	case tokenString, tokenKey:
+		r.scanString(b, pos)
+		return
+	case tokenNumber:
+		if strings.IndexByte("0123456789+-.eE", b) >= 0 {
+			return
+		}
+		if !json.Valid(r.buf[r.start:pos]) {
+			r.failed = true
+			return
+		}
+		r.endValue(pos)
+	case tokenKeyword:
+		if b >= 'a' && b <= 'z' {
+			if !isKeywordPrefix(string(r.buf[r.start : pos+1])) {
+				r.failed = true
+			}
+			return
+		}
+		if !isKeyword(string(r.buf[r.start:pos])) {
+			r.failed = true
+			return
+		}
+		r.endValue(pos)
+	}
+	r.scanStructure(b, pos)
+}
+
+func (r *StreamRepairer) scanString(b byte, pos int) {
+	switch {
+	case r.escape:
+		r.escape = false
+		if b == 'u' {
+			r.unicode = 4
+		} else if strings.IndexByte(`"\/bfnrt`, b) < 0 {
+			r.failed = true
+		}
+	case r.unicode > 0:
+		if !isHexByte(b) {
+			r.failed = true
+		}
+		r.unicode--
+	case b == '\\':
+		r.escape = true
+	case b == '"':
+		if r.token == tokenKey {
+			r.token = tokenNone
+			r.frames[len(r.frames)-1].phase = phaseColon
+			return
+		}
+		r.endValue(pos + 1)
+	case b < 0x20:
+		r.failed = true
+	}
+}
+
+func (r *StreamRepairer) scanStructure(b byte, pos int) {
+	top := &r.frames[len(r.frames)-1]
+	switch b {
+	case ' ', '\t', '\n', '\r':
+		return
+	}
+
+	switch top.phase {
+	case phaseValue:
+		switch {
+		case b == '"':
+			r.startToken(tokenString, pos)
+		case b == '{':
+			r.frames = append(r.frames, streamFrame{kind: '{', phase: phaseKey, empty: true, commit: pos + 1})
+		case b == '[':
+			r.frames = append(r.frames, streamFrame{kind: '[', phase: phaseValue, empty: true, commit: pos + 1})
+		case b == '-' || (b >= '0' && b <= '9'):
+			r.startToken(tokenNumber, pos)
+		case b == 't' || b == 'f' || b == 'n':
+			r.startToken(tokenKeyword, pos)
+		case b == ']' && top.kind == '[' && top.empty:
+			r.closeContainer(pos)
+		default:
+			r.failed = true
+		}
+	case phaseKey:
+		switch {
+		case b == '"':
+			r.startToken(tokenKey, pos)
+		case b == '}' && top.empty:
+			r.closeContainer(pos)
+		default:
+			r.failed = true
+		}
+	case phaseColon:
+		if b != ':' {
+			r.failed = true
+			return
+		}
+		top.phase = phaseValue
+	case phaseAfter:
+		switch {
+		case b == ',' && top.kind == '{':
+			top.phase = phaseKey
+		case b == ',' && top.kind == '[':
+			top.phase = phaseValue
+		case b == '}' && top.kind == '{', b == ']' && top.kind == '[':
+			r.closeContainer(pos)
+		default:
+			r.failed = true
+		}
+	}
+}
+
+func (r *StreamRepairer) startToken(token streamToken, pos int) {
+	r.token = token
+	r.start = pos
+}
+
+// endValue completes the current value, which ends just before end.
+func (r *StreamRepairer) endValue(end int) {
+	r.token = tokenNone
+	top := &r.frames[len(r.frames)-1]
+	top.phase = phaseAfter
+	top.empty = false
+	top.commit = end
+}
+
+func (r *StreamRepairer) closeContainer(pos int) {
+	r.frames = r.frames[:len(r.frames)-1]
+	r.endValue(pos + 1)
+}
+
+func isKeyword(s string) bool {
+	for _, keyword := range keywords {
+		if s == keyword {
+			return true
+		}
+	}
+	return false
+}
+
+func isKeywordPrefix(s string) bool {
+	for _, keyword := range keywords {
+		if strings.HasPrefix(keyword, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func isHexByte(b byte) bool {
+	return (b >= '0' && b <= '9') || (b >= 'a' && b <= 'f') || (b >= 'A' && b <= 'F')
+}
@@ -0,0 +1,113 @@
+package jsonrepair
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStreamRepairerCurrent(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "empty", input: "  ", want: ""},
+		{name: "open_object", input: `{`, want: `{}`},
+		{name: "partial_key", input: `{"a": 1, "b`, want: `{"a": 1}`},
+		{name: "dangling_key", input: `{"a": 1, "b"`, want: `{"a": 1}`},
+		{name: "dangling_colon", input: `{"a": 1, "b":`, want: `{"a": 1}`},
+		{name: "trailing_comma", input: `[1, 2,`, want: `[1, 2]`},
+		{name: "partial_string", input: `{"a": "hel`, want: `{"a": "hel"}`},
+		{name: "partial_escape", input: `{"a": "x\`, want: `{"a": "x"}`},
+		{name: "partial_unicode_escape", input: `{"a": "x\u00`, want: `{"a": "x"}`},
+		{name: "partial_utf8", input: "{\"a\": \"caf\xc3", want: `{"a": "caf"}`},
+		{name: "partial_keyword", input: `{"a": tr`, want: `{"a": true}`},
+		{name: "partial_null", input: `[nu`, want: `[null]`},
+		{name: "partial_exponent", input: `{"a": 1e`, want: `{"a": 1}`},
+		{name: "partial_fraction", input: `{"a": -1.`, want: `{"a": -1}`},
+		{name: "lone_minus", input: `{"a": 1, "b": -`, want: `{"a": 1}`},
+		{name: "negative_number", input: `{"a": -3}`, want: `{"a": -3}`},
+		{name: "nested", input: `{"a": [{"b": [1, {"c": "d`, want: `{"a": [{"b": [1, {"c": "d"}]}]}`},
+		{name: "top_level_number", input: `12`, want: `12`},
+		{name: "complete", input: `{"a": [true, false, null]} `, want: `{"a": [true, false, null]} `},
+		{name: "fallback_single_quotes", input: `{'a': 1`, want: `{"a": 1}`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewStreamRepairer()
+			_, _ = r.WriteString(tc.input)
+			got, err := r.Current()
+			if err != nil {
+				t.Fatalf("Current() error = %v", err)
+			}
+			if got != tc.want {
+				t.Fatalf("Current() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestStreamRepairerPrefixes(t *testing.T) {
+	input := `{"name": "Ünïcode \"quoted\" é 😀", "tags": ["a", "b"], "n": -12.5e+3, "ok": true, "none": null, "nested": {"list": [[], {}, [1, 2]]}}`
+
+	r := NewStreamRepairer()
+	for i := range len(input) {
+		_, _ = r.WriteString(input[i : i+1])
+		got, err := r.Current()
+		if err != nil {
+			t.Fatalf("prefix %q: Current() error = %v", input[:i+1], err)
+		}
+		if got != "" && !json.Valid([]byte(got)) {
+			t.Fatalf("prefix %q: Current() = %q is not valid JSON", input[:i+1], got)
+		}
+	}
+
+	got, err := r.Current()
+	if err != nil {
+		t.Fatalf("Current() error = %v", err)
+	}
+	if got != input {
+		t.Fatalf("Current() = %q, want the complete input", got)
+	}
+	if r.String() != input {
+		t.Fatalf("String() = %q, want the raw input", r.String())
+	}
+}
+
+func TestStreamRepairerFallback(t *testing.T) {
+	input := "```json\n{\"a\": 1, \"b\": [1, 2"
+	r := NewStreamRepairer()
+	for _, chunk := range strings.SplitAfter(input, " ") {
+		_, _ = r.Write([]byte(chunk))
+	}
+	got, err := r.Current()
+	if err != nil {
+		t.Fatalf("Current() error = %v", err)
+	}
+	want, _ := RepairJSON(input)
+	if got != want {
+		t.Fatalf("Current() = %q, want %q", got, want)
+	}
+
+	r.Reset()
+	_, _ = r.WriteString(`[1`)
+	got, _ = r.Current()
+	var value []int
+	if err := json.Unmarshal([]byte(got), &value); err != nil || !reflect.DeepEqual(value, []int{1}) {
+		t.Fatalf("Current() after Reset = %q", got)
+	}
+}
+
+func BenchmarkStreamRepairer(b *testing.B) {
+	input := `{"items": [` + strings.Repeat(`{"name": "item", "value": 12345},`, 1000) + `{}]}`
+	for b.Loop() {
+		r := NewStreamRepairer()
+		for i := 0; i < len(input); i += 64 {
+			_, _ = r.WriteString(input[i:min(i+64, len(input))])
+			_, _ = r.Current()
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package fantasy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Names of the tools returned by MemoryToolset.
+const (
+	RememberToolName     = "remember"
+	RecallToolName       = "recall"
+	ListMemoriesToolName = "list_memories"
+)
+
+// ErrMemoryNotFound is returned by a MemoryStore when there is no note with
+// the requested key.
+var ErrMemoryNotFound = errors.New("memory not found")
+
+// MemoryStore stores the notes written by the memory tools. See
+// MemoryToolset.
+type MemoryStore interface {
+	// Remember stores the value under the key, replacing any previous value.
+	Remember(ctx context.Context, key, value string) error
+	// Recall returns the value stored under the key, or ErrMemoryNotFound.
+	Recall(ctx context.Context, key string) (string, error)
+	// List returns the stored keys in order.
+	List(ctx context.Context) ([]string, error)
+}
+
+// MemoryToolset returns the remember, recall and list_memories tools, which
+// let the model keep notes in the store and read them back, for example in
+// a later session.
+func MemoryToolset(store MemoryStore) []AgentTool {
+	return []AgentTool{
+		NewAgentTool(RememberToolName,
+			"Save a note to memory under a short key so it can be recalled later, including in future sessions. "+
+				"Saving under an existing key replaces the note.",
+			func(ctx context.Context, input rememberInput, _ ToolCall) (ToolResponse, error) {
+				key := strings.TrimSpace(input.Key)
+				if key == "" {
+					return NewTextErrorResponse("key is required"), nil
+				}
+				if err := store.Remember(ctx, key, input.Value); err != nil {
+					return ToolResponse{}, err
+				}
+				return NewTextResponse(fmt.Sprintf("Saved %q.", key)), nil
+			}),
+		NewParallelAgentTool(RecallToolName,
+			"Read a note saved in memory by its key. Use list_memories to see the keys.",
+			func(ctx context.Context, input recallInput, _ ToolCall) (ToolResponse, error) {
+				value, err := store.Recall(ctx, strings.TrimSpace(input.Key))
+				if errors.Is(err, ErrMemoryNotFound) {
+					return NewTextErrorResponse(fmt.Sprintf("no memory saved under %q", input.Key)), nil
+				}
+				if err != nil {
+					return ToolResponse{}, err
+				}
+				return NewTextResponse(value), nil
+			}),
+		NewParallelAgentTool(ListMemoriesToolName,
+			"List the keys of the notes saved in memory.",
+			func(ctx context.Context, _ struct{}, _ ToolCall) (ToolResponse, error) {
+				keys, err := store.List(ctx)
+				if err != nil {
+					return ToolResponse{}, err
+				}
+				if len(keys) == 0 {
+					return NewTextResponse("No memories saved."), nil
+				}
+				return NewTextResponse(strings.Join(keys, "\n")), nil
+			}),
+	}
+}
+
+type rememberInput struct {
+	Key   string `json:"key" description:"A short key describing the note"`
+	Value string `json:"value" description:"The note to save"`
+}
+
+type recallInput struct {
+	Key string `json:"key" description:"The key of the note"`
+}
+
+// InMemoryStore is a MemoryStore that keeps notes in memory for the life of
+// the process. It is safe for concurrent use.
+type InMemoryStore struct {
+	mu    sync.Mutex
+	notes map[string]string
+}
+
+// NewInMemoryStore creates an empty in-memory store.
+func NewInMemoryStore() *InMemoryStore {
+	return &InMemoryStore{notes: map[string]string{}}
+}
+
+// Remember implements MemoryStore.
+func (s *InMemoryStore) Remember(_ context.Context, key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notes[key] = value
+	return nil
+}
+
+// Recall implements MemoryStore.
+func (s *InMemoryStore) Recall(_ context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.notes[key]
+	if !ok {
+		return "", ErrMemoryNotFound
+	}
+	return value, nil
+}
+
+// List implements MemoryStore.
+func (s *InMemoryStore) List(context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Sorted(maps.Keys(s.notes)), nil
+}
+
+// FileMemoryStore is a MemoryStore that keeps notes in a JSON file, so they
+// persist across sessions. It is safe for concurrent use within a process.
+type FileMemoryStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileMemoryStore creates a store backed by the JSON file at path. The
+// file and its directory are created on the first write.
+func NewFileMemoryStore(path string) *FileMemoryStore {
+	return &FileMemoryStore{path: path}
+}
+
+// Remember implements MemoryStore.
+func (s *FileMemoryStore) Remember(_ context.Context, key, value string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	notes, err := s.load()
+	if err != nil {
+		return err
+	}
+	notes[key] = value
+	data, err := json.MarshalIndent(notes, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+// Recall implements MemoryStore.
+func (s *FileMemoryStore) Recall(_ context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	notes, err := s.load()
+	if err != nil {
+		return "", err
+	}
+	value, ok := notes[key]
+	if !ok {
+		return "", ErrMemoryNotFound
+	}
+	return value, nil
+}
+
+// List implements MemoryStore.
+func (s *FileMemoryStore) List(context.Context) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	notes, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return slices.Sorted(maps.Keys(notes)), nil
+}
+
+func (s *FileMemoryStore) load() (map[string]string, error) {
+	notes := map[string]string{}
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return notes, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &notes); err != nil {
+		return nil, fmt.Errorf("reading memory file %s: %w", s.path, err)
+	}
+	return notes, nil
+}
@@ -0,0 +1,56 @@
+package fantasy
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryToolset(t *testing.T) {
+	t.Parallel()
+
+	stores := map[string]func(t *testing.T) MemoryStore{
+		"in memory": func(*testing.T) MemoryStore { return NewInMemoryStore() },
+		"file": func(t *testing.T) MemoryStore {
+			return NewFileMemoryStore(filepath.Join(t.TempDir(), "memory", "notes.json"))
+		},
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			tools := map[string]AgentTool{}
+			for _, tool := range MemoryToolset(newStore(t)) {
+				tools[tool.Info().Name] = tool
+			}
+			run := func(name, input string) ToolResponse {
+				resp, err := tools[name].Run(t.Context(), ToolCall{ID: "1", Name: name, Input: input})
+				require.NoError(t, err)
+				return resp
+			}
+
+			require.Equal(t, "No memories saved.", run(ListMemoriesToolName, `{}`).Content)
+			require.True(t, run(RecallToolName, `{"key":"editor"}`).IsError)
+			require.True(t, run(RememberToolName, `{"key":" ","value":"x"}`).IsError)
+
+			require.False(t, run(RememberToolName, `{"key":"editor","value":"vim"}`).IsError)
+			run(RememberToolName, `{"key":"editor","value":"helix"}`)
+			run(RememberToolName, `{"key":"deadline","value":"friday"}`)
+
+			require.Equal(t, "helix", run(RecallToolName, `{"key":"editor"}`).Content)
+			require.Equal(t, "deadline\neditor", run(ListMemoriesToolName, `{}`).Content)
+		})
+	}
+}
+
+func TestFileMemoryStorePersists(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "notes.json")
+	require.NoError(t, NewFileMemoryStore(path).Remember(t.Context(), "name", "Ada"))
+
+	value, err := NewFileMemoryStore(path).Recall(t.Context(), "name")
+	require.NoError(t, err)
+	require.Equal(t, "Ada", value)
+}
@@ -117,12 +117,42 @@ func (r ResponseContent) ToolResults() []ToolResultContent {
 	return toolResults
 }
 
+// SafetyInfo describes the safety and content-filter results reported by
+// the provider for a call. It is nil when the provider reported nothing.
+type SafetyInfo struct {
+	// Blocked reports whether the provider blocked the prompt or the
+	// response, or the model refused to answer.
+	Blocked bool `json:"blocked"`
+	// Reason is the provider-specific reason for the block, e.g. "SAFETY",
+	// "content_filter" or "refusal".
+	Reason string `json:"reason,omitempty"`
+	// Refusal is the refusal message returned by the model, if any.
+	Refusal string `json:"refusal,omitempty"`
+	// Ratings are the per-category safety ratings, when the provider
+	// returns them.
+	Ratings []SafetyRating `json:"ratings,omitempty"`
+}
+
+// SafetyRating is the safety rating of a response for a single harm
+// category. Values are provider-specific.
+type SafetyRating struct {
+	Category    string `json:"category"`
+	Probability string `json:"probability,omitempty"`
+	Severity    string `json:"severity,omitempty"`
+	Blocked     bool   `json:"blocked"`
+}
+
 // Response represents a response from a language model.
 type Response struct {
 	Content      ResponseContent `json:"content"`
 	FinishReason FinishReason    `json:"finish_reason"`
 	Usage        Usage           `json:"usage"`
 	Warnings     []CallWarning   `json:"warnings"`
+	Safety       *SafetyInfo     `json:"safety,omitempty"`
+
+	// Alternatives holds the content of the choices after the first, when
+	// the call asked for more than one with NumChoices.
+	Alternatives []ResponseContent `json:"alternatives,omitempty"`
 
 	// for provider specific response metadata, the key is the provider id
 	ProviderMetadata ProviderMetadata `json:"provider_metadata"`
@@ -163,6 +193,10 @@ const (
 	StreamPartTypeFinish StreamPartType = "finish"
 	// StreamPartTypeError represents error stream part type.
 	StreamPartTypeError StreamPartType = "error"
+	// StreamPartTypeToolExecutionDelta represents output a running tool
+	// wrote to ToolCall.Output. The ID is the tool call's. Only Agent.Stream
+	// emits it, to the OnChunk callback.
+	StreamPartTypeToolExecutionDelta StreamPartType = "tool_execution_delta"
 )
 
 // StreamPart represents a part of a streaming response.
@@ -177,11 +211,13 @@ type StreamPart struct {
 	FinishReason     FinishReason   `json:"finish_reason"`
 	Error            error          `json:"error"`
 	Warnings         []CallWarning  `json:"warnings"`
+	Safety           *SafetyInfo    `json:"safety,omitempty"` // set on finish parts
 
 	// Source-related fields
 	SourceType SourceType `json:"source_type"`
 	URL        string     `json:"url"`
 	Title      string     `json:"title"`
+	Citations  []Citation `json:"citations"`
 
 	ProviderMetadata ProviderMetadata `json:"provider_metadata"`
 }
@@ -218,16 +254,106 @@ type Call struct {
 	Tools            []Tool      `json:"tools"`
 	ToolChoice       *ToolChoice `json:"tool_choice"`
 
+	// OutputConstraint constrains the generated text on providers that
+	// support constrained decoding. Providers that can't honor it return a
+	// warning and generate unconstrained text.
+	OutputConstraint *OutputConstraint `json:"output_constraint,omitempty"`
+
+	// NumChoices asks for that many alternative responses to the prompt.
+	// Models implementing MultiChoiceModel generate them in one request;
+	// GenerateChoices emulates it on other models with parallel calls.
+	// Streams always produce a single choice.
+	NumChoices *int64 `json:"num_choices,omitempty"`
+
+	// ServiceTier is the processing tier to run the call on, for providers
+	// that offer several. Empty means the provider's default.
+	ServiceTier ServiceTier `json:"service_tier,omitempty"`
+
+	// StrictPromptConversion makes providers fail the call with a
+	// *DroppedContentError before sending it when converting the prompt
+	// left content out, instead of only warning about it.
+	StrictPromptConversion bool `json:"strict_prompt_conversion,omitempty"`
+
 	// UserAgent overrides the provider-level User-Agent header for this call.
 	UserAgent string `json:"-"`
 
 	// Headers overrides matching provider-level headers for this call.
 	Headers map[string]string `json:"-"`
 
+	// ExtraQuery adds query parameters to the request URL for this call,
+	// e.g. for gateway routing. Providers that sign the URL, like Bedrock,
+	// ignore it.
+	ExtraQuery map[string]string `json:"-"`
+
+	// ExtraBody adds fields to the JSON body of the request for this call,
+	// replacing the fields the provider sets, so upstream parameters can be
+	// used before the provider supports them. Models on the Bedrock Converse
+	// API get them as additional model request fields.
+	ExtraBody map[string]any `json:"-"`
+
+	// Metadata tags the request, e.g. with the end user or a trace ID, for
+	// providers that accept request metadata. Agents set it from
+	// CallMetadata of their context; see WithCallMetadata.
+	Metadata map[string]string `json:"metadata,omitempty"`
+
 	// for provider specific options, the key is the provider id
 	ProviderOptions ProviderOptions `json:"provider_options"`
 }
 
+// OutputConstraintType represents the kind of an output constraint.
+type OutputConstraintType string
+
+const (
+	// OutputConstraintTypeJSONSchema constrains the output to JSON matching a schema.
+	OutputConstraintTypeJSONSchema OutputConstraintType = "json_schema"
+	// OutputConstraintTypeGrammar constrains the output to a GBNF grammar.
+	OutputConstraintTypeGrammar OutputConstraintType = "grammar"
+	// OutputConstraintTypeRegex constrains the output to a regular expression.
+	OutputConstraintTypeRegex OutputConstraintType = "regex"
+)
+
+// OutputConstraint describes a constraint on the generated text. Only the
+// field matching Type is used.
+type OutputConstraint struct {
+	Type OutputConstraintType `json:"type"`
+
+	// Name is the name of the schema, used by providers that require one.
+	Name string `json:"name,omitempty"`
+	// Schema is the JSON schema for OutputConstraintTypeJSONSchema.
+	Schema Schema `json:"schema,omitzero"`
+	// Grammar is the GBNF grammar for OutputConstraintTypeGrammar.
+	Grammar string `json:"grammar,omitempty"`
+	// Regex is the pattern for OutputConstraintTypeRegex.
+	Regex string `json:"regex,omitempty"`
+}
+
+// JSONSchemaConstraint returns an output constraint for JSON matching the
+// given schema.
+func JSONSchemaConstraint(name string, schema Schema) *OutputConstraint {
+	return &OutputConstraint{Type: OutputConstraintTypeJSONSchema, Name: name, Schema: schema}
+}
+
+// GrammarConstraint returns an output constraint for the given GBNF grammar.
+func GrammarConstraint(grammar string) *OutputConstraint {
+	return &OutputConstraint{Type: OutputConstraintTypeGrammar, Grammar: grammar}
+}
+
+// RegexConstraint returns an output constraint for the given regular
+// expression.
+func RegexConstraint(pattern string) *OutputConstraint {
+	return &OutputConstraint{Type: OutputConstraintTypeRegex, Regex: pattern}
+}
+
+// UnsupportedOutputConstraintWarning returns the warning providers emit
+// when they can't honor an output constraint.
+func UnsupportedOutputConstraintWarning(c *OutputConstraint) CallWarning {
+	return CallWarning{
+		Type:    CallWarningTypeUnsupportedSetting,
+		Setting: "output_constraint",
+		Details: fmt.Sprintf("%s output constraints are not supported by this provider", c.Type),
+	}
+}
+
 // CallWarningType represents the type of call warning.
 type CallWarningType string
 
@@ -236,6 +362,10 @@ const (
 	CallWarningTypeUnsupportedSetting CallWarningType = "unsupported-setting"
 	// CallWarningTypeUnsupportedTool indicates an unsupported tool.
 	CallWarningTypeUnsupportedTool CallWarningType = "unsupported-tool"
+	// CallWarningTypeDroppedContent indicates prompt content the provider
+	// couldn't send and left out, such as files of an unsupported media
+	// type or empty messages.
+	CallWarningTypeDroppedContent CallWarningType = "dropped-content"
 	// CallWarningTypeOther indicates other warnings.
 	CallWarningTypeOther CallWarningType = "other"
 )
@@ -262,3 +392,43 @@ type LanguageModel interface {
 	Provider() string
 	Model() string
 }
+
+// PrefillModel is implemented by language models that continue a trailing
+// assistant message in the prompt instead of replying after it. The agent
+// uses it to resume interrupted responses from their partial text.
+type PrefillModel interface {
+	SupportsPrefill() bool
+}
+
+// PDFModel is implemented by language models that read application/pdf
+// file parts. The agent's PDF fallback extracts documents for models that
+// don't.
+type PDFModel interface {
+	SupportsPDF() bool
+}
+
+// FileURLModel is implemented by language models that pass the URL of file
+// parts of a media type to the provider, which fetches the file itself.
+// The agent downloads the files of other models.
+type FileURLModel interface {
+	SupportsFileURL(mediaType string) bool
+}
+
+// MultiChoiceModel is implemented by language models that honor
+// Call.NumChoices natively, returning the extra choices in
+// Response.Alternatives.
+type MultiChoiceModel interface {
+	SupportsNumChoices() bool
+}
+
+// TokenCounter is implemented by language models that can count the input
+// tokens of a call without generating a response.
+type TokenCounter interface {
+	CountTokens(context.Context, Call) (int64, error)
+}
+
+// Tokenizer counts the tokens of a text locally, as the tokenizer of a model
+// family does. The tokenizer package provides them for common families.
+type Tokenizer interface {
+	Count(text string) int
+}
@@ -0,0 +1,79 @@
+package fantasy
+
+import (
+	"context"
+	"time"
+)
+
+// generateModel makes a Generate call to the model, pausing in the debugger,
+// enforcing the spend limit, deduplicating requests and auditing the call as
+// configured.
+func (a *agent) generateModel(ctx context.Context, model LanguageModel, call Call) (*Response, error) {
+	call, err := a.debugModelCall(ctx, model, call)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	resp, err := a.generateWithinLimits(ctx, model, call)
+	var (
+		usage        Usage
+		finishReason FinishReason
+	)
+	if resp != nil {
+		usage, finishReason = resp.Usage, resp.FinishReason
+	}
+	if auditErr := a.auditModelCall(ctx, model, start, usage, finishReason, err); auditErr != nil {
+		return nil, auditErr
+	}
+	return resp, err
+}
+
+func (a *agent) generateWithinLimits(ctx context.Context, model LanguageModel, call Call) (*Response, error) {
+	if err := a.checkSpend(ctx); err != nil {
+		return nil, err
+	}
+	var (
+		resp *Response
+		err  error
+	)
+	if a.settings.requestGroup != nil {
+		resp, err = a.settings.requestGroup.Generate(ctx, model, call)
+	} else {
+		resp, err = model.Generate(ctx, call)
+	}
+	if err != nil {
+		return resp, err
+	}
+	if err := a.recordSpend(ctx, model, resp.Usage); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// streamModel is like generateModel for Stream calls.
+func (a *agent) streamModel(ctx context.Context, model LanguageModel, call Call) (StreamResponse, error) {
+	call, err := a.debugModelCall(ctx, model, call)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	stream, err := a.streamWithinLimits(ctx, model, call)
+	if err != nil {
+		if auditErr := a.auditModelCall(ctx, model, start, Usage{}, "", err); auditErr != nil {
+			return nil, auditErr
+		}
+		return stream, err
+	}
+	return a.auditStream(ctx, model, start, stream), nil
+}
+
+func (a *agent) streamWithinLimits(ctx context.Context, model LanguageModel, call Call) (StreamResponse, error) {
+	if err := a.checkSpend(ctx); err != nil {
+		return nil, err
+	}
+	stream, err := model.Stream(ctx, call)
+	if err != nil {
+		return stream, err
+	}
+	return a.spendStream(ctx, model, stream), nil
+}
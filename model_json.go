@@ -17,6 +17,8 @@ func (c *Call) UnmarshalJSON(data []byte) error {
 		FrequencyPenalty *float64                   `json:"frequency_penalty"`
 		Tools            []json.RawMessage          `json:"tools"`
 		ToolChoice       *ToolChoice                `json:"tool_choice"`
+		OutputConstraint *OutputConstraint          `json:"output_constraint"`
+		Metadata         map[string]string          `json:"metadata"`
 		ProviderOptions  map[string]json.RawMessage `json:"provider_options"`
 	}
 
@@ -32,6 +34,8 @@ func (c *Call) UnmarshalJSON(data []byte) error {
 	c.PresencePenalty = aux.PresencePenalty
 	c.FrequencyPenalty = aux.FrequencyPenalty
 	c.ToolChoice = aux.ToolChoice
+	c.OutputConstraint = aux.OutputConstraint
+	c.Metadata = aux.Metadata
 
 	// Unmarshal Tools slice
 	c.Tools = make([]Tool, len(aux.Tools))
@@ -62,6 +66,7 @@ func (r *Response) UnmarshalJSON(data []byte) error {
 		FinishReason     FinishReason               `json:"finish_reason"`
 		Usage            Usage                      `json:"usage"`
 		Warnings         []CallWarning              `json:"warnings"`
+		Safety           *SafetyInfo                `json:"safety"`
 		ProviderMetadata map[string]json.RawMessage `json:"provider_metadata"`
 	}
 
@@ -72,6 +77,7 @@ func (r *Response) UnmarshalJSON(data []byte) error {
 	r.FinishReason = aux.FinishReason
 	r.Usage = aux.Usage
 	r.Warnings = aux.Warnings
+	r.Safety = aux.Safety
 
 	// Unmarshal ResponseContent (need to know the type definition)
 	// If ResponseContent is []Content:
@@ -102,6 +108,25 @@ func (r *Response) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// UnmarshalJSON implements json.Unmarshaler for StepResult. Without it the
+// embedded Response's UnmarshalJSON would be promoted and the step's
+// messages dropped.
+func (s *StepResult) UnmarshalJSON(data []byte) error {
+	if err := s.Response.UnmarshalJSON(data); err != nil {
+		return err
+	}
+	var aux struct {
+		Messages       []Message
+		InputBreakdown TokenBreakdown
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	s.Messages = aux.Messages
+	s.InputBreakdown = aux.InputBreakdown
+	return nil
+}
+
 // MarshalJSON implements json.Marshaler for StreamPart.
 func (s StreamPart) MarshalJSON() ([]byte, error) {
 	type alias StreamPart
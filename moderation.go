@@ -0,0 +1,160 @@
+package fantasy
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ModerationCall represents a call to a moderation model.
+type ModerationCall struct {
+	// Values are the texts to classify. The response contains one result
+	// per value, in the same order.
+	Values []string `json:"values"`
+
+	// for provider specific options, the key is the provider id
+	ProviderOptions ProviderOptions `json:"provider_options"`
+}
+
+// ModerationCategory is the classification of a text for a single
+// category of harmful content.
+type ModerationCategory struct {
+	// Name is the provider's name of the category, such as "harassment" or
+	// "self-harm/intent".
+	Name    string `json:"name"`
+	Flagged bool   `json:"flagged"`
+	// Score is the model's confidence that the text belongs to the
+	// category, from 0 to 1.
+	Score float64 `json:"score"`
+}
+
+// ModerationResult is the classification of a single text.
+type ModerationResult struct {
+	// Flagged reports whether the text belongs to any of the categories.
+	Flagged    bool                 `json:"flagged"`
+	Categories []ModerationCategory `json:"categories"`
+}
+
+// FlaggedCategories returns the names of the flagged categories.
+func (r ModerationResult) FlaggedCategories() []string {
+	var names []string
+	for _, category := range r.Categories {
+		if category.Flagged {
+			names = append(names, category.Name)
+		}
+	}
+	return names
+}
+
+// ModerationResponse represents a response from a moderation model.
+type ModerationResponse struct {
+	Results  []ModerationResult `json:"results"`
+	Warnings []CallWarning      `json:"warnings"`
+
+	// for provider specific response metadata, the key is the provider id
+	ProviderMetadata ProviderMetadata `json:"provider_metadata"`
+}
+
+// ModerationModel represents a model that classifies text as harmful.
+type ModerationModel interface {
+	Moderate(context.Context, ModerationCall) (*ModerationResponse, error)
+
+	Provider() string
+	Model() string
+}
+
+// ModerationRule flags texts that contain any of its keywords or match any
+// of its patterns as belonging to its category.
+type ModerationRule struct {
+	Category string
+	// Keywords are matched as whole words, ignoring case.
+	Keywords []string
+	Patterns []*regexp.Regexp
+}
+
+type keywordModerationModel struct {
+	categories []string
+	patterns   [][]*regexp.Regexp
+}
+
+// NewKeywordModerationModel creates a local moderation model that flags
+// texts with keyword and regular expression rules. It needs no network
+// access, so it can stand in for a hosted model in tests, offline, or as a
+// cheap first pass.
+func NewKeywordModerationModel(rules ...ModerationRule) ModerationModel {
+	m := &keywordModerationModel{}
+	for _, rule := range rules {
+		patterns := rule.Patterns
+		if len(rule.Keywords) > 0 {
+			quoted := make([]string, len(rule.Keywords))
+			for i, keyword := range rule.Keywords {
+				quoted[i] = regexp.QuoteMeta(keyword)
+			}
+			keywords := regexp.MustCompile(`(?i)\b(?:` + strings.Join(quoted, "|") + `)\b`)
+			patterns = append([]*regexp.Regexp{keywords}, patterns...)
+		}
+		m.categories = append(m.categories, rule.Category)
+		m.patterns = append(m.patterns, patterns)
+	}
+	return m
+}
+
+// Moderate implements ModerationModel.
+func (m *keywordModerationModel) Moderate(_ context.Context, call ModerationCall) (*ModerationResponse, error) {
+	resp := &ModerationResponse{Results: make([]ModerationResult, len(call.Values))}
+	for i, value := range call.Values {
+		result := ModerationResult{Categories: make([]ModerationCategory, len(m.categories))}
+		for j, category := range m.categories {
+			result.Categories[j] = ModerationCategory{Name: category}
+			for _, pattern := range m.patterns[j] {
+				if pattern.MatchString(value) {
+					result.Categories[j].Flagged = true
+					result.Categories[j].Score = 1
+					result.Flagged = true
+					break
+				}
+			}
+		}
+		resp.Results[i] = result
+	}
+	return resp, nil
+}
+
+// Provider implements ModerationModel.
+func (m *keywordModerationModel) Provider() string {
+	return "local"
+}
+
+// Model implements ModerationModel.
+func (m *keywordModerationModel) Model() string {
+	return "keyword"
+}
+
+// ModerationGuardrail adapts a moderation model to a Guardrail that blocks
+// flagged texts with a *GuardrailError.
+func ModerationGuardrail(model ModerationModel) Guardrail {
+	return GuardrailFunc(func(ctx context.Context, stage GuardrailStage, text string) (string, error) {
+		resp, err := model.Moderate(ctx, ModerationCall{Values: []string{text}})
+		if err != nil {
+			return "", fmt.Errorf("moderating %s: %w", stage, err)
+		}
+		for _, result := range resp.Results {
+			if result.Flagged {
+				return "", &GuardrailError{
+					Stage:      stage,
+					Reason:     "flagged by moderation",
+					Categories: result.FlaggedCategories(),
+				}
+			}
+		}
+		return text, nil
+	})
+}
+
+// WithModeration screens the input, output and tool results of the agent
+// with the moderation model. It is shorthand for
+// WithGuardrails(ModerationGuardrail(model)).
+func WithModeration(model ModerationModel) AgentOption {
+	return WithGuardrails(ModerationGuardrail(model))
+}
@@ -47,6 +47,12 @@ type ObjectCall struct {
 	// Headers overrides matching provider-level headers for this call.
 	Headers map[string]string `json:"-"`
 
+	// ExtraQuery adds query parameters to the request URL for this call.
+	ExtraQuery map[string]string `json:"-"`
+
+	// ExtraBody adds fields to the JSON body of the request for this call.
+	ExtraBody map[string]any `json:"-"`
+
 	ProviderOptions ProviderOptions
 
 	RepairText schema.ObjectRepairFunc
@@ -122,6 +122,8 @@ func GenerateWithTool(
 		FrequencyPenalty: call.FrequencyPenalty,
 		UserAgent:        call.UserAgent,
 		Headers:          call.Headers,
+		ExtraQuery:       call.ExtraQuery,
+		ExtraBody:        call.ExtraBody,
 		ProviderOptions:  call.ProviderOptions,
 	})
 	if err != nil {
@@ -216,6 +218,8 @@ func GenerateWithText(
 		FrequencyPenalty: call.FrequencyPenalty,
 		UserAgent:        call.UserAgent,
 		Headers:          call.Headers,
+		ExtraQuery:       call.ExtraQuery,
+		ExtraBody:        call.ExtraBody,
 		ProviderOptions:  call.ProviderOptions,
 	})
 	if err != nil {
@@ -300,6 +304,8 @@ func StreamWithTool(
 		FrequencyPenalty: call.FrequencyPenalty,
 		UserAgent:        call.UserAgent,
 		Headers:          call.Headers,
+		ExtraQuery:       call.ExtraQuery,
+		ExtraBody:        call.ExtraBody,
 		ProviderOptions:  call.ProviderOptions,
 	})
 	if err != nil {
@@ -511,6 +517,8 @@ func StreamWithText(
 		FrequencyPenalty: call.FrequencyPenalty,
 		UserAgent:        call.UserAgent,
 		Headers:          call.Headers,
+		ExtraQuery:       call.ExtraQuery,
+		ExtraBody:        call.ExtraBody,
 		ProviderOptions:  call.ProviderOptions,
 	})
 	if err != nil {
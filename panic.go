@@ -0,0 +1,92 @@
+package fantasy
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicError is returned in place of a panic in a provider, a tool or an
+// agent step, so that one bad response can't crash the application.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value any
+	// Stack is the stack trace of the goroutine that panicked.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// newPanicError must be called from the deferred function that recovered
+// the panic, so the stack trace includes the panicking frames.
+func newPanicError(value any) *PanicError {
+	return &PanicError{Value: value, Stack: debug.Stack()}
+}
+
+// RecoverStream wraps a stream so that a panic while producing its parts
+// ends the stream with a StreamPartTypeError part holding a *PanicError
+// instead of crashing. Panics in the code consuming the stream are not
+// recovered.
+func RecoverStream(stream StreamResponse) StreamResponse {
+	return func(yield func(StreamPart) bool) {
+		inYield := false
+		defer func() {
+			if inYield {
+				// The consumer panicked; let it propagate.
+				return
+			}
+			if r := recover(); r != nil {
+				yield(StreamPart{Type: StreamPartTypeError, Error: newPanicError(r)})
+			}
+		}()
+		for part := range stream {
+			inYield = true
+			if !yield(part) {
+				return
+			}
+			inYield = false
+		}
+	}
+}
+
+// WithPanicRecovery sets whether the agent recovers panics in providers,
+// tools and its own step processing and returns them as a *PanicError.
+// Recovery is on by default; turn it off during development to crash with
+// the original panic instead.
+func WithPanicRecovery(enabled bool) AgentOption {
+	return func(s *agentSettings) {
+		s.disablePanicRecovery = !enabled
+	}
+}
+
+// recoverPanic sets *err to a *PanicError if the function deferring it
+// panics, unless panic recovery is disabled.
+func (a *agent) recoverPanic(err *error) {
+	if a.settings.disablePanicRecovery {
+		return
+	}
+	if r := recover(); r != nil {
+		*err = newPanicError(r)
+	}
+}
+
+func (a *agent) safeGenerate(ctx context.Context, model LanguageModel, call Call) (_ *Response, err error) {
+	defer a.recoverPanic(&err)
+	return a.generateModel(ctx, model, call)
+}
+
+func (a *agent) safeStream(ctx context.Context, model LanguageModel, call Call) (_ StreamResponse, err error) {
+	defer a.recoverPanic(&err)
+	stream, err := a.streamModel(ctx, model, call)
+	if err != nil || a.settings.disablePanicRecovery {
+		return stream, err
+	}
+	return RecoverStream(stream), nil
+}
+
+func (a *agent) callTool(ctx context.Context, run func(context.Context, ToolCall) (ToolResponse, error), call ToolCall) (_ ToolResponse, err error) {
+	defer a.recoverPanic(&err)
+	return run(ctx, call)
+}
@@ -0,0 +1,141 @@
+package fantasy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func panickingStream(ctx context.Context, call Call) (StreamResponse, error) {
+	return func(yield func(StreamPart) bool) {
+		if !yield(StreamPart{Type: StreamPartTypeTextDelta, ID: "text-1", Delta: "Hel"}) {
+			return
+		}
+		var metadata *ProviderMetadata
+		_ = (*metadata)["google"] // nil dereference in the middle of a stream
+	}, nil
+}
+
+func TestRecoverStream(t *testing.T) {
+	t.Parallel()
+
+	t.Run("provider panic becomes an error part", func(t *testing.T) {
+		t.Parallel()
+		stream, _ := panickingStream(t.Context(), Call{})
+		var parts []StreamPart
+		for part := range RecoverStream(stream) {
+			parts = append(parts, part)
+		}
+		require.Len(t, parts, 2)
+		require.Equal(t, StreamPartTypeError, parts[1].Type)
+
+		var panicErr *PanicError
+		require.ErrorAs(t, parts[1].Error, &panicErr)
+		require.Contains(t, panicErr.Error(), "nil pointer dereference")
+		require.Contains(t, string(panicErr.Stack), "panickingStream")
+	})
+
+	t.Run("consumer panics propagate", func(t *testing.T) {
+		t.Parallel()
+		stream := func(yield func(StreamPart) bool) {
+			yield(StreamPart{Type: StreamPartTypeTextDelta})
+		}
+		require.PanicsWithValue(t, "consumer", func() {
+			for range RecoverStream(stream) {
+				panic("consumer")
+			}
+		})
+	})
+}
+
+func TestAgentPanicRecovery(t *testing.T) {
+	t.Parallel()
+
+	t.Run("generate", func(t *testing.T) {
+		t.Parallel()
+		model := &mockLanguageModel{
+			generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+				panic("boom")
+			},
+		}
+		_, err := NewAgent(model).Generate(t.Context(), AgentCall{Prompt: "hi"})
+		var panicErr *PanicError
+		require.ErrorAs(t, err, &panicErr)
+		require.Equal(t, "boom", panicErr.Value)
+		require.Equal(t, "panic: boom", err.Error())
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		t.Parallel()
+		var chunks []StreamPart
+		var onError error
+		_, err := NewAgent(&mockLanguageModel{streamFunc: panickingStream}).Stream(t.Context(), AgentStreamCall{
+			Prompt: "hi",
+			OnChunk: func(part StreamPart) error {
+				chunks = append(chunks, part)
+				return nil
+			},
+			OnError: func(err error) { onError = err },
+		})
+		var panicErr *PanicError
+		require.ErrorAs(t, err, &panicErr)
+		require.Equal(t, err, onError)
+		require.Equal(t, StreamPartTypeError, chunks[len(chunks)-1].Type)
+	})
+
+	t.Run("stream callback", func(t *testing.T) {
+		t.Parallel()
+		model := &mockLanguageModel{
+			streamFunc: func(ctx context.Context, call Call) (StreamResponse, error) {
+				return func(yield func(StreamPart) bool) {
+					yield(StreamPart{Type: StreamPartTypeFinish, FinishReason: FinishReasonStop})
+				}, nil
+			},
+		}
+		_, err := NewAgent(model).Stream(t.Context(), AgentStreamCall{
+			Prompt:         "hi",
+			OnStreamFinish: func(Usage, FinishReason, ProviderMetadata) error { panic("callback") },
+		})
+		var panicErr *PanicError
+		require.ErrorAs(t, err, &panicErr)
+		require.Equal(t, "callback", panicErr.Value)
+	})
+
+	t.Run("tool", func(t *testing.T) {
+		t.Parallel()
+		tool := &mockTool{
+			name: "explode",
+			executeFunc: func(ctx context.Context, call ToolCall) (ToolResponse, error) {
+				panic("tool failed")
+			},
+		}
+		model := &mockLanguageModel{
+			streamFunc: func(ctx context.Context, call Call) (StreamResponse, error) {
+				return func(yield func(StreamPart) bool) {
+					if !yield(StreamPart{Type: StreamPartTypeToolCall, ID: "tool-1", ToolCallName: "explode", ToolCallInput: `{}`}) {
+						return
+					}
+					yield(StreamPart{Type: StreamPartTypeFinish, FinishReason: FinishReasonToolCalls})
+				}, nil
+			},
+		}
+		_, err := NewAgent(model, WithTools(tool)).Stream(t.Context(), AgentStreamCall{Prompt: "hi"})
+		var panicErr *PanicError
+		require.ErrorAs(t, err, &panicErr)
+		require.Equal(t, "tool failed", panicErr.Value)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+		model := &mockLanguageModel{
+			generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+				panic("boom")
+			},
+		}
+		agent := NewAgent(model, WithPanicRecovery(false))
+		require.PanicsWithValue(t, "boom", func() {
+			_, _ = agent.Generate(t.Context(), AgentCall{Prompt: "hi"})
+		})
+	})
+}
@@ -0,0 +1,149 @@
+package fantasy
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// PDFPage is a page of a PDF document extracted by a PDFExtractor.
+type PDFPage struct {
+	Text string
+	// Image is a rendering of the page, such as a PNG, if the extractor
+	// renders pages.
+	Image          []byte
+	ImageMediaType string
+}
+
+// PDFExtractor extracts the pages of PDF documents for models that can't
+// read them. Fantasy doesn't parse PDFs itself; wrap the PDF library of the
+// application.
+type PDFExtractor interface {
+	ExtractPDF(ctx context.Context, data []byte) ([]PDFPage, error)
+}
+
+// PDFExtractorFunc adapts a function to a PDFExtractor.
+type PDFExtractorFunc func(ctx context.Context, data []byte) ([]PDFPage, error)
+
+// ExtractPDF implements PDFExtractor.
+func (f PDFExtractorFunc) ExtractPDF(ctx context.Context, data []byte) ([]PDFPage, error) {
+	return f(ctx, data)
+}
+
+// PDFFallback configures how the agent sends PDF documents to models that
+// can't read them. See WithPDFFallback.
+type PDFFallback struct {
+	Extractor PDFExtractor
+	// MaxPages is the number of pages sent of each document. Zero sends
+	// them all.
+	MaxPages int
+	// Images sends the page renderings of the extractor along with their
+	// text.
+	Images bool
+	// Always extracts documents even for models that report reading PDFs,
+	// such as OpenAI-compatible servers that don't.
+	Always bool
+}
+
+// WithPDFFallback makes the agent extract the PDF file parts of the prompt
+// with the fallback's extractor when the model doesn't implement PDFModel,
+// sending their pages as text, and images if enabled, instead of letting
+// the provider drop them. A warning is added to the first step when
+// documents are extracted.
+func WithPDFFallback(fallback PDFFallback) AgentOption {
+	return func(s *agentSettings) {
+		s.pdfFallback = &fallback
+	}
+}
+
+// fallBackFromPDFs replaces the PDF documents of prompt with their pages
+// when model can't read them.
+func (a *agent) fallBackFromPDFs(ctx context.Context, model LanguageModel, prompt Prompt) (Prompt, []CallWarning, error) {
+	fallback := a.settings.pdfFallback
+	if fallback == nil || fallback.Extractor == nil {
+		return prompt, nil, nil
+	}
+	if pdfModel, ok := model.(PDFModel); ok && pdfModel.SupportsPDF() && !fallback.Always {
+		return prompt, nil, nil
+	}
+	var warnings []CallWarning
+	var prepared Prompt
+	for i, msg := range prompt {
+		if !slices.ContainsFunc(msg.Content, isPDFPart) {
+			continue
+		}
+		var content []MessagePart
+		for _, part := range msg.Content {
+			if !isPDFPart(part) {
+				content = append(content, part)
+				continue
+			}
+			file, _ := AsMessagePart[FilePart](part)
+			if file.URL != "" {
+				var err error
+				if file, err = LoadFileURL(ctx, file, a.maxFileDownloadBytes()); err != nil {
+					return nil, nil, err
+				}
+			}
+			name := cmp.Or(file.Filename, "PDF document")
+			pages, err := fallback.Extractor.ExtractPDF(ctx, file.Data)
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil, nil, ctx.Err()
+				}
+				warnings = append(warnings, CallWarning{
+					Type:    CallWarningTypeOther,
+					Message: fmt.Sprintf("couldn't extract %s: %v", name, err),
+				})
+				content = append(content, part)
+				continue
+			}
+			content = append(content, fallback.pageParts(name, pages, msg.Role == MessageRoleUser)...)
+			warnings = append(warnings, CallWarning{
+				Type:    CallWarningTypeOther,
+				Message: fmt.Sprintf("%s was sent as its extracted pages since the model can't read PDFs", name),
+			})
+		}
+		if prepared == nil {
+			prepared = slices.Clone(prompt)
+		}
+		prepared[i].Content = content
+	}
+	if prepared == nil {
+		return prompt, warnings, nil
+	}
+	return prepared, warnings, nil
+}
+
+// pageParts returns the parts sending the pages of the document name. Page
+// images are only sent in user messages.
+func (f *PDFFallback) pageParts(name string, pages []PDFPage, images bool) []MessagePart {
+	sent := pages
+	if f.MaxPages > 0 && len(sent) > f.MaxPages {
+		sent = sent[:f.MaxPages]
+	}
+	parts := make([]MessagePart, 0, len(sent)+1)
+	for i, page := range sent {
+		parts = append(parts, TextPart{
+			Text: fmt.Sprintf("%s, page %d of %d:\n%s", name, i+1, len(pages), strings.TrimSpace(page.Text)),
+		})
+		if f.Images && images && len(page.Image) > 0 {
+			parts = append(parts, FilePart{
+				Filename:  fmt.Sprintf("%s-page-%d", strings.TrimSuffix(name, ".pdf"), i+1),
+				Data:      page.Image,
+				MediaType: page.ImageMediaType,
+			})
+		}
+	}
+	if omitted := len(pages) - len(sent); omitted > 0 {
+		parts = append(parts, TextPart{Text: fmt.Sprintf("(%d more pages of %s omitted)", omitted, name)})
+	}
+	return parts
+}
+
+func isPDFPart(part MessagePart) bool {
+	file, ok := AsMessagePart[FilePart](part)
+	return ok && file.MediaType == "application/pdf"
+}
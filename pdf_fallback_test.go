@@ -0,0 +1,86 @@
+package fantasy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type pdfMockModel struct {
+	mockLanguageModel
+}
+
+func (*pdfMockModel) SupportsPDF() bool { return true }
+
+func TestPDFFallback(t *testing.T) {
+	t.Parallel()
+
+	extractor := PDFExtractorFunc(func(_ context.Context, data []byte) ([]PDFPage, error) {
+		if string(data) == "broken" {
+			return nil, errors.New("bad xref")
+		}
+		return []PDFPage{
+			{Text: "first page", Image: []byte("png"), ImageMediaType: "image/png"},
+			{Text: "second page"},
+			{Text: "third page"},
+		}, nil
+	})
+	run := func(t *testing.T, model *mockLanguageModel, languageModel LanguageModel, fallback PDFFallback, file FilePart) (*AgentResult, Prompt) {
+		t.Helper()
+		var prompt Prompt
+		model.generateFunc = func(_ context.Context, call Call) (*Response, error) {
+			prompt = call.Prompt
+			return &Response{Content: ResponseContent{TextContent{Text: "ok"}}, FinishReason: FinishReasonStop}, nil
+		}
+		agent := NewAgent(languageModel, WithPDFFallback(fallback))
+		result, err := agent.Generate(t.Context(), AgentCall{Prompt: "summarize", Files: []FilePart{file}})
+		require.NoError(t, err)
+		return result, prompt
+	}
+	report := FilePart{Filename: "report.pdf", Data: []byte("%PDF"), MediaType: "application/pdf"}
+
+	t.Run("extracts pages for models that can't read PDFs", func(t *testing.T) {
+		t.Parallel()
+		model := &mockLanguageModel{}
+		result, prompt := run(t, model, model, PDFFallback{Extractor: extractor, MaxPages: 2, Images: true}, report)
+
+		content := prompt[0].Content
+		require.Len(t, content, 5)
+		require.Equal(t, TextPart{Text: "summarize"}, content[0])
+		require.Equal(t, TextPart{Text: "report.pdf, page 1 of 3:\nfirst page"}, content[1])
+		image, ok := AsMessagePart[FilePart](content[2])
+		require.True(t, ok)
+		require.Equal(t, "image/png", image.MediaType)
+		require.Equal(t, TextPart{Text: "report.pdf, page 2 of 3:\nsecond page"}, content[3])
+		require.Equal(t, TextPart{Text: "(1 more pages of report.pdf omitted)"}, content[4])
+
+		require.Len(t, result.Steps[0].Warnings, 1)
+		require.Contains(t, result.Steps[0].Warnings[0].Message, "report.pdf")
+	})
+
+	t.Run("keeps PDFs for models that read them", func(t *testing.T) {
+		t.Parallel()
+		model := &pdfMockModel{}
+		_, prompt := run(t, &model.mockLanguageModel, model, PDFFallback{Extractor: extractor}, report)
+		require.Equal(t, report, prompt[0].Content[1])
+	})
+
+	t.Run("always", func(t *testing.T) {
+		t.Parallel()
+		model := &pdfMockModel{}
+		_, prompt := run(t, &model.mockLanguageModel, model, PDFFallback{Extractor: extractor, Always: true}, report)
+		require.Len(t, prompt[0].Content, 4)
+	})
+
+	t.Run("keeps documents that fail to extract", func(t *testing.T) {
+		t.Parallel()
+		model := &mockLanguageModel{}
+		broken := FilePart{Data: []byte("broken"), MediaType: "application/pdf"}
+		result, prompt := run(t, model, model, PDFFallback{Extractor: extractor}, broken)
+		require.Equal(t, broken, prompt[0].Content[1])
+		require.Len(t, result.Steps[0].Warnings, 1)
+		require.Contains(t, result.Steps[0].Warnings[0].Message, "bad xref")
+	})
+}
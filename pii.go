@@ -0,0 +1,289 @@
+package fantasy
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// PIIKind is a kind of personal or secret information.
+type PIIKind string
+
+// Kinds of information detected by DetectPII.
+const (
+	PIIKindEmail      PIIKind = "email"
+	PIIKindPhone      PIIKind = "phone"
+	PIIKindCreditCard PIIKind = "credit_card"
+	PIIKindAPIKey     PIIKind = "api_key"
+)
+
+// PIIMatch is personal or secret information found in a text.
+type PIIMatch struct {
+	Kind  PIIKind `json:"kind"`
+	Value string  `json:"value"`
+	// Start and End are the byte offsets of the value in the text.
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+var piiPatterns = []struct {
+	kind    PIIKind
+	pattern *regexp.Regexp
+}{
+	// API keys come first so that the digits in them aren't taken for
+	// phone or card numbers.
+	{PIIKindAPIKey, regexp.MustCompile(`\b(?:sk-[A-Za-z0-9_-]{20,}|(?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36}|github_pat_[A-Za-z0-9_]{22,}|AKIA[0-9A-Z]{16}|AIza[0-9A-Za-z_-]{35}|xox[abprs]-[A-Za-z0-9-]{10,}|glpat-[A-Za-z0-9_-]{20})`)},
+	{PIIKindEmail, regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`)},
+	{PIIKindCreditCard, regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)},
+	{PIIKindPhone, regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{2,4}\)|\b\d{2,4})[ .-]?\d{3,4}[ .-]?\d{3,4}\b`)},
+}
+
+// DetectPII finds email addresses, phone numbers, credit card numbers and
+// API keys in a text, in the order they appear. Card numbers are checked
+// with the Luhn algorithm to avoid flagging other long numbers.
+func DetectPII(text string) []PIIMatch {
+	var matches []PIIMatch
+	overlaps := func(start, end int) bool {
+		return slices.ContainsFunc(matches, func(m PIIMatch) bool {
+			return start < m.End && m.Start < end
+		})
+	}
+	for _, p := range piiPatterns {
+		for _, loc := range p.pattern.FindAllStringIndex(text, -1) {
+			value := text[loc[0]:loc[1]]
+			if overlaps(loc[0], loc[1]) {
+				continue
+			}
+			if p.kind == PIIKindCreditCard && !luhnValid(value) {
+				continue
+			}
+			if p.kind == PIIKindPhone && (countDigits(value) < 7 || inLongerNumber(text, loc[0], loc[1])) {
+				continue
+			}
+			matches = append(matches, PIIMatch{Kind: p.kind, Value: value, Start: loc[0], End: loc[1]})
+		}
+	}
+	slices.SortFunc(matches, func(a, b PIIMatch) int {
+		return cmp.Compare(a.Start, b.Start)
+	})
+	return matches
+}
+
+func luhnValid(number string) bool {
+	sum, n := 0, 0
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
+
+// inLongerNumber reports whether the digits at text[start:end] continue
+// past either end, across a single separator.
+func inLongerNumber(text string, start, end int) bool {
+	isDigit := func(i int) bool { return i >= 0 && i < len(text) && text[i] >= '0' && text[i] <= '9' }
+	isSep := func(i int) bool { return i >= 0 && i < len(text) && strings.IndexByte(" .-", text[i]) >= 0 }
+	return isDigit(end) || (isSep(end) && isDigit(end+1)) ||
+		isDigit(start-1) || (isSep(start-1) && isDigit(start-2))
+}
+
+func countDigits(s string) int {
+	n := 0
+	for _, c := range s {
+		if c >= '0' && c <= '9' {
+			n++
+		}
+	}
+	return n
+}
+
+// PIIMode is what a PIIGuardrail does with the information it finds.
+type PIIMode int
+
+const (
+	// PIIModeRedact replaces each value with a token, such as
+	// "[EMAIL_1]". The same value always gets the same token within a
+	// run, and PIIRedactions.Restore puts the values back.
+	PIIModeRedact PIIMode = iota
+	// PIIModeBlock stops the run with a *GuardrailError.
+	PIIModeBlock
+	// PIIModeWarn lets the text through unchanged and only reports it to
+	// OnDetect.
+	PIIModeWarn
+)
+
+// PIIOptions configures a PIIGuardrail.
+type PIIOptions struct {
+	Mode PIIMode
+	// Kinds limits detection to these kinds. Defaults to all of them.
+	Kinds []PIIKind
+	// Stages limits checking to these stages. Defaults to the input and
+	// tool results, which is what the model sees.
+	Stages []GuardrailStage
+	// OnDetect is called with the information found in a text, in every
+	// mode.
+	OnDetect func(ctx context.Context, stage GuardrailStage, matches []PIIMatch)
+}
+
+// PIIGuardrail is a Guardrail that detects email addresses, phone numbers,
+// credit card numbers and API keys, and blocks, redacts or reports them.
+// Use it with WithGuardrails.
+//
+// Redacted values are kept in the PIIRedactions of the context, so that
+// runs for different users never share them. Each agent run gets its own
+// unless the context already has some, which is how callers restore them:
+//
+//	ctx, redactions := fantasy.WithPIIRedactions(ctx)
+//	result, err := agent.Generate(ctx, call)
+//	answer := redactions.Restore(result.Response.Content.Text())
+type PIIGuardrail struct {
+	opts PIIOptions
+}
+
+// NewPIIGuardrail creates a PII guardrail.
+func NewPIIGuardrail(opts PIIOptions) *PIIGuardrail {
+	if len(opts.Stages) == 0 {
+		opts.Stages = []GuardrailStage{GuardrailStageInput, GuardrailStageToolResult}
+	}
+	return &PIIGuardrail{opts: opts}
+}
+
+// Check implements Guardrail.
+func (g *PIIGuardrail) Check(ctx context.Context, stage GuardrailStage, text string) (string, error) {
+	if !slices.Contains(g.opts.Stages, stage) {
+		return text, nil
+	}
+	var matches []PIIMatch
+	for _, m := range DetectPII(text) {
+		if len(g.opts.Kinds) == 0 || slices.Contains(g.opts.Kinds, m.Kind) {
+			matches = append(matches, m)
+		}
+	}
+	if len(matches) == 0 {
+		return text, nil
+	}
+	if g.opts.OnDetect != nil {
+		g.opts.OnDetect(ctx, stage, matches)
+	}
+
+	switch g.opts.Mode {
+	case PIIModeBlock:
+		var kinds []string
+		for _, m := range matches {
+			if !slices.Contains(kinds, string(m.Kind)) {
+				kinds = append(kinds, string(m.Kind))
+			}
+		}
+		return "", &GuardrailError{Stage: stage, Reason: "contains personal information", Categories: kinds}
+	case PIIModeWarn:
+		return text, nil
+	}
+
+	redactions, ok := PIIRedactionsFromContext(ctx)
+	if !ok {
+		// Outside of a run, the values are only consistent within the text.
+		redactions = &PIIRedactions{}
+	}
+	var sb strings.Builder
+	last := 0
+	for _, m := range matches {
+		sb.WriteString(text[last:m.Start])
+		sb.WriteString(redactions.token(m))
+		last = m.End
+	}
+	sb.WriteString(text[last:])
+	return sb.String(), nil
+}
+
+// PIIRedactions are the values redacted by PIIGuardrails during a run or a
+// session, by token. Create them with WithPIIRedactions.
+type PIIRedactions struct {
+	mu      sync.Mutex
+	tokens  map[string]string // value to token
+	values  map[string]string // token to value
+	counter map[PIIKind]int
+}
+
+type piiRedactionsKey struct{}
+
+// WithPIIRedactions returns a context in which PIIGuardrails keep the values
+// they redact in new redactions, and the redactions, to restore them.
+func WithPIIRedactions(ctx context.Context) (context.Context, *PIIRedactions) {
+	redactions := &PIIRedactions{}
+	return context.WithValue(ctx, piiRedactionsKey{}, redactions), redactions
+}
+
+// withRunPIIRedactions gives a run its own redactions, unless the caller
+// set some.
+func withRunPIIRedactions(ctx context.Context) context.Context {
+	if _, ok := PIIRedactionsFromContext(ctx); ok {
+		return ctx
+	}
+	ctx, _ = WithPIIRedactions(ctx)
+	return ctx
+}
+
+// PIIRedactionsFromContext returns the redactions of the context set with
+// WithPIIRedactions.
+func PIIRedactionsFromContext(ctx context.Context) (*PIIRedactions, bool) {
+	redactions, ok := ctx.Value(piiRedactionsKey{}).(*PIIRedactions)
+	return redactions, ok
+}
+
+func (r *PIIRedactions) token(m PIIMatch) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if token, ok := r.tokens[m.Value]; ok {
+		return token
+	}
+	if r.tokens == nil {
+		r.tokens = map[string]string{}
+		r.values = map[string]string{}
+		r.counter = map[PIIKind]int{}
+	}
+	r.counter[m.Kind]++
+	token := fmt.Sprintf("[%s_%d]", strings.ToUpper(string(m.Kind)), r.counter[m.Kind])
+	r.tokens[m.Value] = token
+	r.values[token] = m.Value
+	return token
+}
+
+// Restore replaces the tokens in a text with the values they redacted, for
+// example to show the model's answer to the user or to pass the real value
+// to a tool.
+func (r *PIIRedactions) Restore(text string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.values) == 0 {
+		return text
+	}
+	pairs := make([]string, 0, 2*len(r.values))
+	for token, value := range r.values {
+		pairs = append(pairs, token, value)
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// Reset forgets the redacted values.
+func (r *PIIRedactions) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	clear(r.tokens)
+	clear(r.values)
+	clear(r.counter)
+}
@@ -0,0 +1,348 @@
+package fantasy
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"sync"
+)
+
+// CacheProfile describes how a provider caches prompt prefixes. Providers
+// register theirs with RegisterCacheProfile for AnalyzeCacheability and
+// WithAutomaticCacheBreakpoints.
+type CacheProfile struct {
+	// MaxBreakpoints is the most cache breakpoints a call may have. Zero
+	// means the provider caches prefixes automatically.
+	MaxBreakpoints int
+	// MinTokens is the shortest prefix the provider caches.
+	MinTokens int64
+	// ReadCost is the price of a cached input token relative to an
+	// uncached one.
+	ReadCost float64
+	// IsBreakpoint reports whether provider options mark a cache
+	// breakpoint.
+	IsBreakpoint func(ProviderOptions) bool
+	// Breakpoint returns the message provider options that mark a cache
+	// breakpoint at the end of the message.
+	Breakpoint func() ProviderOptions
+}
+
+var cacheProfiles sync.Map
+
+// RegisterCacheProfile registers how the provider with the name caches
+// prompts. It should only be called during package initialization.
+func RegisterCacheProfile(provider string, profile CacheProfile) {
+	cacheProfiles.Store(provider, profile)
+}
+
+func cacheProfile(provider string) (CacheProfile, bool) {
+	profile, ok := cacheProfiles.Load(provider)
+	if !ok {
+		return CacheProfile{}, false
+	}
+	return profile.(CacheProfile), true //nolint:forcetypeassert // type enforced by RegisterCacheProfile
+}
+
+// CacheBreakpoint is the end of a cached prompt prefix.
+type CacheBreakpoint struct {
+	// Message and Part are the indexes of the last part of the prefix.
+	Message int
+	Part    int
+	// PrefixTokens is the estimated tokens of the prefix.
+	PrefixTokens int64
+}
+
+// CacheReport is the result of AnalyzeCacheability. Token counts are
+// estimated from the text of the prompt; files aren't counted.
+type CacheReport struct {
+	Provider    string
+	TotalTokens int64
+	// StableTokens is the prefix the next call repeats: the whole prompt,
+	// up to the first system message that seems to change on every call,
+	// such as one with the current time.
+	StableTokens int64
+	// Breakpoints are the breakpoints of the prompt and Suggested the ones
+	// it should have. Providers that cache automatically have neither.
+	Breakpoints []CacheBreakpoint
+	Suggested   []CacheBreakpoint
+	// CachedTokens is the prefix the next call reads from the cache with
+	// the breakpoints of the prompt, and SuggestedCachedTokens with the
+	// suggested ones.
+	CachedTokens          int64
+	SuggestedCachedTokens int64
+	// Savings is the share of the input cost of the next call saved by
+	// caching with the breakpoints of the prompt, and SuggestedSavings with
+	// the suggested ones.
+	Savings          float64
+	SuggestedSavings float64
+	// Issues describe misconfigurations, such as too many breakpoints or
+	// breakpoints on prefixes too short to be cached.
+	Issues []string
+}
+
+// volatileContent matches content that usually changes on every call, such
+// as timestamps and UUIDs, which invalidates the cache of everything after
+// it.
+var volatileContent = regexp.MustCompile(
+	`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}|\b\d{1,2}:\d{2}:\d{2}\b|` +
+		`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`,
+)
+
+// AnalyzeCacheability reports how the prompt caches with the provider, by
+// name: the prefix the next call repeats, where its cache breakpoints are
+// and where they should be, and the estimated savings. It assumes the next
+// call repeats the prompt and appends to it, as the steps and turns of an
+// agent do.
+func AnalyzeCacheability(prompt Prompt, provider string) CacheReport {
+	report := CacheReport{Provider: provider}
+	profile, ok := cacheProfile(provider)
+	if !ok {
+		report.Issues = append(report.Issues, fmt.Sprintf("no cache profile is registered for provider %q", provider))
+	}
+
+	// prefix[i][j] is the tokens of the prompt up to and including part j
+	// of message i.
+	prefix := make([][]int64, len(prompt))
+	stableEnd := -1 // The first message with volatile content.
+	for i, msg := range prompt {
+		if stableEnd < 0 && msg.Role == MessageRoleSystem && slices.ContainsFunc(msg.Content, func(part MessagePart) bool {
+			return volatileContent.MatchString(partText(part))
+		}) {
+			stableEnd = i
+			report.StableTokens = report.TotalTokens
+			report.Issues = append(report.Issues, fmt.Sprintf(
+				"system message %d contains what looks like a timestamp or an ID, which changes on every call; nothing from it on can be read from the cache",
+				i,
+			))
+		}
+		prefix[i] = make([]int64, len(msg.Content))
+		for j, part := range msg.Content {
+			report.TotalTokens += EstimateTokens(partText(part))
+			prefix[i][j] = report.TotalTokens
+			if ok && profile.MaxBreakpoints > 0 && isCacheBreakpoint(profile, msg, j) {
+				report.Breakpoints = append(report.Breakpoints, CacheBreakpoint{Message: i, Part: j, PrefixTokens: report.TotalTokens})
+			}
+		}
+	}
+	if stableEnd < 0 {
+		stableEnd = len(prompt)
+		report.StableTokens = report.TotalTokens
+	}
+	if !ok || report.TotalTokens == 0 {
+		return report
+	}
+
+	if profile.MaxBreakpoints == 0 {
+		if report.StableTokens >= profile.MinTokens {
+			report.CachedTokens = report.StableTokens
+		} else {
+			report.Issues = append(report.Issues, fmt.Sprintf(
+				"the stable prefix of %d tokens is shorter than the %d tokens the provider caches",
+				report.StableTokens, profile.MinTokens,
+			))
+		}
+		report.SuggestedCachedTokens = report.CachedTokens
+		report.Savings = cacheSavings(profile, report.CachedTokens, report.TotalTokens)
+		report.SuggestedSavings = report.Savings
+		return report
+	}
+
+	if len(report.Breakpoints) > profile.MaxBreakpoints {
+		report.Issues = append(report.Issues, fmt.Sprintf(
+			"the prompt has %d cache breakpoints but the provider allows %d",
+			len(report.Breakpoints), profile.MaxBreakpoints,
+		))
+	}
+	for _, bp := range report.Breakpoints {
+		switch {
+		case bp.Message >= stableEnd:
+			report.Issues = append(report.Issues, fmt.Sprintf(
+				"the breakpoint at message %d follows content that changes on every call and is never read",
+				bp.Message,
+			))
+		case bp.PrefixTokens < profile.MinTokens:
+			report.Issues = append(report.Issues, fmt.Sprintf(
+				"the breakpoint at message %d caches %d tokens, fewer than the %d tokens the provider caches",
+				bp.Message, bp.PrefixTokens, profile.MinTokens,
+			))
+		default:
+			report.CachedTokens = max(report.CachedTokens, bp.PrefixTokens)
+		}
+	}
+
+	report.Suggested = suggestCacheBreakpoints(prompt, prefix, stableEnd, profile)
+	for _, bp := range report.Suggested {
+		report.SuggestedCachedTokens = max(report.SuggestedCachedTokens, bp.PrefixTokens)
+	}
+	if len(report.Breakpoints) == 0 && report.SuggestedCachedTokens > 0 {
+		report.Issues = append(report.Issues, fmt.Sprintf(
+			"the prompt has no cache breakpoints; %d tokens could be cached",
+			report.SuggestedCachedTokens,
+		))
+	}
+	report.Savings = cacheSavings(profile, report.CachedTokens, report.TotalTokens)
+	report.SuggestedSavings = cacheSavings(profile, report.SuggestedCachedTokens, report.TotalTokens)
+	return report
+}
+
+// suggestCacheBreakpoints places breakpoints at the end of messages within
+// the stable prefix: the last one, so the next call reads the whole
+// conversation; the one the previous step ended with, so this call reads
+// what it wrote; and the system prompt, shared by all conversations.
+func suggestCacheBreakpoints(prompt Prompt, prefix [][]int64, stableEnd int, profile CacheProfile) []CacheBreakpoint {
+	var candidates []int
+	if last := lastNonEmpty(prompt[:stableEnd]); last >= 0 {
+		candidates = append(candidates, last)
+	}
+	for i := stableEnd - 1; i >= 0; i-- {
+		if prompt[i].Role != MessageRoleAssistant {
+			continue
+		}
+		if previous := lastNonEmpty(prompt[:i]); previous >= 0 {
+			candidates = append(candidates, previous)
+		}
+		break
+	}
+	lastSystem := -1
+	for i, msg := range prompt[:stableEnd] {
+		if msg.Role == MessageRoleSystem && len(msg.Content) > 0 {
+			lastSystem = i
+		}
+	}
+	if lastSystem >= 0 {
+		candidates = append(candidates, lastSystem)
+	}
+
+	var suggested []CacheBreakpoint
+	for _, i := range candidates {
+		bp := CacheBreakpoint{Message: i, Part: len(prompt[i].Content) - 1, PrefixTokens: prefix[i][len(prefix[i])-1]}
+		if bp.PrefixTokens < profile.MinTokens || len(suggested) == profile.MaxBreakpoints ||
+			slices.ContainsFunc(suggested, func(s CacheBreakpoint) bool { return s.Message == i }) {
+			continue
+		}
+		suggested = append(suggested, bp)
+	}
+	slices.SortFunc(suggested, func(a, b CacheBreakpoint) int {
+		return cmp.Compare(a.Message, b.Message)
+	})
+	return suggested
+}
+
+// ApplyCacheBreakpoints returns the prompt with the breakpoints
+// AnalyzeCacheability suggests for the provider, keeping the ones it already
+// has. Messages whose provider options for the provider are set aren't
+// changed. The prompt is left untouched.
+func ApplyCacheBreakpoints(prompt Prompt, provider string) Prompt {
+	profile, ok := cacheProfile(provider)
+	if !ok || profile.MaxBreakpoints == 0 || profile.Breakpoint == nil {
+		return prompt
+	}
+	report := AnalyzeCacheability(prompt, provider)
+	available := profile.MaxBreakpoints - len(report.Breakpoints)
+	var applied Prompt
+	for _, bp := range slices.Backward(report.Suggested) {
+		if available <= 0 {
+			break
+		}
+		msg := prompt[bp.Message]
+		if isCacheBreakpoint(profile, msg, bp.Part) {
+			continue
+		}
+		options := profile.Breakpoint()
+		if hasProviderOptions(msg.ProviderOptions, options) {
+			continue
+		}
+		if applied == nil {
+			applied = slices.Clone(prompt)
+		}
+		merged := maps.Clone(msg.ProviderOptions)
+		if merged == nil {
+			merged = ProviderOptions{}
+		}
+		maps.Copy(merged, options)
+		applied[bp.Message].ProviderOptions = merged
+		available--
+	}
+	if applied == nil {
+		return prompt
+	}
+	return applied
+}
+
+// WithAutomaticCacheBreakpoints makes the agent add the cache breakpoints
+// AnalyzeCacheability suggests to the prompt of each step, for providers
+// that need them, such as Anthropic. Breakpoints on tools aren't counted
+// against the provider's limit.
+func WithAutomaticCacheBreakpoints() AgentOption {
+	return func(s *agentSettings) {
+		s.automaticCacheBreakpoints = true
+	}
+}
+
+func (a *agent) applyCacheBreakpoints(model LanguageModel, prompt Prompt) Prompt {
+	if !a.settings.automaticCacheBreakpoints {
+		return prompt
+	}
+	return ApplyCacheBreakpoints(prompt, model.Provider())
+}
+
+// isCacheBreakpoint reports whether part j of msg ends a cached prefix.
+// Message options apply to the last part.
+func isCacheBreakpoint(profile CacheProfile, msg Message, j int) bool {
+	if profile.IsBreakpoint == nil {
+		return false
+	}
+	if profile.IsBreakpoint(msg.Content[j].Options()) {
+		return true
+	}
+	return j == len(msg.Content)-1 && profile.IsBreakpoint(msg.ProviderOptions)
+}
+
+// hasProviderOptions reports whether options has any of the providers of
+// other.
+func hasProviderOptions(options, other ProviderOptions) bool {
+	for provider := range other {
+		if _, ok := options[provider]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func cacheSavings(profile CacheProfile, cached, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(cached) * (1 - profile.ReadCost) / float64(total)
+}
+
+func lastNonEmpty(prompt Prompt) int {
+	for i := len(prompt) - 1; i >= 0; i-- {
+		if len(prompt[i].Content) > 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// partText returns the text of a message part that counts towards its
+// tokens.
+func partText(part MessagePart) string {
+	switch part.GetType() {
+	case ContentTypeText:
+		text, _ := AsMessagePart[TextPart](part)
+		return text.Text
+	case ContentTypeReasoning:
+		reasoning, _ := AsMessagePart[ReasoningPart](part)
+		return reasoning.Text
+	case ContentTypeToolCall:
+		call, _ := AsMessagePart[ToolCallPart](part)
+		return call.ToolName + call.Input
+	case ContentTypeToolResult:
+		result, _ := AsMessagePart[ToolResultPart](part)
+		return toolResultOutputText(result.Output)
+	}
+	return ""
+}
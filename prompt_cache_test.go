@@ -0,0 +1,139 @@
+package fantasy
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	RegisterCacheProfile("mock-provider", CacheProfile{
+		MaxBreakpoints: 2,
+		MinTokens:      100,
+		ReadCost:       0.1,
+		IsBreakpoint: func(options ProviderOptions) bool {
+			_, ok := options["mock-provider"].(*testProviderOptions)
+			return ok
+		},
+		Breakpoint: func() ProviderOptions {
+			return ProviderOptions{"mock-provider": &testProviderOptions{}}
+		},
+	})
+	RegisterCacheProfile("mock-automatic", CacheProfile{MinTokens: 100, ReadCost: 0.5})
+}
+
+// cacheTestPrompt is a conversation of a system prompt and an agent step.
+func cacheTestPrompt(system string) Prompt {
+	return Prompt{
+		NewSystemMessage(system),
+		NewUserMessage(strings.Repeat("question ", 50)),
+		{
+			Role:    MessageRoleAssistant,
+			Content: []MessagePart{ToolCallPart{ToolCallID: "call-1", ToolName: "search", Input: `{}`}},
+		},
+		{
+			Role:    MessageRoleTool,
+			Content: []MessagePart{ToolResultPart{ToolCallID: "call-1", Output: ToolResultOutputContentText{Text: strings.Repeat("result ", 100)}}},
+		},
+	}
+}
+
+func TestAnalyzeCacheability(t *testing.T) {
+	t.Parallel()
+
+	system := strings.Repeat("You are a helpful assistant. ", 20)
+
+	t.Run("suggests breakpoints", func(t *testing.T) {
+		t.Parallel()
+		report := AnalyzeCacheability(cacheTestPrompt(system), "mock-provider")
+		require.Empty(t, report.Breakpoints)
+		require.Equal(t, report.TotalTokens, report.StableTokens)
+		require.Equal(t, []int{1, 3}, breakpointMessages(report.Suggested))
+		require.Equal(t, report.TotalTokens, report.SuggestedCachedTokens)
+		require.Zero(t, report.Savings)
+		require.InDelta(t, 0.9, report.SuggestedSavings, 0.001)
+		require.Len(t, report.Issues, 1)
+		require.Contains(t, report.Issues[0], "no cache breakpoints")
+	})
+
+	t.Run("reports misplaced breakpoints", func(t *testing.T) {
+		t.Parallel()
+		prompt := cacheTestPrompt("Today is 2026-10-16 09:30. " + system)
+		prompt[1].ProviderOptions = ProviderOptions{"mock-provider": &testProviderOptions{}}
+		report := AnalyzeCacheability(prompt, "mock-provider")
+		require.Equal(t, []int{1}, breakpointMessages(report.Breakpoints))
+		require.Zero(t, report.StableTokens)
+		require.Zero(t, report.CachedTokens)
+		require.Empty(t, report.Suggested)
+		require.Len(t, report.Issues, 2)
+		require.Contains(t, report.Issues[0], "timestamp")
+		require.Contains(t, report.Issues[1], "never read")
+	})
+
+	t.Run("reports short prefixes", func(t *testing.T) {
+		t.Parallel()
+		prompt := cacheTestPrompt("Be brief.")
+		prompt[0].ProviderOptions = ProviderOptions{"mock-provider": &testProviderOptions{}}
+		report := AnalyzeCacheability(prompt, "mock-provider")
+		require.Contains(t, report.Issues[0], "fewer than the 100 tokens")
+		require.Equal(t, []int{1, 3}, breakpointMessages(report.Suggested))
+	})
+
+	t.Run("automatic caching", func(t *testing.T) {
+		t.Parallel()
+		report := AnalyzeCacheability(cacheTestPrompt(system), "mock-automatic")
+		require.Empty(t, report.Suggested)
+		require.Equal(t, report.TotalTokens, report.CachedTokens)
+		require.InDelta(t, 0.5, report.Savings, 0.001)
+	})
+
+	t.Run("unknown provider", func(t *testing.T) {
+		t.Parallel()
+		report := AnalyzeCacheability(cacheTestPrompt(system), "unknown")
+		require.Contains(t, report.Issues[0], "no cache profile")
+	})
+}
+
+func TestApplyCacheBreakpoints(t *testing.T) {
+	t.Parallel()
+
+	prompt := cacheTestPrompt(strings.Repeat("You are a helpful assistant. ", 20))
+	applied := ApplyCacheBreakpoints(prompt, "mock-provider")
+	require.Equal(t, []int{1, 3}, breakpointMessages(AnalyzeCacheability(applied, "mock-provider").Breakpoints))
+	require.Empty(t, AnalyzeCacheability(prompt, "mock-provider").Breakpoints, "the prompt must not be modified")
+
+	// Existing breakpoints count against the limit.
+	prompt[0].ProviderOptions = ProviderOptions{"mock-provider": &testProviderOptions{}}
+	applied = ApplyCacheBreakpoints(prompt, "mock-provider")
+	require.Equal(t, []int{0, 3}, breakpointMessages(AnalyzeCacheability(applied, "mock-provider").Breakpoints))
+}
+
+func TestWithAutomaticCacheBreakpoints(t *testing.T) {
+	t.Parallel()
+
+	var prompts []Prompt
+	model := &mockLanguageModel{
+		generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+			prompts = append(prompts, call.Prompt)
+			return &Response{Content: []Content{TextContent{Text: "done"}}, FinishReason: FinishReasonStop}, nil
+		},
+	}
+	agent := NewAgent(model,
+		WithSystemPrompt(strings.Repeat("You are a helpful assistant. ", 20)),
+		WithAutomaticCacheBreakpoints(),
+	)
+	_, err := agent.Generate(context.Background(), AgentCall{Prompt: strings.Repeat("question ", 50)})
+	require.NoError(t, err)
+	require.Len(t, prompts, 1)
+	require.Equal(t, []int{0, 1}, breakpointMessages(AnalyzeCacheability(prompts[0], "mock-provider").Breakpoints))
+}
+
+func breakpointMessages(breakpoints []CacheBreakpoint) []int {
+	var messages []int
+	for _, bp := range breakpoints {
+		messages = append(messages, bp.Message)
+	}
+	return messages
+}
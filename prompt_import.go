@@ -0,0 +1,492 @@
+package fantasy
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Provider data type IDs registered by the Anthropic provider. They are used
+// to restore reasoning signatures and cache control markers when that
+// provider is linked in.
+const (
+	anthropicProviderName      = "anthropic"
+	anthropicReasoningMetadata = anthropicProviderName + ".reasoning_metadata"
+	anthropicCacheControl      = anthropicProviderName + ".cache_control_options"
+)
+
+// openAIMessageJSON is a message in the OpenAI chat completions format.
+type openAIMessageJSON struct {
+	Role             string          `json:"role"`
+	Content          json.RawMessage `json:"content"`
+	ReasoningContent string          `json:"reasoning_content"`
+	ToolCalls        []struct {
+		ID       string `json:"id"`
+		Type     string `json:"type"`
+		Function struct {
+			Name      string `json:"name"`
+			Arguments string `json:"arguments"`
+		} `json:"function"`
+	} `json:"tool_calls"`
+	ToolCallID string `json:"tool_call_id"`
+}
+
+// openAIContentPartJSON is a part of an OpenAI message content array.
+type openAIContentPartJSON struct {
+	Type     string `json:"type"`
+	Text     string `json:"text"`
+	Refusal  string `json:"refusal"`
+	ImageURL struct {
+		URL string `json:"url"`
+	} `json:"image_url"`
+	File struct {
+		Filename string `json:"filename"`
+		FileData string `json:"file_data"`
+		FileID   string `json:"file_id"`
+	} `json:"file"`
+	InputAudio struct {
+		Data   string `json:"data"`
+		Format string `json:"format"`
+	} `json:"input_audio"`
+}
+
+// PromptFromOpenAIMessages converts messages in the OpenAI chat completions
+// format into a Prompt. It accepts a JSON array of messages or an object
+// with a "messages" array, such as a fine-tuning dataset line.
+//
+// Consecutive tool messages are grouped into a single tool message, and
+// every tool result must answer a tool call made earlier in the
+// conversation. Images and files must be inline data URLs, since a Prompt
+// can't reference remote or uploaded files.
+func PromptFromOpenAIMessages(data []byte) (Prompt, error) {
+	var messages []openAIMessageJSON
+	if err := unmarshalMessageList(data, &messages); err != nil {
+		return nil, err
+	}
+
+	var prompt Prompt
+	toolCalls := map[string]bool{}
+	for i, m := range messages {
+		switch m.Role {
+		case "system", "developer":
+			text, err := openAIText(m.Content)
+			if err != nil {
+				return nil, fmt.Errorf("message %d: %w", i, err)
+			}
+			prompt = append(prompt, NewSystemMessage(text))
+		case "user":
+			parts, err := openAIUserParts(m.Content)
+			if err != nil {
+				return nil, fmt.Errorf("message %d: %w", i, err)
+			}
+			prompt = append(prompt, Message{Role: MessageRoleUser, Content: parts})
+		case "assistant":
+			var parts []MessagePart
+			if m.ReasoningContent != "" {
+				parts = append(parts, ReasoningPart{Text: m.ReasoningContent})
+			}
+			text, err := openAIText(m.Content)
+			if err != nil {
+				return nil, fmt.Errorf("message %d: %w", i, err)
+			}
+			if text != "" {
+				parts = append(parts, TextPart{Text: text})
+			}
+			for _, call := range m.ToolCalls {
+				toolCalls[call.ID] = true
+				parts = append(parts, ToolCallPart{
+					ToolCallID: call.ID,
+					ToolName:   call.Function.Name,
+					Input:      call.Function.Arguments,
+				})
+			}
+			prompt = appendMessage(prompt, MessageRoleAssistant, parts)
+		case "tool":
+			if !toolCalls[m.ToolCallID] {
+				return nil, fmt.Errorf("message %d: tool result %q has no matching tool call", i, m.ToolCallID)
+			}
+			text, err := openAIText(m.Content)
+			if err != nil {
+				return nil, fmt.Errorf("message %d: %w", i, err)
+			}
+			prompt = appendToolResult(prompt, ToolResultPart{
+				ToolCallID: m.ToolCallID,
+				Output:     ToolResultOutputContentText{Text: text},
+			})
+		default:
+			return nil, fmt.Errorf("message %d: unsupported role %q", i, m.Role)
+		}
+	}
+	return prompt, nil
+}
+
+// openAIText returns the text of OpenAI message content, which is a string,
+// an array of parts or null.
+func openAIText(content json.RawMessage) (string, error) {
+	parts, err := openAIContentParts(content)
+	if err != nil {
+		return "", err
+	}
+	var texts []string
+	for _, part := range parts {
+		switch part.Type {
+		case "text":
+			texts = append(texts, part.Text)
+		case "refusal":
+			texts = append(texts, part.Refusal)
+		default:
+			return "", fmt.Errorf("unsupported content part %q", part.Type)
+		}
+	}
+	return strings.Join(texts, "\n"), nil
+}
+
+func openAIUserParts(content json.RawMessage) ([]MessagePart, error) {
+	parts, err := openAIContentParts(content)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]MessagePart, 0, len(parts))
+	for _, part := range parts {
+		switch part.Type {
+		case "text":
+			result = append(result, TextPart{Text: part.Text})
+		case "image_url":
+			file, err := fileFromDataURL(part.ImageURL.URL)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, file)
+		case "file":
+			if part.File.FileData == "" {
+				return nil, errors.New("files must be inline data, not uploaded file IDs")
+			}
+			file, err := fileFromDataURL(part.File.FileData)
+			if err != nil {
+				return nil, err
+			}
+			file.Filename = part.File.Filename
+			result = append(result, file)
+		case "input_audio":
+			data, err := base64.StdEncoding.DecodeString(part.InputAudio.Data)
+			if err != nil {
+				return nil, fmt.Errorf("invalid audio data: %w", err)
+			}
+			result = append(result, FilePart{Data: data, MediaType: "audio/" + part.InputAudio.Format})
+		default:
+			return nil, fmt.Errorf("unsupported content part %q", part.Type)
+		}
+	}
+	return result, nil
+}
+
+// openAIContentParts normalizes OpenAI message content into parts, turning a
+// plain string into a single text part.
+func openAIContentParts(content json.RawMessage) ([]openAIContentPartJSON, error) {
+	content = bytes.TrimSpace(content)
+	if len(content) == 0 || bytes.Equal(content, []byte("null")) {
+		return nil, nil
+	}
+	if content[0] == '"' {
+		var text string
+		if err := json.Unmarshal(content, &text); err != nil {
+			return nil, err
+		}
+		return []openAIContentPartJSON{{Type: "text", Text: text}}, nil
+	}
+	var parts []openAIContentPartJSON
+	if err := json.Unmarshal(content, &parts); err != nil {
+		return nil, err
+	}
+	return parts, nil
+}
+
+// fileFromDataURL decodes a base64 data URL into a FilePart.
+func fileFromDataURL(url string) (FilePart, error) {
+	rest, ok := strings.CutPrefix(url, "data:")
+	if !ok {
+		return FilePart{}, errors.New("files must be inline data URLs, not remote URLs")
+	}
+	mediaType, encoded, ok := strings.Cut(rest, ";base64,")
+	if !ok {
+		return FilePart{}, errors.New("data URLs must be base64 encoded")
+	}
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return FilePart{}, fmt.Errorf("invalid data URL: %w", err)
+	}
+	return FilePart{Data: data, MediaType: mediaType}, nil
+}
+
+// anthropicBlockJSON is a content block in the Anthropic messages format.
+type anthropicBlockJSON struct {
+	Type      string `json:"type"`
+	Text      string `json:"text"`
+	Thinking  string `json:"thinking"`
+	Signature string `json:"signature"`
+	Data      string `json:"data"`
+	Source    struct {
+		Type      string `json:"type"`
+		MediaType string `json:"media_type"`
+		Data      string `json:"data"`
+	} `json:"source"`
+	ID           string          `json:"id"`
+	Name         string          `json:"name"`
+	Input        json.RawMessage `json:"input"`
+	ToolUseID    string          `json:"tool_use_id"`
+	Content      json.RawMessage `json:"content"`
+	IsError      bool            `json:"is_error"`
+	CacheControl json.RawMessage `json:"cache_control"`
+}
+
+// anthropicMessageJSON is a message in the Anthropic messages format.
+type anthropicMessageJSON struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+// PromptFromAnthropicMessages converts messages in the Anthropic messages
+// format into a Prompt. It accepts a JSON array of messages or a request-like
+// object with "system" and "messages" fields.
+//
+// Tool results sent in user messages become tool messages, and every tool
+// result must answer a tool use made earlier in the conversation. Reasoning
+// signatures and cache control markers are restored as Anthropic provider
+// options when the Anthropic provider is linked in, and dropped otherwise.
+// Server tool blocks are skipped.
+func PromptFromAnthropicMessages(data []byte) (Prompt, error) {
+	var request struct {
+		System   json.RawMessage        `json:"system"`
+		Messages []anthropicMessageJSON `json:"messages"`
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := json.Unmarshal(trimmed, &request.Messages); err != nil {
+			return nil, err
+		}
+	} else if err := json.Unmarshal(data, &request); err != nil {
+		return nil, err
+	}
+
+	var prompt Prompt
+	system, err := anthropicBlocks(request.System)
+	if err != nil {
+		return nil, fmt.Errorf("system: %w", err)
+	}
+	if len(system) > 0 {
+		msg := Message{Role: MessageRoleSystem}
+		for _, block := range system {
+			msg.Content = append(msg.Content, TextPart{Text: block.Text, ProviderOptions: anthropicCacheOptions(block)})
+		}
+		prompt = append(prompt, msg)
+	}
+
+	toolUses := map[string]bool{}
+	for i, m := range request.Messages {
+		blocks, err := anthropicBlocks(m.Content)
+		if err != nil {
+			return nil, fmt.Errorf("message %d: %w", i, err)
+		}
+		var role MessageRole
+		switch m.Role {
+		case "user":
+			role = MessageRoleUser
+		case "assistant":
+			role = MessageRoleAssistant
+		default:
+			return nil, fmt.Errorf("message %d: unsupported role %q", i, m.Role)
+		}
+
+		var parts []MessagePart
+		for _, block := range blocks {
+			switch block.Type {
+			case "text":
+				parts = append(parts, TextPart{Text: block.Text, ProviderOptions: anthropicCacheOptions(block)})
+			case "thinking":
+				parts = append(parts, ReasoningPart{
+					Text:            block.Thinking,
+					ProviderOptions: anthropicReasoningOptions(map[string]string{"signature": block.Signature}),
+				})
+			case "redacted_thinking":
+				parts = append(parts, ReasoningPart{
+					ProviderOptions: anthropicReasoningOptions(map[string]string{"redacted_data": block.Data}),
+				})
+			case "image", "document":
+				part, err := anthropicFilePart(block)
+				if err != nil {
+					return nil, fmt.Errorf("message %d: %w", i, err)
+				}
+				parts = append(parts, part)
+			case "tool_use":
+				toolUses[block.ID] = true
+				input := "{}"
+				if len(block.Input) > 0 {
+					input = string(block.Input)
+				}
+				parts = append(parts, ToolCallPart{
+					ToolCallID:      block.ID,
+					ToolName:        block.Name,
+					Input:           input,
+					ProviderOptions: anthropicCacheOptions(block),
+				})
+			case "tool_result":
+				if !toolUses[block.ToolUseID] {
+					return nil, fmt.Errorf("message %d: tool result %q has no matching tool use", i, block.ToolUseID)
+				}
+				result, err := anthropicToolResult(block)
+				if err != nil {
+					return nil, fmt.Errorf("message %d: %w", i, err)
+				}
+				// Tool results must directly follow the tool calls, so
+				// flush the parts that came before them.
+				prompt = appendMessage(prompt, role, parts)
+				parts = nil
+				prompt = appendToolResult(prompt, result)
+			case "server_tool_use", "web_search_tool_result", "web_fetch_tool_result", "mcp_tool_use", "mcp_tool_result":
+				continue
+			default:
+				return nil, fmt.Errorf("message %d: unsupported content block %q", i, block.Type)
+			}
+		}
+		prompt = appendMessage(prompt, role, parts)
+	}
+	return prompt, nil
+}
+
+// anthropicBlocks normalizes Anthropic content into blocks, turning a plain
+// string into a single text block.
+func anthropicBlocks(content json.RawMessage) ([]anthropicBlockJSON, error) {
+	content = bytes.TrimSpace(content)
+	if len(content) == 0 || bytes.Equal(content, []byte("null")) {
+		return nil, nil
+	}
+	if content[0] == '"' {
+		var text string
+		if err := json.Unmarshal(content, &text); err != nil {
+			return nil, err
+		}
+		return []anthropicBlockJSON{{Type: "text", Text: text}}, nil
+	}
+	var blocks []anthropicBlockJSON
+	if err := json.Unmarshal(content, &blocks); err != nil {
+		return nil, err
+	}
+	return blocks, nil
+}
+
+func anthropicFilePart(block anthropicBlockJSON) (FilePart, error) {
+	switch block.Source.Type {
+	case "base64":
+		data, err := base64.StdEncoding.DecodeString(block.Source.Data)
+		if err != nil {
+			return FilePart{}, fmt.Errorf("invalid %s data: %w", block.Type, err)
+		}
+		return FilePart{Data: data, MediaType: block.Source.MediaType, ProviderOptions: anthropicCacheOptions(block)}, nil
+	case "text":
+		return FilePart{Data: []byte(block.Source.Data), MediaType: block.Source.MediaType, ProviderOptions: anthropicCacheOptions(block)}, nil
+	default:
+		return FilePart{}, fmt.Errorf("unsupported %s source %q", block.Type, block.Source.Type)
+	}
+}
+
+func anthropicToolResult(block anthropicBlockJSON) (ToolResultPart, error) {
+	blocks, err := anthropicBlocks(block.Content)
+	if err != nil {
+		return ToolResultPart{}, err
+	}
+	var texts []string
+	var media *ToolResultOutputContentMedia
+	for _, b := range blocks {
+		switch b.Type {
+		case "text":
+			texts = append(texts, b.Text)
+		case "image":
+			media = &ToolResultOutputContentMedia{Data: b.Source.Data, MediaType: b.Source.MediaType}
+		}
+	}
+	text := strings.Join(texts, "\n")
+
+	result := ToolResultPart{ToolCallID: block.ToolUseID, ProviderOptions: anthropicCacheOptions(block)}
+	switch {
+	case block.IsError:
+		result.Output = ToolResultOutputContentError{Error: errors.New(text)}
+	case media != nil:
+		media.Text = text
+		result.Output = *media
+	default:
+		result.Output = ToolResultOutputContentText{Text: text}
+	}
+	return result, nil
+}
+
+// anthropicReasoningOptions restores the Anthropic reasoning metadata through
+// the provider registry. It returns nil when the provider isn't linked in.
+func anthropicReasoningOptions(data map[string]string) ProviderOptions {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil
+	}
+	return registeredProviderOptions(anthropicProviderName, anthropicReasoningMetadata, raw)
+}
+
+// anthropicCacheOptions restores the block's cache control marker through the
+// provider registry. It returns nil when there is no marker or the provider
+// isn't linked in.
+func anthropicCacheOptions(block anthropicBlockJSON) ProviderOptions {
+	if len(block.CacheControl) == 0 || bytes.Equal(block.CacheControl, []byte("null")) {
+		return nil
+	}
+	raw, err := json.Marshal(map[string]json.RawMessage{"cache_control": block.CacheControl})
+	if err != nil {
+		return nil
+	}
+	return registeredProviderOptions(anthropicProviderName, anthropicCacheControl, raw)
+}
+
+func registeredProviderOptions(provider, typeID string, data []byte) ProviderOptions {
+	val, ok := providerRegistry.Load(typeID)
+	if !ok {
+		return nil
+	}
+	options, err := val.(UnmarshalFunc)(data) //nolint:forcetypeassert // type enforced by RegisterProviderType
+	if err != nil {
+		return nil
+	}
+	return ProviderOptions{provider: options}
+}
+
+// unmarshalMessageList reads a JSON array of messages, or an object whose
+// "messages" field holds one.
+func unmarshalMessageList[T any](data []byte, messages *[]T) error {
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		return json.Unmarshal(trimmed, messages)
+	}
+	var doc struct {
+		Messages []T `json:"messages"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	*messages = doc.Messages
+	return nil
+}
+
+// appendMessage appends a message with the parts, unless there are none.
+func appendMessage(prompt Prompt, role MessageRole, parts []MessagePart) Prompt {
+	if len(parts) == 0 {
+		return prompt
+	}
+	return append(prompt, Message{Role: role, Content: parts})
+}
+
+// appendToolResult adds the result to the trailing tool message, starting a
+// new one if needed, so results of parallel tool calls share a message.
+func appendToolResult(prompt Prompt, result ToolResultPart) Prompt {
+	if n := len(prompt); n > 0 && prompt[n-1].Role == MessageRoleTool {
+		prompt[n-1].Content = append(prompt[n-1].Content, result)
+		return prompt
+	}
+	return append(prompt, Message{Role: MessageRoleTool, Content: []MessagePart{result}})
+}
@@ -0,0 +1,113 @@
+package fantasy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPromptFromOpenAIMessages(t *testing.T) {
+	t.Parallel()
+
+	data := `{"messages": [
+		{"role": "system", "content": "You are helpful."},
+		{"role": "user", "content": [
+			{"type": "text", "text": "What is in this image?"},
+			{"type": "image_url", "image_url": {"url": "data:image/png;base64,cG5n"}}
+		]},
+		{"role": "assistant", "content": null, "tool_calls": [
+			{"id": "call-1", "type": "function", "function": {"name": "lookup", "arguments": "{\"q\":\"a\"}"}},
+			{"id": "call-2", "type": "function", "function": {"name": "lookup", "arguments": "{\"q\":\"b\"}"}}
+		]},
+		{"role": "tool", "tool_call_id": "call-1", "content": "A"},
+		{"role": "tool", "tool_call_id": "call-2", "content": [{"type": "text", "text": "B"}]},
+		{"role": "assistant", "content": "A cat."}
+	]}`
+
+	prompt, err := PromptFromOpenAIMessages([]byte(data))
+	require.NoError(t, err)
+	require.Equal(t, Prompt{
+		NewSystemMessage("You are helpful."),
+		NewUserMessage("What is in this image?", FilePart{Data: []byte("png"), MediaType: "image/png"}),
+		{
+			Role: MessageRoleAssistant,
+			Content: []MessagePart{
+				ToolCallPart{ToolCallID: "call-1", ToolName: "lookup", Input: `{"q":"a"}`},
+				ToolCallPart{ToolCallID: "call-2", ToolName: "lookup", Input: `{"q":"b"}`},
+			},
+		},
+		{
+			Role: MessageRoleTool,
+			Content: []MessagePart{
+				ToolResultPart{ToolCallID: "call-1", Output: ToolResultOutputContentText{Text: "A"}},
+				ToolResultPart{ToolCallID: "call-2", Output: ToolResultOutputContentText{Text: "B"}},
+			},
+		},
+		{Role: MessageRoleAssistant, Content: []MessagePart{TextPart{Text: "A cat."}}},
+	}, prompt)
+
+	t.Run("unmatched tool result", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := PromptFromOpenAIMessages([]byte(`[{"role": "tool", "tool_call_id": "missing", "content": "x"}]`))
+		require.ErrorContains(t, err, "no matching tool call")
+	})
+
+	t.Run("remote image", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := PromptFromOpenAIMessages([]byte(`[{"role": "user", "content": [{"type": "image_url", "image_url": {"url": "https://example.com/cat.png"}}]}]`))
+		require.ErrorContains(t, err, "remote URLs")
+	})
+}
+
+func TestPromptFromAnthropicMessages(t *testing.T) {
+	t.Parallel()
+
+	data := `{
+		"system": "You are helpful.",
+		"messages": [
+			{"role": "user", "content": "Look it up"},
+			{"role": "assistant", "content": [
+				{"type": "thinking", "thinking": "Need a lookup.", "signature": "sig"},
+				{"type": "tool_use", "id": "toolu_1", "name": "lookup", "input": {"q": "a"}}
+			]},
+			{"role": "user", "content": [
+				{"type": "tool_result", "tool_use_id": "toolu_1", "content": "not found", "is_error": true},
+				{"type": "text", "text": "Try again"}
+			]}
+		]
+	}`
+
+	prompt, err := PromptFromAnthropicMessages([]byte(data))
+	require.NoError(t, err)
+	require.Len(t, prompt, 5)
+	require.Equal(t, NewSystemMessage("You are helpful."), prompt[0])
+	require.Equal(t, NewUserMessage("Look it up"), prompt[1])
+
+	// Without the Anthropic provider registered the signature is dropped.
+	require.Equal(t, Message{
+		Role: MessageRoleAssistant,
+		Content: []MessagePart{
+			ReasoningPart{Text: "Need a lookup."},
+			ToolCallPart{ToolCallID: "toolu_1", ToolName: "lookup", Input: `{"q": "a"}`},
+		},
+	}, prompt[2])
+
+	require.Equal(t, MessageRoleTool, prompt[3].Role)
+	result, ok := AsMessagePart[ToolResultPart](prompt[3].Content[0])
+	require.True(t, ok)
+	require.Equal(t, "toolu_1", result.ToolCallID)
+	errOutput, ok := AsToolResultOutputType[ToolResultOutputContentError](result.Output)
+	require.True(t, ok)
+	require.EqualError(t, errOutput.Error, "not found")
+
+	require.Equal(t, NewUserMessage("Try again"), prompt[4])
+
+	t.Run("unmatched tool result", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := PromptFromAnthropicMessages([]byte(`[{"role": "user", "content": [{"type": "tool_result", "tool_use_id": "missing"}]}]`))
+		require.ErrorContains(t, err, "no matching tool use")
+	})
+}
@@ -9,3 +9,15 @@ type Provider interface {
 	Name() string
 	LanguageModel(ctx context.Context, modelID string) (LanguageModel, error)
 }
+
+// EmbeddingProvider is implemented by providers that offer embedding
+// models.
+type EmbeddingProvider interface {
+	EmbeddingModel(ctx context.Context, modelID string) (EmbeddingModel, error)
+}
+
+// ModerationProvider is implemented by providers that offer moderation
+// models.
+type ModerationProvider interface {
+	ModerationModel(ctx context.Context, modelID string) (ModerationModel, error)
+}
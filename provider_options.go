@@ -0,0 +1,83 @@
+package fantasy
+
+import "reflect"
+
+// MergeProviderOptions layers provider options, later layers taking
+// precedence over earlier ones. Nil layers are skipped and the layers are
+// left untouched.
+//
+// Options for the same provider key are merged field by field when both
+// are pointers to the same struct type, as all the providers' options are:
+// fields set in the later layer, meaning non-zero, override the earlier
+// ones, nested struct pointers are merged the same way, and maps are
+// merged key by key. Otherwise, the later options replace the earlier ones
+// whole.
+//
+// For instance, an agent-level reasoning effort survives a per-call
+// options value that only sets a different field:
+//
+//	fantasy.MergeProviderOptions(
+//	    fantasy.ProviderOptions{"openai": &openai.ProviderOptions{ReasoningEffort: &high}},
+//	    fantasy.ProviderOptions{"openai": &openai.ProviderOptions{User: &user}},
+//	)
+func MergeProviderOptions(layers ...ProviderOptions) ProviderOptions {
+	merged := ProviderOptions{}
+	for _, layer := range layers {
+		for key, data := range layer {
+			base, ok := merged[key]
+			if !ok || base == nil || data == nil {
+				merged[key] = data
+				continue
+			}
+			if m, ok := mergeValues(reflect.ValueOf(base), reflect.ValueOf(data)).Interface().(ProviderOptionsData); ok {
+				merged[key] = m
+			} else {
+				merged[key] = data
+			}
+		}
+	}
+	return merged
+}
+
+// mergeValues returns override merged onto base, copying rather than
+// modifying either of them.
+func mergeValues(base, override reflect.Value) reflect.Value {
+	if base.Type() != override.Type() {
+		return override
+	}
+	switch base.Kind() {
+	case reflect.Pointer:
+		if base.IsNil() || override.IsNil() || base.Elem().Kind() != reflect.Struct {
+			break
+		}
+		merged := reflect.New(base.Type().Elem())
+		merged.Elem().Set(base.Elem())
+		for i := range merged.Elem().NumField() {
+			field := merged.Elem().Field(i)
+			value := override.Elem().Field(i)
+			if !field.CanSet() || value.IsZero() {
+				continue
+			}
+			field.Set(mergeValues(field, value))
+		}
+		return merged
+	case reflect.Map:
+		if base.IsNil() || override.IsNil() {
+			break
+		}
+		merged := reflect.MakeMapWithSize(base.Type(), base.Len()+override.Len())
+		iter := base.MapRange()
+		for iter.Next() {
+			merged.SetMapIndex(iter.Key(), iter.Value())
+		}
+		iter = override.MapRange()
+		for iter.Next() {
+			merged.SetMapIndex(iter.Key(), iter.Value())
+		}
+		return merged
+	}
+	if override.IsZero() {
+		return base
+	}
+	return override
+}
@@ -0,0 +1,137 @@
+package fantasy
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testThinking struct {
+	Budget  *int64 `json:"budget"`
+	Summary *bool  `json:"summary"`
+}
+
+type testProviderOptions struct {
+	Effort    *string        `json:"effort"`
+	User      *string        `json:"user"`
+	Thinking  *testThinking  `json:"thinking"`
+	ExtraBody map[string]any `json:"extra_body"`
+}
+
+func (o *testProviderOptions) Options() {}
+
+func (o testProviderOptions) MarshalJSON() ([]byte, error) {
+	type plain testProviderOptions
+	return json.Marshal(plain(o))
+}
+
+func (o *testProviderOptions) UnmarshalJSON(data []byte) error {
+	type plain testProviderOptions
+	return json.Unmarshal(data, (*plain)(o))
+}
+
+// otherProviderOptions is another options type, which doesn't merge with
+// testProviderOptions.
+type otherProviderOptions struct {
+	testProviderOptions
+}
+
+func TestProviderMetadataOf(t *testing.T) {
+	t.Parallel()
+
+	own := &testProviderOptions{User: Opt("own")}
+	other := &otherProviderOptions{}
+	metadata := ProviderMetadata{"test": own, "other": other}
+
+	got, ok := ProviderMetadataOf[*testProviderOptions](metadata, "test")
+	require.True(t, ok)
+	require.Same(t, own, got)
+
+	// Metadata stored under another provider's name is found by its type.
+	got, ok = ProviderMetadataOf[*testProviderOptions](ProviderMetadata{"openai": own}, "test")
+	require.True(t, ok)
+	require.Same(t, own, got)
+
+	_, ok = ProviderMetadataOf[*testProviderOptions](ProviderMetadata{"test": other}, "test")
+	require.False(t, ok)
+	_, ok = ProviderMetadataOf[*testProviderOptions](nil, "test")
+	require.False(t, ok)
+}
+
+func TestMergeProviderOptions(t *testing.T) {
+	t.Parallel()
+
+	t.Run("field by field", func(t *testing.T) {
+		t.Parallel()
+		base := &testProviderOptions{
+			Effort:    new("high"),
+			Thinking:  &testThinking{Budget: new(int64(1024))},
+			ExtraBody: map[string]any{"a": 1, "b": 1},
+		}
+		override := &testProviderOptions{
+			User:      new("ada"),
+			Thinking:  &testThinking{Summary: new(true)},
+			ExtraBody: map[string]any{"b": 2},
+		}
+		merged := MergeProviderOptions(
+			ProviderOptions{"test": base},
+			nil,
+			ProviderOptions{"test": override},
+		)
+		require.Equal(t, &testProviderOptions{
+			Effort:    new("high"),
+			User:      new("ada"),
+			Thinking:  &testThinking{Budget: new(int64(1024)), Summary: new(true)},
+			ExtraBody: map[string]any{"a": 1, "b": 2},
+		}, merged["test"])
+
+		// The layers are left untouched.
+		require.Nil(t, base.User)
+		require.Nil(t, base.Thinking.Summary)
+		require.Equal(t, map[string]any{"a": 1, "b": 1}, base.ExtraBody)
+	})
+
+	t.Run("later layers win", func(t *testing.T) {
+		t.Parallel()
+		merged := MergeProviderOptions(
+			ProviderOptions{"test": &testProviderOptions{Effort: new("high")}},
+			ProviderOptions{"test": &testProviderOptions{Effort: new("low")}},
+		)
+		require.Equal(t, "low", *merged["test"].(*testProviderOptions).Effort)
+	})
+
+	t.Run("different types replace", func(t *testing.T) {
+		t.Parallel()
+		other := &otherProviderOptions{}
+		merged := MergeProviderOptions(
+			ProviderOptions{"test": &testProviderOptions{Effort: new("high")}, "keep": other},
+			ProviderOptions{"test": other},
+		)
+		require.Same(t, other, merged["test"])
+		require.Same(t, other, merged["keep"])
+	})
+}
+
+func TestAgentProviderOptionsLayering(t *testing.T) {
+	t.Parallel()
+
+	var got ProviderOptions
+	model := &mockLanguageModel{generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+		got = call.ProviderOptions
+		return &Response{
+			Content:      ResponseContent{TextContent{Text: "ok"}},
+			FinishReason: FinishReasonStop,
+		}, nil
+	}}
+	agent := NewAgent(model, WithProviderOptions(ProviderOptions{
+		"test": &testProviderOptions{Effort: new("high")},
+	}))
+	_, err := agent.Generate(t.Context(), AgentCall{
+		Prompt:          "hi",
+		ProviderOptions: ProviderOptions{"test": &testProviderOptions{User: new("ada")}},
+	})
+	require.NoError(t, err)
+	require.Equal(t, &testProviderOptions{Effort: new("high"), User: new("ada")}, got["test"])
+}
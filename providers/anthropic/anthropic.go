@@ -11,12 +11,19 @@ import (
 	"io"
 	"maps"
 	"math"
+	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"charm.land/fantasy"
 	"charm.land/fantasy/object"
+	"charm.land/fantasy/providers/internal/httpclient"
 	"charm.land/fantasy/providers/internal/httpheaders"
+	"charm.land/fantasy/schema"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/charmbracelet/anthropic-sdk-go"
 	"github.com/charmbracelet/anthropic-sdk-go/bedrock"
@@ -39,6 +46,66 @@ func betaRequestOptions(flags []string) []option.RequestOption {
 	return opts
 }
 
+// mergeBetaFlags adds the requested beta features to the flags the tools
+// need. Flags already set through the anthropic-beta call header are not
+// repeated, and of two versions of the same feature only the first one is
+// kept, with a warning.
+func mergeBetaFlags(toolFlags []string, betas []Beta, headers map[string]string) ([]string, []fantasy.CallWarning) {
+	var warnings []fantasy.CallWarning
+	versions := map[string]string{}
+	add := func(flag string) bool {
+		flag = strings.TrimSpace(flag)
+		if flag == "" {
+			return false
+		}
+		feature := betaFeature(flag)
+		if existing, ok := versions[feature]; ok {
+			if existing != flag {
+				warnings = append(warnings, fantasy.CallWarning{
+					Type:    fantasy.CallWarningTypeOther,
+					Message: fmt.Sprintf("beta %q conflicts with %q and was not sent", flag, existing),
+				})
+			}
+			return false
+		}
+		versions[feature] = flag
+		return true
+	}
+
+	for key, value := range headers {
+		if strings.EqualFold(key, "anthropic-beta") {
+			for flag := range strings.SplitSeq(value, ",") {
+				add(flag)
+			}
+		}
+	}
+	var flags []string
+	for _, flag := range toolFlags {
+		if add(flag) {
+			flags = append(flags, flag)
+		}
+	}
+	for _, beta := range betas {
+		if add(string(beta)) {
+			flags = append(flags, strings.TrimSpace(string(beta)))
+		}
+	}
+	return flags, warnings
+}
+
+// betaFeature returns the name of a beta flag without its date, so that
+// different versions of a feature can be told apart.
+func betaFeature(flag string) string {
+	const date = "2006-01-02"
+	if len(flag) <= len(date)+1 || flag[len(flag)-len(date)-1] != '-' {
+		return flag
+	}
+	if _, err := time.Parse(date, flag[len(flag)-len(date):]); err != nil {
+		return flag
+	}
+	return flag[:len(flag)-len(date)-1]
+}
+
 func thinkingDisplay(providerOptions *ProviderOptions, modelID string) (ThinkingDisplay, bool) {
 	if providerOptions != nil && providerOptions.ThinkingDisplay != nil && *providerOptions.ThinkingDisplay != "" {
 		return *providerOptions.ThinkingDisplay, true
@@ -85,8 +152,8 @@ func defaultsToOmittedOpusThinkingDisplay(model string) bool {
 }
 
 // buildRequestOptions constructs the common request options shared
-// by Generate and Stream: user-agent, raw tool injection, and any
-// beta API flags.
+// by Generate and Stream: user-agent, per-call headers, query and body,
+// raw tool injection, and any beta API flags.
 func buildRequestOptions(call fantasy.Call, rawTools []json.RawMessage, betaFlags []string) []option.RequestOption {
 	providerOptions := &ProviderOptions{}
 	if v, ok := call.ProviderOptions[Name]; ok {
@@ -95,6 +162,7 @@ func buildRequestOptions(call fantasy.Call, rawTools []json.RawMessage, betaFlag
 
 	reqOpts := callUARequestOptions(call)
 	reqOpts = append(reqOpts, callHeadersRequestOptions(call)...)
+	reqOpts = append(reqOpts, callQueryRequestOptions(call)...)
 	if len(rawTools) > 0 {
 		// Tools are injected as raw JSON rather than via params.Tools
 		// because the SDK doesn't model beta tool types (e.g. computer
@@ -105,6 +173,9 @@ func buildRequestOptions(call fantasy.Call, rawTools []json.RawMessage, betaFlag
 	for k, v := range providerOptions.ExtraBody {
 		reqOpts = append(reqOpts, option.WithJSONSet(k, v))
 	}
+	for k, v := range call.ExtraBody {
+		reqOpts = append(reqOpts, option.WithJSONSet(k, v))
+	}
 	if len(betaFlags) > 0 {
 		reqOpts = append(reqOpts, betaRequestOptions(betaFlags)...)
 	}
@@ -120,22 +191,35 @@ const (
 	VertexAuthScope = "https://www.googleapis.com/auth/cloud-platform"
 )
 
-type options struct {
-	baseURL   string
-	apiKey    string
-	name      string
-	headers   map[string]string
-	userAgent string
-	client    option.HTTPClient
-
-	vertexProject  string
-	vertexLocation string
-	skipAuth       bool
-
-	useBedrock    bool
-	bedrockRegion string
+// DefaultImageLimits are the limits images are prepared for unless
+// WithImageLimits is set. The 5MB limit of the API applies to the base64
+// encoded image, so the decoded one is kept below 3.75MB.
+var DefaultImageLimits = fantasy.ImageLimits{
+	MaxBytes:   5 * 1024 * 1024 * 3 / 4,
+	MaxEdge:    8000,
+	MediaTypes: []string{"image/jpeg", "image/png", "image/gif", "image/webp"},
+}
 
-	objectMode fantasy.ObjectMode
+type options struct {
+	baseURL    string
+	apiKey     string
+	name       string
+	headers    map[string]string
+	userAgent  string
+	client     option.HTTPClient
+	httpConfig httpclient.Config
+
+	vertexProject        string
+	vertexLocation       string
+	vertexServiceAccount []byte
+	skipAuth             bool
+
+	useBedrock         bool
+	bedrockRegion      string
+	bedrockCredentials aws.CredentialsProvider
+
+	objectMode  fantasy.ObjectMode
+	imageLimits fantasy.ImageLimits
 }
 
 type provider struct {
@@ -148,8 +232,9 @@ type Option = func(*options)
 // New creates a new Anthropic provider with the given options.
 func New(opts ...Option) (fantasy.Provider, error) {
 	providerOptions := options{
-		headers:    map[string]string{},
-		objectMode: fantasy.ObjectModeAuto,
+		headers:     map[string]string{},
+		objectMode:  fantasy.ObjectModeAuto,
+		imageLimits: DefaultImageLimits,
 	}
 	for _, o := range opts {
 		o(&providerOptions)
@@ -184,6 +269,14 @@ func WithVertex(project, location string) Option {
 	}
 }
 
+// WithVertexServiceAccount authenticates Vertex AI requests with the given
+// service account key JSON instead of Application Default Credentials.
+func WithVertexServiceAccount(credentialsJSON []byte) Option {
+	return func(o *options) {
+		o.vertexServiceAccount = credentialsJSON
+	}
+}
+
 // WithSkipAuth configures whether to skip authentication for the Anthropic provider.
 func WithSkipAuth(skip bool) Option {
 	return func(o *options) {
@@ -205,6 +298,14 @@ func WithBedrockRegion(region string) Option {
 	}
 }
 
+// WithBedrockCredentials sets the AWS credentials used to sign Bedrock
+// requests, instead of the default credential chain.
+func WithBedrockCredentials(credentials aws.CredentialsProvider) Option {
+	return func(o *options) {
+		o.bedrockCredentials = credentials
+	}
+}
+
 // WithName sets the name for the Anthropic provider.
 func WithName(name string) Option {
 	return func(o *options) {
@@ -226,6 +327,31 @@ func WithHTTPClient(client option.HTTPClient) Option {
 	}
 }
 
+// WithHTTPTimeout sets the overall timeout of each request, including
+// reading streamed responses. Use fantasy.WithStreamIdleTimeout to detect
+// stalled streams instead. It's ignored when WithHTTPClient is set.
+func WithHTTPTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.httpConfig.Timeout = timeout
+	}
+}
+
+// WithProxy sends the requests through the given proxy instead of the one
+// from the environment. It's ignored when WithHTTPClient is set.
+func WithProxy(proxy *url.URL) Option {
+	return func(o *options) {
+		o.httpConfig.Proxy = proxy
+	}
+}
+
+// WithTransport sets the HTTP transport of the Anthropic provider. It's
+// ignored when WithHTTPClient is set.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *options) {
+		o.httpConfig.Transport = transport
+	}
+}
+
 // WithUserAgent sets an explicit User-Agent header, overriding the default and any
 // value set via WithHeaders.
 func WithUserAgent(ua string) Option {
@@ -245,7 +371,16 @@ func WithObjectMode(om fantasy.ObjectMode) Option {
 	}
 }
 
-func (a *provider) LanguageModel(ctx context.Context, modelID string) (fantasy.LanguageModel, error) {
+// WithImageLimits sets the limits images are downscaled and recompressed to
+// fit before they're sent, adding a warning to the response when they are.
+// The zero value sends images as is. Defaults to DefaultImageLimits.
+func WithImageLimits(limits fantasy.ImageLimits) Option {
+	return func(o *options) {
+		o.imageLimits = limits
+	}
+}
+
+func (a *provider) newClient(ctx context.Context) (anthropic.Client, error) {
 	clientOptions := make([]option.RequestOption, 0, 5+len(a.options.headers))
 	clientOptions = append(clientOptions, option.WithMaxRetries(0))
 
@@ -262,16 +397,25 @@ func (a *provider) LanguageModel(ctx context.Context, modelID string) (fantasy.L
 	}
 	if a.options.client != nil {
 		clientOptions = append(clientOptions, option.WithHTTPClient(a.options.client))
+	} else {
+		clientOptions = append(clientOptions, option.WithHTTPClient(a.options.httpConfig.Client()))
 	}
 	if a.options.vertexProject != "" && a.options.vertexLocation != "" {
 		var credentials *google.Credentials
-		if a.options.skipAuth {
+		switch {
+		case a.options.skipAuth:
 			credentials = &google.Credentials{TokenSource: &googleDummyTokenSource{}}
-		} else {
+		case a.options.vertexServiceAccount != nil:
+			var err error
+			credentials, err = google.CredentialsFromJSONWithType(ctx, a.options.vertexServiceAccount, google.ServiceAccount, VertexAuthScope)
+			if err != nil {
+				return anthropic.Client{}, err
+			}
+		default:
 			var err error
 			credentials, err = google.FindDefaultCredentials(ctx, VertexAuthScope)
 			if err != nil {
-				return nil, err
+				return anthropic.Client{}, err
 			}
 		}
 
@@ -286,12 +430,21 @@ func (a *provider) LanguageModel(ctx context.Context, modelID string) (fantasy.L
 		)
 	}
 	if a.options.useBedrock {
-		if a.options.skipAuth || a.options.apiKey != "" {
+		switch {
+		case a.options.skipAuth || a.options.apiKey != "":
 			clientOptions = append(
 				clientOptions,
 				bedrock.WithConfig(bedrockBasicAuthConfig(a.options.apiKey, a.options.bedrockRegion)),
 			)
-		} else {
+		case a.options.bedrockCredentials != nil:
+			clientOptions = append(
+				clientOptions,
+				bedrock.WithConfig(aws.Config{
+					Region:      cmp.Or(a.options.bedrockRegion, "us-east-1"),
+					Credentials: a.options.bedrockCredentials,
+				}),
+			)
+		default:
 			if cfg, err := config.LoadDefaultConfig(ctx); err == nil {
 				cfg.Region = cmp.Or(a.options.bedrockRegion, cfg.Region)
 				clientOptions = append(
@@ -304,14 +457,41 @@ func (a *provider) LanguageModel(ctx context.Context, modelID string) (fantasy.L
 			clientOptions = append(clientOptions, option.WithBaseURL(a.options.baseURL))
 		}
 	}
+	return anthropic.NewClient(clientOptions...), nil
+}
+
+// LanguageModel implements fantasy.Provider.
+func (a *provider) LanguageModel(ctx context.Context, modelID string) (fantasy.LanguageModel, error) {
+	client, err := a.newClient(ctx)
+	if err != nil {
+		return nil, err
+	}
 	return languageModel{
 		modelID:  modelID,
 		provider: a.options.name,
 		options:  a.options,
-		client:   anthropic.NewClient(clientOptions...),
+		client:   client,
 	}, nil
 }
 
+// Validate implements fantasy.Validator by listing the available models.
+// Bedrock and Vertex AI don't serve the models endpoint, so they report
+// fantasy.ErrValidationUnsupported.
+func (a *provider) Validate(ctx context.Context) error {
+	if a.options.useBedrock || a.options.vertexProject != "" {
+		return fantasy.ErrValidationUnsupported
+	}
+	client, err := a.newClient(ctx)
+	if err != nil {
+		return fantasy.NewValidationError(a.options.name, err)
+	}
+	_, err = client.Models.List(ctx, anthropic.ModelListParams{Limit: anthropic.Int(1)})
+	if err != nil {
+		return fantasy.NewValidationError(a.options.name, toProviderErr(err))
+	}
+	return nil
+}
+
 type languageModel struct {
 	provider string
 	modelID  string
@@ -329,6 +509,26 @@ func (a languageModel) Provider() string {
 	return a.provider
 }
 
+// SupportsPrefill implements fantasy.PrefillModel. Claude continues a
+// trailing assistant message.
+func (a languageModel) SupportsPrefill() bool {
+	return true
+}
+
+// SupportsPDF implements fantasy.PDFModel.
+func (a languageModel) SupportsPDF() bool {
+	return true
+}
+
+// SupportsFileURL implements fantasy.FileURLModel. Anthropic fetches image
+// and PDF URLs, except on Bedrock.
+func (a languageModel) SupportsFileURL(mediaType string) bool {
+	if a.options.useBedrock {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "image/") || mediaType == "application/pdf"
+}
+
 func (a languageModel) prepareParams(call fantasy.Call) (
 	params *anthropic.MessageNewParams,
 	rawTools []json.RawMessage,
@@ -348,7 +548,17 @@ func (a languageModel) prepareParams(call fantasy.Call) (
 	if providerOptions.SendReasoning != nil {
 		sendReasoning = *providerOptions.SendReasoning
 	}
-	systemBlocks, messages, warnings := toPrompt(call.Prompt, sendReasoning)
+	prompt, imageWarnings := fantasy.PrepareImages(call.Prompt, func(fantasy.FilePart) fantasy.ImageLimits {
+		return a.options.imageLimits
+	})
+	if providerOptions.CacheToolResults != nil && *providerOptions.CacheToolResults {
+		prompt = cacheLastToolResult(prompt, call.Tools)
+	}
+	systemBlocks, messages, warnings := toPrompt(prompt, sendReasoning)
+	warnings = append(imageWarnings, warnings...)
+	if err := fantasy.CheckDroppedContent(call, warnings); err != nil {
+		return nil, nil, nil, nil, err
+	}
 
 	if call.FrequencyPenalty != nil {
 		warnings = append(warnings, fantasy.CallWarning{
@@ -381,6 +591,10 @@ func (a languageModel) prepareParams(call fantasy.Call) (
 	if call.TopP != nil {
 		params.TopP = param.NewOpt(*call.TopP)
 	}
+	// Anthropic only accepts the end user in the request metadata.
+	if userID := call.Metadata[fantasy.MetadataUserID]; userID != "" {
+		params.Metadata.UserID = param.NewOpt(userID)
+	}
 
 	switch {
 	case providerOptions.Effort != nil:
@@ -441,6 +655,30 @@ func (a languageModel) prepareParams(call fantasy.Call) (
 		params.Thinking.OfAdaptive = &adaptive
 	}
 
+	switch call.ServiceTier {
+	case "":
+	case fantasy.ServiceTierPriority:
+		params.ServiceTier = anthropic.MessageNewParamsServiceTierAuto
+	case fantasy.ServiceTierDefault:
+		params.ServiceTier = anthropic.MessageNewParamsServiceTierStandardOnly
+	default:
+		warnings = append(warnings, fantasy.CallWarning{
+			Type:    fantasy.CallWarningTypeUnsupportedSetting,
+			Setting: "ServiceTier",
+			Details: fmt.Sprintf("the %s service tier is not supported by anthropic", call.ServiceTier),
+		})
+	}
+
+	if call.OutputConstraint != nil {
+		if call.OutputConstraint.Type == fantasy.OutputConstraintTypeJSONSchema {
+			jsonSchema := schema.ToMap(call.OutputConstraint.Schema)
+			closeObjectSchemas(jsonSchema)
+			params.OutputConfig.Format = anthropic.JSONOutputFormatParam{Schema: jsonSchema}
+		} else {
+			warnings = append(warnings, fantasy.UnsupportedOutputConstraintWarning(call.OutputConstraint))
+		}
+	}
+
 	if len(call.Tools) > 0 {
 		disableParallelToolUse := false
 		if providerOptions.DisableParallelToolUse != nil {
@@ -453,11 +691,108 @@ func (a languageModel) prepareParams(call fantasy.Call) (
 			params.ToolChoice = *toolChoice
 		}
 		warnings = append(warnings, toolWarnings...)
+	} else if call.ToolChoice != nil && *call.ToolChoice == fantasy.ToolChoiceNone {
+		// Anthropic rejects tool use in the prompt without tools, so when an
+		// agent drops its tools mid-conversation the tools called so far are
+		// declared for the none tool choice to be sent.
+		rawTools = calledTools(prompt)
+		if len(rawTools) > 0 {
+			none := anthropic.NewToolChoiceNoneParam()
+			params.ToolChoice = anthropic.ToolChoiceUnionParam{OfNone: &none}
+		}
 	}
 
+	betaFlags, betaWarnings := mergeBetaFlags(betaFlags, providerOptions.Betas, call.Headers)
+	warnings = append(warnings, betaWarnings...)
+
 	return params, rawTools, warnings, betaFlags, nil
 }
 
+// calledTools returns a tool without parameters for every function tool
+// called in the prompt.
+func calledTools(prompt fantasy.Prompt) []json.RawMessage {
+	var rawTools []json.RawMessage
+	seen := map[string]bool{}
+	for _, msg := range prompt {
+		for _, part := range msg.Content {
+			call, ok := fantasy.AsMessagePart[fantasy.ToolCallPart](part)
+			if !ok || call.ProviderExecuted || seen[call.ToolName] {
+				continue
+			}
+			seen[call.ToolName] = true
+			raw, err := json.Marshal(anthropic.ToolUnionParam{OfTool: &anthropic.ToolParam{
+				Name:        call.ToolName,
+				InputSchema: anthropic.ToolInputSchemaParam{Properties: map[string]any{}},
+			}})
+			if err != nil {
+				continue
+			}
+			rawTools = append(rawTools, raw)
+		}
+	}
+	return rawTools
+}
+
+// cacheLastToolResult returns the prompt with a cache breakpoint on its last
+// tool result, unless the prompt and tools already have as many breakpoints
+// as Anthropic allows. The prompt is left untouched.
+func cacheLastToolResult(prompt fantasy.Prompt, tools []fantasy.Tool) fantasy.Prompt {
+	last := -1
+	for i, msg := range prompt {
+		if msg.Role == fantasy.MessageRoleTool && len(msg.Content) > 0 {
+			last = i
+		}
+	}
+	if last < 0 {
+		return prompt
+	}
+	msg := prompt[last]
+	if GetCacheControl(msg.Content[len(msg.Content)-1].Options()) != nil || GetCacheControl(msg.ProviderOptions) != nil {
+		return prompt
+	}
+
+	breakpoints := len(fantasy.AnalyzeCacheability(prompt, Name).Breakpoints)
+	for _, tool := range tools {
+		if ft, ok := tool.(fantasy.FunctionTool); ok && GetCacheControl(ft.ProviderOptions) != nil {
+			breakpoints++
+		}
+	}
+	if breakpoints >= maxCacheBreakpoints {
+		return prompt
+	}
+
+	cached := slices.Clone(prompt)
+	options := maps.Clone(msg.ProviderOptions)
+	if options == nil {
+		options = fantasy.ProviderOptions{}
+	}
+	maps.Copy(options, NewProviderCacheControlOptions(&ProviderCacheControlOptions{
+		CacheControl: CacheControl{Type: "ephemeral"},
+	}))
+	cached[last].ProviderOptions = options
+	return cached
+}
+
+// closeObjectSchemas sets additionalProperties to false on every object in
+// the schema, as required by structured outputs.
+func closeObjectSchemas(node map[string]any) {
+	if node["type"] == "object" {
+		if _, ok := node["additionalProperties"]; !ok {
+			node["additionalProperties"] = false
+		}
+		if properties, ok := node["properties"].(map[string]any); ok {
+			for _, property := range properties {
+				if child, ok := property.(map[string]any); ok {
+					closeObjectSchemas(child)
+				}
+			}
+		}
+	}
+	if items, ok := node["items"].(map[string]any); ok {
+		closeObjectSchemas(items)
+	}
+}
+
 func (a *provider) Name() string {
 	return Name
 }
@@ -932,6 +1267,9 @@ func toPrompt(prompt fantasy.Prompt, sendReasoningData bool) ([]anthropic.TextBl
 							case strings.HasPrefix(file.MediaType, "image/"):
 								base64Encoded := base64.StdEncoding.EncodeToString(file.Data)
 								imageBlock := anthropic.NewImageBlockBase64(file.MediaType, base64Encoded)
+								if file.URL != "" {
+									imageBlock = anthropic.NewImageBlock(anthropic.URLImageSourceParam{URL: file.URL})
+								}
 								if cacheControl != nil {
 									imageBlock.OfImage.CacheControl = anthropic.NewCacheControlEphemeralParam()
 								}
@@ -941,6 +1279,9 @@ func toPrompt(prompt fantasy.Prompt, sendReasoningData bool) ([]anthropic.TextBl
 								docBlock := anthropic.NewDocumentBlock(anthropic.Base64PDFSourceParam{
 									Data: base64Encoded,
 								})
+								if file.URL != "" {
+									docBlock = anthropic.NewDocumentBlock(anthropic.URLPDFSourceParam{URL: file.URL})
+								}
 								docBlock.OfDocument.Title = anthropic.String(sanitizeAnthropicDocumentTitle(file.Filename))
 								if cacheControl != nil {
 									docBlock.OfDocument.CacheControl = anthropic.NewCacheControlEphemeralParam()
@@ -957,7 +1298,7 @@ func toPrompt(prompt fantasy.Prompt, sendReasoningData bool) ([]anthropic.TextBl
 								anthropicContent = append(anthropicContent, documentBlock)
 							default:
 								warnings = append(warnings, fantasy.CallWarning{
-									Type:    fantasy.CallWarningTypeOther,
+									Type:    fantasy.CallWarningTypeDroppedContent,
 									Message: fmt.Sprintf("file part media type %s not supported", file.MediaType),
 								})
 							}
@@ -1033,7 +1374,7 @@ func toPrompt(prompt fantasy.Prompt, sendReasoningData bool) ([]anthropic.TextBl
 			}
 			if !hasVisibleUserContent(anthropicContent) {
 				warnings = append(warnings, fantasy.CallWarning{
-					Type:    fantasy.CallWarningTypeOther,
+					Type:    fantasy.CallWarningTypeDroppedContent,
 					Message: "dropping empty user message (contains neither user-facing content nor tool results)",
 				})
 				continue
@@ -1078,7 +1419,7 @@ func toPrompt(prompt fantasy.Prompt, sendReasoningData bool) ([]anthropic.TextBl
 						reasoningMetadata := GetReasoningMetadata(part.Options())
 						if reasoningMetadata == nil {
 							warnings = append(warnings, fantasy.CallWarning{
-								Type:    fantasy.CallWarningTypeOther,
+								Type:    fantasy.CallWarningTypeDroppedContent,
 								Message: "unsupported reasoning metadata",
 							})
 							continue
@@ -1090,7 +1431,7 @@ func toPrompt(prompt fantasy.Prompt, sendReasoningData bool) ([]anthropic.TextBl
 							anthropicContent = append(anthropicContent, anthropic.NewRedactedThinkingBlock(reasoningMetadata.RedactedData))
 						} else {
 							warnings = append(warnings, fantasy.CallWarning{
-								Type:    fantasy.CallWarningTypeOther,
+								Type:    fantasy.CallWarningTypeDroppedContent,
 								Message: "unsupported reasoning metadata",
 							})
 							continue
@@ -1100,6 +1441,10 @@ func toPrompt(prompt fantasy.Prompt, sendReasoningData bool) ([]anthropic.TextBl
 						if !ok {
 							continue
 						}
+						if meta, ok := mcpToolMetadata(toolCall.ProviderOptions); ok && toolCall.ProviderExecuted {
+							anthropicContent = append(anthropicContent, buildMCPToolUseBlock(toolCall, meta))
+							continue
+						}
 						if toolCall.ProviderExecuted {
 							// Reconstruct server_tool_use block for
 							// multi-turn round-tripping.
@@ -1130,6 +1475,10 @@ func toPrompt(prompt fantasy.Prompt, sendReasoningData bool) ([]anthropic.TextBl
 						if !ok {
 							continue
 						}
+						if meta, ok := mcpToolMetadata(result.ProviderOptions); ok && result.ProviderExecuted {
+							anthropicContent = append(anthropicContent, buildMCPToolResultBlock(result.ToolCallID, meta))
+							continue
+						}
 						if result.ProviderExecuted {
 							// Reconstruct web_search_tool_result blocks,
 							// including encrypted content and errors, for
@@ -1151,7 +1500,7 @@ func toPrompt(prompt fantasy.Prompt, sendReasoningData bool) ([]anthropic.TextBl
 			}
 			if !hasVisibleAssistantContent(anthropicContent) {
 				warnings = append(warnings, fantasy.CallWarning{
-					Type:    fantasy.CallWarningTypeOther,
+					Type:    fantasy.CallWarningTypeDroppedContent,
 					Message: "dropping empty assistant message (contains neither user-facing content nor tool calls)",
 				})
 				continue
@@ -1272,12 +1621,49 @@ func mapFinishReason(finishReason string) fantasy.FinishReason {
 		return fantasy.FinishReasonLength
 	case "tool_use":
 		return fantasy.FinishReasonToolCalls
+	case "refusal":
+		return fantasy.FinishReasonContentFilter
 	default:
 		return fantasy.FinishReasonUnknown
 	}
 }
 
+// mapSafety reports a refusal stop reason as safety information. Anthropic
+// does not return per-category ratings.
+func mapSafety(stopReason string) *fantasy.SafetyInfo {
+	if stopReason != "refusal" {
+		return nil
+	}
+	return &fantasy.SafetyInfo{
+		Blocked: true,
+		Reason:  stopReason,
+	}
+}
+
 // Generate implements fantasy.LanguageModel.
+// CountTokens implements fantasy.TokenCounter using the count_tokens
+// endpoint. The call is converted exactly as Generate would send it.
+func (a languageModel) CountTokens(ctx context.Context, call fantasy.Call) (int64, error) {
+	params, rawTools, _, betaFlags, err := a.prepareParams(call)
+	if err != nil {
+		return 0, err
+	}
+	reqOpts := buildRequestOptions(call, rawTools, betaFlags)
+
+	count, err := a.client.Messages.CountTokens(ctx, anthropic.MessageCountTokensParams{
+		Messages:     params.Messages,
+		Model:        params.Model,
+		OutputConfig: params.OutputConfig,
+		System:       anthropic.MessageCountTokensParamsSystemUnion{OfTextBlockArray: params.System},
+		Thinking:     params.Thinking,
+		ToolChoice:   params.ToolChoice,
+	}, reqOpts...)
+	if err != nil {
+		return 0, toProviderErr(err)
+	}
+	return count.InputTokens, nil
+}
+
 func (a languageModel) Generate(ctx context.Context, call fantasy.Call) (*fantasy.Response, error) {
 	params, rawTools, warnings, betaFlags, err := a.prepareParams(call)
 	if err != nil {
@@ -1294,6 +1680,9 @@ func (a languageModel) Generate(ctx context.Context, call fantasy.Call) (*fantas
 	}
 
 	var content []fantasy.Content
+	// textOffset is the byte length of the text emitted so far, used to make
+	// citation offsets relative to the whole response.
+	textOffset := 0
 	for _, block := range response.Content {
 		switch block.Type {
 		case "text":
@@ -1304,6 +1693,15 @@ func (a languageModel) Generate(ctx context.Context, call fantasy.Call) (*fantas
 			content = append(content, fantasy.TextContent{
 				Text: text.Text,
 			})
+			span := fantasy.Citation{
+				StartIndex: textOffset,
+				EndIndex:   textOffset + len(text.Text),
+				Text:       text.Text,
+			}
+			for _, c := range text.Citations {
+				content = append(content, citationFromTextCitation(c).source(span))
+			}
+			textOffset += len(text.Text)
 		case "thinking":
 			reasoning, ok := block.AsAny().(anthropic.ThinkingBlock)
 			if !ok {
@@ -1397,6 +1795,10 @@ func (a languageModel) Generate(ctx context.Context, call fantasy.Call) (*fantas
 				}
 			}
 			content = append(content, toolResult)
+		case "mcp_tool_use":
+			content = append(content, parseMCPBlock(block.RawJSON()).toolCall())
+		case "mcp_tool_result":
+			content = append(content, parseMCPBlock(block.RawJSON()).toolResult())
 		}
 	}
 
@@ -1410,6 +1812,7 @@ func (a languageModel) Generate(ctx context.Context, call fantasy.Call) (*fantas
 			CacheReadTokens:     response.Usage.CacheReadInputTokens,
 		},
 		FinishReason:     mapFinishReason(string(response.StopReason)),
+		Safety:           mapSafety(string(response.StopReason)),
 		ProviderMetadata: fantasy.ProviderMetadata{},
 		Warnings:         warnings,
 	}, nil
@@ -1437,6 +1840,12 @@ func (a languageModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.S
 		}
 
 		sawMessageStop := false
+		// textOffset is the byte length of the text streamed so far, used to
+		// make citation offsets relative to the whole response.
+		textOffset := 0
+		// mcpBlocks keeps the raw MCP blocks from content_block_start, as
+		// the accumulator drops the fields the SDK doesn't model.
+		mcpBlocks := map[int64]mcpBlock{}
 
 		for stream.Next() {
 			chunk := stream.Current()
@@ -1486,6 +1895,20 @@ func (a languageModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.S
 					}) {
 						return
 					}
+				case "mcp_tool_use":
+					block := parseMCPBlock(chunk.ContentBlock.RawJSON())
+					mcpBlocks[chunk.Index] = block
+					if !yield(fantasy.StreamPart{
+						Type:             fantasy.StreamPartTypeToolInputStart,
+						ID:               block.ID,
+						ToolCallName:     block.Name,
+						ToolCallInput:    "",
+						ProviderExecuted: true,
+					}) {
+						return
+					}
+				case "mcp_tool_result":
+					mcpBlocks[chunk.Index] = parseMCPBlock(chunk.ContentBlock.RawJSON())
 				}
 			case "content_block_stop":
 				if len(acc.Content)-1 < int(chunk.Index) {
@@ -1500,6 +1923,29 @@ func (a languageModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.S
 					}) {
 						return
 					}
+					// Citations arrive as citations_delta events ahead of
+					// the text they cite and are collected by the
+					// accumulator, so they are emitted once the block's
+					// text is known.
+					span := fantasy.Citation{
+						StartIndex: textOffset,
+						EndIndex:   textOffset + len(contentBlock.Text),
+						Text:       contentBlock.Text,
+					}
+					for _, c := range contentBlock.Citations {
+						source := citationFromTextCitation(c).source(span)
+						if !yield(fantasy.StreamPart{
+							Type:       fantasy.StreamPartTypeSource,
+							ID:         source.ID,
+							SourceType: source.SourceType,
+							URL:        source.URL,
+							Title:      source.Title,
+							Citations:  source.Citations,
+						}) {
+							return
+						}
+					}
+					textOffset += len(contentBlock.Text)
 				case "thinking":
 					if !yield(fantasy.StreamPart{
 						Type:             fantasy.StreamPartTypeReasoningEnd,
@@ -1598,6 +2044,39 @@ func (a languageModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.S
 					}) {
 						return
 					}
+				case "mcp_tool_use":
+					block := mcpBlocks[chunk.Index]
+					if len(contentBlock.Input) > 0 {
+						block.Input = contentBlock.Input
+					}
+					toolCall := block.toolCall()
+					if !yield(fantasy.StreamPart{
+						Type:             fantasy.StreamPartTypeToolInputEnd,
+						ID:               toolCall.ToolCallID,
+						ProviderExecuted: true,
+					}) {
+						return
+					}
+					if !yield(fantasy.StreamPart{
+						Type:             fantasy.StreamPartTypeToolCall,
+						ID:               toolCall.ToolCallID,
+						ToolCallName:     toolCall.ToolName,
+						ToolCallInput:    toolCall.Input,
+						ProviderExecuted: true,
+						ProviderMetadata: toolCall.ProviderMetadata,
+					}) {
+						return
+					}
+				case "mcp_tool_result":
+					result := mcpBlocks[chunk.Index].toolResult()
+					if !yield(fantasy.StreamPart{
+						Type:             fantasy.StreamPartTypeToolResult,
+						ID:               result.ToolCallID,
+						ProviderExecuted: true,
+						ProviderMetadata: result.ProviderMetadata,
+					}) {
+						return
+					}
 				}
 			case "content_block_delta":
 				switch chunk.Delta.Type {
@@ -1675,6 +2154,7 @@ func (a languageModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.S
 			Type:         fantasy.StreamPartTypeFinish,
 			ID:           acc.ID,
 			FinishReason: mapFinishReason(string(acc.StopReason)),
+			Safety:       mapSafety(string(acc.StopReason)),
 			Usage: fantasy.Usage{
 				InputTokens:         acc.Usage.InputTokens,
 				OutputTokens:        acc.Usage.OutputTokens,
@@ -1,10 +1,14 @@
 package anthropic
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"image"
+	"image/png"
 	"io"
 	"math"
 	"net/http"
@@ -57,7 +61,7 @@ func TestToPrompt_DropsEmptyMessages(t *testing.T) {
 		require.Empty(t, systemBlocks)
 		require.Len(t, messages, 1, "should only have user message, assistant message should be dropped")
 		require.Len(t, warnings, 1)
-		require.Equal(t, fantasy.CallWarningTypeOther, warnings[0].Type)
+		require.Equal(t, fantasy.CallWarningTypeDroppedContent, warnings[0].Type)
 		require.Contains(t, warnings[0].Message, "dropping empty assistant message")
 		require.Contains(t, warnings[0].Message, "neither user-facing content nor tool calls")
 	})
@@ -94,7 +98,7 @@ func TestToPrompt_DropsEmptyMessages(t *testing.T) {
 		require.Len(t, warnings, 2)
 		require.Equal(t, fantasy.CallWarningTypeOther, warnings[0].Type)
 		require.Contains(t, warnings[0].Message, "sending reasoning content is disabled")
-		require.Equal(t, fantasy.CallWarningTypeOther, warnings[1].Type)
+		require.Equal(t, fantasy.CallWarningTypeDroppedContent, warnings[1].Type)
 		require.Contains(t, warnings[1].Message, "dropping empty assistant message")
 	})
 
@@ -119,7 +123,7 @@ func TestToPrompt_DropsEmptyMessages(t *testing.T) {
 		require.Empty(t, systemBlocks)
 		require.Len(t, messages, 1, "should only have user message")
 		require.Len(t, warnings, 1)
-		require.Equal(t, fantasy.CallWarningTypeOther, warnings[0].Type)
+		require.Equal(t, fantasy.CallWarningTypeDroppedContent, warnings[0].Type)
 		require.Contains(t, warnings[0].Message, "dropping empty assistant message")
 	})
 
@@ -442,7 +446,7 @@ func TestToPrompt_DropsEmptyMessages(t *testing.T) {
 		require.Empty(t, systemBlocks)
 		require.Len(t, messages, 1)
 		require.Len(t, warnings, 1)
-		require.Equal(t, fantasy.CallWarningTypeOther, warnings[0].Type)
+		require.Equal(t, fantasy.CallWarningTypeDroppedContent, warnings[0].Type)
 		require.Contains(t, warnings[0].Message, "application/zip")
 		require.Contains(t, warnings[0].Message, "not supported")
 	})
@@ -467,10 +471,10 @@ func TestToPrompt_DropsEmptyMessages(t *testing.T) {
 		require.Empty(t, systemBlocks)
 		require.Empty(t, messages)
 		require.Len(t, warnings, 2)
-		require.Equal(t, fantasy.CallWarningTypeOther, warnings[0].Type)
+		require.Equal(t, fantasy.CallWarningTypeDroppedContent, warnings[0].Type)
 		require.Contains(t, warnings[0].Message, "application/zip")
 		require.Contains(t, warnings[0].Message, "not supported")
-		require.Equal(t, fantasy.CallWarningTypeOther, warnings[1].Type)
+		require.Equal(t, fantasy.CallWarningTypeDroppedContent, warnings[1].Type)
 		require.Contains(t, warnings[1].Message, "dropping empty user message")
 		require.Contains(t, warnings[1].Message, "neither user-facing content nor tool results")
 	})
@@ -658,6 +662,204 @@ func TestGenerate_SendsOutputConfigEffort(t *testing.T) {
 	requireAnthropicEffort(t, call.body, EffortMedium)
 }
 
+func TestGenerate_SendsServiceTier(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		tier     fantasy.ServiceTier
+		want     any
+		warnings int
+	}{
+		{fantasy.ServiceTierPriority, "auto", 0},
+		{fantasy.ServiceTierDefault, "standard_only", 0},
+		{fantasy.ServiceTierFlex, nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.tier), func(t *testing.T) {
+			t.Parallel()
+
+			server, calls := newAnthropicJSONServer(mockAnthropicGenerateResponse())
+			defer server.Close()
+
+			provider, err := New(
+				WithAPIKey("test-api-key"),
+				WithBaseURL(server.URL),
+			)
+			require.NoError(t, err)
+
+			model, err := provider.LanguageModel(context.Background(), "claude-sonnet-4-20250514")
+			require.NoError(t, err)
+
+			resp, err := model.Generate(context.Background(), fantasy.Call{
+				Prompt:      testPrompt(),
+				ServiceTier: tt.tier,
+			})
+			require.NoError(t, err)
+			require.Len(t, resp.Warnings, tt.warnings)
+
+			call := awaitAnthropicCall(t, calls)
+			require.Equal(t, tt.want, call.body["service_tier"])
+		})
+	}
+}
+
+func TestGenerate_SendsMetadataUserID(t *testing.T) {
+	t.Parallel()
+
+	server, calls := newAnthropicJSONServer(mockAnthropicGenerateResponse())
+	defer server.Close()
+
+	provider, err := New(
+		WithAPIKey("test-api-key"),
+		WithBaseURL(server.URL),
+	)
+	require.NoError(t, err)
+
+	model, err := provider.LanguageModel(context.Background(), "claude-sonnet-4-20250514")
+	require.NoError(t, err)
+
+	_, err = model.Generate(context.Background(), fantasy.Call{
+		Prompt:   testPrompt(),
+		Metadata: map[string]string{fantasy.MetadataUserID: "user-123", "trace_id": "abc"},
+	})
+	require.NoError(t, err)
+
+	call := awaitAnthropicCall(t, calls)
+	require.Equal(t, map[string]any{"user_id": "user-123"}, call.body["metadata"])
+}
+
+func TestGenerate_SendsExtraBody(t *testing.T) {
+	t.Parallel()
+
+	server, calls := newAnthropicJSONServer(mockAnthropicGenerateResponse())
+	defer server.Close()
+
+	provider, err := New(
+		WithAPIKey("test-api-key"),
+		WithBaseURL(server.URL),
+	)
+	require.NoError(t, err)
+
+	model, err := provider.LanguageModel(context.Background(), "claude-sonnet-4-20250514")
+	require.NoError(t, err)
+
+	_, err = model.Generate(context.Background(), fantasy.Call{
+		Prompt:          testPrompt(),
+		MaxOutputTokens: fantasy.Opt[int64](100),
+		ExtraBody:       map[string]any{"max_tokens": 200, "new_param": "on"},
+		ProviderOptions: NewProviderOptions(&ProviderOptions{
+			ExtraBody: map[string]any{"new_param": "off", "other_param": true},
+		}),
+	})
+	require.NoError(t, err)
+
+	call := awaitAnthropicCall(t, calls)
+	require.Equal(t, float64(200), call.body["max_tokens"])
+	require.Equal(t, "on", call.body["new_param"])
+	require.Equal(t, true, call.body["other_param"])
+}
+
+func TestGenerate_PreparesImages(t *testing.T) {
+	t.Parallel()
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewGray(image.Rect(0, 0, 9000, 10))))
+
+	server, calls := newAnthropicJSONServer(mockAnthropicGenerateResponse())
+	defer server.Close()
+
+	provider, err := New(
+		WithAPIKey("test-api-key"),
+		WithBaseURL(server.URL),
+	)
+	require.NoError(t, err)
+
+	model, err := provider.LanguageModel(context.Background(), "claude-sonnet-4-20250514")
+	require.NoError(t, err)
+
+	resp, err := model.Generate(context.Background(), fantasy.Call{
+		Prompt: fantasy.Prompt{
+			fantasy.NewUserMessage("describe", fantasy.FilePart{Data: buf.Bytes(), MediaType: "image/png"}),
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Warnings, 1)
+	require.Contains(t, resp.Warnings[0].Message, "9000x10")
+
+	call := awaitAnthropicCall(t, calls)
+	messages := call.body["messages"].([]any)
+	content := messages[0].(map[string]any)["content"].([]any)
+	source := content[1].(map[string]any)["source"].(map[string]any)
+	data, err := base64.StdEncoding.DecodeString(source["data"].(string))
+	require.NoError(t, err)
+	cfg, err := png.DecodeConfig(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, 8000, cfg.Width)
+}
+
+func TestGenerate_StrictPromptConversion(t *testing.T) {
+	t.Parallel()
+
+	server, calls := newAnthropicJSONServer(mockAnthropicGenerateResponse())
+	defer server.Close()
+
+	provider, err := New(
+		WithAPIKey("test-api-key"),
+		WithBaseURL(server.URL),
+	)
+	require.NoError(t, err)
+
+	model, err := provider.LanguageModel(context.Background(), "claude-sonnet-4-20250514")
+	require.NoError(t, err)
+
+	_, err = model.Generate(context.Background(), fantasy.Call{
+		Prompt: fantasy.Prompt{
+			fantasy.NewUserMessage("unzip this", fantasy.FilePart{Data: []byte("PK"), MediaType: "application/zip"}),
+		},
+		StrictPromptConversion: true,
+	})
+	var droppedErr *fantasy.DroppedContentError
+	require.ErrorAs(t, err, &droppedErr)
+	require.Contains(t, droppedErr.Error(), "application/zip")
+	require.Empty(t, calls)
+}
+
+func TestCountTokens(t *testing.T) {
+	t.Parallel()
+
+	server, calls := newAnthropicJSONServer(map[string]any{"input_tokens": 42})
+	defer server.Close()
+
+	provider, err := New(
+		WithAPIKey("test-api-key"),
+		WithBaseURL(server.URL),
+	)
+	require.NoError(t, err)
+
+	model, err := provider.LanguageModel(context.Background(), "claude-sonnet-4-20250514")
+	require.NoError(t, err)
+
+	counter, ok := model.(fantasy.TokenCounter)
+	require.True(t, ok)
+
+	count, err := counter.CountTokens(context.Background(), fantasy.Call{
+		Prompt: testPrompt(),
+		Tools: []fantasy.Tool{
+			WebSearchTool(nil),
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(42), count)
+
+	call := awaitAnthropicCall(t, calls)
+	require.Equal(t, "POST", call.method)
+	require.Equal(t, "/v1/messages/count_tokens", call.path)
+	require.Equal(t, "claude-sonnet-4-20250514", call.body["model"])
+	require.NotEmpty(t, call.body["messages"])
+	require.NotEmpty(t, call.body["tools"])
+	require.NotContains(t, call.body, "max_tokens")
+}
+
 func TestGenerate_SendsThinkingDisplay(t *testing.T) {
 	t.Parallel()
 
@@ -1522,6 +1724,93 @@ func TestGenerate_WebSearchResponse(t *testing.T) {
 	)
 }
 
+func TestGenerate_TextCitations(t *testing.T) {
+	t.Parallel()
+
+	response := mockAnthropicGenerateResponse()
+	response["content"] = []any{
+		map[string]any{
+			"type": "text",
+			"text": "Intro. ",
+		},
+		map[string]any{
+			"type": "text",
+			"text": "The sky is blue.",
+			"citations": []any{
+				map[string]any{
+					"type":            "web_search_result_location",
+					"url":             "https://example.com/sky",
+					"title":           "Sky",
+					"cited_text":      "the sky appears blue",
+					"encrypted_index": "enc",
+				},
+				map[string]any{
+					"type":             "char_location",
+					"document_index":   0,
+					"document_title":   "Notes",
+					"start_char_index": 0,
+					"end_char_index":   10,
+					"cited_text":       "sky: blue",
+				},
+			},
+		},
+	}
+	server, _ := newAnthropicJSONServer(response)
+	defer server.Close()
+
+	provider, err := New(
+		WithAPIKey("test-api-key"),
+		WithBaseURL(server.URL),
+	)
+	require.NoError(t, err)
+
+	model, err := provider.LanguageModel(context.Background(), "claude-sonnet-4-20250514")
+	require.NoError(t, err)
+
+	resp, err := model.Generate(context.Background(), fantasy.Call{Prompt: testPrompt()})
+	require.NoError(t, err)
+
+	sources := resp.Content.Sources()
+	require.Len(t, sources, 2)
+
+	require.Equal(t, fantasy.SourceTypeURL, sources[0].SourceType)
+	require.Equal(t, "https://example.com/sky", sources[0].URL)
+	require.Equal(t, []fantasy.Citation{{
+		StartIndex: len("Intro. "),
+		EndIndex:   len("Intro. The sky is blue."),
+		Text:       "The sky is blue.",
+		CitedText:  "the sky appears blue",
+	}}, sources[0].Citations)
+
+	require.Equal(t, fantasy.SourceTypeDocument, sources[1].SourceType)
+	require.Equal(t, "Notes", sources[1].Title)
+	require.Equal(t, "document-0", sources[1].ID)
+	require.Equal(t, "sky: blue", sources[1].Citations[0].CitedText)
+}
+
+func TestGenerate_RefusalReportsSafety(t *testing.T) {
+	t.Parallel()
+
+	response := mockAnthropicGenerateResponse()
+	response["stop_reason"] = "refusal"
+	server, _ := newAnthropicJSONServer(response)
+	defer server.Close()
+
+	provider, err := New(
+		WithAPIKey("test-api-key"),
+		WithBaseURL(server.URL),
+	)
+	require.NoError(t, err)
+
+	model, err := provider.LanguageModel(context.Background(), "claude-sonnet-4-20250514")
+	require.NoError(t, err)
+
+	resp, err := model.Generate(context.Background(), fantasy.Call{Prompt: testPrompt()})
+	require.NoError(t, err)
+	require.Equal(t, fantasy.FinishReasonContentFilter, resp.FinishReason)
+	require.Equal(t, &fantasy.SafetyInfo{Blocked: true, Reason: "refusal"}, resp.Safety)
+}
+
 func TestGenerate_WebSearchErrorPreservesErrorCode(t *testing.T) {
 	t.Parallel()
 
@@ -2053,6 +2342,186 @@ func TestStream_WebSearchResponse(t *testing.T) {
 	require.Equal(t, "Here are the results.", textDeltas[0].Delta)
 }
 
+func TestStream_CitationsDelta(t *testing.T) {
+	t.Parallel()
+
+	chunks := []string{
+		"event: message_start\n",
+		`data: {"type":"message_start","message":{"id":"msg_01","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[],"stop_reason":null,"usage":{"input_tokens":10,"output_tokens":0}}}` + "\n\n",
+		"event: content_block_start\n",
+		`data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}` + "\n\n",
+		"event: content_block_delta\n",
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Intro. "}}` + "\n\n",
+		"event: content_block_stop\n",
+		`data: {"type":"content_block_stop","index":0}` + "\n\n",
+		"event: content_block_start\n",
+		`data: {"type":"content_block_start","index":1,"content_block":{"type":"text","text":"","citations":[]}}` + "\n\n",
+		"event: content_block_delta\n",
+		`data: {"type":"content_block_delta","index":1,"delta":{"type":"citations_delta","citation":{"type":"web_search_result_location","cited_text":"The sky is blue.","url":"https://example.com/sky","title":"Sky","encrypted_index":"abc"}}}` + "\n\n",
+		"event: content_block_delta\n",
+		`data: {"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"The sky is blue."}}` + "\n\n",
+		"event: content_block_stop\n",
+		`data: {"type":"content_block_stop","index":1}` + "\n\n",
+		"event: message_delta\n",
+		`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":5}}` + "\n\n",
+		"event: message_stop\n",
+		`data: {"type":"message_stop"}` + "\n\n",
+	}
+
+	server, calls := newAnthropicStreamingServer(chunks)
+	defer server.Close()
+
+	provider, err := New(
+		WithAPIKey("test-api-key"),
+		WithBaseURL(server.URL),
+	)
+	require.NoError(t, err)
+
+	model, err := provider.LanguageModel(context.Background(), "claude-sonnet-4-20250514")
+	require.NoError(t, err)
+
+	stream, err := model.Stream(context.Background(), fantasy.Call{Prompt: testPrompt()})
+	require.NoError(t, err)
+
+	var sources []fantasy.StreamPart
+	stream(func(part fantasy.StreamPart) bool {
+		if part.Type == fantasy.StreamPartTypeSource {
+			sources = append(sources, part)
+		}
+		return true
+	})
+
+	_ = awaitAnthropicCall(t, calls)
+
+	require.Len(t, sources, 1)
+	require.Equal(t, "https://example.com/sky", sources[0].URL)
+	require.Equal(t, "Sky", sources[0].Title)
+	require.Equal(t, fantasy.SourceTypeURL, sources[0].SourceType)
+	require.Equal(t, []fantasy.Citation{{
+		StartIndex: len("Intro. "),
+		EndIndex:   len("Intro. The sky is blue."),
+		Text:       "The sky is blue.",
+		CitedText:  "The sky is blue.",
+	}}, sources[0].Citations)
+}
+
+func TestStream_MCPToolUse(t *testing.T) {
+	t.Parallel()
+
+	chunks := []string{
+		"event: message_start\n",
+		`data: {"type":"message_start","message":{"id":"msg_01","type":"message","role":"assistant","model":"claude-sonnet-4-20250514","content":[],"stop_reason":null,"usage":{"input_tokens":10,"output_tokens":0}}}` + "\n\n",
+		"event: content_block_start\n",
+		`data: {"type":"content_block_start","index":0,"content_block":{"type":"mcp_tool_use","id":"mcptoolu_01","name":"echo","server_name":"tools","input":{}}}` + "\n\n",
+		"event: content_block_delta\n",
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"text\":\"hi\"}"}}` + "\n\n",
+		"event: content_block_stop\n",
+		`data: {"type":"content_block_stop","index":0}` + "\n\n",
+		"event: content_block_start\n",
+		`data: {"type":"content_block_start","index":1,"content_block":{"type":"mcp_tool_result","tool_use_id":"mcptoolu_01","is_error":false,"content":[{"type":"text","text":"hi"}]}}` + "\n\n",
+		"event: content_block_stop\n",
+		`data: {"type":"content_block_stop","index":1}` + "\n\n",
+		"event: message_delta\n",
+		`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":5}}` + "\n\n",
+		"event: message_stop\n",
+		`data: {"type":"message_stop"}` + "\n\n",
+	}
+
+	server, calls := newAnthropicStreamingServer(chunks)
+	defer server.Close()
+
+	provider, err := New(
+		WithAPIKey("test-api-key"),
+		WithBaseURL(server.URL),
+	)
+	require.NoError(t, err)
+
+	model, err := provider.LanguageModel(context.Background(), "claude-sonnet-4-20250514")
+	require.NoError(t, err)
+
+	stream, err := model.Stream(context.Background(), fantasy.Call{Prompt: testPrompt()})
+	require.NoError(t, err)
+
+	var toolCalls, toolResults []fantasy.StreamPart
+	stream(func(part fantasy.StreamPart) bool {
+		switch part.Type {
+		case fantasy.StreamPartTypeToolCall:
+			toolCalls = append(toolCalls, part)
+		case fantasy.StreamPartTypeToolResult:
+			toolResults = append(toolResults, part)
+		}
+		return true
+	})
+
+	_ = awaitAnthropicCall(t, calls)
+
+	require.Len(t, toolCalls, 1)
+	require.Equal(t, "mcptoolu_01", toolCalls[0].ID)
+	require.Equal(t, "echo", toolCalls[0].ToolCallName)
+	require.JSONEq(t, `{"text":"hi"}`, toolCalls[0].ToolCallInput)
+	require.True(t, toolCalls[0].ProviderExecuted)
+	callMeta, ok := toolCalls[0].ProviderMetadata[Name].(*MCPToolMetadata)
+	require.True(t, ok)
+	require.Equal(t, "tools", callMeta.ServerName)
+
+	require.Len(t, toolResults, 1)
+	require.Equal(t, "mcptoolu_01", toolResults[0].ID)
+	require.True(t, toolResults[0].ProviderExecuted)
+	resultMeta, ok := toolResults[0].ProviderMetadata[Name].(*MCPToolMetadata)
+	require.True(t, ok)
+	require.JSONEq(t, `[{"type":"text","text":"hi"}]`, string(resultMeta.Content))
+
+	typed, ok := MetadataFrom(toolResults[0].ProviderMetadata)
+	require.True(t, ok)
+	require.Equal(t, Metadata{MCPTool: resultMeta}, typed)
+	_, ok = MetadataFrom(fantasy.ProviderMetadata{})
+	require.False(t, ok)
+}
+
+func TestToPrompt_MCPToolRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	prompt := fantasy.Prompt{
+		fantasy.NewUserMessage("Echo hi"),
+		{
+			Role: fantasy.MessageRoleAssistant,
+			Content: []fantasy.MessagePart{
+				fantasy.ToolCallPart{
+					ToolCallID:       "mcptoolu_01",
+					ToolName:         "echo",
+					Input:            `{"text":"hi"}`,
+					ProviderExecuted: true,
+					ProviderOptions: fantasy.ProviderOptions{
+						Name: &MCPToolMetadata{ServerName: "tools"},
+					},
+				},
+				fantasy.ToolResultPart{
+					ToolCallID:       "mcptoolu_01",
+					ProviderExecuted: true,
+					ProviderOptions: fantasy.ProviderOptions{
+						Name: &MCPToolMetadata{Content: json.RawMessage(`[{"type":"text","text":"hi"}]`)},
+					},
+				},
+				fantasy.TextPart{Text: "hi"},
+			},
+		},
+	}
+
+	_, messages, _ := toPrompt(prompt, true)
+	require.Len(t, messages, 2)
+
+	data, err := json.Marshal(messages[1])
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"role": "assistant",
+		"content": [
+			{"type": "mcp_tool_use", "id": "mcptoolu_01", "name": "echo", "server_name": "tools", "input": {"text": "hi"}},
+			{"type": "mcp_tool_result", "tool_use_id": "mcptoolu_01", "is_error": false, "content": [{"type": "text", "text": "hi"}]},
+			{"type": "text", "text": "hi"}
+		]
+	}`, string(data))
+}
+
 func TestStream_WebSearchErrorPreservesErrorCode(t *testing.T) {
 	t.Parallel()
 
@@ -2153,6 +2622,121 @@ func TestGenerate_ToolChoiceNone(t *testing.T) {
 	require.Equal(t, "none", toolChoice["type"], "tool_choice should be 'none'")
 }
 
+func toolLoopPrompt() fantasy.Prompt {
+	return fantasy.Prompt{
+		fantasy.NewUserMessage("What's the weather?"),
+		{
+			Role: fantasy.MessageRoleAssistant,
+			Content: []fantasy.MessagePart{
+				fantasy.ToolCallPart{ToolCallID: "call-1", ToolName: "weather", Input: `{"city":"Paris"}`},
+			},
+		},
+		{
+			Role: fantasy.MessageRoleTool,
+			Content: []fantasy.MessagePart{
+				fantasy.ToolResultPart{ToolCallID: "call-1", Output: fantasy.ToolResultOutputContentText{Text: "Sunny"}},
+			},
+		},
+	}
+}
+
+func TestGenerate_ToolChoiceNoneWithoutTools(t *testing.T) {
+	t.Parallel()
+
+	server, calls := newAnthropicJSONServer(mockAnthropicGenerateResponse())
+	defer server.Close()
+
+	provider, err := New(WithAPIKey("test-api-key"), WithBaseURL(server.URL))
+	require.NoError(t, err)
+	model, err := provider.LanguageModel(t.Context(), "claude-sonnet-4-20250514")
+	require.NoError(t, err)
+
+	toolChoiceNone := fantasy.ToolChoiceNone
+	_, err = model.Generate(t.Context(), fantasy.Call{
+		Prompt:     toolLoopPrompt(),
+		ToolChoice: &toolChoiceNone,
+	})
+	require.NoError(t, err)
+
+	call := awaitAnthropicCall(t, calls)
+	toolChoice, ok := call.body["tool_choice"].(map[string]any)
+	require.True(t, ok, "request body should have tool_choice")
+	require.Equal(t, "none", toolChoice["type"])
+	tools, ok := call.body["tools"].([]any)
+	require.True(t, ok, "request body should declare the called tools")
+	require.Len(t, tools, 1)
+	require.Equal(t, "weather", tools[0].(map[string]any)["name"])
+}
+
+func TestGenerate_CacheToolResults(t *testing.T) {
+	t.Parallel()
+
+	lastToolResult := func(t *testing.T, body map[string]any) map[string]any {
+		t.Helper()
+		messages := body["messages"].([]any)
+		content := messages[len(messages)-1].(map[string]any)["content"].([]any)
+		return content[len(content)-1].(map[string]any)
+	}
+	cacheControl := NewProviderCacheControlOptions(&ProviderCacheControlOptions{
+		CacheControl: CacheControl{Type: "ephemeral"},
+	})
+
+	t.Run("marks the last tool result", func(t *testing.T) {
+		t.Parallel()
+		server, calls := newAnthropicJSONServer(mockAnthropicGenerateResponse())
+		defer server.Close()
+		provider, err := New(WithAPIKey("test-api-key"), WithBaseURL(server.URL))
+		require.NoError(t, err)
+		model, err := provider.LanguageModel(t.Context(), "claude-sonnet-4-20250514")
+		require.NoError(t, err)
+
+		prompt := toolLoopPrompt()
+		_, err = model.Generate(t.Context(), fantasy.Call{
+			Prompt: prompt,
+			ProviderOptions: NewProviderOptions(&ProviderOptions{
+				CacheToolResults: fantasy.Opt(true),
+			}),
+		})
+		require.NoError(t, err)
+
+		call := awaitAnthropicCall(t, calls)
+		result := lastToolResult(t, call.body)
+		require.Equal(t, "tool_result", result["type"])
+		require.Equal(t, map[string]any{"type": "ephemeral"}, result["cache_control"])
+		require.Nil(t, prompt[2].ProviderOptions, "the prompt should be left untouched")
+	})
+
+	t.Run("keeps within the breakpoint limit", func(t *testing.T) {
+		t.Parallel()
+		server, calls := newAnthropicJSONServer(mockAnthropicGenerateResponse())
+		defer server.Close()
+		provider, err := New(WithAPIKey("test-api-key"), WithBaseURL(server.URL))
+		require.NoError(t, err)
+		model, err := provider.LanguageModel(t.Context(), "claude-sonnet-4-20250514")
+		require.NoError(t, err)
+
+		prompt := toolLoopPrompt()
+		system := fantasy.Message{Role: fantasy.MessageRoleSystem}
+		for i := range maxCacheBreakpoints {
+			system.Content = append(system.Content, fantasy.TextPart{
+				Text:            fmt.Sprintf("instructions %d", i),
+				ProviderOptions: cacheControl,
+			})
+		}
+		prompt = append(fantasy.Prompt{system}, prompt...)
+		_, err = model.Generate(t.Context(), fantasy.Call{
+			Prompt: prompt,
+			ProviderOptions: NewProviderOptions(&ProviderOptions{
+				CacheToolResults: fantasy.Opt(true),
+			}),
+		})
+		require.NoError(t, err)
+
+		call := awaitAnthropicCall(t, calls)
+		require.NotContains(t, lastToolResult(t, call.body), "cache_control")
+	})
+}
+
 // --- Computer Use Tests ---
 
 // jsonRoundTripTool simulates a JSON round-trip on a
@@ -2477,6 +3061,22 @@ func TestToTools_RawJSON(t *testing.T) {
 	require.Equal(t, "computer", cuToolJSON["name"])
 }
 
+func TestMergeBetaFlags(t *testing.T) {
+	t.Parallel()
+
+	flags, warnings := mergeBetaFlags(
+		[]string{"computer-use-2025-01-24"},
+		[]Beta{BetaInterleavedThinking, BetaContext1M, "computer-use-2025-11-24"},
+		map[string]string{"anthropic-beta": "context-1m-2025-08-07, files-api-2025-04-14"},
+	)
+	require.Equal(t, []string{"computer-use-2025-01-24", string(BetaInterleavedThinking)}, flags)
+	require.Len(t, warnings, 1)
+	require.Contains(t, warnings[0].Message, "computer-use-2025-11-24")
+
+	require.Equal(t, "context-1m", betaFeature("context-1m-2025-08-07"))
+	require.Equal(t, "custom-beta", betaFeature("custom-beta"))
+}
+
 func TestGenerate_BetaAPI(t *testing.T) {
 	t.Parallel()
 
@@ -2548,6 +3148,38 @@ func TestGenerate_BetaAPI(t *testing.T) {
 		require.Contains(t, capturedHeaders.Get("Anthropic-Beta"), "computer-use-2025-11-24")
 	})
 
+	t.Run("sends betas from provider options", func(t *testing.T) {
+		t.Parallel()
+
+		var capturedHeaders http.Header
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			capturedHeaders = r.Header.Clone()
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(mockAnthropicGenerateResponse())
+		}))
+		defer server.Close()
+
+		provider, err := New(
+			WithAPIKey("test-api-key"),
+			WithBaseURL(server.URL),
+		)
+		require.NoError(t, err)
+
+		model, err := provider.LanguageModel(context.Background(), "claude-sonnet-4-20250514")
+		require.NoError(t, err)
+
+		resp, err := model.Generate(context.Background(), fantasy.Call{
+			Prompt: testPrompt(),
+			ProviderOptions: NewProviderOptions(&ProviderOptions{
+				Betas: []Beta{BetaContext1M, BetaExtendedCacheTTL, BetaContext1M, "context-1m-2026-01-01"},
+			}),
+		})
+		require.NoError(t, err)
+		require.Equal(t, []string{string(BetaContext1M), string(BetaExtendedCacheTTL)}, capturedHeaders.Values("Anthropic-Beta"))
+		require.Len(t, resp.Warnings, 1)
+		require.Contains(t, resp.Warnings[0].Message, "context-1m-2026-01-01")
+	})
+
 	t.Run("returns tool use from beta response", func(t *testing.T) {
 		t.Parallel()
 
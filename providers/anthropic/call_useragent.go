@@ -24,3 +24,15 @@ func callHeadersRequestOptions(call fantasy.Call) []option.RequestOption {
 	}
 	return opts
 }
+
+func callQueryRequestOptions(call fantasy.Call) []option.RequestOption {
+	query, ok := httpheaders.CallQuery(call.ExtraQuery)
+	if !ok {
+		return nil
+	}
+	opts := make([]option.RequestOption, 0, len(query))
+	for k, v := range query {
+		opts = append(opts, option.WithQuery(k, v))
+	}
+	return opts
+}
@@ -0,0 +1,66 @@
+package anthropic
+
+import (
+	"fmt"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/anthropic-sdk-go"
+)
+
+// citation holds the fields shared by the citation unions Anthropic returns
+// on text blocks.
+type citation struct {
+	Type          string
+	CitedText     string
+	URL           string
+	Title         string
+	Source        string
+	DocumentIndex int64
+	DocumentTitle string
+}
+
+func citationFromTextCitation(c anthropic.TextCitationUnion) citation {
+	return citation{
+		Type:          c.Type,
+		CitedText:     c.CitedText,
+		URL:           c.URL,
+		Title:         c.Title,
+		Source:        c.Source,
+		DocumentIndex: c.DocumentIndex,
+		DocumentTitle: c.DocumentTitle,
+	}
+}
+
+// source converts a citation into a fantasy.SourceContent. Anthropic
+// attaches citations to whole text blocks, so span is the text block the
+// citation belongs to.
+func (c citation) source(span fantasy.Citation) fantasy.SourceContent {
+	span.CitedText = c.CitedText
+	switch c.Type {
+	case "web_search_result_location":
+		return fantasy.SourceContent{
+			SourceType: fantasy.SourceTypeURL,
+			ID:         c.URL,
+			URL:        c.URL,
+			Title:      c.Title,
+			Citations:  []fantasy.Citation{span},
+		}
+	case "search_result_location":
+		return fantasy.SourceContent{
+			SourceType: fantasy.SourceTypeURL,
+			ID:         c.Source,
+			URL:        c.Source,
+			Title:      c.Title,
+			Citations:  []fantasy.Citation{span},
+		}
+	default:
+		// char_location, page_location and content_block_location all
+		// reference a document from the prompt.
+		return fantasy.SourceContent{
+			SourceType: fantasy.SourceTypeDocument,
+			ID:         fmt.Sprintf("document-%d", c.DocumentIndex),
+			Title:      c.DocumentTitle,
+			Citations:  []fantasy.Citation{span},
+		}
+	}
+}
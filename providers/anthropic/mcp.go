@@ -0,0 +1,91 @@
+package anthropic
+
+import (
+	"encoding/json"
+
+	"charm.land/fantasy"
+	"github.com/charmbracelet/anthropic-sdk-go"
+	"github.com/charmbracelet/anthropic-sdk-go/packages/param"
+)
+
+// mcpBlock holds the fields of the mcp_tool_use and mcp_tool_result blocks
+// the SDK doesn't model.
+type mcpBlock struct {
+	ID         string          `json:"id"`
+	Name       string          `json:"name"`
+	ServerName string          `json:"server_name"`
+	Input      json.RawMessage `json:"input"`
+	ToolUseID  string          `json:"tool_use_id"`
+	IsError    bool            `json:"is_error"`
+	Content    json.RawMessage `json:"content"`
+}
+
+func parseMCPBlock(raw string) mcpBlock {
+	var block mcpBlock
+	_ = json.Unmarshal([]byte(raw), &block)
+	return block
+}
+
+func (b mcpBlock) toolCall() fantasy.ToolCallContent {
+	return fantasy.ToolCallContent{
+		ToolCallID:       b.ID,
+		ToolName:         b.Name,
+		Input:            string(b.Input),
+		ProviderExecuted: true,
+		ProviderMetadata: fantasy.ProviderMetadata{
+			Name: &MCPToolMetadata{ServerName: b.ServerName},
+		},
+	}
+}
+
+func (b mcpBlock) toolResult() fantasy.ToolResultContent {
+	return fantasy.ToolResultContent{
+		ToolCallID:       b.ToolUseID,
+		ProviderExecuted: true,
+		ProviderMetadata: fantasy.ProviderMetadata{
+			Name: &MCPToolMetadata{Content: b.Content, IsError: b.IsError},
+		},
+	}
+}
+
+// mcpToolMetadata returns the MCP metadata attached to a message part, if
+// any.
+func mcpToolMetadata(options fantasy.ProviderOptions) (*MCPToolMetadata, bool) {
+	meta, ok := options[Name].(*MCPToolMetadata)
+	return meta, ok
+}
+
+// rawContentBlock sends a block the SDK doesn't model as raw JSON. The SDK
+// only allows overriding variant structs, so the server_tool_use slot is
+// used as the carrier.
+func rawContentBlock(block map[string]any) anthropic.ContentBlockParamUnion {
+	override := param.Override[anthropic.ServerToolUseBlockParam](block)
+	return anthropic.ContentBlockParamUnion{OfServerToolUse: &override}
+}
+
+func buildMCPToolUseBlock(toolCall fantasy.ToolCallPart, meta *MCPToolMetadata) anthropic.ContentBlockParamUnion {
+	input := json.RawMessage(toolCall.Input)
+	if !json.Valid(input) {
+		input = json.RawMessage("{}")
+	}
+	return rawContentBlock(map[string]any{
+		"type":        "mcp_tool_use",
+		"id":          toolCall.ToolCallID,
+		"name":        toolCall.ToolName,
+		"server_name": meta.ServerName,
+		"input":       input,
+	})
+}
+
+func buildMCPToolResultBlock(toolCallID string, meta *MCPToolMetadata) anthropic.ContentBlockParamUnion {
+	content := meta.Content
+	if len(content) == 0 {
+		content = json.RawMessage("[]")
+	}
+	return rawContentBlock(map[string]any{
+		"type":        "mcp_tool_result",
+		"tool_use_id": toolCallID,
+		"is_error":    meta.IsError,
+		"content":     content,
+	})
+}
@@ -0,0 +1,33 @@
+package anthropic
+
+import "charm.land/fantasy"
+
+// Metadata is the provider metadata Anthropic sets, by type. MetadataFrom
+// sets the field of the type it finds and leaves the others nil.
+type Metadata struct {
+	// Reasoning is set on reasoning content, with its signature or redacted
+	// data.
+	Reasoning *ReasoningOptionMetadata
+	// WebSearchResult is set on the results of the web search tool.
+	WebSearchResult *WebSearchResultMetadata
+	// MCPTool is set on the tool calls and results of MCP servers.
+	MCPTool *MCPToolMetadata
+}
+
+// MetadataFrom returns the Anthropic metadata of a response, content or
+// stream part, and whether there is any. Claude models on Bedrock and Vertex
+// AI served through this package set the same metadata.
+func MetadataFrom(meta fantasy.ProviderMetadata) (Metadata, bool) {
+	var m Metadata
+	var ok bool
+	if m.Reasoning, ok = fantasy.ProviderMetadataOf[*ReasoningOptionMetadata](meta, Name); ok {
+		return m, true
+	}
+	if m.WebSearchResult, ok = fantasy.ProviderMetadataOf[*WebSearchResultMetadata](meta, Name); ok {
+		return m, true
+	}
+	if m.MCPTool, ok = fantasy.ProviderMetadataOf[*MCPToolMetadata](meta, Name); ok {
+		return m, true
+	}
+	return Metadata{}, false
+}
@@ -0,0 +1,46 @@
+package anthropic
+
+import (
+	"testing"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/fantasytest"
+	"github.com/charmbracelet/anthropic-sdk-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToPrompt_Properties(t *testing.T) {
+	t.Parallel()
+
+	fantasytest.ForAll(t, 200, func(t *testing.T, call fantasy.Call) {
+		systemBlocks, messages, _ := toPrompt(call.Prompt, true)
+		fantasytest.RequireTextsPreserved(t, call.Prompt, []any{systemBlocks, messages})
+		fantasytest.RequireToolCallIDsPreserved(t, call.Prompt, messages)
+
+		// Messages alternate, starting with the user, and every tool use is
+		// answered in the next message.
+		for i, msg := range messages {
+			role := anthropic.MessageParamRoleUser
+			if i%2 == 1 {
+				role = anthropic.MessageParamRoleAssistant
+			}
+			require.Equal(t, role, msg.Role, "message %d", i)
+			for _, block := range msg.Content {
+				if block.OfToolUse == nil {
+					continue
+				}
+				require.Less(t, i+1, len(messages), "tool use %s is not answered", block.OfToolUse.ID)
+				require.True(t, hasToolResult(messages[i+1], block.OfToolUse.ID), "tool use %s is not answered", block.OfToolUse.ID)
+			}
+		}
+	})
+}
+
+func hasToolResult(msg anthropic.MessageParam, id string) bool {
+	for _, block := range msg.Content {
+		if block.OfToolResult != nil && block.OfToolResult.ToolUseID == id {
+			return true
+		}
+	}
+	return false
+}
@@ -35,12 +35,36 @@ const (
 	ThinkingDisplayOmitted ThinkingDisplay = "omitted"
 )
 
+// Beta is an Anthropic beta feature, enabled through the anthropic-beta
+// header. Any header value can be used, the constants are the common ones.
+type Beta string
+
+const (
+	// BetaTokenEfficientTools reduces the output tokens spent on tool
+	// calls on Claude 3.7 Sonnet.
+	BetaTokenEfficientTools Beta = "token-efficient-tools-2025-02-19"
+	// BetaExtendedCacheTTL allows the 1 hour prompt cache lifetime.
+	BetaExtendedCacheTTL Beta = "extended-cache-ttl-2025-04-11"
+	// BetaContext1M enables the 1M token context window on supported
+	// models.
+	BetaContext1M Beta = "context-1m-2025-08-07"
+	// BetaInterleavedThinking allows thinking between tool calls.
+	BetaInterleavedThinking Beta = "interleaved-thinking-2025-05-14"
+	// BetaFineGrainedToolStreaming streams tool call inputs without
+	// buffering them.
+	BetaFineGrainedToolStreaming Beta = "fine-grained-tool-streaming-2025-05-14"
+	// BetaOutput128K raises the output limit of Claude 3.7 Sonnet to 128K
+	// tokens.
+	BetaOutput128K Beta = "output-128k-2025-02-19"
+)
+
 // Global type identifiers for Anthropic-specific provider data.
 const (
 	TypeProviderOptions         = Name + ".options"
 	TypeReasoningOptionMetadata = Name + ".reasoning_metadata"
 	TypeProviderCacheControl    = Name + ".cache_control_options"
 	TypeWebSearchResultMetadata = Name + ".web_search_result_metadata"
+	TypeMCPToolMetadata         = Name + ".mcp_tool_metadata"
 )
 
 // Register Anthropic provider-specific types with the global registry.
@@ -73,8 +97,31 @@ func init() {
 		}
 		return &v, nil
 	})
+	fantasy.RegisterProviderType(TypeMCPToolMetadata, func(data []byte) (fantasy.ProviderOptionsData, error) {
+		var v MCPToolMetadata
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		return &v, nil
+	})
+	fantasy.RegisterCacheProfile(Name, fantasy.CacheProfile{
+		MaxBreakpoints: maxCacheBreakpoints,
+		MinTokens:      1024,
+		ReadCost:       0.1,
+		IsBreakpoint: func(options fantasy.ProviderOptions) bool {
+			return GetCacheControl(options) != nil
+		},
+		Breakpoint: func() fantasy.ProviderOptions {
+			return NewProviderCacheControlOptions(&ProviderCacheControlOptions{
+				CacheControl: CacheControl{Type: "ephemeral"},
+			})
+		},
+	})
 }
 
+// maxCacheBreakpoints is the most cache breakpoints a call may have.
+const maxCacheBreakpoints = 4
+
 // ProviderOptions represents additional options for the Anthropic provider.
 type ProviderOptions struct {
 	SendReasoning          *bool                   `json:"send_reasoning"`
@@ -83,6 +130,18 @@ type ProviderOptions struct {
 	ThinkingDisplay        *ThinkingDisplay        `json:"thinking_display"`
 	DisableParallelToolUse *bool                   `json:"disable_parallel_tool_use"`
 	ExtraBody              map[string]any          `json:"extra_body,omitempty"`
+
+	// CacheToolResults marks the last tool result of the prompt as a cache
+	// breakpoint, so each step of a tool-heavy agent run reads the steps
+	// before it from the cache. It's skipped when the call already has the
+	// most breakpoints Anthropic allows.
+	CacheToolResults *bool `json:"cache_tool_results,omitempty"`
+
+	// Betas are the beta features to enable for the call, on top of the
+	// ones the tools need. Duplicates are sent once, and when two
+	// versions of the same feature are requested the first one is kept
+	// with a warning.
+	Betas []Beta `json:"betas,omitempty"`
 }
 
 // Options implements the ProviderOptions interface.
@@ -201,6 +260,36 @@ func (m *WebSearchResultMetadata) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// MCPToolMetadata stores the details of an mcp_tool_use or mcp_tool_result
+// block executed by Anthropic's MCP connector, so the blocks can be sent
+// back in multi-turn conversations.
+type MCPToolMetadata struct {
+	ServerName string `json:"server_name,omitempty"`
+	// Content is the raw content of an mcp_tool_result block.
+	Content json.RawMessage `json:"content,omitempty"`
+	IsError bool            `json:"is_error,omitempty"`
+}
+
+// Options implements the ProviderOptions interface.
+func (*MCPToolMetadata) Options() {}
+
+// MarshalJSON implements custom JSON marshaling with type info for MCPToolMetadata.
+func (m MCPToolMetadata) MarshalJSON() ([]byte, error) {
+	type plain MCPToolMetadata
+	return fantasy.MarshalProviderType(TypeMCPToolMetadata, plain(m))
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling with type info for MCPToolMetadata.
+func (m *MCPToolMetadata) UnmarshalJSON(data []byte) error {
+	type plain MCPToolMetadata
+	var p plain
+	if err := fantasy.UnmarshalProviderType(data, &p); err != nil {
+		return err
+	}
+	*m = MCPToolMetadata(p)
+	return nil
+}
+
 // CacheControl represents cache control settings for the Anthropic provider.
 type CacheControl struct {
 	Type string `json:"type"`
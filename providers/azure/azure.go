@@ -3,8 +3,11 @@ package azure
 
 import (
 	"fmt"
+	"net/http"
+	"net/url"
 	"regexp"
 	"strings"
+	"time"
 
 	"charm.land/fantasy"
 	"charm.land/fantasy/providers/openai"
@@ -109,6 +112,31 @@ func WithHTTPClient(client option.HTTPClient) Option {
 	}
 }
 
+// WithHTTPTimeout sets the overall timeout of each request, including
+// reading streamed responses. Use fantasy.WithStreamIdleTimeout to detect
+// stalled streams instead. It's ignored when WithHTTPClient is set.
+func WithHTTPTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.openaiOptions = append(o.openaiOptions, openai.WithHTTPTimeout(timeout))
+	}
+}
+
+// WithProxy sends the requests through the given proxy instead of the one
+// from the environment. It's ignored when WithHTTPClient is set.
+func WithProxy(proxy *url.URL) Option {
+	return func(o *options) {
+		o.openaiOptions = append(o.openaiOptions, openai.WithProxy(proxy))
+	}
+}
+
+// WithTransport sets the HTTP transport of the Azure provider. It's
+// ignored when WithHTTPClient is set.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *options) {
+		o.openaiOptions = append(o.openaiOptions, openai.WithTransport(transport))
+	}
+}
+
 // WithUserAgent sets an explicit User-Agent header, overriding the default and any
 // value set via WithHeaders.
 func WithUserAgent(ua string) Option {
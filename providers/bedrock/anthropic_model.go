@@ -0,0 +1,84 @@
+package bedrock
+
+import (
+	"context"
+	"errors"
+
+	"charm.land/fantasy"
+)
+
+// anthropicModel serves a Claude model, switching to the Anthropic provider
+// for the call's region when ProviderOptions.Region is set.
+type anthropicModel struct {
+	fantasy.LanguageModel
+	provider *provider
+}
+
+func (m anthropicModel) regional(ctx context.Context, providerOptions fantasy.ProviderOptions) (fantasy.LanguageModel, error) {
+	region := callRegion(providerOptions)
+	if region == "" || region == m.provider.options.region {
+		return m.LanguageModel, nil
+	}
+	p, err := m.provider.anthropicProvider(region)
+	if err != nil {
+		return nil, err
+	}
+	return p.LanguageModel(ctx, m.Model())
+}
+
+// Generate implements fantasy.LanguageModel.
+func (m anthropicModel) Generate(ctx context.Context, call fantasy.Call) (*fantasy.Response, error) {
+	model, err := m.regional(ctx, call.ProviderOptions)
+	if err != nil {
+		return nil, err
+	}
+	return model.Generate(ctx, call)
+}
+
+// Stream implements fantasy.LanguageModel.
+func (m anthropicModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.StreamResponse, error) {
+	model, err := m.regional(ctx, call.ProviderOptions)
+	if err != nil {
+		return nil, err
+	}
+	return model.Stream(ctx, call)
+}
+
+// GenerateObject implements fantasy.LanguageModel.
+func (m anthropicModel) GenerateObject(ctx context.Context, call fantasy.ObjectCall) (*fantasy.ObjectResponse, error) {
+	model, err := m.regional(ctx, call.ProviderOptions)
+	if err != nil {
+		return nil, err
+	}
+	return model.GenerateObject(ctx, call)
+}
+
+// StreamObject implements fantasy.LanguageModel.
+func (m anthropicModel) StreamObject(ctx context.Context, call fantasy.ObjectCall) (fantasy.ObjectStreamResponse, error) {
+	model, err := m.regional(ctx, call.ProviderOptions)
+	if err != nil {
+		return nil, err
+	}
+	return model.StreamObject(ctx, call)
+}
+
+// CountTokens implements fantasy.TokenCounter.
+func (m anthropicModel) CountTokens(ctx context.Context, call fantasy.Call) (int64, error) {
+	model, err := m.regional(ctx, call.ProviderOptions)
+	if err != nil {
+		return 0, err
+	}
+	counter, ok := model.(fantasy.TokenCounter)
+	if !ok {
+		return 0, errors.New("token counting is not supported")
+	}
+	return counter.CountTokens(ctx, call)
+}
+
+// callRegion returns the per-call region override, if any.
+func callRegion(providerOptions fantasy.ProviderOptions) string {
+	if v, ok := providerOptions[Name].(*ProviderOptions); ok {
+		return v.Region
+	}
+	return ""
+}
@@ -1,14 +1,42 @@
 // Package bedrock provides an implementation of the fantasy AI SDK for AWS Bedrock's language models.
+//
+// Claude models (anthropic.* model IDs and their inference profiles) are
+// served through the Anthropic Messages API on Bedrock. Every other model
+// family, such as Amazon Nova, Meta Llama, Mistral, DeepSeek or Cohere, is
+// served through the Bedrock Converse API.
 package bedrock
 
 import (
+	"context"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
 	"charm.land/fantasy"
 	"charm.land/fantasy/providers/anthropic"
+	"charm.land/fantasy/providers/internal/httpclient"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/charmbracelet/anthropic-sdk-go/option"
 )
 
 type options struct {
-	skipAuth         bool
+	apiKey     string
+	baseURL    string
+	region     string
+	headers    map[string]string
+	userAgent  string
+	client     option.HTTPClient
+	httpConfig httpclient.Config
+	skipAuth   bool
+	objectMode fantasy.ObjectMode
+
+	credentials aws.CredentialsProvider
+	role        *roleCredentials
+
 	anthropicOptions []anthropic.Option
 }
 
@@ -20,25 +48,57 @@ const (
 // Option defines a function that configures Bedrock provider options.
 type Option = func(*options)
 
+type provider struct {
+	options   options
+	anthropic fantasy.Provider
+
+	mu                sync.Mutex
+	regionalAnthropic map[string]fantasy.Provider
+}
+
 // New creates a new Bedrock provider with the given options.
 func New(opts ...Option) (fantasy.Provider, error) {
-	var o options
+	o := options{
+		headers:    map[string]string{},
+		objectMode: fantasy.ObjectModeAuto,
+	}
 	for _, opt := range opts {
 		opt(&o)
 	}
-	return anthropic.New(
-		append(
-			o.anthropicOptions,
-			anthropic.WithName(Name),
-			anthropic.WithBedrock(),
-			anthropic.WithSkipAuth(o.skipAuth),
-		)...,
+	if o.role != nil {
+		o.role.region = o.region
+		o.credentials = aws.NewCredentialsCache(o.role)
+	}
+	p := &provider{
+		options:           o,
+		regionalAnthropic: map[string]fantasy.Provider{},
+	}
+	var err error
+	p.anthropic, err = p.newAnthropic(o.region)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *provider) newAnthropic(region string) (fantasy.Provider, error) {
+	opts := append(
+		slices.Clone(p.options.anthropicOptions),
+		anthropic.WithName(Name),
+		anthropic.WithBedrock(),
+		anthropic.WithBedrockRegion(region),
+		anthropic.WithSkipAuth(p.options.skipAuth),
 	)
+	if p.options.credentials != nil {
+		opts = append(opts, anthropic.WithBedrockCredentials(p.options.credentials))
+	}
+	return anthropic.New(opts...)
 }
 
 // WithAPIKey sets the access token for the Bedrock provider.
 func WithAPIKey(apiKey string) Option {
 	return func(o *options) {
+		o.apiKey = apiKey
 		o.anthropicOptions = append(o.anthropicOptions, anthropic.WithAPIKey(apiKey))
 	}
 }
@@ -46,6 +106,9 @@ func WithAPIKey(apiKey string) Option {
 // WithHeaders sets the headers for the Bedrock provider.
 func WithHeaders(headers map[string]string) Option {
 	return func(o *options) {
+		for k, v := range headers {
+			o.headers[k] = v
+		}
 		o.anthropicOptions = append(o.anthropicOptions, anthropic.WithHeaders(headers))
 	}
 }
@@ -53,14 +116,44 @@ func WithHeaders(headers map[string]string) Option {
 // WithHTTPClient sets the HTTP client for the Bedrock provider.
 func WithHTTPClient(client option.HTTPClient) Option {
 	return func(o *options) {
+		o.client = client
 		o.anthropicOptions = append(o.anthropicOptions, anthropic.WithHTTPClient(client))
 	}
 }
 
+// WithHTTPTimeout sets the overall timeout of each request, including
+// reading streamed responses. Use fantasy.WithStreamIdleTimeout to detect
+// stalled streams instead. It's ignored when WithHTTPClient is set.
+func WithHTTPTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.httpConfig.Timeout = timeout
+		o.anthropicOptions = append(o.anthropicOptions, anthropic.WithHTTPTimeout(timeout))
+	}
+}
+
+// WithProxy sends the requests through the given proxy instead of the one
+// from the environment. It's ignored when WithHTTPClient is set.
+func WithProxy(proxy *url.URL) Option {
+	return func(o *options) {
+		o.httpConfig.Proxy = proxy
+		o.anthropicOptions = append(o.anthropicOptions, anthropic.WithProxy(proxy))
+	}
+}
+
+// WithTransport sets the HTTP transport of the Bedrock provider. It's
+// ignored when WithHTTPClient is set.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *options) {
+		o.httpConfig.Transport = transport
+		o.anthropicOptions = append(o.anthropicOptions, anthropic.WithTransport(transport))
+	}
+}
+
 // WithUserAgent sets an explicit User-Agent header, overriding the default and any
 // value set via WithHeaders.
 func WithUserAgent(ua string) Option {
 	return func(o *options) {
+		o.userAgent = ua
 		o.anthropicOptions = append(o.anthropicOptions, anthropic.WithUserAgent(ua))
 	}
 }
@@ -68,6 +161,7 @@ func WithUserAgent(ua string) Option {
 // WithBaseURL sets the base URL for the Bedrock provider.
 func WithBaseURL(baseURL string) Option {
 	return func(o *options) {
+		o.baseURL = baseURL
 		o.anthropicOptions = append(o.anthropicOptions, anthropic.WithBaseURL(baseURL))
 	}
 }
@@ -82,6 +176,90 @@ func WithSkipAuth(skipAuth bool) Option {
 // WithRegion sets the AWS region for the Bedrock provider.
 func WithRegion(region string) Option {
 	return func(o *options) {
-		o.anthropicOptions = append(o.anthropicOptions, anthropic.WithBedrockRegion(region))
+		o.region = region
+	}
+}
+
+// WithCredentials sets the AWS credentials used to sign requests, instead of
+// the default credential chain.
+func WithCredentials(credentials aws.CredentialsProvider) Option {
+	return func(o *options) {
+		o.credentials = credentials
+	}
+}
+
+// WithAssumeRole signs requests with temporary credentials for the given IAM
+// role, assumed with the default credential chain. Use it to reach Bedrock in
+// another account.
+func WithAssumeRole(roleARN string, optFns ...func(*stscreds.AssumeRoleOptions)) Option {
+	return func(o *options) {
+		o.role = &roleCredentials{roleARN: roleARN, assumeRoleOptions: optFns}
+	}
+}
+
+// WithWebIdentityRole signs requests with temporary credentials for the
+// given IAM role, assumed with the OIDC token in tokenFile, as on EKS or in
+// CI runners.
+func WithWebIdentityRole(roleARN, tokenFile string, optFns ...func(*stscreds.WebIdentityRoleOptions)) Option {
+	return func(o *options) {
+		o.role = &roleCredentials{roleARN: roleARN, tokenFile: tokenFile, webIdentityOptions: optFns}
+	}
+}
+
+// WithObjectMode sets the object generation mode for models served through
+// the Converse API. ObjectModeJSON is converted to ObjectModeTool since
+// Converse has no JSON response format.
+func WithObjectMode(om fantasy.ObjectMode) Option {
+	return func(o *options) {
+		o.objectMode = om
+		o.anthropicOptions = append(o.anthropicOptions, anthropic.WithObjectMode(om))
+	}
+}
+
+// Name implements fantasy.Provider.
+func (p *provider) Name() string {
+	return Name
+}
+
+// LanguageModel implements fantasy.Provider.
+func (p *provider) LanguageModel(ctx context.Context, modelID string) (fantasy.LanguageModel, error) {
+	if isAnthropicModel(modelID) {
+		model, err := p.anthropic.LanguageModel(ctx, modelID)
+		if err != nil {
+			return nil, err
+		}
+		return anthropicModel{LanguageModel: model, provider: p}, nil
+	}
+	return p.converseModel(ctx, modelID)
+}
+
+// anthropicProvider returns the Anthropic provider for the given region,
+// creating it on first use.
+func (p *provider) anthropicProvider(region string) (fantasy.Provider, error) {
+	if region == "" || region == p.options.region {
+		return p.anthropic, nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if regional, ok := p.regionalAnthropic[region]; ok {
+		return regional, nil
+	}
+	regional, err := p.newAnthropic(region)
+	if err != nil {
+		return nil, err
+	}
+	p.regionalAnthropic[region] = regional
+	return regional, nil
+}
+
+// isAnthropicModel reports whether the model ID names a Claude model. Model
+// IDs, cross-region inference profiles such as
+// "us.anthropic.claude-sonnet-4-20250514-v1:0" and their ARNs are all
+// recognized. Application inference profile ARNs don't name the model, so
+// they are served through Converse, which supports Claude as well.
+func isAnthropicModel(modelID string) bool {
+	if strings.HasPrefix(modelID, "arn:") {
+		_, modelID, _ = strings.Cut(modelID, "/")
 	}
+	return strings.HasPrefix(modelID, "anthropic.") || strings.Contains(modelID, ".anthropic.")
 }
@@ -0,0 +1,47 @@
+package bedrock
+
+import (
+	"context"
+	"net/http"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/internal/httpheaders"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+)
+
+type callUAKey struct{}
+
+type callHeadersKey struct{}
+
+func withCallUA(ctx context.Context, call fantasy.Call) context.Context {
+	if ua, ok := httpheaders.CallUserAgent(call.UserAgent); ok {
+		ctx = context.WithValue(ctx, callUAKey{}, ua)
+	}
+	if headers, ok := httpheaders.CallHeaders(call.Headers); ok {
+		ctx = context.WithValue(ctx, callHeadersKey{}, headers)
+	}
+	return ctx
+}
+
+// headerClient sets the provider-level headers, and any per-call overrides,
+// on every Converse request. The AWS SDK doesn't sign the User-Agent header,
+// so replacing it after signing is safe.
+type headerClient struct {
+	client  bedrockruntime.HTTPClient
+	headers map[string]string
+}
+
+func (c *headerClient) Do(req *http.Request) (*http.Response, error) {
+	for k, v := range c.headers {
+		req.Header.Set(k, v)
+	}
+	if ua, ok := req.Context().Value(callUAKey{}).(string); ok && ua != "" {
+		req.Header.Set("User-Agent", ua)
+	}
+	if headers, ok := req.Context().Value(callHeadersKey{}).(map[string]string); ok {
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+	}
+	return c.client.Do(req)
+}
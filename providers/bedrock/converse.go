@@ -0,0 +1,805 @@
+package bedrock
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"regexp"
+	"strings"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/object"
+	"charm.land/fantasy/providers/internal/httpheaders"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/document"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/aws/smithy-go/auth/bearer"
+)
+
+type languageModel struct {
+	modelID    string
+	provider   string
+	client     *bedrockruntime.Client
+	objectMode fantasy.ObjectMode
+}
+
+// converseParams holds the fields shared by Converse and ConverseStream
+// requests.
+type converseParams struct {
+	messages         []types.Message
+	system           []types.SystemContentBlock
+	inferenceConfig  *types.InferenceConfiguration
+	toolConfig       *types.ToolConfiguration
+	additionalFields document.Interface
+	region           string
+}
+
+// clientOptions applies the per-call region override, if any.
+func (p *converseParams) clientOptions() []func(*bedrockruntime.Options) {
+	if p.region == "" {
+		return nil
+	}
+	return []func(*bedrockruntime.Options){func(o *bedrockruntime.Options) {
+		o.Region = p.region
+	}}
+}
+
+func (p *provider) converseModel(ctx context.Context, modelID string) (fantasy.LanguageModel, error) {
+	cfg, err := p.awsConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var httpClient bedrockruntime.HTTPClient = p.options.httpConfig.Client()
+	if p.options.client != nil {
+		httpClient = p.options.client
+	}
+	defaultUA := httpheaders.DefaultUserAgent(fantasy.Version)
+	client := bedrockruntime.NewFromConfig(cfg, func(o *bedrockruntime.Options) {
+		if p.options.baseURL != "" {
+			o.BaseEndpoint = aws.String(p.options.baseURL)
+		}
+		o.HTTPClient = &headerClient{
+			client:  httpClient,
+			headers: httpheaders.ResolveHeaders(p.options.headers, p.options.userAgent, defaultUA),
+		}
+	})
+
+	objectMode := p.options.objectMode
+	if objectMode == fantasy.ObjectModeJSON || objectMode == "" {
+		objectMode = fantasy.ObjectModeTool
+	}
+
+	return languageModel{
+		modelID:    modelID,
+		provider:   Name,
+		client:     client,
+		objectMode: objectMode,
+	}, nil
+}
+
+func (p *provider) awsConfig(ctx context.Context) (aws.Config, error) {
+	if p.options.skipAuth || p.options.apiKey != "" {
+		return aws.Config{
+			Region:                  cmp.Or(p.options.region, "us-east-1"),
+			BearerAuthTokenProvider: bearer.StaticTokenProvider{Token: bearer.Token{Value: p.options.apiKey}},
+			AuthSchemePreference:    []string{"httpBearerAuth"},
+		}, nil
+	}
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return aws.Config{}, err
+	}
+	cfg.Region = cmp.Or(p.options.region, cfg.Region, "us-east-1")
+	return cfg, nil
+}
+
+// Model implements fantasy.LanguageModel.
+func (m languageModel) Model() string {
+	return m.modelID
+}
+
+// Provider implements fantasy.LanguageModel.
+func (m languageModel) Provider() string {
+	return m.provider
+}
+
+// SupportsPDF implements fantasy.PDFModel.
+func (m languageModel) SupportsPDF() bool {
+	return true
+}
+
+func (m languageModel) prepareParams(call fantasy.Call) (*converseParams, []fantasy.CallWarning, error) {
+	providerOptions := &ProviderOptions{}
+	if v, ok := call.ProviderOptions[Name]; ok {
+		providerOptions, ok = v.(*ProviderOptions)
+		if !ok {
+			return nil, nil, &fantasy.Error{Title: "invalid argument", Message: "bedrock provider options should be *bedrock.ProviderOptions"}
+		}
+	}
+
+	params := &converseParams{region: providerOptions.Region}
+	var warnings []fantasy.CallWarning
+
+	params.system, params.messages, warnings = toPrompt(call.Prompt)
+	if err := fantasy.CheckDroppedContent(call, warnings); err != nil {
+		return nil, nil, err
+	}
+
+	var inferenceConfig types.InferenceConfiguration
+	hasInferenceConfig := false
+	if call.MaxOutputTokens != nil {
+		inferenceConfig.MaxTokens = aws.Int32(int32(*call.MaxOutputTokens))
+		hasInferenceConfig = true
+	}
+	if call.Temperature != nil {
+		inferenceConfig.Temperature = aws.Float32(float32(*call.Temperature))
+		hasInferenceConfig = true
+	}
+	if call.TopP != nil {
+		inferenceConfig.TopP = aws.Float32(float32(*call.TopP))
+		hasInferenceConfig = true
+	}
+	if hasInferenceConfig {
+		params.inferenceConfig = &inferenceConfig
+	}
+
+	if call.TopK != nil {
+		warnings = append(warnings, fantasy.CallWarning{
+			Type:    fantasy.CallWarningTypeUnsupportedSetting,
+			Setting: "TopK",
+			Details: "top_k is model specific on Bedrock, set it through AdditionalModelRequestFields",
+		})
+	}
+	if call.PresencePenalty != nil {
+		warnings = append(warnings, fantasy.CallWarning{
+			Type:    fantasy.CallWarningTypeUnsupportedSetting,
+			Setting: "PresencePenalty",
+		})
+	}
+	if call.FrequencyPenalty != nil {
+		warnings = append(warnings, fantasy.CallWarning{
+			Type:    fantasy.CallWarningTypeUnsupportedSetting,
+			Setting: "FrequencyPenalty",
+		})
+	}
+	if call.OutputConstraint != nil {
+		warnings = append(warnings, fantasy.UnsupportedOutputConstraintWarning(call.OutputConstraint))
+	}
+
+	additionalFields := maps.Clone(providerOptions.AdditionalModelRequestFields)
+	if providerOptions.ReasoningConfig != nil {
+		if additionalFields == nil {
+			additionalFields = map[string]any{}
+		}
+		additionalFields["reasoningConfig"] = providerOptions.ReasoningConfig
+	}
+	if len(call.ExtraBody) > 0 {
+		if additionalFields == nil {
+			additionalFields = map[string]any{}
+		}
+		maps.Copy(additionalFields, call.ExtraBody)
+	}
+	if len(additionalFields) > 0 {
+		params.additionalFields = document.NewLazyDocument(additionalFields)
+	}
+
+	if len(call.Tools) > 0 {
+		toolConfig, toolWarnings := toTools(call.Tools, call.ToolChoice)
+		params.toolConfig = toolConfig
+		warnings = append(warnings, toolWarnings...)
+	}
+
+	return params, warnings, nil
+}
+
+func toTools(tools []fantasy.Tool, toolChoice *fantasy.ToolChoice) (*types.ToolConfiguration, []fantasy.CallWarning) {
+	var warnings []fantasy.CallWarning
+	if toolChoice != nil && *toolChoice == fantasy.ToolChoiceNone {
+		// Converse has no "none" tool choice, so the tools are dropped.
+		return nil, nil
+	}
+
+	toolConfig := &types.ToolConfiguration{}
+	for _, tool := range tools {
+		ft, ok := tool.(fantasy.FunctionTool)
+		if !ok {
+			warnings = append(warnings, fantasy.CallWarning{
+				Type:    fantasy.CallWarningTypeUnsupportedTool,
+				Tool:    tool,
+				Message: "tool is not supported",
+			})
+			continue
+		}
+		spec := types.ToolSpecification{
+			Name: aws.String(ft.Name),
+			InputSchema: &types.ToolInputSchemaMemberJson{
+				Value: document.NewLazyDocument(ft.InputSchema),
+			},
+		}
+		if ft.Description != "" {
+			spec.Description = aws.String(ft.Description)
+		}
+		toolConfig.Tools = append(toolConfig.Tools, &types.ToolMemberToolSpec{Value: spec})
+	}
+	if len(toolConfig.Tools) == 0 {
+		return nil, warnings
+	}
+
+	if toolChoice != nil {
+		switch *toolChoice {
+		case fantasy.ToolChoiceAuto:
+			toolConfig.ToolChoice = &types.ToolChoiceMemberAuto{}
+		case fantasy.ToolChoiceRequired:
+			toolConfig.ToolChoice = &types.ToolChoiceMemberAny{}
+		default:
+			toolConfig.ToolChoice = &types.ToolChoiceMemberTool{
+				Value: types.SpecificToolChoice{Name: aws.String(string(*toolChoice))},
+			}
+		}
+	}
+	return toolConfig, warnings
+}
+
+func toPrompt(prompt fantasy.Prompt) ([]types.SystemContentBlock, []types.Message, []fantasy.CallWarning) {
+	var system []types.SystemContentBlock
+	var messages []types.Message
+	var warnings []fantasy.CallWarning
+	documents := 0
+
+	// Converse requires alternating roles, so tool results are merged into
+	// the surrounding user turn.
+	appendMessage := func(role types.ConversationRole, content []types.ContentBlock) {
+		if len(content) == 0 {
+			return
+		}
+		if n := len(messages); n > 0 && messages[n-1].Role == role {
+			messages[n-1].Content = append(messages[n-1].Content, content...)
+			return
+		}
+		messages = append(messages, types.Message{Role: role, Content: content})
+	}
+
+	for _, msg := range prompt {
+		switch msg.Role {
+		case fantasy.MessageRoleSystem:
+			for _, part := range msg.Content {
+				text, ok := fantasy.AsMessagePart[fantasy.TextPart](part)
+				if !ok || text.Text == "" {
+					continue
+				}
+				system = append(system, &types.SystemContentBlockMemberText{Value: text.Text})
+			}
+		case fantasy.MessageRoleUser:
+			var content []types.ContentBlock
+			for _, part := range msg.Content {
+				switch part.GetType() {
+				case fantasy.ContentTypeText:
+					text, ok := fantasy.AsMessagePart[fantasy.TextPart](part)
+					if !ok || text.Text == "" {
+						continue
+					}
+					content = append(content, &types.ContentBlockMemberText{Value: text.Text})
+				case fantasy.ContentTypeFile:
+					file, ok := fantasy.AsMessagePart[fantasy.FilePart](part)
+					if !ok {
+						continue
+					}
+					if format, ok := imageFormat(file.MediaType); ok {
+						content = append(content, &types.ContentBlockMemberImage{Value: types.ImageBlock{
+							Format: format,
+							Source: &types.ImageSourceMemberBytes{Value: file.Data},
+						}})
+						continue
+					}
+					if format, ok := documentFormat(file.MediaType); ok {
+						documents++
+						content = append(content, &types.ContentBlockMemberDocument{Value: types.DocumentBlock{
+							Name:   aws.String(documentName(file.Filename, documents)),
+							Format: format,
+							Source: &types.DocumentSourceMemberBytes{Value: file.Data},
+						}})
+						continue
+					}
+					warnings = append(warnings, fantasy.CallWarning{
+						Type:    fantasy.CallWarningTypeDroppedContent,
+						Message: fmt.Sprintf("file part media type %s not supported", file.MediaType),
+					})
+				}
+			}
+			appendMessage(types.ConversationRoleUser, content)
+		case fantasy.MessageRoleTool:
+			var content []types.ContentBlock
+			for _, part := range msg.Content {
+				result, ok := fantasy.AsMessagePart[fantasy.ToolResultPart](part)
+				if !ok {
+					continue
+				}
+				block, warning := toToolResult(result)
+				if warning != nil {
+					warnings = append(warnings, *warning)
+				}
+				content = append(content, &types.ContentBlockMemberToolResult{Value: block})
+			}
+			appendMessage(types.ConversationRoleUser, content)
+		case fantasy.MessageRoleAssistant:
+			var content []types.ContentBlock
+			for _, part := range msg.Content {
+				switch part.GetType() {
+				case fantasy.ContentTypeText:
+					text, ok := fantasy.AsMessagePart[fantasy.TextPart](part)
+					if !ok || text.Text == "" {
+						continue
+					}
+					content = append(content, &types.ContentBlockMemberText{Value: text.Text})
+				case fantasy.ContentTypeReasoning:
+					reasoning, ok := fantasy.AsMessagePart[fantasy.ReasoningPart](part)
+					if !ok {
+						continue
+					}
+					metadata := GetReasoningMetadata(reasoning.ProviderOptions)
+					switch {
+					case metadata != nil && len(metadata.RedactedData) > 0:
+						content = append(content, &types.ContentBlockMemberReasoningContent{
+							Value: &types.ReasoningContentBlockMemberRedactedContent{Value: metadata.RedactedData},
+						})
+					case metadata != nil && metadata.Signature != "":
+						content = append(content, &types.ContentBlockMemberReasoningContent{
+							Value: &types.ReasoningContentBlockMemberReasoningText{Value: types.ReasoningTextBlock{
+								Text:      aws.String(reasoning.Text),
+								Signature: aws.String(metadata.Signature),
+							}},
+						})
+					default:
+						// Unsigned reasoning can't be sent back to the model.
+						warnings = append(warnings, fantasy.CallWarning{
+							Type:    fantasy.CallWarningTypeDroppedContent,
+							Message: "dropping reasoning part without a signature",
+						})
+					}
+				case fantasy.ContentTypeToolCall:
+					toolCall, ok := fantasy.AsMessagePart[fantasy.ToolCallPart](part)
+					if !ok || toolCall.ProviderExecuted {
+						continue
+					}
+					var input any
+					if err := json.Unmarshal([]byte(toolCall.Input), &input); err != nil || input == nil {
+						input = map[string]any{}
+					}
+					content = append(content, &types.ContentBlockMemberToolUse{Value: types.ToolUseBlock{
+						ToolUseId: aws.String(toolCall.ToolCallID),
+						Name:      aws.String(toolCall.ToolName),
+						Input:     document.NewLazyDocument(input),
+					}})
+				}
+			}
+			appendMessage(types.ConversationRoleAssistant, content)
+		}
+	}
+	return system, messages, warnings
+}
+
+func toToolResult(result fantasy.ToolResultPart) (types.ToolResultBlock, *fantasy.CallWarning) {
+	block := types.ToolResultBlock{ToolUseId: aws.String(result.ToolCallID)}
+	switch result.Output.GetType() {
+	case fantasy.ToolResultContentTypeText:
+		content, _ := fantasy.AsToolResultOutputType[fantasy.ToolResultOutputContentText](result.Output)
+		block.Content = []types.ToolResultContentBlock{
+			&types.ToolResultContentBlockMemberText{Value: content.Text},
+		}
+	case fantasy.ToolResultContentTypeError:
+		content, _ := fantasy.AsToolResultOutputType[fantasy.ToolResultOutputContentError](result.Output)
+		message := ""
+		if content.Error != nil {
+			message = content.Error.Error()
+		}
+		block.Content = []types.ToolResultContentBlock{
+			&types.ToolResultContentBlockMemberText{Value: message},
+		}
+		block.Status = types.ToolResultStatusError
+	case fantasy.ToolResultContentTypeMedia:
+		content, _ := fantasy.AsToolResultOutputType[fantasy.ToolResultOutputContentMedia](result.Output)
+		format, ok := imageFormat(content.MediaType)
+		data, err := base64.StdEncoding.DecodeString(content.Data)
+		if !ok || err != nil {
+			block.Content = []types.ToolResultContentBlock{
+				&types.ToolResultContentBlockMemberText{Value: content.Text},
+			}
+			return block, &fantasy.CallWarning{
+				Type:    fantasy.CallWarningTypeDroppedContent,
+				Message: fmt.Sprintf("tool result media type %s not supported", content.MediaType),
+			}
+		}
+		block.Content = []types.ToolResultContentBlock{
+			&types.ToolResultContentBlockMemberImage{Value: types.ImageBlock{
+				Format: format,
+				Source: &types.ImageSourceMemberBytes{Value: data},
+			}},
+		}
+		if content.Text != "" {
+			block.Content = append(block.Content, &types.ToolResultContentBlockMemberText{Value: content.Text})
+		}
+	}
+	return block, nil
+}
+
+func imageFormat(mediaType string) (types.ImageFormat, bool) {
+	switch mediaType {
+	case "image/png":
+		return types.ImageFormatPng, true
+	case "image/jpeg", "image/jpg":
+		return types.ImageFormatJpeg, true
+	case "image/gif":
+		return types.ImageFormatGif, true
+	case "image/webp":
+		return types.ImageFormatWebp, true
+	}
+	return "", false
+}
+
+func documentFormat(mediaType string) (types.DocumentFormat, bool) {
+	switch mediaType {
+	case "application/pdf":
+		return types.DocumentFormatPdf, true
+	case "text/csv":
+		return types.DocumentFormatCsv, true
+	case "application/msword":
+		return types.DocumentFormatDoc, true
+	case "application/vnd.openxmlformats-officedocument.wordprocessingml.document":
+		return types.DocumentFormatDocx, true
+	case "application/vnd.ms-excel":
+		return types.DocumentFormatXls, true
+	case "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":
+		return types.DocumentFormatXlsx, true
+	case "text/html":
+		return types.DocumentFormatHtml, true
+	case "text/plain":
+		return types.DocumentFormatTxt, true
+	case "text/markdown":
+		return types.DocumentFormatMd, true
+	}
+	return "", false
+}
+
+var (
+	documentNameInvalid    = regexp.MustCompile(`[^a-zA-Z0-9\s\-()\[\]]`)
+	documentNameWhitespace = regexp.MustCompile(`\s+`)
+)
+
+// documentName derives a document name Converse accepts: alphanumerics,
+// single spaces, hyphens, parentheses and square brackets only.
+func documentName(filename string, n int) string {
+	if i := strings.LastIndex(filename, "."); i > 0 {
+		filename = filename[:i]
+	}
+	name := documentNameInvalid.ReplaceAllString(filename, "-")
+	name = strings.TrimSpace(documentNameWhitespace.ReplaceAllString(name, " "))
+	if name == "" {
+		return fmt.Sprintf("document-%d", n)
+	}
+	return name
+}
+
+// Generate implements fantasy.LanguageModel.
+func (m languageModel) Generate(ctx context.Context, call fantasy.Call) (*fantasy.Response, error) {
+	ctx = withCallUA(ctx, call)
+	params, warnings, err := m.prepareParams(call)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := m.client.Converse(ctx, &bedrockruntime.ConverseInput{
+		ModelId:                      aws.String(m.modelID),
+		Messages:                     params.messages,
+		System:                       params.system,
+		InferenceConfig:              params.inferenceConfig,
+		ToolConfig:                   params.toolConfig,
+		AdditionalModelRequestFields: params.additionalFields,
+	}, params.clientOptions()...)
+	if err != nil {
+		return nil, toProviderErr(err)
+	}
+
+	var content []fantasy.Content
+	if output, ok := response.Output.(*types.ConverseOutputMemberMessage); ok {
+		for _, block := range output.Value.Content {
+			switch block := block.(type) {
+			case *types.ContentBlockMemberText:
+				content = append(content, fantasy.TextContent{Text: block.Value})
+			case *types.ContentBlockMemberReasoningContent:
+				switch reasoning := block.Value.(type) {
+				case *types.ReasoningContentBlockMemberReasoningText:
+					reasoningContent := fantasy.ReasoningContent{Text: aws.ToString(reasoning.Value.Text)}
+					if signature := aws.ToString(reasoning.Value.Signature); signature != "" {
+						reasoningContent.ProviderMetadata = fantasy.ProviderMetadata{
+							Name: &ReasoningOptionMetadata{Signature: signature},
+						}
+					}
+					content = append(content, reasoningContent)
+				case *types.ReasoningContentBlockMemberRedactedContent:
+					content = append(content, fantasy.ReasoningContent{
+						ProviderMetadata: fantasy.ProviderMetadata{
+							Name: &ReasoningOptionMetadata{RedactedData: reasoning.Value},
+						},
+					})
+				}
+			case *types.ContentBlockMemberToolUse:
+				input := "{}"
+				if block.Value.Input != nil {
+					data, err := block.Value.Input.MarshalSmithyDocument()
+					if err != nil {
+						return nil, err
+					}
+					input = string(data)
+				}
+				content = append(content, fantasy.ToolCallContent{
+					ToolCallID: aws.ToString(block.Value.ToolUseId),
+					ToolName:   aws.ToString(block.Value.Name),
+					Input:      input,
+				})
+			}
+		}
+	}
+
+	return &fantasy.Response{
+		Content:          content,
+		Usage:            mapUsage(response.Usage),
+		FinishReason:     mapFinishReason(response.StopReason),
+		ProviderMetadata: fantasy.ProviderMetadata{},
+		Warnings:         warnings,
+	}, nil
+}
+
+type streamBlock struct {
+	kind      fantasy.ContentType
+	id        string
+	toolName  string
+	input     strings.Builder
+	signature string
+	redacted  []byte
+}
+
+// Stream implements fantasy.LanguageModel.
+func (m languageModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.StreamResponse, error) {
+	ctx = withCallUA(ctx, call)
+	params, warnings, err := m.prepareParams(call)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := m.client.ConverseStream(ctx, &bedrockruntime.ConverseStreamInput{
+		ModelId:                      aws.String(m.modelID),
+		Messages:                     params.messages,
+		System:                       params.system,
+		InferenceConfig:              params.inferenceConfig,
+		ToolConfig:                   params.toolConfig,
+		AdditionalModelRequestFields: params.additionalFields,
+	}, params.clientOptions()...)
+	if err != nil {
+		return nil, toProviderErr(err)
+	}
+
+	return func(yield func(fantasy.StreamPart) bool) {
+		stream := response.GetStream()
+		defer stream.Close()
+
+		if len(warnings) > 0 {
+			if !yield(fantasy.StreamPart{
+				Type:     fantasy.StreamPartTypeWarnings,
+				Warnings: warnings,
+			}) {
+				return
+			}
+		}
+
+		blocks := map[int32]*streamBlock{}
+		var usage fantasy.Usage
+		var finishReason fantasy.FinishReason
+
+		// startBlock lazily opens text and reasoning blocks, which Converse
+		// starts with their first delta instead of a start event.
+		startBlock := func(index int32, kind fantasy.ContentType) (*streamBlock, bool) {
+			if block, ok := blocks[index]; ok {
+				return block, true
+			}
+			block := &streamBlock{kind: kind, id: fmt.Sprintf("%d", index)}
+			blocks[index] = block
+			partType := fantasy.StreamPartTypeTextStart
+			if kind == fantasy.ContentTypeReasoning {
+				partType = fantasy.StreamPartTypeReasoningStart
+			}
+			return block, yield(fantasy.StreamPart{Type: partType, ID: block.id})
+		}
+
+		for event := range stream.Events() {
+			switch event := event.(type) {
+			case *types.ConverseStreamOutputMemberContentBlockStart:
+				index := aws.ToInt32(event.Value.ContentBlockIndex)
+				start, ok := event.Value.Start.(*types.ContentBlockStartMemberToolUse)
+				if !ok {
+					continue
+				}
+				block := &streamBlock{
+					kind:     fantasy.ContentTypeToolCall,
+					id:       aws.ToString(start.Value.ToolUseId),
+					toolName: aws.ToString(start.Value.Name),
+				}
+				blocks[index] = block
+				if !yield(fantasy.StreamPart{
+					Type:         fantasy.StreamPartTypeToolInputStart,
+					ID:           block.id,
+					ToolCallName: block.toolName,
+				}) {
+					return
+				}
+			case *types.ConverseStreamOutputMemberContentBlockDelta:
+				index := aws.ToInt32(event.Value.ContentBlockIndex)
+				switch delta := event.Value.Delta.(type) {
+				case *types.ContentBlockDeltaMemberText:
+					block, ok := startBlock(index, fantasy.ContentTypeText)
+					if !ok || !yield(fantasy.StreamPart{
+						Type:  fantasy.StreamPartTypeTextDelta,
+						ID:    block.id,
+						Delta: delta.Value,
+					}) {
+						return
+					}
+				case *types.ContentBlockDeltaMemberReasoningContent:
+					block, ok := startBlock(index, fantasy.ContentTypeReasoning)
+					if !ok {
+						return
+					}
+					switch reasoning := delta.Value.(type) {
+					case *types.ReasoningContentBlockDeltaMemberText:
+						if !yield(fantasy.StreamPart{
+							Type:  fantasy.StreamPartTypeReasoningDelta,
+							ID:    block.id,
+							Delta: reasoning.Value,
+						}) {
+							return
+						}
+					case *types.ReasoningContentBlockDeltaMemberSignature:
+						block.signature += reasoning.Value
+					case *types.ReasoningContentBlockDeltaMemberRedactedContent:
+						block.redacted = append(block.redacted, reasoning.Value...)
+					}
+				case *types.ContentBlockDeltaMemberToolUse:
+					block, ok := blocks[index]
+					if !ok {
+						continue
+					}
+					input := aws.ToString(delta.Value.Input)
+					block.input.WriteString(input)
+					if !yield(fantasy.StreamPart{
+						Type:  fantasy.StreamPartTypeToolInputDelta,
+						ID:    block.id,
+						Delta: input,
+					}) {
+						return
+					}
+				}
+			case *types.ConverseStreamOutputMemberContentBlockStop:
+				index := aws.ToInt32(event.Value.ContentBlockIndex)
+				block, ok := blocks[index]
+				if !ok {
+					continue
+				}
+				delete(blocks, index)
+				if !yieldBlockEnd(block, yield) {
+					return
+				}
+			case *types.ConverseStreamOutputMemberMessageStop:
+				finishReason = mapFinishReason(event.Value.StopReason)
+			case *types.ConverseStreamOutputMemberMetadata:
+				usage = mapUsage(event.Value.Usage)
+			}
+		}
+
+		if err := stream.Err(); err != nil {
+			yield(fantasy.StreamPart{
+				Type:  fantasy.StreamPartTypeError,
+				Error: toProviderErr(err),
+			})
+			return
+		}
+		if finishReason == "" {
+			// Truncated stream: the messageStop event never arrived.
+			yield(fantasy.StreamPart{
+				Type:  fantasy.StreamPartTypeError,
+				Error: fantasy.NewIncompleteStreamError(),
+			})
+			return
+		}
+
+		yield(fantasy.StreamPart{
+			Type:             fantasy.StreamPartTypeFinish,
+			Usage:            usage,
+			FinishReason:     finishReason,
+			ProviderMetadata: fantasy.ProviderMetadata{},
+		})
+	}, nil
+}
+
+func yieldBlockEnd(block *streamBlock, yield func(fantasy.StreamPart) bool) bool {
+	switch block.kind {
+	case fantasy.ContentTypeText:
+		return yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeTextEnd, ID: block.id})
+	case fantasy.ContentTypeReasoning:
+		part := fantasy.StreamPart{Type: fantasy.StreamPartTypeReasoningEnd, ID: block.id}
+		if block.signature != "" || len(block.redacted) > 0 {
+			part.ProviderMetadata = fantasy.ProviderMetadata{
+				Name: &ReasoningOptionMetadata{Signature: block.signature, RedactedData: block.redacted},
+			}
+		}
+		return yield(part)
+	case fantasy.ContentTypeToolCall:
+		input := cmp.Or(block.input.String(), "{}")
+		if !yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeToolInputEnd, ID: block.id}) {
+			return false
+		}
+		return yield(fantasy.StreamPart{
+			Type:          fantasy.StreamPartTypeToolCall,
+			ID:            block.id,
+			ToolCallName:  block.toolName,
+			ToolCallInput: input,
+		})
+	}
+	return true
+}
+
+// GenerateObject implements fantasy.LanguageModel.
+func (m languageModel) GenerateObject(ctx context.Context, call fantasy.ObjectCall) (*fantasy.ObjectResponse, error) {
+	switch m.objectMode {
+	case fantasy.ObjectModeText:
+		return object.GenerateWithText(ctx, m, call)
+	default:
+		return object.GenerateWithTool(ctx, m, call)
+	}
+}
+
+// StreamObject implements fantasy.LanguageModel.
+func (m languageModel) StreamObject(ctx context.Context, call fantasy.ObjectCall) (fantasy.ObjectStreamResponse, error) {
+	switch m.objectMode {
+	case fantasy.ObjectModeText:
+		return object.StreamWithText(ctx, m, call)
+	default:
+		return object.StreamWithTool(ctx, m, call)
+	}
+}
+
+func mapFinishReason(reason types.StopReason) fantasy.FinishReason {
+	switch reason {
+	case types.StopReasonEndTurn, types.StopReasonStopSequence:
+		return fantasy.FinishReasonStop
+	case types.StopReasonToolUse:
+		return fantasy.FinishReasonToolCalls
+	case types.StopReasonMaxTokens, types.StopReasonModelContextWindowExceeded:
+		return fantasy.FinishReasonLength
+	case types.StopReasonGuardrailIntervened, types.StopReasonContentFiltered:
+		return fantasy.FinishReasonContentFilter
+	case types.StopReasonMalformedModelOutput, types.StopReasonMalformedToolUse:
+		return fantasy.FinishReasonError
+	case "":
+		return fantasy.FinishReasonUnknown
+	default:
+		return fantasy.FinishReasonOther
+	}
+}
+
+func mapUsage(usage *types.TokenUsage) fantasy.Usage {
+	if usage == nil {
+		return fantasy.Usage{}
+	}
+	return fantasy.Usage{
+		InputTokens:         int64(aws.ToInt32(usage.InputTokens)),
+		OutputTokens:        int64(aws.ToInt32(usage.OutputTokens)),
+		TotalTokens:         int64(aws.ToInt32(usage.TotalTokens)),
+		CacheReadTokens:     int64(aws.ToInt32(usage.CacheReadInputTokens)),
+		CacheCreationTokens: int64(aws.ToInt32(usage.CacheWriteInputTokens)),
+	}
+}
@@ -0,0 +1,216 @@
+package bedrock
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsAnthropicModel(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, isAnthropicModel("anthropic.claude-3-5-haiku-20241022-v1:0"))
+	require.True(t, isAnthropicModel("us.anthropic.claude-sonnet-4-20250514-v1:0"))
+	require.True(t, isAnthropicModel("global.anthropic.claude-sonnet-4-5-20250929-v1:0"))
+	require.False(t, isAnthropicModel("us.amazon.nova-pro-v1:0"))
+	require.False(t, isAnthropicModel("meta.llama3-3-70b-instruct-v1:0"))
+	require.False(t, isAnthropicModel("us.deepseek.r1-v1:0"))
+	require.True(t, isAnthropicModel("arn:aws:bedrock:us-east-1:123456789012:inference-profile/us.anthropic.claude-sonnet-4-20250514-v1:0"))
+	require.False(t, isAnthropicModel("arn:aws:bedrock:us-east-1:123456789012:inference-profile/us.amazon.nova-pro-v1:0"))
+	require.False(t, isAnthropicModel("arn:aws:bedrock:us-east-1:123456789012:application-inference-profile/a1b2c3d4e5f6"))
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestProviderOptions_Region(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/converse") {
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"output":     map[string]any{"message": map[string]any{"role": "assistant", "content": []any{map[string]any{"text": "Hi"}}}},
+				"stopReason": "end_turn",
+			})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(mockAnthropicResponse())
+	}))
+	t.Cleanup(server.Close)
+
+	hosts := make(chan string, 4)
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		hosts <- req.URL.Host
+		return redirectTransport(server.URL).RoundTrip(req)
+	})}
+	provider, err := New(WithAPIKey("k"), WithRegion("us-east-1"), WithHTTPClient(client))
+	require.NoError(t, err)
+
+	call := fantasy.Call{
+		Prompt:          fantasy.Prompt{fantasy.NewUserMessage("Hi")},
+		ProviderOptions: NewProviderOptions(&ProviderOptions{Region: "eu-west-1"}),
+	}
+	for _, modelID := range []string{
+		"eu.amazon.nova-pro-v1:0",
+		"eu.anthropic.claude-sonnet-4-20250514-v1:0",
+	} {
+		model, err := provider.LanguageModel(t.Context(), modelID)
+		require.NoError(t, err)
+
+		_, err = model.Generate(t.Context(), fantasy.Call{Prompt: call.Prompt})
+		require.NoError(t, err)
+		require.Equal(t, "bedrock-runtime.us-east-1.amazonaws.com", <-hosts)
+
+		_, err = model.Generate(t.Context(), call)
+		require.NoError(t, err)
+		require.Equal(t, "bedrock-runtime.eu-west-1.amazonaws.com", <-hosts)
+	}
+}
+
+func TestConverse_Generate(t *testing.T) {
+	t.Parallel()
+
+	type request struct {
+		path   string
+		auth   string
+		ua     string
+		header string
+		body   map[string]any
+	}
+	calls := make(chan request, 1)
+	// The bearer token signer requires HTTPS.
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		calls <- request{
+			path:   r.URL.Path,
+			auth:   r.Header.Get("Authorization"),
+			ua:     r.Header.Get("User-Agent"),
+			header: r.Header.Get("X-Test"),
+			body:   body,
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"output": map[string]any{"message": map[string]any{
+				"role": "assistant",
+				"content": []any{
+					map[string]any{"reasoningContent": map[string]any{
+						"reasoningText": map[string]any{"text": "Checking the weather.", "signature": "sig"},
+					}},
+					map[string]any{"text": "Let me check."},
+					map[string]any{"toolUse": map[string]any{
+						"toolUseId": "tooluse_1",
+						"name":      "weather",
+						"input":     map[string]any{"city": "Lisbon"},
+					}},
+				},
+			}},
+			"stopReason": "tool_use",
+			"usage": map[string]any{
+				"inputTokens":          10,
+				"outputTokens":         5,
+				"totalTokens":          15,
+				"cacheReadInputTokens": 2,
+			},
+			"metrics": map[string]any{"latencyMs": 1},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := New(
+		WithAPIKey("k"),
+		WithBaseURL(server.URL),
+		WithHTTPClient(server.Client()),
+		WithHeaders(map[string]string{"X-Test": "1"}),
+	)
+	require.NoError(t, err)
+	model, err := provider.LanguageModel(t.Context(), "us.amazon.nova-pro-v1:0")
+	require.NoError(t, err)
+	require.Equal(t, Name, model.Provider())
+
+	resp, err := model.Generate(t.Context(), fantasy.Call{
+		Prompt: fantasy.Prompt{
+			fantasy.NewSystemMessage("Be brief."),
+			fantasy.NewUserMessage("Weather in Lisbon?", fantasy.FilePart{
+				Filename:  "forecast.pdf",
+				Data:      []byte("%PDF"),
+				MediaType: "application/pdf",
+			}),
+			{
+				Role: fantasy.MessageRoleAssistant,
+				Content: []fantasy.MessagePart{
+					fantasy.ReasoningPart{
+						Text:            "Earlier thought.",
+						ProviderOptions: fantasy.ProviderOptions{Name: &ReasoningOptionMetadata{Signature: "old"}},
+					},
+					fantasy.ToolCallPart{ToolCallID: "tooluse_0", ToolName: "weather", Input: `{"city":"Porto"}`},
+				},
+			},
+			{
+				Role: fantasy.MessageRoleTool,
+				Content: []fantasy.MessagePart{
+					fantasy.ToolResultPart{ToolCallID: "tooluse_0", Output: fantasy.ToolResultOutputContentError{Error: errors.New("unavailable")}},
+				},
+			},
+			fantasy.NewUserMessage("Try Lisbon instead."),
+		},
+		MaxOutputTokens: fantasy.Opt[int64](100),
+		Tools: []fantasy.Tool{fantasy.FunctionTool{
+			Name:        "weather",
+			Description: "Get the weather",
+			InputSchema: map[string]any{"type": "object"},
+		}},
+		ExtraBody: map[string]any{"topK": 20},
+		ProviderOptions: NewProviderOptions(&ProviderOptions{
+			ReasoningConfig: &ReasoningConfig{Type: "enabled", MaxReasoningEffort: ReasoningEffortLow},
+		}),
+	})
+	require.NoError(t, err)
+
+	req := <-calls
+	require.Equal(t, "/model/us.amazon.nova-pro-v1:0/converse", req.path)
+	require.Equal(t, "Bearer k", req.auth)
+	require.Equal(t, "Charm-Fantasy/"+fantasy.Version+" (https://charm.land/fantasy)", req.ua)
+	require.Equal(t, "1", req.header)
+	require.Equal(t, []any{map[string]any{"text": "Be brief."}}, req.body["system"])
+	require.Equal(t, map[string]any{"maxTokens": float64(100)}, req.body["inferenceConfig"])
+	require.Equal(t, map[string]any{
+		"reasoningConfig": map[string]any{"type": "enabled", "maxReasoningEffort": "low"},
+		"topK":            float64(20),
+	}, req.body["additionalModelRequestFields"])
+
+	messages := req.body["messages"].([]any)
+	// The tool result and the following user message share one user turn.
+	require.Len(t, messages, 3)
+	document := messages[0].(map[string]any)["content"].([]any)[1].(map[string]any)["document"].(map[string]any)
+	require.Equal(t, "forecast", document["name"])
+	require.Equal(t, "pdf", document["format"])
+	require.Equal(t, map[string]any{
+		"reasoningContent": map[string]any{"reasoningText": map[string]any{"text": "Earlier thought.", "signature": "old"}},
+	}, messages[1].(map[string]any)["content"].([]any)[0])
+	user := messages[2].(map[string]any)["content"].([]any)
+	require.Len(t, user, 2)
+	require.Equal(t, "error", user[0].(map[string]any)["toolResult"].(map[string]any)["status"])
+
+	require.Equal(t, fantasy.FinishReasonToolCalls, resp.FinishReason)
+	require.Equal(t, fantasy.Usage{InputTokens: 10, OutputTokens: 5, TotalTokens: 15, CacheReadTokens: 2}, resp.Usage)
+	require.Equal(t, "Let me check.", resp.Content.Text())
+	reasoning := resp.Content.Reasoning()
+	require.Len(t, reasoning, 1)
+	require.Equal(t, &ReasoningOptionMetadata{Signature: "sig"}, reasoning[0].ProviderMetadata[Name])
+	toolCalls := resp.Content.ToolCalls()
+	require.Len(t, toolCalls, 1)
+	require.Equal(t, "tooluse_1", toolCalls[0].ToolCallID)
+	require.JSONEq(t, `{"city":"Lisbon"}`, toolCalls[0].Input)
+}
@@ -0,0 +1,55 @@
+package bedrock
+
+import (
+	"cmp"
+	"context"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// roleCredentials assumes an IAM role through STS, either with the default
+// credential chain or with a web identity token. The STS client is created
+// on first use because loading the default config needs a context.
+type roleCredentials struct {
+	roleARN            string
+	tokenFile          string
+	region             string
+	assumeRoleOptions  []func(*stscreds.AssumeRoleOptions)
+	webIdentityOptions []func(*stscreds.WebIdentityRoleOptions)
+
+	mu       sync.Mutex
+	provider aws.CredentialsProvider
+}
+
+func (r *roleCredentials) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	provider, err := r.stsProvider(ctx)
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+	return provider.Retrieve(ctx)
+}
+
+func (r *roleCredentials) stsProvider(ctx context.Context) (aws.CredentialsProvider, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.provider != nil {
+		return r.provider, nil
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Region = cmp.Or(r.region, cfg.Region, "us-east-1")
+	client := sts.NewFromConfig(cfg)
+	if r.tokenFile != "" {
+		r.provider = stscreds.NewWebIdentityRoleProvider(client, r.roleARN, stscreds.IdentityTokenFile(r.tokenFile), r.webIdentityOptions...)
+	} else {
+		r.provider = stscreds.NewAssumeRoleProvider(client, r.roleARN, r.assumeRoleOptions...)
+	}
+	return r.provider, nil
+}
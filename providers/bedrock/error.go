@@ -0,0 +1,48 @@
+package bedrock
+
+import (
+	"cmp"
+	"errors"
+	"strings"
+
+	"charm.land/fantasy"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+)
+
+func toProviderErr(err error) error {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		// Wrap transient transport failures so `.IsRetryable()` works.
+		return fantasy.WrapTransportError(err)
+	}
+
+	providerErr := &fantasy.ProviderError{
+		Message: apiErr.ErrorMessage(),
+		Title:   "provider request failed",
+		Cause:   err,
+	}
+
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) {
+		providerErr.StatusCode = respErr.HTTPStatusCode()
+		providerErr.Title = cmp.Or(fantasy.ErrorTitleForStatusCode(providerErr.StatusCode), providerErr.Title)
+		if respErr.Response != nil && respErr.Response.Request != nil && respErr.Response.Request.URL != nil {
+			providerErr.URL = respErr.Response.Request.URL.String()
+		}
+	}
+
+	// Converse reports an oversized prompt as a ValidationException.
+	if apiErr.ErrorCode() == "ValidationException" && isContextTooLarge(apiErr.ErrorMessage()) {
+		providerErr.ContextTooLargeErr = true
+	}
+
+	return providerErr
+}
+
+func isContextTooLarge(message string) bool {
+	message = strings.ToLower(message)
+	return strings.Contains(message, "input is too long") ||
+		strings.Contains(message, "too many input tokens") ||
+		strings.Contains(message, "context length")
+}
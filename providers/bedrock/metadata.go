@@ -0,0 +1,24 @@
+package bedrock
+
+import "charm.land/fantasy"
+
+// Metadata is the provider metadata the Bedrock Converse API sets, by type.
+// MetadataFrom sets the field of the type it finds and leaves the others
+// nil.
+type Metadata struct {
+	// Reasoning is set on reasoning content, with its signature or redacted
+	// data.
+	Reasoning *ReasoningOptionMetadata
+}
+
+// MetadataFrom returns the Bedrock metadata of a response, content or stream
+// part, and whether there is any. Claude models served through the
+// Anthropic API set the metadata of the anthropic package instead.
+func MetadataFrom(meta fantasy.ProviderMetadata) (Metadata, bool) {
+	var m Metadata
+	var ok bool
+	if m.Reasoning, ok = fantasy.ProviderMetadataOf[*ReasoningOptionMetadata](meta, Name); ok {
+		return m, true
+	}
+	return Metadata{}, false
+}
@@ -0,0 +1,46 @@
+package bedrock
+
+import (
+	"testing"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/fantasytest"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToPrompt_Properties(t *testing.T) {
+	t.Parallel()
+
+	fantasytest.ForAll(t, 200, func(t *testing.T, call fantasy.Call) {
+		system, messages, _ := toPrompt(call.Prompt)
+		fantasytest.RequireTextsPreserved(t, call.Prompt, []any{system, messages})
+		fantasytest.RequireToolCallIDsPreserved(t, call.Prompt, messages)
+
+		// Messages alternate, starting with the user, and every tool use is
+		// answered in the next message.
+		for i, msg := range messages {
+			role := types.ConversationRoleUser
+			if i%2 == 1 {
+				role = types.ConversationRoleAssistant
+			}
+			require.Equal(t, role, msg.Role, "message %d", i)
+			for _, block := range msg.Content {
+				toolUse, ok := block.(*types.ContentBlockMemberToolUse)
+				if !ok {
+					continue
+				}
+				id := aws.ToString(toolUse.Value.ToolUseId)
+				require.Less(t, i+1, len(messages), "tool use %s is not answered", id)
+				answered := false
+				for _, next := range messages[i+1].Content {
+					if result, ok := next.(*types.ContentBlockMemberToolResult); ok && aws.ToString(result.Value.ToolUseId) == id {
+						answered = true
+					}
+				}
+				require.True(t, answered, "tool use %s is not answered", id)
+			}
+		}
+	})
+}
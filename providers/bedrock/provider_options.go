@@ -0,0 +1,136 @@
+package bedrock
+
+import (
+	"encoding/json"
+
+	"charm.land/fantasy"
+)
+
+// Global type identifiers for Bedrock-specific provider data.
+const (
+	TypeProviderOptions         = Name + ".options"
+	TypeReasoningOptionMetadata = Name + ".reasoning_metadata"
+)
+
+// Register Bedrock provider-specific types with the global registry.
+func init() {
+	fantasy.RegisterProviderType(TypeProviderOptions, func(data []byte) (fantasy.ProviderOptionsData, error) {
+		var v ProviderOptions
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		return &v, nil
+	})
+	fantasy.RegisterProviderType(TypeReasoningOptionMetadata, func(data []byte) (fantasy.ProviderOptionsData, error) {
+		var v ReasoningOptionMetadata
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		return &v, nil
+	})
+}
+
+// ReasoningEffort controls how much a model reasons before answering.
+type ReasoningEffort = string
+
+// Reasoning efforts supported by Bedrock reasoning models.
+const (
+	ReasoningEffortLow    ReasoningEffort = "low"
+	ReasoningEffortMedium ReasoningEffort = "medium"
+	ReasoningEffortHigh   ReasoningEffort = "high"
+)
+
+// ReasoningConfig enables extended reasoning on models served through the
+// Converse API, such as Amazon Nova 2. The document tags name the fields
+// sent to the model.
+type ReasoningConfig struct {
+	Type               string          `json:"type" document:"type"`
+	MaxReasoningEffort ReasoningEffort `json:"max_reasoning_effort,omitempty" document:"maxReasoningEffort,omitempty"`
+}
+
+// ProviderOptions represents additional options for models served through
+// the Bedrock Converse API. Apart from Region, Claude models use
+// anthropic.ProviderOptions instead.
+type ProviderOptions struct {
+	// Region overrides the provider region for a single call. It also
+	// applies to Claude models.
+	Region          string           `json:"region,omitempty"`
+	ReasoningConfig *ReasoningConfig `json:"reasoning_config,omitempty"`
+	// AdditionalModelRequestFields are passed to the model as-is, for
+	// family-specific parameters the Converse API doesn't model.
+	AdditionalModelRequestFields map[string]any `json:"additional_model_request_fields,omitempty"`
+}
+
+// Options implements the ProviderOptions interface.
+func (*ProviderOptions) Options() {}
+
+// MarshalJSON implements custom JSON marshaling with type info for ProviderOptions.
+func (o ProviderOptions) MarshalJSON() ([]byte, error) {
+	type plain ProviderOptions
+	return fantasy.MarshalProviderType(TypeProviderOptions, plain(o))
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling with type info for ProviderOptions.
+func (o *ProviderOptions) UnmarshalJSON(data []byte) error {
+	type plain ProviderOptions
+	var p plain
+	if err := fantasy.UnmarshalProviderType(data, &p); err != nil {
+		return err
+	}
+	*o = ProviderOptions(p)
+	return nil
+}
+
+// ReasoningOptionMetadata carries the reasoning signature or redacted
+// reasoning that must be sent back with the assistant turn.
+type ReasoningOptionMetadata struct {
+	Signature    string `json:"signature,omitempty"`
+	RedactedData []byte `json:"redacted_data,omitempty"`
+}
+
+// Options implements the ProviderOptions interface.
+func (*ReasoningOptionMetadata) Options() {}
+
+// MarshalJSON implements custom JSON marshaling with type info for ReasoningOptionMetadata.
+func (m ReasoningOptionMetadata) MarshalJSON() ([]byte, error) {
+	type plain ReasoningOptionMetadata
+	return fantasy.MarshalProviderType(TypeReasoningOptionMetadata, plain(m))
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling with type info for ReasoningOptionMetadata.
+func (m *ReasoningOptionMetadata) UnmarshalJSON(data []byte) error {
+	type plain ReasoningOptionMetadata
+	var p plain
+	if err := fantasy.UnmarshalProviderType(data, &p); err != nil {
+		return err
+	}
+	*m = ReasoningOptionMetadata(p)
+	return nil
+}
+
+// GetReasoningMetadata returns the Bedrock reasoning metadata from the
+// given provider options, if any.
+func GetReasoningMetadata(providerOptions fantasy.ProviderOptions) *ReasoningOptionMetadata {
+	if v, ok := providerOptions[Name]; ok {
+		if reasoning, ok := v.(*ReasoningOptionMetadata); ok {
+			return reasoning
+		}
+	}
+	return nil
+}
+
+// NewProviderOptions creates new provider options for the Bedrock provider.
+func NewProviderOptions(opts *ProviderOptions) fantasy.ProviderOptions {
+	return fantasy.ProviderOptions{
+		Name: opts,
+	}
+}
+
+// ParseOptions parses provider options from a map for the Bedrock provider.
+func ParseOptions(data map[string]any) (*ProviderOptions, error) {
+	var options ProviderOptions
+	if err := fantasy.ParseOptions(data, &options); err != nil {
+		return nil, err
+	}
+	return &options, nil
+}
@@ -12,6 +12,8 @@ type callUAKey struct{}
 
 type callHeadersKey struct{}
 
+type callQueryKey struct{}
+
 func withCallUA(ctx context.Context, call fantasy.Call) context.Context {
 	if ua, ok := httpheaders.CallUserAgent(call.UserAgent); ok {
 		ctx = context.WithValue(ctx, callUAKey{}, ua)
@@ -19,6 +21,9 @@ func withCallUA(ctx context.Context, call fantasy.Call) context.Context {
 	if headers, ok := httpheaders.CallHeaders(call.Headers); ok {
 		ctx = context.WithValue(ctx, callHeadersKey{}, headers)
 	}
+	if query, ok := httpheaders.CallQuery(call.ExtraQuery); ok {
+		ctx = context.WithValue(ctx, callQueryKey{}, query)
+	}
 	return ctx
 }
 
@@ -29,6 +34,9 @@ func withObjectCallUA(ctx context.Context, call fantasy.ObjectCall) context.Cont
 	if headers, ok := httpheaders.CallHeaders(call.Headers); ok {
 		ctx = context.WithValue(ctx, callHeadersKey{}, headers)
 	}
+	if query, ok := httpheaders.CallQuery(call.ExtraQuery); ok {
+		ctx = context.WithValue(ctx, callQueryKey{}, query)
+	}
 	return ctx
 }
 
@@ -66,5 +74,13 @@ func (t *uaTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 			req.Header.Set(k, v)
 		}
 	}
+	if query, ok := req.Context().Value(callQueryKey{}).(map[string]string); ok && len(query) > 0 {
+		req = req.Clone(req.Context())
+		values := req.URL.Query()
+		for k, v := range query {
+			values.Set(k, v)
+		}
+		req.URL.RawQuery = values.Encode()
+	}
 	return t.base.RoundTrip(req)
 }
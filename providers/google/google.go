@@ -8,17 +8,21 @@ import (
 	"fmt"
 	"maps"
 	"net/http"
+	"net/url"
 	"reflect"
+	"slices"
 	"strings"
+	"time"
 
 	"charm.land/fantasy"
 	"charm.land/fantasy/object"
 	"charm.land/fantasy/providers/anthropic"
+	"charm.land/fantasy/providers/internal/httpclient"
 	"charm.land/fantasy/providers/internal/httpheaders"
 	"charm.land/fantasy/schema"
 	"cloud.google.com/go/auth"
+	"cloud.google.com/go/auth/credentials"
 	"github.com/charmbracelet/x/exp/slice"
-	"github.com/google/uuid"
 	"google.golang.org/genai"
 )
 
@@ -39,10 +43,12 @@ type options struct {
 	headers        map[string]string
 	userAgent      string
 	client         *http.Client
+	httpConfig     httpclient.Config
 	backend        genai.Backend
 	project        string
 	location       string
 	skipAuth       bool
+	serviceAccount []byte
 	toolCallIDFunc ToolCallIDFunc
 	objectMode     fantasy.ObjectMode
 }
@@ -53,16 +59,17 @@ type Option = func(*options)
 // New creates a new Google provider with the given options.
 func New(opts ...Option) (fantasy.Provider, error) {
 	options := options{
-		headers: map[string]string{},
-		toolCallIDFunc: func() string {
-			return uuid.NewString()
-		},
+		headers:        map[string]string{},
+		toolCallIDFunc: fantasy.NewID,
 	}
 	for _, o := range opts {
 		o(&options)
 	}
 
 	options.name = cmp.Or(options.name, Name)
+	if options.client == nil {
+		options.client = options.httpConfig.Client()
+	}
 
 	return &provider{
 		options: options,
@@ -99,6 +106,14 @@ func WithVertex(project, location string) Option {
 	}
 }
 
+// WithVertexServiceAccount authenticates Vertex AI requests with the given
+// service account key JSON instead of Application Default Credentials.
+func WithVertexServiceAccount(credentialsJSON []byte) Option {
+	return func(o *options) {
+		o.serviceAccount = credentialsJSON
+	}
+}
+
 // WithSkipAuth configures whether to skip authentication for the Google provider.
 func WithSkipAuth(skipAuth bool) Option {
 	return func(o *options) {
@@ -127,7 +142,33 @@ func WithHTTPClient(client *http.Client) Option {
 	}
 }
 
-// WithToolCallIDFunc sets the function that generates a tool call ID.
+// WithHTTPTimeout sets the overall timeout of each request, including
+// reading streamed responses. Use fantasy.WithStreamIdleTimeout to detect
+// stalled streams instead. It's ignored when WithHTTPClient is set.
+func WithHTTPTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.httpConfig.Timeout = timeout
+	}
+}
+
+// WithProxy sends the requests through the given proxy instead of the one
+// from the environment. It's ignored when WithHTTPClient is set.
+func WithProxy(proxy *url.URL) Option {
+	return func(o *options) {
+		o.httpConfig.Proxy = proxy
+	}
+}
+
+// WithTransport sets the HTTP transport of the Google provider. It's
+// ignored when WithHTTPClient is set.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *options) {
+		o.httpConfig.Transport = transport
+	}
+}
+
+// WithToolCallIDFunc sets the function that generates a tool call ID when the
+// model returns none. Defaults to fantasy.NewID.
 func WithToolCallIDFunc(f ToolCallIDFunc) Option {
 	return func(o *options) {
 		o.toolCallIDFunc = f
@@ -153,6 +194,20 @@ func (*provider) Name() string {
 	return Name
 }
 
+// publisherModelID strips a Vertex AI publisher model path such as
+// "publishers/anthropic/models/claude-sonnet-4" or
+// "projects/p/locations/l/publishers/anthropic/models/claude-sonnet-4" down to
+// the bare model ID.
+func publisherModelID(modelID string) string {
+	if !strings.Contains(modelID, "publishers/") {
+		return modelID
+	}
+	if i := strings.LastIndex(modelID, "/models/"); i >= 0 {
+		return modelID[i+len("/models/"):]
+	}
+	return modelID
+}
+
 type languageModel struct {
 	provider        string
 	modelID         string
@@ -161,24 +216,7 @@ type languageModel struct {
 	objectMode      fantasy.ObjectMode
 }
 
-// LanguageModel implements fantasy.Provider.
-func (a *provider) LanguageModel(ctx context.Context, modelID string) (fantasy.LanguageModel, error) {
-	if strings.Contains(modelID, "anthropic") || strings.Contains(modelID, "claude") {
-		anthropicOpts := []anthropic.Option{
-			anthropic.WithVertex(a.options.project, a.options.location),
-			anthropic.WithHTTPClient(a.options.client),
-			anthropic.WithSkipAuth(a.options.skipAuth),
-		}
-		if a.options.userAgent != "" {
-			anthropicOpts = append(anthropicOpts, anthropic.WithUserAgent(a.options.userAgent))
-		}
-		p, err := anthropic.New(anthropicOpts...)
-		if err != nil {
-			return nil, err
-		}
-		return p.LanguageModel(ctx, modelID)
-	}
-
+func (a *provider) newClient(ctx context.Context) (*genai.Client, error) {
 	cc := &genai.ClientConfig{
 		HTTPClient: wrapHTTPClient(a.options.client),
 		Backend:    a.options.backend,
@@ -186,9 +224,18 @@ func (a *provider) LanguageModel(ctx context.Context, modelID string) (fantasy.L
 		Project:    a.options.project,
 		Location:   a.options.location,
 	}
-	if a.options.skipAuth {
+	switch {
+	case a.options.skipAuth:
 		cc.Credentials = &auth.Credentials{TokenProvider: dummyTokenProvider{}}
-	} else if cc.Backend == genai.BackendVertexAI {
+	case cc.Backend == genai.BackendVertexAI && a.options.serviceAccount != nil:
+		creds, err := credentials.NewCredentialsFromJSON(credentials.ServiceAccount, a.options.serviceAccount, &credentials.DetectOptions{
+			Scopes: []string{anthropic.VertexAuthScope},
+		})
+		if err != nil {
+			return nil, err
+		}
+		cc.Credentials = creds
+	case cc.Backend == genai.BackendVertexAI:
 		if err := cc.UseDefaultCredentials(); err != nil {
 			return nil, err
 		}
@@ -205,7 +252,44 @@ func (a *provider) LanguageModel(ctx context.Context, modelID string) (fantasy.L
 		BaseURL: a.options.baseURL,
 		Headers: headers,
 	}
-	client, err := genai.NewClient(ctx, cc)
+	return genai.NewClient(ctx, cc)
+}
+
+// Validate implements fantasy.Validator by listing the available models.
+func (a *provider) Validate(ctx context.Context) error {
+	client, err := a.newClient(ctx)
+	if err != nil {
+		return fantasy.NewValidationError(a.options.name, err)
+	}
+	_, err = client.Models.List(ctx, &genai.ListModelsConfig{PageSize: 1})
+	if err != nil {
+		return fantasy.NewValidationError(a.options.name, toProviderErr(err))
+	}
+	return nil
+}
+
+// LanguageModel implements fantasy.Provider.
+func (a *provider) LanguageModel(ctx context.Context, modelID string) (fantasy.LanguageModel, error) {
+	if strings.Contains(modelID, "anthropic") || strings.Contains(modelID, "claude") {
+		anthropicOpts := []anthropic.Option{
+			anthropic.WithVertex(a.options.project, a.options.location),
+			anthropic.WithHTTPClient(a.options.client),
+			anthropic.WithSkipAuth(a.options.skipAuth),
+		}
+		if a.options.serviceAccount != nil {
+			anthropicOpts = append(anthropicOpts, anthropic.WithVertexServiceAccount(a.options.serviceAccount))
+		}
+		if a.options.userAgent != "" {
+			anthropicOpts = append(anthropicOpts, anthropic.WithUserAgent(a.options.userAgent))
+		}
+		p, err := anthropic.New(anthropicOpts...)
+		if err != nil {
+			return nil, err
+		}
+		return p.LanguageModel(ctx, publisherModelID(modelID))
+	}
+
+	client, err := a.newClient(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -235,6 +319,10 @@ func (g languageModel) prepareParams(call fantasy.Call) (*genai.GenerateContentC
 		}
 	}
 
+	if len(call.ExtraBody) > 0 {
+		config.HTTPOptions = &genai.HTTPOptions{ExtraBody: call.ExtraBody}
+	}
+
 	isVertexAI := g.providerOptions.backend == genai.BackendVertexAI
 	systemInstructions, content, warnings := toGooglePrompt(call.Prompt, isVertexAI)
 
@@ -335,6 +423,21 @@ func (g languageModel) prepareParams(call fantasy.Call) (*genai.GenerateContentC
 	if providerOptions.CachedContent != "" {
 		config.CachedContent = providerOptions.CachedContent
 	}
+	if providerOptions.ResponseMIMEType != "" {
+		config.ResponseMIMEType = providerOptions.ResponseMIMEType
+	}
+	if providerOptions.ResponseSchema != nil {
+		config.ResponseJsonSchema = providerOptions.ResponseSchema
+		config.ResponseMIMEType = cmp.Or(config.ResponseMIMEType, "application/json")
+	}
+	if call.OutputConstraint != nil {
+		if call.OutputConstraint.Type == fantasy.OutputConstraintTypeJSONSchema {
+			config.ResponseMIMEType = "application/json"
+			config.ResponseJsonSchema = schema.ToMap(call.OutputConstraint.Schema)
+		} else {
+			warnings = append(warnings, fantasy.UnsupportedOutputConstraintWarning(call.OutputConstraint))
+		}
+	}
 
 	if len(call.Tools) > 0 {
 		tools, toolChoice, toolWarnings := toGoogleTools(call.Tools, call.ToolChoice)
@@ -343,6 +446,11 @@ func (g languageModel) prepareParams(call fantasy.Call) (*genai.GenerateContentC
 			FunctionDeclarations: tools,
 		})
 		warnings = append(warnings, toolWarnings...)
+		if providerOptions.FunctionCalling != nil {
+			var functionCallingWarnings []fantasy.CallWarning
+			config.ToolConfig, functionCallingWarnings = applyFunctionCalling(config.ToolConfig, providerOptions.FunctionCalling, tools)
+			warnings = append(warnings, functionCallingWarnings...)
+		}
 	}
 
 	return config, content, warnings, nil
@@ -398,6 +506,15 @@ func toGooglePrompt(prompt fantasy.Prompt, isVertexAI bool) (*genai.Content, []*
 					if !ok {
 						continue
 					}
+					if file.URL != "" {
+						parts = append(parts, &genai.Part{
+							FileData: &genai.FileData{
+								FileURI:  file.URL,
+								MIMEType: file.MediaType,
+							},
+						})
+						continue
+					}
 					parts = append(parts, &genai.Part{
 						InlineData: &genai.Blob{
 							Data:     file.Data,
@@ -538,6 +655,35 @@ func toGooglePrompt(prompt fantasy.Prompt, isVertexAI bool) (*genai.Content, []*
 							Name:     toolCall.ToolName,
 						}
 
+						// Vertex breaks with a 400 if this field be present.
+						if isVertexAI {
+							functionResponse.ID = ""
+						}
+						parts = append(parts, &genai.Part{
+							FunctionResponse: functionResponse,
+						})
+
+					case fantasy.ToolResultContentTypeMedia:
+						content, ok := fantasy.AsToolResultOutputType[fantasy.ToolResultOutputContentMedia](result.Output)
+						if !ok {
+							continue
+						}
+						// Answer the function call with the text only, as an
+						// unanswered function call is rejected.
+						text := content.Text
+						if text == "" {
+							text = fmt.Sprintf("The tool returned %s content, which can't be shown.", content.MediaType)
+						}
+						warnings = append(warnings, fantasy.CallWarning{
+							Type:    fantasy.CallWarningTypeDroppedContent,
+							Message: fmt.Sprintf("tool result media type %s not supported, sending text only", content.MediaType),
+						})
+						functionResponse := &genai.FunctionResponse{
+							ID:       result.ToolCallID,
+							Response: map[string]any{"result": text},
+							Name:     toolCall.ToolName,
+						}
+
 						// Vertex breaks with a 400 if this field be present.
 						if isVertexAI {
 							functionResponse.ID = ""
@@ -598,6 +744,17 @@ func (g *languageModel) Provider() string {
 }
 
 // Stream implements fantasy.LanguageModel.
+// SupportsPDF implements fantasy.PDFModel.
+func (g *languageModel) SupportsPDF() bool {
+	return true
+}
+
+// SupportsFileURL implements fantasy.FileURLModel. URLs are sent as file
+// data, which Gemini fetches.
+func (g *languageModel) SupportsFileURL(string) bool {
+	return true
+}
+
 func (g *languageModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.StreamResponse, error) {
 	ctx = withCallUA(ctx, call)
 	config, contents, warnings, err := g.prepareParams(call)
@@ -634,6 +791,9 @@ func (g *languageModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.
 		var currentReasoningBlockID string
 		var usage *fantasy.Usage
 		var lastFinishReason fantasy.FinishReason
+		var groundingMetadata *genai.GroundingMetadata
+		var safety *fantasy.SafetyInfo
+		var promptBlocked bool
 
 		for resp, err := range chat.SendMessageStream(ctx, depointerSlice(lastMessage.Parts)...) {
 			if err != nil {
@@ -644,6 +804,15 @@ func (g *languageModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.
 				return
 			}
 
+			if s := mapSafety(resp); s != nil {
+				safety = s
+			}
+			promptBlocked = promptBlocked || isPromptBlocked(resp)
+
+			if len(resp.Candidates) > 0 && resp.Candidates[0].GroundingMetadata != nil {
+				groundingMetadata = resp.Candidates[0].GroundingMetadata
+			}
+
 			if len(resp.Candidates) > 0 && resp.Candidates[0].Content != nil {
 				for _, part := range resp.Candidates[0].Content.Parts {
 					switch {
@@ -881,7 +1050,25 @@ func (g *languageModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.
 			}
 		}
 
+		// Streamed parts are split across chunks, so the text of the
+		// stream is cited as one part.
+		for _, source := range groundingSources(groundingMetadata, currentContent, nil) {
+			if !yield(fantasy.StreamPart{
+				Type:       fantasy.StreamPartTypeSource,
+				ID:         source.ID,
+				SourceType: source.SourceType,
+				URL:        source.URL,
+				Title:      source.Title,
+				Citations:  source.Citations,
+			}) {
+				return
+			}
+		}
+
 		finishReason := lastFinishReason
+		if promptBlocked {
+			finishReason = fantasy.FinishReasonContentFilter
+		}
 		if len(toolCalls) > 0 {
 			finishReason = fantasy.FinishReasonToolCalls
 		} else if finishReason == "" {
@@ -903,6 +1090,7 @@ func (g *languageModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.
 			Type:         fantasy.StreamPartTypeFinish,
 			Usage:        finalUsage,
 			FinishReason: finishReason,
+			Safety:       safety,
 		})
 	}, nil
 }
@@ -945,6 +1133,7 @@ func (g *languageModel) generateObjectWithJSONMode(ctx context.Context, call fan
 		TopK:             call.TopK,
 		PresencePenalty:  call.PresencePenalty,
 		FrequencyPenalty: call.FrequencyPenalty,
+		ExtraBody:        call.ExtraBody,
 		ProviderOptions:  call.ProviderOptions,
 	}
 
@@ -1028,6 +1217,7 @@ func (g *languageModel) streamObjectWithJSONMode(ctx context.Context, call fanta
 		TopK:             call.TopK,
 		PresencePenalty:  call.PresencePenalty,
 		FrequencyPenalty: call.FrequencyPenalty,
+		ExtraBody:        call.ExtraBody,
 		ProviderOptions:  call.ProviderOptions,
 	}
 
@@ -1245,6 +1435,44 @@ func toGoogleTools(tools []fantasy.Tool, toolChoice *fantasy.ToolChoice) (google
 	return googleTools, googleToolChoice, warnings
 }
 
+// applyFunctionCalling returns the tool config with the function calling
+// config of the provider options applied.
+func applyFunctionCalling(toolConfig *genai.ToolConfig, functionCalling *FunctionCallingConfig, tools []*genai.FunctionDeclaration) (*genai.ToolConfig, []fantasy.CallWarning) {
+	var warnings []fantasy.CallWarning
+	config := &genai.FunctionCallingConfig{}
+	if toolConfig != nil && toolConfig.FunctionCallingConfig != nil {
+		config.Mode = toolConfig.FunctionCallingConfig.Mode
+		config.AllowedFunctionNames = toolConfig.FunctionCallingConfig.AllowedFunctionNames
+	}
+	if len(functionCalling.AllowedFunctionNames) > 0 {
+		config.AllowedFunctionNames = functionCalling.AllowedFunctionNames
+		config.Mode = genai.FunctionCallingConfigModeAny
+		for _, name := range functionCalling.AllowedFunctionNames {
+			if !slices.ContainsFunc(tools, func(tool *genai.FunctionDeclaration) bool { return tool.Name == name }) {
+				warnings = append(warnings, fantasy.CallWarning{
+					Type:    fantasy.CallWarningTypeUnsupportedSetting,
+					Setting: "FunctionCalling",
+					Details: fmt.Sprintf("allowed function %q is not one of the call's tools", name),
+				})
+			}
+		}
+	}
+	if functionCalling.Mode != "" {
+		config.Mode = genai.FunctionCallingConfigMode(functionCalling.Mode)
+	}
+	if config.Mode != genai.FunctionCallingConfigModeAny && config.Mode != genai.FunctionCallingConfigModeValidated {
+		if len(functionCalling.AllowedFunctionNames) > 0 {
+			warnings = append(warnings, fantasy.CallWarning{
+				Type:    fantasy.CallWarningTypeUnsupportedSetting,
+				Setting: "FunctionCalling",
+				Details: fmt.Sprintf("allowed function names are ignored with the %s mode", config.Mode),
+			})
+		}
+		config.AllowedFunctionNames = nil
+	}
+	return &genai.ToolConfig{FunctionCallingConfig: config}, warnings
+}
+
 func convertSchemaProperties(parameters map[string]any) map[string]*genai.Schema {
 	properties := make(map[string]*genai.Schema)
 
@@ -1321,6 +1549,16 @@ func mapJSONTypeToGoogle(jsonType string) genai.Type {
 
 func (g languageModel) mapResponse(response *genai.GenerateContentResponse, warnings []fantasy.CallWarning) (*fantasy.Response, error) {
 	if len(response.Candidates) == 0 || response.Candidates[0].Content == nil {
+		// A blocked prompt or response carries no content, but is a
+		// valid outcome rather than an error.
+		if safety := mapSafety(response); safety != nil && safety.Blocked {
+			return &fantasy.Response{
+				Usage:        mapUsage(response.UsageMetadata),
+				FinishReason: fantasy.FinishReasonContentFilter,
+				Warnings:     warnings,
+				Safety:       safety,
+			}, nil
+		}
 		return nil, errors.New("no response from model")
 	}
 
@@ -1328,10 +1566,13 @@ func (g languageModel) mapResponse(response *genai.GenerateContentResponse, warn
 		content      []fantasy.Content
 		finishReason fantasy.FinishReason
 		hasToolCalls bool
+		text         strings.Builder
+		partOffsets  []int
 		candidate    = response.Candidates[0]
 	)
 
 	for _, part := range candidate.Content.Parts {
+		partOffsets = append(partOffsets, text.Len())
 		switch {
 		case part.Text != "":
 			if part.Thought {
@@ -1379,6 +1620,7 @@ func (g languageModel) mapResponse(response *genai.GenerateContentResponse, warn
 					}
 				}
 				content = append(content, fantasy.TextContent{Text: part.Text})
+				text.WriteString(part.Text)
 			}
 		case part.FunctionCall != nil:
 			input, err := json.Marshal(part.FunctionCall.Args)
@@ -1429,6 +1671,10 @@ func (g languageModel) mapResponse(response *genai.GenerateContentResponse, warn
 		}
 	}
 
+	for _, source := range groundingSources(candidate.GroundingMetadata, text.String(), partOffsets) {
+		content = append(content, source)
+	}
+
 	if hasToolCalls {
 		finishReason = fantasy.FinishReasonToolCalls
 	} else {
@@ -1440,6 +1686,7 @@ func (g languageModel) mapResponse(response *genai.GenerateContentResponse, warn
 		Usage:        mapUsage(response.UsageMetadata),
 		FinishReason: finishReason,
 		Warnings:     warnings,
+		Safety:       mapSafety(response),
 	}, nil
 }
 
@@ -1477,6 +1724,9 @@ func mapFinishReason(reason genai.FinishReason) fantasy.FinishReason {
 }
 
 func mapUsage(usage *genai.GenerateContentResponseUsageMetadata) fantasy.Usage {
+	if usage == nil {
+		return fantasy.Usage{}
+	}
 	return fantasy.Usage{
 		InputTokens:         int64(usage.PromptTokenCount),
 		OutputTokens:        int64(usage.CandidatesTokenCount),
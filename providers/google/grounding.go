@@ -0,0 +1,86 @@
+package google
+
+import (
+	"fmt"
+
+	"charm.land/fantasy"
+	"google.golang.org/genai"
+)
+
+// groundingSources converts Gemini grounding metadata into sources. Each
+// grounding chunk becomes one source, and every grounding support that
+// references the chunk becomes one of its citations. Gemini reports segment
+// offsets in bytes within the response part at the segment's part index;
+// text is the generated text and partOffsets holds the offset in text at
+// which each part starts. Without partOffsets, all segments are taken to
+// be in the first part.
+func groundingSources(metadata *genai.GroundingMetadata, text string, partOffsets []int) []fantasy.SourceContent {
+	if metadata == nil || len(metadata.GroundingChunks) == 0 {
+		return nil
+	}
+
+	citations := make(map[int32][]fantasy.Citation)
+	for _, support := range metadata.GroundingSupports {
+		if support == nil || support.Segment == nil {
+			continue
+		}
+		citation := segmentCitation(support.Segment, text, partOffsets)
+		for _, idx := range support.GroundingChunkIndices {
+			citations[idx] = append(citations[idx], citation)
+		}
+	}
+
+	sources := make([]fantasy.SourceContent, 0, len(metadata.GroundingChunks))
+	for i, chunk := range metadata.GroundingChunks {
+		if chunk == nil {
+			continue
+		}
+		source := fantasy.SourceContent{
+			ID:        fmt.Sprintf("grounding-%d", i),
+			Citations: citations[int32(i)], //nolint:gosec
+		}
+		switch {
+		case chunk.Web != nil:
+			source.SourceType = fantasy.SourceTypeURL
+			source.URL = chunk.Web.URI
+			source.Title = chunk.Web.Title
+		case chunk.RetrievedContext != nil:
+			source.SourceType = fantasy.SourceTypeDocument
+			source.URL = chunk.RetrievedContext.URI
+			source.Title = chunk.RetrievedContext.Title
+			source.Filename = chunk.RetrievedContext.DocumentName
+		case chunk.Maps != nil:
+			source.SourceType = fantasy.SourceTypeURL
+			source.URL = chunk.Maps.URI
+			source.Title = chunk.Maps.Title
+		case chunk.Image != nil:
+			source.SourceType = fantasy.SourceTypeURL
+			source.URL = chunk.Image.SourceURI
+			source.Title = chunk.Image.Title
+		default:
+			continue
+		}
+		sources = append(sources, source)
+	}
+	return sources
+}
+
+// segmentCitation converts a grounding segment into a citation, moving its
+// offsets to the start of its part and clamping them to the bounds of text.
+func segmentCitation(segment *genai.Segment, text string, partOffsets []int) fantasy.Citation {
+	var offset int
+	if idx := int(segment.PartIndex); idx > 0 && idx < len(partOffsets) {
+		offset = partOffsets[idx]
+	}
+	start := min(max(offset+int(segment.StartIndex), 0), len(text))
+	end := min(max(offset+int(segment.EndIndex), start), len(text))
+	citation := fantasy.Citation{
+		StartIndex: start,
+		EndIndex:   end,
+		Text:       segment.Text,
+	}
+	if citation.Text == "" {
+		citation.Text = text[start:end]
+	}
+	return citation
+}
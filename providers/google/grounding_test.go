@@ -0,0 +1,76 @@
+package google
+
+import (
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+)
+
+func TestGroundingSources(t *testing.T) {
+	t.Parallel()
+
+	text := "Paris is the capital of France. It is large."
+	metadata := &genai.GroundingMetadata{
+		GroundingChunks: []*genai.GroundingChunk{
+			{Web: &genai.GroundingChunkWeb{URI: "https://example.com/paris", Title: "Paris"}},
+			{RetrievedContext: &genai.GroundingChunkRetrievedContext{URI: "gs://docs/france.pdf", Title: "France", DocumentName: "france.pdf"}},
+		},
+		GroundingSupports: []*genai.GroundingSupport{
+			{
+				Segment:               &genai.Segment{StartIndex: 0, EndIndex: 31},
+				GroundingChunkIndices: []int32{0, 1},
+			},
+			{
+				Segment:               &genai.Segment{StartIndex: 32, EndIndex: 44, Text: "It is large."},
+				GroundingChunkIndices: []int32{1},
+			},
+		},
+	}
+
+	sources := groundingSources(metadata, text, nil)
+	require.Len(t, sources, 2)
+
+	require.Equal(t, fantasy.SourceTypeURL, sources[0].SourceType)
+	require.Equal(t, "https://example.com/paris", sources[0].URL)
+	require.Equal(t, []fantasy.Citation{
+		{StartIndex: 0, EndIndex: 31, Text: "Paris is the capital of France."},
+	}, sources[0].Citations)
+
+	require.Equal(t, fantasy.SourceTypeDocument, sources[1].SourceType)
+	require.Equal(t, "france.pdf", sources[1].Filename)
+	require.Len(t, sources[1].Citations, 2)
+	require.Equal(t, "It is large.", sources[1].Citations[1].Text)
+}
+
+func TestGroundingSources_Empty(t *testing.T) {
+	t.Parallel()
+
+	require.Nil(t, groundingSources(nil, "text", nil))
+	require.Nil(t, groundingSources(&genai.GroundingMetadata{}, "text", nil))
+}
+
+func TestGroundingSources_PartIndex(t *testing.T) {
+	t.Parallel()
+
+	parts := []string{"Paris is in France. ", "Berlin is in Germany."}
+	text := parts[0] + parts[1]
+	metadata := &genai.GroundingMetadata{
+		GroundingChunks: []*genai.GroundingChunk{
+			{Web: &genai.GroundingChunkWeb{URI: "https://example.com/berlin", Title: "Berlin"}},
+		},
+		GroundingSupports: []*genai.GroundingSupport{
+			{
+				Segment:               &genai.Segment{PartIndex: 1, StartIndex: 0, EndIndex: 21},
+				GroundingChunkIndices: []int32{0},
+			},
+		},
+	}
+
+	sources := groundingSources(metadata, text, []int{0, len(parts[0])})
+	require.Len(t, sources, 1)
+	require.Equal(t, []fantasy.Citation{
+		{StartIndex: 20, EndIndex: 41, Text: "Berlin is in Germany."},
+	}, sources[0].Citations)
+}
@@ -0,0 +1,22 @@
+package google
+
+import "charm.land/fantasy"
+
+// Metadata is the provider metadata Google sets, by type. MetadataFrom sets
+// the field of the type it finds and leaves the others nil.
+type Metadata struct {
+	// Reasoning is set on reasoning content, with its thought signature and
+	// the ID of the tool call it precedes.
+	Reasoning *ReasoningMetadata
+}
+
+// MetadataFrom returns the Google metadata of a response, content or stream
+// part, and whether there is any.
+func MetadataFrom(meta fantasy.ProviderMetadata) (Metadata, bool) {
+	var m Metadata
+	var ok bool
+	if m.Reasoning, ok = fantasy.ProviderMetadataOf[*ReasoningMetadata](meta, Name); ok {
+		return m, true
+	}
+	return Metadata{}, false
+}
@@ -0,0 +1,47 @@
+package google
+
+import (
+	"testing"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/fantasytest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+)
+
+func TestToGooglePrompt_Properties(t *testing.T) {
+	t.Parallel()
+
+	fantasytest.ForAll(t, 200, func(t *testing.T, call fantasy.Call) {
+		for _, isVertexAI := range []bool{false, true} {
+			system, contents, _ := toGooglePrompt(call.Prompt, isVertexAI)
+			fantasytest.RequireTextsPreserved(t, call.Prompt, []any{system, contents})
+			if !isVertexAI {
+				fantasytest.RequireToolCallIDsPreserved(t, call.Prompt, contents)
+			}
+
+			// The content after function calls has a response to each of
+			// them.
+			for i, content := range contents {
+				var calls []string
+				for _, part := range content.Parts {
+					if part.FunctionCall != nil {
+						calls = append(calls, part.FunctionCall.Name)
+					}
+				}
+				if len(calls) == 0 {
+					continue
+				}
+				require.Equal(t, genai.RoleModel, content.Role)
+				require.Less(t, i+1, len(contents), "function calls are not answered")
+				var responses []string
+				for _, part := range contents[i+1].Parts {
+					if part.FunctionResponse != nil {
+						responses = append(responses, part.FunctionResponse.Name)
+					}
+				}
+				require.Equal(t, calls, responses)
+			}
+		}
+	})
+}
@@ -77,23 +77,64 @@ func (m *ReasoningMetadata) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// HarmCategory is a category of harmful content used in safety settings.
+type HarmCategory = string
+
+// Harm categories supported by Gemini safety settings.
+const (
+	HarmCategoryUnspecified      HarmCategory = "HARM_CATEGORY_UNSPECIFIED"
+	HarmCategoryHateSpeech       HarmCategory = "HARM_CATEGORY_HATE_SPEECH"
+	HarmCategoryDangerousContent HarmCategory = "HARM_CATEGORY_DANGEROUS_CONTENT"
+	HarmCategoryHarassment       HarmCategory = "HARM_CATEGORY_HARASSMENT"
+	HarmCategorySexuallyExplicit HarmCategory = "HARM_CATEGORY_SEXUALLY_EXPLICIT"
+	HarmCategoryCivicIntegrity   HarmCategory = "HARM_CATEGORY_CIVIC_INTEGRITY"
+)
+
+// HarmBlockThreshold is the probability threshold at which content of a
+// harm category is blocked.
+type HarmBlockThreshold = string
+
+// Block thresholds supported by Gemini safety settings.
+const (
+	HarmBlockThresholdUnspecified    HarmBlockThreshold = "HARM_BLOCK_THRESHOLD_UNSPECIFIED"
+	HarmBlockThresholdLowAndAbove    HarmBlockThreshold = "BLOCK_LOW_AND_ABOVE"
+	HarmBlockThresholdMediumAndAbove HarmBlockThreshold = "BLOCK_MEDIUM_AND_ABOVE"
+	HarmBlockThresholdOnlyHigh       HarmBlockThreshold = "BLOCK_ONLY_HIGH"
+	HarmBlockThresholdNone           HarmBlockThreshold = "BLOCK_NONE"
+	HarmBlockThresholdOff            HarmBlockThreshold = "OFF"
+)
+
 // SafetySetting represents safety settings for the Google provider.
 type SafetySetting struct {
-	// 'HARM_CATEGORY_UNSPECIFIED',
-	// 'HARM_CATEGORY_HATE_SPEECH',
-	// 'HARM_CATEGORY_DANGEROUS_CONTENT',
-	// 'HARM_CATEGORY_HARASSMENT',
-	// 'HARM_CATEGORY_SEXUALLY_EXPLICIT',
-	// 'HARM_CATEGORY_CIVIC_INTEGRITY',
-	Category string `json:"category"`
+	Category  HarmCategory       `json:"category"`
+	Threshold HarmBlockThreshold `json:"threshold"`
+}
 
-	// 'HARM_BLOCK_THRESHOLD_UNSPECIFIED',
-	// 'BLOCK_LOW_AND_ABOVE',
-	// 'BLOCK_MEDIUM_AND_ABOVE',
-	// 'BLOCK_ONLY_HIGH',
-	// 'BLOCK_NONE',
-	// 'OFF',
-	Threshold string `json:"threshold"`
+// FunctionCallingMode is how the model calls functions.
+type FunctionCallingMode = string
+
+// Function calling modes supported by Gemini.
+const (
+	// FunctionCallingModeAuto lets the model answer or call a function.
+	FunctionCallingModeAuto FunctionCallingMode = "AUTO"
+	// FunctionCallingModeAny makes the model call a function.
+	FunctionCallingModeAny FunctionCallingMode = "ANY"
+	// FunctionCallingModeNone stops the model from calling functions.
+	FunctionCallingModeNone FunctionCallingMode = "NONE"
+	// FunctionCallingModeValidated lets the model answer or call a function,
+	// with the function calls following their schemas.
+	FunctionCallingModeValidated FunctionCallingMode = "VALIDATED"
+)
+
+// FunctionCallingConfig configures how the model calls functions, overriding
+// what the tool choice of the call sets.
+type FunctionCallingConfig struct {
+	// Mode is the function calling mode. When empty, it's the one of the
+	// tool choice, or ANY if AllowedFunctionNames is set.
+	Mode FunctionCallingMode `json:"mode,omitempty"`
+	// AllowedFunctionNames restricts the functions the model calls, with
+	// the ANY and VALIDATED modes.
+	AllowedFunctionNames []string `json:"allowed_function_names,omitempty"`
 }
 
 // ProviderOptions represents additional options for the Google provider.
@@ -107,6 +148,18 @@ type ProviderOptions struct {
 
 	// Optional. A list of unique safety settings for blocking unsafe content.
 	SafetySettings []SafetySetting `json:"safety_settings"`
+
+	// Optional. The IANA media type of the response, e.g.
+	// "application/json" or "text/x.enum".
+	ResponseMIMEType string `json:"response_mime_type,omitempty"`
+
+	// Optional. A JSON schema the response must follow. When set without
+	// ResponseMIMEType, the media type defaults to "application/json".
+	ResponseSchema map[string]any `json:"response_schema,omitempty"`
+
+	// Optional. How the model calls the functions of the call's tools.
+	FunctionCalling *FunctionCallingConfig `json:"function_calling,omitempty"`
+
 	// 'HARM_BLOCK_THRESHOLD_UNSPECIFIED',
 	// 'BLOCK_LOW_AND_ABOVE',
 	// 'BLOCK_MEDIUM_AND_ABOVE',
@@ -0,0 +1,143 @@
+package google
+
+import (
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+)
+
+func TestPrepareParams_ProviderOptions(t *testing.T) {
+	t.Parallel()
+
+	prompt := fantasy.Prompt{fantasy.NewUserMessage("Hello")}
+
+	t.Run("safety settings and response schema", func(t *testing.T) {
+		t.Parallel()
+
+		g := languageModel{modelID: "gemini-2.5-flash"}
+		config, _, warnings, err := g.prepareParams(fantasy.Call{
+			Prompt: prompt,
+			ProviderOptions: fantasy.ProviderOptions{Name: &ProviderOptions{
+				SafetySettings: []SafetySetting{
+					{Category: HarmCategoryHarassment, Threshold: HarmBlockThresholdOnlyHigh},
+				},
+				ResponseSchema: map[string]any{"type": "object"},
+			}},
+		})
+		require.NoError(t, err)
+		require.Empty(t, warnings)
+		require.Equal(t, []*genai.SafetySetting{{
+			Category:  genai.HarmCategoryHarassment,
+			Threshold: genai.HarmBlockThresholdBlockOnlyHigh,
+		}}, config.SafetySettings)
+		require.Equal(t, "application/json", config.ResponseMIMEType)
+		require.Equal(t, map[string]any{"type": "object"}, config.ResponseJsonSchema)
+	})
+
+	t.Run("explicit response mime type", func(t *testing.T) {
+		t.Parallel()
+
+		g := languageModel{modelID: "gemini-2.5-flash"}
+		config, _, _, err := g.prepareParams(fantasy.Call{
+			Prompt: prompt,
+			ProviderOptions: fantasy.ProviderOptions{Name: &ProviderOptions{
+				ResponseMIMEType: "text/x.enum",
+				ResponseSchema:   map[string]any{"type": "string", "enum": []any{"a", "b"}},
+			}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, "text/x.enum", config.ResponseMIMEType)
+	})
+
+	t.Run("output constraint", func(t *testing.T) {
+		t.Parallel()
+
+		g := languageModel{modelID: "gemini-2.5-flash"}
+		config, _, warnings, err := g.prepareParams(fantasy.Call{
+			Prompt:           prompt,
+			OutputConstraint: fantasy.JSONSchemaConstraint("answer", fantasy.Schema{Type: "string"}),
+		})
+		require.NoError(t, err)
+		require.Empty(t, warnings)
+		require.Equal(t, "application/json", config.ResponseMIMEType)
+		require.Equal(t, map[string]any{"type": "string"}, config.ResponseJsonSchema)
+
+		_, _, warnings, err = g.prepareParams(fantasy.Call{
+			Prompt:           prompt,
+			OutputConstraint: fantasy.RegexConstraint(`\d+`),
+		})
+		require.NoError(t, err)
+		require.Len(t, warnings, 1)
+		require.Equal(t, "output_constraint", warnings[0].Setting)
+	})
+
+	t.Run("function calling", func(t *testing.T) {
+		t.Parallel()
+
+		tools := []fantasy.Tool{
+			fantasy.FunctionTool{Name: "search", InputSchema: map[string]any{"type": "object"}},
+			fantasy.FunctionTool{Name: "delete", InputSchema: map[string]any{"type": "object"}},
+		}
+		required := fantasy.ToolChoiceRequired
+		g := languageModel{modelID: "gemini-2.5-flash"}
+
+		config, _, warnings, err := g.prepareParams(fantasy.Call{
+			Prompt: prompt,
+			Tools:  tools,
+			ProviderOptions: fantasy.ProviderOptions{Name: &ProviderOptions{
+				FunctionCalling: &FunctionCallingConfig{AllowedFunctionNames: []string{"search"}},
+			}},
+		})
+		require.NoError(t, err)
+		require.Empty(t, warnings)
+		require.Equal(t, &genai.FunctionCallingConfig{
+			Mode:                 genai.FunctionCallingConfigModeAny,
+			AllowedFunctionNames: []string{"search"},
+		}, config.ToolConfig.FunctionCallingConfig)
+
+		config, _, warnings, err = g.prepareParams(fantasy.Call{
+			Prompt:     prompt,
+			Tools:      tools,
+			ToolChoice: &required,
+			ProviderOptions: fantasy.ProviderOptions{Name: &ProviderOptions{
+				FunctionCalling: &FunctionCallingConfig{Mode: FunctionCallingModeValidated},
+			}},
+		})
+		require.NoError(t, err)
+		require.Empty(t, warnings)
+		require.Equal(t, genai.FunctionCallingConfigModeValidated, config.ToolConfig.FunctionCallingConfig.Mode)
+
+		_, _, warnings, err = g.prepareParams(fantasy.Call{
+			Prompt: prompt,
+			Tools:  tools,
+			ProviderOptions: fantasy.ProviderOptions{Name: &ProviderOptions{
+				FunctionCalling: &FunctionCallingConfig{AllowedFunctionNames: []string{"missing"}},
+			}},
+		})
+		require.NoError(t, err)
+		require.Len(t, warnings, 1)
+		require.Equal(t, "FunctionCalling", warnings[0].Setting)
+	})
+
+	t.Run("options round trip through json", func(t *testing.T) {
+		t.Parallel()
+
+		opts, err := ParseOptions(map[string]any{
+			"safety_settings": []any{
+				map[string]any{"category": "HARM_CATEGORY_HATE_SPEECH", "threshold": "BLOCK_NONE"},
+			},
+			"response_mime_type": "application/json",
+			"function_calling":   map[string]any{"mode": "ANY", "allowed_function_names": []any{"search"}},
+		})
+		require.NoError(t, err)
+		require.Equal(t, HarmCategoryHateSpeech, opts.SafetySettings[0].Category)
+		require.Equal(t, HarmBlockThresholdNone, opts.SafetySettings[0].Threshold)
+		require.Equal(t, "application/json", opts.ResponseMIMEType)
+		require.Equal(t, &FunctionCallingConfig{
+			Mode:                 FunctionCallingModeAny,
+			AllowedFunctionNames: []string{"search"},
+		}, opts.FunctionCalling)
+	})
+}
@@ -0,0 +1,60 @@
+package google
+
+import (
+	"charm.land/fantasy"
+	"google.golang.org/genai"
+)
+
+// mapSafety builds safety information from the prompt feedback and the
+// safety ratings of the first candidate. It returns nil when Gemini
+// reported neither.
+func mapSafety(response *genai.GenerateContentResponse) *fantasy.SafetyInfo {
+	if response == nil {
+		return nil
+	}
+	var info fantasy.SafetyInfo
+	if feedback := response.PromptFeedback; feedback != nil {
+		if feedback.BlockReason != "" {
+			info.Blocked = true
+			info.Reason = string(feedback.BlockReason)
+		}
+		info.Ratings = append(info.Ratings, mapSafetyRatings(feedback.SafetyRatings)...)
+	}
+	if len(response.Candidates) > 0 && response.Candidates[0] != nil {
+		candidate := response.Candidates[0]
+		if mapFinishReason(candidate.FinishReason) == fantasy.FinishReasonContentFilter {
+			info.Blocked = true
+			info.Reason = string(candidate.FinishReason)
+		}
+		info.Ratings = append(info.Ratings, mapSafetyRatings(candidate.SafetyRatings)...)
+	}
+	for _, rating := range info.Ratings {
+		info.Blocked = info.Blocked || rating.Blocked
+	}
+	if !info.Blocked && len(info.Ratings) == 0 {
+		return nil
+	}
+	return &info
+}
+
+func mapSafetyRatings(ratings []*genai.SafetyRating) []fantasy.SafetyRating {
+	mapped := make([]fantasy.SafetyRating, 0, len(ratings))
+	for _, rating := range ratings {
+		if rating == nil {
+			continue
+		}
+		mapped = append(mapped, fantasy.SafetyRating{
+			Category:    string(rating.Category),
+			Probability: string(rating.Probability),
+			Severity:    string(rating.Severity),
+			Blocked:     rating.Blocked,
+		})
+	}
+	return mapped
+}
+
+// isPromptBlocked reports whether Gemini rejected the prompt itself, in
+// which case the response carries no candidates.
+func isPromptBlocked(response *genai.GenerateContentResponse) bool {
+	return response != nil && response.PromptFeedback != nil && response.PromptFeedback.BlockReason != ""
+}
@@ -0,0 +1,57 @@
+package google
+
+import (
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+)
+
+func TestMapSafety(t *testing.T) {
+	t.Parallel()
+
+	t.Run("no safety data", func(t *testing.T) {
+		t.Parallel()
+		require.Nil(t, mapSafety(&genai.GenerateContentResponse{
+			Candidates: []*genai.Candidate{{FinishReason: genai.FinishReasonStop}},
+		}))
+	})
+
+	t.Run("candidate blocked for safety", func(t *testing.T) {
+		t.Parallel()
+		safety := mapSafety(&genai.GenerateContentResponse{
+			Candidates: []*genai.Candidate{{
+				FinishReason: genai.FinishReasonSafety,
+				SafetyRatings: []*genai.SafetyRating{
+					{Category: genai.HarmCategoryHarassment, Probability: genai.HarmProbabilityHigh, Blocked: true},
+				},
+			}},
+		})
+		require.Equal(t, &fantasy.SafetyInfo{
+			Blocked: true,
+			Reason:  string(genai.FinishReasonSafety),
+			Ratings: []fantasy.SafetyRating{{
+				Category:    string(genai.HarmCategoryHarassment),
+				Probability: string(genai.HarmProbabilityHigh),
+				Blocked:     true,
+			}},
+		}, safety)
+	})
+}
+
+func TestMapResponse_PromptBlocked(t *testing.T) {
+	t.Parallel()
+
+	g := languageModel{}
+	resp, err := g.mapResponse(&genai.GenerateContentResponse{
+		PromptFeedback: &genai.GenerateContentResponsePromptFeedback{
+			BlockReason: genai.BlockedReasonSafety,
+		},
+	}, nil)
+	require.NoError(t, err)
+	require.Equal(t, fantasy.FinishReasonContentFilter, resp.FinishReason)
+	require.NotNil(t, resp.Safety)
+	require.True(t, resp.Safety.Blocked)
+	require.Equal(t, string(genai.BlockedReasonSafety), resp.Safety.Reason)
+}
@@ -123,6 +123,61 @@ func TestUserAgent(t *testing.T) {
 		assert.True(t, findUA(captured, "custom-from-headers"))
 	})
 
+	t.Run("Call.ExtraQuery added to the URL", func(t *testing.T) {
+		t.Parallel()
+		var query string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			query = r.URL.Query().Get("route")
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]any{})
+		}))
+		defer server.Close()
+
+		p, err := New(
+			WithVertex("test-project", "us-central1"),
+			WithBaseURL(server.URL),
+			WithSkipAuth(true),
+		)
+		require.NoError(t, err)
+		model, err := p.LanguageModel(t.Context(), "gemini-2.0-flash")
+		require.NoError(t, err)
+		_, _ = model.Generate(t.Context(), fantasy.Call{
+			Prompt:     prompt,
+			ExtraQuery: map[string]string{"route": "eu"},
+		})
+		assert.Equal(t, "eu", query)
+	})
+
+	t.Run("Call.ExtraBody merged into the body", func(t *testing.T) {
+		t.Parallel()
+		var body map[string]any
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(map[string]any{})
+		}))
+		defer server.Close()
+
+		p, err := New(
+			WithVertex("test-project", "us-central1"),
+			WithBaseURL(server.URL),
+			WithSkipAuth(true),
+		)
+		require.NoError(t, err)
+		model, err := p.LanguageModel(t.Context(), "gemini-2.0-flash")
+		require.NoError(t, err)
+		_, _ = model.Generate(t.Context(), fantasy.Call{
+			Prompt:      prompt,
+			Temperature: fantasy.Opt(0.9),
+			ExtraBody: map[string]any{
+				"generationConfig": map[string]any{"newParam": "on"},
+			},
+		})
+		config, _ := body["generationConfig"].(map[string]any)
+		assert.Equal(t, "on", config["newParam"])
+		assert.Equal(t, 0.9, config["temperature"])
+	})
+
 	t.Run("WithUserAgent wins over WithHeaders", func(t *testing.T) {
 		t.Parallel()
 		server, captured := newUAServer()
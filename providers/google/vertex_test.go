@@ -0,0 +1,33 @@
+package google
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublisherModelID(t *testing.T) {
+	t.Parallel()
+
+	for input, want := range map[string]string{
+		"claude-sonnet-4": "claude-sonnet-4",
+		"publishers/anthropic/models/claude-sonnet-4":                 "claude-sonnet-4",
+		"projects/p/locations/us-east5/publishers/anthropic/models/x": "x",
+		"models/gemini-2.5-flash":                                     "models/gemini-2.5-flash",
+	} {
+		require.Equal(t, want, publisherModelID(input), input)
+	}
+}
+
+func TestVertexServiceAccount_InvalidJSON(t *testing.T) {
+	t.Parallel()
+
+	p, err := New(
+		WithVertex("project", "us-central1"),
+		WithVertexServiceAccount([]byte(`{"type":"authorized_user"}`)),
+	)
+	require.NoError(t, err)
+
+	_, err = p.LanguageModel(t.Context(), "gemini-2.5-flash")
+	require.Error(t, err)
+}
@@ -0,0 +1,177 @@
+// Package huggingface provides an implementation of the fantasy AI SDK for
+// the Hugging Face Inference API and self-hosted Text Generation Inference
+// (TGI) endpoints.
+package huggingface
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/openai"
+	"charm.land/fantasy/providers/openaicompat"
+	"github.com/openai/openai-go/v3/option"
+)
+
+type options struct {
+	openaiOptions        []openai.Option
+	languageModelOptions []openai.LanguageModelOption
+	sdkOptions           []option.RequestOption
+	objectMode           fantasy.ObjectMode
+	tgi                  bool
+}
+
+const (
+	// DefaultURL is the URL of the serverless Hugging Face Inference API.
+	DefaultURL = "https://router.huggingface.co/v1"
+	// Name is the name of the Hugging Face provider.
+	Name = "huggingface"
+)
+
+// Option defines a function that configures Hugging Face provider options.
+type Option = func(*options)
+
+// New creates a new Hugging Face provider with the given options. By
+// default it talks to the serverless Inference API; use WithEndpointURL to
+// target a TGI endpoint.
+func New(opts ...Option) (fantasy.Provider, error) {
+	providerOptions := options{
+		openaiOptions: []openai.Option{
+			openai.WithName(Name),
+			openai.WithBaseURL(DefaultURL),
+		},
+		objectMode: fantasy.ObjectModeTool, // Default to tool mode for huggingface
+	}
+	for _, o := range opts {
+		o(&providerOptions)
+	}
+
+	languageModelOptions := []openai.LanguageModelOption{
+		openai.WithLanguageModelPrepareCallFunc(languagePrepareModelCall),
+		openai.WithLanguageModelStreamExtraFunc(openaicompat.StreamExtraFunc),
+		openai.WithLanguageModelExtraContentFunc(openaicompat.ExtraContentFunc),
+	}
+	if providerOptions.tgi {
+		languageModelOptions = append(languageModelOptions, openai.WithLanguageModelOutputConstraintFunc(languageModelOutputConstraint))
+	}
+
+	// Handle object mode: convert unsupported modes to tool
+	// The Inference API routes to backends with varying JSON mode support, so we use tool or text
+	objectMode := providerOptions.objectMode
+	if objectMode == fantasy.ObjectModeAuto || objectMode == fantasy.ObjectModeJSON {
+		objectMode = fantasy.ObjectModeTool
+	}
+
+	providerOptions.openaiOptions = append(
+		providerOptions.openaiOptions,
+		openai.WithSDKOptions(providerOptions.sdkOptions...),
+		openai.WithLanguageModelOptions(append(languageModelOptions, providerOptions.languageModelOptions...)...),
+		openai.WithObjectMode(objectMode),
+	)
+	return openai.New(providerOptions.openaiOptions...)
+}
+
+// WithAPIKey sets the Hugging Face access token.
+func WithAPIKey(apiKey string) Option {
+	return func(o *options) {
+		o.openaiOptions = append(o.openaiOptions, openai.WithAPIKey(apiKey))
+	}
+}
+
+// WithBaseURL sets the base URL of an OpenAI-compatible Hugging Face API,
+// e.g. a specific inference provider on the router.
+func WithBaseURL(url string) Option {
+	return func(o *options) {
+		o.openaiOptions = append(o.openaiOptions, openai.WithBaseURL(url))
+	}
+}
+
+// WithEndpointURL targets a Text Generation Inference endpoint, such as a
+// dedicated Inference Endpoint or a self-hosted TGI server, e.g.
+// "http://localhost:8080". Output constraints are sent using TGI's grammar
+// parameter.
+func WithEndpointURL(url string) Option {
+	return func(o *options) {
+		o.openaiOptions = append(o.openaiOptions, openai.WithBaseURL(strings.TrimSuffix(url, "/")+"/v1"))
+		o.tgi = true
+	}
+}
+
+// WithName sets the name for the Hugging Face provider.
+func WithName(name string) Option {
+	return func(o *options) {
+		o.openaiOptions = append(o.openaiOptions, openai.WithName(name))
+	}
+}
+
+// WithHeaders sets the headers for the Hugging Face provider.
+func WithHeaders(headers map[string]string) Option {
+	return func(o *options) {
+		o.openaiOptions = append(o.openaiOptions, openai.WithHeaders(headers))
+	}
+}
+
+// WithHTTPClient sets the HTTP client for the Hugging Face provider.
+func WithHTTPClient(client option.HTTPClient) Option {
+	return func(o *options) {
+		o.openaiOptions = append(o.openaiOptions, openai.WithHTTPClient(client))
+	}
+}
+
+// WithHTTPTimeout sets the overall timeout of each request, including
+// reading streamed responses. Use fantasy.WithStreamIdleTimeout to detect
+// stalled streams instead. It's ignored when WithHTTPClient is set.
+func WithHTTPTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.openaiOptions = append(o.openaiOptions, openai.WithHTTPTimeout(timeout))
+	}
+}
+
+// WithProxy sends the requests through the given proxy instead of the one
+// from the environment. It's ignored when WithHTTPClient is set.
+func WithProxy(proxy *url.URL) Option {
+	return func(o *options) {
+		o.openaiOptions = append(o.openaiOptions, openai.WithProxy(proxy))
+	}
+}
+
+// WithTransport sets the HTTP transport of the Hugging Face provider. It's
+// ignored when WithHTTPClient is set.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *options) {
+		o.openaiOptions = append(o.openaiOptions, openai.WithTransport(transport))
+	}
+}
+
+// WithSDKOptions sets the SDK options for the Hugging Face provider.
+func WithSDKOptions(opts ...option.RequestOption) Option {
+	return func(o *options) {
+		o.sdkOptions = append(o.sdkOptions, opts...)
+	}
+}
+
+// WithObjectMode sets the object generation mode for the Hugging Face provider.
+// Supported modes: ObjectModeTool, ObjectModeText.
+// ObjectModeAuto and ObjectModeJSON are automatically converted to ObjectModeTool.
+func WithObjectMode(om fantasy.ObjectMode) Option {
+	return func(o *options) {
+		o.objectMode = om
+	}
+}
+
+// WithUserAgent sets an explicit User-Agent header, overriding the default and any
+// value set via WithHeaders.
+func WithUserAgent(ua string) Option {
+	return func(o *options) {
+		o.openaiOptions = append(o.openaiOptions, openai.WithUserAgent(ua))
+	}
+}
+
+// WithLanguageModelOptions appends language model options to the provider.
+func WithLanguageModelOptions(opts ...openai.LanguageModelOption) Option {
+	return func(o *options) {
+		o.languageModelOptions = append(o.languageModelOptions, opts...)
+	}
+}
@@ -0,0 +1,109 @@
+package huggingface
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/require"
+)
+
+func newChatServer(t *testing.T) (*httptest.Server, chan map[string]any) {
+	t.Helper()
+
+	calls := make(chan map[string]any, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		body["path"] = r.URL.Path
+		calls <- body
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"id":      "chatcmpl-1",
+			"object":  "chat.completion",
+			"created": 1,
+			"model":   "tgi",
+			"choices": []any{map[string]any{
+				"index":         0,
+				"finish_reason": "stop",
+				"message":       map[string]any{"role": "assistant", "content": "42"},
+			}},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server, calls
+}
+
+func TestLanguageModel_Grammar(t *testing.T) {
+	t.Parallel()
+
+	t.Run("provider options grammar", func(t *testing.T) {
+		t.Parallel()
+
+		server, calls := newChatServer(t)
+		provider, err := New(WithBaseURL(server.URL + "/v1"))
+		require.NoError(t, err)
+		model, err := provider.LanguageModel(t.Context(), "meta-llama/Llama-3.1-8B-Instruct")
+		require.NoError(t, err)
+
+		_, err = model.Generate(t.Context(), fantasy.Call{
+			Prompt: fantasy.Prompt{fantasy.NewUserMessage("Pick a number")},
+			ProviderOptions: NewProviderOptions(&ProviderOptions{
+				Grammar: &Grammar{Type: GrammarTypeRegex, Value: `\d+`},
+				Seed:    fantasy.Opt[int64](7),
+			}),
+		})
+		require.NoError(t, err)
+
+		body := <-calls
+		require.Equal(t, "/v1/chat/completions", body["path"])
+		require.Equal(t, map[string]any{"type": "regex", "value": `\d+`}, body["response_format"])
+		require.Equal(t, float64(7), body["seed"])
+	})
+
+	t.Run("output constraint on tgi endpoint", func(t *testing.T) {
+		t.Parallel()
+
+		server, calls := newChatServer(t)
+		provider, err := New(WithEndpointURL(server.URL))
+		require.NoError(t, err)
+		model, err := provider.LanguageModel(t.Context(), "tgi")
+		require.NoError(t, err)
+
+		resp, err := model.Generate(t.Context(), fantasy.Call{
+			Prompt:           fantasy.Prompt{fantasy.NewUserMessage("Pick a number")},
+			OutputConstraint: fantasy.JSONSchemaConstraint("number", fantasy.Schema{Type: "integer"}),
+		})
+		require.NoError(t, err)
+		require.Empty(t, resp.Warnings)
+
+		body := <-calls
+		require.Equal(t, "/v1/chat/completions", body["path"])
+		require.Equal(t, map[string]any{
+			"type":  "json",
+			"value": map[string]any{"type": "integer"},
+		}, body["response_format"])
+	})
+
+	t.Run("output constraint on inference api", func(t *testing.T) {
+		t.Parallel()
+
+		server, calls := newChatServer(t)
+		provider, err := New(WithBaseURL(server.URL))
+		require.NoError(t, err)
+		model, err := provider.LanguageModel(t.Context(), "meta-llama/Llama-3.1-8B-Instruct")
+		require.NoError(t, err)
+
+		_, err = model.Generate(t.Context(), fantasy.Call{
+			Prompt:           fantasy.Prompt{fantasy.NewUserMessage("Pick a number")},
+			OutputConstraint: fantasy.JSONSchemaConstraint("number", fantasy.Schema{Type: "integer"}),
+		})
+		require.NoError(t, err)
+
+		format := (<-calls)["response_format"].(map[string]any)
+		require.Equal(t, "json_schema", format["type"])
+	})
+}
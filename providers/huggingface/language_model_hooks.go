@@ -0,0 +1,55 @@
+package huggingface
+
+import (
+	"maps"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/schema"
+	openaisdk "github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/packages/param"
+)
+
+func languagePrepareModelCall(model fantasy.LanguageModel, params *openaisdk.ChatCompletionNewParams, call fantasy.Call) ([]fantasy.CallWarning, error) {
+	providerOptions := &ProviderOptions{}
+	if v, ok := call.ProviderOptions[model.Provider()]; ok {
+		providerOptions, ok = v.(*ProviderOptions)
+		if !ok {
+			return nil, &fantasy.Error{Title: "invalid argument", Message: "huggingface provider options should be *huggingface.ProviderOptions"}
+		}
+	}
+
+	extraFields := make(map[string]any)
+	if providerOptions.Grammar != nil {
+		extraFields["response_format"] = providerOptions.Grammar
+	}
+	if providerOptions.Seed != nil {
+		params.Seed = param.NewOpt(*providerOptions.Seed)
+	}
+
+	maps.Copy(extraFields, providerOptions.ExtraBody)
+	if len(extraFields) > 0 {
+		params.SetExtraFields(extraFields)
+	}
+	return nil, nil
+}
+
+// languageModelOutputConstraint maps output constraints to TGI grammars.
+// TGI has no GBNF support.
+func languageModelOutputConstraint(params *openaisdk.ChatCompletionNewParams, constraint *fantasy.OutputConstraint) ([]fantasy.CallWarning, error) {
+	var grammar Grammar
+	switch constraint.Type {
+	case fantasy.OutputConstraintTypeJSONSchema:
+		grammar = Grammar{Type: GrammarTypeJSON, Value: schema.ToMap(constraint.Schema)}
+	case fantasy.OutputConstraintTypeRegex:
+		grammar = Grammar{Type: GrammarTypeRegex, Value: constraint.Regex}
+	default:
+		return []fantasy.CallWarning{fantasy.UnsupportedOutputConstraintWarning(constraint)}, nil
+	}
+	fields := params.ExtraFields()
+	if fields == nil {
+		fields = make(map[string]any)
+	}
+	fields["response_format"] = grammar
+	params.SetExtraFields(fields)
+	return nil, nil
+}
@@ -0,0 +1,86 @@
+package huggingface
+
+import (
+	"encoding/json"
+
+	"charm.land/fantasy"
+)
+
+// Global type identifiers for Hugging Face-specific provider data.
+const (
+	TypeProviderOptions = Name + ".options"
+)
+
+// Register Hugging Face provider-specific types with the global registry.
+func init() {
+	fantasy.RegisterProviderType(TypeProviderOptions, func(data []byte) (fantasy.ProviderOptionsData, error) {
+		var v ProviderOptions
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		return &v, nil
+	})
+}
+
+// GrammarType is the kind of a TGI grammar.
+type GrammarType = string
+
+// Grammar types supported by TGI.
+const (
+	GrammarTypeJSON  GrammarType = "json"
+	GrammarTypeRegex GrammarType = "regex"
+)
+
+// Grammar constrains TGI generation to a JSON schema or a regular
+// expression. Value is the schema for GrammarTypeJSON and the pattern for
+// GrammarTypeRegex.
+type Grammar struct {
+	Type  GrammarType `json:"type"`
+	Value any         `json:"value"`
+}
+
+// ProviderOptions represents additional options for the Hugging Face provider.
+type ProviderOptions struct {
+	// Grammar is sent as TGI's response_format grammar. It is only
+	// supported by TGI endpoints.
+	Grammar *Grammar `json:"grammar,omitempty"`
+	Seed    *int64   `json:"seed,omitempty"`
+
+	ExtraBody map[string]any `json:"extra_body,omitempty"`
+}
+
+// Options implements the ProviderOptions interface.
+func (*ProviderOptions) Options() {}
+
+// MarshalJSON implements custom JSON marshaling with type info for ProviderOptions.
+func (o ProviderOptions) MarshalJSON() ([]byte, error) {
+	type plain ProviderOptions
+	return fantasy.MarshalProviderType(TypeProviderOptions, plain(o))
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling with type info for ProviderOptions.
+func (o *ProviderOptions) UnmarshalJSON(data []byte) error {
+	type plain ProviderOptions
+	var p plain
+	if err := fantasy.UnmarshalProviderType(data, &p); err != nil {
+		return err
+	}
+	*o = ProviderOptions(p)
+	return nil
+}
+
+// NewProviderOptions creates new provider options for the Hugging Face provider.
+func NewProviderOptions(opts *ProviderOptions) fantasy.ProviderOptions {
+	return fantasy.ProviderOptions{
+		Name: opts,
+	}
+}
+
+// ParseOptions parses provider options from a map for the Hugging Face provider.
+func ParseOptions(data map[string]any) (*ProviderOptions, error) {
+	var options ProviderOptions
+	if err := fantasy.ParseOptions(data, &options); err != nil {
+		return nil, err
+	}
+	return &options, nil
+}
@@ -0,0 +1,62 @@
+// Package httpclient builds the HTTP client of the providers from their
+// timeout, proxy and transport options.
+package httpclient
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Connection setup timeouts of the default transport. They only bound
+// establishing a connection, not waiting for or streaming the response,
+// which can take minutes.
+const (
+	DefaultDialTimeout         = 10 * time.Second
+	DefaultTLSHandshakeTimeout = 10 * time.Second
+)
+
+// Config holds the HTTP options of a provider.
+type Config struct {
+	// Timeout is the overall timeout of a request, including reading the
+	// response body. Zero means no timeout.
+	Timeout time.Duration
+	// Proxy is the proxy requests go through. When nil the environment
+	// proxy settings are used.
+	Proxy *url.URL
+	// Transport replaces the default transport.
+	Transport http.RoundTripper
+}
+
+// DefaultTransport returns a transport like http.DefaultTransport with the
+// default connection setup timeouts.
+func DefaultTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   DefaultDialTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = DefaultTLSHandshakeTimeout
+	return transport
+}
+
+// Client returns an HTTP client for the config. The proxy is only applied
+// to a custom transport if it's an *http.Transport.
+func (c Config) Client() *http.Client {
+	transport := c.Transport
+	if transport == nil {
+		transport = DefaultTransport()
+	}
+	if c.Proxy != nil {
+		if t, ok := transport.(*http.Transport); ok {
+			t = t.Clone()
+			t.Proxy = http.ProxyURL(c.Proxy)
+			transport = t
+		}
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   c.Timeout,
+	}
+}
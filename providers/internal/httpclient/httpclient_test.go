@@ -0,0 +1,53 @@
+package httpclient
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestConfigClient(t *testing.T) {
+	t.Parallel()
+
+	t.Run("defaults", func(t *testing.T) {
+		t.Parallel()
+		client := Config{}.Client()
+		require.Zero(t, client.Timeout)
+		transport, ok := client.Transport.(*http.Transport)
+		require.True(t, ok)
+		require.Equal(t, DefaultTLSHandshakeTimeout, transport.TLSHandshakeTimeout)
+		require.NotNil(t, transport.Proxy)
+	})
+
+	t.Run("timeout and proxy", func(t *testing.T) {
+		t.Parallel()
+		proxy, err := url.Parse("http://proxy.local:3128")
+		require.NoError(t, err)
+		client := Config{Timeout: time.Minute, Proxy: proxy}.Client()
+		require.Equal(t, time.Minute, client.Timeout)
+
+		req, err := http.NewRequest(http.MethodGet, "https://api.example.com", nil)
+		require.NoError(t, err)
+		got, err := client.Transport.(*http.Transport).Proxy(req)
+		require.NoError(t, err)
+		require.Equal(t, proxy, got)
+	})
+
+	t.Run("custom transport", func(t *testing.T) {
+		t.Parallel()
+		custom := roundTripperFunc(func(*http.Request) (*http.Response, error) { return nil, nil })
+		client := Config{Transport: custom}.Client()
+		require.NotNil(t, client.Transport)
+		_, isTransport := client.Transport.(*http.Transport)
+		require.False(t, isTransport)
+	})
+}
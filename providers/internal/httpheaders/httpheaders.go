@@ -68,3 +68,13 @@ func CallHeaders(headers map[string]string) (map[string]string, bool) {
 	}
 	return nil, false
 }
+
+// CallQuery resolves per-call query parameters. It returns the parameters
+// and true if they should be added to the request URL, or nil and false if
+// there are none.
+func CallQuery(query map[string]string) (map[string]string, bool) {
+	if len(query) > 0 {
+		return query, true
+	}
+	return nil, false
+}
@@ -0,0 +1,61 @@
+package kronk
+
+import (
+	"context"
+	"fmt"
+
+	"charm.land/fantasy"
+	"github.com/ardanlabs/kronk/sdk/kronk"
+	"github.com/ardanlabs/kronk/sdk/kronk/model"
+)
+
+type embeddingModel struct {
+	provider string
+	modelID  string
+	kronk    *kronk.Kronk
+}
+
+// Model implements fantasy.EmbeddingModel.
+func (e *embeddingModel) Model() string {
+	return e.modelID
+}
+
+// Provider implements fantasy.EmbeddingModel.
+func (e *embeddingModel) Provider() string {
+	return e.provider
+}
+
+// Embed implements fantasy.EmbeddingModel.
+func (e *embeddingModel) Embed(ctx context.Context, call fantasy.EmbeddingCall) (*fantasy.EmbeddingResponse, error) {
+	if len(call.Values) == 0 {
+		return &fantasy.EmbeddingResponse{}, nil
+	}
+
+	d := model.D{
+		"input": call.Values,
+	}
+	if call.Dimensions != nil {
+		d["dimensions"] = int(*call.Dimensions)
+	}
+
+	resp, err := e.kronk.Embeddings(ctx, d)
+	if err != nil {
+		return nil, err
+	}
+
+	embeddings := make([]fantasy.Embedding, len(call.Values))
+	for _, data := range resp.Data {
+		if data.Index < 0 || data.Index >= len(embeddings) {
+			return nil, fmt.Errorf("embedding index %d out of range", data.Index)
+		}
+		embeddings[data.Index] = data.Embedding
+	}
+
+	return &fantasy.EmbeddingResponse{
+		Embeddings: embeddings,
+		Usage: fantasy.Usage{
+			InputTokens: int64(resp.Usage.PromptTokens),
+			TotalTokens: int64(resp.Usage.TotalTokens),
+		},
+	}, nil
+}
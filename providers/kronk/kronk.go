@@ -5,6 +5,8 @@ package kronk
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
 	"sync"
 
 	"charm.land/fantasy"
@@ -19,13 +21,34 @@ const (
 	Name = "kronk"
 )
 
+// Manager manages the models loaded by a Kronk provider. The provider
+// returned by New implements it:
+//
+//	manager := provider.(kronk.Manager)
+//	defer manager.Close(ctx)
+type Manager interface {
+	// Stats returns the resource usage of every loaded model, sorted by
+	// ID.
+	Stats() []ModelStats
+	// Unload unloads a single model, freeing its resources. Language and
+	// embedding models obtained for it before can't be used anymore;
+	// getting them again from the provider loads the model again. It is a
+	// no-op if the model isn't loaded.
+	Unload(ctx context.Context, id string) error
+	// Close unloads all models. Call it when done with the provider.
+	Close(ctx context.Context) error
+}
+
+var _ Manager = (*provider)(nil)
+
 type provider struct {
 	options options
 	mu      sync.Mutex
 	kronks  map[string]*kronk.Kronk
 }
 
-// New creates a new Kronk provider with the given options.
+// New creates a new Kronk provider with the given options. It also
+// implements Manager and fantasy.EmbeddingProvider.
 func New(opts ...Option) (fantasy.Provider, error) {
 	providerOptions := options{
 		languageModelOptions: make([]LanguageModelOption, 0),
@@ -53,34 +76,131 @@ func (p *provider) Name() string {
 }
 
 // LanguageModel implements fantasy.Provider.
-// The modelURL parameter should be a URL to a GGUF model file (e.g., from Hugging Face).
+// The modelURL parameter should be a URL to a GGUF model file (e.g., from Hugging Face)
+// or the ID of a model registered with WithModel.
 func (p *provider) LanguageModel(ctx context.Context, modelURL string) (fantasy.LanguageModel, error) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	if krn, ok := p.kronks[modelURL]; ok {
-		opts := append(p.options.languageModelOptions, WithLanguageModelObjectMode(p.options.objectMode))
-		return newLanguageModel(modelURL, p.options.name, krn, opts...), nil
+	krn, err := p.load(ctx, modelURL)
+	if err != nil {
+		return nil, err
 	}
 
-	mp, err := p.installSystem(ctx, modelURL)
+	opts := append(p.options.languageModelOptions, WithLanguageModelObjectMode(p.options.objectMode))
+
+	return newLanguageModel(modelURL, p.options.name, krn, opts...), nil
+}
+
+// EmbeddingModel implements fantasy.EmbeddingProvider.
+// The modelURL parameter should be a URL to a GGUF embedding model file or
+// the ID of a model registered with WithModel.
+func (p *provider) EmbeddingModel(ctx context.Context, modelURL string) (fantasy.EmbeddingModel, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	krn, err := p.load(ctx, modelURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if !krn.ModelInfo().IsEmbedModel {
+		return nil, fmt.Errorf("model %s is not an embedding model", modelURL)
+	}
+
+	return &embeddingModel{
+		provider: p.options.name,
+		modelID:  modelURL,
+		kronk:    krn,
+	}, nil
+}
+
+// load returns the Kronk instance for id, loading the model on first use.
+// Callers must hold p.mu.
+func (p *provider) load(ctx context.Context, id string) (*kronk.Kronk, error) {
+	if krn, ok := p.kronks[id]; ok {
+		return krn, nil
+	}
+
+	entry, ok := p.options.models[id]
+	if !ok {
+		entry = modelEntry{source: id}
+	}
+
+	mp, err := p.installSystem(ctx, entry.source)
 	if err != nil {
 		return nil, fmt.Errorf("failed to install system: %w", err)
 	}
 
-	krn, err := p.newKronk(mp)
+	krn, err := p.newKronk(mp, entry.opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create kronk instance: %w", err)
 	}
 
-	p.kronks[modelURL] = krn
+	p.kronks[id] = krn
+	return krn, nil
+}
 
-	opts := append(p.options.languageModelOptions, WithLanguageModelObjectMode(p.options.objectMode))
+// ModelStats describes the resources used by a loaded model.
+type ModelStats struct {
+	// ID is the ID or URL the model was loaded with.
+	ID string
+	// Size is the size of the model weights in bytes.
+	Size uint64
+	// VRAMTotal is the estimated GPU memory used by the model in bytes.
+	VRAMTotal int64
+	// SlotMemory is the memory used by the model's KV cache slots in bytes.
+	SlotMemory int64
+	// ContextWindow is the context size in tokens.
+	ContextWindow int
+	// GPULayers is the number of layers offloaded to the GPU.
+	GPULayers int
+	// ActiveStreams is the number of requests currently being served.
+	ActiveStreams int
+}
 
-	return newLanguageModel(modelURL, p.options.name, krn, opts...), nil
+// Stats implements Manager.
+func (p *provider) Stats() []ModelStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := make([]ModelStats, 0, len(p.kronks))
+	for id, krn := range p.kronks {
+		info := krn.ModelInfo()
+		cfg := krn.ModelConfig()
+		stats = append(stats, ModelStats{
+			ID:            id,
+			Size:          info.Size,
+			VRAMTotal:     info.VRAMTotal,
+			SlotMemory:    info.SlotMemory,
+			ContextWindow: cfg.ContextWindow(),
+			GPULayers:     cfg.NGpuLayers(),
+			ActiveStreams: krn.ActiveStreams(),
+		})
+	}
+	slices.SortFunc(stats, func(a, b ModelStats) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+	return stats
+}
+
+// Unload implements Manager.
+func (p *provider) Unload(ctx context.Context, id string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	krn, ok := p.kronks[id]
+	if !ok {
+		return nil
+	}
+	delete(p.kronks, id)
+	if err := krn.Unload(ctx); err != nil {
+		return fmt.Errorf("failed to unload model %s: %w", id, err)
+	}
+	return nil
 }
 
-// Close unloads all Kronk instances. Call this when done with the provider.
+// Close implements Manager.
 func (p *provider) Close(ctx context.Context) error {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -130,18 +250,29 @@ func (p *provider) installSystem(ctx context.Context, modelSource string) (model
 	return mp, nil
 }
 
-func (p *provider) newKronk(mp models.Path) (*kronk.Kronk, error) {
+func (p *provider) newKronk(mp models.Path, opts ...ModelConfigOption) (*kronk.Kronk, error) {
 	if err := kronk.Init(); err != nil {
 		return nil, fmt.Errorf("unable to init kronk: %w", err)
 	}
 
-	cfg := p.options.modelConfig
-	cfg.ModelFiles = mp.ModelFiles
-
-	krn, err := kronk.New(model.WithConfig(cfg))
+	krn, err := kronk.New(model.WithConfig(p.modelConfig(mp, opts...)))
 	if err != nil {
 		return nil, fmt.Errorf("unable to create inference model: %w", err)
 	}
 
 	return krn, nil
 }
+
+// modelConfig returns the configuration to load the model files with: the
+// provider's configuration, adjusted by its options and then by opts.
+func (p *provider) modelConfig(mp models.Path, opts ...ModelConfigOption) model.Config {
+	cfg := p.options.modelConfig
+	for _, opt := range p.options.modelConfigOptions {
+		opt(&cfg)
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	cfg.ModelFiles = mp.ModelFiles
+	return cfg
+}
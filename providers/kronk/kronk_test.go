@@ -0,0 +1,67 @@
+package kronk
+
+import (
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/ardanlabs/kronk/sdk/kronk/model"
+	"github.com/ardanlabs/kronk/sdk/tools/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	p, err := New()
+	require.NoError(t, err)
+	require.Equal(t, Name, p.Name())
+	require.Implements(t, (*fantasy.EmbeddingProvider)(nil), p)
+
+	manager, ok := p.(Manager)
+	require.True(t, ok)
+	require.Empty(t, manager.Stats())
+	require.NoError(t, manager.Unload(t.Context(), "not-loaded"))
+	require.NoError(t, manager.Close(t.Context()))
+}
+
+func TestModelConfig(t *testing.T) {
+	t.Parallel()
+
+	p, err := New(
+		// Options apply on top of the base configuration whatever their
+		// order.
+		WithModelConfigOptions(GPULayers(10)),
+		WithModelConfig(model.Config{PtrContextWindow: new(8192), FlashAttention: model.FlashAttentionEnabled}),
+		WithModel("small", "https://example.com/small.gguf", ContextWindow(2048)),
+	)
+	require.NoError(t, err)
+	prov := p.(*provider)
+	mp := models.Path{ModelFiles: []string{"small.gguf"}}
+
+	cfg := prov.modelConfig(mp)
+	require.Equal(t, 10, cfg.NGpuLayers())
+	require.Equal(t, 8192, cfg.ContextWindow())
+	require.Equal(t, []string{"small.gguf"}, cfg.ModelFiles)
+
+	entry := prov.options.models["small"]
+	require.Equal(t, "https://example.com/small.gguf", entry.source)
+	cfg = prov.modelConfig(mp, entry.opts...)
+	require.Equal(t, 10, cfg.NGpuLayers())
+	require.Equal(t, 2048, cfg.ContextWindow())
+	require.Equal(t, model.FlashAttentionEnabled, cfg.FlashAttention)
+
+	// The provider-wide configuration isn't changed by the model's.
+	require.Equal(t, 8192, prov.modelConfig(mp).ContextWindow())
+}
+
+func TestEmbeddingModel(t *testing.T) {
+	t.Parallel()
+
+	m := &embeddingModel{provider: Name, modelID: "embed"}
+	require.Equal(t, Name, m.Provider())
+	require.Equal(t, "embed", m.Model())
+
+	resp, err := m.Embed(t.Context(), fantasy.EmbeddingCall{})
+	require.NoError(t, err)
+	require.Empty(t, resp.Embeddings)
+}
@@ -5,13 +5,15 @@ import (
 	"encoding/json"
 	"errors"
 	"io"
+	"sync"
 
 	"charm.land/fantasy"
 	"charm.land/fantasy/object"
+	"charm.land/fantasy/schema"
+	"charm.land/fantasy/tokenizer"
 	"github.com/ardanlabs/kronk/sdk/kronk"
 	"github.com/ardanlabs/kronk/sdk/kronk/model"
 	xjson "github.com/charmbracelet/x/json"
-	"github.com/google/uuid"
 )
 
 type languageModel struct {
@@ -22,6 +24,10 @@ type languageModel struct {
 	prepareCallFunc     LanguageModelPrepareCallFunc
 	mapFinishReasonFunc LanguageModelMapFinishReasonFunc
 	toPromptFunc        LanguageModelToPromptFunc
+
+	tokenizerOnce sync.Once
+	tokenizer     tokenizer.Tokenizer
+	tokenizerErr  error
 }
 
 // LanguageModelOption is a function that configures a languageModel.
@@ -92,6 +98,9 @@ func (l *languageModel) Provider() string {
 
 func (l *languageModel) prepareDocument(call fantasy.Call) (model.D, []fantasy.CallWarning, error) {
 	messages, warnings := l.toPromptFunc(call.Prompt, l.provider, l.modelID)
+	if err := fantasy.CheckDroppedContent(call, warnings); err != nil {
+		return nil, nil, err
+	}
 
 	if call.TopK != nil {
 		warnings = append(warnings, fantasy.CallWarning{
@@ -141,6 +150,20 @@ func (l *languageModel) prepareDocument(call fantasy.Call) (model.D, []fantasy.C
 		warnings = append(warnings, optionsWarnings...)
 	}
 
+	if c := call.OutputConstraint; c != nil {
+		switch c.Type {
+		case fantasy.OutputConstraintTypeGrammar:
+			d["grammar"] = c.Grammar
+		case fantasy.OutputConstraintTypeJSONSchema:
+			d["response_format"] = model.D{
+				"type":        "json_schema",
+				"json_schema": model.D{"schema": schema.ToMap(c.Schema)},
+			}
+		default:
+			warnings = append(warnings, fantasy.UnsupportedOutputConstraintWarning(c))
+		}
+	}
+
 	if len(call.Tools) > 0 {
 		tools, toolWarnings := toKronkTools(call.Tools)
 		d["tools"] = tools
@@ -350,7 +373,7 @@ func (l *languageModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.
 
 					toolID := tc.ID
 					if toolID == "" {
-						toolID = uuid.NewString()
+						toolID = fantasy.NewID()
 					}
 
 					if !yield(fantasy.StreamPart{
@@ -504,7 +527,7 @@ func (l *languageModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.
 					case false:
 						toolID := tc.ID
 						if toolID == "" {
-							toolID = uuid.NewString()
+							toolID = fantasy.NewID()
 						}
 
 						if !yield(fantasy.StreamPart{
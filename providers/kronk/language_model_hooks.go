@@ -145,7 +145,7 @@ func DefaultToPrompt(prompt fantasy.Prompt, _ string, _ string) ([]model.D, []fa
 
 					default:
 						warnings = append(warnings, fantasy.CallWarning{
-							Type:    fantasy.CallWarningTypeOther,
+							Type:    fantasy.CallWarningTypeDroppedContent,
 							Message: fmt.Sprintf("file part media type %s not supported", filePart.MediaType),
 						})
 					}
@@ -17,11 +17,44 @@ type Logger func(ctx context.Context, msg string, args ...any)
 type options struct {
 	name                 string
 	modelConfig          model.Config
+	modelConfigOptions   []ModelConfigOption
+	models               map[string]modelEntry
 	logger               Logger
 	objectMode           fantasy.ObjectMode
 	languageModelOptions []LanguageModelOption
 }
 
+// modelEntry is a model registered with WithModel.
+type modelEntry struct {
+	source string
+	opts   []ModelConfigOption
+}
+
+// ModelConfigOption adjusts the configuration a model is loaded with.
+type ModelConfigOption func(*model.Config)
+
+// GPULayers sets the number of model layers offloaded to the GPU.
+func GPULayers(n int) ModelConfigOption {
+	return func(cfg *model.Config) {
+		cfg.PtrNGpuLayers = &n
+	}
+}
+
+// ContextWindow sets the context size, in tokens, of the model.
+func ContextWindow(n int) ModelConfigOption {
+	return func(cfg *model.Config) {
+		cfg.PtrContextWindow = &n
+	}
+}
+
+// FlashAttention sets whether flash attention is enabled, disabled or
+// chosen by llama.cpp.
+func FlashAttention(mode model.FlashAttentionType) ModelConfigOption {
+	return func(cfg *model.Config) {
+		cfg.FlashAttention = mode
+	}
+}
+
 // WithName sets the name for the Kronk provider.
 func WithName(name string) Option {
 	return func(o *options) {
@@ -36,6 +69,26 @@ func WithModelConfig(cfg model.Config) Option {
 	}
 }
 
+// WithModelConfigOptions adjusts the configuration every model is loaded
+// with, on top of WithModelConfig whatever their order.
+func WithModelConfigOptions(opts ...ModelConfigOption) Option {
+	return func(o *options) {
+		o.modelConfigOptions = append(o.modelConfigOptions, opts...)
+	}
+}
+
+// WithModel registers a model under id so it can be loaded with
+// LanguageModel(ctx, id). source is the model URL or catalog ID, and opts
+// apply on top of the provider-wide model configuration.
+func WithModel(id, source string, opts ...ModelConfigOption) Option {
+	return func(o *options) {
+		if o.models == nil {
+			o.models = make(map[string]modelEntry)
+		}
+		o.models[id] = modelEntry{source: source, opts: opts}
+	}
+}
+
 // WithLogger sets the logger function for download progress.
 func WithLogger(logger Logger) Option {
 	return func(o *options) {
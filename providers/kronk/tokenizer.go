@@ -0,0 +1,33 @@
+package kronk
+
+import (
+	"context"
+	"errors"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/tokenizer"
+)
+
+// Tokenizer returns the tokenizer stored in the metadata of the model's GGUF
+// file, read on first use. Models whose vocabulary isn't SentencePiece return
+// an error.
+func (l *languageModel) Tokenizer() (tokenizer.Tokenizer, error) {
+	l.tokenizerOnce.Do(func() {
+		files := l.kronk.ModelConfig().ModelFiles
+		if len(files) == 0 {
+			l.tokenizerErr = errors.New("model has no GGUF file")
+			return
+		}
+		l.tokenizer, l.tokenizerErr = tokenizer.LoadGGUF(files[0])
+	})
+	return l.tokenizer, l.tokenizerErr
+}
+
+// CountTokens implements fantasy.TokenCounter with the model's tokenizer.
+func (l *languageModel) CountTokens(ctx context.Context, call fantasy.Call) (int64, error) {
+	tok, err := l.Tokenizer()
+	if err != nil {
+		return 0, err
+	}
+	return tokenizer.Counter{Tokenizer: tok}.CountTokens(ctx, call)
+}
@@ -0,0 +1,70 @@
+package llamacpp
+
+import (
+	"encoding/json"
+	"maps"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/schema"
+	openaisdk "github.com/openai/openai-go/v3"
+)
+
+func languagePrepareModelCall(model fantasy.LanguageModel, params *openaisdk.ChatCompletionNewParams, call fantasy.Call) ([]fantasy.CallWarning, error) {
+	providerOptions := &ProviderOptions{}
+	if v, ok := call.ProviderOptions[model.Provider()]; ok {
+		providerOptions, ok = v.(*ProviderOptions)
+		if !ok {
+			return nil, &fantasy.Error{Title: "invalid argument", Message: "llamacpp provider options should be *llamacpp.ProviderOptions"}
+		}
+	}
+	if providerOptions.Grammar != nil && providerOptions.JSONSchema != nil {
+		return nil, &fantasy.Error{Title: "invalid argument", Message: "llamacpp grammar and json_schema are mutually exclusive"}
+	}
+
+	extraFields, err := samplingFields(providerOptions)
+	if err != nil {
+		return nil, err
+	}
+	if len(extraFields) > 0 {
+		params.SetExtraFields(extraFields)
+	}
+	return nil, nil
+}
+
+// languageModelOutputConstraint maps output constraints to llama-server's
+// grammar and json_schema fields instead of the OpenAI response format.
+func languageModelOutputConstraint(params *openaisdk.ChatCompletionNewParams, constraint *fantasy.OutputConstraint) ([]fantasy.CallWarning, error) {
+	fields := params.ExtraFields()
+	if fields == nil {
+		fields = make(map[string]any)
+	}
+	switch constraint.Type {
+	case fantasy.OutputConstraintTypeGrammar:
+		delete(fields, "json_schema")
+		fields["grammar"] = constraint.Grammar
+	case fantasy.OutputConstraintTypeJSONSchema:
+		delete(fields, "grammar")
+		fields["json_schema"] = schema.ToMap(constraint.Schema)
+	default:
+		return []fantasy.CallWarning{fantasy.UnsupportedOutputConstraintWarning(constraint)}, nil
+	}
+	params.SetExtraFields(fields)
+	return nil, nil
+}
+
+// samplingFields returns the llama-server request fields for the given
+// options, with ExtraBody merged on top.
+func samplingFields(providerOptions *ProviderOptions) (map[string]any, error) {
+	type plain ProviderOptions
+	data, err := json.Marshal(plain(*providerOptions))
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	delete(fields, "extra_body")
+	maps.Copy(fields, providerOptions.ExtraBody)
+	return fields, nil
+}
@@ -0,0 +1,249 @@
+package llamacpp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/require"
+)
+
+type recordedRequest struct {
+	path  string
+	query string
+	body  map[string]any
+}
+
+func newLlamaServer(t *testing.T, responses map[string]any) (*httptest.Server, chan recordedRequest) {
+	t.Helper()
+
+	calls := make(chan recordedRequest, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		calls <- recordedRequest{path: r.URL.Path, query: r.URL.RawQuery, body: body}
+
+		resp, ok := responses[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"error": map[string]any{"code": 404, "message": "File Not Found", "type": "not_found_error"},
+			})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+	return server, calls
+}
+
+func TestLanguageModel_ProviderOptions(t *testing.T) {
+	t.Parallel()
+
+	server, calls := newLlamaServer(t, map[string]any{
+		"/v1/chat/completions": map[string]any{
+			"id":      "chatcmpl-1",
+			"object":  "chat.completion",
+			"created": 1,
+			"model":   "qwen3",
+			"choices": []any{map[string]any{
+				"index":         0,
+				"finish_reason": "stop",
+				"message":       map[string]any{"role": "assistant", "content": "yes"},
+			}},
+			"usage": map[string]any{"prompt_tokens": 3, "completion_tokens": 1, "total_tokens": 4},
+		},
+	})
+
+	provider, err := New(WithBaseURL(server.URL))
+	require.NoError(t, err)
+	model, err := provider.LanguageModel(t.Context(), "qwen3")
+	require.NoError(t, err)
+
+	grammar := `root ::= "yes" | "no"`
+	resp, err := model.Generate(t.Context(), fantasy.Call{
+		Prompt: fantasy.Prompt{fantasy.NewUserMessage("Is the sky blue?")},
+		ProviderOptions: NewProviderOptions(&ProviderOptions{
+			Grammar:     &grammar,
+			IDSlot:      fantasy.Opt[int64](1),
+			CachePrompt: fantasy.Opt(true),
+			MinP:        fantasy.Opt(0.05),
+			ExtraBody:   map[string]any{"dry_multiplier": 0.8},
+		}),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "yes", resp.Content.Text())
+
+	call := <-calls
+	require.Equal(t, "/v1/chat/completions", call.path)
+	require.Equal(t, grammar, call.body["grammar"])
+	require.Equal(t, float64(1), call.body["id_slot"])
+	require.Equal(t, true, call.body["cache_prompt"])
+	require.Equal(t, 0.05, call.body["min_p"])
+	require.Equal(t, 0.8, call.body["dry_multiplier"])
+	require.NotContains(t, call.body, "extra_body")
+	require.NotContains(t, call.body, "json_schema")
+}
+
+func TestLanguageModel_GrammarAndSchemaExclusive(t *testing.T) {
+	t.Parallel()
+
+	provider, err := New()
+	require.NoError(t, err)
+	model, err := provider.LanguageModel(t.Context(), "qwen3")
+	require.NoError(t, err)
+
+	grammar := `root ::= "a"`
+	_, err = model.Generate(t.Context(), fantasy.Call{
+		Prompt: fantasy.Prompt{fantasy.NewUserMessage("Hi")},
+		ProviderOptions: NewProviderOptions(&ProviderOptions{
+			Grammar:    &grammar,
+			JSONSchema: map[string]any{"type": "string"},
+		}),
+	})
+	require.ErrorContains(t, err, "mutually exclusive")
+}
+
+func TestNativeEndpoints(t *testing.T) {
+	t.Parallel()
+
+	type native interface {
+		Complete(context.Context, CompletionRequest) (*CompletionResponse, error)
+		Slots(context.Context) ([]Slot, error)
+		SaveSlot(context.Context, int64, string) (*SlotActionResponse, error)
+	}
+
+	t.Run("completion", func(t *testing.T) {
+		t.Parallel()
+
+		server, calls := newLlamaServer(t, map[string]any{
+			"/completion": map[string]any{
+				"content":          " world",
+				"id_slot":          0,
+				"stop":             true,
+				"stop_type":        "eos",
+				"tokens_cached":    5,
+				"tokens_evaluated": 7,
+				"tokens_predicted": 2,
+				"timings":          map[string]any{"predicted_n": 2, "predicted_per_second": 40.5},
+			},
+		})
+		provider, err := New(WithBaseURL(server.URL + "/"))
+		require.NoError(t, err)
+
+		resp, err := provider.(native).Complete(t.Context(), CompletionRequest{
+			Prompt:   "Hello",
+			NPredict: fantasy.Opt[int64](16),
+			Options: &ProviderOptions{
+				JSONSchema:  map[string]any{"type": "string"},
+				CachePrompt: fantasy.Opt(true),
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, " world", resp.Content)
+		require.Equal(t, "eos", resp.StopType)
+		require.Equal(t, 40.5, resp.Timings.PredictedPerSecond)
+		require.Equal(t, fantasy.Usage{
+			InputTokens:     7,
+			OutputTokens:    2,
+			TotalTokens:     9,
+			CacheReadTokens: 5,
+		}, resp.Usage())
+
+		call := <-calls
+		require.Equal(t, "/completion", call.path)
+		require.Equal(t, "Hello", call.body["prompt"])
+		require.Equal(t, float64(16), call.body["n_predict"])
+		require.Equal(t, map[string]any{"type": "string"}, call.body["json_schema"])
+		require.Equal(t, true, call.body["cache_prompt"])
+	})
+
+	t.Run("slots", func(t *testing.T) {
+		t.Parallel()
+
+		server, calls := newLlamaServer(t, map[string]any{
+			"/slots": []any{
+				map[string]any{"id": 0, "n_ctx": 4096, "is_processing": false},
+				map[string]any{"id": 1, "n_ctx": 4096, "is_processing": true},
+			},
+			"/slots/1": map[string]any{"id_slot": 1, "filename": "chat.bin", "n_saved": 42},
+		})
+		provider, err := New(WithBaseURL(server.URL))
+		require.NoError(t, err)
+
+		slots, err := provider.(native).Slots(t.Context())
+		require.NoError(t, err)
+		require.Equal(t, []Slot{
+			{ID: 0, NCtx: 4096},
+			{ID: 1, NCtx: 4096, IsProcessing: true},
+		}, slots)
+		require.Equal(t, "/slots", (<-calls).path)
+
+		saved, err := provider.(native).SaveSlot(t.Context(), 1, "chat.bin")
+		require.NoError(t, err)
+		require.Equal(t, int64(42), saved.NSaved)
+
+		call := <-calls
+		require.Equal(t, "/slots/1", call.path)
+		require.Equal(t, "action=save", call.query)
+		require.Equal(t, "chat.bin", call.body["filename"])
+	})
+
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+
+		server, _ := newLlamaServer(t, map[string]any{})
+		provider, err := New(WithBaseURL(server.URL))
+		require.NoError(t, err)
+
+		_, err = provider.(native).Slots(t.Context())
+		var providerErr *fantasy.ProviderError
+		require.ErrorAs(t, err, &providerErr)
+		require.Equal(t, http.StatusNotFound, providerErr.StatusCode)
+	})
+}
+
+func TestLanguageModel_OutputConstraint(t *testing.T) {
+	t.Parallel()
+
+	server, calls := newLlamaServer(t, map[string]any{
+		"/v1/chat/completions": map[string]any{
+			"id":      "chatcmpl-1",
+			"object":  "chat.completion",
+			"created": 1,
+			"model":   "qwen3",
+			"choices": []any{map[string]any{
+				"index":         0,
+				"finish_reason": "stop",
+				"message":       map[string]any{"role": "assistant", "content": "no"},
+			}},
+		},
+	})
+
+	provider, err := New(WithBaseURL(server.URL))
+	require.NoError(t, err)
+	model, err := provider.LanguageModel(t.Context(), "qwen3")
+	require.NoError(t, err)
+
+	grammar := `root ::= "yes" | "no"`
+	resp, err := model.Generate(t.Context(), fantasy.Call{
+		Prompt:           fantasy.Prompt{fantasy.NewUserMessage("Is the sky green?")},
+		OutputConstraint: fantasy.GrammarConstraint(grammar),
+		ProviderOptions: NewProviderOptions(&ProviderOptions{
+			JSONSchema:  map[string]any{"type": "string"},
+			CachePrompt: fantasy.Opt(false),
+		}),
+	})
+	require.NoError(t, err)
+	require.Empty(t, resp.Warnings)
+
+	call := <-calls
+	require.Equal(t, grammar, call.body["grammar"])
+	require.Equal(t, false, call.body["cache_prompt"])
+	require.NotContains(t, call.body, "json_schema")
+	require.NotContains(t, call.body, "response_format")
+}
@@ -0,0 +1,144 @@
+package llamacpp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+
+	"charm.land/fantasy"
+)
+
+// CompletionRequest is a request to llama-server's native /completion
+// endpoint, which completes a raw prompt without applying a chat template.
+type CompletionRequest struct {
+	Prompt      string   `json:"prompt"`
+	NPredict    *int64   `json:"n_predict,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopK        *int64   `json:"top_k,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+
+	// Options holds the llama.cpp sampling, grammar and slot options shared
+	// with the chat path.
+	Options *ProviderOptions `json:"-"`
+}
+
+// CompletionResponse is the response of the native /completion endpoint.
+type CompletionResponse struct {
+	Content         string            `json:"content"`
+	IDSlot          int64             `json:"id_slot"`
+	Stop            bool              `json:"stop"`
+	StopType        string            `json:"stop_type"`
+	StoppingWord    string            `json:"stopping_word"`
+	Truncated       bool              `json:"truncated"`
+	TokensCached    int64             `json:"tokens_cached"`
+	TokensEvaluated int64             `json:"tokens_evaluated"`
+	TokensPredicted int64             `json:"tokens_predicted"`
+	Timings         CompletionTimings `json:"timings"`
+}
+
+// CompletionTimings reports how long prompt processing and generation took.
+type CompletionTimings struct {
+	PromptN             int64   `json:"prompt_n"`
+	PromptMS            float64 `json:"prompt_ms"`
+	PromptPerSecond     float64 `json:"prompt_per_second"`
+	PredictedN          int64   `json:"predicted_n"`
+	PredictedMS         float64 `json:"predicted_ms"`
+	PredictedPerSecond  float64 `json:"predicted_per_second"`
+	PredictedPerTokenMS float64 `json:"predicted_per_token_ms"`
+}
+
+// Usage returns the token usage of the completion. Cached prompt tokens
+// are reported as cache reads.
+func (r CompletionResponse) Usage() fantasy.Usage {
+	return fantasy.Usage{
+		InputTokens:     r.TokensEvaluated,
+		OutputTokens:    r.TokensPredicted,
+		TotalTokens:     r.TokensEvaluated + r.TokensPredicted,
+		CacheReadTokens: r.TokensCached,
+	}
+}
+
+// Slot describes a llama-server slot, i.e. a parallel decoding sequence
+// with its own KV cache.
+type Slot struct {
+	ID           int64  `json:"id"`
+	NCtx         int64  `json:"n_ctx"`
+	IsProcessing bool   `json:"is_processing"`
+	Model        string `json:"model,omitempty"`
+}
+
+// SlotActionResponse is the response of a slot save, restore or erase.
+type SlotActionResponse struct {
+	IDSlot    int64  `json:"id_slot"`
+	Filename  string `json:"filename,omitempty"`
+	NSaved    int64  `json:"n_saved,omitempty"`
+	NRestored int64  `json:"n_restored,omitempty"`
+	NErased   int64  `json:"n_erased,omitempty"`
+}
+
+// Complete sends a raw prompt to the native /completion endpoint.
+func (p *provider) Complete(ctx context.Context, req CompletionRequest) (*CompletionResponse, error) {
+	body := map[string]any{}
+	if req.Options != nil {
+		if req.Options.Grammar != nil && req.Options.JSONSchema != nil {
+			return nil, &fantasy.Error{Title: "invalid argument", Message: "llamacpp grammar and json_schema are mutually exclusive"}
+		}
+		fields, err := samplingFields(req.Options)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(body, fields)
+	}
+	type plain CompletionRequest
+	data, err := json.Marshal(plain(req))
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, err
+	}
+
+	var resp CompletionResponse
+	if err := p.client.Post(ctx, "completion", body, &resp); err != nil {
+		return nil, toProviderErr(err)
+	}
+	return &resp, nil
+}
+
+// Slots lists the slots of the server. The server must be started with
+// the slots endpoint enabled.
+func (p *provider) Slots(ctx context.Context) ([]Slot, error) {
+	var slots []Slot
+	if err := p.client.Get(ctx, "slots", nil, &slots); err != nil {
+		return nil, toProviderErr(err)
+	}
+	return slots, nil
+}
+
+// SaveSlot saves the KV cache of a slot to filename in the server's
+// --slot-save-path directory.
+func (p *provider) SaveSlot(ctx context.Context, id int64, filename string) (*SlotActionResponse, error) {
+	return p.slotAction(ctx, id, "save", map[string]any{"filename": filename})
+}
+
+// RestoreSlot restores the KV cache of a slot from a file previously saved
+// with SaveSlot.
+func (p *provider) RestoreSlot(ctx context.Context, id int64, filename string) (*SlotActionResponse, error) {
+	return p.slotAction(ctx, id, "restore", map[string]any{"filename": filename})
+}
+
+// EraseSlot clears the KV cache of a slot.
+func (p *provider) EraseSlot(ctx context.Context, id int64) (*SlotActionResponse, error) {
+	return p.slotAction(ctx, id, "erase", map[string]any{})
+}
+
+func (p *provider) slotAction(ctx context.Context, id int64, action string, body map[string]any) (*SlotActionResponse, error) {
+	var resp SlotActionResponse
+	path := fmt.Sprintf("slots/%d?action=%s", id, action)
+	if err := p.client.Post(ctx, path, body, &resp); err != nil {
+		return nil, toProviderErr(err)
+	}
+	return &resp, nil
+}
@@ -0,0 +1,89 @@
+package llamacpp
+
+import (
+	"encoding/json"
+
+	"charm.land/fantasy"
+)
+
+// Global type identifiers for llama.cpp-specific provider data.
+const (
+	TypeProviderOptions = Name + ".options"
+)
+
+// Register llama.cpp provider-specific types with the global registry.
+func init() {
+	fantasy.RegisterProviderType(TypeProviderOptions, func(data []byte) (fantasy.ProviderOptionsData, error) {
+		var v ProviderOptions
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		return &v, nil
+	})
+}
+
+// ProviderOptions represents additional options for the llama.cpp provider.
+// These map to llama-server sampling and caching parameters that the OpenAI
+// chat completions format does not model.
+type ProviderOptions struct {
+	// Grammar is a GBNF grammar the sampled output must match.
+	Grammar *string `json:"grammar,omitempty"`
+	// JSONSchema is a JSON schema the output must match. llama-server
+	// converts it to a grammar. Mutually exclusive with Grammar.
+	JSONSchema map[string]any `json:"json_schema,omitempty"`
+	// IDSlot pins the request to a server slot, so its KV cache can be
+	// reused across calls. -1 lets the server pick an idle slot.
+	IDSlot *int64 `json:"id_slot,omitempty"`
+	// CachePrompt reuses the KV cache of the slot for the common prompt
+	// prefix. llama-server enables it by default.
+	CachePrompt *bool `json:"cache_prompt,omitempty"`
+	// NProbs returns the probabilities of the top N tokens per sampled token.
+	NProbs *int64 `json:"n_probs,omitempty"`
+
+	MinP          *float64 `json:"min_p,omitempty"`
+	TypicalP      *float64 `json:"typical_p,omitempty"`
+	RepeatPenalty *float64 `json:"repeat_penalty,omitempty"`
+	RepeatLastN   *int64   `json:"repeat_last_n,omitempty"`
+	Mirostat      *int64   `json:"mirostat,omitempty"`
+	MirostatTau   *float64 `json:"mirostat_tau,omitempty"`
+	MirostatEta   *float64 `json:"mirostat_eta,omitempty"`
+	Seed          *int64   `json:"seed,omitempty"`
+
+	ExtraBody map[string]any `json:"extra_body,omitempty"`
+}
+
+// Options implements the ProviderOptions interface.
+func (*ProviderOptions) Options() {}
+
+// MarshalJSON implements custom JSON marshaling with type info for ProviderOptions.
+func (o ProviderOptions) MarshalJSON() ([]byte, error) {
+	type plain ProviderOptions
+	return fantasy.MarshalProviderType(TypeProviderOptions, plain(o))
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling with type info for ProviderOptions.
+func (o *ProviderOptions) UnmarshalJSON(data []byte) error {
+	type plain ProviderOptions
+	var p plain
+	if err := fantasy.UnmarshalProviderType(data, &p); err != nil {
+		return err
+	}
+	*o = ProviderOptions(p)
+	return nil
+}
+
+// NewProviderOptions creates new provider options for the llama.cpp provider.
+func NewProviderOptions(opts *ProviderOptions) fantasy.ProviderOptions {
+	return fantasy.ProviderOptions{
+		Name: opts,
+	}
+}
+
+// ParseOptions parses provider options from a map for the llama.cpp provider.
+func ParseOptions(data map[string]any) (*ProviderOptions, error) {
+	var options ProviderOptions
+	if err := fantasy.ParseOptions(data, &options); err != nil {
+		return nil, err
+	}
+	return &options, nil
+}
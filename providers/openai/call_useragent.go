@@ -53,3 +53,23 @@ func objectCallHeadersRequestOptions(call fantasy.ObjectCall) []option.RequestOp
 	}
 	return opts
 }
+
+func callQueryRequestOptions(query map[string]string) []option.RequestOption {
+	query, ok := httpheaders.CallQuery(query)
+	if !ok {
+		return nil
+	}
+	opts := make([]option.RequestOption, 0, len(query))
+	for k, v := range query {
+		opts = append(opts, option.WithQuery(k, v))
+	}
+	return opts
+}
+
+func callBodyRequestOptions(body map[string]any) []option.RequestOption {
+	opts := make([]option.RequestOption, 0, len(body))
+	for k, v := range body {
+		opts = append(opts, option.WithJSONSet(k, v))
+	}
+	return opts
+}
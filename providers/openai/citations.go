@@ -0,0 +1,40 @@
+package openai
+
+import "charm.land/fantasy"
+
+// newCitation converts a character span reported by OpenAI into a
+// fantasy.Citation. OpenAI reports offsets in characters, while fantasy
+// uses byte offsets so the span can be sliced out of the Go string.
+func newCitation(text string, start, end int64) fantasy.Citation {
+	startByte := runeOffsetToByteOffset(text, start)
+	endByte := max(runeOffsetToByteOffset(text, end), startByte)
+	return fantasy.Citation{
+		StartIndex: startByte,
+		EndIndex:   endByte,
+		Text:       text[startByte:endByte],
+	}
+}
+
+// runeOffsetToByteOffset returns the byte offset of the n-th rune in text,
+// clamped to the bounds of text.
+func runeOffsetToByteOffset(text string, n int64) int {
+	if n <= 0 {
+		return 0
+	}
+	var count int64
+	for i := range text {
+		if count == n {
+			return i
+		}
+		count++
+	}
+	return len(text)
+}
+
+// shiftCitation moves a citation computed against a single text part so it
+// is relative to the concatenated text of the whole response.
+func shiftCitation(c fantasy.Citation, offset int) fantasy.Citation {
+	c.StartIndex += offset
+	c.EndIndex += offset
+	return c
+}
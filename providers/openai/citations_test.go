@@ -0,0 +1,43 @@
+package openai
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCitation(t *testing.T) {
+	t.Parallel()
+
+	t.Run("ascii", func(t *testing.T) {
+		t.Parallel()
+		c := newCitation("Go is fun.", 0, 2)
+		require.Equal(t, 0, c.StartIndex)
+		require.Equal(t, 2, c.EndIndex)
+		require.Equal(t, "Go", c.Text)
+	})
+
+	t.Run("multibyte characters are converted to byte offsets", func(t *testing.T) {
+		t.Parallel()
+		text := "café au lait"
+		c := newCitation(text, 5, 7)
+		require.Equal(t, "au", c.Text)
+		require.Equal(t, "au", text[c.StartIndex:c.EndIndex])
+	})
+
+	t.Run("out of range offsets are clamped", func(t *testing.T) {
+		t.Parallel()
+		c := newCitation("short", 3, 100)
+		require.Equal(t, 3, c.StartIndex)
+		require.Equal(t, 5, c.EndIndex)
+		require.Equal(t, "rt", c.Text)
+	})
+
+	t.Run("shift", func(t *testing.T) {
+		t.Parallel()
+		c := shiftCitation(newCitation("abc", 1, 2), 10)
+		require.Equal(t, 11, c.StartIndex)
+		require.Equal(t, 12, c.EndIndex)
+		require.Equal(t, "b", c.Text)
+	})
+}
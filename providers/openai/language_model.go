@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"reflect"
 	"slices"
 	"strings"
@@ -14,8 +15,8 @@ import (
 	"charm.land/fantasy"
 	"charm.land/fantasy/object"
 	"charm.land/fantasy/schema"
-	"github.com/google/uuid"
 	"github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
 	"github.com/openai/openai-go/v3/packages/param"
 	"github.com/openai/openai-go/v3/shared"
 )
@@ -26,13 +27,17 @@ type languageModel struct {
 	client                     openai.Client
 	objectMode                 fantasy.ObjectMode
 	prepareCallFunc            LanguageModelPrepareCallFunc
+	outputConstraintFunc       LanguageModelOutputConstraintFunc
 	mapFinishReasonFunc        LanguageModelMapFinishReasonFunc
 	extraContentFunc           LanguageModelExtraContentFunc
+	responseContentFunc        LanguageModelResponseContentFunc
 	usageFunc                  LanguageModelUsageFunc
 	streamUsageFunc            LanguageModelStreamUsageFunc
 	streamExtraFunc            LanguageModelStreamExtraFunc
 	streamProviderMetadataFunc LanguageModelStreamProviderMetadataFunc
+	responseHeadersFunc        LanguageModelResponseHeadersFunc
 	toPromptFunc               LanguageModelToPromptFunc
+	imageLimits                *fantasy.ImageLimits
 }
 
 // LanguageModelOption is a function that configures a languageModel.
@@ -45,6 +50,13 @@ func WithLanguageModelPrepareCallFunc(fn LanguageModelPrepareCallFunc) LanguageM
 	}
 }
 
+// WithLanguageModelOutputConstraintFunc sets the output constraint function for the language model.
+func WithLanguageModelOutputConstraintFunc(fn LanguageModelOutputConstraintFunc) LanguageModelOption {
+	return func(l *languageModel) {
+		l.outputConstraintFunc = fn
+	}
+}
+
 // WithLanguageModelMapFinishReasonFunc sets the map finish reason function for the language model.
 func WithLanguageModelMapFinishReasonFunc(fn LanguageModelMapFinishReasonFunc) LanguageModelOption {
 	return func(l *languageModel) {
@@ -59,6 +71,13 @@ func WithLanguageModelExtraContentFunc(fn LanguageModelExtraContentFunc) Languag
 	}
 }
 
+// WithLanguageModelResponseContentFunc sets the response content function for the language model.
+func WithLanguageModelResponseContentFunc(fn LanguageModelResponseContentFunc) LanguageModelOption {
+	return func(l *languageModel) {
+		l.responseContentFunc = fn
+	}
+}
+
 // WithLanguageModelStreamExtraFunc sets the stream extra function for the language model.
 func WithLanguageModelStreamExtraFunc(fn LanguageModelStreamExtraFunc) LanguageModelOption {
 	return func(l *languageModel) {
@@ -87,6 +106,13 @@ func WithLanguageModelToPromptFunc(fn LanguageModelToPromptFunc) LanguageModelOp
 	}
 }
 
+// WithLanguageModelResponseHeadersFunc sets the response headers function for the language model.
+func WithLanguageModelResponseHeadersFunc(fn LanguageModelResponseHeadersFunc) LanguageModelOption {
+	return func(l *languageModel) {
+		l.responseHeadersFunc = fn
+	}
+}
+
 // WithLanguageModelObjectMode sets the object generation mode.
 func WithLanguageModelObjectMode(om fantasy.ObjectMode) LanguageModelOption {
 	return func(l *languageModel) {
@@ -105,6 +131,7 @@ func newLanguageModel(modelID string, provider string, client openai.Client, opt
 		client:                     client,
 		objectMode:                 fantasy.ObjectModeAuto,
 		prepareCallFunc:            DefaultPrepareCallFunc,
+		outputConstraintFunc:       DefaultOutputConstraintFunc,
 		mapFinishReasonFunc:        DefaultMapFinishReasonFunc,
 		usageFunc:                  DefaultUsageFunc,
 		streamUsageFunc:            DefaultStreamUsageFunc,
@@ -135,9 +162,26 @@ func (o languageModel) Provider() string {
 	return o.provider
 }
 
+// SupportsPDF implements fantasy.PDFModel. OpenAI-compatible servers may
+// not actually read the documents.
+func (o languageModel) SupportsPDF() bool {
+	return true
+}
+
+// SupportsFileURL implements fantasy.FileURLModel. Image URLs are passed to
+// the provider.
+func (o languageModel) SupportsFileURL(mediaType string) bool {
+	return strings.HasPrefix(mediaType, "image/")
+}
+
 func (o languageModel) prepareParams(call fantasy.Call) (*openai.ChatCompletionNewParams, []fantasy.CallWarning, error) {
 	params := &openai.ChatCompletionNewParams{}
-	messages, warnings := o.toPromptFunc(call.Prompt, o.provider, o.modelID)
+	prompt, imageWarnings := prepareImages(call.Prompt, o.imageLimits)
+	messages, warnings := o.toPromptFunc(prompt, o.provider, o.modelID)
+	warnings = append(imageWarnings, warnings...)
+	if err := fantasy.CheckDroppedContent(call, warnings); err != nil {
+		return nil, nil, err
+	}
 	if call.TopK != nil {
 		warnings = append(warnings, fantasy.CallWarning{
 			Type:    fantasy.CallWarningTypeUnsupportedSetting,
@@ -160,6 +204,9 @@ func (o languageModel) prepareParams(call fantasy.Call) (*openai.ChatCompletionN
 	if call.PresencePenalty != nil {
 		params.PresencePenalty = param.NewOpt(*call.PresencePenalty)
 	}
+	if call.NumChoices != nil {
+		params.N = param.NewOpt(*call.NumChoices)
+	}
 
 	if isReasoningModel(o.modelID) {
 		// remove unsupported settings for reasoning models
@@ -227,6 +274,14 @@ func (o languageModel) prepareParams(call fantasy.Call) (*openai.ChatCompletionN
 		warnings = append(warnings, optionsWarnings...)
 	}
 
+	if call.OutputConstraint != nil {
+		constraintWarnings, err := o.outputConstraintFunc(params, call.OutputConstraint)
+		if err != nil {
+			return nil, nil, err
+		}
+		warnings = append(warnings, constraintWarnings...)
+	}
+
 	params.Messages = messages
 	params.Model = o.modelID
 
@@ -247,7 +302,10 @@ func (o languageModel) Generate(ctx context.Context, call fantasy.Call) (*fantas
 	if err != nil {
 		return nil, err
 	}
-	response, err := o.client.Chat.Completions.New(ctx, *params, append(callUARequestOptions(call), callHeadersRequestOptions(call)...)...)
+	var httpResp *http.Response
+	reqOpts := slices.Concat(callUARequestOptions(call), callHeadersRequestOptions(call), callQueryRequestOptions(call.ExtraQuery), callBodyRequestOptions(call.ExtraBody))
+	reqOpts = append(reqOpts, option.WithResponseInto(&httpResp))
+	response, err := o.client.Chat.Completions.New(ctx, *params, reqOpts...)
 	if err != nil {
 		return nil, toProviderErr(err)
 	}
@@ -259,6 +317,41 @@ func (o languageModel) Generate(ctx context.Context, call fantasy.Call) (*fantas
 		return nil, &fantasy.Error{Title: "no response", Message: "no response generated"}
 	}
 	choice := response.Choices[0]
+	content := o.choiceContent(choice)
+	if o.responseContentFunc != nil {
+		content = append(content, o.responseContentFunc(*response)...)
+	}
+	var alternatives []fantasy.ResponseContent
+	for _, alternative := range response.Choices[1:] {
+		alternatives = append(alternatives, o.choiceContent(alternative))
+	}
+
+	usage, providerMetadata := o.usageFunc(*response)
+
+	mappedFinishReason := o.mapFinishReasonFunc(choice.FinishReason)
+	if len(choice.Message.ToolCalls) > 0 {
+		mappedFinishReason = fantasy.FinishReasonToolCalls
+	}
+	safety := mapSafety(choice.Message.Refusal, choice.FinishReason, choice.RawJSON())
+	mappedFinishReason = mapRefusalFinishReason(mappedFinishReason, safety)
+	metadata := fantasy.ProviderMetadata{
+		Name: providerMetadata,
+	}
+	if o.responseHeadersFunc != nil && httpResp != nil {
+		metadata = o.responseHeadersFunc(httpResp.Header, metadata)
+	}
+	return &fantasy.Response{
+		Content:          content,
+		Usage:            usage,
+		FinishReason:     mappedFinishReason,
+		Safety:           safety,
+		Alternatives:     alternatives,
+		ProviderMetadata: metadata,
+		Warnings:         warnings,
+	}, nil
+}
+
+func (o languageModel) choiceContent(choice openai.ChatCompletionChoice) fantasy.ResponseContent {
 	content := make([]fantasy.Content, 0, 1+len(choice.Message.ToolCalls)+len(choice.Message.Annotations))
 	text := choice.Message.Content
 	if text != "" {
@@ -283,28 +376,22 @@ func (o languageModel) Generate(ctx context.Context, call fantasy.Call) (*fantas
 		if annotation.Type == "url_citation" {
 			content = append(content, fantasy.SourceContent{
 				SourceType: fantasy.SourceTypeURL,
-				ID:         uuid.NewString(),
+				ID:         fantasy.NewID(),
 				URL:        annotation.URLCitation.URL,
 				Title:      annotation.URLCitation.Title,
+				Citations: []fantasy.Citation{
+					newCitation(choice.Message.Content, annotation.URLCitation.StartIndex, annotation.URLCitation.EndIndex),
+				},
 			})
 		}
 	}
+	return content
+}
 
-	usage, providerMetadata := o.usageFunc(*response)
-
-	mappedFinishReason := o.mapFinishReasonFunc(choice.FinishReason)
-	if len(choice.Message.ToolCalls) > 0 {
-		mappedFinishReason = fantasy.FinishReasonToolCalls
-	}
-	return &fantasy.Response{
-		Content:      content,
-		Usage:        usage,
-		FinishReason: mappedFinishReason,
-		ProviderMetadata: fantasy.ProviderMetadata{
-			Name: providerMetadata,
-		},
-		Warnings: warnings,
-	}, nil
+// SupportsNumChoices implements fantasy.MultiChoiceModel, mapping
+// Call.NumChoices to the n parameter.
+func (o languageModel) SupportsNumChoices() bool {
+	return true
 }
 
 // Stream implements fantasy.LanguageModel.
@@ -314,11 +401,23 @@ func (o languageModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.S
 		return nil, err
 	}
 
+	if call.NumChoices != nil && *call.NumChoices > 1 {
+		params.N = param.Opt[int64]{}
+		warnings = append(warnings, fantasy.CallWarning{
+			Type:    fantasy.CallWarningTypeUnsupportedSetting,
+			Setting: "num_choices",
+			Details: "streams produce a single choice",
+		})
+	}
 	params.StreamOptions = openai.ChatCompletionStreamOptionsParam{
 		IncludeUsage: openai.Bool(true),
 	}
 
-	stream := o.client.Chat.Completions.NewStreaming(ctx, *params, append(callUARequestOptions(call), callHeadersRequestOptions(call)...)...)
+	var httpResp *http.Response
+	reqOpts := slices.Concat(callUARequestOptions(call), callHeadersRequestOptions(call), callQueryRequestOptions(call.ExtraQuery), callBodyRequestOptions(call.ExtraBody))
+	reqOpts = append(reqOpts, option.WithResponseInto(&httpResp))
+	streamCtx, skipped := withSkippedChunks(ctx)
+	stream := o.client.Chat.Completions.NewStreaming(streamCtx, *params, reqOpts...)
 	isActiveText := false
 	toolCalls := make(map[int64]streamToolCall)
 
@@ -329,6 +428,9 @@ func (o languageModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.S
 	extraContext := make(map[string]any)
 	var usage fantasy.Usage
 	var finishReason string
+	// contentFilterChoice is the raw JSON of the last choice carrying Azure
+	// OpenAI content filter results.
+	var contentFilterChoice string
 	return func(yield func(fantasy.StreamPart) bool) {
 		if len(warnings) > 0 {
 			if !yield(fantasy.StreamPart{
@@ -339,6 +441,14 @@ func (o languageModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.S
 			}
 		}
 		for stream.Next() {
+			if warnings := skipped.drain(); len(warnings) > 0 {
+				if !yield(fantasy.StreamPart{
+					Type:     fantasy.StreamPartTypeWarnings,
+					Warnings: warnings,
+				}) {
+					return
+				}
+			}
 			chunk := stream.Current()
 			acc.AddChunk(chunk)
 			usage, providerMetadata = o.streamUsageFunc(chunk, extraContext, providerMetadata)
@@ -349,6 +459,9 @@ func (o languageModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.S
 				if choice.FinishReason != "" {
 					finishReason = choice.FinishReason
 				}
+				if raw := choice.RawJSON(); strings.Contains(raw, "content_filter_results") {
+					contentFilterChoice = raw
+				}
 				if choice.Delta.Content != "" {
 					if !isActiveText {
 						isActiveText = true
@@ -476,14 +589,21 @@ func (o languageModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.S
 
 			for _, choice := range chunk.Choices {
 				if annotations := parseAnnotationsFromDelta(choice.Delta); len(annotations) > 0 {
+					var text string
+					if len(acc.Choices) > 0 {
+						text = acc.Choices[0].Message.Content
+					}
 					for _, annotation := range annotations {
 						if annotation.Type == "url_citation" {
 							if !yield(fantasy.StreamPart{
 								Type:       fantasy.StreamPartTypeSource,
-								ID:         uuid.NewString(),
+								ID:         fantasy.NewID(),
 								SourceType: fantasy.SourceTypeURL,
 								URL:        annotation.URLCitation.URL,
 								Title:      annotation.URLCitation.Title,
+								Citations: []fantasy.Citation{
+									newCitation(text, annotation.URLCitation.StartIndex, annotation.URLCitation.EndIndex),
+								},
 							}) {
 								return
 							}
@@ -492,6 +612,14 @@ func (o languageModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.S
 				}
 			}
 		}
+		if warnings := skipped.drain(); len(warnings) > 0 {
+			if !yield(fantasy.StreamPart{
+				Type:     fantasy.StreamPartTypeWarnings,
+				Warnings: warnings,
+			}) {
+				return
+			}
+		}
 		err := stream.Err()
 		if err == nil || errors.Is(err, io.EOF) {
 			if isActiveText {
@@ -540,6 +668,9 @@ func (o languageModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.S
 							SourceType: fantasy.SourceTypeURL,
 							URL:        annotation.URLCitation.URL,
 							Title:      annotation.URLCitation.Title,
+							Citations: []fantasy.Citation{
+								newCitation(choice.Message.Content, annotation.URLCitation.StartIndex, annotation.URLCitation.EndIndex),
+							},
 						}) {
 							return
 						}
@@ -547,12 +678,16 @@ func (o languageModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.S
 				}
 			}
 			mappedFinishReason := o.mapFinishReasonFunc(finishReason)
+			var refusal string
 			if len(acc.Choices) > 0 {
 				choice := acc.Choices[0]
 				if len(choice.Message.ToolCalls) > 0 {
 					mappedFinishReason = fantasy.FinishReasonToolCalls
 				}
+				refusal = choice.Message.Refusal
 			}
+			safety := mapSafety(refusal, finishReason, contentFilterChoice)
+			mappedFinishReason = mapRefusalFinishReason(mappedFinishReason, safety)
 			// Truncated stream: upstream closed without finish_reason and we
 			// can't infer a tool-call turn. Surface as a retryable error so
 			// the retry middleware re-runs the step.
@@ -567,10 +702,14 @@ func (o languageModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.S
 				})
 				return
 			}
+			if o.responseHeadersFunc != nil && httpResp != nil {
+				providerMetadata = o.responseHeadersFunc(httpResp.Header, providerMetadata)
+			}
 			yield(fantasy.StreamPart{
 				Type:             fantasy.StreamPartTypeFinish,
 				Usage:            usage,
 				FinishReason:     mappedFinishReason,
+				Safety:           safety,
 				ProviderMetadata: providerMetadata,
 			})
 			return
@@ -617,13 +756,15 @@ func toOpenAiTools(tools []fantasy.Tool, toolChoice *fantasy.ToolChoice) (openAi
 			if !ok {
 				continue
 			}
+			parameters, strict, toolWarnings := functionToolParameters(ft, false)
+			warnings = append(warnings, toolWarnings...)
 			openAiTools = append(openAiTools, openai.ChatCompletionToolUnionParam{
 				OfFunction: &openai.ChatCompletionFunctionToolParam{
 					Function: shared.FunctionDefinitionParam{
 						Name:        ft.Name,
 						Description: param.NewOpt(ft.Description),
-						Parameters:  openai.FunctionParameters(ft.InputSchema),
-						Strict:      param.NewOpt(false),
+						Parameters:  openai.FunctionParameters(parameters),
+						Strict:      param.NewOpt(strict),
 					},
 					Type: "function",
 				},
@@ -686,11 +827,15 @@ func parseAnnotationsFromDelta(delta openai.ChatCompletionChunkChoiceDelta) []op
 						url, urlOk := urlCitationData["url"].(string)
 						title, titleOk := urlCitationData["title"].(string)
 						if urlOk && titleOk {
+							startIndex, _ := urlCitationData["start_index"].(float64)
+							endIndex, _ := urlCitationData["end_index"].(float64)
 							annotation := openai.ChatCompletionMessageAnnotation{
 								Type: "url_citation",
 								URLCitation: openai.ChatCompletionMessageAnnotationURLCitation{
-									URL:   url,
-									Title: title,
+									URL:        url,
+									Title:      title,
+									StartIndex: int64(startIndex),
+									EndIndex:   int64(endIndex),
 								},
 							}
 							annotations = append(annotations, annotation)
@@ -729,9 +874,7 @@ func (o languageModel) StreamObject(ctx context.Context, call fantasy.ObjectCall
 }
 
 func (o languageModel) generateObjectWithJSONMode(ctx context.Context, call fantasy.ObjectCall) (*fantasy.ObjectResponse, error) {
-	jsonSchemaMap := schema.ToMap(call.Schema)
-
-	addAdditionalPropertiesFalse(jsonSchemaMap)
+	jsonSchemaMap, schemaWarnings := call.Schema.Strictify()
 
 	schemaName := call.SchemaName
 	if schemaName == "" {
@@ -752,6 +895,7 @@ func (o languageModel) generateObjectWithJSONMode(ctx context.Context, call fant
 	if err != nil {
 		return nil, err
 	}
+	warnings = append(warnings, strictSchemaWarnings(schemaWarnings)...)
 
 	params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
 		OfJSONSchema: &shared.ResponseFormatJSONSchemaParam{
@@ -764,7 +908,7 @@ func (o languageModel) generateObjectWithJSONMode(ctx context.Context, call fant
 		},
 	}
 
-	response, err := o.client.Chat.Completions.New(ctx, *params, append(objectCallUARequestOptions(call), objectCallHeadersRequestOptions(call)...)...)
+	response, err := o.client.Chat.Completions.New(ctx, *params, slices.Concat(objectCallUARequestOptions(call), objectCallHeadersRequestOptions(call), callQueryRequestOptions(call.ExtraQuery), callBodyRequestOptions(call.ExtraBody))...)
 	if err != nil {
 		return nil, toProviderErr(err)
 	}
@@ -809,9 +953,7 @@ func (o languageModel) generateObjectWithJSONMode(ctx context.Context, call fant
 }
 
 func (o languageModel) streamObjectWithJSONMode(ctx context.Context, call fantasy.ObjectCall) (fantasy.ObjectStreamResponse, error) {
-	jsonSchemaMap := schema.ToMap(call.Schema)
-
-	addAdditionalPropertiesFalse(jsonSchemaMap)
+	jsonSchemaMap, schemaWarnings := call.Schema.Strictify()
 
 	schemaName := call.SchemaName
 	if schemaName == "" {
@@ -832,6 +974,7 @@ func (o languageModel) streamObjectWithJSONMode(ctx context.Context, call fantas
 	if err != nil {
 		return nil, err
 	}
+	warnings = append(warnings, strictSchemaWarnings(schemaWarnings)...)
 
 	params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
 		OfJSONSchema: &shared.ResponseFormatJSONSchemaParam{
@@ -848,7 +991,7 @@ func (o languageModel) streamObjectWithJSONMode(ctx context.Context, call fantas
 		IncludeUsage: openai.Bool(true),
 	}
 
-	stream := o.client.Chat.Completions.NewStreaming(ctx, *params, append(objectCallUARequestOptions(call), objectCallHeadersRequestOptions(call)...)...)
+	stream := o.client.Chat.Completions.NewStreaming(ctx, *params, slices.Concat(objectCallUARequestOptions(call), objectCallHeadersRequestOptions(call), callQueryRequestOptions(call.ExtraQuery), callBodyRequestOptions(call.ExtraBody))...)
 
 	return func(yield func(fantasy.ObjectStreamPart) bool) {
 		if len(warnings) > 0 {
@@ -965,26 +1108,33 @@ func (o languageModel) streamObjectWithJSONMode(ctx context.Context, call fantas
 	}, nil
 }
 
-// addAdditionalPropertiesFalse recursively adds "additionalProperties": false to all object schemas.
-// This is required by OpenAI's strict mode for structured outputs.
-func addAdditionalPropertiesFalse(schema map[string]any) {
-	if schema["type"] == "object" {
-		if _, hasAdditional := schema["additionalProperties"]; !hasAdditional {
-			schema["additionalProperties"] = false
-		}
-
-		// Recursively process nested properties
-		if properties, ok := schema["properties"].(map[string]any); ok {
-			for _, propValue := range properties {
-				if propSchema, ok := propValue.(map[string]any); ok {
-					addAdditionalPropertiesFalse(propSchema)
-				}
-			}
-		}
+// functionToolParameters returns the parameters of a function tool and
+// whether it's strict: as set by its ProviderToolOptions, or strict
+// otherwise. The schema of strict tools is strictified.
+func functionToolParameters(ft fantasy.FunctionTool, strict bool) (map[string]any, bool, []fantasy.CallWarning) {
+	if opts, ok := ft.ProviderOptions[Name].(*ProviderToolOptions); ok && opts.Strict != nil {
+		strict = *opts.Strict
 	}
+	if !strict {
+		return ft.InputSchema, false, nil
+	}
+	parameters, schemaWarnings := schema.StrictifyMap(ft.InputSchema)
+	warnings := strictSchemaWarnings(schemaWarnings)
+	for i := range warnings {
+		warnings[i].Tool = ft
+	}
+	return parameters, true, warnings
+}
 
-	// Handle array items
-	if items, ok := schema["items"].(map[string]any); ok {
-		addAdditionalPropertiesFalse(items)
+// strictSchemaWarnings converts the lossy transformations made by
+// schema.Strictify into call warnings.
+func strictSchemaWarnings(schemaWarnings []string) []fantasy.CallWarning {
+	warnings := make([]fantasy.CallWarning, 0, len(schemaWarnings))
+	for _, w := range schemaWarnings {
+		warnings = append(warnings, fantasy.CallWarning{
+			Type:    fantasy.CallWarningTypeOther,
+			Message: "strict schema: " + w,
+		})
 	}
+	return warnings
 }
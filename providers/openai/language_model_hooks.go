@@ -1,8 +1,11 @@
 package openai
 
 import (
+	"cmp"
 	"encoding/base64"
 	"fmt"
+	"maps"
+	"net/http"
 	"strings"
 
 	"charm.land/fantasy"
@@ -23,6 +26,9 @@ type LanguageModelUsageFunc = func(choice openai.ChatCompletion) (fantasy.Usage,
 // LanguageModelExtraContentFunc is a function that adds extra content for the language model.
 type LanguageModelExtraContentFunc = func(choice openai.ChatCompletionChoice) []fantasy.Content
 
+// LanguageModelResponseContentFunc is a function that adds extra content taken from the whole response, e.g. top-level fields outside the choices.
+type LanguageModelResponseContentFunc = func(response openai.ChatCompletion) []fantasy.Content
+
 // LanguageModelStreamExtraFunc is a function that handles stream extra functionality for the language model.
 type LanguageModelStreamExtraFunc = func(chunk openai.ChatCompletionChunk, yield func(fantasy.StreamPart) bool, ctx map[string]any) (map[string]any, bool)
 
@@ -32,12 +38,41 @@ type LanguageModelStreamUsageFunc = func(chunk openai.ChatCompletionChunk, ctx m
 // LanguageModelStreamProviderMetadataFunc is a function that handles stream provider metadata for the language model.
 type LanguageModelStreamProviderMetadataFunc = func(choice openai.ChatCompletionChoice, metadata fantasy.ProviderMetadata) fantasy.ProviderMetadata
 
+// LanguageModelResponseHeadersFunc is a function that adds provider metadata taken from the HTTP response headers.
+type LanguageModelResponseHeadersFunc = func(header http.Header, metadata fantasy.ProviderMetadata) fantasy.ProviderMetadata
+
 // LanguageModelToPromptFunc is a function that handles converting fantasy prompts to openai sdk messages.
 type LanguageModelToPromptFunc = func(prompt fantasy.Prompt, provider, model string) ([]openai.ChatCompletionMessageParamUnion, []fantasy.CallWarning)
 
+// LanguageModelOutputConstraintFunc is a function that applies the call's output constraint to the request.
+type LanguageModelOutputConstraintFunc = func(params *openai.ChatCompletionNewParams, constraint *fantasy.OutputConstraint) ([]fantasy.CallWarning, error)
+
+// DefaultOutputConstraintFunc maps JSON schema constraints to a strict
+// json_schema response format, strictifying the schema. Grammar and regex
+// constraints are not supported by OpenAI.
+func DefaultOutputConstraintFunc(params *openai.ChatCompletionNewParams, constraint *fantasy.OutputConstraint) ([]fantasy.CallWarning, error) {
+	if constraint.Type != fantasy.OutputConstraintTypeJSONSchema {
+		return []fantasy.CallWarning{fantasy.UnsupportedOutputConstraintWarning(constraint)}, nil
+	}
+	jsonSchemaMap, schemaWarnings := constraint.Schema.Strictify()
+	params.ResponseFormat = openai.ChatCompletionNewParamsResponseFormatUnion{
+		OfJSONSchema: &shared.ResponseFormatJSONSchemaParam{
+			JSONSchema: shared.ResponseFormatJSONSchemaJSONSchemaParam{
+				Name:   cmp.Or(constraint.Name, "response"),
+				Schema: jsonSchemaMap,
+				Strict: param.NewOpt(true),
+			},
+		},
+	}
+	return strictSchemaWarnings(schemaWarnings), nil
+}
+
 // DefaultPrepareCallFunc is the default implementation for preparing a call to the language model.
 func DefaultPrepareCallFunc(model fantasy.LanguageModel, params *openai.ChatCompletionNewParams, call fantasy.Call) ([]fantasy.CallWarning, error) {
-	if call.ProviderOptions == nil {
+	if len(call.Metadata) > 0 {
+		params.Metadata = maps.Clone(call.Metadata)
+	}
+	if call.ProviderOptions == nil && call.ServiceTier == "" {
 		return nil, nil
 	}
 	var warnings []fantasy.CallWarning
@@ -90,8 +125,12 @@ func DefaultPrepareCallFunc(model fantasy.LanguageModel, params *openai.ChatComp
 		params.Store = param.NewOpt(*providerOptions.Store)
 	}
 	if providerOptions.Metadata != nil {
-		// Convert map[string]any to map[string]string
-		metadata := make(map[string]string)
+		// Convert map[string]any to map[string]string, over the call's
+		// metadata.
+		metadata := maps.Clone(call.Metadata)
+		if metadata == nil {
+			metadata = make(map[string]string)
+		}
 		for k, v := range providerOptions.Metadata {
 			if str, ok := v.(string); ok {
 				metadata[k] = str
@@ -105,8 +144,12 @@ func DefaultPrepareCallFunc(model fantasy.LanguageModel, params *openai.ChatComp
 	if providerOptions.SafetyIdentifier != nil {
 		params.SafetyIdentifier = param.NewOpt(*providerOptions.SafetyIdentifier)
 	}
+	serviceTier := string(call.ServiceTier)
 	if providerOptions.ServiceTier != nil {
-		params.ServiceTier = openai.ChatCompletionNewParamsServiceTier(*providerOptions.ServiceTier)
+		serviceTier = *providerOptions.ServiceTier
+	}
+	if serviceTier != "" {
+		params.ServiceTier = openai.ChatCompletionNewParamsServiceTier(serviceTier)
 	}
 
 	if providerOptions.ReasoningEffort != nil {
@@ -158,23 +201,20 @@ func DefaultPrepareCallFunc(model fantasy.LanguageModel, params *openai.ChatComp
 	}
 
 	// Handle service tier validation
-	if providerOptions.ServiceTier != nil {
-		serviceTier := *providerOptions.ServiceTier
-		if serviceTier == "flex" && !supportsFlexProcessing(model.Model()) {
-			params.ServiceTier = ""
-			warnings = append(warnings, fantasy.CallWarning{
-				Type:    fantasy.CallWarningTypeUnsupportedSetting,
-				Setting: "ServiceTier",
-				Details: "flex processing is only available for o3, o4-mini, and gpt-5 models",
-			})
-		} else if serviceTier == "priority" && !supportsPriorityProcessing(model.Model()) {
-			params.ServiceTier = ""
-			warnings = append(warnings, fantasy.CallWarning{
-				Type:    fantasy.CallWarningTypeUnsupportedSetting,
-				Setting: "ServiceTier",
-				Details: "priority processing is only available for supported models (gpt-4, gpt-5, gpt-5-mini, o3, o4-mini) and requires Enterprise access. gpt-5-nano is not supported",
-			})
-		}
+	if serviceTier == "flex" && !supportsFlexProcessing(model.Model()) {
+		params.ServiceTier = ""
+		warnings = append(warnings, fantasy.CallWarning{
+			Type:    fantasy.CallWarningTypeUnsupportedSetting,
+			Setting: "ServiceTier",
+			Details: "flex processing is only available for o3, o4-mini, and gpt-5 models",
+		})
+	} else if serviceTier == "priority" && !supportsPriorityProcessing(model.Model()) {
+		params.ServiceTier = ""
+		warnings = append(warnings, fantasy.CallWarning{
+			Type:    fantasy.CallWarningTypeUnsupportedSetting,
+			Setting: "ServiceTier",
+			Details: "priority processing is only available for supported models (gpt-4, gpt-5, gpt-5-mini, o3, o4-mini) and requires Enterprise access. gpt-5-nano is not supported",
+		})
 	}
 	return warnings, nil
 }
@@ -383,6 +423,9 @@ func DefaultToPrompt(prompt fantasy.Prompt, _, _ string) ([]openai.ChatCompletio
 						// Handle image files
 						base64Encoded := base64.StdEncoding.EncodeToString(filePart.Data)
 						data := "data:" + filePart.MediaType + ";base64," + base64Encoded
+						if filePart.URL != "" {
+							data = filePart.URL
+						}
 						imageURL := openai.ChatCompletionContentPartImageImageURLParam{URL: data}
 
 						// Check for provider-specific options like image detail
@@ -451,7 +494,7 @@ func DefaultToPrompt(prompt fantasy.Prompt, _, _ string) ([]openai.ChatCompletio
 
 					default:
 						warnings = append(warnings, fantasy.CallWarning{
-							Type:    fantasy.CallWarningTypeOther,
+							Type:    fantasy.CallWarningTypeDroppedContent,
 							Message: fmt.Sprintf("file part media type %s not supported", filePart.MediaType),
 						})
 					}
@@ -459,7 +502,7 @@ func DefaultToPrompt(prompt fantasy.Prompt, _, _ string) ([]openai.ChatCompletio
 			}
 			if !hasVisibleUserContent(content) {
 				warnings = append(warnings, fantasy.CallWarning{
-					Type:    fantasy.CallWarningTypeOther,
+					Type:    fantasy.CallWarningTypeDroppedContent,
 					Message: "dropping empty user message (contains neither user-facing content nor tool results)",
 				})
 				continue
@@ -520,7 +563,7 @@ func DefaultToPrompt(prompt fantasy.Prompt, _, _ string) ([]openai.ChatCompletio
 			}
 			if !hasVisibleAssistantContent(&assistantMsg) {
 				warnings = append(warnings, fantasy.CallWarning{
-					Type:    fantasy.CallWarningTypeOther,
+					Type:    fantasy.CallWarningTypeDroppedContent,
 					Message: "dropping empty assistant message (contains neither user-facing content nor tool calls)",
 				})
 				continue
@@ -529,6 +572,10 @@ func DefaultToPrompt(prompt fantasy.Prompt, _, _ string) ([]openai.ChatCompletio
 				OfAssistant: &assistantMsg,
 			})
 		case fantasy.MessageRoleTool:
+			// The media of tool results goes in user messages after all the
+			// tool messages, as the tool messages must follow the assistant
+			// message with the tool calls.
+			var mediaMessages []openai.ChatCompletionMessageParamUnion
 			for _, c := range msg.Content {
 				if c.GetType() != fantasy.ContentTypeToolResult {
 					warnings = append(warnings, fantasy.CallWarning{
@@ -595,7 +642,7 @@ func DefaultToPrompt(prompt fantasy.Prompt, _, _ string) ([]openai.ChatCompletio
 						warnings = append(warnings, *mediaWarning)
 					}
 					if emit {
-						messages = append(messages, openai.UserMessage(
+						mediaMessages = append(mediaMessages, openai.UserMessage(
 							[]openai.ChatCompletionContentPartUnionParam{mediaPart},
 						))
 					}
@@ -606,6 +653,7 @@ func DefaultToPrompt(prompt fantasy.Prompt, _, _ string) ([]openai.ChatCompletio
 					})
 				}
 			}
+			messages = append(messages, mediaMessages...)
 		}
 	}
 	return messages, warnings
@@ -640,7 +688,7 @@ func toolResultMediaUserPart(output fantasy.ToolResultOutputContentMedia) (opena
 		return openai.ChatCompletionContentPartUnionParam{OfInputAudio: &audioBlock}, nil, true
 	default:
 		return openai.ChatCompletionContentPartUnionParam{}, &fantasy.CallWarning{
-			Type:    fantasy.CallWarningTypeOther,
+			Type:    fantasy.CallWarningTypeDroppedContent,
 			Message: fmt.Sprintf("tool result media type %s not supported, sending text placeholder only", output.MediaType),
 		}, false
 	}
@@ -0,0 +1,39 @@
+package openai
+
+import "charm.land/fantasy"
+
+// Metadata is the provider metadata OpenAI sets, by type. MetadataFrom sets
+// the field of the type it finds and leaves the others nil.
+type Metadata struct {
+	// Chat is set on Chat Completions responses and their finish stream
+	// parts, with usage details and log probabilities.
+	Chat *ProviderMetadata
+	// Responses is set on Responses API responses and their finish stream
+	// parts, with the response ID.
+	Responses *ResponsesProviderMetadata
+	// Reasoning is set on the reasoning content of the Responses API.
+	Reasoning *ResponsesReasoningMetadata
+	// WebSearchCall is set on the results of the web search tool.
+	WebSearchCall *WebSearchCallMetadata
+}
+
+// MetadataFrom returns the OpenAI metadata of a response, content or stream
+// part, and whether there is any. Providers built on this package, such as
+// Azure and OpenAI-compatible providers, set the same metadata.
+func MetadataFrom(meta fantasy.ProviderMetadata) (Metadata, bool) {
+	var m Metadata
+	var ok bool
+	if m.Chat, ok = fantasy.ProviderMetadataOf[*ProviderMetadata](meta, Name); ok {
+		return m, true
+	}
+	if m.Responses, ok = fantasy.ProviderMetadataOf[*ResponsesProviderMetadata](meta, Name); ok {
+		return m, true
+	}
+	if m.Reasoning, ok = fantasy.ProviderMetadataOf[*ResponsesReasoningMetadata](meta, Name); ok {
+		return m, true
+	}
+	if m.WebSearchCall, ok = fantasy.ProviderMetadataOf[*WebSearchCallMetadata](meta, Name); ok {
+		return m, true
+	}
+	return Metadata{}, false
+}
@@ -0,0 +1,89 @@
+package openai
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"charm.land/fantasy"
+	"github.com/openai/openai-go/v3"
+)
+
+// DefaultModerationModel is the moderation model used when none is given.
+const DefaultModerationModel = "omni-moderation-latest"
+
+type moderationModel struct {
+	provider string
+	modelID  string
+	client   openai.Client
+}
+
+// ModerationModel implements fantasy.ModerationProvider. An empty modelID
+// selects DefaultModerationModel.
+func (o *provider) ModerationModel(_ context.Context, modelID string) (fantasy.ModerationModel, error) {
+	return &moderationModel{
+		provider: o.options.name,
+		modelID:  cmp.Or(modelID, DefaultModerationModel),
+		client:   o.newClient(),
+	}, nil
+}
+
+// Model implements fantasy.ModerationModel.
+func (m *moderationModel) Model() string {
+	return m.modelID
+}
+
+// Provider implements fantasy.ModerationModel.
+func (m *moderationModel) Provider() string {
+	return m.provider
+}
+
+// Moderate implements fantasy.ModerationModel.
+func (m *moderationModel) Moderate(ctx context.Context, call fantasy.ModerationCall) (*fantasy.ModerationResponse, error) {
+	if len(call.Values) == 0 {
+		return &fantasy.ModerationResponse{}, nil
+	}
+
+	resp, err := m.client.Moderations.New(ctx, openai.ModerationNewParams{
+		Model: m.modelID,
+		Input: openai.ModerationNewParamsInputUnion{OfStringArray: call.Values},
+	})
+	if err != nil {
+		return nil, toProviderErr(err)
+	}
+
+	results := make([]fantasy.ModerationResult, len(resp.Results))
+	for i, r := range resp.Results {
+		results[i] = fantasy.ModerationResult{
+			Flagged:    r.Flagged,
+			Categories: moderationCategories(r.Categories.RawJSON(), r.CategoryScores.RawJSON()),
+		}
+	}
+	return &fantasy.ModerationResponse{Results: results}, nil
+}
+
+// moderationCategories reads the categories from the raw JSON of the
+// response rather than the SDK's fields, so categories added to the API
+// are kept.
+func moderationCategories(rawFlags, rawScores string) []fantasy.ModerationCategory {
+	var flags map[string]bool
+	var scores map[string]float64
+	_ = json.Unmarshal([]byte(rawFlags), &flags)
+	_ = json.Unmarshal([]byte(rawScores), &scores)
+
+	categories := make([]fantasy.ModerationCategory, 0, len(scores))
+	for name, score := range scores {
+		categories = append(categories, fantasy.ModerationCategory{Name: name, Flagged: flags[name], Score: score})
+	}
+	for name, flagged := range flags {
+		if _, ok := scores[name]; !ok {
+			categories = append(categories, fantasy.ModerationCategory{Name: name, Flagged: flagged})
+		}
+	}
+	slices.SortFunc(categories, func(a, b fantasy.ModerationCategory) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return categories
+}
@@ -0,0 +1,63 @@
+package openai
+
+import (
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModerationModel(t *testing.T) {
+	t.Parallel()
+
+	server := newMockServer()
+	defer server.close()
+	server.response = map[string]any{
+		"id":    "modr-1",
+		"model": "omni-moderation-2024-09-26",
+		"results": []map[string]any{
+			{
+				"flagged":         true,
+				"categories":      map[string]any{"harassment": true, "violence": false},
+				"category_scores": map[string]any{"harassment": 0.91, "violence": 0.02},
+			},
+			{
+				"flagged":         false,
+				"categories":      map[string]any{"harassment": false, "violence": false},
+				"category_scores": map[string]any{"harassment": 0.01, "violence": 0.01},
+			},
+		},
+	}
+
+	p, err := New(WithAPIKey("k"), WithBaseURL(server.server.URL))
+	require.NoError(t, err)
+	model, err := p.(fantasy.ModerationProvider).ModerationModel(t.Context(), "")
+	require.NoError(t, err)
+	require.Equal(t, DefaultModerationModel, model.Model())
+	require.Equal(t, Name, model.Provider())
+
+	resp, err := model.Moderate(t.Context(), fantasy.ModerationCall{Values: []string{"you are awful", "hello"}})
+	require.NoError(t, err)
+
+	require.Len(t, server.calls, 1)
+	require.Equal(t, "/moderations", server.calls[0].path)
+	require.Equal(t, DefaultModerationModel, server.calls[0].body["model"])
+	require.Equal(t, []any{"you are awful", "hello"}, server.calls[0].body["input"])
+
+	require.Equal(t, []fantasy.ModerationResult{
+		{
+			Flagged: true,
+			Categories: []fantasy.ModerationCategory{
+				{Name: "harassment", Flagged: true, Score: 0.91},
+				{Name: "violence", Score: 0.02},
+			},
+		},
+		{
+			Categories: []fantasy.ModerationCategory{
+				{Name: "harassment", Score: 0.01},
+				{Name: "violence", Score: 0.01},
+			},
+		},
+	}, resp.Results)
+	require.Equal(t, []string{"harassment"}, resp.Results[0].FlaggedCategories())
+}
@@ -5,8 +5,12 @@ import (
 	"cmp"
 	"context"
 	"maps"
+	"net/http"
+	"net/url"
+	"time"
 
 	"charm.land/fantasy"
+	"charm.land/fantasy/providers/internal/httpclient"
 	"charm.land/fantasy/providers/internal/httpheaders"
 	"github.com/openai/openai-go/v3"
 	"github.com/openai/openai-go/v3/option"
@@ -19,6 +23,19 @@ const (
 	DefaultURL = "https://api.openai.com/v1"
 )
 
+// DefaultImageLimits are the limits images are prepared for by the OpenAI
+// provider unless WithImageLimits is set. OpenAI scales high detail images
+// down to fit them anyway, and low detail ones to 512px.
+var DefaultImageLimits = fantasy.ImageLimits{
+	MaxBytes:     20 * 1024 * 1024,
+	MaxEdge:      2048,
+	MaxShortEdge: 768,
+	MediaTypes:   []string{"image/jpeg", "image/png", "image/gif", "image/webp"},
+}
+
+// lowDetailMaxEdge is the size OpenAI scales low detail images down to.
+const lowDetailMaxEdge = 512
+
 type provider struct {
 	options options
 }
@@ -34,9 +51,12 @@ type options struct {
 	headers              map[string]string
 	userAgent            string
 	client               option.HTTPClient
+	httpConfig           httpclient.Config
 	sdkOptions           []option.RequestOption
 	objectMode           fantasy.ObjectMode
 	languageModelOptions []LanguageModelOption
+	resilientStreams     bool
+	imageLimits          *fantasy.ImageLimits
 }
 
 // Option defines a function that configures OpenAI provider options.
@@ -114,6 +134,31 @@ func WithHTTPClient(client option.HTTPClient) Option {
 	}
 }
 
+// WithHTTPTimeout sets the overall timeout of each request, including
+// reading streamed responses. Use fantasy.WithStreamIdleTimeout to detect
+// stalled streams instead. It's ignored when WithHTTPClient is set.
+func WithHTTPTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.httpConfig.Timeout = timeout
+	}
+}
+
+// WithProxy sends the requests through the given proxy instead of the one
+// from the environment. It's ignored when WithHTTPClient is set.
+func WithProxy(proxy *url.URL) Option {
+	return func(o *options) {
+		o.httpConfig.Proxy = proxy
+	}
+}
+
+// WithTransport sets the HTTP transport of the OpenAI provider. It's
+// ignored when WithHTTPClient is set.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *options) {
+		o.httpConfig.Transport = transport
+	}
+}
+
 // WithSDKOptions sets the SDK options for the OpenAI provider.
 func WithSDKOptions(opts ...option.RequestOption) Option {
 	return func(o *options) {
@@ -128,6 +173,16 @@ func WithLanguageModelOptions(opts ...LanguageModelOption) Option {
 	}
 }
 
+// WithImageLimits sets the limits images are downscaled and recompressed to
+// fit before they're sent, adding a warning to the response when they are.
+// The zero value sends images as is. Defaults to DefaultImageLimits for
+// OpenAI and to no limits for OpenAI-compatible providers.
+func WithImageLimits(limits fantasy.ImageLimits) Option {
+	return func(o *options) {
+		o.imageLimits = &limits
+	}
+}
+
 // WithUseResponsesAPI configures the provider to use the responses API for models that support it.
 func WithUseResponsesAPI() Option {
 	return func(o *options) {
@@ -162,8 +217,7 @@ func WithObjectMode(om fantasy.ObjectMode) Option {
 	}
 }
 
-// LanguageModel implements fantasy.Provider.
-func (o *provider) LanguageModel(_ context.Context, modelID string) (fantasy.LanguageModel, error) {
+func (o *provider) newClient() openai.Client {
 	openaiClientOptions := make([]option.RequestOption, 0, 5+len(o.options.headers)+len(o.options.sdkOptions))
 	openaiClientOptions = append(openaiClientOptions, option.WithMaxRetries(0))
 
@@ -182,11 +236,22 @@ func (o *provider) LanguageModel(_ context.Context, modelID string) (fantasy.Lan
 
 	if o.options.client != nil {
 		openaiClientOptions = append(openaiClientOptions, option.WithHTTPClient(o.options.client))
+	} else {
+		openaiClientOptions = append(openaiClientOptions, option.WithHTTPClient(o.options.httpConfig.Client()))
+	}
+
+	if o.options.resilientStreams {
+		openaiClientOptions = append(openaiClientOptions, option.WithMiddleware(resilientStreamMiddleware))
 	}
 
 	openaiClientOptions = append(openaiClientOptions, o.options.sdkOptions...)
 
-	client := openai.NewClient(openaiClientOptions...)
+	return openai.NewClient(openaiClientOptions...)
+}
+
+// LanguageModel implements fantasy.Provider.
+func (o *provider) LanguageModel(_ context.Context, modelID string) (fantasy.LanguageModel, error) {
+	client := o.newClient()
 
 	if o.options.useResponsesAPI && o.isResponsesModel(modelID) {
 		// Not supported for responses API
@@ -194,11 +259,16 @@ func (o *provider) LanguageModel(_ context.Context, modelID string) (fantasy.Lan
 		if objectMode == fantasy.ObjectModeJSON {
 			objectMode = fantasy.ObjectModeAuto
 		}
-		return newResponsesLanguageModel(modelID, o.options.name, client, objectMode), nil
+		model := newResponsesLanguageModel(modelID, o.options.name, client, objectMode)
+		model.imageLimits = o.imageLimits()
+		return model, nil
 	}
 
 	languageModelOptions := append([]LanguageModelOption{}, o.options.languageModelOptions...)
 	languageModelOptions = append(languageModelOptions, WithLanguageModelObjectMode(o.options.objectMode))
+	languageModelOptions = append(languageModelOptions, func(m *languageModel) {
+		m.imageLimits = o.imageLimits()
+	})
 
 	return newLanguageModel(
 		modelID,
@@ -212,6 +282,42 @@ func (o *provider) Name() string {
 	return o.options.name
 }
 
+// Validate implements fantasy.Validator by listing the available models.
+func (o *provider) Validate(ctx context.Context) error {
+	client := o.newClient()
+	_, err := client.Models.List(ctx)
+	if err != nil {
+		return fantasy.NewValidationError(o.options.name, toProviderErr(err))
+	}
+	return nil
+}
+
+// imageLimits returns the limits images are prepared for. OpenAI-compatible
+// providers send images as is unless WithImageLimits is set.
+func (o *provider) imageLimits() *fantasy.ImageLimits {
+	if o.options.imageLimits == nil && o.options.name == Name {
+		return &DefaultImageLimits
+	}
+	return o.options.imageLimits
+}
+
+// prepareImages prepares the images of prompt for limits, scaling low
+// detail images down further. Nil limits leave the prompt as is.
+func prepareImages(prompt fantasy.Prompt, limits *fantasy.ImageLimits) (fantasy.Prompt, []fantasy.CallWarning) {
+	if limits == nil {
+		return prompt, nil
+	}
+	return fantasy.PrepareImages(prompt, func(file fantasy.FilePart) fantasy.ImageLimits {
+		fileLimits := *limits
+		if opts, ok := file.ProviderOptions[Name].(*ProviderFileOptions); ok && opts.ImageDetail == "low" {
+			if fileLimits.MaxEdge == 0 || fileLimits.MaxEdge > lowDetailMaxEdge {
+				fileLimits.MaxEdge = lowDetailMaxEdge
+			}
+		}
+		return fileLimits
+	})
+}
+
 func (o *provider) isResponsesModel(modelID string) bool {
 	if o.options.responsesAPIFunc != nil {
 		return o.options.responsesAPIFunc(modelID)
@@ -9,7 +9,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"charm.land/fantasy"
 	"github.com/openai/openai-go/v3/packages/param"
@@ -182,6 +184,29 @@ func TestToOpenAiPrompt_UserMessages(t *testing.T) {
 		require.NotNil(t, imagePart)
 		require.Equal(t, "low", imagePart.ImageURL.Detail)
 	})
+
+	t.Run("should pass image URLs through", func(t *testing.T) {
+		t.Parallel()
+
+		prompt := fantasy.Prompt{
+			{
+				Role: fantasy.MessageRoleUser,
+				Content: []fantasy.MessagePart{
+					fantasy.FilePart{
+						MediaType: "image/png",
+						URL:       "https://example.com/cat.png",
+					},
+				},
+			},
+		}
+
+		messages, warnings := DefaultToPrompt(prompt, "openai", "gpt-5")
+
+		require.Empty(t, warnings)
+		imagePart := messages[0].OfUser.Content.OfArrayOfContentParts[0].OfImageURL
+		require.NotNil(t, imagePart)
+		require.Equal(t, "https://example.com/cat.png", imagePart.ImageURL.URL)
+	})
 }
 
 func TestToOpenAiPrompt_FileParts(t *testing.T) {
@@ -699,6 +724,7 @@ type mockServer struct {
 type mockCall struct {
 	method  string
 	path    string
+	query   string
 	headers map[string]string
 	body    map[string]any
 }
@@ -713,6 +739,7 @@ func newMockServer() *mockServer {
 		call := mockCall{
 			method:  r.Method,
 			path:    r.URL.Path,
+			query:   r.URL.RawQuery,
 			headers: make(map[string]string),
 		}
 
@@ -1089,6 +1116,42 @@ func TestDoGenerate(t *testing.T) {
 		require.Equal(t, "test-user-id", call.body["user"])
 	})
 
+	t.Run("should map num choices to n and return alternatives", func(t *testing.T) {
+		t.Parallel()
+
+		server := newMockServer()
+		defer server.close()
+
+		server.prepareJSONResponse(map[string]any{"content": "first"})
+		choices := server.response["choices"].([]map[string]any)
+		server.response["choices"] = append(choices, map[string]any{
+			"index": 1,
+			"message": map[string]any{
+				"role":    "assistant",
+				"content": "second",
+			},
+			"finish_reason": "stop",
+		})
+
+		provider, err := New(
+			WithAPIKey("test-api-key"),
+			WithBaseURL(server.server.URL),
+		)
+		require.NoError(t, err)
+		model, _ := provider.LanguageModel(t.Context(), "gpt-3.5-turbo")
+
+		result, err := model.Generate(context.Background(), fantasy.Call{
+			Prompt:     testPrompt,
+			NumChoices: new(int64(2)),
+		})
+
+		require.NoError(t, err)
+		require.Equal(t, float64(2), server.calls[0].body["n"])
+		require.Equal(t, "first", result.Content.Text())
+		require.Len(t, result.Alternatives, 1)
+		require.Equal(t, "second", result.Alternatives[0].Text())
+	})
+
 	t.Run("should pass reasoningEffort setting", func(t *testing.T) {
 		t.Parallel()
 
@@ -1714,6 +1777,38 @@ func TestDoGenerate(t *testing.T) {
 		require.Equal(t, "Hello", message["content"])
 	})
 
+	t.Run("should send call metadata under provider metadata", func(t *testing.T) {
+		t.Parallel()
+
+		server := newMockServer()
+		defer server.close()
+
+		server.prepareJSONResponse(map[string]any{
+			"content": "",
+		})
+
+		provider, err := New(
+			WithAPIKey("test-api-key"),
+			WithBaseURL(server.server.URL),
+		)
+		require.NoError(t, err)
+		model, _ := provider.LanguageModel(t.Context(), "gpt-3.5-turbo")
+
+		_, err = model.Generate(context.Background(), fantasy.Call{
+			Prompt:   testPrompt,
+			Metadata: map[string]string{"user_id": "user-123", "custom": "call"},
+			ProviderOptions: NewProviderOptions(&ProviderOptions{
+				Metadata: map[string]any{
+					"custom": "value",
+				},
+			}),
+		})
+
+		require.NoError(t, err)
+		require.Len(t, server.calls, 1)
+		require.Equal(t, map[string]any{"user_id": "user-123", "custom": "value"}, server.calls[0].body["metadata"])
+	})
+
 	t.Run("should send metadata extension values", func(t *testing.T) {
 		t.Parallel()
 
@@ -1907,6 +2002,31 @@ func TestDoGenerate(t *testing.T) {
 		require.Equal(t, "Hello", message["content"])
 	})
 
+	t.Run("should send ServiceTier from the call", func(t *testing.T) {
+		t.Parallel()
+
+		server := newMockServer()
+		defer server.close()
+
+		server.prepareJSONResponse(map[string]any{})
+
+		provider, err := New(
+			WithAPIKey("test-api-key"),
+			WithBaseURL(server.server.URL),
+		)
+		require.NoError(t, err)
+		model, _ := provider.LanguageModel(t.Context(), "o3-mini")
+
+		_, err = model.Generate(context.Background(), fantasy.Call{
+			Prompt:      testPrompt,
+			ServiceTier: fantasy.ServiceTierFlex,
+		})
+
+		require.NoError(t, err)
+		require.Len(t, server.calls, 1)
+		require.Equal(t, "flex", server.calls[0].body["service_tier"])
+	})
+
 	t.Run("should show warning when using flex processing with unsupported model", func(t *testing.T) {
 		t.Parallel()
 
@@ -3512,7 +3632,7 @@ func TestDefaultToPrompt_DropsEmptyMessages(t *testing.T) {
 
 		require.Len(t, messages, 1, "should only have user message")
 		require.Len(t, warnings, 1)
-		require.Equal(t, fantasy.CallWarningTypeOther, warnings[0].Type)
+		require.Equal(t, fantasy.CallWarningTypeDroppedContent, warnings[0].Type)
 		require.Contains(t, warnings[0].Message, "dropping empty assistant message")
 	})
 
@@ -3677,7 +3797,7 @@ func TestResponsesToPrompt_DropsEmptyMessages(t *testing.T) {
 
 		require.Len(t, input, 1, "should only have user message")
 		require.Len(t, warnings, 1)
-		require.Equal(t, fantasy.CallWarningTypeOther, warnings[0].Type)
+		require.Equal(t, fantasy.CallWarningTypeDroppedContent, warnings[0].Type)
 		require.Contains(t, warnings[0].Message, "dropping empty assistant message")
 	})
 
@@ -3973,6 +4093,48 @@ func TestUserAgent(t *testing.T) {
 		assert.Equal(t, "call-level-ua", server.calls[0].headers["User-Agent"])
 	})
 
+	t.Run("Call.Headers and Call.ExtraQuery are sent", func(t *testing.T) {
+		t.Parallel()
+
+		server := newMockServer()
+		defer server.close()
+		server.prepareJSONResponse(map[string]any{})
+
+		p, err := New(WithAPIKey("k"), WithBaseURL(server.server.URL))
+		require.NoError(t, err)
+		model, _ := p.LanguageModel(t.Context(), "gpt-4")
+		_, _ = model.Generate(t.Context(), fantasy.Call{
+			Prompt:     testPrompt,
+			Headers:    map[string]string{"X-Tenant": "acme"},
+			ExtraQuery: map[string]string{"route": "eu"},
+		})
+
+		require.Len(t, server.calls, 1)
+		assert.Equal(t, "acme", server.calls[0].headers["X-Tenant"])
+		assert.Equal(t, "route=eu", server.calls[0].query)
+	})
+
+	t.Run("Call.ExtraBody is merged into the body", func(t *testing.T) {
+		t.Parallel()
+
+		server := newMockServer()
+		defer server.close()
+		server.prepareJSONResponse(map[string]any{})
+
+		p, err := New(WithAPIKey("k"), WithBaseURL(server.server.URL))
+		require.NoError(t, err)
+		model, _ := p.LanguageModel(t.Context(), "gpt-4")
+		_, _ = model.Generate(t.Context(), fantasy.Call{
+			Prompt:      testPrompt,
+			Temperature: fantasy.Opt(0.9),
+			ExtraBody:   map[string]any{"temperature": 0.2, "new_param": map[string]any{"on": true}},
+		})
+
+		require.Len(t, server.calls, 1)
+		assert.Equal(t, 0.2, server.calls[0].body["temperature"])
+		assert.Equal(t, map[string]any{"on": true}, server.calls[0].body["new_param"])
+	})
+
 	t.Run("no Call UA falls through to provider UA", func(t *testing.T) {
 		t.Parallel()
 
@@ -4208,6 +4370,24 @@ func TestResponsesGenerate_StoreOption(t *testing.T) {
 	require.Equal(t, true, server.calls[0].body["store"])
 }
 
+func TestResponsesGenerate_CallMetadata(t *testing.T) {
+	t.Parallel()
+
+	server := newMockServer()
+	defer server.close()
+	server.response = mockResponsesWebSearchResponse()
+
+	model := newResponsesProvider(t, server.server.URL)
+
+	_, err := model.Generate(context.Background(), fantasy.Call{
+		Prompt:   testPrompt,
+		Metadata: map[string]string{"user_id": "user-123"},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]any{"user_id": "user-123"}, server.calls[0].body["metadata"])
+}
+
 func TestResponsesGenerate_PreviousResponseIDOption(t *testing.T) {
 	t.Parallel()
 
@@ -4277,6 +4457,9 @@ func TestResponsesGenerate_StateChainingAcrossTurns(t *testing.T) {
 	meta, ok := first.ProviderMetadata[Name].(*ResponsesProviderMetadata)
 	require.True(t, ok)
 	require.Equal(t, "resp_turn_1", meta.ResponseID)
+	typed, ok := MetadataFrom(first.ProviderMetadata)
+	require.True(t, ok)
+	require.Equal(t, Metadata{Responses: meta}, typed)
 
 	server.response = map[string]any{
 		"id":     "resp_turn_2",
@@ -5011,3 +5194,69 @@ func TestResponsesStream_TruncatedWithoutResponseCompleted(t *testing.T) {
 	require.True(t, providerErr.IsRetryable())
 	require.ErrorIs(t, providerErr.Cause, io.ErrUnexpectedEOF)
 }
+
+func TestProvider_Validate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		problem fantasy.ValidationProblem
+	}{
+		{name: "valid", status: http.StatusOK, body: `{"object":"list","data":[]}`},
+		{name: "bad key", status: http.StatusUnauthorized, body: `{"error":{"message":"Incorrect API key provided"}}`, problem: fantasy.ValidationProblemInvalidCredentials},
+		{name: "wrong base url", status: http.StatusNotFound, body: `{"error":{"message":"Not found"}}`, problem: fantasy.ValidationProblemWrongBaseURL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal(t, "/models", r.URL.Path)
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				_, _ = io.WriteString(w, tt.body)
+			}))
+			t.Cleanup(server.Close)
+
+			provider, err := New(WithAPIKey("test"), WithBaseURL(server.URL))
+			require.NoError(t, err)
+
+			err = fantasy.Validate(t.Context(), provider)
+			if tt.problem == "" {
+				require.NoError(t, err)
+				return
+			}
+			var validationErr *fantasy.ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			require.Equal(t, tt.problem, validationErr.Problem)
+			require.Equal(t, Name, validationErr.Provider)
+		})
+	}
+}
+
+func TestWithTransport(t *testing.T) {
+	t.Parallel()
+
+	server := newMockServer()
+	defer server.close()
+	server.prepareJSONResponse(map[string]any{})
+
+	var requests atomic.Int32
+	transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		requests.Add(1)
+		return http.DefaultTransport.RoundTrip(req)
+	})
+	p, err := New(WithAPIKey("k"), WithBaseURL(server.server.URL), WithTransport(transport), WithHTTPTimeout(time.Minute))
+	require.NoError(t, err)
+	model, _ := p.LanguageModel(t.Context(), "gpt-4")
+	_, err = model.Generate(t.Context(), fantasy.Call{Prompt: testPrompt})
+	require.NoError(t, err)
+	require.Equal(t, int32(1), requests.Load())
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
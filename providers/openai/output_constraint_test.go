@@ -0,0 +1,98 @@
+package openai
+
+import (
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/openai/openai-go/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrepareParams_OutputConstraint(t *testing.T) {
+	t.Parallel()
+
+	prompt := fantasy.Prompt{testTextMessage(fantasy.MessageRoleUser, "hello")}
+	answer := fantasy.Schema{
+		Type:       "object",
+		Properties: map[string]*fantasy.Schema{"answer": {Type: "string"}},
+		Required:   []string{"answer"},
+	}
+
+	t.Run("chat json schema", func(t *testing.T) {
+		t.Parallel()
+
+		lm := newLanguageModel("gpt-4o", Name, openai.Client{})
+		params, warnings, err := lm.prepareParams(fantasy.Call{
+			Prompt:           prompt,
+			OutputConstraint: fantasy.JSONSchemaConstraint("answer", answer),
+		})
+		require.NoError(t, err)
+		require.Empty(t, warnings)
+
+		format := params.ResponseFormat.OfJSONSchema
+		require.NotNil(t, format)
+		require.Equal(t, "answer", format.JSONSchema.Name)
+		require.True(t, format.JSONSchema.Strict.Value)
+		require.Equal(t, false, format.JSONSchema.Schema.(map[string]any)["additionalProperties"])
+	})
+
+	t.Run("chat grammar", func(t *testing.T) {
+		t.Parallel()
+
+		lm := newLanguageModel("gpt-4o", Name, openai.Client{})
+		params, warnings, err := lm.prepareParams(fantasy.Call{
+			Prompt:           prompt,
+			OutputConstraint: fantasy.GrammarConstraint(`root ::= "yes"`),
+		})
+		require.NoError(t, err)
+		require.Nil(t, params.ResponseFormat.OfJSONSchema)
+		require.Len(t, warnings, 1)
+		require.Equal(t, "output_constraint", warnings[0].Setting)
+	})
+
+	t.Run("responses json schema", func(t *testing.T) {
+		t.Parallel()
+
+		params, warnings, err := testResponsesLM().prepareParams(fantasy.Call{
+			Prompt:           prompt,
+			OutputConstraint: fantasy.JSONSchemaConstraint("", answer),
+		})
+		require.NoError(t, err)
+		require.Empty(t, warnings)
+		require.NotNil(t, params.Text.Format.OfJSONSchema)
+		require.Equal(t, "response", params.Text.Format.OfJSONSchema.Name)
+	})
+
+	t.Run("responses regex", func(t *testing.T) {
+		t.Parallel()
+
+		_, warnings, err := testResponsesLM().prepareParams(fantasy.Call{
+			Prompt:           prompt,
+			OutputConstraint: fantasy.RegexConstraint(`\d+`),
+		})
+		require.NoError(t, err)
+		require.Len(t, warnings, 1)
+		require.Equal(t, fantasy.CallWarningTypeUnsupportedSetting, warnings[0].Type)
+	})
+
+	t.Run("strictified schema warnings", func(t *testing.T) {
+		t.Parallel()
+
+		lossy := fantasy.Schema{
+			Type: "object",
+			Properties: map[string]*fantasy.Schema{
+				"answer": {Type: "string", MinLength: new(1)},
+				"note":   {Type: "string"},
+			},
+			Required: []string{"answer"},
+		}
+		params, warnings, err := testResponsesLM().prepareParams(fantasy.Call{
+			Prompt:           prompt,
+			OutputConstraint: fantasy.JSONSchemaConstraint("", lossy),
+		})
+		require.NoError(t, err)
+		require.Len(t, warnings, 2)
+		require.Equal(t, fantasy.CallWarningTypeOther, warnings[0].Type)
+		require.Equal(t, []string{"answer", "note"}, params.Text.Format.OfJSONSchema.Schema["required"])
+	})
+}
@@ -0,0 +1,59 @@
+package openai
+
+import (
+	"testing"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/fantasytest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultToPrompt_Properties(t *testing.T) {
+	t.Parallel()
+
+	fantasytest.ForAll(t, 200, func(t *testing.T, call fantasy.Call) {
+		messages, _ := DefaultToPrompt(call.Prompt, "openai", "gpt-4o")
+		fantasytest.RequireTextsPreserved(t, call.Prompt, messages)
+		fantasytest.RequireToolCallIDsPreserved(t, call.Prompt, messages)
+
+		// The tool messages answering an assistant's tool calls follow it
+		// immediately.
+		for i, msg := range messages {
+			if msg.OfAssistant == nil {
+				continue
+			}
+			for j, toolCall := range msg.OfAssistant.ToolCalls {
+				id := toolCall.OfFunction.ID
+				require.Less(t, i+1+j, len(messages), "tool call %s is not answered", id)
+				next := messages[i+1+j]
+				require.NotNil(t, next.OfTool, "tool call %s is not answered by message %d", id, i+1+j)
+				require.Equal(t, id, next.OfTool.ToolCallID)
+			}
+		}
+	})
+}
+
+func TestToResponsesPrompt_Properties(t *testing.T) {
+	t.Parallel()
+
+	fantasytest.ForAll(t, 200, func(t *testing.T, call fantasy.Call) {
+		input, _ := toResponsesPrompt(call.Prompt, "system", false)
+		fantasytest.RequireTextsPreserved(t, call.Prompt, input)
+		fantasytest.RequireToolCallIDsPreserved(t, call.Prompt, input)
+
+		// Every function call has an output after it.
+		for i, item := range input {
+			if item.OfFunctionCall == nil {
+				continue
+			}
+			answered := false
+			for _, later := range input[i+1:] {
+				if later.OfFunctionCallOutput != nil && later.OfFunctionCallOutput.CallID == item.OfFunctionCall.CallID {
+					answered = true
+					break
+				}
+			}
+			require.True(t, answered, "function call %s is not answered", item.OfFunctionCall.CallID)
+		}
+	})
+}
@@ -33,6 +33,7 @@ const (
 const (
 	TypeProviderOptions     = Name + ".options"
 	TypeProviderFileOptions = Name + ".file_options"
+	TypeProviderToolOptions = Name + ".tool_options"
 	TypeProviderMetadata    = Name + ".metadata"
 )
 
@@ -52,6 +53,13 @@ func init() {
 		}
 		return &v, nil
 	})
+	fantasy.RegisterProviderType(TypeProviderToolOptions, func(data []byte) (fantasy.ProviderOptionsData, error) {
+		var v ProviderToolOptions
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		return &v, nil
+	})
 	fantasy.RegisterProviderType(TypeProviderMetadata, func(data []byte) (fantasy.ProviderOptionsData, error) {
 		var v ProviderMetadata
 		if err := json.Unmarshal(data, &v); err != nil {
@@ -59,6 +67,11 @@ func init() {
 		}
 		return &v, nil
 	})
+	// OpenAI caches prompt prefixes automatically.
+	fantasy.RegisterCacheProfile(Name, fantasy.CacheProfile{
+		MinTokens: 1024,
+		ReadCost:  0.5,
+	})
 }
 
 // ProviderMetadata represents additional metadata from OpenAI provider.
@@ -186,6 +199,36 @@ func (o *ProviderFileOptions) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// ProviderToolOptions represents function tool options for OpenAI provider.
+type ProviderToolOptions struct {
+	// Strict makes the model follow the tool's input schema exactly, so its
+	// arguments are always valid. The schema is made strict first: every
+	// object gets additionalProperties false and all its properties
+	// required, with optional ones made nullable. It overrides
+	// StrictJSONSchema of the Responses API for the tool.
+	Strict *bool `json:"strict,omitempty"`
+}
+
+// Options implements the ProviderOptions interface.
+func (*ProviderToolOptions) Options() {}
+
+// MarshalJSON implements custom JSON marshaling with type info for ProviderToolOptions.
+func (o ProviderToolOptions) MarshalJSON() ([]byte, error) {
+	type plain ProviderToolOptions
+	return fantasy.MarshalProviderType(TypeProviderToolOptions, plain(o))
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling with type info for ProviderToolOptions.
+func (o *ProviderToolOptions) UnmarshalJSON(data []byte) error {
+	type plain ProviderToolOptions
+	var p plain
+	if err := fantasy.UnmarshalProviderType(data, &p); err != nil {
+		return err
+	}
+	*o = ProviderToolOptions(p)
+	return nil
+}
+
 // ReasoningEffortOption creates a pointer to a ReasoningEffort value.
 //
 //go:fix inline
@@ -207,6 +250,13 @@ func NewProviderFileOptions(opts *ProviderFileOptions) fantasy.ProviderOptions {
 	}
 }
 
+// NewProviderToolOptions creates new function tool options for OpenAI.
+func NewProviderToolOptions(opts *ProviderToolOptions) fantasy.ProviderOptions {
+	return fantasy.ProviderOptions{
+		Name: opts,
+	}
+}
+
 // ParseOptions parses provider options from a map.
 func ParseOptions(data map[string]any) (*ProviderOptions, error) {
 	var options ProviderOptions
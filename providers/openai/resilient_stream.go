@@ -0,0 +1,149 @@
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+
+	"charm.land/fantasy"
+	"github.com/openai/openai-go/v3/option"
+)
+
+// WithResilientStreamParsing makes chat completion streams skip events
+// whose data isn't a valid chunk, instead of failing the whole generation.
+// Each skipped event is reported as a warning stream part with its raw data
+// in Details. Some OpenAI-compatible gateways occasionally emit such junk.
+func WithResilientStreamParsing() Option {
+	return func(o *options) {
+		o.resilientStreams = true
+	}
+}
+
+type skippedChunksKey struct{}
+
+// skippedChunks collects the warnings for the events dropped from a stream.
+type skippedChunks struct {
+	mu       sync.Mutex
+	warnings []fantasy.CallWarning
+}
+
+func (s *skippedChunks) add(reason string, data []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.warnings = append(s.warnings, fantasy.CallWarning{
+		Type:    fantasy.CallWarningTypeOther,
+		Message: "skipped malformed stream chunk: " + reason,
+		Details: string(data),
+	})
+}
+
+func (s *skippedChunks) drain() []fantasy.CallWarning {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	warnings := s.warnings
+	s.warnings = nil
+	return warnings
+}
+
+// resilientStreamMiddleware filters the body of streaming responses whose
+// request context carries a *skippedChunks.
+func resilientStreamMiddleware(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+	resp, err := next(req)
+	if err != nil || resp == nil || resp.Body == nil || resp.StatusCode >= 400 {
+		return resp, err
+	}
+	skipped, ok := req.Context().Value(skippedChunksKey{}).(*skippedChunks)
+	if !ok {
+		return resp, err
+	}
+	resp.Body = &sseFilter{body: resp.Body, r: bufio.NewReader(resp.Body), skipped: skipped}
+	return resp, nil
+}
+
+// sseFilter passes server-sent events through, dropping the events whose
+// data is not a JSON object with the shape of a chunk.
+type sseFilter struct {
+	body    io.ReadCloser
+	r       *bufio.Reader
+	skipped *skippedChunks
+	event   [][]byte
+	out     bytes.Buffer
+	err     error
+}
+
+func (f *sseFilter) Read(p []byte) (int, error) {
+	for f.out.Len() == 0 && f.err == nil {
+		line, err := f.r.ReadBytes('\n')
+		if len(line) > 0 {
+			f.event = append(f.event, line)
+			if len(bytes.TrimRight(line, "\r\n")) == 0 {
+				f.flush()
+			}
+		}
+		if err != nil {
+			f.flush()
+			f.err = err
+		}
+	}
+	if f.out.Len() > 0 {
+		return f.out.Read(p)
+	}
+	return 0, f.err
+}
+
+func (f *sseFilter) Close() error {
+	return f.body.Close()
+}
+
+// flush writes the buffered event out, unless its data is malformed.
+func (f *sseFilter) flush() {
+	defer func() { f.event = f.event[:0] }()
+	var data []byte
+	hasData := false
+	for _, line := range f.event {
+		value, ok := bytes.CutPrefix(bytes.TrimRight(line, "\r\n"), []byte("data:"))
+		if !ok {
+			continue
+		}
+		if hasData {
+			data = append(data, '\n')
+		}
+		data = append(data, bytes.TrimPrefix(value, []byte(" "))...)
+		hasData = true
+	}
+	if hasData {
+		if err := checkChunk(data); err != nil {
+			f.skipped.add(err.Error(), data)
+			return
+		}
+	}
+	for _, line := range f.event {
+		f.out.Write(line)
+	}
+}
+
+// checkChunk returns an error if the event data can't be decoded as a
+// chat completion chunk.
+func checkChunk(data []byte) error {
+	if bytes.HasPrefix(data, []byte("[DONE]")) {
+		return nil
+	}
+	var chunk map[string]json.RawMessage
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		return err
+	}
+	if choices, ok := chunk["choices"]; ok && !bytes.HasPrefix(choices, []byte("[")) && !bytes.Equal(choices, []byte("null")) {
+		return errors.New("choices is not an array")
+	}
+	return nil
+}
+
+func withSkippedChunks(ctx context.Context) (context.Context, *skippedChunks) {
+	skipped := &skippedChunks{}
+	return context.WithValue(ctx, skippedChunksKey{}, skipped), skipped
+}
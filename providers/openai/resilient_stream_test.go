@@ -0,0 +1,88 @@
+package openai
+
+import (
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResilientStreamParsing(t *testing.T) {
+	t.Parallel()
+
+	chunks := []string{
+		`data: {"id":"c1","object":"chat.completion.chunk","created":1,"model":"m","choices":[{"index":0,"delta":{"role":"assistant","content":"Hel"},"finish_reason":null}]}` + "\n\n",
+		"data: {not json\n\n",
+		`data: {"id":"c1","object":"chat.completion.chunk","created":1,"model":"m","choices":"oops"}` + "\n\n",
+		`data: {"id":"c1","object":"chat.completion.chunk","created":1,"model":"m","choices":[{"index":0,"delta":{"content":"lo"},"finish_reason":"stop"}]}` + "\n\n",
+		`data: {"id":"c1","object":"chat.completion.chunk","created":1,"model":"m","choices":[],"usage":{"prompt_tokens":1,"completion_tokens":2,"total_tokens":3}}` + "\n\n",
+		"data: [DONE]\n\n",
+	}
+
+	stream := func(t *testing.T, opts ...Option) ([]fantasy.StreamPart, error) {
+		server := newStreamingMockServer()
+		defer server.close()
+		server.chunks = chunks
+
+		p, err := New(append([]Option{WithAPIKey("k"), WithBaseURL(server.server.URL)}, opts...)...)
+		require.NoError(t, err)
+		model, err := p.LanguageModel(t.Context(), "gpt-4o")
+		require.NoError(t, err)
+		resp, err := model.Stream(t.Context(), fantasy.Call{Prompt: testPrompt})
+		require.NoError(t, err)
+
+		var parts []fantasy.StreamPart
+		for part := range resp {
+			if part.Type == fantasy.StreamPartTypeError {
+				return parts, part.Error
+			}
+			parts = append(parts, part)
+		}
+		return parts, nil
+	}
+
+	t.Run("default fails on malformed chunks", func(t *testing.T) {
+		t.Parallel()
+		_, err := stream(t)
+		require.Error(t, err)
+	})
+
+	t.Run("resilient mode skips them with warnings", func(t *testing.T) {
+		t.Parallel()
+		parts, err := stream(t, WithResilientStreamParsing())
+		require.NoError(t, err)
+
+		var text string
+		var warnings []fantasy.CallWarning
+		var finish fantasy.StreamPart
+		for _, part := range parts {
+			switch part.Type {
+			case fantasy.StreamPartTypeTextDelta:
+				text += part.Delta
+			case fantasy.StreamPartTypeWarnings:
+				warnings = append(warnings, part.Warnings...)
+			case fantasy.StreamPartTypeFinish:
+				finish = part
+			}
+		}
+		require.Equal(t, "Hello", text)
+		require.Len(t, warnings, 2)
+		require.Equal(t, fantasy.CallWarningTypeOther, warnings[0].Type)
+		require.Contains(t, warnings[0].Message, "skipped malformed stream chunk")
+		require.Equal(t, "{not json", warnings[0].Details)
+		require.Equal(t, "skipped malformed stream chunk: choices is not an array", warnings[1].Message)
+		require.Equal(t, fantasy.FinishReasonStop, finish.FinishReason)
+		require.Equal(t, int64(3), finish.Usage.TotalTokens)
+	})
+}
+
+func TestCheckChunk(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, checkChunk([]byte("[DONE]")))
+	require.NoError(t, checkChunk([]byte(`{"choices":[]}`)))
+	require.NoError(t, checkChunk([]byte(`{"error":{"message":"overloaded"}}`)))
+	require.Error(t, checkChunk([]byte(`[1, 2]`)))
+	require.Error(t, checkChunk([]byte(`{"choices":{}}`)))
+	require.Error(t, checkChunk([]byte(`: keep-alive`)))
+}
@@ -1,18 +1,20 @@
 package openai
 
 import (
+	"cmp"
 	"context"
 	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"reflect"
+	"slices"
 	"strings"
 
 	"charm.land/fantasy"
 	"charm.land/fantasy/object"
 	"charm.land/fantasy/schema"
-	"github.com/google/uuid"
 	"github.com/openai/openai-go/v3"
 	"github.com/openai/openai-go/v3/packages/param"
 	"github.com/openai/openai-go/v3/responses"
@@ -26,6 +28,9 @@ type responsesLanguageModel struct {
 	modelID    string
 	client     openai.Client
 	objectMode fantasy.ObjectMode
+	// imageLimits are the limits images are prepared for. Nil sends them
+	// as is.
+	imageLimits *fantasy.ImageLimits
 }
 
 // newResponsesLanguageModel implements a responses api model.
@@ -124,6 +129,17 @@ const (
 	previousResponseIDStoreError   = "previous_response_id requires store to be true; the current response will not be stored and cannot be used for further chaining"
 )
 
+// SupportsPDF implements fantasy.PDFModel.
+func (o responsesLanguageModel) SupportsPDF() bool {
+	return true
+}
+
+// SupportsFileURL implements fantasy.FileURLModel. Image URLs are passed to
+// OpenAI.
+func (o responsesLanguageModel) SupportsFileURL(mediaType string) bool {
+	return strings.HasPrefix(mediaType, "image/")
+}
+
 func (o responsesLanguageModel) prepareParams(call fantasy.Call) (*responses.ResponseNewParams, []fantasy.CallWarning, error) {
 	var warnings []fantasy.CallWarning
 	params := &responses.ResponseNewParams{}
@@ -175,7 +191,12 @@ func (o responsesLanguageModel) prepareParams(call fantasy.Call) (*responses.Res
 	}
 
 	storeEnabled := openaiOptions != nil && openaiOptions.Store != nil && *openaiOptions.Store
-	input, inputWarnings := toResponsesPrompt(call.Prompt, modelConfig.systemMessageMode, storeEnabled)
+	prompt, imageWarnings := prepareImages(call.Prompt, o.imageLimits)
+	warnings = append(warnings, imageWarnings...)
+	input, inputWarnings := toResponsesPrompt(prompt, modelConfig.systemMessageMode, storeEnabled)
+	if err := fantasy.CheckDroppedContent(call, inputWarnings); err != nil {
+		return nil, nil, err
+	}
 	warnings = append(warnings, inputWarnings...)
 
 	var include []IncludeType
@@ -217,12 +238,19 @@ func (o responsesLanguageModel) prepareParams(call fantasy.Call) (*responses.Res
 		params.MaxOutputTokens = param.NewOpt(*call.MaxOutputTokens)
 	}
 
+	if len(call.Metadata) > 0 {
+		params.Metadata = maps.Clone(call.Metadata)
+	}
+
 	if openaiOptions != nil {
 		if openaiOptions.MaxToolCalls != nil {
 			params.MaxToolCalls = param.NewOpt(*openaiOptions.MaxToolCalls)
 		}
 		if openaiOptions.Metadata != nil {
-			metadata := make(shared.Metadata)
+			metadata := shared.Metadata(maps.Clone(call.Metadata))
+			if metadata == nil {
+				metadata = make(shared.Metadata)
+			}
 			for k, v := range openaiOptions.Metadata {
 				if str, ok := v.(string); ok {
 					metadata[k] = str
@@ -239,9 +267,6 @@ func (o responsesLanguageModel) prepareParams(call fantasy.Call) (*responses.Res
 		if openaiOptions.Instructions != nil {
 			params.Instructions = param.NewOpt(*openaiOptions.Instructions)
 		}
-		if openaiOptions.ServiceTier != nil {
-			params.ServiceTier = responses.ResponseNewParamsServiceTier(*openaiOptions.ServiceTier)
-		}
 		if openaiOptions.PromptCacheKey != nil {
 			params.PromptCacheKey = param.NewOpt(*openaiOptions.PromptCacheKey)
 		}
@@ -318,8 +343,14 @@ func (o responsesLanguageModel) prepareParams(call fantasy.Call) (*responses.Res
 		}
 	}
 
+	serviceTier := ServiceTier(call.ServiceTier)
 	if openaiOptions != nil && openaiOptions.ServiceTier != nil {
-		if *openaiOptions.ServiceTier == ServiceTierFlex && !modelConfig.supportsFlexProcessing {
+		serviceTier = *openaiOptions.ServiceTier
+	}
+	if serviceTier != "" {
+		params.ServiceTier = responses.ResponseNewParamsServiceTier(serviceTier)
+
+		if serviceTier == ServiceTierFlex && !modelConfig.supportsFlexProcessing {
 			warnings = append(warnings, fantasy.CallWarning{
 				Type:    fantasy.CallWarningTypeUnsupportedSetting,
 				Setting: "serviceTier",
@@ -328,7 +359,7 @@ func (o responsesLanguageModel) prepareParams(call fantasy.Call) (*responses.Res
 			params.ServiceTier = ""
 		}
 
-		if *openaiOptions.ServiceTier == ServiceTierPriority && !modelConfig.supportsPriorityProcessing {
+		if serviceTier == ServiceTierPriority && !modelConfig.supportsPriorityProcessing {
 			warnings = append(warnings, fantasy.CallWarning{
 				Type:    fantasy.CallWarningTypeUnsupportedSetting,
 				Setting: "serviceTier",
@@ -338,6 +369,16 @@ func (o responsesLanguageModel) prepareParams(call fantasy.Call) (*responses.Res
 		}
 	}
 
+	if call.OutputConstraint != nil {
+		if call.OutputConstraint.Type == fantasy.OutputConstraintTypeJSONSchema {
+			jsonSchemaMap, schemaWarnings := call.OutputConstraint.Schema.Strictify()
+			warnings = append(warnings, strictSchemaWarnings(schemaWarnings)...)
+			params.Text.Format = responses.ResponseFormatTextConfigParamOfJSONSchema(cmp.Or(call.OutputConstraint.Name, "response"), jsonSchemaMap)
+		} else {
+			warnings = append(warnings, fantasy.UnsupportedOutputConstraintWarning(call.OutputConstraint))
+		}
+	}
+
 	tools, toolChoice, toolWarnings := toResponsesTools(call.Tools, call.ToolChoice, openaiOptions)
 	warnings = append(warnings, toolWarnings...)
 
@@ -472,6 +513,9 @@ func toResponsesPrompt(prompt fantasy.Prompt, systemMessageMode string, store bo
 					if strings.HasPrefix(filePart.MediaType, "image/") {
 						base64Encoded := base64.StdEncoding.EncodeToString(filePart.Data)
 						imageURL := fmt.Sprintf("data:%s;base64,%s", filePart.MediaType, base64Encoded)
+						if filePart.URL != "" {
+							imageURL = filePart.URL
+						}
 						contentParts = append(contentParts, responses.ResponseInputContentUnionParam{
 							OfInputImage: &responses.ResponseInputImageParam{
 								Type:     "input_image",
@@ -494,7 +538,7 @@ func toResponsesPrompt(prompt fantasy.Prompt, systemMessageMode string, store bo
 						})
 					} else {
 						warnings = append(warnings, fantasy.CallWarning{
-							Type:    fantasy.CallWarningTypeOther,
+							Type:    fantasy.CallWarningTypeDroppedContent,
 							Message: fmt.Sprintf("file part media type %s not supported", filePart.MediaType),
 						})
 					}
@@ -503,7 +547,7 @@ func toResponsesPrompt(prompt fantasy.Prompt, systemMessageMode string, store bo
 
 			if !hasVisibleResponsesUserContent(contentParts) {
 				warnings = append(warnings, fantasy.CallWarning{
-					Type:    fantasy.CallWarningTypeOther,
+					Type:    fantasy.CallWarningTypeDroppedContent,
 					Message: "dropping empty user message (contains neither user-facing content nor tool results)",
 				})
 				continue
@@ -568,7 +612,7 @@ func toResponsesPrompt(prompt fantasy.Prompt, systemMessageMode string, store bo
 
 			if !hasVisibleResponsesAssistantContent(input, startIdx) {
 				warnings = append(warnings, fantasy.CallWarning{
-					Type:    fantasy.CallWarningTypeOther,
+					Type:    fantasy.CallWarningTypeDroppedContent,
 					Message: "dropping empty assistant message (contains neither user-facing content nor tool calls)",
 				})
 				// Remove any items that were added during this iteration
@@ -653,7 +697,7 @@ func toResponsesPrompt(prompt fantasy.Prompt, systemMessageMode string, store bo
 						})
 					} else {
 						warnings = append(warnings, fantasy.CallWarning{
-							Type:    fantasy.CallWarningTypeOther,
+							Type:    fantasy.CallWarningTypeDroppedContent,
 							Message: fmt.Sprintf("tool result media type %s not supported, sending text placeholder only", output.MediaType),
 						})
 					}
@@ -709,12 +753,14 @@ func toResponsesTools(tools []fantasy.Tool, toolChoice *fantasy.ToolChoice, opti
 			if !ok {
 				continue
 			}
+			parameters, strict, toolWarnings := functionToolParameters(ft, strictJSONSchema)
+			warnings = append(warnings, toolWarnings...)
 			openaiTools = append(openaiTools, responses.ToolUnionParam{
 				OfFunction: &responses.FunctionToolParam{
 					Name:        ft.Name,
 					Description: param.NewOpt(ft.Description),
-					Parameters:  ft.InputSchema,
-					Strict:      param.NewOpt(strictJSONSchema),
+					Parameters:  parameters,
+					Strict:      param.NewOpt(strict),
 					Type:        "function",
 				},
 			})
@@ -776,7 +822,7 @@ func (o responsesLanguageModel) Generate(ctx context.Context, call fantasy.Call)
 		return nil, err
 	}
 
-	response, err := o.client.Responses.New(ctx, *params, append(callUARequestOptions(call), callHeadersRequestOptions(call)...)...)
+	response, err := o.client.Responses.New(ctx, *params, slices.Concat(callUARequestOptions(call), callHeadersRequestOptions(call), callQueryRequestOptions(call.ExtraQuery), callBodyRequestOptions(call.ExtraBody))...)
 	if err != nil {
 		return nil, toProviderErr(err)
 	}
@@ -794,6 +840,9 @@ func (o responsesLanguageModel) Generate(ctx context.Context, call fantasy.Call)
 
 	var content []fantasy.Content
 	hasFunctionCall := false
+	// textOffset is the byte length of the text emitted so far, used to make
+	// citation offsets relative to the whole response.
+	textOffset := 0
 
 	for _, outputItem := range response.Output {
 		switch outputItem.Type {
@@ -809,9 +858,12 @@ func (o responsesLanguageModel) Generate(ctx context.Context, call fantasy.Call)
 						case "url_citation":
 							content = append(content, fantasy.SourceContent{
 								SourceType: fantasy.SourceTypeURL,
-								ID:         uuid.NewString(),
+								ID:         fantasy.NewID(),
 								URL:        annotation.URL,
 								Title:      annotation.Title,
+								Citations: []fantasy.Citation{
+									shiftCitation(newCitation(contentPart.Text, annotation.StartIndex, annotation.EndIndex), textOffset),
+								},
 							})
 						case "file_citation":
 							title := "Document"
@@ -824,13 +876,17 @@ func (o responsesLanguageModel) Generate(ctx context.Context, call fantasy.Call)
 							}
 							content = append(content, fantasy.SourceContent{
 								SourceType: fantasy.SourceTypeDocument,
-								ID:         uuid.NewString(),
+								ID:         fantasy.NewID(),
 								MediaType:  "text/plain",
 								Title:      title,
 								Filename:   filename,
+								Citations: []fantasy.Citation{
+									shiftCitation(newCitation(contentPart.Text, annotation.Index, annotation.Index), textOffset),
+								},
 							})
 						}
 					}
+					textOffset += len(contentPart.Text)
 				}
 			}
 
@@ -897,17 +953,36 @@ func (o responsesLanguageModel) Generate(ctx context.Context, call fantasy.Call)
 	}
 
 	usage := responsesUsage(*response)
-	finishReason := mapResponsesFinishReason(response.IncompleteDetails.Reason, hasFunctionCall)
+	safety := responsesSafety(*response)
+	finishReason := mapRefusalFinishReason(mapResponsesFinishReason(response.IncompleteDetails.Reason, hasFunctionCall), safety)
 
 	return &fantasy.Response{
 		Content:          content,
 		Usage:            usage,
 		FinishReason:     finishReason,
+		Safety:           safety,
 		ProviderMetadata: responsesProviderMetadata(response.ID),
 		Warnings:         warnings,
 	}, nil
 }
 
+// responsesSafety reports refusal output parts and content_filter
+// incomplete reasons as safety information.
+func responsesSafety(response responses.Response) *fantasy.SafetyInfo {
+	var refusal strings.Builder
+	for _, outputItem := range response.Output {
+		if outputItem.Type != "message" {
+			continue
+		}
+		for _, contentPart := range outputItem.Content {
+			if contentPart.Type == "refusal" {
+				refusal.WriteString(contentPart.Refusal)
+			}
+		}
+	}
+	return mapSafety(refusal.String(), response.IncompleteDetails.Reason, "")
+}
+
 func mapResponsesFinishReason(reason string, hasFunctionCall bool) fantasy.FinishReason {
 	if hasFunctionCall {
 		return fantasy.FinishReasonToolCalls
@@ -931,10 +1006,11 @@ func (o responsesLanguageModel) Stream(ctx context.Context, call fantasy.Call) (
 		return nil, err
 	}
 
-	stream := o.client.Responses.NewStreaming(ctx, *params, append(callUARequestOptions(call), callHeadersRequestOptions(call)...)...)
+	stream := o.client.Responses.NewStreaming(ctx, *params, slices.Concat(callUARequestOptions(call), callHeadersRequestOptions(call), callQueryRequestOptions(call.ExtraQuery), callBodyRequestOptions(call.ExtraBody))...)
 
 	finishReason := fantasy.FinishReasonUnknown
 	var usage fantasy.Usage
+	var safety *fantasy.SafetyInfo
 	// responseID tracks the server-assigned response ID. It's first set from the
 	// response.created event and may be overwritten by response.completed or
 	// response.incomplete events. Per the OpenAI API contract, these IDs are
@@ -945,6 +1021,11 @@ func (o responsesLanguageModel) Stream(ctx context.Context, call fantasy.Call) (
 	ongoingToolCalls := make(map[int64]*ongoingToolCall)
 	hasFunctionCall := false
 	activeReasoning := make(map[string]*reasoningState)
+	// streamedText accumulates all text deltas so citations can be resolved
+	// to byte offsets; textItemOffsets records where each message item
+	// starts within it.
+	var streamedText strings.Builder
+	textItemOffsets := make(map[string]int)
 
 	return func(yield func(fantasy.StreamPart) bool) {
 		if len(warnings) > 0 {
@@ -1117,6 +1198,10 @@ func (o responsesLanguageModel) Stream(ctx context.Context, call fantasy.Call) (
 
 			case "response.output_text.delta":
 				textDelta := event.AsResponseOutputTextDelta()
+				if _, ok := textItemOffsets[textDelta.ItemID]; !ok {
+					textItemOffsets[textDelta.ItemID] = streamedText.Len()
+				}
+				streamedText.WriteString(textDelta.Delta)
 				if !yield(fantasy.StreamPart{
 					Type:  fantasy.StreamPartTypeTextDelta,
 					ID:    textDelta.ItemID,
@@ -1134,16 +1219,26 @@ func (o responsesLanguageModel) Stream(ctx context.Context, call fantasy.Call) (
 					break
 				}
 				annotationType, _ := annotationMap["type"].(string)
+				itemOffset, ok := textItemOffsets[added.ItemID]
+				if !ok {
+					itemOffset = streamedText.Len()
+				}
+				itemText := streamedText.String()[itemOffset:]
 				switch annotationType {
 				case "url_citation":
 					url, _ := annotationMap["url"].(string)
 					title, _ := annotationMap["title"].(string)
+					startIndex, _ := annotationMap["start_index"].(float64)
+					endIndex, _ := annotationMap["end_index"].(float64)
 					if !yield(fantasy.StreamPart{
 						Type:       fantasy.StreamPartTypeSource,
-						ID:         uuid.NewString(),
+						ID:         fantasy.NewID(),
 						SourceType: fantasy.SourceTypeURL,
 						URL:        url,
 						Title:      title,
+						Citations: []fantasy.Citation{
+							shiftCitation(newCitation(itemText, int64(startIndex), int64(endIndex)), itemOffset),
+						},
 					}) {
 						return
 					}
@@ -1152,11 +1247,15 @@ func (o responsesLanguageModel) Stream(ctx context.Context, call fantasy.Call) (
 					if fn, ok := annotationMap["filename"].(string); ok && fn != "" {
 						title = fn
 					}
+					index, _ := annotationMap["index"].(float64)
 					if !yield(fantasy.StreamPart{
 						Type:       fantasy.StreamPartTypeSource,
-						ID:         uuid.NewString(),
+						ID:         fantasy.NewID(),
 						SourceType: fantasy.SourceTypeDocument,
 						Title:      title,
+						Citations: []fantasy.Citation{
+							shiftCitation(newCitation(itemText, int64(index), int64(index)), itemOffset),
+						},
 					}) {
 						return
 					}
@@ -1204,14 +1303,16 @@ func (o responsesLanguageModel) Stream(ctx context.Context, call fantasy.Call) (
 				sawTerminalEvent = true
 				completed := event.AsResponseCompleted()
 				responseID = completed.Response.ID
-				finishReason = mapResponsesFinishReason(completed.Response.IncompleteDetails.Reason, hasFunctionCall)
+				safety = responsesSafety(completed.Response)
+				finishReason = mapRefusalFinishReason(mapResponsesFinishReason(completed.Response.IncompleteDetails.Reason, hasFunctionCall), safety)
 				usage = responsesUsage(completed.Response)
 
 			case "response.incomplete":
 				sawTerminalEvent = true
 				incomplete := event.AsResponseIncomplete()
 				responseID = incomplete.Response.ID
-				finishReason = mapResponsesFinishReason(incomplete.Response.IncompleteDetails.Reason, hasFunctionCall)
+				safety = responsesSafety(incomplete.Response)
+				finishReason = mapRefusalFinishReason(mapResponsesFinishReason(incomplete.Response.IncompleteDetails.Reason, hasFunctionCall), safety)
 				usage = responsesUsage(incomplete.Response)
 
 			case "response.failed":
@@ -1261,6 +1362,7 @@ func (o responsesLanguageModel) Stream(ctx context.Context, call fantasy.Call) (
 			Type:             fantasy.StreamPartTypeFinish,
 			Usage:            usage,
 			FinishReason:     finishReason,
+			Safety:           safety,
 			ProviderMetadata: responsesProviderMetadata(responseID),
 		})
 	}, nil
@@ -1386,10 +1488,9 @@ func (o responsesLanguageModel) StreamObject(ctx context.Context, call fantasy.O
 
 func (o responsesLanguageModel) generateObjectWithJSONMode(ctx context.Context, call fantasy.ObjectCall) (*fantasy.ObjectResponse, error) {
 	// Convert our Schema to OpenAI's JSON Schema format
-	jsonSchemaMap := schema.ToMap(call.Schema)
-
-	// Add additionalProperties: false recursively for strict mode (OpenAI requirement)
-	addAdditionalPropertiesFalse(jsonSchemaMap)
+	// Strict mode requires additionalProperties: false and every property
+	// to be required.
+	jsonSchemaMap, schemaWarnings := call.Schema.Strictify()
 
 	schemaName := call.SchemaName
 	if schemaName == "" {
@@ -1411,6 +1512,7 @@ func (o responsesLanguageModel) generateObjectWithJSONMode(ctx context.Context,
 	if err != nil {
 		return nil, err
 	}
+	warnings = append(warnings, strictSchemaWarnings(schemaWarnings)...)
 
 	// Add structured output via Text.Format field
 	params.Text = responses.ResponseTextConfigParam{
@@ -1418,7 +1520,7 @@ func (o responsesLanguageModel) generateObjectWithJSONMode(ctx context.Context,
 	}
 
 	// Make request
-	response, err := o.client.Responses.New(ctx, *params, append(objectCallUARequestOptions(call), objectCallHeadersRequestOptions(call)...)...)
+	response, err := o.client.Responses.New(ctx, *params, slices.Concat(objectCallUARequestOptions(call), objectCallHeadersRequestOptions(call), callQueryRequestOptions(call.ExtraQuery), callBodyRequestOptions(call.ExtraBody))...)
 	if err != nil {
 		return nil, toProviderErr(err)
 	}
@@ -1490,10 +1592,9 @@ func (o responsesLanguageModel) generateObjectWithJSONMode(ctx context.Context,
 
 func (o responsesLanguageModel) streamObjectWithJSONMode(ctx context.Context, call fantasy.ObjectCall) (fantasy.ObjectStreamResponse, error) {
 	// Convert our Schema to OpenAI's JSON Schema format
-	jsonSchemaMap := schema.ToMap(call.Schema)
-
-	// Add additionalProperties: false recursively for strict mode (OpenAI requirement)
-	addAdditionalPropertiesFalse(jsonSchemaMap)
+	// Strict mode requires additionalProperties: false and every property
+	// to be required.
+	jsonSchemaMap, schemaWarnings := call.Schema.Strictify()
 
 	schemaName := call.SchemaName
 	if schemaName == "" {
@@ -1515,13 +1616,14 @@ func (o responsesLanguageModel) streamObjectWithJSONMode(ctx context.Context, ca
 	if err != nil {
 		return nil, err
 	}
+	warnings = append(warnings, strictSchemaWarnings(schemaWarnings)...)
 
 	// Add structured output via Text.Format field
 	params.Text = responses.ResponseTextConfigParam{
 		Format: responses.ResponseFormatTextConfigParamOfJSONSchema(schemaName, jsonSchemaMap),
 	}
 
-	stream := o.client.Responses.NewStreaming(ctx, *params, append(objectCallUARequestOptions(call), objectCallHeadersRequestOptions(call)...)...)
+	stream := o.client.Responses.NewStreaming(ctx, *params, slices.Concat(objectCallUARequestOptions(call), objectCallHeadersRequestOptions(call), callQueryRequestOptions(call.ExtraQuery), callBodyRequestOptions(call.ExtraBody))...)
 
 	return func(yield func(fantasy.ObjectStreamPart) bool) {
 		if len(warnings) > 0 {
@@ -0,0 +1,81 @@
+package openai
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+
+	"charm.land/fantasy"
+)
+
+// contentFilterResult is a single category of the content_filter_results
+// object Azure OpenAI attaches to choices.
+type contentFilterResult struct {
+	Filtered bool   `json:"filtered"`
+	Severity string `json:"severity"`
+	Detected *bool  `json:"detected"`
+}
+
+// mapSafety builds safety information for a chat completion choice from the
+// model refusal, the raw finish reason and, when present, the Azure OpenAI
+// content_filter_results in the raw choice JSON. It returns nil when there
+// is nothing to report.
+func mapSafety(refusal, finishReason, rawChoice string) *fantasy.SafetyInfo {
+	ratings := parseContentFilterResults(rawChoice)
+	if refusal == "" && finishReason != "content_filter" && len(ratings) == 0 {
+		return nil
+	}
+	info := &fantasy.SafetyInfo{
+		Refusal: refusal,
+		Ratings: ratings,
+	}
+	switch {
+	case finishReason == "content_filter":
+		info.Blocked = true
+		info.Reason = finishReason
+	case refusal != "":
+		info.Blocked = true
+		info.Reason = "refusal"
+	}
+	for _, r := range ratings {
+		info.Blocked = info.Blocked || r.Blocked
+	}
+	return info
+}
+
+func parseContentFilterResults(rawChoice string) []fantasy.SafetyRating {
+	if !strings.Contains(rawChoice, "content_filter_results") {
+		return nil
+	}
+	var choice struct {
+		ContentFilterResults map[string]contentFilterResult `json:"content_filter_results"`
+	}
+	if err := json.Unmarshal([]byte(rawChoice), &choice); err != nil {
+		return nil
+	}
+	ratings := make([]fantasy.SafetyRating, 0, len(choice.ContentFilterResults))
+	for category, result := range choice.ContentFilterResults {
+		rating := fantasy.SafetyRating{
+			Category: category,
+			Severity: result.Severity,
+			Blocked:  result.Filtered,
+		}
+		if result.Detected != nil && *result.Detected {
+			rating.Probability = "detected"
+		}
+		ratings = append(ratings, rating)
+	}
+	slices.SortFunc(ratings, func(a, b fantasy.SafetyRating) int {
+		return strings.Compare(a.Category, b.Category)
+	})
+	return ratings
+}
+
+// mapRefusalFinishReason reports a refusal as a content-filter finish,
+// unless the model also requested tool calls.
+func mapRefusalFinishReason(reason fantasy.FinishReason, safety *fantasy.SafetyInfo) fantasy.FinishReason {
+	if safety != nil && safety.Blocked && reason != fantasy.FinishReasonToolCalls {
+		return fantasy.FinishReasonContentFilter
+	}
+	return reason
+}
@@ -0,0 +1,47 @@
+package openai
+
+import (
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMapSafety(t *testing.T) {
+	t.Parallel()
+
+	t.Run("nothing to report", func(t *testing.T) {
+		t.Parallel()
+		require.Nil(t, mapSafety("", "stop", `{"index":0}`))
+	})
+
+	t.Run("refusal", func(t *testing.T) {
+		t.Parallel()
+		safety := mapSafety("I can't help with that.", "stop", "")
+		require.Equal(t, &fantasy.SafetyInfo{
+			Blocked: true,
+			Reason:  "refusal",
+			Refusal: "I can't help with that.",
+		}, safety)
+		require.Equal(t, fantasy.FinishReasonContentFilter, mapRefusalFinishReason(fantasy.FinishReasonStop, safety))
+		require.Equal(t, fantasy.FinishReasonToolCalls, mapRefusalFinishReason(fantasy.FinishReasonToolCalls, safety))
+	})
+
+	t.Run("azure content filter results", func(t *testing.T) {
+		t.Parallel()
+		raw := `{"index":0,"finish_reason":"content_filter","content_filter_results":{
+			"violence":{"filtered":true,"severity":"high"},
+			"hate":{"filtered":false,"severity":"safe"},
+			"jailbreak":{"filtered":false,"detected":true}
+		}}`
+		safety := mapSafety("", "content_filter", raw)
+		require.NotNil(t, safety)
+		require.True(t, safety.Blocked)
+		require.Equal(t, "content_filter", safety.Reason)
+		require.Equal(t, []fantasy.SafetyRating{
+			{Category: "hate", Severity: "safe"},
+			{Category: "jailbreak", Probability: "detected"},
+			{Category: "violence", Severity: "high", Blocked: true},
+		}, safety.Ratings)
+	})
+}
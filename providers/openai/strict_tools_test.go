@@ -0,0 +1,90 @@
+package openai
+
+import (
+	"encoding/json"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/require"
+)
+
+func strictTestTools() []fantasy.Tool {
+	inputSchema := map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"path":  map[string]any{"type": "string"},
+			"limit": map[string]any{"type": "integer"},
+		},
+		"required": []string{"path"},
+	}
+	return []fantasy.Tool{
+		fantasy.FunctionTool{
+			Name:            "delete_file",
+			InputSchema:     inputSchema,
+			ProviderOptions: NewProviderToolOptions(&ProviderToolOptions{Strict: new(true)}),
+		},
+		fantasy.FunctionTool{
+			Name:        "read_file",
+			InputSchema: inputSchema,
+		},
+	}
+}
+
+func TestToOpenAiTools_Strict(t *testing.T) {
+	t.Parallel()
+
+	tools, _, warnings := toOpenAiTools(strictTestTools(), nil)
+	require.Len(t, tools, 2)
+	require.Len(t, warnings, 1, "making limit required should be reported")
+	require.Equal(t, "delete_file", warnings[0].Tool.GetName())
+
+	strict := tools[0].OfFunction.Function
+	require.True(t, strict.Strict.Value)
+	require.Equal(t, false, strict.Parameters["additionalProperties"])
+	require.ElementsMatch(t, []string{"path", "limit"}, strict.Parameters["required"])
+
+	loose := tools[1].OfFunction.Function
+	require.False(t, loose.Strict.Value)
+	require.NotContains(t, loose.Parameters, "additionalProperties")
+}
+
+func TestToResponsesTools_Strict(t *testing.T) {
+	t.Parallel()
+
+	t.Run("per tool", func(t *testing.T) {
+		t.Parallel()
+		tools, _, warnings := toResponsesTools(strictTestTools(), nil, nil)
+		require.Len(t, tools, 2)
+		require.Len(t, warnings, 1)
+		require.True(t, tools[0].OfFunction.Strict.Value)
+		require.Equal(t, false, tools[0].OfFunction.Parameters["additionalProperties"])
+		require.False(t, tools[1].OfFunction.Strict.Value)
+	})
+
+	t.Run("tool overrides StrictJSONSchema", func(t *testing.T) {
+		t.Parallel()
+		tools := strictTestTools()
+		ft := tools[0].(fantasy.FunctionTool)
+		ft.ProviderOptions = NewProviderToolOptions(&ProviderToolOptions{Strict: new(false)})
+		tools[0] = ft
+
+		params, _, _ := toResponsesTools(tools, nil, &ResponsesProviderOptions{StrictJSONSchema: new(true)})
+		require.False(t, params[0].OfFunction.Strict.Value)
+		require.True(t, params[1].OfFunction.Strict.Value)
+	})
+}
+
+func TestProviderToolOptions_JSON(t *testing.T) {
+	t.Parallel()
+
+	data, err := json.Marshal(strictTestTools()[0])
+	require.NoError(t, err)
+
+	decoded, err := fantasy.UnmarshalTool(data)
+	require.NoError(t, err)
+	ft, ok := decoded.(fantasy.FunctionTool)
+	require.True(t, ok)
+	toolOpts, ok := ft.ProviderOptions[Name].(*ProviderToolOptions)
+	require.True(t, ok)
+	require.True(t, *toolOpts.Strict)
+}
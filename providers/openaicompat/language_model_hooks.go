@@ -265,6 +265,9 @@ func ToPromptFunc(prompt fantasy.Prompt, _, _ string) ([]openaisdk.ChatCompletio
 						// Handle image files
 						base64Encoded := base64.StdEncoding.EncodeToString(filePart.Data)
 						data := "data:" + filePart.MediaType + ";base64," + base64Encoded
+						if filePart.URL != "" {
+							data = filePart.URL
+						}
 						imageURL := openaisdk.ChatCompletionContentPartImageImageURLParam{URL: data}
 
 						// Check for provider-specific options like image detail
@@ -333,7 +336,7 @@ func ToPromptFunc(prompt fantasy.Prompt, _, _ string) ([]openaisdk.ChatCompletio
 
 					default:
 						warnings = append(warnings, fantasy.CallWarning{
-							Type:    fantasy.CallWarningTypeOther,
+							Type:    fantasy.CallWarningTypeDroppedContent,
 							Message: fmt.Sprintf("file part media type %s not supported", filePart.MediaType),
 						})
 					}
@@ -341,7 +344,7 @@ func ToPromptFunc(prompt fantasy.Prompt, _, _ string) ([]openaisdk.ChatCompletio
 			}
 			if !hasVisibleCompatUserContent(content) {
 				warnings = append(warnings, fantasy.CallWarning{
-					Type:    fantasy.CallWarningTypeOther,
+					Type:    fantasy.CallWarningTypeDroppedContent,
 					Message: "dropping empty user message (contains neither user-facing content nor tool results)",
 				})
 				continue
@@ -439,7 +442,7 @@ func ToPromptFunc(prompt fantasy.Prompt, _, _ string) ([]openaisdk.ChatCompletio
 			}
 			if !hasVisibleCompatAssistantContent(&assistantMsg) {
 				warnings = append(warnings, fantasy.CallWarning{
-					Type:    fantasy.CallWarningTypeOther,
+					Type:    fantasy.CallWarningTypeDroppedContent,
 					Message: "dropping empty assistant message (contains neither user-facing content nor tool calls)",
 				})
 				continue
@@ -487,6 +490,27 @@ func ToPromptFunc(prompt fantasy.Prompt, _, _ string) ([]openaisdk.ChatCompletio
 						continue
 					}
 					messages = append(messages, openaisdk.ToolMessage(output.Error.Error(), toolResultPart.ToolCallID))
+				case fantasy.ToolResultContentTypeMedia:
+					output, ok := fantasy.AsToolResultOutputType[fantasy.ToolResultOutputContentMedia](toolResultPart.Output)
+					if !ok {
+						warnings = append(warnings, fantasy.CallWarning{
+							Type:    fantasy.CallWarningTypeOther,
+							Message: "tool result output does not have the right type",
+						})
+						continue
+					}
+					// Tool messages only carry text. Answer the tool call with
+					// a placeholder anyway, as an unanswered tool call is
+					// rejected.
+					text := output.Text
+					if text == "" {
+						text = fmt.Sprintf("The tool returned %s content, which can't be shown.", output.MediaType)
+					}
+					warnings = append(warnings, fantasy.CallWarning{
+						Type:    fantasy.CallWarningTypeDroppedContent,
+						Message: fmt.Sprintf("tool result media type %s not supported, sending text only", output.MediaType),
+					})
+					messages = append(messages, openaisdk.ToolMessage(text, toolResultPart.ToolCallID))
 				}
 			}
 		}
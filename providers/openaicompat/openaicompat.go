@@ -5,6 +5,9 @@ import (
 	"charm.land/fantasy"
 	"charm.land/fantasy/providers/openai"
 	"github.com/openai/openai-go/v3/option"
+	"net/http"
+	"net/url"
+	"time"
 )
 
 type options struct {
@@ -91,6 +94,31 @@ func WithHTTPClient(client option.HTTPClient) Option {
 	}
 }
 
+// WithHTTPTimeout sets the overall timeout of each request, including
+// reading streamed responses. Use fantasy.WithStreamIdleTimeout to detect
+// stalled streams instead. It's ignored when WithHTTPClient is set.
+func WithHTTPTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.openaiOptions = append(o.openaiOptions, openai.WithHTTPTimeout(timeout))
+	}
+}
+
+// WithProxy sends the requests through the given proxy instead of the one
+// from the environment. It's ignored when WithHTTPClient is set.
+func WithProxy(proxy *url.URL) Option {
+	return func(o *options) {
+		o.openaiOptions = append(o.openaiOptions, openai.WithProxy(proxy))
+	}
+}
+
+// WithTransport sets the HTTP transport of the OpenAI-compatible provider. It's
+// ignored when WithHTTPClient is set.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *options) {
+		o.openaiOptions = append(o.openaiOptions, openai.WithTransport(transport))
+	}
+}
+
 // WithSDKOptions sets the SDK options for the OpenAI-compatible provider.
 func WithSDKOptions(opts ...option.RequestOption) Option {
 	return func(o *options) {
@@ -98,6 +126,14 @@ func WithSDKOptions(opts ...option.RequestOption) Option {
 	}
 }
 
+// WithResilientStreamParsing makes streams skip malformed chunks and report
+// them as warnings instead of failing the generation.
+func WithResilientStreamParsing() Option {
+	return func(o *options) {
+		o.openaiOptions = append(o.openaiOptions, openai.WithResilientStreamParsing())
+	}
+}
+
 // WithObjectMode sets the object generation mode for the OpenAI-compatible provider.
 // Supported modes: ObjectModeTool, ObjectModeText.
 // ObjectModeAuto and ObjectModeJSON are automatically converted to ObjectModeTool
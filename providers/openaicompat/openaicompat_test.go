@@ -1,7 +1,10 @@
 package openaicompat
 
 import (
+	"encoding/json"
 	"errors"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"charm.land/fantasy"
@@ -291,7 +294,7 @@ func TestToPromptFunc_DropsEmptyMessages(t *testing.T) {
 
 		require.Len(t, messages, 1, "should only have user message")
 		require.Len(t, warnings, 1)
-		require.Equal(t, fantasy.CallWarningTypeOther, warnings[0].Type)
+		require.Equal(t, fantasy.CallWarningTypeDroppedContent, warnings[0].Type)
 		require.Contains(t, warnings[0].Message, "dropping empty assistant message")
 	})
 
@@ -850,3 +853,47 @@ func TestToPromptFunc_ContentExtraFields(t *testing.T) {
 		require.Equal(t, "ephemeral", cacheControl["type"])
 	})
 }
+
+func TestLanguageModel_Citations(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"id":      "chatcmpl-1",
+			"object":  "chat.completion",
+			"created": 1,
+			"model":   "search-model",
+			"choices": []any{map[string]any{
+				"index":         0,
+				"finish_reason": "stop",
+				"message": map[string]any{
+					"role":    "assistant",
+					"content": "Go is fun.",
+					"annotations": []any{map[string]any{
+						"type": "url_citation",
+						"url_citation": map[string]any{
+							"url":         "https://go.dev",
+							"title":       "Go",
+							"start_index": 0,
+							"end_index":   2,
+						},
+					}},
+				},
+			}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := New(WithBaseURL(server.URL), WithAPIKey("key"))
+	require.NoError(t, err)
+	model, err := provider.LanguageModel(t.Context(), "search-model")
+	require.NoError(t, err)
+	resp, err := model.Generate(t.Context(), fantasy.Call{Prompt: fantasy.Prompt{fantasy.NewUserMessage("Is Go fun?")}})
+	require.NoError(t, err)
+
+	sources := resp.Content.Sources()
+	require.Len(t, sources, 1)
+	require.Equal(t, "https://go.dev", sources[0].URL)
+	require.Equal(t, []fantasy.Citation{{StartIndex: 0, EndIndex: 2, Text: "Go"}}, sources[0].Citations)
+}
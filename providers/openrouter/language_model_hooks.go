@@ -14,6 +14,7 @@ import (
 	xstrings "github.com/charmbracelet/x/exp/strings"
 	openaisdk "github.com/openai/openai-go/v3"
 	"github.com/openai/openai-go/v3/packages/param"
+	"github.com/openai/openai-go/v3/packages/respjson"
 )
 
 const reasoningStartedCtx = "reasoning_started"
@@ -444,14 +445,9 @@ func languageModelUsage(response openaisdk.ChatCompletion) (fantasy.Usage, fanta
 	completionTokenDetails := usage.CompletionTokensDetails
 	promptTokenDetails := usage.PromptTokensDetails
 
-	var provider string
-	if p, ok := response.JSON.ExtraFields["provider"]; ok {
-		provider = p.Raw()
-	}
-
 	// Build provider metadata
 	providerMetadata := &ProviderMetadata{
-		Provider: provider,
+		Provider: upstreamProvider(response.JSON.ExtraFields),
 		Usage:    openrouterUsage,
 	}
 
@@ -467,6 +463,20 @@ func languageModelUsage(response openaisdk.ChatCompletion) (fantasy.Usage, fanta
 	}, providerMetadata
 }
 
+// upstreamProvider returns the name of the provider OpenRouter routed the
+// request to, e.g. "Anthropic".
+func upstreamProvider(fields map[string]respjson.Field) string {
+	field, ok := fields["provider"]
+	if !ok {
+		return ""
+	}
+	var provider string
+	if err := json.Unmarshal([]byte(field.Raw()), &provider); err != nil {
+		return ""
+	}
+	return provider
+}
+
 func languageModelStreamUsage(chunk openaisdk.ChatCompletionChunk, _ map[string]any, metadata fantasy.ProviderMetadata) (fantasy.Usage, fantasy.ProviderMetadata) {
 	usage := chunk.Usage
 	if usage.TotalTokens == 0 {
@@ -486,8 +496,8 @@ func languageModelStreamUsage(chunk openaisdk.ChatCompletionChunk, _ map[string]
 	_ = json.Unmarshal([]byte(usage.RawJSON()), &openrouterUsage)
 	streamProviderMetadata.Usage = openrouterUsage
 
-	if p, ok := chunk.JSON.ExtraFields["provider"]; ok {
-		streamProviderMetadata.Provider = p.Raw()
+	if provider := upstreamProvider(chunk.JSON.ExtraFields); provider != "" {
+		streamProviderMetadata.Provider = provider
 	}
 
 	// we do this here because the acc does not add prompt details
@@ -628,6 +638,9 @@ func languageModelToPrompt(prompt fantasy.Prompt, _, model string) ([]openaisdk.
 						// Handle image files
 						base64Encoded := base64.StdEncoding.EncodeToString(filePart.Data)
 						data := "data:" + filePart.MediaType + ";base64," + base64Encoded
+						if filePart.URL != "" {
+							data = filePart.URL
+						}
 						imageURL := openaisdk.ChatCompletionContentPartImageImageURLParam{URL: data}
 
 						// Check for provider-specific options like image detail
@@ -732,7 +745,7 @@ func languageModelToPrompt(prompt fantasy.Prompt, _, model string) ([]openaisdk.
 
 					default:
 						warnings = append(warnings, fantasy.CallWarning{
-							Type:    fantasy.CallWarningTypeOther,
+							Type:    fantasy.CallWarningTypeDroppedContent,
 							Message: fmt.Sprintf("file part media type %s not supported", filePart.MediaType),
 						})
 					}
@@ -1055,6 +1068,35 @@ func languageModelToPrompt(prompt fantasy.Prompt, _, model string) ([]openaisdk.
 						})
 					}
 					messages = append(messages, tr)
+				case fantasy.ToolResultContentTypeMedia:
+					output, ok := fantasy.AsToolResultOutputType[fantasy.ToolResultOutputContentMedia](toolResultPart.Output)
+					if !ok {
+						warnings = append(warnings, fantasy.CallWarning{
+							Type:    fantasy.CallWarningTypeOther,
+							Message: "tool result output does not have the right type",
+						})
+						continue
+					}
+					// Tool messages only carry text. Answer the tool call with
+					// a placeholder anyway, as an unanswered tool call is
+					// rejected.
+					text := output.Text
+					if text == "" {
+						text = fmt.Sprintf("The tool returned %s content, which can't be shown.", output.MediaType)
+					}
+					warnings = append(warnings, fantasy.CallWarning{
+						Type:    fantasy.CallWarningTypeDroppedContent,
+						Message: fmt.Sprintf("tool result media type %s not supported, sending text only", output.MediaType),
+					})
+					tr := openaisdk.ToolMessage(text, toolResultPart.ToolCallID)
+					if cacheControl != nil {
+						tr.SetExtraFields(map[string]any{
+							"cache_control": map[string]string{
+								"type": cacheControl.Type,
+							},
+						})
+					}
+					messages = append(messages, tr)
 				}
 			}
 		}
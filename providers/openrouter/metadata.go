@@ -0,0 +1,24 @@
+package openrouter
+
+import "charm.land/fantasy"
+
+// Metadata is the provider metadata OpenRouter sets, by type. MetadataFrom sets
+// the field of the type it finds and leaves the others nil.
+type Metadata struct {
+	// Response is set on responses and their finish stream parts, with the
+	// upstream provider and usage accounting.
+	Response *ProviderMetadata
+}
+
+// MetadataFrom returns the OpenRouter metadata of a response, content or
+// stream part, and whether there is any. Reasoning content carries the
+// metadata of the provider the model is from instead, read with
+// openai.MetadataFrom, anthropic.MetadataFrom or google.MetadataFrom.
+func MetadataFrom(meta fantasy.ProviderMetadata) (Metadata, bool) {
+	var m Metadata
+	var ok bool
+	if m.Response, ok = fantasy.ProviderMetadataOf[*ProviderMetadata](meta, Name); ok {
+		return m, true
+	}
+	return Metadata{}, false
+}
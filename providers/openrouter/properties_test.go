@@ -0,0 +1,34 @@
+package openrouter
+
+import (
+	"testing"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/fantasytest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLanguageModelToPrompt_Properties(t *testing.T) {
+	t.Parallel()
+
+	fantasytest.ForAll(t, 200, func(t *testing.T, call fantasy.Call) {
+		messages, _ := languageModelToPrompt(call.Prompt, Name, "model")
+		fantasytest.RequireTextsPreserved(t, call.Prompt, messages)
+		fantasytest.RequireToolCallIDsPreserved(t, call.Prompt, messages)
+
+		// The tool messages answering an assistant's tool calls follow it
+		// immediately.
+		for i, msg := range messages {
+			if msg.OfAssistant == nil {
+				continue
+			}
+			for j, toolCall := range msg.OfAssistant.ToolCalls {
+				id := toolCall.OfFunction.ID
+				require.Less(t, i+1+j, len(messages), "tool call %s is not answered", id)
+				next := messages[i+1+j]
+				require.NotNil(t, next.OfTool, "tool call %s is not answered by message %d", id, i+1+j)
+				require.Equal(t, id, next.OfTool.ToolCallID)
+			}
+		}
+	})
+}
@@ -79,6 +79,8 @@ type UsageAccounting struct {
 
 // ProviderMetadata represents metadata from OpenRouter provider.
 type ProviderMetadata struct {
+	// Provider is the upstream provider that served the request, e.g.
+	// "Anthropic".
 	Provider string          `json:"provider"`
 	Usage    UsageAccounting `json:"usage"`
 }
@@ -115,6 +117,55 @@ type ReasoningOptions struct {
 	Effort *ReasoningEffort `json:"effort,omitempty"`
 }
 
+// DataCollection controls whether OpenRouter may route to providers that
+// store request data.
+type DataCollection = string
+
+const (
+	// DataCollectionAllow allows providers that may store data.
+	DataCollectionAllow DataCollection = "allow"
+	// DataCollectionDeny only uses providers that don't store data.
+	DataCollectionDeny DataCollection = "deny"
+)
+
+// ProviderSort is the attribute OpenRouter sorts candidate providers by.
+type ProviderSort = string
+
+const (
+	// ProviderSortPrice prefers the cheapest provider.
+	ProviderSortPrice ProviderSort = "price"
+	// ProviderSortThroughput prefers the provider with the highest throughput.
+	ProviderSortThroughput ProviderSort = "throughput"
+	// ProviderSortLatency prefers the provider with the lowest latency.
+	ProviderSortLatency ProviderSort = "latency"
+)
+
+// Quantization is a model quantization level OpenRouter can filter
+// providers by.
+type Quantization = string
+
+// Quantization levels supported by OpenRouter.
+const (
+	QuantizationInt4    Quantization = "int4"
+	QuantizationInt8    Quantization = "int8"
+	QuantizationFP4     Quantization = "fp4"
+	QuantizationFP6     Quantization = "fp6"
+	QuantizationFP8     Quantization = "fp8"
+	QuantizationFP16    Quantization = "fp16"
+	QuantizationBF16    Quantization = "bf16"
+	QuantizationFP32    Quantization = "fp32"
+	QuantizationUnknown Quantization = "unknown"
+)
+
+// MaxPrice is the maximum price, in USD per million tokens (or per request
+// or image), a provider may charge to be routed to.
+type MaxPrice struct {
+	Prompt     *float64 `json:"prompt,omitempty"`
+	Completion *float64 `json:"completion,omitempty"`
+	Request    *float64 `json:"request,omitempty"`
+	Image      *float64 `json:"image,omitempty"`
+}
+
 // Provider represents provider routing preferences for OpenRouter.
 type Provider struct {
 	// List of provider slugs to try in order (e.g. ["anthropic", "openai"])
@@ -124,15 +175,17 @@ type Provider struct {
 	// Only use providers that support all parameters in your request (default: false)
 	RequireParameters *bool `json:"require_parameters,omitempty"`
 	// Control whether to use providers that may store data: "allow" | "deny"
-	DataCollection *string `json:"data_collection,omitempty"`
+	DataCollection *DataCollection `json:"data_collection,omitempty"`
 	// List of provider slugs to allow for this request
 	Only []string `json:"only,omitempty"`
 	// List of provider slugs to skip for this request
 	Ignore []string `json:"ignore,omitempty"`
 	// List of quantization levels to filter by (e.g. ["int4", "int8"])
-	Quantizations []string `json:"quantizations,omitempty"`
+	Quantizations []Quantization `json:"quantizations,omitempty"`
 	// Sort providers by "price" | "throughput" | "latency"
-	Sort *string `json:"sort,omitempty"`
+	Sort *ProviderSort `json:"sort,omitempty"`
+	// Maximum price to pay for this request
+	MaxPrice *MaxPrice `json:"max_price,omitempty"`
 }
 
 // ProviderOptions represents additional options for OpenRouter provider.
@@ -0,0 +1,66 @@
+package openrouter
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/openai"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderRouting(t *testing.T) {
+	t.Parallel()
+
+	var body map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+
+		response := mockOpenAIResponse()
+		response["provider"] = "Anthropic"
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	defer server.Close()
+
+	p, err := New(WithAPIKey("k"), func(o *options) {
+		o.openaiOptions = append(o.openaiOptions, openai.WithBaseURL(server.URL))
+	})
+	require.NoError(t, err)
+	model, err := p.LanguageModel(t.Context(), "anthropic/claude-sonnet-4")
+	require.NoError(t, err)
+
+	resp, err := model.Generate(t.Context(), fantasy.Call{
+		Prompt: fantasy.Prompt{fantasy.NewUserMessage("Hi")},
+		ProviderOptions: NewProviderOptions(&ProviderOptions{
+			Provider: &Provider{
+				Order:          []string{"anthropic", "amazon-bedrock"},
+				AllowFallbacks: new(false),
+				DataCollection: new(DataCollectionDeny),
+				Quantizations:  []Quantization{QuantizationFP8},
+				Sort:           new(ProviderSortLatency),
+			},
+		}),
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, map[string]any{
+		"order":           []any{"anthropic", "amazon-bedrock"},
+		"allow_fallbacks": false,
+		"data_collection": "deny",
+		"quantizations":   []any{"fp8"},
+		"sort":            "latency",
+	}, body["provider"])
+
+	// The openai chat model stores the usage hook's metadata under its own
+	// name in non-streaming responses.
+	metadata, ok := resp.ProviderMetadata[openai.Name].(*ProviderMetadata)
+	require.True(t, ok)
+	require.Equal(t, "Anthropic", metadata.Provider)
+
+	typed, ok := MetadataFrom(resp.ProviderMetadata)
+	require.True(t, ok)
+	require.Same(t, metadata, typed.Response)
+}
@@ -0,0 +1,144 @@
+package perplexity
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"strings"
+
+	"charm.land/fantasy"
+	openaisdk "github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/packages/respjson"
+)
+
+const sourcesEmittedCtx = "sources_emitted"
+
+type searchResult struct {
+	Title       string `json:"title"`
+	URL         string `json:"url"`
+	Date        string `json:"date"`
+	LastUpdated string `json:"last_updated"`
+	Snippet     string `json:"snippet"`
+}
+
+func languagePrepareModelCall(model fantasy.LanguageModel, params *openaisdk.ChatCompletionNewParams, call fantasy.Call) ([]fantasy.CallWarning, error) {
+	providerOptions := &ProviderOptions{}
+	if v, ok := call.ProviderOptions[model.Provider()]; ok {
+		providerOptions, ok = v.(*ProviderOptions)
+		if !ok {
+			return nil, &fantasy.Error{Title: "invalid argument", Message: "perplexity provider options should be *perplexity.ProviderOptions"}
+		}
+	}
+
+	type plain ProviderOptions
+	data, err := json.Marshal(plain(*providerOptions))
+	if err != nil {
+		return nil, err
+	}
+	var extraFields map[string]any
+	if err := json.Unmarshal(data, &extraFields); err != nil {
+		return nil, err
+	}
+	delete(extraFields, "extra_body")
+
+	maps.Copy(extraFields, providerOptions.ExtraBody)
+	if len(extraFields) > 0 {
+		params.SetExtraFields(extraFields)
+	}
+	return nil, nil
+}
+
+// sources maps the top-level search_results field to sources, falling
+// back to the plain citations URL list returned by older models.
+func sources(fields map[string]respjson.Field) []fantasy.SourceContent {
+	var results []searchResult
+	if field, ok := fields["search_results"]; ok {
+		_ = json.Unmarshal([]byte(field.Raw()), &results)
+	}
+	if len(results) == 0 {
+		if field, ok := fields["citations"]; ok {
+			var urls []string
+			_ = json.Unmarshal([]byte(field.Raw()), &urls)
+			for _, url := range urls {
+				results = append(results, searchResult{URL: url})
+			}
+		}
+	}
+
+	sources := make([]fantasy.SourceContent, 0, len(results))
+	for _, result := range results {
+		source := fantasy.SourceContent{
+			SourceType: fantasy.SourceTypeURL,
+			ID:         fantasy.NewID(),
+			URL:        result.URL,
+			Title:      result.Title,
+		}
+		if result.Date != "" || result.LastUpdated != "" || result.Snippet != "" {
+			source.ProviderMetadata = fantasy.ProviderMetadata{
+				Name: &SourceMetadata{
+					Date:        result.Date,
+					LastUpdated: result.LastUpdated,
+					Snippet:     result.Snippet,
+				},
+			}
+		}
+		sources = append(sources, source)
+	}
+	return sources
+}
+
+func languageModelResponseContent(response openaisdk.ChatCompletion) []fantasy.Content {
+	var text string
+	if len(response.Choices) > 0 {
+		text = response.Choices[0].Message.Content
+	}
+	var content []fantasy.Content
+	for i, source := range sources(response.JSON.ExtraFields) {
+		source.Citations = markerCitations(text, i+1)
+		content = append(content, source)
+	}
+	return content
+}
+
+// markerCitations returns the positions of the markers citing the n-th
+// source in text, such as "[1]" for the first one.
+func markerCitations(text string, n int) []fantasy.Citation {
+	marker := fmt.Sprintf("[%d]", n)
+	var citations []fantasy.Citation
+	for offset := 0; ; {
+		i := strings.Index(text[offset:], marker)
+		if i < 0 {
+			return citations
+		}
+		offset += i
+		citations = append(citations, fantasy.Citation{StartIndex: offset, EndIndex: offset})
+		offset += len(marker)
+	}
+}
+
+// languageModelStreamExtra emits the search results once. Perplexity
+// repeats them on every chunk. They come before the text citing them, so
+// they have no citations.
+func languageModelStreamExtra(chunk openaisdk.ChatCompletionChunk, yield func(fantasy.StreamPart) bool, ctx map[string]any) (map[string]any, bool) {
+	if emitted, _ := ctx[sourcesEmittedCtx].(bool); emitted {
+		return ctx, true
+	}
+	found := sources(chunk.JSON.ExtraFields)
+	if len(found) == 0 {
+		return ctx, true
+	}
+	ctx[sourcesEmittedCtx] = true
+	for _, source := range found {
+		if !yield(fantasy.StreamPart{
+			Type:             fantasy.StreamPartTypeSource,
+			ID:               source.ID,
+			SourceType:       source.SourceType,
+			URL:              source.URL,
+			Title:            source.Title,
+			ProviderMetadata: source.ProviderMetadata,
+		}) {
+			return ctx, false
+		}
+	}
+	return ctx, true
+}
@@ -0,0 +1,23 @@
+package perplexity
+
+import "charm.land/fantasy"
+
+// Metadata is the provider metadata Perplexity sets, by type. MetadataFrom
+// sets the field of the type it finds and leaves the others nil.
+type Metadata struct {
+	// Source is set on the sources of search results, with their dates and
+	// snippet.
+	Source *SourceMetadata
+}
+
+// MetadataFrom returns the Perplexity metadata of a response, content or
+// stream part, and whether there is any. Responses also carry the metadata
+// of the openai package, read with openai.MetadataFrom.
+func MetadataFrom(meta fantasy.ProviderMetadata) (Metadata, bool) {
+	var m Metadata
+	var ok bool
+	if m.Source, ok = fantasy.ProviderMetadataOf[*SourceMetadata](meta, Name); ok {
+		return m, true
+	}
+	return Metadata{}, false
+}
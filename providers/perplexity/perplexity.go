@@ -0,0 +1,156 @@
+// Package perplexity provides an implementation of the fantasy AI SDK for
+// Perplexity's Sonar models.
+package perplexity
+
+import (
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/openai"
+	"github.com/openai/openai-go/v3/option"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+type options struct {
+	openaiOptions        []openai.Option
+	languageModelOptions []openai.LanguageModelOption
+	sdkOptions           []option.RequestOption
+	objectMode           fantasy.ObjectMode
+}
+
+const (
+	// DefaultURL is the default URL for the Perplexity API.
+	DefaultURL = "https://api.perplexity.ai"
+	// Name is the name of the Perplexity provider.
+	Name = "perplexity"
+)
+
+// Option defines a function that configures Perplexity provider options.
+type Option = func(*options)
+
+// New creates a new Perplexity provider with the given options. Search
+// results are returned as SourceContent.
+func New(opts ...Option) (fantasy.Provider, error) {
+	providerOptions := options{
+		openaiOptions: []openai.Option{
+			openai.WithName(Name),
+			openai.WithBaseURL(DefaultURL),
+		},
+		languageModelOptions: []openai.LanguageModelOption{
+			openai.WithLanguageModelPrepareCallFunc(languagePrepareModelCall),
+			openai.WithLanguageModelResponseContentFunc(languageModelResponseContent),
+			openai.WithLanguageModelStreamExtraFunc(languageModelStreamExtra),
+		},
+		objectMode: fantasy.ObjectModeAuto,
+	}
+	for _, o := range opts {
+		o(&providerOptions)
+	}
+
+	// Handle object mode: convert unsupported modes to auto
+	// Perplexity has no tool calling but supports json_schema response formats
+	objectMode := providerOptions.objectMode
+	if objectMode == fantasy.ObjectModeTool {
+		objectMode = fantasy.ObjectModeAuto
+	}
+
+	providerOptions.openaiOptions = append(
+		providerOptions.openaiOptions,
+		openai.WithSDKOptions(providerOptions.sdkOptions...),
+		openai.WithLanguageModelOptions(providerOptions.languageModelOptions...),
+		openai.WithObjectMode(objectMode),
+	)
+	return openai.New(providerOptions.openaiOptions...)
+}
+
+// WithBaseURL sets the base URL for the Perplexity provider.
+func WithBaseURL(url string) Option {
+	return func(o *options) {
+		o.openaiOptions = append(o.openaiOptions, openai.WithBaseURL(url))
+	}
+}
+
+// WithAPIKey sets the API key for the Perplexity provider.
+func WithAPIKey(apiKey string) Option {
+	return func(o *options) {
+		o.openaiOptions = append(o.openaiOptions, openai.WithAPIKey(apiKey))
+	}
+}
+
+// WithName sets the name for the Perplexity provider.
+func WithName(name string) Option {
+	return func(o *options) {
+		o.openaiOptions = append(o.openaiOptions, openai.WithName(name))
+	}
+}
+
+// WithHeaders sets the headers for the Perplexity provider.
+func WithHeaders(headers map[string]string) Option {
+	return func(o *options) {
+		o.openaiOptions = append(o.openaiOptions, openai.WithHeaders(headers))
+	}
+}
+
+// WithHTTPClient sets the HTTP client for the Perplexity provider.
+func WithHTTPClient(client option.HTTPClient) Option {
+	return func(o *options) {
+		o.openaiOptions = append(o.openaiOptions, openai.WithHTTPClient(client))
+	}
+}
+
+// WithHTTPTimeout sets the overall timeout of each request, including
+// reading streamed responses. Use fantasy.WithStreamIdleTimeout to detect
+// stalled streams instead. It's ignored when WithHTTPClient is set.
+func WithHTTPTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.openaiOptions = append(o.openaiOptions, openai.WithHTTPTimeout(timeout))
+	}
+}
+
+// WithProxy sends the requests through the given proxy instead of the one
+// from the environment. It's ignored when WithHTTPClient is set.
+func WithProxy(proxy *url.URL) Option {
+	return func(o *options) {
+		o.openaiOptions = append(o.openaiOptions, openai.WithProxy(proxy))
+	}
+}
+
+// WithTransport sets the HTTP transport of the Perplexity provider. It's
+// ignored when WithHTTPClient is set.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *options) {
+		o.openaiOptions = append(o.openaiOptions, openai.WithTransport(transport))
+	}
+}
+
+// WithSDKOptions sets the SDK options for the Perplexity provider.
+func WithSDKOptions(opts ...option.RequestOption) Option {
+	return func(o *options) {
+		o.sdkOptions = append(o.sdkOptions, opts...)
+	}
+}
+
+// WithObjectMode sets the object generation mode for the Perplexity provider.
+// Supported modes: ObjectModeAuto, ObjectModeText.
+// ObjectModeTool is converted to ObjectModeAuto since Perplexity doesn't
+// support tool calling.
+func WithObjectMode(om fantasy.ObjectMode) Option {
+	return func(o *options) {
+		o.objectMode = om
+	}
+}
+
+// WithUserAgent sets an explicit User-Agent header, overriding the default and any
+// value set via WithHeaders.
+func WithUserAgent(ua string) Option {
+	return func(o *options) {
+		o.openaiOptions = append(o.openaiOptions, openai.WithUserAgent(ua))
+	}
+}
+
+// WithLanguageModelOptions appends language model options to the provider.
+func WithLanguageModelOptions(opts ...openai.LanguageModelOption) Option {
+	return func(o *options) {
+		o.languageModelOptions = append(o.languageModelOptions, opts...)
+	}
+}
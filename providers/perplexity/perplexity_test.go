@@ -0,0 +1,125 @@
+package perplexity
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/require"
+)
+
+var testSearchResults = []any{
+	map[string]any{"title": "Go 1.26 Release Notes", "url": "https://go.dev/doc/go1.26", "date": "2026-02-10"},
+	map[string]any{"title": "Go Blog", "url": "https://go.dev/blog"},
+}
+
+func TestLanguageModel_Generate(t *testing.T) {
+	t.Parallel()
+
+	calls := make(chan map[string]any, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		calls <- body
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"id":             "chatcmpl-1",
+			"object":         "chat.completion",
+			"created":        1,
+			"model":          "sonar",
+			"citations":      []any{"https://go.dev/doc/go1.26", "https://go.dev/blog"},
+			"search_results": testSearchResults,
+			"choices": []any{map[string]any{
+				"index":         0,
+				"finish_reason": "stop",
+				"message":       map[string]any{"role": "assistant", "content": "Go 1.26 was released in February [1]."},
+			}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := New(WithBaseURL(server.URL))
+	require.NoError(t, err)
+	model, err := provider.LanguageModel(t.Context(), "sonar")
+	require.NoError(t, err)
+
+	resp, err := model.Generate(t.Context(), fantasy.Call{
+		Prompt: fantasy.Prompt{fantasy.NewUserMessage("When was Go 1.26 released?")},
+		ProviderOptions: NewProviderOptions(&ProviderOptions{
+			SearchDomainFilter:  []string{"go.dev", "-reddit.com"},
+			SearchRecencyFilter: SearchRecencyMonth,
+			WebSearchOptions:    &WebSearchOptions{SearchContextSize: SearchContextSizeHigh},
+		}),
+	})
+	require.NoError(t, err)
+
+	body := <-calls
+	require.Equal(t, []any{"go.dev", "-reddit.com"}, body["search_domain_filter"])
+	require.Equal(t, "month", body["search_recency_filter"])
+	require.Equal(t, map[string]any{"search_context_size": "high"}, body["web_search_options"])
+	require.NotContains(t, body, "return_images")
+
+	sources := resp.Content.Sources()
+	require.Len(t, sources, 2)
+	require.Equal(t, "https://go.dev/doc/go1.26", sources[0].URL)
+	require.Equal(t, "Go 1.26 Release Notes", sources[0].Title)
+	require.Equal(t, &SourceMetadata{Date: "2026-02-10"}, sources[0].ProviderMetadata[Name])
+	require.Nil(t, sources[1].ProviderMetadata)
+	require.Equal(t, []fantasy.Citation{{StartIndex: 33, EndIndex: 33}}, sources[0].Citations)
+	require.Empty(t, sources[1].Citations)
+}
+
+func TestLanguageModel_StreamSources(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i, delta := range []string{"Go 1.26 ", "was released."} {
+			chunk := map[string]any{
+				"id":      "chatcmpl-1",
+				"object":  "chat.completion.chunk",
+				"created": 1,
+				"model":   "sonar",
+				"citations": []any{
+					"https://go.dev/doc/go1.26",
+				},
+				"choices": []any{map[string]any{
+					"index": 0,
+					"delta": map[string]any{"role": "assistant", "content": delta},
+				}},
+			}
+			if i == 1 {
+				chunk["choices"].([]any)[0].(map[string]any)["finish_reason"] = "stop"
+			}
+			data, _ := json.Marshal(chunk)
+			_, _ = fmt.Fprintf(w, "data: %s\n\n", data)
+		}
+		_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	t.Cleanup(server.Close)
+
+	provider, err := New(WithBaseURL(server.URL))
+	require.NoError(t, err)
+	model, err := provider.LanguageModel(t.Context(), "sonar")
+	require.NoError(t, err)
+
+	stream, err := model.Stream(t.Context(), fantasy.Call{
+		Prompt: fantasy.Prompt{fantasy.NewUserMessage("When was Go 1.26 released?")},
+	})
+	require.NoError(t, err)
+
+	var sources []fantasy.StreamPart
+	for part := range stream {
+		require.NotEqual(t, fantasy.StreamPartTypeError, part.Type, part.Error)
+		if part.Type == fantasy.StreamPartTypeSource {
+			sources = append(sources, part)
+		}
+	}
+	require.Len(t, sources, 1)
+	require.Equal(t, "https://go.dev/doc/go1.26", sources[0].URL)
+	require.Equal(t, fantasy.SourceTypeURL, sources[0].SourceType)
+}
@@ -0,0 +1,149 @@
+package perplexity
+
+import (
+	"encoding/json"
+
+	"charm.land/fantasy"
+)
+
+// Global type identifiers for Perplexity-specific provider data.
+const (
+	TypeProviderOptions = Name + ".options"
+	TypeSourceMetadata  = Name + ".source_metadata"
+)
+
+// Register Perplexity provider-specific types with the global registry.
+func init() {
+	fantasy.RegisterProviderType(TypeProviderOptions, func(data []byte) (fantasy.ProviderOptionsData, error) {
+		var v ProviderOptions
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		return &v, nil
+	})
+	fantasy.RegisterProviderType(TypeSourceMetadata, func(data []byte) (fantasy.ProviderOptionsData, error) {
+		var v SourceMetadata
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		return &v, nil
+	})
+}
+
+// SearchRecencyFilter restricts search results to a publication window.
+type SearchRecencyFilter = string
+
+// Search recency filters supported by Perplexity.
+const (
+	SearchRecencyHour  SearchRecencyFilter = "hour"
+	SearchRecencyDay   SearchRecencyFilter = "day"
+	SearchRecencyWeek  SearchRecencyFilter = "week"
+	SearchRecencyMonth SearchRecencyFilter = "month"
+	SearchRecencyYear  SearchRecencyFilter = "year"
+)
+
+// SearchMode selects the index searched by the model.
+type SearchMode = string
+
+// Search modes supported by Perplexity.
+const (
+	SearchModeWeb      SearchMode = "web"
+	SearchModeAcademic SearchMode = "academic"
+	SearchModeSEC      SearchMode = "sec"
+)
+
+// SearchContextSize controls how much search context is retrieved.
+type SearchContextSize = string
+
+// Search context sizes supported by Perplexity.
+const (
+	SearchContextSizeLow    SearchContextSize = "low"
+	SearchContextSizeMedium SearchContextSize = "medium"
+	SearchContextSizeHigh   SearchContextSize = "high"
+)
+
+// WebSearchOptions configures the web search.
+type WebSearchOptions struct {
+	SearchContextSize SearchContextSize `json:"search_context_size,omitempty"`
+}
+
+// ProviderOptions represents additional options for the Perplexity provider.
+type ProviderOptions struct {
+	// SearchDomainFilter limits search to the given domains. Prefix a
+	// domain with "-" to exclude it instead.
+	SearchDomainFilter  []string            `json:"search_domain_filter,omitempty"`
+	SearchRecencyFilter SearchRecencyFilter `json:"search_recency_filter,omitempty"`
+	SearchMode          SearchMode          `json:"search_mode,omitempty"`
+	// SearchAfterDateFilter and SearchBeforeDateFilter limit results by
+	// publication date, formatted as "%m/%d/%Y".
+	SearchAfterDateFilter  string            `json:"search_after_date_filter,omitempty"`
+	SearchBeforeDateFilter string            `json:"search_before_date_filter,omitempty"`
+	WebSearchOptions       *WebSearchOptions `json:"web_search_options,omitempty"`
+	ReturnImages           *bool             `json:"return_images,omitempty"`
+	ReturnRelatedQuestions *bool             `json:"return_related_questions,omitempty"`
+
+	ExtraBody map[string]any `json:"extra_body,omitempty"`
+}
+
+// Options implements the ProviderOptions interface.
+func (*ProviderOptions) Options() {}
+
+// MarshalJSON implements custom JSON marshaling with type info for ProviderOptions.
+func (o ProviderOptions) MarshalJSON() ([]byte, error) {
+	type plain ProviderOptions
+	return fantasy.MarshalProviderType(TypeProviderOptions, plain(o))
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling with type info for ProviderOptions.
+func (o *ProviderOptions) UnmarshalJSON(data []byte) error {
+	type plain ProviderOptions
+	var p plain
+	if err := fantasy.UnmarshalProviderType(data, &p); err != nil {
+		return err
+	}
+	*o = ProviderOptions(p)
+	return nil
+}
+
+// SourceMetadata holds the Perplexity-specific fields of a search result.
+type SourceMetadata struct {
+	Date        string `json:"date,omitempty"`
+	LastUpdated string `json:"last_updated,omitempty"`
+	Snippet     string `json:"snippet,omitempty"`
+}
+
+// Options implements the ProviderOptions interface.
+func (*SourceMetadata) Options() {}
+
+// MarshalJSON implements custom JSON marshaling with type info for SourceMetadata.
+func (m SourceMetadata) MarshalJSON() ([]byte, error) {
+	type plain SourceMetadata
+	return fantasy.MarshalProviderType(TypeSourceMetadata, plain(m))
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling with type info for SourceMetadata.
+func (m *SourceMetadata) UnmarshalJSON(data []byte) error {
+	type plain SourceMetadata
+	var p plain
+	if err := fantasy.UnmarshalProviderType(data, &p); err != nil {
+		return err
+	}
+	*m = SourceMetadata(p)
+	return nil
+}
+
+// NewProviderOptions creates new provider options for the Perplexity provider.
+func NewProviderOptions(opts *ProviderOptions) fantasy.ProviderOptions {
+	return fantasy.ProviderOptions{
+		Name: opts,
+	}
+}
+
+// ParseOptions parses provider options from a map for the Perplexity provider.
+func ParseOptions(data map[string]any) (*ProviderOptions, error) {
+	var options ProviderOptions
+	if err := fantasy.ParseOptions(data, &options); err != nil {
+		return nil, err
+	}
+	return &options, nil
+}
@@ -0,0 +1,57 @@
+package together
+
+import (
+	"encoding/json"
+	"maps"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/openai"
+	openaisdk "github.com/openai/openai-go/v3"
+)
+
+func languagePrepareModelCall(model fantasy.LanguageModel, params *openaisdk.ChatCompletionNewParams, call fantasy.Call) ([]fantasy.CallWarning, error) {
+	providerOptions := &ProviderOptions{}
+	if v, ok := call.ProviderOptions[model.Provider()]; ok {
+		providerOptions, ok = v.(*ProviderOptions)
+		if !ok {
+			return nil, &fantasy.Error{Title: "invalid argument", Message: "together provider options should be *together.ProviderOptions"}
+		}
+	}
+
+	type plain ProviderOptions
+	data, err := json.Marshal(plain(*providerOptions))
+	if err != nil {
+		return nil, err
+	}
+	var extraFields map[string]any
+	if err := json.Unmarshal(data, &extraFields); err != nil {
+		return nil, err
+	}
+	delete(extraFields, "extra_body")
+
+	maps.Copy(extraFields, providerOptions.ExtraBody)
+	if len(extraFields) > 0 {
+		params.SetExtraFields(extraFields)
+	}
+	return nil, nil
+}
+
+// languageModelUsage reads the usage and replaces the OpenAI logprobs with
+// Together's token/token_logprobs format and the echoed prompt.
+func languageModelUsage(response openaisdk.ChatCompletion) (fantasy.Usage, fantasy.ProviderOptionsData) {
+	usage, _ := openai.DefaultUsageFunc(response)
+
+	metadata := &ProviderMetadata{}
+	if len(response.Choices) > 0 {
+		if raw := response.Choices[0].JSON.Logprobs.Raw(); raw != "" && raw != "null" {
+			var logprobs Logprobs
+			if json.Unmarshal([]byte(raw), &logprobs) == nil && len(logprobs.Tokens) > 0 {
+				metadata.Logprobs = &logprobs
+			}
+		}
+	}
+	if field, ok := response.JSON.ExtraFields["prompt"]; ok {
+		_ = json.Unmarshal([]byte(field.Raw()), &metadata.Prompt)
+	}
+	return usage, metadata
+}
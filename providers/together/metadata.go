@@ -0,0 +1,22 @@
+package together
+
+import "charm.land/fantasy"
+
+// Metadata is the provider metadata Together AI sets, by type. MetadataFrom
+// sets the field of the type it finds and leaves the others nil.
+type Metadata struct {
+	// Response is set on responses, with log probabilities and the echoed
+	// prompt.
+	Response *ProviderMetadata
+}
+
+// MetadataFrom returns the Together AI metadata of a response, content or
+// stream part, and whether there is any.
+func MetadataFrom(meta fantasy.ProviderMetadata) (Metadata, bool) {
+	var m Metadata
+	var ok bool
+	if m.Response, ok = fantasy.ProviderMetadataOf[*ProviderMetadata](meta, Name); ok {
+		return m, true
+	}
+	return Metadata{}, false
+}
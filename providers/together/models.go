@@ -0,0 +1,33 @@
+package together
+
+import (
+	"context"
+)
+
+// Model describes a model available on Together AI.
+type Model struct {
+	ID            string       `json:"id"`
+	Type          string       `json:"type"`
+	DisplayName   string       `json:"display_name"`
+	Organization  string       `json:"organization"`
+	ContextLength int64        `json:"context_length"`
+	Created       int64        `json:"created"`
+	Pricing       ModelPricing `json:"pricing"`
+}
+
+// ModelPricing is the price of a model in dollars per million tokens.
+type ModelPricing struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// ListModels returns the models available to the account, including
+// embedding, image and moderation models. Filter on Model.Type to find
+// chat models.
+func (p *provider) ListModels(ctx context.Context) ([]Model, error) {
+	var models []Model
+	if err := p.client.Get(ctx, "models", nil, &models); err != nil {
+		return nil, toProviderErr(err)
+	}
+	return models, nil
+}
@@ -0,0 +1,125 @@
+package together
+
+import (
+	"encoding/json"
+
+	"charm.land/fantasy"
+)
+
+// Global type identifiers for Together AI-specific provider data.
+const (
+	TypeProviderOptions  = Name + ".options"
+	TypeProviderMetadata = Name + ".metadata"
+)
+
+// Register Together AI provider-specific types with the global registry.
+func init() {
+	fantasy.RegisterProviderType(TypeProviderOptions, func(data []byte) (fantasy.ProviderOptionsData, error) {
+		var v ProviderOptions
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		return &v, nil
+	})
+	fantasy.RegisterProviderType(TypeProviderMetadata, func(data []byte) (fantasy.ProviderOptionsData, error) {
+		var v ProviderMetadata
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		return &v, nil
+	})
+}
+
+// ProviderOptions represents additional options for the Together AI provider.
+type ProviderOptions struct {
+	// SafetyModel moderates the prompt and response with the given model,
+	// e.g. "meta-llama/Meta-Llama-Guard-3-8B".
+	SafetyModel *string `json:"safety_model,omitempty"`
+	// Echo returns the prompt in the response. The echoed prompt is
+	// available in ProviderMetadata.Prompt.
+	Echo *bool `json:"echo,omitempty"`
+	// LogProbs returns the log probabilities of the top N tokens. They are
+	// available in ProviderMetadata.Logprobs.
+	LogProbs *int64 `json:"logprobs,omitempty"`
+
+	TopK              *int64   `json:"top_k,omitempty"`
+	MinP              *float64 `json:"min_p,omitempty"`
+	RepetitionPenalty *float64 `json:"repetition_penalty,omitempty"`
+
+	ExtraBody map[string]any `json:"extra_body,omitempty"`
+}
+
+// Options implements the ProviderOptions interface.
+func (*ProviderOptions) Options() {}
+
+// MarshalJSON implements custom JSON marshaling with type info for ProviderOptions.
+func (o ProviderOptions) MarshalJSON() ([]byte, error) {
+	type plain ProviderOptions
+	return fantasy.MarshalProviderType(TypeProviderOptions, plain(o))
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling with type info for ProviderOptions.
+func (o *ProviderOptions) UnmarshalJSON(data []byte) error {
+	type plain ProviderOptions
+	var p plain
+	if err := fantasy.UnmarshalProviderType(data, &p); err != nil {
+		return err
+	}
+	*o = ProviderOptions(p)
+	return nil
+}
+
+// Logprobs holds Together's log probabilities, one entry per token.
+type Logprobs struct {
+	Tokens        []string  `json:"tokens"`
+	TokenLogprobs []float64 `json:"token_logprobs"`
+	TokenIDs      []int64   `json:"token_ids,omitempty"`
+}
+
+// EchoedPrompt is a prompt returned when ProviderOptions.Echo is set.
+type EchoedPrompt struct {
+	Text     string    `json:"text"`
+	Logprobs *Logprobs `json:"logprobs,omitempty"`
+}
+
+// ProviderMetadata represents additional metadata from the Together AI provider.
+type ProviderMetadata struct {
+	Logprobs *Logprobs      `json:"logprobs,omitempty"`
+	Prompt   []EchoedPrompt `json:"prompt,omitempty"`
+}
+
+// Options implements the ProviderOptions interface.
+func (*ProviderMetadata) Options() {}
+
+// MarshalJSON implements custom JSON marshaling with type info for ProviderMetadata.
+func (m ProviderMetadata) MarshalJSON() ([]byte, error) {
+	type plain ProviderMetadata
+	return fantasy.MarshalProviderType(TypeProviderMetadata, plain(m))
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling with type info for ProviderMetadata.
+func (m *ProviderMetadata) UnmarshalJSON(data []byte) error {
+	type plain ProviderMetadata
+	var p plain
+	if err := fantasy.UnmarshalProviderType(data, &p); err != nil {
+		return err
+	}
+	*m = ProviderMetadata(p)
+	return nil
+}
+
+// NewProviderOptions creates new provider options for the Together AI provider.
+func NewProviderOptions(opts *ProviderOptions) fantasy.ProviderOptions {
+	return fantasy.ProviderOptions{
+		Name: opts,
+	}
+}
+
+// ParseOptions parses provider options from a map for the Together AI provider.
+func ParseOptions(data map[string]any) (*ProviderOptions, error) {
+	var options ProviderOptions
+	if err := fantasy.ParseOptions(data, &options); err != nil {
+		return nil, err
+	}
+	return &options, nil
+}
@@ -0,0 +1,127 @@
+package together
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/openai"
+	"github.com/stretchr/testify/require"
+)
+
+func newTogetherServer(t *testing.T, responses map[string]any) (*httptest.Server, chan map[string]any) {
+	t.Helper()
+
+	calls := make(chan map[string]any, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]any{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		body["path"] = r.URL.Path
+		calls <- body
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(responses[r.URL.Path])
+	}))
+	t.Cleanup(server.Close)
+	return server, calls
+}
+
+func TestLanguageModel_ProviderOptions(t *testing.T) {
+	t.Parallel()
+
+	server, calls := newTogetherServer(t, map[string]any{
+		"/chat/completions": map[string]any{
+			"id":      "chatcmpl-1",
+			"object":  "chat.completion",
+			"created": 1,
+			"model":   "meta-llama/Llama-3.3-70B-Instruct-Turbo",
+			"prompt": []any{map[string]any{
+				"text": "<|begin_of_text|>Hi",
+			}},
+			"choices": []any{map[string]any{
+				"index":         0,
+				"finish_reason": "stop",
+				"message":       map[string]any{"role": "assistant", "content": "Hello"},
+				"logprobs": map[string]any{
+					"tokens":         []any{"Hello"},
+					"token_logprobs": []any{-0.25},
+					"token_ids":      []any{9906},
+				},
+			}},
+			"usage": map[string]any{"prompt_tokens": 2, "completion_tokens": 1, "total_tokens": 3},
+		},
+	})
+
+	provider, err := New(WithBaseURL(server.URL), WithAPIKey("test"))
+	require.NoError(t, err)
+	model, err := provider.LanguageModel(t.Context(), "meta-llama/Llama-3.3-70B-Instruct-Turbo")
+	require.NoError(t, err)
+
+	resp, err := model.Generate(t.Context(), fantasy.Call{
+		Prompt: fantasy.Prompt{fantasy.NewUserMessage("Hi")},
+		ProviderOptions: NewProviderOptions(&ProviderOptions{
+			SafetyModel: fantasy.Opt("meta-llama/Meta-Llama-Guard-3-8B"),
+			Echo:        fantasy.Opt(true),
+			LogProbs:    fantasy.Opt[int64](1),
+			TopK:        fantasy.Opt[int64](40),
+		}),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Hello", resp.Content.Text())
+
+	body := <-calls
+	require.Equal(t, "/chat/completions", body["path"])
+	require.Equal(t, "meta-llama/Meta-Llama-Guard-3-8B", body["safety_model"])
+	require.Equal(t, true, body["echo"])
+	require.Equal(t, float64(1), body["logprobs"])
+	require.Equal(t, float64(40), body["top_k"])
+
+	// Non-streaming usage metadata is keyed by the openai provider name.
+	metadata, ok := resp.ProviderMetadata[openai.Name].(*ProviderMetadata)
+	require.True(t, ok)
+	require.Equal(t, &Logprobs{
+		Tokens:        []string{"Hello"},
+		TokenLogprobs: []float64{-0.25},
+		TokenIDs:      []int64{9906},
+	}, metadata.Logprobs)
+	require.Equal(t, []EchoedPrompt{{Text: "<|begin_of_text|>Hi"}}, metadata.Prompt)
+}
+
+func TestListModels(t *testing.T) {
+	t.Parallel()
+
+	server, calls := newTogetherServer(t, map[string]any{
+		"/models": []any{
+			map[string]any{
+				"id":             "meta-llama/Llama-3.3-70B-Instruct-Turbo",
+				"type":           "chat",
+				"display_name":   "Llama 3.3 70B Instruct Turbo",
+				"organization":   "Meta",
+				"context_length": 131072,
+				"pricing":        map[string]any{"input": 0.88, "output": 0.88},
+			},
+		},
+	})
+
+	provider, err := New(WithBaseURL(server.URL))
+	require.NoError(t, err)
+
+	lister, ok := provider.(interface {
+		ListModels(context.Context) ([]Model, error)
+	})
+	require.True(t, ok)
+	models, err := lister.ListModels(t.Context())
+	require.NoError(t, err)
+	require.Equal(t, []Model{{
+		ID:            "meta-llama/Llama-3.3-70B-Instruct-Turbo",
+		Type:          "chat",
+		DisplayName:   "Llama 3.3 70B Instruct Turbo",
+		Organization:  "Meta",
+		ContextLength: 131072,
+		Pricing:       ModelPricing{Input: 0.88, Output: 0.88},
+	}}, models)
+	require.Equal(t, "/models", (<-calls)["path"])
+}
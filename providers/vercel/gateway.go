@@ -0,0 +1,120 @@
+package vercel
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"charm.land/fantasy"
+	"github.com/openai/openai-go/v3/packages/respjson"
+)
+
+// gatewayHeaderPrefix is the prefix of the response headers the gateway
+// uses to report request details such as routing and credits.
+const gatewayHeaderPrefix = "X-Vercel-"
+
+// gatewayFile is an image or file the gateway returns alongside the message
+// text, in the "images" or "files" field.
+type gatewayFile struct {
+	MediaType string `json:"media_type"`
+	MimeType  string `json:"mime_type"`
+	Data      string `json:"data"`
+	URL       string `json:"url"`
+	ImageURL  struct {
+		URL string `json:"url"`
+	} `json:"image_url"`
+}
+
+type gatewayFiles struct {
+	Images []gatewayFile `json:"images"`
+	Files  []gatewayFile `json:"files"`
+}
+
+// content decodes the file into FileContent. Only inline data is
+// supported; files referenced by a remote URL are skipped.
+func (f gatewayFile) content() (fantasy.FileContent, bool) {
+	mediaType := f.MediaType
+	if mediaType == "" {
+		mediaType = f.MimeType
+	}
+	data := f.Data
+	if data == "" {
+		url := f.URL
+		if url == "" {
+			url = f.ImageURL.URL
+		}
+		header, payload, ok := strings.Cut(strings.TrimPrefix(url, "data:"), ",")
+		if !ok || !strings.HasPrefix(url, "data:") || !strings.HasSuffix(header, ";base64") {
+			return fantasy.FileContent{}, false
+		}
+		if mediaType == "" {
+			mediaType = strings.TrimSuffix(header, ";base64")
+		}
+		data = payload
+	}
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return fantasy.FileContent{}, false
+	}
+	return fantasy.FileContent{
+		MediaType: mediaType,
+		Data:      decoded,
+	}, true
+}
+
+// fileContent returns the images and files of a gateway message.
+func fileContent(rawMessage string) []fantasy.Content {
+	var files gatewayFiles
+	if err := json.Unmarshal([]byte(rawMessage), &files); err != nil {
+		return nil
+	}
+	var content []fantasy.Content
+	for _, f := range append(files.Images, files.Files...) {
+		if c, ok := f.content(); ok {
+			content = append(content, c)
+		}
+	}
+	return content
+}
+
+// upstreamProvider returns the provider the gateway routed the request to.
+func upstreamProvider(fields map[string]respjson.Field) string {
+	field, ok := fields["provider"]
+	if !ok {
+		return ""
+	}
+	var provider string
+	if err := json.Unmarshal([]byte(field.Raw()), &provider); err != nil {
+		return ""
+	}
+	return provider
+}
+
+// languageModelResponseHeaders records the gateway response headers in the
+// Vercel provider metadata.
+func languageModelResponseHeaders(header http.Header, metadata fantasy.ProviderMetadata) fantasy.ProviderMetadata {
+	headers := map[string]string{}
+	for key, values := range header {
+		if strings.HasPrefix(key, gatewayHeaderPrefix) && len(values) > 0 {
+			headers[strings.ToLower(key)] = values[0]
+		}
+	}
+	if len(headers) == 0 {
+		return metadata
+	}
+
+	if metadata == nil {
+		metadata = fantasy.ProviderMetadata{}
+	}
+	// Non-streaming responses store the usage metadata under the openai
+	// provider name, so look for it under any key.
+	for _, v := range metadata {
+		if m, ok := v.(*ProviderMetadata); ok {
+			m.Headers = headers
+			return metadata
+		}
+	}
+	metadata[Name] = &ProviderMetadata{Headers: headers}
+	return metadata
+}
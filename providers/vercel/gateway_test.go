@@ -0,0 +1,78 @@
+package vercel
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/openai"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate_GatewayFilesAndHeaders(t *testing.T) {
+	t.Parallel()
+
+	png := []byte{0x89, 'P', 'N', 'G'}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Vercel-Id", "iad1::abc")
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"id":       "chatcmpl-test",
+			"object":   "chat.completion",
+			"created":  1711115037,
+			"model":    "google/gemini-2.5-flash-image",
+			"provider": "vertex",
+			"choices": []map[string]any{{
+				"index": 0,
+				"message": map[string]any{
+					"role":    "assistant",
+					"content": "Here is your image",
+					"images": []map[string]any{{
+						"type":      "image_url",
+						"image_url": map[string]any{"url": "data:image/png;base64," + base64.StdEncoding.EncodeToString(png)},
+					}},
+					"files": []map[string]any{{
+						"media_type": "text/plain",
+						"data":       base64.StdEncoding.EncodeToString([]byte("hello")),
+					}},
+				},
+				"finish_reason": "stop",
+			}},
+			"usage": map[string]any{
+				"prompt_tokens":     4,
+				"total_tokens":      6,
+				"completion_tokens": 2,
+			},
+		})
+	}))
+	defer server.Close()
+
+	p, err := New(WithAPIKey("k"), WithBaseURL(server.URL))
+	require.NoError(t, err)
+	model, err := p.LanguageModel(t.Context(), "google/gemini-2.5-flash-image")
+	require.NoError(t, err)
+
+	resp, err := model.Generate(t.Context(), fantasy.Call{
+		Prompt: fantasy.Prompt{fantasy.NewUserMessage("Draw a cat")},
+	})
+	require.NoError(t, err)
+
+	files := resp.Content.Files()
+	require.Len(t, files, 2)
+	require.Equal(t, "image/png", files[0].MediaType)
+	require.Equal(t, png, files[0].Data)
+	require.Equal(t, "text/plain", files[1].MediaType)
+	require.Equal(t, []byte("hello"), files[1].Data)
+
+	metadata, ok := resp.ProviderMetadata[openai.Name].(*ProviderMetadata)
+	require.True(t, ok)
+	require.Equal(t, "vertex", metadata.Provider)
+	require.Equal(t, "iad1::abc", metadata.Headers["x-vercel-id"])
+
+	typed, ok := MetadataFrom(resp.ProviderMetadata)
+	require.True(t, ok)
+	require.Same(t, metadata, typed.Response)
+}
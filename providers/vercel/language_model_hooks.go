@@ -100,6 +100,11 @@ func languagePrepareModelCall(_ fantasy.LanguageModel, params *openaisdk.ChatCom
 }
 
 func languageModelExtraContent(choice openaisdk.ChatCompletionChoice) []fantasy.Content {
+	content := reasoningContent(choice)
+	return append(content, fileContent(choice.Message.RawJSON())...)
+}
+
+func reasoningContent(choice openaisdk.ChatCompletionChoice) []fantasy.Content {
 	content := make([]fantasy.Content, 0)
 	reasoningData := ReasoningData{}
 	err := json.Unmarshal([]byte(choice.Message.RawJSON()), &reasoningData)
@@ -494,13 +499,8 @@ func languageModelUsage(response openaisdk.ChatCompletion) (fantasy.Usage, fanta
 	completionTokenDetails := usage.CompletionTokensDetails
 	promptTokenDetails := usage.PromptTokensDetails
 
-	var provider string
-	if p, ok := response.JSON.ExtraFields["provider"]; ok {
-		provider = p.Raw()
-	}
-
 	providerMetadata := &ProviderMetadata{
-		Provider: provider,
+		Provider: upstreamProvider(response.JSON.ExtraFields),
 	}
 
 	// Vercel reports prompt_tokens INCLUDING cached tokens. Subtract to avoid double-counting.
@@ -531,8 +531,8 @@ func languageModelStreamUsage(chunk openaisdk.ChatCompletionChunk, _ map[string]
 		}
 	}
 
-	if p, ok := chunk.JSON.ExtraFields["provider"]; ok {
-		streamProviderMetadata.Provider = p.Raw()
+	if provider := upstreamProvider(chunk.JSON.ExtraFields); provider != "" {
+		streamProviderMetadata.Provider = provider
 	}
 
 	completionTokenDetails := usage.CompletionTokensDetails
@@ -667,6 +667,9 @@ func languageModelToPrompt(prompt fantasy.Prompt, _, model string) ([]openaisdk.
 					case strings.HasPrefix(filePart.MediaType, "image/"):
 						base64Encoded := base64.StdEncoding.EncodeToString(filePart.Data)
 						data := "data:" + filePart.MediaType + ";base64," + base64Encoded
+						if filePart.URL != "" {
+							data = filePart.URL
+						}
 						imageURL := openaisdk.ChatCompletionContentPartImageImageURLParam{URL: data}
 						if providerOptions, ok := filePart.ProviderOptions[openaipkg.Name]; ok {
 							if detail, ok := providerOptions.(*openaipkg.ProviderFileOptions); ok {
@@ -758,7 +761,7 @@ func languageModelToPrompt(prompt fantasy.Prompt, _, model string) ([]openaisdk.
 
 					default:
 						warnings = append(warnings, fantasy.CallWarning{
-							Type:    fantasy.CallWarningTypeOther,
+							Type:    fantasy.CallWarningTypeDroppedContent,
 							Message: fmt.Sprintf("file part media type %s not supported", filePart.MediaType),
 						})
 					}
@@ -766,7 +769,7 @@ func languageModelToPrompt(prompt fantasy.Prompt, _, model string) ([]openaisdk.
 			}
 			if !hasVisibleUserContent(content) {
 				warnings = append(warnings, fantasy.CallWarning{
-					Type:    fantasy.CallWarningTypeOther,
+					Type:    fantasy.CallWarningTypeDroppedContent,
 					Message: "dropping empty user message (contains neither user-facing content nor tool results)",
 				})
 				continue
@@ -1044,6 +1047,35 @@ func languageModelToPrompt(prompt fantasy.Prompt, _, model string) ([]openaisdk.
 						})
 					}
 					messages = append(messages, tr)
+				case fantasy.ToolResultContentTypeMedia:
+					output, ok := fantasy.AsToolResultOutputType[fantasy.ToolResultOutputContentMedia](toolResultPart.Output)
+					if !ok {
+						warnings = append(warnings, fantasy.CallWarning{
+							Type:    fantasy.CallWarningTypeOther,
+							Message: "tool result output does not have the right type",
+						})
+						continue
+					}
+					// Tool messages only carry text. Answer the tool call with
+					// a placeholder anyway, as an unanswered tool call is
+					// rejected.
+					text := output.Text
+					if text == "" {
+						text = fmt.Sprintf("The tool returned %s content, which can't be shown.", output.MediaType)
+					}
+					warnings = append(warnings, fantasy.CallWarning{
+						Type:    fantasy.CallWarningTypeDroppedContent,
+						Message: fmt.Sprintf("tool result media type %s not supported, sending text only", output.MediaType),
+					})
+					tr := openaisdk.ToolMessage(text, toolResultPart.ToolCallID)
+					if cacheControl != nil {
+						tr.SetExtraFields(map[string]any{
+							"cache_control": map[string]string{
+								"type": cacheControl.Type,
+							},
+						})
+					}
+					messages = append(messages, tr)
 				}
 			}
 		}
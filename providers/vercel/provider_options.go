@@ -130,7 +130,11 @@ func (o *ProviderOptions) UnmarshalJSON(data []byte) error {
 
 // ProviderMetadata represents metadata from Vercel AI Gateway provider.
 type ProviderMetadata struct {
+	// Provider is the upstream provider that served the request.
 	Provider string `json:"provider,omitempty"`
+	// Headers holds the gateway's X-Vercel-* response headers, keyed by
+	// lowercase header name, e.g. routing and credit details.
+	Headers map[string]string `json:"headers,omitempty"`
 }
 
 // Options implements the ProviderOptionsData interface for ProviderMetadata.
@@ -5,6 +5,9 @@ import (
 	"charm.land/fantasy"
 	"charm.land/fantasy/providers/openai"
 	"github.com/openai/openai-go/v3/option"
+	"net/http"
+	"net/url"
+	"time"
 )
 
 type options struct {
@@ -38,6 +41,7 @@ func New(opts ...Option) (fantasy.Provider, error) {
 			openai.WithLanguageModelStreamExtraFunc(languageModelStreamExtra),
 			openai.WithLanguageModelExtraContentFunc(languageModelExtraContent),
 			openai.WithLanguageModelToPromptFunc(languageModelToPrompt),
+			openai.WithLanguageModelResponseHeadersFunc(languageModelResponseHeaders),
 		},
 		objectMode: fantasy.ObjectModeTool, // Default to tool mode for vercel
 	}
@@ -96,6 +100,31 @@ func WithHTTPClient(client option.HTTPClient) Option {
 	}
 }
 
+// WithHTTPTimeout sets the overall timeout of each request, including
+// reading streamed responses. Use fantasy.WithStreamIdleTimeout to detect
+// stalled streams instead. It's ignored when WithHTTPClient is set.
+func WithHTTPTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.openaiOptions = append(o.openaiOptions, openai.WithHTTPTimeout(timeout))
+	}
+}
+
+// WithProxy sends the requests through the given proxy instead of the one
+// from the environment. It's ignored when WithHTTPClient is set.
+func WithProxy(proxy *url.URL) Option {
+	return func(o *options) {
+		o.openaiOptions = append(o.openaiOptions, openai.WithProxy(proxy))
+	}
+}
+
+// WithTransport sets the HTTP transport of the Vercel provider. It's
+// ignored when WithHTTPClient is set.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *options) {
+		o.openaiOptions = append(o.openaiOptions, openai.WithTransport(transport))
+	}
+}
+
 // WithUserAgent sets an explicit User-Agent header, overriding the default and any
 // value set via WithHeaders.
 func WithUserAgent(ua string) Option {
@@ -0,0 +1,29 @@
+package vllm
+
+import (
+	"cmp"
+	"errors"
+
+	"charm.land/fantasy"
+	openaisdk "github.com/openai/openai-go/v3"
+)
+
+// toProviderErr converts errors from the tokenizer endpoints. Errors from the
+// chat endpoint are converted by the openai provider.
+func toProviderErr(err error) error {
+	var apiErr *openaisdk.Error
+	if errors.As(err, &apiErr) {
+		providerErr := &fantasy.ProviderError{
+			Title:        cmp.Or(fantasy.ErrorTitleForStatusCode(apiErr.StatusCode), "provider request failed"),
+			Message:      cmp.Or(apiErr.Message, apiErr.Error()),
+			Cause:        apiErr,
+			StatusCode:   apiErr.StatusCode,
+			ResponseBody: apiErr.DumpResponse(true),
+		}
+		if apiErr.Request != nil {
+			providerErr.URL = apiErr.Request.URL.String()
+		}
+		return providerErr
+	}
+	return fantasy.WrapTransportError(err)
+}
@@ -0,0 +1,66 @@
+package vllm
+
+import (
+	"context"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/openaicompat"
+	openaisdk "github.com/openai/openai-go/v3"
+)
+
+// languageModel wraps the OpenAI-compatible language model with vLLM's
+// tokenizer endpoints.
+type languageModel struct {
+	fantasy.LanguageModel
+	client openaisdk.Client
+}
+
+type tokenizeResponse struct {
+	Count       int64   `json:"count"`
+	MaxModelLen int64   `json:"max_model_len"`
+	Tokens      []int64 `json:"tokens"`
+}
+
+// CountTokens implements fantasy.TokenCounter. The prompt is tokenized
+// with the model's chat template; tools are not counted.
+func (l *languageModel) CountTokens(ctx context.Context, call fantasy.Call) (int64, error) {
+	messages, _ := openaicompat.ToPromptFunc(call.Prompt, l.Provider(), l.Model())
+	body := map[string]any{
+		"model":                 l.Model(),
+		"messages":              messages,
+		"add_generation_prompt": true,
+	}
+	var resp tokenizeResponse
+	if err := l.client.Post(ctx, "tokenize", body, &resp); err != nil {
+		return 0, toProviderErr(err)
+	}
+	return resp.Count, nil
+}
+
+// Tokenize returns the token IDs of text.
+func (l *languageModel) Tokenize(ctx context.Context, text string) ([]int64, error) {
+	body := map[string]any{
+		"model":  l.Model(),
+		"prompt": text,
+	}
+	var resp tokenizeResponse
+	if err := l.client.Post(ctx, "tokenize", body, &resp); err != nil {
+		return nil, toProviderErr(err)
+	}
+	return resp.Tokens, nil
+}
+
+// Detokenize returns the text of the token IDs.
+func (l *languageModel) Detokenize(ctx context.Context, tokens []int64) (string, error) {
+	body := map[string]any{
+		"model":  l.Model(),
+		"tokens": tokens,
+	}
+	var resp struct {
+		Prompt string `json:"prompt"`
+	}
+	if err := l.client.Post(ctx, "detokenize", body, &resp); err != nil {
+		return "", toProviderErr(err)
+	}
+	return resp.Prompt, nil
+}
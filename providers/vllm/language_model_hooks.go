@@ -0,0 +1,74 @@
+package vllm
+
+import (
+	"encoding/json"
+	"maps"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/schema"
+	openaisdk "github.com/openai/openai-go/v3"
+)
+
+// guidedFields are the mutually exclusive guided decoding fields.
+var guidedFields = []string{"guided_json", "guided_choice", "guided_regex", "guided_grammar"}
+
+func languagePrepareModelCall(model fantasy.LanguageModel, params *openaisdk.ChatCompletionNewParams, call fantasy.Call) ([]fantasy.CallWarning, error) {
+	providerOptions := &ProviderOptions{}
+	if v, ok := call.ProviderOptions[model.Provider()]; ok {
+		providerOptions, ok = v.(*ProviderOptions)
+		if !ok {
+			return nil, &fantasy.Error{Title: "invalid argument", Message: "vllm provider options should be *vllm.ProviderOptions"}
+		}
+	}
+
+	type plain ProviderOptions
+	data, err := json.Marshal(plain(*providerOptions))
+	if err != nil {
+		return nil, err
+	}
+	var extraFields map[string]any
+	if err := json.Unmarshal(data, &extraFields); err != nil {
+		return nil, err
+	}
+	delete(extraFields, "extra_body")
+
+	guided := 0
+	for _, field := range guidedFields {
+		if _, ok := extraFields[field]; ok {
+			guided++
+		}
+	}
+	if guided > 1 {
+		return nil, &fantasy.Error{Title: "invalid argument", Message: "vllm guided decoding options are mutually exclusive"}
+	}
+
+	maps.Copy(extraFields, providerOptions.ExtraBody)
+	if len(extraFields) > 0 {
+		params.SetExtraFields(extraFields)
+	}
+	return nil, nil
+}
+
+// languageModelOutputConstraint maps output constraints to vLLM's guided
+// decoding fields, replacing any guided option set through ProviderOptions.
+func languageModelOutputConstraint(params *openaisdk.ChatCompletionNewParams, constraint *fantasy.OutputConstraint) ([]fantasy.CallWarning, error) {
+	fields := params.ExtraFields()
+	if fields == nil {
+		fields = make(map[string]any)
+	}
+	for _, field := range guidedFields {
+		delete(fields, field)
+	}
+	switch constraint.Type {
+	case fantasy.OutputConstraintTypeJSONSchema:
+		fields["guided_json"] = schema.ToMap(constraint.Schema)
+	case fantasy.OutputConstraintTypeRegex:
+		fields["guided_regex"] = constraint.Regex
+	case fantasy.OutputConstraintTypeGrammar:
+		fields["guided_grammar"] = constraint.Grammar
+	default:
+		return []fantasy.CallWarning{fantasy.UnsupportedOutputConstraintWarning(constraint)}, nil
+	}
+	params.SetExtraFields(fields)
+	return nil, nil
+}
@@ -0,0 +1,85 @@
+package vllm
+
+import (
+	"encoding/json"
+
+	"charm.land/fantasy"
+)
+
+// Global type identifiers for vLLM-specific provider data.
+const (
+	TypeProviderOptions = Name + ".options"
+)
+
+// Register vLLM provider-specific types with the global registry.
+func init() {
+	fantasy.RegisterProviderType(TypeProviderOptions, func(data []byte) (fantasy.ProviderOptionsData, error) {
+		var v ProviderOptions
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		return &v, nil
+	})
+}
+
+// ProviderOptions represents additional options for the vLLM provider.
+// These are sent as extra body parameters of the chat completions request.
+type ProviderOptions struct {
+	// GuidedJSON constrains the output to JSON matching the schema.
+	GuidedJSON map[string]any `json:"guided_json,omitempty"`
+	// GuidedChoice constrains the output to exactly one of the choices.
+	GuidedChoice []string `json:"guided_choice,omitempty"`
+	// GuidedRegex constrains the output to the regular expression.
+	GuidedRegex *string `json:"guided_regex,omitempty"`
+	// GuidedGrammar constrains the output to the context-free grammar.
+	GuidedGrammar *string `json:"guided_grammar,omitempty"`
+
+	// BestOf generates this many sequences and returns the best one.
+	BestOf *int64 `json:"best_of,omitempty"`
+	// UseBeamSearch uses beam search instead of sampling.
+	UseBeamSearch *bool `json:"use_beam_search,omitempty"`
+
+	TopK              *int64   `json:"top_k,omitempty"`
+	MinP              *float64 `json:"min_p,omitempty"`
+	RepetitionPenalty *float64 `json:"repetition_penalty,omitempty"`
+	LengthPenalty     *float64 `json:"length_penalty,omitempty"`
+	Seed              *int64   `json:"seed,omitempty"`
+
+	ExtraBody map[string]any `json:"extra_body,omitempty"`
+}
+
+// Options implements the ProviderOptions interface.
+func (*ProviderOptions) Options() {}
+
+// MarshalJSON implements custom JSON marshaling with type info for ProviderOptions.
+func (o ProviderOptions) MarshalJSON() ([]byte, error) {
+	type plain ProviderOptions
+	return fantasy.MarshalProviderType(TypeProviderOptions, plain(o))
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling with type info for ProviderOptions.
+func (o *ProviderOptions) UnmarshalJSON(data []byte) error {
+	type plain ProviderOptions
+	var p plain
+	if err := fantasy.UnmarshalProviderType(data, &p); err != nil {
+		return err
+	}
+	*o = ProviderOptions(p)
+	return nil
+}
+
+// NewProviderOptions creates new provider options for the vLLM provider.
+func NewProviderOptions(opts *ProviderOptions) fantasy.ProviderOptions {
+	return fantasy.ProviderOptions{
+		Name: opts,
+	}
+}
+
+// ParseOptions parses provider options from a map for the vLLM provider.
+func ParseOptions(data map[string]any) (*ProviderOptions, error) {
+	var options ProviderOptions
+	if err := fantasy.ParseOptions(data, &options); err != nil {
+		return nil, err
+	}
+	return &options, nil
+}
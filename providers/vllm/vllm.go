@@ -0,0 +1,235 @@
+// Package vllm provides an implementation of the fantasy AI SDK for vLLM's
+// OpenAI-compatible server.
+package vllm
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/internal/httpclient"
+	"charm.land/fantasy/providers/openai"
+	"charm.land/fantasy/providers/openaicompat"
+	openaisdk "github.com/openai/openai-go/v3"
+	"github.com/openai/openai-go/v3/option"
+)
+
+type options struct {
+	baseURL              string
+	apiKey               string
+	headers              map[string]string
+	userAgent            string
+	client               option.HTTPClient
+	httpConfig           httpclient.Config
+	openaiOptions        []openai.Option
+	languageModelOptions []openai.LanguageModelOption
+	sdkOptions           []option.RequestOption
+	objectMode           fantasy.ObjectMode
+}
+
+const (
+	// DefaultURL is the default URL of a local vLLM server.
+	DefaultURL = "http://localhost:8000"
+	// Name is the name of the vLLM provider.
+	Name = "vllm"
+)
+
+// Option defines a function that configures vLLM provider options.
+type Option = func(*options)
+
+type provider struct {
+	fantasy.Provider
+	client openaisdk.Client
+}
+
+// Validate implements fantasy.Validator.
+func (p *provider) Validate(ctx context.Context) error {
+	return fantasy.Validate(ctx, p.Provider)
+}
+
+// New creates a new vLLM provider with the given options.
+//
+// Language models returned by the provider implement fantasy.TokenCounter
+// using the server's /tokenize endpoint, and expose Tokenize and
+// Detokenize methods.
+func New(opts ...Option) (fantasy.Provider, error) {
+	providerOptions := options{
+		baseURL: DefaultURL,
+		headers: map[string]string{},
+		openaiOptions: []openai.Option{
+			openai.WithName(Name),
+		},
+		languageModelOptions: []openai.LanguageModelOption{
+			openai.WithLanguageModelPrepareCallFunc(languagePrepareModelCall),
+			openai.WithLanguageModelOutputConstraintFunc(languageModelOutputConstraint),
+			openai.WithLanguageModelStreamExtraFunc(openaicompat.StreamExtraFunc),
+			openai.WithLanguageModelExtraContentFunc(openaicompat.ExtraContentFunc),
+			openai.WithLanguageModelToPromptFunc(openaicompat.ToPromptFunc),
+		},
+		objectMode: fantasy.ObjectModeAuto,
+	}
+	for _, o := range opts {
+		o(&providerOptions)
+	}
+	if providerOptions.client == nil {
+		providerOptions.client = providerOptions.httpConfig.Client()
+	}
+
+	baseURL := strings.TrimSuffix(providerOptions.baseURL, "/")
+	openaiOptions := append(
+		[]openai.Option{
+			openai.WithBaseURL(baseURL + "/v1"),
+			openai.WithHeaders(providerOptions.headers),
+		},
+		providerOptions.openaiOptions...,
+	)
+	if providerOptions.apiKey != "" {
+		openaiOptions = append(openaiOptions, openai.WithAPIKey(providerOptions.apiKey))
+	}
+	if providerOptions.userAgent != "" {
+		openaiOptions = append(openaiOptions, openai.WithUserAgent(providerOptions.userAgent))
+	}
+	if providerOptions.client != nil {
+		openaiOptions = append(openaiOptions, openai.WithHTTPClient(providerOptions.client))
+	}
+	openaiOptions = append(
+		openaiOptions,
+		openai.WithSDKOptions(providerOptions.sdkOptions...),
+		openai.WithLanguageModelOptions(providerOptions.languageModelOptions...),
+		openai.WithObjectMode(providerOptions.objectMode),
+	)
+	p, err := openai.New(openaiOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &provider{
+		Provider: p,
+		client:   openaisdk.NewClient(providerOptions.clientOptions(baseURL)...),
+	}, nil
+}
+
+// LanguageModel implements fantasy.Provider.
+func (p *provider) LanguageModel(ctx context.Context, modelID string) (fantasy.LanguageModel, error) {
+	model, err := p.Provider.LanguageModel(ctx, modelID)
+	if err != nil {
+		return nil, err
+	}
+	return &languageModel{LanguageModel: model, client: p.client}, nil
+}
+
+// clientOptions returns the request options used for the tokenizer
+// endpoints.
+func (o options) clientOptions(baseURL string) []option.RequestOption {
+	clientOptions := []option.RequestOption{option.WithBaseURL(baseURL)}
+	if o.apiKey != "" {
+		clientOptions = append(clientOptions, option.WithAPIKey(o.apiKey))
+	}
+	for key, value := range o.headers {
+		clientOptions = append(clientOptions, option.WithHeader(key, value))
+	}
+	if o.userAgent != "" {
+		clientOptions = append(clientOptions, option.WithHeader("User-Agent", o.userAgent))
+	}
+	if o.client != nil {
+		clientOptions = append(clientOptions, option.WithHTTPClient(o.client))
+	}
+	return append(clientOptions, o.sdkOptions...)
+}
+
+// WithBaseURL sets the root URL of the vLLM server, e.g.
+// "http://localhost:8000". The OpenAI-compatible endpoints are reached
+// under "/v1".
+func WithBaseURL(url string) Option {
+	return func(o *options) {
+		o.baseURL = url
+	}
+}
+
+// WithAPIKey sets the API key configured on the vLLM server with --api-key.
+func WithAPIKey(apiKey string) Option {
+	return func(o *options) {
+		o.apiKey = apiKey
+	}
+}
+
+// WithName sets the name for the vLLM provider.
+func WithName(name string) Option {
+	return func(o *options) {
+		o.openaiOptions = append(o.openaiOptions, openai.WithName(name))
+	}
+}
+
+// WithHeaders sets the headers for the vLLM provider.
+func WithHeaders(headers map[string]string) Option {
+	return func(o *options) {
+		maps.Copy(o.headers, headers)
+	}
+}
+
+// WithHTTPClient sets the HTTP client for the vLLM provider.
+func WithHTTPClient(client option.HTTPClient) Option {
+	return func(o *options) {
+		o.client = client
+	}
+}
+
+// WithHTTPTimeout sets the overall timeout of each request, including
+// reading streamed responses. Use fantasy.WithStreamIdleTimeout to detect
+// stalled streams instead. It's ignored when WithHTTPClient is set.
+func WithHTTPTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.httpConfig.Timeout = timeout
+	}
+}
+
+// WithProxy sends the requests through the given proxy instead of the one
+// from the environment. It's ignored when WithHTTPClient is set.
+func WithProxy(proxy *url.URL) Option {
+	return func(o *options) {
+		o.httpConfig.Proxy = proxy
+	}
+}
+
+// WithTransport sets the HTTP transport of the vLLM provider. It's
+// ignored when WithHTTPClient is set.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *options) {
+		o.httpConfig.Transport = transport
+	}
+}
+
+// WithSDKOptions sets the SDK options for the vLLM provider.
+func WithSDKOptions(opts ...option.RequestOption) Option {
+	return func(o *options) {
+		o.sdkOptions = append(o.sdkOptions, opts...)
+	}
+}
+
+// WithObjectMode sets the object generation mode for the vLLM provider.
+// vLLM supports json_schema response formats, so ObjectModeAuto uses
+// native JSON mode.
+func WithObjectMode(om fantasy.ObjectMode) Option {
+	return func(o *options) {
+		o.objectMode = om
+	}
+}
+
+// WithUserAgent sets an explicit User-Agent header, overriding the default and any
+// value set via WithHeaders.
+func WithUserAgent(ua string) Option {
+	return func(o *options) {
+		o.userAgent = ua
+	}
+}
+
+// WithLanguageModelOptions appends language model options to the provider.
+func WithLanguageModelOptions(opts ...openai.LanguageModelOption) Option {
+	return func(o *options) {
+		o.languageModelOptions = append(o.languageModelOptions, opts...)
+	}
+}
@@ -0,0 +1,164 @@
+package vllm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/require"
+)
+
+type recordedRequest struct {
+	path string
+	body map[string]any
+}
+
+func newVLLMServer(t *testing.T, responses map[string]any) (*httptest.Server, chan recordedRequest) {
+	t.Helper()
+
+	calls := make(chan recordedRequest, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		calls <- recordedRequest{path: r.URL.Path, body: body}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(responses[r.URL.Path])
+	}))
+	t.Cleanup(server.Close)
+	return server, calls
+}
+
+func mockChatCompletion() map[string]any {
+	return map[string]any{
+		"id":      "chatcmpl-1",
+		"object":  "chat.completion",
+		"created": 1,
+		"model":   "meta-llama/Llama-3.1-8B-Instruct",
+		"choices": []any{map[string]any{
+			"index":         0,
+			"finish_reason": "stop",
+			"message":       map[string]any{"role": "assistant", "content": "positive"},
+		}},
+	}
+}
+
+func TestLanguageModel_ProviderOptions(t *testing.T) {
+	t.Parallel()
+
+	t.Run("guided choice and beam search", func(t *testing.T) {
+		t.Parallel()
+
+		server, calls := newVLLMServer(t, map[string]any{"/v1/chat/completions": mockChatCompletion()})
+		provider, err := New(WithBaseURL(server.URL))
+		require.NoError(t, err)
+		model, err := provider.LanguageModel(t.Context(), "meta-llama/Llama-3.1-8B-Instruct")
+		require.NoError(t, err)
+
+		resp, err := model.Generate(t.Context(), fantasy.Call{
+			Prompt: fantasy.Prompt{fantasy.NewUserMessage("Classify: I love it")},
+			ProviderOptions: NewProviderOptions(&ProviderOptions{
+				GuidedChoice:  []string{"positive", "negative"},
+				BestOf:        fantasy.Opt[int64](4),
+				UseBeamSearch: fantasy.Opt(true),
+				MinP:          fantasy.Opt(0.1),
+			}),
+		})
+		require.NoError(t, err)
+		require.Equal(t, "positive", resp.Content.Text())
+
+		call := <-calls
+		require.Equal(t, "/v1/chat/completions", call.path)
+		require.Equal(t, []any{"positive", "negative"}, call.body["guided_choice"])
+		require.Equal(t, float64(4), call.body["best_of"])
+		require.Equal(t, true, call.body["use_beam_search"])
+		require.Equal(t, 0.1, call.body["min_p"])
+	})
+
+	t.Run("output constraint replaces guided options", func(t *testing.T) {
+		t.Parallel()
+
+		server, calls := newVLLMServer(t, map[string]any{"/v1/chat/completions": mockChatCompletion()})
+		provider, err := New(WithBaseURL(server.URL))
+		require.NoError(t, err)
+		model, err := provider.LanguageModel(t.Context(), "meta-llama/Llama-3.1-8B-Instruct")
+		require.NoError(t, err)
+
+		_, err = model.Generate(t.Context(), fantasy.Call{
+			Prompt:           fantasy.Prompt{fantasy.NewUserMessage("Pick a number")},
+			OutputConstraint: fantasy.RegexConstraint(`\d+`),
+			ProviderOptions: NewProviderOptions(&ProviderOptions{
+				GuidedChoice: []string{"one", "two"},
+			}),
+		})
+		require.NoError(t, err)
+
+		call := <-calls
+		require.Equal(t, `\d+`, call.body["guided_regex"])
+		require.NotContains(t, call.body, "guided_choice")
+		require.NotContains(t, call.body, "response_format")
+	})
+
+	t.Run("guided options are exclusive", func(t *testing.T) {
+		t.Parallel()
+
+		provider, err := New()
+		require.NoError(t, err)
+		model, err := provider.LanguageModel(t.Context(), "meta-llama/Llama-3.1-8B-Instruct")
+		require.NoError(t, err)
+
+		regex := `\d+`
+		_, err = model.Generate(t.Context(), fantasy.Call{
+			Prompt: fantasy.Prompt{fantasy.NewUserMessage("Hi")},
+			ProviderOptions: NewProviderOptions(&ProviderOptions{
+				GuidedChoice: []string{"a"},
+				GuidedRegex:  &regex,
+			}),
+		})
+		require.ErrorContains(t, err, "mutually exclusive")
+	})
+}
+
+func TestLanguageModel_Tokenizer(t *testing.T) {
+	t.Parallel()
+
+	server, calls := newVLLMServer(t, map[string]any{
+		"/tokenize":   map[string]any{"count": 3, "max_model_len": 8192, "tokens": []int64{9906, 11, 1917}},
+		"/detokenize": map[string]any{"prompt": "Hello, world"},
+	})
+	provider, err := New(WithBaseURL(server.URL))
+	require.NoError(t, err)
+	model, err := provider.LanguageModel(t.Context(), "meta-llama/Llama-3.1-8B-Instruct")
+	require.NoError(t, err)
+
+	counter, ok := model.(fantasy.TokenCounter)
+	require.True(t, ok)
+	count, err := counter.CountTokens(t.Context(), fantasy.Call{
+		Prompt: fantasy.Prompt{fantasy.NewUserMessage("Hello, world")},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(3), count)
+
+	call := <-calls
+	require.Equal(t, "/tokenize", call.path)
+	require.Equal(t, "meta-llama/Llama-3.1-8B-Instruct", call.body["model"])
+	require.Equal(t, true, call.body["add_generation_prompt"])
+	require.Len(t, call.body["messages"], 1)
+
+	tokenizer := model.(interface {
+		Tokenize(context.Context, string) ([]int64, error)
+		Detokenize(context.Context, []int64) (string, error)
+	})
+	tokens, err := tokenizer.Tokenize(t.Context(), "Hello, world")
+	require.NoError(t, err)
+	require.Equal(t, []int64{9906, 11, 1917}, tokens)
+	require.Equal(t, "Hello, world", (<-calls).body["prompt"])
+
+	text, err := tokenizer.Detokenize(t.Context(), tokens)
+	require.NoError(t, err)
+	require.Equal(t, "Hello, world", text)
+	require.Equal(t, []any{float64(9906), float64(11), float64(1917)}, (<-calls).body["tokens"])
+}
@@ -171,6 +171,51 @@ func TestProviderRegistry_Serialization_AnthropicOptions(t *testing.T) {
 	require.Equal(t, true, *opt.SendReasoning)
 }
 
+func TestProviderRegistry_PromptRoundTrip(t *testing.T) {
+	prompt := fantasy.Prompt{
+		fantasy.NewSystemMessage("You are helpful"),
+		{
+			Role: fantasy.MessageRoleUser,
+			Content: []fantasy.MessagePart{
+				fantasy.TextPart{Text: "What is 2+2?"},
+			},
+			ProviderOptions: fantasy.ProviderOptions{
+				anthropic.Name: &anthropic.ProviderCacheControlOptions{
+					CacheControl: anthropic.CacheControl{Type: "ephemeral"},
+				},
+			},
+		},
+		{
+			Role: fantasy.MessageRoleAssistant,
+			Content: []fantasy.MessagePart{
+				fantasy.ReasoningPart{
+					Text: "Simple arithmetic.",
+					ProviderOptions: fantasy.ProviderOptions{
+						anthropic.Name: &anthropic.ReasoningOptionMetadata{Signature: "sig-123"},
+					},
+				},
+				fantasy.ToolCallPart{ToolCallID: "call-1", ToolName: "add", Input: `{"a":2,"b":2}`},
+			},
+		},
+		{
+			Role: fantasy.MessageRoleTool,
+			Content: []fantasy.MessagePart{
+				fantasy.ToolResultPart{
+					ToolCallID: "call-1",
+					Output:     fantasy.ToolResultOutputContentText{Text: "4"},
+				},
+			},
+		},
+	}
+
+	data, err := fantasy.MarshalPrompt(prompt)
+	require.NoError(t, err)
+
+	decoded, err := fantasy.UnmarshalPrompt(data)
+	require.NoError(t, err)
+	require.Equal(t, prompt, decoded)
+}
+
 func TestProviderRegistry_Serialization_GoogleOptions(t *testing.T) {
 	msg := fantasy.Message{
 		Role: fantasy.MessageRoleUser,
@@ -351,6 +396,7 @@ func TestProviderRegistry_AllTypesRegistered(t *testing.T) {
 		{"OpenAI Metadata", openai.Name, &openai.ProviderMetadata{}},
 		{"OpenAI Responses Options", openai.Name, &openai.ResponsesProviderOptions{}},
 		{"Anthropic Options", anthropic.Name, &anthropic.ProviderOptions{}},
+		{"Anthropic Cache Control Options", anthropic.Name, &anthropic.ProviderCacheControlOptions{}},
 		{"Google Options", google.Name, &google.ProviderOptions{}},
 		{"OpenRouter Options", openrouter.Name, &openrouter.ProviderOptions{}},
 		{"OpenAICompat Options", openaicompat.Name, &openaicompat.ProviderOptions{}},
@@ -427,3 +473,26 @@ func TestProviderRegistry_AllTypesRegistered(t *testing.T) {
 		})
 	}
 }
+
+func TestProviderRegistry_PromptFromAnthropicMessages(t *testing.T) {
+	data := `[
+		{"role": "user", "content": [{"type": "text", "text": "Hi", "cache_control": {"type": "ephemeral"}}]},
+		{"role": "assistant", "content": [{"type": "thinking", "thinking": "Greeting.", "signature": "sig-123"}, {"type": "text", "text": "Hello"}]}
+	]`
+
+	prompt, err := fantasy.PromptFromAnthropicMessages([]byte(data))
+	require.NoError(t, err)
+	require.Len(t, prompt, 2)
+
+	text, ok := fantasy.AsMessagePart[fantasy.TextPart](prompt[0].Content[0])
+	require.True(t, ok)
+	cache, ok := text.ProviderOptions[anthropic.Name].(*anthropic.ProviderCacheControlOptions)
+	require.True(t, ok)
+	require.Equal(t, "ephemeral", cache.CacheControl.Type)
+
+	reasoning, ok := fantasy.AsMessagePart[fantasy.ReasoningPart](prompt[1].Content[0])
+	require.True(t, ok)
+	meta, ok := reasoning.ProviderOptions[anthropic.Name].(*anthropic.ReasoningOptionMetadata)
+	require.True(t, ok)
+	require.Equal(t, "sig-123", meta.Signature)
+}
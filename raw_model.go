@@ -0,0 +1,459 @@
+package fantasy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// RawProviderName is the provider name of the models created with
+// RawModel.
+const RawProviderName = "raw"
+
+// RawRequestTemplate describes the requests a RawModel sends.
+type RawRequestTemplate struct {
+	// Model is the model ID, returned by Model and available to the body as
+	// {{model}}.
+	Model string
+	// Method is the HTTP method, POST by default.
+	Method string
+	// Headers are sent with every request, e.g. for authentication.
+	Headers map[string]string
+	// Body is the JSON body of the requests. String values are templates
+	// whose variables are replaced with the values of the call:
+	//
+	//	{{model}}              the model ID
+	//	{{messages}}           the messages, as [{"role": ..., "content": ...}]
+	//	{{prompt}}             the text of the last user message
+	//	{{system}}             the text of the system messages
+	//	{{max_output_tokens}}  and {{temperature}}, {{top_p}}, {{top_k}},
+	//	{{presence_penalty}}, {{frequency_penalty}}: the settings of the call
+	//
+	// A string that is only a variable becomes its JSON value, and its field
+	// is left out when the call doesn't set it. Variables within a string
+	// are replaced with their text. Maps and slices are templated
+	// recursively.
+	Body map[string]any
+	// Client sends the requests, http.DefaultClient by default.
+	Client *http.Client
+}
+
+// RawResponseMapper describes where a RawModel finds the content of the
+// responses. Its fields are paths into the response JSON, with keys
+// separated by dots and array indexes in brackets, e.g.
+// "choices[0].message.content". A leading "$." is allowed.
+type RawResponseMapper struct {
+	// Text is the path of the generated text. A path to an array of strings
+	// gets them joined.
+	Text string
+	// FinishReason is the path of the finish reason. Without it, responses
+	// finish with FinishReasonStop.
+	FinishReason string
+	// FinishReasons maps the finish reasons of the provider to fantasy's.
+	// Unmapped reasons that aren't fantasy's finish with FinishReasonOther.
+	FinishReasons map[string]FinishReason
+	// InputTokens and OutputTokens are the paths of the token counts.
+	InputTokens  string
+	OutputTokens string
+	// Error is the path of the error message of failed requests, e.g.
+	// "error.message". The response body is the message otherwise.
+	Error string
+}
+
+// RawModel returns a language model that sends the calls to endpoint as the
+// JSON requests of the template and maps the JSON responses with the
+// mapper, to use a provider there's no provider package for:
+//
+//	model := fantasy.RawModel("https://api.example.com/v1/generate",
+//	    fantasy.RawRequestTemplate{
+//	        Model:   "example-1",
+//	        Headers: map[string]string{"Authorization": "Bearer " + key},
+//	        Body: map[string]any{
+//	            "model":       "{{model}}",
+//	            "input":       "{{messages}}",
+//	            "temperature": "{{temperature}}",
+//	        },
+//	    },
+//	    fantasy.RawResponseMapper{
+//	        Text:         "output[0].text",
+//	        FinishReason: "stop_reason",
+//	        OutputTokens: "usage.generated_tokens",
+//	    },
+//	)
+//
+// Raw models generate text only: tools and files are dropped with a
+// warning. Stream yields the whole text at once, and object generation
+// isn't supported; use object.GenerateWithText with a raw model instead.
+func RawModel(endpoint string, request RawRequestTemplate, response RawResponseMapper) LanguageModel {
+	return &rawModel{endpoint: endpoint, request: request, response: response}
+}
+
+type rawModel struct {
+	endpoint string
+	request  RawRequestTemplate
+	response RawResponseMapper
+}
+
+// Generate implements LanguageModel.
+func (m *rawModel) Generate(ctx context.Context, call Call) (*Response, error) {
+	vars, warnings := rawVariables(m.request.Model, call)
+	body, _ := rawTemplate(m.request.Body, vars).(map[string]any)
+	if body == nil {
+		body = map[string]any{}
+	}
+	maps.Copy(body, call.ExtraBody)
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := m.endpoint
+	if len(call.ExtraQuery) > 0 {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		query := u.Query()
+		for k, v := range call.ExtraQuery {
+			query.Set(k, v)
+		}
+		u.RawQuery = query.Encode()
+		endpoint = u.String()
+	}
+	method := m.request.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range m.request.Headers {
+		req.Header.Set(k, v)
+	}
+	for k, v := range call.Headers {
+		req.Header.Set(k, v)
+	}
+	if call.UserAgent != "" {
+		req.Header.Set("User-Agent", call.UserAgent)
+	}
+
+	client := m.request.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, WrapTransportError(err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, WrapTransportError(err)
+	}
+
+	var decoded any
+	decodeErr := json.Unmarshal(respBody, &decoded)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		message := strings.TrimSpace(string(respBody))
+		if s, ok := rawLookup(decoded, m.response.Error).(string); ok {
+			message = s
+		}
+		headers := map[string]string{}
+		for k := range resp.Header {
+			headers[strings.ToLower(k)] = resp.Header.Get(k)
+		}
+		return nil, &ProviderError{
+			Title:           ErrorTitleForStatusCode(resp.StatusCode),
+			Message:         message,
+			URL:             endpoint,
+			StatusCode:      resp.StatusCode,
+			RequestBody:     data,
+			ResponseHeaders: headers,
+			ResponseBody:    respBody,
+		}
+	}
+	if decodeErr != nil {
+		return nil, &ProviderError{
+			Title:        "invalid response",
+			Message:      decodeErr.Error(),
+			Cause:        decodeErr,
+			URL:          endpoint,
+			StatusCode:   resp.StatusCode,
+			ResponseBody: respBody,
+		}
+	}
+	return m.mapResponse(decoded, warnings)
+}
+
+func (m *rawModel) mapResponse(decoded any, warnings []CallWarning) (*Response, error) {
+	var text string
+	switch v := rawLookup(decoded, m.response.Text).(type) {
+	case string:
+		text = v
+	case []any:
+		var parts []string
+		for _, part := range v {
+			if s, ok := part.(string); ok {
+				parts = append(parts, s)
+			}
+		}
+		text = strings.Join(parts, "")
+	case nil:
+		return nil, &Error{Title: "invalid response", Message: fmt.Sprintf("no text at %q", m.response.Text)}
+	default:
+		return nil, &Error{Title: "invalid response", Message: fmt.Sprintf("text at %q is a %T, not a string", m.response.Text, v)}
+	}
+
+	result := &Response{
+		Content:      ResponseContent{TextContent{Text: text}},
+		FinishReason: FinishReasonStop,
+		Usage: Usage{
+			InputTokens:  rawInt(rawLookup(decoded, m.response.InputTokens)),
+			OutputTokens: rawInt(rawLookup(decoded, m.response.OutputTokens)),
+		},
+		Warnings: warnings,
+	}
+	result.Usage.TotalTokens = result.Usage.InputTokens + result.Usage.OutputTokens
+	if m.response.FinishReason != "" {
+		result.FinishReason = m.mapFinishReason(rawLookup(decoded, m.response.FinishReason))
+	}
+	return result, nil
+}
+
+func (m *rawModel) mapFinishReason(v any) FinishReason {
+	reason, ok := v.(string)
+	if !ok {
+		return FinishReasonUnknown
+	}
+	if mapped, ok := m.response.FinishReasons[reason]; ok {
+		return mapped
+	}
+	switch FinishReason(reason) {
+	case FinishReasonStop, FinishReasonLength, FinishReasonContentFilter, FinishReasonToolCalls, FinishReasonError, FinishReasonOther:
+		return FinishReason(reason)
+	}
+	return FinishReasonOther
+}
+
+// Stream implements LanguageModel. The text is yielded at once when the
+// response arrives.
+func (m *rawModel) Stream(ctx context.Context, call Call) (StreamResponse, error) {
+	resp, err := m.Generate(ctx, call)
+	if err != nil {
+		return nil, err
+	}
+	return func(yield func(StreamPart) bool) {
+		if len(resp.Warnings) > 0 {
+			if !yield(StreamPart{Type: StreamPartTypeWarnings, Warnings: resp.Warnings}) {
+				return
+			}
+		}
+		id := NewID()
+		for _, part := range []StreamPart{
+			{Type: StreamPartTypeTextStart, ID: id},
+			{Type: StreamPartTypeTextDelta, ID: id, Delta: resp.Content.Text()},
+			{Type: StreamPartTypeTextEnd, ID: id},
+			{Type: StreamPartTypeFinish, FinishReason: resp.FinishReason, Usage: resp.Usage},
+		} {
+			if !yield(part) {
+				return
+			}
+		}
+	}, nil
+}
+
+var errRawModelObjects = &Error{
+	Title:   "unsupported functionality",
+	Message: "raw models don't generate objects, use object.GenerateWithText",
+}
+
+// GenerateObject implements LanguageModel. Raw models don't generate
+// objects.
+func (m *rawModel) GenerateObject(context.Context, ObjectCall) (*ObjectResponse, error) {
+	return nil, errRawModelObjects
+}
+
+// StreamObject implements LanguageModel. Raw models don't generate objects.
+func (m *rawModel) StreamObject(context.Context, ObjectCall) (ObjectStreamResponse, error) {
+	return nil, errRawModelObjects
+}
+
+// Provider implements LanguageModel.
+func (m *rawModel) Provider() string {
+	return RawProviderName
+}
+
+// Model implements LanguageModel.
+func (m *rawModel) Model() string {
+	return m.request.Model
+}
+
+// rawVariables returns the values of the template variables for the call.
+// Variables the call doesn't set are nil.
+func rawVariables(model string, call Call) (map[string]any, []CallWarning) {
+	var warnings []CallWarning
+	if len(call.Tools) > 0 {
+		warnings = append(warnings, CallWarning{
+			Type:    CallWarningTypeUnsupportedSetting,
+			Setting: "Tools",
+			Details: "raw models don't support tools",
+		})
+	}
+
+	var messages []map[string]any
+	var system []string
+	var prompt string
+	dropped := false
+	for _, msg := range call.Prompt {
+		var texts []string
+		for _, part := range msg.Content {
+			switch part := part.(type) {
+			case TextPart:
+				texts = append(texts, part.Text)
+			case ToolResultPart:
+				if output, ok := part.Output.(ToolResultOutputContentText); ok {
+					texts = append(texts, output.Text)
+				} else {
+					dropped = true
+				}
+			case ReasoningPart:
+			default:
+				dropped = true
+			}
+		}
+		text := strings.Join(texts, "\n")
+		switch msg.Role {
+		case MessageRoleSystem:
+			system = append(system, text)
+		case MessageRoleUser:
+			prompt = text
+		}
+		if text != "" {
+			messages = append(messages, map[string]any{"role": string(msg.Role), "content": text})
+		}
+	}
+	if dropped {
+		warnings = append(warnings, CallWarning{
+			Type:    CallWarningTypeOther,
+			Message: "raw models send text only, other content was dropped",
+		})
+	}
+
+	vars := map[string]any{
+		"model":    model,
+		"messages": messages,
+		"prompt":   prompt,
+		"system":   strings.Join(system, "\n\n"),
+	}
+	for name, v := range map[string]any{
+		"max_output_tokens": call.MaxOutputTokens,
+		"temperature":       call.Temperature,
+		"top_p":             call.TopP,
+		"top_k":             call.TopK,
+		"presence_penalty":  call.PresencePenalty,
+		"frequency_penalty": call.FrequencyPenalty,
+	} {
+		vars[name] = rawPointerValue(v)
+	}
+	return vars, warnings
+}
+
+func rawPointerValue(v any) any {
+	switch v := v.(type) {
+	case *int64:
+		if v != nil {
+			return *v
+		}
+	case *float64:
+		if v != nil {
+			return *v
+		}
+	}
+	return nil
+}
+
+// rawTemplate replaces the variables in the string values of v.
+func rawTemplate(v any, vars map[string]any) any {
+	switch v := v.(type) {
+	case string:
+		if name, ok := strings.CutPrefix(v, "{{"); ok {
+			if name, ok := strings.CutSuffix(name, "}}"); ok {
+				if value, ok := vars[name]; ok {
+					return value
+				}
+			}
+		}
+		for name, value := range vars {
+			if s, ok := value.(string); ok {
+				v = strings.ReplaceAll(v, "{{"+name+"}}", s)
+			}
+		}
+		return v
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, value := range v {
+			if value = rawTemplate(value, vars); value != nil {
+				out[k] = value
+			}
+		}
+		return out
+	case []any:
+		out := make([]any, 0, len(v))
+		for _, value := range v {
+			if value = rawTemplate(value, vars); value != nil {
+				out = append(out, value)
+			}
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// rawLookup returns the value at path in v, or nil if there is none.
+func rawLookup(v any, path string) any {
+	if path == "" {
+		return nil
+	}
+	path = strings.TrimPrefix(strings.TrimPrefix(path, "$"), ".")
+	path = strings.ReplaceAll(path, "[", ".")
+	path = strings.ReplaceAll(path, "]", "")
+	for key := range strings.SplitSeq(path, ".") {
+		if key == "" {
+			continue
+		}
+		switch node := v.(type) {
+		case map[string]any:
+			v = node[key]
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil
+			}
+			v = node[i]
+		default:
+			return nil
+		}
+	}
+	return v
+}
+
+func rawInt(v any) int64 {
+	switch v := v.(type) {
+	case float64:
+		return int64(v)
+	case string:
+		n, _ := strconv.ParseInt(v, 10, 64)
+		return n
+	}
+	return 0
+}
@@ -0,0 +1,153 @@
+package fantasy
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type rawRequest struct {
+	query  string
+	header http.Header
+	body   map[string]any
+}
+
+func newRawServer(t *testing.T, status int, response string) (*httptest.Server, <-chan rawRequest) {
+	t.Helper()
+	requests := make(chan rawRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		requests <- rawRequest{query: r.URL.RawQuery, header: r.Header, body: body}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+	return server, requests
+}
+
+var rawTestTemplate = RawRequestTemplate{
+	Model:   "example-1",
+	Headers: map[string]string{"Authorization": "Bearer key"},
+	Body: map[string]any{
+		"model":       "{{model}}",
+		"input":       "{{messages}}",
+		"instruction": "{{system}} Answer: {{prompt}}",
+		"options": map[string]any{
+			"temperature": "{{temperature}}",
+			"max_tokens":  "{{max_output_tokens}}",
+		},
+	},
+}
+
+var rawTestMapper = RawResponseMapper{
+	Text:          "$.output[0].text",
+	FinishReason:  "output[0].stop_reason",
+	FinishReasons: map[string]FinishReason{"max_length": FinishReasonLength},
+	InputTokens:   "usage.prompt",
+	OutputTokens:  "usage.generated",
+	Error:         "error.message",
+}
+
+func TestRawModel_Generate(t *testing.T) {
+	t.Parallel()
+
+	server, requests := newRawServer(t, http.StatusOK, `{
+		"output": [{"text": ["Hello", " there"], "stop_reason": "max_length"}],
+		"usage": {"prompt": 12, "generated": 3}
+	}`)
+	model := RawModel(server.URL, rawTestTemplate, rawTestMapper)
+	require.Equal(t, RawProviderName, model.Provider())
+	require.Equal(t, "example-1", model.Model())
+
+	resp, err := model.Generate(t.Context(), Call{
+		Prompt: Prompt{
+			NewSystemMessage("Be brief."),
+			NewUserMessage("Hi!", FilePart{Data: []byte("x"), MediaType: "image/png"}),
+		},
+		Temperature: Opt(0.5),
+		Headers:     map[string]string{"X-Tenant": "acme"},
+		ExtraQuery:  map[string]string{"route": "eu"},
+		ExtraBody:   map[string]any{"seed": 7},
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Hello there", resp.Content.Text())
+	require.Equal(t, FinishReasonLength, resp.FinishReason)
+	require.Equal(t, Usage{InputTokens: 12, OutputTokens: 3, TotalTokens: 15}, resp.Usage)
+	require.Len(t, resp.Warnings, 1)
+
+	req := <-requests
+	require.Equal(t, "route=eu", req.query)
+	require.Equal(t, "Bearer key", req.header.Get("Authorization"))
+	require.Equal(t, "acme", req.header.Get("X-Tenant"))
+	require.Equal(t, map[string]any{
+		"model": "example-1",
+		"input": []any{
+			map[string]any{"role": "system", "content": "Be brief."},
+			map[string]any{"role": "user", "content": "Hi!"},
+		},
+		"instruction": "Be brief. Answer: Hi!",
+		// The unset max_tokens is left out.
+		"options": map[string]any{"temperature": 0.5},
+		"seed":    float64(7),
+	}, req.body)
+}
+
+func TestRawModel_Error(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newRawServer(t, http.StatusTooManyRequests, `{"error": {"message": "slow down"}}`)
+	model := RawModel(server.URL, rawTestTemplate, rawTestMapper)
+	_, err := model.Generate(t.Context(), Call{Prompt: Prompt{NewUserMessage("Hi!")}})
+
+	var providerErr *ProviderError
+	require.ErrorAs(t, err, &providerErr)
+	require.Equal(t, http.StatusTooManyRequests, providerErr.StatusCode)
+	require.Equal(t, "slow down", providerErr.Message)
+	require.True(t, providerErr.IsRetryable())
+
+	server, _ = newRawServer(t, http.StatusOK, `{"output": []}`)
+	model = RawModel(server.URL, rawTestTemplate, rawTestMapper)
+	_, err = model.Generate(t.Context(), Call{Prompt: Prompt{NewUserMessage("Hi!")}})
+	require.EqualError(t, err, `invalid response: no text at "$.output[0].text"`)
+}
+
+func TestRawModel_Stream(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newRawServer(t, http.StatusOK, `{"output": [{"text": "Hello", "stop_reason": "stop"}]}`)
+	model := RawModel(server.URL, rawTestTemplate, rawTestMapper)
+	stream, err := model.Stream(t.Context(), Call{Prompt: Prompt{NewUserMessage("Hi!")}})
+	require.NoError(t, err)
+
+	var types []StreamPartType
+	var text string
+	var finish StreamPart
+	for part := range stream {
+		types = append(types, part.Type)
+		text += part.Delta
+		if part.Type == StreamPartTypeFinish {
+			finish = part
+		}
+	}
+	require.Equal(t, []StreamPartType{StreamPartTypeTextStart, StreamPartTypeTextDelta, StreamPartTypeTextEnd, StreamPartTypeFinish}, types)
+	require.Equal(t, "Hello", text)
+	require.Equal(t, FinishReasonStop, finish.FinishReason)
+}
+
+func TestRawLookup(t *testing.T) {
+	t.Parallel()
+
+	var v any
+	require.NoError(t, json.Unmarshal([]byte(`{"a": [{"b": {"c": 1}}, 2]}`), &v))
+	require.Equal(t, float64(1), rawLookup(v, "a[0].b.c"))
+	require.Equal(t, float64(1), rawLookup(v, "$.a.0.b.c"))
+	require.Equal(t, float64(2), rawLookup(v, "a[1]"))
+	require.Nil(t, rawLookup(v, "a[2]"))
+	require.Nil(t, rawLookup(v, "a[0].x.y"))
+	require.Nil(t, rawLookup(v, ""))
+}
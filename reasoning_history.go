@@ -0,0 +1,68 @@
+package fantasy
+
+// ReasoningHistoryMode is which reasoning parts of earlier assistant
+// messages the agent sends back to the model on each step.
+type ReasoningHistoryMode int
+
+const (
+	// ReasoningHistoryAll sends all the reasoning back. This is the
+	// default.
+	ReasoningHistoryAll ReasoningHistoryMode = iota
+	// ReasoningHistoryLastStep only sends back the reasoning of the last
+	// assistant message.
+	ReasoningHistoryLastStep
+	// ReasoningHistoryNone doesn't send any reasoning back, except for
+	// signed reasoning of the last assistant message. See
+	// WithReasoningHistory.
+	ReasoningHistoryNone
+)
+
+// WithReasoningHistory sets which reasoning parts the agent sends back to
+// the model on subsequent steps. Resending long reasoning wastes input
+// tokens on models where it's optional.
+//
+// Reasoning of the last assistant message that carries provider options,
+// like Anthropic's thinking signatures, is always kept, since some
+// providers reject a tool use loop whose last thinking block went missing.
+func WithReasoningHistory(mode ReasoningHistoryMode) AgentOption {
+	return func(s *agentSettings) {
+		s.reasoningHistory = mode
+	}
+}
+
+// filterReasoningHistory drops the reasoning parts of messages that the
+// agent's ReasoningHistoryMode doesn't send back. The messages are left
+// untouched.
+func (a *agent) filterReasoningHistory(messages []Message) []Message {
+	if a.settings.reasoningHistory == ReasoningHistoryAll {
+		return messages
+	}
+	last := -1
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role == MessageRoleAssistant {
+			last = i
+			break
+		}
+	}
+	filtered := make([]Message, 0, len(messages))
+	for i, msg := range messages {
+		if msg.Role != MessageRoleAssistant {
+			filtered = append(filtered, msg)
+			continue
+		}
+		keepAll := i == last && a.settings.reasoningHistory == ReasoningHistoryLastStep
+		parts := make([]MessagePart, 0, len(msg.Content))
+		for _, part := range msg.Content {
+			if part.GetType() == ContentTypeReasoning && !keepAll && (i != last || len(part.Options()) == 0) {
+				continue
+			}
+			parts = append(parts, part)
+		}
+		if len(parts) == 0 {
+			continue
+		}
+		msg.Content = parts
+		filtered = append(filtered, msg)
+	}
+	return filtered
+}
@@ -0,0 +1,67 @@
+package fantasy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterReasoningHistory(t *testing.T) {
+	t.Parallel()
+
+	signed := ProviderOptions{"test": &testProviderOptions{}}
+	messages := []Message{
+		NewUserMessage("hi"),
+		{Role: MessageRoleAssistant, Content: []MessagePart{
+			ReasoningPart{Text: "first"},
+			TextPart{Text: "hello"},
+		}},
+		NewUserMessage("again"),
+		{Role: MessageRoleAssistant, Content: []MessagePart{
+			ReasoningPart{Text: "only reasoning", ProviderOptions: signed},
+		}},
+		{Role: MessageRoleAssistant, Content: []MessagePart{
+			ReasoningPart{Text: "unsigned"},
+			ReasoningPart{Text: "signed", ProviderOptions: signed},
+			ToolCallPart{ToolCallID: "call-1", ToolName: "echo", Input: "{}"},
+		}},
+		{Role: MessageRoleTool, Content: []MessagePart{
+			ToolResultPart{ToolCallID: "call-1", Output: ToolResultOutputContentText{Text: "ok"}},
+		}},
+	}
+	reasoning := func(messages []Message) []string {
+		var texts []string
+		for _, msg := range messages {
+			for _, part := range msg.Content {
+				if r, ok := AsMessagePart[ReasoningPart](part); ok {
+					texts = append(texts, r.Text)
+				}
+			}
+		}
+		return texts
+	}
+	filter := func(mode ReasoningHistoryMode) []Message {
+		a := NewAgent(&mockLanguageModel{}, WithReasoningHistory(mode)).(*agent)
+		return a.filterReasoningHistory(messages)
+	}
+
+	t.Run("all", func(t *testing.T) {
+		t.Parallel()
+		require.Equal(t, messages, filter(ReasoningHistoryAll))
+	})
+
+	t.Run("last step", func(t *testing.T) {
+		t.Parallel()
+		filtered := filter(ReasoningHistoryLastStep)
+		require.Equal(t, []string{"unsigned", "signed"}, reasoning(filtered))
+		require.Len(t, filtered, len(messages)-1)
+	})
+
+	t.Run("none keeps signed reasoning of the last message", func(t *testing.T) {
+		t.Parallel()
+		filtered := filter(ReasoningHistoryNone)
+		require.Equal(t, []string{"signed"}, reasoning(filtered))
+		require.Len(t, filtered, len(messages)-1)
+		require.Len(t, messages[1].Content, 2)
+	})
+}
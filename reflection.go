@@ -0,0 +1,139 @@
+package fantasy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	critiquePrompt = `You review answers. Point out factual errors, gaps and unclear parts in the assistant's final answer to the conversation below, as a list of concrete problems.
+
+If the answer needs no changes, reply with only APPROVED.`
+	revisePrompt = "A reviewer critiqued your answer:\n\n%s\n\nRevise your answer to address the critique. Reply with the complete revised answer."
+)
+
+// ErrReflectionUnsupported is returned by Stream for agents created
+// WithReflection, which only Generate supports.
+var ErrReflectionUnsupported = errors.New("reflection is not supported when streaming")
+
+type reflectionSettings struct {
+	model     LanguageModel
+	maxRounds int
+}
+
+// Reflection is one critique round of an agent created WithReflection.
+type Reflection struct {
+	// Draft is the response that was critiqued.
+	Draft Response
+	// Critique is the critique model's reply.
+	Critique string
+	// Approved reports whether the critique approved the draft, ending the
+	// reflection. Otherwise the agent revised the draft.
+	Approved bool
+	// Usage is the usage of the critique call.
+	Usage Usage
+}
+
+// WithReflection makes Generate critique its final answer with
+// critiqueModel and revise it, up to maxRounds times or until the critique
+// approves it. The critique model sees the user messages, the results of
+// the tools the agent called and the final answer, and its calls go through
+// the same retries, spend limits, debugger, audit and request group as the
+// agent's own. Revisions run as further steps of the agent, with its tools,
+// continuing from the prompt the call was prepared with, and all drafts and
+// critiques are recorded in AgentResult.Reflections. Stream returns
+// ErrReflectionUnsupported.
+func WithReflection(critiqueModel LanguageModel, maxRounds int) AgentOption {
+	return func(s *agentSettings) {
+		s.reflection = &reflectionSettings{model: critiqueModel, maxRounds: maxRounds}
+	}
+}
+
+func (a *agent) reflect(ctx context.Context, opts AgentCall, prepared *preparedPrompt, result *AgentResult) (*AgentResult, error) {
+	messages := slices.Clone(prepared.prompt)
+	newSteps := result.Steps
+
+	for range a.settings.reflection.maxRounds {
+		for _, step := range newSteps {
+			messages = append(messages, step.Messages...)
+		}
+
+		reflection, err := a.critique(ctx, opts, prepared.prompt, result.Steps, result.Response)
+		if err != nil {
+			return nil, err
+		}
+		result.Reflections = append(result.Reflections, reflection)
+		result.TotalUsage = addUsage(result.TotalUsage, reflection.Usage)
+		if reflection.Approved {
+			break
+		}
+
+		messages = append(messages, NewUserMessage(fmt.Sprintf(revisePrompt, reflection.Critique)))
+		revised, err := a.generateSteps(ctx, opts, &preparedPrompt{
+			system:      prepared.system,
+			prompt:      slices.Clone(messages),
+			toolCallIDs: prepared.toolCallIDs,
+		})
+		if err != nil {
+			return nil, err
+		}
+		newSteps = revised.Steps
+		result.Steps = append(result.Steps, revised.Steps...)
+		result.Response = revised.Response
+		result.TotalUsage = addUsage(result.TotalUsage, revised.TotalUsage)
+	}
+	return result, nil
+}
+
+// critique asks the critique model to review the draft answering the
+// user messages of the conversation, given the tool results of the steps.
+func (a *agent) critique(ctx context.Context, opts AgentCall, conversation []Message, steps []StepResult, draft Response) (Reflection, error) {
+	var transcript strings.Builder
+	for _, msg := range conversation {
+		if msg.Role != MessageRoleUser {
+			continue
+		}
+		for _, part := range msg.Content {
+			if text, ok := AsMessagePart[TextPart](part); ok {
+				fmt.Fprintf(&transcript, "user: %s\n", text.Text)
+			}
+		}
+	}
+	for _, step := range steps {
+		for _, result := range step.Content.ToolResults() {
+			fmt.Fprintf(&transcript, "tool %s: %s\n", result.ToolName, toolResultOutputText(result.Result))
+		}
+	}
+	fmt.Fprintf(&transcript, "\n<final answer>\n%s\n</final answer>\n", draft.Content.Text())
+
+	retryOptions := DefaultRetryOptions()
+	if opts.MaxRetries != nil {
+		retryOptions.MaxRetries = *opts.MaxRetries
+	}
+	retryOptions.OnRetry = opts.OnRetry
+	retryOptions.OnAuthRefresh = opts.OnAuthRefresh
+	retry := RetryWithExponentialBackoffRespectingRetryHeaders[*Response](retryOptions)
+	resp, err := retry(ctx, func() (*Response, error) {
+		return a.safeGenerate(ctx, a.settings.reflection.model, Call{
+			Prompt: Prompt{
+				{Role: MessageRoleSystem, Content: []MessagePart{TextPart{Text: critiquePrompt}}},
+				NewUserMessage(transcript.String()),
+			},
+			UserAgent: a.settings.userAgent,
+			Metadata:  CallMetadata(ctx),
+		})
+	})
+	if err != nil {
+		return Reflection{}, fmt.Errorf("critiquing answer: %w", err)
+	}
+	critique := strings.TrimSpace(resp.Content.Text())
+	return Reflection{
+		Draft:    draft,
+		Critique: critique,
+		Approved: strings.HasPrefix(strings.ToUpper(critique), "APPROVED"),
+		Usage:    resp.Usage,
+	}, nil
+}
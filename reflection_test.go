@@ -0,0 +1,155 @@
+package fantasy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReflection(t *testing.T) {
+	t.Parallel()
+
+	newModel := func(answers ...string) (*mockLanguageModel, *[]Call) {
+		var calls []Call
+		return &mockLanguageModel{
+			generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+				calls = append(calls, call)
+				return &Response{
+					Content:      ResponseContent{TextContent{Text: answers[len(calls)-1]}},
+					FinishReason: FinishReasonStop,
+					Usage:        Usage{OutputTokens: 10},
+				}, nil
+			},
+		}, &calls
+	}
+
+	t.Run("revises until approved", func(t *testing.T) {
+		t.Parallel()
+		model, calls := newModel("Paris is in Germany.", "Paris is in France.")
+		critic, critiques := newModel("The answer puts Paris in the wrong country.", "APPROVED")
+
+		result, err := NewAgent(model, WithReflection(critic, 3)).Generate(t.Context(), AgentCall{Prompt: "Where is Paris?"})
+		require.NoError(t, err)
+		require.Equal(t, "Paris is in France.", result.Response.Content.Text())
+		require.Len(t, result.Steps, 2)
+		require.Len(t, *critiques, 2)
+		require.Equal(t, int64(40), result.TotalUsage.OutputTokens)
+
+		require.Len(t, result.Reflections, 2)
+		require.Equal(t, "Paris is in Germany.", result.Reflections[0].Draft.Content.Text())
+		require.False(t, result.Reflections[0].Approved)
+		require.Equal(t, "Paris is in France.", result.Reflections[1].Draft.Content.Text())
+		require.True(t, result.Reflections[1].Approved)
+
+		transcript := (*critiques)[1].Prompt[1].Content[0].(TextPart).Text
+		require.Contains(t, transcript, "user: Where is Paris?")
+		require.NotContains(t, transcript, "reviewer")
+		require.Contains(t, transcript, "Paris is in France.")
+
+		revision := (*calls)[1].Prompt
+		require.Len(t, revision, 3)
+		require.Equal(t, MessageRoleAssistant, revision[1].Role)
+		require.Contains(t, revision[2].Content[0].(TextPart).Text, "wrong country")
+	})
+
+	t.Run("stops after max rounds", func(t *testing.T) {
+		t.Parallel()
+		model, calls := newModel("draft 1", "draft 2")
+		critic, _ := newModel("too short", "still too short")
+
+		result, err := NewAgent(model, WithReflection(critic, 1)).Generate(t.Context(), AgentCall{Prompt: "write"})
+		require.NoError(t, err)
+		require.Len(t, *calls, 2)
+		require.Len(t, result.Reflections, 1)
+		require.Equal(t, "draft 2", result.Response.Content.Text())
+	})
+
+	t.Run("critic sees tool results", func(t *testing.T) {
+		t.Parallel()
+		var steps int
+		model := &mockLanguageModel{
+			generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+				steps++
+				if steps == 1 {
+					return &Response{
+						Content:      ResponseContent{ToolCallContent{ToolCallID: "call-1", ToolName: "weather", Input: `{}`}},
+						FinishReason: FinishReasonToolCalls,
+					}, nil
+				}
+				return &Response{Content: ResponseContent{TextContent{Text: "It is sunny."}}, FinishReason: FinishReasonStop}, nil
+			},
+		}
+		weather := &mockTool{
+			name: "weather",
+			executeFunc: func(ctx context.Context, call ToolCall) (ToolResponse, error) {
+				return NewTextResponse("rain, 12°C"), nil
+			},
+		}
+		critic, critiques := newModel("APPROVED")
+
+		_, err := NewAgent(model, WithTools(weather), WithReflection(critic, 1)).Generate(t.Context(), AgentCall{Prompt: "What's the weather?"})
+		require.NoError(t, err)
+		require.Len(t, *critiques, 1)
+		transcript := (*critiques)[0].Prompt[1].Content[0].(TextPart).Text
+		require.Contains(t, transcript, "tool weather: rain, 12°C")
+		require.Contains(t, transcript, "It is sunny.")
+	})
+
+	t.Run("critiques respect spend limits", func(t *testing.T) {
+		t.Parallel()
+		model, _ := newModel("draft")
+		critic, critiques := newModel("APPROVED")
+
+		agent := NewAgent(model,
+			WithReflection(critic, 1),
+			// The draft alone reaches the limit.
+			WithSpendLimit(1, time.Hour, nil),
+			WithCostFunction(PricingTable(map[string]Pricing{"mock-model": {Output: 100_000}})),
+		)
+		_, err := agent.Generate(t.Context(), AgentCall{Prompt: "write"})
+		var limitErr *SpendLimitError
+		require.ErrorAs(t, err, &limitErr)
+		require.Empty(t, *critiques)
+	})
+
+	t.Run("revisions reuse the prepared prompt", func(t *testing.T) {
+		t.Parallel()
+		model, calls := newModel("draft 1", "draft 2")
+		critic, _ := newModel("too short", "APPROVED")
+		var systemPrompts, inputChecks int
+		agent := NewAgent(model,
+			WithReflection(critic, 2),
+			WithSystemPromptFunc(func(ctx context.Context, call AgentCall) (string, error) {
+				systemPrompts++
+				return "Be brief.", nil
+			}),
+			WithGuardrails(GuardrailFunc(func(ctx context.Context, stage GuardrailStage, text string) (string, error) {
+				if stage == GuardrailStageInput {
+					inputChecks++
+				}
+				return text, nil
+			})),
+		)
+
+		_, err := agent.Generate(t.Context(), AgentCall{Prompt: "write"})
+		require.NoError(t, err)
+		require.Len(t, *calls, 2)
+		require.Equal(t, 1, systemPrompts)
+		require.Equal(t, 1, inputChecks)
+		revision := (*calls)[1].Prompt
+		require.Len(t, revision, 4)
+		require.Equal(t, MessageRoleSystem, revision[0].Role)
+	})
+
+	t.Run("stream is not supported", func(t *testing.T) {
+		t.Parallel()
+		model, calls := newModel("draft")
+		critic, _ := newModel("APPROVED")
+
+		_, err := NewAgent(model, WithReflection(critic, 1)).Stream(t.Context(), AgentStreamCall{Prompt: "write"})
+		require.ErrorIs(t, err, ErrReflectionUnsupported)
+		require.Empty(t, *calls)
+	})
+}
@@ -0,0 +1,162 @@
+package fantasy
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// Registry maps aliases such as "fast", "smart" or "cheap" to language
+// models, so apps can swap models from configuration without touching agent
+// code. It is safe for concurrent use.
+type Registry struct {
+	mu     sync.RWMutex
+	models map[string]LanguageModel
+}
+
+// ModelNotFoundError is returned when an alias has no registered model.
+type ModelNotFoundError struct {
+	Alias string
+}
+
+func (e *ModelNotFoundError) Error() string {
+	return fmt.Sprintf("no model registered for alias %q", e.Alias)
+}
+
+// NewRegistry creates an empty model registry.
+func NewRegistry() *Registry {
+	return &Registry{models: map[string]LanguageModel{}}
+}
+
+// Register registers the model under the alias, replacing any model
+// previously registered under it. Replacing an alias is how per-environment
+// overrides are applied.
+func (r *Registry) Register(alias string, model LanguageModel) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.models[alias] = model
+}
+
+// RegisterProviderModel creates the model from the provider and registers it
+// under the alias.
+func (r *Registry) RegisterProviderModel(ctx context.Context, alias string, provider Provider, modelID string) error {
+	model, err := provider.LanguageModel(ctx, modelID)
+	if err != nil {
+		return fmt.Errorf("alias %q: %w", alias, err)
+	}
+	r.Register(alias, model)
+	return nil
+}
+
+// Unregister removes the alias.
+func (r *Registry) Unregister(alias string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.models, alias)
+}
+
+// Lookup returns the model currently registered under the alias, or a
+// *ModelNotFoundError.
+func (r *Registry) Lookup(alias string) (LanguageModel, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	model, ok := r.models[alias]
+	if !ok {
+		return nil, &ModelNotFoundError{Alias: alias}
+	}
+	return model, nil
+}
+
+// Aliases returns the registered aliases in sorted order.
+func (r *Registry) Aliases() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	aliases := make([]string, 0, len(r.models))
+	for alias := range r.models {
+		aliases = append(aliases, alias)
+	}
+	slices.Sort(aliases)
+	return aliases
+}
+
+// Model returns a language model that resolves the alias on every call, so
+// an agent built with it picks up models registered after its construction.
+// Calls fail with a *ModelNotFoundError while the alias is unregistered.
+// The model has the optional capabilities of the registered model, such as
+// PDFModel; it always implements TokenCounter, which fails for models that
+// can't count tokens.
+func (r *Registry) Model(alias string) LanguageModel {
+	model := &aliasModel{registry: r, alias: alias}
+	return tokenCountingModel{capabilityModel{LanguageModel: model, capabilities: model}}
+}
+
+type aliasModel struct {
+	registry *Registry
+	alias    string
+}
+
+// Generate implements LanguageModel.
+func (m *aliasModel) Generate(ctx context.Context, call Call) (*Response, error) {
+	model, err := m.registry.Lookup(m.alias)
+	if err != nil {
+		return nil, err
+	}
+	return model.Generate(ctx, call)
+}
+
+// Stream implements LanguageModel.
+func (m *aliasModel) Stream(ctx context.Context, call Call) (StreamResponse, error) {
+	model, err := m.registry.Lookup(m.alias)
+	if err != nil {
+		return nil, err
+	}
+	return model.Stream(ctx, call)
+}
+
+// GenerateObject implements LanguageModel.
+func (m *aliasModel) GenerateObject(ctx context.Context, call ObjectCall) (*ObjectResponse, error) {
+	model, err := m.registry.Lookup(m.alias)
+	if err != nil {
+		return nil, err
+	}
+	return model.GenerateObject(ctx, call)
+}
+
+// StreamObject implements LanguageModel.
+func (m *aliasModel) StreamObject(ctx context.Context, call ObjectCall) (ObjectStreamResponse, error) {
+	model, err := m.registry.Lookup(m.alias)
+	if err != nil {
+		return nil, err
+	}
+	return model.StreamObject(ctx, call)
+}
+
+// Provider implements LanguageModel. It returns the provider of the model
+// currently registered under the alias, or an empty string.
+func (m *aliasModel) Provider() string {
+	model, err := m.registry.Lookup(m.alias)
+	if err != nil {
+		return ""
+	}
+	return model.Provider()
+}
+
+// Model implements LanguageModel. It returns the ID of the model currently
+// registered under the alias, or the alias itself.
+func (m *aliasModel) Model() string {
+	model, err := m.registry.Lookup(m.alias)
+	if err != nil {
+		return m.alias
+	}
+	return model.Model()
+}
+
+func (m *aliasModel) capable() (LanguageModel, bool) {
+	model, err := m.registry.Lookup(m.alias)
+	return model, err == nil
+}
+
+func (m *aliasModel) counter(context.Context) (LanguageModel, error) {
+	return m.registry.Lookup(m.alias)
+}
@@ -0,0 +1,61 @@
+package fantasy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Parallel()
+
+	registry := NewRegistry()
+	agent := NewAgent(registry.Model("fast"))
+
+	// The alias is resolved per call, so an agent built before registration
+	// fails until a model is registered.
+	_, err := agent.Generate(t.Context(), AgentCall{Prompt: "Hi"})
+	var notFound *ModelNotFoundError
+	require.ErrorAs(t, err, &notFound)
+	require.Equal(t, "fast", notFound.Alias)
+
+	var used []string
+	model := func(text string) LanguageModel {
+		return &mockLanguageModel{generateFunc: func(context.Context, Call) (*Response, error) {
+			used = append(used, text)
+			return &Response{Content: []Content{TextContent{Text: text}}, FinishReason: FinishReasonStop}, nil
+		}}
+	}
+	registry.Register("fast", model("small"))
+	registry.Register("smart", model("large"))
+	require.Equal(t, []string{"fast", "smart"}, registry.Aliases())
+
+	result, err := agent.Generate(t.Context(), AgentCall{Prompt: "Hi"})
+	require.NoError(t, err)
+	require.Equal(t, "small", result.Response.Content.Text())
+
+	// Overriding the alias swaps the model without rebuilding the agent.
+	registry.Register("fast", model("override"))
+	result, err = agent.Generate(t.Context(), AgentCall{Prompt: "Hi"})
+	require.NoError(t, err)
+	require.Equal(t, "override", result.Response.Content.Text())
+	require.Equal(t, []string{"small", "override"}, used)
+
+	require.Equal(t, "mock-provider", registry.Model("smart").Provider())
+	registry.Unregister("smart")
+	require.Equal(t, "smart", registry.Model("smart").Model())
+}
+
+func TestRegistry_ModelCapabilities(t *testing.T) {
+	t.Parallel()
+
+	registry := NewRegistry()
+	model := registry.Model("fast")
+	pdf, ok := model.(PDFModel)
+	require.True(t, ok)
+	require.False(t, pdf.SupportsPDF(), "unregistered aliases have no capabilities")
+
+	registry.Register("fast", &capableMockModel{})
+	requireCapabilities(t, model)
+}
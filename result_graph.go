@@ -0,0 +1,159 @@
+package fantasy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// graphNodeKind is the kind of a node of the run graph.
+type graphNodeKind int
+
+const (
+	graphNodeStep graphNodeKind = iota
+	graphNodeTool
+	graphNodeToolError
+	graphNodeTotal
+)
+
+type graphNode struct {
+	id    string
+	kind  graphNodeKind
+	lines []string
+}
+
+type graphEdge struct {
+	from, to string
+}
+
+// ToMermaid renders the steps and tool calls of the run as a Mermaid
+// flowchart, with the tokens used by each step and returned by each tool.
+func (r *AgentResult) ToMermaid() string {
+	nodes, edges := r.graph()
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+	for _, node := range nodes {
+		label := mermaidLabel(node.lines)
+		switch node.kind {
+		case graphNodeStep:
+			fmt.Fprintf(&b, "    %s[%s]\n", node.id, label)
+		case graphNodeTool, graphNodeToolError:
+			fmt.Fprintf(&b, "    %s([%s])\n", node.id, label)
+		case graphNodeTotal:
+			fmt.Fprintf(&b, "    %s((%s))\n", node.id, label)
+		}
+	}
+	for _, edge := range edges {
+		fmt.Fprintf(&b, "    %s --> %s\n", edge.from, edge.to)
+	}
+	for _, node := range nodes {
+		if node.kind == graphNodeToolError {
+			fmt.Fprintf(&b, "    style %s stroke:#d33,stroke-width:2px\n", node.id)
+		}
+	}
+	return b.String()
+}
+
+// ToDOT renders the steps and tool calls of the run as a Graphviz DOT
+// digraph, with the tokens used by each step and returned by each tool.
+func (r *AgentResult) ToDOT() string {
+	nodes, edges := r.graph()
+	var b strings.Builder
+	b.WriteString("digraph agent {\n")
+	b.WriteString("    node [shape=box];\n")
+	for _, node := range nodes {
+		attrs := ""
+		switch node.kind {
+		case graphNodeTool:
+			attrs = ", shape=ellipse"
+		case graphNodeToolError:
+			attrs = ", shape=ellipse, color=red"
+		case graphNodeTotal:
+			attrs = ", shape=doublecircle"
+		}
+		fmt.Fprintf(&b, "    %s [label=%s%s];\n", node.id, dotLabel(node.lines), attrs)
+	}
+	for _, edge := range edges {
+		fmt.Fprintf(&b, "    %s -> %s;\n", edge.from, edge.to)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// graph returns a node per step, per tool call and for the totals of the run.
+// Steps lead to the tools they called, which lead to the next step.
+func (r *AgentResult) graph() ([]graphNode, []graphEdge) {
+	var (
+		nodes []graphNode
+		edges []graphEdge
+		// prev are the nodes leading to the next step.
+		prev []string
+	)
+	for i, step := range r.Steps {
+		id := fmt.Sprintf("step%d", i+1)
+		lines := []string{fmt.Sprintf("Step %d", i+1), tokenLine(step.Usage)}
+		if step.FinishReason != "" {
+			lines = append(lines, string(step.FinishReason))
+		}
+		nodes = append(nodes, graphNode{id: id, kind: graphNodeStep, lines: lines})
+		for _, from := range prev {
+			edges = append(edges, graphEdge{from: from, to: id})
+		}
+		prev = []string{id}
+
+		toolCalls := step.Content.ToolCalls()
+		if len(toolCalls) == 0 {
+			continue
+		}
+		results := map[string]ToolResultContent{}
+		for _, result := range step.Content.ToolResults() {
+			results[result.ToolCallID] = result
+		}
+		prev = prev[:0]
+		for j, call := range toolCalls {
+			toolID := fmt.Sprintf("%s_tool%d", id, j+1)
+			node := graphNode{id: toolID, kind: graphNodeTool, lines: []string{call.ToolName}}
+			if result, ok := results[call.ToolCallID]; ok {
+				if result.Result != nil && result.Result.GetType() == ToolResultContentTypeError {
+					node.kind = graphNodeToolError
+				}
+				node.lines = append(node.lines, fmt.Sprintf("~%d result tokens", EstimateTokens(toolResultOutputText(result.Result))))
+			}
+			nodes = append(nodes, node)
+			edges = append(edges, graphEdge{from: id, to: toolID})
+			prev = append(prev, toolID)
+		}
+	}
+	nodes = append(nodes, graphNode{id: "total", kind: graphNodeTotal, lines: []string{"Total", tokenLine(r.TotalUsage)}})
+	for _, from := range prev {
+		edges = append(edges, graphEdge{from: from, to: "total"})
+	}
+	return nodes, edges
+}
+
+func tokenLine(usage Usage) string {
+	line := fmt.Sprintf("%d in / %d out tokens", usage.InputTokens, usage.OutputTokens)
+	if usage.CacheReadTokens > 0 {
+		line += fmt.Sprintf(", %d cached", usage.CacheReadTokens)
+	}
+	return line
+}
+
+var mermaidEscaper = strings.NewReplacer(`"`, "#quot;", "<", "#lt;", ">", "#gt;")
+
+func mermaidLabel(lines []string) string {
+	escaped := make([]string, len(lines))
+	for i, line := range lines {
+		escaped[i] = mermaidEscaper.Replace(line)
+	}
+	return `"` + strings.Join(escaped, "<br/>") + `"`
+}
+
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func dotLabel(lines []string) string {
+	escaped := make([]string, len(lines))
+	for i, line := range lines {
+		escaped[i] = dotEscaper.Replace(line)
+	}
+	return `"` + strings.Join(escaped, `\n`) + `"`
+}
@@ -0,0 +1,78 @@
+package fantasy
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func graphTestResult() *AgentResult {
+	return &AgentResult{
+		Steps: []StepResult{
+			{Response: Response{
+				Content: ResponseContent{
+					ToolCallContent{ToolCallID: "call-1", ToolName: "lookup"},
+					ToolCallContent{ToolCallID: "call-2", ToolName: "fetch"},
+					ToolResultContent{ToolCallID: "call-1", ToolName: "lookup", Result: ToolResultOutputContentText{Text: "found it"}},
+					ToolResultContent{ToolCallID: "call-2", ToolName: "fetch", Result: ToolResultOutputContentError{Error: errors.New(`"404"`)}},
+				},
+				FinishReason: FinishReasonToolCalls,
+				Usage:        Usage{InputTokens: 10, OutputTokens: 5},
+			}},
+			{Response: Response{
+				Content:      ResponseContent{TextContent{Text: "done"}},
+				FinishReason: FinishReasonStop,
+				Usage:        Usage{InputTokens: 20, OutputTokens: 2, CacheReadTokens: 8},
+			}},
+		},
+		TotalUsage: Usage{InputTokens: 30, OutputTokens: 7, CacheReadTokens: 8},
+	}
+}
+
+func TestAgentResult_ToMermaid(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, `flowchart TD
+    step1["Step 1<br/>10 in / 5 out tokens<br/>tool-calls"]
+    step1_tool1(["lookup<br/>~2 result tokens"])
+    step1_tool2(["fetch<br/>~2 result tokens"])
+    step2["Step 2<br/>20 in / 2 out tokens, 8 cached<br/>stop"]
+    total(("Total<br/>30 in / 7 out tokens, 8 cached"))
+    step1 --> step1_tool1
+    step1 --> step1_tool2
+    step1_tool1 --> step2
+    step1_tool2 --> step2
+    step2 --> total
+    style step1_tool2 stroke:#d33,stroke-width:2px
+`, graphTestResult().ToMermaid())
+}
+
+func TestAgentResult_ToDOT(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, `digraph agent {
+    node [shape=box];
+    step1 [label="Step 1\n10 in / 5 out tokens\ntool-calls"];
+    step1_tool1 [label="lookup\n~2 result tokens", shape=ellipse];
+    step1_tool2 [label="fetch\n~2 result tokens", shape=ellipse, color=red];
+    step2 [label="Step 2\n20 in / 2 out tokens, 8 cached\nstop"];
+    total [label="Total\n30 in / 7 out tokens, 8 cached", shape=doublecircle];
+    step1 -> step1_tool1;
+    step1 -> step1_tool2;
+    step1_tool1 -> step2;
+    step1_tool2 -> step2;
+    step2 -> total;
+}
+`, graphTestResult().ToDOT())
+}
+
+func TestAgentResult_GraphEscaping(t *testing.T) {
+	t.Parallel()
+
+	result := &AgentResult{Steps: []StepResult{{Response: Response{
+		Content: ResponseContent{ToolCallContent{ToolCallID: "call-1", ToolName: `say "hi" <now>`}},
+	}}}}
+	require.Contains(t, result.ToMermaid(), `step1_tool1(["say #quot;hi#quot; #lt;now#gt;"])`)
+	require.Contains(t, result.ToDOT(), `step1_tool1 [label="say \"hi\" <now>", shape=ellipse];`)
+}
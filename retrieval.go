@@ -0,0 +1,151 @@
+package fantasy
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+)
+
+// RetrievalToolName is the name of the tool created by RetrievalTool.
+const RetrievalToolName = "retrieve"
+
+// RetrievedDocument is a document found by a Retriever.
+type RetrievedDocument struct {
+	ID    string `json:"id"`
+	Title string `json:"title,omitempty"`
+	URL   string `json:"url,omitempty"`
+	Text  string `json:"text"`
+	// Score is how relevant the document is to the query. Higher is more
+	// relevant.
+	Score float32 `json:"score"`
+}
+
+// Retriever finds the documents most relevant to a query, such as the
+// chunks of a vector store. The vectorstore package's Index is a Retriever.
+type Retriever interface {
+	// Retrieve returns up to k documents, most relevant first.
+	Retrieve(ctx context.Context, query string, k int) ([]RetrievedDocument, error)
+}
+
+// RetrieverFunc adapts a function to a Retriever.
+type RetrieverFunc func(ctx context.Context, query string, k int) ([]RetrievedDocument, error)
+
+// Retrieve implements Retriever.
+func (f RetrieverFunc) Retrieve(ctx context.Context, query string, k int) ([]RetrievedDocument, error) {
+	return f(ctx, query, k)
+}
+
+type retrievalInput struct {
+	Query string `json:"query" description:"What to search for, in natural language"`
+}
+
+// RetrievalTool creates a tool that lets the model search the retriever and
+// returns the k most relevant documents, numbered and with their titles and
+// URLs so the model can cite them.
+func RetrievalTool(retriever Retriever, k int) AgentTool {
+	return NewParallelAgentTool(RetrievalToolName,
+		"Search the knowledge base for documents relevant to a query.",
+		func(ctx context.Context, input retrievalInput, _ ToolCall) (ToolResponse, error) {
+			if strings.TrimSpace(input.Query) == "" {
+				return NewTextErrorResponse("query is required"), nil
+			}
+			docs, err := retriever.Retrieve(ctx, input.Query, k)
+			if err != nil {
+				return ToolResponse{}, err
+			}
+			if len(docs) == 0 {
+				return NewTextResponse("No relevant documents found."), nil
+			}
+			return NewTextResponse(formatRetrievedDocuments(docs)), nil
+		})
+}
+
+const retrievedContextPrompt = "Use the following documents to answer if they are relevant, and cite them by number, like [1].\n\n"
+
+// retrievedContextKey keys the documents retrieved for a run in its
+// context. Each WithRetrievedContext has its own key.
+type retrievedContextKey struct {
+	id uint64
+}
+
+var retrievedContextKeys atomic.Uint64
+
+// WithRetrievedContext creates a PrepareStepFunction that retrieves the k
+// documents most relevant to the last user message and adds them to that
+// message as context, for use with WithPrepareStep or
+// AgentCall.PrepareStep. Retrieval happens once per run, before the first
+// step, and the same documents are added to every step. They are reported
+// as SourceContent in the first step's content.
+func WithRetrievedContext(retriever Retriever, k int) PrepareStepFunction {
+	key := retrievedContextKey{id: retrievedContextKeys.Add(1)}
+	return func(ctx context.Context, options PrepareStepFunctionOptions) (context.Context, PrepareStepResult, error) {
+		userIndex := -1
+		for i, msg := range slices.Backward(options.Messages) {
+			if msg.Role == MessageRoleUser {
+				userIndex = i
+				break
+			}
+		}
+		if userIndex < 0 {
+			return ctx, PrepareStepResult{}, nil
+		}
+
+		docs, retrieved := ctx.Value(key).([]RetrievedDocument)
+		firstStep := !retrieved
+		if !retrieved {
+			var query strings.Builder
+			for _, part := range options.Messages[userIndex].Content {
+				if text, ok := AsMessagePart[TextPart](part); ok {
+					query.WriteString(text.Text)
+				}
+			}
+			if strings.TrimSpace(query.String()) != "" {
+				var err error
+				docs, err = retriever.Retrieve(ctx, query.String(), k)
+				if err != nil {
+					return ctx, PrepareStepResult{}, fmt.Errorf("retrieving context: %w", err)
+				}
+			}
+			ctx = context.WithValue(ctx, key, docs)
+		}
+		if len(docs) == 0 {
+			return ctx, PrepareStepResult{}, nil
+		}
+
+		messages := slices.Clone(options.Messages)
+		msg := messages[userIndex]
+		msg.Content = append([]MessagePart{TextPart{Text: retrievedContextPrompt + formatRetrievedDocuments(docs)}}, msg.Content...)
+		messages[userIndex] = msg
+		result := PrepareStepResult{Messages: messages}
+		if firstStep {
+			for _, doc := range docs {
+				source := SourceContent{SourceType: SourceTypeDocument, ID: doc.ID, Title: doc.Title, MediaType: "text/plain"}
+				if doc.URL != "" {
+					source = SourceContent{SourceType: SourceTypeURL, ID: doc.ID, Title: doc.Title, URL: doc.URL}
+				}
+				result.Sources = append(result.Sources, source)
+			}
+		}
+		return ctx, result, nil
+	}
+}
+
+func formatRetrievedDocuments(docs []RetrievedDocument) string {
+	var sb strings.Builder
+	for i, doc := range docs {
+		if i > 0 {
+			sb.WriteString("\n\n")
+		}
+		fmt.Fprintf(&sb, "[%d]", i+1)
+		if doc.Title != "" {
+			sb.WriteString(" " + doc.Title)
+		}
+		if doc.URL != "" {
+			fmt.Fprintf(&sb, " (%s)", doc.URL)
+		}
+		sb.WriteString("\n" + doc.Text)
+	}
+	return sb.String()
+}
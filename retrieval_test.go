@@ -0,0 +1,105 @@
+package fantasy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testRetriever = RetrieverFunc(func(ctx context.Context, query string, k int) ([]RetrievedDocument, error) {
+	docs := []RetrievedDocument{
+		{ID: "refunds", Title: "Refund policy", URL: "https://example.com/refunds", Text: "Refunds take 5 days."},
+		{ID: "shipping", Text: "Shipping is free."},
+	}
+	return docs[:min(k, len(docs))], nil
+})
+
+func TestRetrievalTool(t *testing.T) {
+	t.Parallel()
+
+	tool := RetrievalTool(testRetriever, 2)
+	resp, err := tool.Run(t.Context(), ToolCall{ID: "1", Name: RetrievalToolName, Input: `{"query":"refunds"}`})
+	require.NoError(t, err)
+	require.Equal(t, "[1] Refund policy (https://example.com/refunds)\nRefunds take 5 days.\n\n[2]\nShipping is free.", resp.Content)
+
+	resp, err = tool.Run(t.Context(), ToolCall{ID: "2", Name: RetrievalToolName, Input: `{"query":" "}`})
+	require.NoError(t, err)
+	require.True(t, resp.IsError)
+}
+
+func TestWithRetrievedContext(t *testing.T) {
+	t.Parallel()
+
+	retrievals := 0
+	retriever := RetrieverFunc(func(ctx context.Context, query string, k int) ([]RetrievedDocument, error) {
+		retrievals++
+		require.Equal(t, "how long do refunds take?", query)
+		return testRetriever(ctx, query, k)
+	})
+
+	var prompts []Prompt
+	model := &mockLanguageModel{
+		generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+			prompts = append(prompts, call.Prompt)
+			if len(prompts) == 1 {
+				return &Response{
+					Content:      []Content{ToolCallContent{ToolCallID: "call-1", ToolName: "noop", Input: `{}`}},
+					FinishReason: FinishReasonToolCalls,
+				}, nil
+			}
+			return &Response{Content: []Content{TextContent{Text: "5 days [1]"}}, FinishReason: FinishReasonStop}, nil
+		},
+	}
+	noop := &mockTool{
+		name: "noop",
+		executeFunc: func(ctx context.Context, call ToolCall) (ToolResponse, error) {
+			return NewTextResponse("ok"), nil
+		},
+	}
+
+	agent := NewAgent(model, WithTools(noop), WithPrepareStep(WithRetrievedContext(retriever, 1)))
+	result, err := agent.Generate(t.Context(), AgentCall{Prompt: "how long do refunds take?"})
+	require.NoError(t, err)
+	require.Equal(t, 1, retrievals)
+	require.Len(t, prompts, 2)
+
+	for _, prompt := range prompts {
+		parts := prompt[0].Content
+		require.Len(t, parts, 2)
+		require.Equal(t, retrievedContextPrompt+"[1] Refund policy (https://example.com/refunds)\nRefunds take 5 days.", parts[0].(TextPart).Text)
+		require.Equal(t, "how long do refunds take?", parts[1].(TextPart).Text)
+	}
+
+	// The context is added for each step only, not to the history.
+	require.Len(t, result.Steps[0].Messages, 2)
+	require.Equal(t, []SourceContent{{SourceType: SourceTypeURL, ID: "refunds", Title: "Refund policy", URL: "https://example.com/refunds"}},
+		result.Steps[0].Content.Sources())
+	require.Empty(t, result.Steps[1].Content.Sources())
+}
+
+func TestWithRetrievedContextStream(t *testing.T) {
+	t.Parallel()
+
+	model := &mockLanguageModel{
+		streamFunc: func(ctx context.Context, call Call) (StreamResponse, error) {
+			return func(yield func(StreamPart) bool) {
+				yield(StreamPart{Type: StreamPartTypeFinish, FinishReason: FinishReasonStop})
+			}, nil
+		},
+	}
+
+	var sources []SourceContent
+	agent := NewAgent(model, WithPrepareStep(WithRetrievedContext(testRetriever, 2)))
+	result, err := agent.Stream(t.Context(), AgentStreamCall{
+		Prompt: "shipping?",
+		OnSource: func(source SourceContent) error {
+			sources = append(sources, source)
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, sources, 2)
+	require.Equal(t, SourceContent{SourceType: SourceTypeDocument, ID: "shipping", MediaType: "text/plain"}, sources[1])
+	require.Equal(t, sources, result.Steps[0].Content.Sources())
+}
@@ -177,10 +177,15 @@ func isAuthError(err *ProviderError) bool {
 
 // isRetryableError reports whether the error should be retried.
 // It checks for retryable ProviderError, network-level connection errors
-// (DNS failures, TCP timeouts, connection refused), and HTTP/2 stream-
-// level transport errors. The latter two categories may not be wrapped
-// in ProviderError when they occur outside the provider's error handler.
+// (DNS failures, TCP timeouts, connection refused), HTTP/2 stream-
+// level transport errors and stalled streams. Network and transport errors
+// may not be wrapped in ProviderError when they occur outside the
+// provider's error handler.
 func isRetryableError(err error) bool {
+	var fatalErr *ToolFatalError
+	if errors.As(err, &fatalErr) {
+		return false
+	}
 	var providerErr *ProviderError
 	if errors.As(err, &providerErr) {
 		return providerErr.IsRetryable()
@@ -188,6 +193,10 @@ func isRetryableError(err error) bool {
 	if isAbortError(err) {
 		return false
 	}
+	var stalledErr *StreamStalledError
+	if errors.As(err, &stalledErr) {
+		return true
+	}
 	var netErr net.Error
 	if errors.As(err, &netErr) {
 		return true
@@ -8,6 +8,7 @@ import (
 	"fmt"
 	"reflect"
 	"slices"
+	"strconv"
 	"strings"
 
 	"charm.land/fantasy/jsonrepair"
@@ -40,6 +41,8 @@ type Schema struct {
 	Maximum     *float64           `json:"maximum,omitempty"`
 	MinLength   *int               `json:"minLength,omitempty"`
 	MaxLength   *int               `json:"maxLength,omitempty"`
+	Pattern     string             `json:"pattern,omitempty"`
+	Default     any                `json:"default,omitempty"`
 }
 
 // ParseState represents the state of JSON parsing.
@@ -164,6 +167,8 @@ func generateSchemaRecursive(t reflect.Type, visited map[reflect.Type]bool) Sche
 				}
 			}
 
+			applyConstraintTags(&fieldSchema, field.Tag)
+
 			schema.Properties[fieldName] = &fieldSchema
 
 			if required {
@@ -179,6 +184,66 @@ func generateSchemaRecursive(t reflect.Type, visited map[reflect.Type]bool) Sche
 	}
 }
 
+// applyConstraintTags sets the constraints given by the minimum, maximum,
+// minLength, maxLength, pattern, format and default struct tags. Tags whose
+// value can't be parsed are ignored.
+func applyConstraintTags(schema *Schema, tag reflect.StructTag) {
+	if v, ok := tag.Lookup("minimum"); ok {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			schema.Minimum = &f
+		}
+	}
+	if v, ok := tag.Lookup("maximum"); ok {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			schema.Maximum = &f
+		}
+	}
+	if v, ok := tag.Lookup("minLength"); ok {
+		if n, err := strconv.Atoi(v); err == nil {
+			schema.MinLength = &n
+		}
+	}
+	if v, ok := tag.Lookup("maxLength"); ok {
+		if n, err := strconv.Atoi(v); err == nil {
+			schema.MaxLength = &n
+		}
+	}
+	if v := tag.Get("pattern"); v != "" {
+		schema.Pattern = v
+	}
+	if v := tag.Get("format"); v != "" {
+		schema.Format = v
+	}
+	if v, ok := tag.Lookup("default"); ok {
+		schema.Default = parseDefault(v, schema.Type)
+	}
+}
+
+// parseDefault converts a default tag value to the schema's type, falling
+// back to the raw string.
+func parseDefault(value, typ string) any {
+	switch typ {
+	case "integer":
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	case "array", "object":
+		var v any
+		if err := json.Unmarshal([]byte(value), &v); err == nil {
+			return v
+		}
+	}
+	return value
+}
+
 // ToMap converts a Schema to a map representation suitable for JSON Schema.
 func ToMap(schema Schema) map[string]any {
 	result := make(map[string]any)
@@ -215,6 +280,14 @@ func ToMap(schema Schema) map[string]any {
 		result["maxLength"] = *schema.MaxLength
 	}
 
+	if schema.Pattern != "" {
+		result["pattern"] = schema.Pattern
+	}
+
+	if schema.Default != nil {
+		result["default"] = schema.Default
+	}
+
 	if schema.Properties != nil {
 		props := make(map[string]any)
 		for name, propSchema := range schema.Properties {
@@ -298,6 +371,9 @@ func ParseAndValidate(text string, schema Schema) (any, error) {
 	}
 
 	if err := validateAgainstSchema(obj, schema); err != nil {
+		if coerced, ok := coerceToSchema(text, schema); ok {
+			return coerced, nil
+		}
 		return nil, &ParseError{
 			RawText:         text,
 			ValidationError: err,
@@ -307,7 +383,28 @@ func ParseAndValidate(text string, schema Schema) (any, error) {
 	return obj, nil
 }
 
-// ValidateAgainstSchema validates a parsed object against a Schema.
+// coerceToSchema repairs the text again, resolving ambiguous values with the
+// types the schema expects and dropping nulls given for optional properties,
+// as strict structured outputs produce, and returns the result if it
+// validates.
+func coerceToSchema(text string, schema Schema) (any, bool) {
+	repaired, err := jsonrepair.RepairJSON(text, jsonrepair.WithSchema(ToMap(schema)))
+	if err != nil || repaired == "" {
+		return nil, false
+	}
+	var obj any
+	if err := json.Unmarshal([]byte(repaired), &obj); err != nil {
+		return nil, false
+	}
+	dropOptionalNulls(obj, &schema)
+	if validateAgainstSchema(obj, schema) != nil {
+		return nil, false
+	}
+	return obj, true
+}
+
+// ValidateAgainstSchema validates a parsed object against a Schema. A failed
+// validation is reported as a *ValidationError.
 func ValidateAgainstSchema(obj any, schema Schema) error {
 	return validateAgainstSchema(obj, schema)
 }
@@ -326,11 +423,7 @@ func validateAgainstSchema(obj any, schema Schema) error {
 
 	result := validator.Validate(obj)
 	if !result.IsValid() {
-		var errMsgs []string
-		for field, validationErr := range result.Errors {
-			errMsgs = append(errMsgs, fmt.Sprintf("%s: %s", field, validationErr.Message))
-		}
-		return fmt.Errorf("validation failed: %s", strings.Join(errMsgs, "; "))
+		return newValidationError(result)
 	}
 
 	return nil
@@ -350,6 +443,9 @@ func ParseAndValidateWithRepair(
 		if validationErr == nil {
 			return obj, nil
 		}
+		if coerced, ok := coerceToSchema(text, schema); ok {
+			return coerced, nil
+		}
 
 		if repair != nil {
 			repairedText, repairErr := repair(ctx, text, validationErr)
@@ -391,6 +487,26 @@ func ParseAndValidateWithRepair(
 	}
 }
 
+func dropOptionalNulls(value any, schema *Schema) {
+	if schema == nil {
+		return
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			if item == nil && !slices.Contains(schema.Required, key) {
+				delete(v, key)
+				continue
+			}
+			dropOptionalNulls(item, schema.Properties[key])
+		}
+	case []any:
+		for _, item := range v {
+			dropOptionalNulls(item, schema.Items)
+		}
+	}
+}
+
 func toSnakeCase(s string) string {
 	var result strings.Builder
 	for i, r := range s {
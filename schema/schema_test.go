@@ -637,3 +637,220 @@ func TestNormalize_NestedProperties(t *testing.T) {
 	require.Nil(t, val["type"])
 	require.NotNil(t, val["anyOf"])
 }
+
+func TestParseAndValidate_CoercesToSchema(t *testing.T) {
+	t.Parallel()
+
+	type person struct {
+		Name   string `json:"name"`
+		Age    int    `json:"age"`
+		Active bool   `json:"active"`
+	}
+	s := Generate(reflect.TypeOf(person{}))
+
+	obj, err := ParseAndValidate(`{"name": "Ada", "age": "36", "active": "true"}`, s)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"name": "Ada", "age": float64(36), "active": true}, obj)
+
+	_, err = ParseAndValidate(`{"name": "Ada", "age": "old", "active": true}`, s)
+	var parseErr *ParseError
+	require.ErrorAs(t, err, &parseErr)
+	require.Error(t, parseErr.ValidationError)
+}
+
+func TestParseAndValidateAs(t *testing.T) {
+	t.Parallel()
+
+	type item struct {
+		Name string `json:"name"`
+	}
+	type order struct {
+		ID     int     `json:"id"`
+		Status string  `json:"status" enum:"open,closed"`
+		Total  float64 `json:"total"`
+		Items  []item  `json:"items"`
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		t.Parallel()
+		got, err := ParseAndValidateAs[order](`{"id": 7, "status": "open", "total": 9.5, "items": [{"name": "pen"}]`)
+		require.NoError(t, err)
+		require.Equal(t, order{ID: 7, Status: "open", Total: 9.5, Items: []item{{Name: "pen"}}}, got)
+	})
+
+	t.Run("field paths", func(t *testing.T) {
+		t.Parallel()
+		_, err := ParseAndValidateAs[order](`{"id": "7", "status": "pending", "total": 1, "items": [{"name": 3}]}`)
+		var parseErr *ParseError
+		require.ErrorAs(t, err, &parseErr)
+		var validationErr *ValidationError
+		require.ErrorAs(t, parseErr.ValidationError, &validationErr)
+
+		paths := make([]string, 0, len(validationErr.Fields))
+		for _, f := range validationErr.Fields {
+			paths = append(paths, f.Path)
+		}
+		require.Equal(t, []string{"id", "items[0].name", "status"}, paths)
+		require.Contains(t, validationErr.Error(), "items[0].name: Value is integer but should be string")
+	})
+
+	t.Run("coercion", func(t *testing.T) {
+		t.Parallel()
+		got, err := ParseAndValidateAs[order](`{"id": "7", "status": "Open", "total": "12.5", "items": []}`, WithCoercion())
+		require.NoError(t, err)
+		require.Equal(t, order{ID: 7, Status: "open", Total: 12.5, Items: []item{}}, got)
+	})
+
+	t.Run("null to zero", func(t *testing.T) {
+		t.Parallel()
+		input := `{"id": 1, "status": "closed", "total": null, "items": null}`
+		_, err := ParseAndValidateAs[order](input)
+		require.Error(t, err)
+
+		got, err := ParseAndValidateAs[order](input, WithNullToZero())
+		require.NoError(t, err)
+		require.Equal(t, order{ID: 1, Status: "closed", Items: []item{}}, got)
+	})
+
+	t.Run("no json", func(t *testing.T) {
+		t.Parallel()
+		_, err := ParseAndValidateAs[order]("")
+		var parseErr *ParseError
+		require.ErrorAs(t, err, &parseErr)
+		require.Error(t, parseErr.ParseError)
+	})
+}
+
+func TestGenerateSchemaWithConstraintTags(t *testing.T) {
+	t.Parallel()
+
+	type SearchInput struct {
+		Query   string   `json:"query" minLength:"1" maxLength:"200" pattern:"^[^<>]*$"`
+		Limit   int      `json:"limit,omitempty" minimum:"1" maximum:"50" default:"10"`
+		Ratio   float64  `json:"ratio,omitempty" minimum:"0.5" default:"0.75"`
+		Since   string   `json:"since,omitempty" format:"date-time"`
+		Exact   bool     `json:"exact,omitempty" default:"true"`
+		Fields  []string `json:"fields,omitempty" default:"[\"title\"]"`
+		Invalid int      `json:"invalid,omitempty" minimum:"low"`
+	}
+
+	schema := Generate(reflect.TypeFor[SearchInput]())
+
+	query := schema.Properties["query"]
+	require.Equal(t, 1, *query.MinLength)
+	require.Equal(t, 200, *query.MaxLength)
+	require.Equal(t, "^[^<>]*$", query.Pattern)
+
+	limit := schema.Properties["limit"]
+	require.Equal(t, 1.0, *limit.Minimum)
+	require.Equal(t, 50.0, *limit.Maximum)
+	require.Equal(t, int64(10), limit.Default)
+
+	require.Equal(t, 0.75, schema.Properties["ratio"].Default)
+	require.Equal(t, "date-time", schema.Properties["since"].Format)
+	require.Equal(t, true, schema.Properties["exact"].Default)
+	require.Equal(t, []any{"title"}, schema.Properties["fields"].Default)
+	require.Nil(t, schema.Properties["invalid"].Minimum)
+
+	m := ToMap(schema)["properties"].(map[string]any)
+	require.Equal(t, "^[^<>]*$", m["query"].(map[string]any)["pattern"])
+	require.Equal(t, int64(10), m["limit"].(map[string]any)["default"])
+
+	_, err := ParseAndValidate(`{"query": "", "limit": 80}`, schema)
+	var validationErr *ValidationError
+	require.ErrorAs(t, err.(*ParseError).ValidationError, &validationErr)
+	require.Len(t, validationErr.Fields, 2)
+}
+
+func TestStrictify(t *testing.T) {
+	t.Parallel()
+
+	type address struct {
+		Street string `json:"street"`
+		Zip    string `json:"zip,omitempty" format:"postal-code"`
+	}
+	type person struct {
+		Name    string            `json:"name" minLength:"1"`
+		Email   string            `json:"email,omitempty" format:"email"`
+		Role    string            `json:"role,omitempty" enum:"admin,user"`
+		Address address           `json:"address"`
+		Tags    []string          `json:"tags"`
+		Labels  map[string]string `json:"labels,omitempty"`
+	}
+
+	strict, warnings := Generate(reflect.TypeOf(person{})).Strictify()
+
+	require.Equal(t, false, strict["additionalProperties"])
+	require.Equal(t, []string{"name", "address", "tags", "email", "labels", "role"}, strict["required"])
+
+	properties := strict["properties"].(map[string]any)
+	name := properties["name"].(map[string]any)
+	require.NotContains(t, name, "minLength")
+	require.Equal(t, "string", name["type"])
+
+	email := properties["email"].(map[string]any)
+	require.Equal(t, []any{"string", "null"}, email["type"])
+	require.Equal(t, "email", email["format"])
+
+	role := properties["role"].(map[string]any)
+	require.Equal(t, []any{"admin", "user", nil}, role["enum"])
+
+	addr := properties["address"].(map[string]any)
+	require.Equal(t, false, addr["additionalProperties"])
+	require.Equal(t, []string{"street", "zip"}, addr["required"])
+	require.NotContains(t, addr["properties"].(map[string]any)["zip"], "format")
+
+	labels := properties["labels"].(map[string]any)
+	require.Equal(t, false, labels["additionalProperties"])
+	require.Empty(t, labels["properties"])
+
+	require.Contains(t, warnings, `name: removed unsupported keyword "minLength"`)
+	require.Contains(t, warnings, `address.zip: removed unsupported format "postal-code"`)
+	require.Contains(t, warnings, "email: optional property is now required and nullable")
+}
+
+func TestStrictifyMap(t *testing.T) {
+	t.Parallel()
+
+	input := map[string]any{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type":    "object",
+		"properties": map[string]any{
+			"value": map[string]any{
+				"oneOf": []any{
+					map[string]any{"type": "string"},
+					map[string]any{"type": "object", "additionalProperties": true},
+				},
+			},
+		},
+		"required": []any{"value"},
+	}
+
+	strict, warnings := StrictifyMap(input)
+	require.NotContains(t, strict, "$schema")
+	require.Contains(t, input, "$schema", "input must not be modified")
+
+	value := strict["properties"].(map[string]any)["value"].(map[string]any)
+	require.NotContains(t, value, "oneOf")
+	variants := value["anyOf"].([]any)
+	require.Len(t, variants, 2)
+	require.Equal(t, false, variants[1].(map[string]any)["additionalProperties"])
+	require.Equal(t, []string{"value: additional properties are no longer allowed"}, warnings)
+}
+
+func TestParseAndValidate_DropsOptionalNulls(t *testing.T) {
+	t.Parallel()
+
+	type person struct {
+		Name  string `json:"name"`
+		Email string `json:"email,omitempty"`
+	}
+	s := Generate(reflect.TypeOf(person{}))
+
+	obj, err := ParseAndValidate(`{"name": "Ada", "email": null}`, s)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"name": "Ada"}, obj)
+
+	_, err = ParseAndValidate(`{"name": null}`, s)
+	require.Error(t, err)
+}
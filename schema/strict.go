@@ -0,0 +1,207 @@
+package schema
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// strictUnsupportedKeywords are rejected by strict structured outputs.
+var strictUnsupportedKeywords = []string{
+	"minLength", "maxLength", "default", "uniqueItems", "contains",
+	"minProperties", "maxProperties", "patternProperties", "propertyNames",
+	"unevaluatedProperties", "allOf", "not", "if", "then", "else",
+	"dependentRequired", "dependentSchemas", "$schema",
+}
+
+// strictFormats are the string formats strict structured outputs accept.
+var strictFormats = []string{
+	"date-time", "time", "date", "duration", "email", "hostname", "ipv4", "ipv6", "uuid",
+}
+
+// Strictify returns the schema as a JSON schema map that satisfies strict
+// structured outputs, as required by OpenAI's strict json_schema mode, along
+// with warnings describing each lossy transformation. See StrictifyMap.
+func (s Schema) Strictify() (map[string]any, []string) {
+	return StrictifyMap(ToMap(s))
+}
+
+// StrictifyMap returns a copy of the JSON schema map that satisfies strict
+// structured outputs, along with warnings describing each lossy
+// transformation:
+//
+//   - objects get "additionalProperties": false;
+//   - optional properties become required and nullable;
+//   - unsupported keywords and string formats are removed;
+//   - the "*" property used for map values is removed, since strict schemas
+//     can't describe arbitrary keys.
+func StrictifyMap(schema map[string]any) (map[string]any, []string) {
+	var warnings []string
+	return strictify(schema, "", &warnings), warnings
+}
+
+func strictify(node map[string]any, path string, warnings *[]string) map[string]any {
+	result := make(map[string]any, len(node))
+	for key, value := range node {
+		if slices.Contains(strictUnsupportedKeywords, key) {
+			if key != "$schema" {
+				warn(warnings, path, "removed unsupported keyword %q", key)
+			}
+			continue
+		}
+		result[key] = value
+	}
+	if format, ok := result["format"].(string); ok && !slices.Contains(strictFormats, format) {
+		warn(warnings, path, "removed unsupported format %q", format)
+		delete(result, "format")
+	}
+
+	if properties, ok := result["properties"].(map[string]any); ok {
+		// Keep the original order of required properties and append the
+		// optional ones after them.
+		required := requiredList(result["required"])
+		strictProperties := make(map[string]any, len(properties))
+		var strictRequired []string
+		for _, name := range required {
+			if _, ok := properties[name]; ok && name != "*" {
+				strictRequired = append(strictRequired, name)
+			}
+		}
+		names := slices.Sorted(maps.Keys(properties))
+		for _, name := range names {
+			propertyPath := joinPath(path, name)
+			if name == "*" {
+				warn(warnings, propertyPath, "removed map value schema, arbitrary keys are not allowed")
+				continue
+			}
+			property, ok := properties[name].(map[string]any)
+			if !ok {
+				strictProperties[name] = properties[name]
+				continue
+			}
+			property = strictify(property, propertyPath, warnings)
+			if !slices.Contains(required, name) {
+				makeNullable(property)
+				warn(warnings, propertyPath, "optional property is now required and nullable")
+				strictRequired = append(strictRequired, name)
+			}
+			strictProperties[name] = property
+		}
+		if strictRequired == nil {
+			strictRequired = []string{}
+		}
+		result["properties"] = strictProperties
+		result["required"] = strictRequired
+	}
+
+	if isObjectType(result["type"]) {
+		if _, ok := result["properties"]; !ok {
+			result["properties"] = map[string]any{}
+			result["required"] = []string{}
+		}
+		if additional, ok := result["additionalProperties"]; ok && additional != false {
+			warn(warnings, path, "additional properties are no longer allowed")
+		}
+		result["additionalProperties"] = false
+	}
+
+	if items, ok := result["items"].(map[string]any); ok {
+		result["items"] = strictify(items, path+"[]", warnings)
+	}
+	for _, key := range []string{"anyOf", "oneOf"} {
+		if variants, ok := result[key].([]any); ok {
+			strictVariants := make([]any, len(variants))
+			for i, variant := range variants {
+				if v, ok := variant.(map[string]any); ok {
+					strictVariants[i] = strictify(v, path, warnings)
+				} else {
+					strictVariants[i] = variant
+				}
+			}
+			// Strict mode supports anyOf but not oneOf.
+			delete(result, key)
+			result["anyOf"] = strictVariants
+		}
+	}
+	for _, key := range []string{"$defs", "definitions"} {
+		if defs, ok := result[key].(map[string]any); ok {
+			strictDefs := make(map[string]any, len(defs))
+			for name, def := range defs {
+				if d, ok := def.(map[string]any); ok {
+					strictDefs[name] = strictify(d, "#"+name, warnings)
+				} else {
+					strictDefs[name] = def
+				}
+			}
+			result[key] = strictDefs
+		}
+	}
+	return result
+}
+
+// makeNullable allows null in addition to the property's type.
+func makeNullable(property map[string]any) {
+	switch t := property["type"].(type) {
+	case string:
+		if t != "null" {
+			property["type"] = []any{t, "null"}
+		}
+	case []any:
+		if !slices.Contains(t, any("null")) {
+			property["type"] = append(slices.Clone(t), "null")
+		}
+	case []string:
+		if !slices.Contains(t, "null") {
+			property["type"] = append(slices.Clone(t), "null")
+		}
+	default:
+		if variants, ok := property["anyOf"].([]any); ok {
+			property["anyOf"] = append(slices.Clone(variants), map[string]any{"type": "null"})
+		}
+	}
+	if enum, ok := property["enum"].([]any); ok && !slices.Contains(enum, nil) {
+		property["enum"] = append(slices.Clone(enum), nil)
+	}
+}
+
+func requiredList(required any) []string {
+	switch r := required.(type) {
+	case []string:
+		return r
+	case []any:
+		names := make([]string, 0, len(r))
+		for _, name := range r {
+			if s, ok := name.(string); ok {
+				names = append(names, s)
+			}
+		}
+		return names
+	}
+	return nil
+}
+
+func isObjectType(t any) bool {
+	switch t := t.(type) {
+	case string:
+		return t == "object"
+	case []any:
+		return slices.Contains(t, any("object"))
+	case []string:
+		return slices.Contains(t, "object")
+	}
+	return false
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func warn(warnings *[]string, path, format string, args ...any) {
+	if path == "" {
+		path = "(root)"
+	}
+	*warnings = append(*warnings, path+": "+fmt.Sprintf(format, args...))
+}
@@ -0,0 +1,209 @@
+package schema
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"charm.land/fantasy/jsonrepair"
+	"github.com/kaptinlin/jsonschema"
+)
+
+// FieldError is a validation failure at a field path such as
+// "items[0].name". The root value has the path "(root)".
+type FieldError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every field that failed validation. Its message is
+// meant to be sent back to the model so it can correct its output.
+type ValidationError struct {
+	Fields []FieldError `json:"fields"`
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, fmt.Sprintf("%s: %s", f.Path, f.Message))
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// aggregateKeywords report that nested values failed; the nested failures
+// carry the useful messages.
+var aggregateKeywords = []string{"properties", "items", "prefixItems"}
+
+func newValidationError(result *jsonschema.EvaluationResult) *ValidationError {
+	e := &ValidationError{}
+	collectFieldErrors(e, result.ToList(), "")
+	slices.SortStableFunc(e.Fields, func(a, b FieldError) int {
+		return cmp.Compare(a.Path, b.Path)
+	})
+	return e
+}
+
+func collectFieldErrors(e *ValidationError, list *jsonschema.List, location string) {
+	location += list.InstanceLocation
+	hasInvalidDetails := false
+	for i := range list.Details {
+		if !list.Details[i].Valid {
+			hasInvalidDetails = true
+			collectFieldErrors(e, &list.Details[i], location)
+		}
+	}
+
+	keywords := make([]string, 0, len(list.Errors))
+	for keyword := range list.Errors {
+		keywords = append(keywords, keyword)
+	}
+	slices.Sort(keywords)
+	for _, keyword := range keywords {
+		if hasInvalidDetails && slices.Contains(aggregateKeywords, keyword) {
+			continue
+		}
+		e.Fields = append(e.Fields, FieldError{
+			Path:    fieldPath(location),
+			Message: list.Errors[keyword],
+		})
+	}
+}
+
+// fieldPath converts a JSON pointer such as "/items/0/name" into
+// "items[0].name".
+func fieldPath(pointer string) string {
+	if pointer == "" || pointer == "/" {
+		return "(root)"
+	}
+	var sb strings.Builder
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+		if _, err := strconv.Atoi(token); err == nil {
+			sb.WriteString("[" + token + "]")
+			continue
+		}
+		if sb.Len() > 0 {
+			sb.WriteByte('.')
+		}
+		sb.WriteString(token)
+	}
+	return sb.String()
+}
+
+// ParseOption configures ParseAndValidateAs.
+type ParseOption func(*parseOptions)
+
+type parseOptions struct {
+	coerce     bool
+	nullToZero bool
+}
+
+// WithCoercion converts values whose type doesn't match the schema when the
+// conversion is lossless, such as "30" to 30 for an integer field or "true"
+// to true for a boolean one.
+func WithCoercion() ParseOption {
+	return func(o *parseOptions) {
+		o.coerce = true
+	}
+}
+
+// WithNullToZero replaces null values of non-nullable fields with the zero
+// value of their type.
+func WithNullToZero() ParseOption {
+	return func(o *parseOptions) {
+		o.nullToZero = true
+	}
+}
+
+// ParseAndValidateAs parses the text, validates it against the schema
+// generated from T and decodes it into T. Validation runs on the canonical
+// JSON value, after repair and the optional coercions. Failures are returned
+// as a *ParseError whose ValidationError is a *ValidationError naming each
+// offending field.
+func ParseAndValidateAs[T any](text string, opts ...ParseOption) (T, error) {
+	var result T
+	var o parseOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	schema := Generate(reflect.TypeFor[T]())
+
+	obj, state, err := ParsePartialJSON(text)
+	if state == ParseStateFailed || state == ParseStateUndefined {
+		if err == nil {
+			err = errors.New("no JSON value found")
+		}
+		return result, &ParseError{RawText: text, ParseError: err}
+	}
+	if o.coerce {
+		if repaired, err := jsonrepair.RepairJSON(text, jsonrepair.WithSchema(ToMap(schema))); err == nil && repaired != "" {
+			var coerced any
+			if json.Unmarshal([]byte(repaired), &coerced) == nil {
+				obj = coerced
+			}
+		}
+	}
+	if o.nullToZero {
+		obj = nullToZero(obj, &schema)
+	}
+
+	if err := validateAgainstSchema(obj, schema); err != nil {
+		return result, &ParseError{RawText: text, ValidationError: err}
+	}
+
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return result, &ParseError{RawText: text, ParseError: err}
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return result, &ParseError{RawText: text, ValidationError: &ValidationError{
+				Fields: []FieldError{{
+					Path:    fieldPath("/" + strings.ReplaceAll(typeErr.Field, ".", "/")),
+					Message: fmt.Sprintf("Value is %s but should be %s", typeErr.Value, typeErr.Type),
+				}},
+			}}
+		}
+		return result, &ParseError{RawText: text, ParseError: err}
+	}
+	return result, nil
+}
+
+func nullToZero(value any, schema *Schema) any {
+	if schema == nil {
+		return value
+	}
+	if value == nil {
+		switch schema.Type {
+		case "string":
+			return ""
+		case "integer", "number":
+			return float64(0)
+		case "boolean":
+			return false
+		case "array":
+			return []any{}
+		case "object":
+			return map[string]any{}
+		}
+		return nil
+	}
+	switch v := value.(type) {
+	case map[string]any:
+		for key, item := range v {
+			v[key] = nullToZero(item, schema.Properties[key])
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = nullToZero(item, schema.Items)
+		}
+	}
+	return value
+}
@@ -0,0 +1,63 @@
+package fantasy
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ServiceTier is a processing tier trading latency and availability for
+// cost, such as OpenAI's flex and priority processing.
+type ServiceTier string
+
+const (
+	// ServiceTierDefault runs the call on the standard tier.
+	ServiceTierDefault ServiceTier = "default"
+	// ServiceTierFlex runs the call on a cheaper, slower tier that may have
+	// no capacity left. OpenAI only.
+	ServiceTierFlex ServiceTier = "flex"
+	// ServiceTierPriority runs the call on a faster tier billed at a
+	// premium. It maps to OpenAI's priority processing and Anthropic's
+	// priority tier.
+	ServiceTierPriority ServiceTier = "priority"
+)
+
+// WithServiceTier sets the service tier of the agent's calls. Provider
+// options setting a tier take precedence.
+//
+// When a flex call fails because the tier has no capacity left, the agent
+// makes it again on the default tier, adding a warning to the step.
+func WithServiceTier(tier ServiceTier) AgentOption {
+	return func(s *agentSettings) {
+		s.serviceTier = tier
+	}
+}
+
+// isResourceUnavailable reports whether err is a provider error for a
+// service tier that has no capacity left, like the 429 OpenAI returns for
+// flex processing.
+func isResourceUnavailable(err error) bool {
+	var providerErr *ProviderError
+	if !errors.As(err, &providerErr) || providerErr.StatusCode != http.StatusTooManyRequests {
+		return false
+	}
+	text := strings.ToLower(providerErr.Message + " " + string(providerErr.ResponseBody))
+	return strings.Contains(strings.ReplaceAll(text, "_", " "), "resource unavailable")
+}
+
+// fallBackFromFlex switches a flex call that failed with err to the
+// default tier, reporting whether it did so the call is made again.
+func fallBackFromFlex(call *Call, err error) bool {
+	if call.ServiceTier != ServiceTierFlex || !isResourceUnavailable(err) {
+		return false
+	}
+	call.ServiceTier = ServiceTierDefault
+	return true
+}
+
+// flexFallbackWarning is added to the response of a call that fell back
+// from the flex tier.
+var flexFallbackWarning = CallWarning{
+	Type:    CallWarningTypeOther,
+	Message: "the flex service tier had no capacity, the call was made on the default tier",
+}
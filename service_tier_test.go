@@ -0,0 +1,68 @@
+package fantasy
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceTierFlexFallback(t *testing.T) {
+	t.Parallel()
+
+	unavailable := &ProviderError{
+		Title:      "too many requests",
+		Message:    "Resource Unavailable",
+		StatusCode: http.StatusTooManyRequests,
+	}
+	newModel := func() (*mockLanguageModel, *[]ServiceTier) {
+		var tiers []ServiceTier
+		return &mockLanguageModel{
+			generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+				tiers = append(tiers, call.ServiceTier)
+				if call.ServiceTier == ServiceTierFlex {
+					return nil, unavailable
+				}
+				return &Response{Content: ResponseContent{TextContent{Text: "ok"}}, FinishReason: FinishReasonStop}, nil
+			},
+			streamFunc: func(ctx context.Context, call Call) (StreamResponse, error) {
+				tiers = append(tiers, call.ServiceTier)
+				return func(yield func(StreamPart) bool) {
+					if call.ServiceTier == ServiceTierFlex {
+						yield(StreamPart{Type: StreamPartTypeError, Error: unavailable})
+						return
+					}
+					_ = yield(StreamPart{Type: StreamPartTypeTextDelta, ID: "1", Delta: "ok"}) &&
+						yield(StreamPart{Type: StreamPartTypeFinish, FinishReason: FinishReasonStop})
+				}, nil
+			},
+		}, &tiers
+	}
+
+	t.Run("generate", func(t *testing.T) {
+		t.Parallel()
+		model, tiers := newModel()
+		agent := NewAgent(model, WithServiceTier(ServiceTierFlex), WithMaxRetries(0))
+		result, err := agent.Generate(t.Context(), AgentCall{Prompt: "hi"})
+		require.NoError(t, err)
+		require.Equal(t, []ServiceTier{ServiceTierFlex, ServiceTierDefault}, *tiers)
+		require.Contains(t, result.Response.Warnings, flexFallbackWarning)
+	})
+
+	t.Run("stream", func(t *testing.T) {
+		t.Parallel()
+		model, tiers := newModel()
+		agent := NewAgent(model, WithServiceTier(ServiceTierFlex), WithMaxRetries(0))
+		result, err := agent.Stream(t.Context(), AgentStreamCall{Prompt: "hi"})
+		require.NoError(t, err)
+		require.Equal(t, []ServiceTier{ServiceTierFlex, ServiceTierDefault}, *tiers)
+		require.Contains(t, result.Response.Warnings, flexFallbackWarning)
+	})
+
+	t.Run("other errors", func(t *testing.T) {
+		t.Parallel()
+		require.False(t, isResourceUnavailable(&ProviderError{StatusCode: http.StatusTooManyRequests, Message: "rate limit reached"}))
+		require.True(t, isResourceUnavailable(&ProviderError{StatusCode: http.StatusTooManyRequests, ResponseBody: []byte(`{"code":"resource_unavailable"}`)}))
+	})
+}
@@ -0,0 +1,86 @@
+package simulate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/eval"
+)
+
+// Assertion checks the outcome of a conversation and returns an error
+// describing why it failed.
+type Assertion func(ctx context.Context, c *Conversation) error
+
+// Check runs the assertions and returns their failures joined, or nil.
+func Check(ctx context.Context, c *Conversation, assertions ...Assertion) error {
+	var errs []error
+	for _, assertion := range assertions {
+		if err := assertion(ctx, c); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Finished asserts that the user ended the conversation before running out
+// of turns, which usually means the goal was reached.
+func Finished() Assertion {
+	return func(_ context.Context, c *Conversation) error {
+		if !c.Finished {
+			return fmt.Errorf("user didn't finish within %d turns", len(c.Turns))
+		}
+		return nil
+	}
+}
+
+// ToolUsed asserts that the agent called the tool at least once.
+func ToolUsed(name string) Assertion {
+	return func(_ context.Context, c *Conversation) error {
+		if !slices.ContainsFunc(c.ToolCalls(), func(call fantasy.ToolCallContent) bool { return call.ToolName == name }) {
+			return fmt.Errorf("tool %s was not used", name)
+		}
+		return nil
+	}
+}
+
+// ToolNotUsed asserts that the agent never called the tool.
+func ToolNotUsed(name string) Assertion {
+	return func(_ context.Context, c *Conversation) error {
+		if slices.ContainsFunc(c.ToolCalls(), func(call fantasy.ToolCallContent) bool { return call.ToolName == name }) {
+			return fmt.Errorf("tool %s was used", name)
+		}
+		return nil
+	}
+}
+
+// FinalAnswerMatches asserts that the agent's last reply matches the
+// pattern.
+func FinalAnswerMatches(pattern *regexp.Regexp) Assertion {
+	return func(_ context.Context, c *Conversation) error {
+		if answer := c.FinalAnswer(); !pattern.MatchString(answer) {
+			return fmt.Errorf("final answer %q does not match %s", answer, pattern)
+		}
+		return nil
+	}
+}
+
+// Judge asserts that the model judges the conversation to satisfy the
+// rubric, using eval.Judge with the scenario's goal as the question and the
+// transcript as the answer.
+func Judge(model fantasy.LanguageModel, rubric string) Assertion {
+	grader := eval.Judge(model, rubric)
+	return func(ctx context.Context, c *Conversation) error {
+		grade, err := grader.Grade(ctx, eval.Case{Name: c.Scenario.Name, Prompt: c.Scenario.Goal}, c.Transcript())
+		if err != nil {
+			return fmt.Errorf("judge: %w", err)
+		}
+		if !grade.Pass {
+			return fmt.Errorf("judge: %s", grade.Reason)
+		}
+		return nil
+	}
+}
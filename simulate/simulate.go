@@ -0,0 +1,195 @@
+// Package simulate tests agents against simulated users. A second model
+// plays the user according to a Scenario and drives a multi-turn
+// conversation with the agent under test, and assertions check the outcome.
+//
+// Example:
+//
+//	conversation, err := simulate.Run(ctx, userModel, agent, simulate.Scenario{
+//	    Persona: "A customer who was charged twice for order 1234.",
+//	    Goal:    "Get a refund for the duplicate charge.",
+//	})
+//	...
+//	err = simulate.Check(ctx, conversation,
+//	    simulate.ToolUsed("issue_refund"),
+//	    simulate.FinalAnswerMatches(regexp.MustCompile(`(?i)refund`)),
+//	)
+package simulate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"charm.land/fantasy"
+)
+
+// DefaultMaxTurns is the number of user turns when Scenario.MaxTurns is
+// zero.
+const DefaultMaxTurns = 10
+
+// StopToken is what the simulated user replies with to end the
+// conversation.
+const StopToken = "[DONE]"
+
+const systemPrompt = `You are role-playing a user talking to an AI assistant, to test the assistant. Stay in character and never mention that this is a simulation. Reply with the user's next message only, short and natural, as the user would type it.
+
+Persona:
+%s
+
+Goal:
+%s
+%s
+When your goal is reached, or the assistant clearly can't help any further, reply with exactly ` + StopToken + `.`
+
+const openingPrompt = "(The assistant is waiting for your first message.)"
+
+// Scenario describes the user to simulate.
+type Scenario struct {
+	Name string `json:"name"`
+	// Persona is who the user is and how they talk.
+	Persona string `json:"persona"`
+	// Goal is what the user wants to achieve in the conversation.
+	Goal string `json:"goal"`
+	// Script lists what the user brings up, in order, when it fits the
+	// conversation.
+	Script []string `json:"script,omitempty"`
+	// Opening is the user's first message. The user model writes it when
+	// it is empty.
+	Opening string `json:"opening,omitempty"`
+	// MaxTurns caps the number of user messages. Defaults to
+	// DefaultMaxTurns.
+	MaxTurns int `json:"max_turns,omitempty"`
+}
+
+// Turn is a user message and the agent's run in reply to it.
+type Turn struct {
+	User   string
+	Result *fantasy.AgentResult
+}
+
+// ToolCalls returns the tool calls the agent made in the turn, in order.
+func (t Turn) ToolCalls() []fantasy.ToolCallContent {
+	var calls []fantasy.ToolCallContent
+	for _, step := range t.Result.Steps {
+		calls = append(calls, step.Content.ToolCalls()...)
+	}
+	return calls
+}
+
+// Conversation is the outcome of a simulation.
+type Conversation struct {
+	Scenario Scenario
+	Turns    []Turn
+	// Messages is the conversation as the agent saw it.
+	Messages []fantasy.Message
+	// Finished reports whether the user ended the conversation, rather
+	// than running out of turns.
+	Finished bool
+	// Usage is the usage of the agent, without the user model's.
+	Usage fantasy.Usage
+}
+
+// FinalAnswer returns the text of the agent's last reply.
+func (c *Conversation) FinalAnswer() string {
+	if len(c.Turns) == 0 {
+		return ""
+	}
+	return c.Turns[len(c.Turns)-1].Result.Response.Content.Text()
+}
+
+// ToolCalls returns the tool calls the agent made, in order.
+func (c *Conversation) ToolCalls() []fantasy.ToolCallContent {
+	var calls []fantasy.ToolCallContent
+	for _, turn := range c.Turns {
+		calls = append(calls, turn.ToolCalls()...)
+	}
+	return calls
+}
+
+// Transcript returns the conversation as text, one message per paragraph,
+// for logs and judges.
+func (c *Conversation) Transcript() string {
+	var b strings.Builder
+	for _, turn := range c.Turns {
+		fmt.Fprintf(&b, "User: %s\n\n", turn.User)
+		for _, call := range turn.ToolCalls() {
+			fmt.Fprintf(&b, "Assistant called %s with %s\n\n", call.ToolName, call.Input)
+		}
+		fmt.Fprintf(&b, "Assistant: %s\n\n", turn.Result.Response.Content.Text())
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// Run simulates the scenario's user with the user model, talking to the
+// agent until the user ends the conversation or runs out of turns.
+func Run(ctx context.Context, user fantasy.LanguageModel, agent fantasy.Agent, scenario Scenario) (*Conversation, error) {
+	maxTurns := scenario.MaxTurns
+	if maxTurns <= 0 {
+		maxTurns = DefaultMaxTurns
+	}
+	conversation := &Conversation{Scenario: scenario}
+	// The user model sees the conversation with the roles swapped: its own
+	// messages are the assistant's.
+	userPrompt := fantasy.Prompt{
+		fantasy.NewSystemMessage(scenario.systemPrompt()),
+		fantasy.NewUserMessage(openingPrompt),
+	}
+
+	message := scenario.Opening
+	for range maxTurns {
+		if message == "" {
+			resp, err := user.Generate(ctx, fantasy.Call{Prompt: userPrompt})
+			if err != nil {
+				return conversation, fmt.Errorf("simulated user: %w", err)
+			}
+			message = strings.TrimSpace(resp.Content.Text())
+		}
+		if strings.Contains(message, StopToken) {
+			conversation.Finished = true
+			break
+		}
+
+		result, err := agent.Generate(ctx, fantasy.AgentCall{Prompt: message, Messages: conversation.Messages})
+		if err != nil {
+			return conversation, err
+		}
+		conversation.Turns = append(conversation.Turns, Turn{User: message, Result: result})
+		conversation.Messages = append(conversation.Messages, fantasy.NewUserMessage(message))
+		for _, step := range result.Steps {
+			conversation.Messages = append(conversation.Messages, step.Messages...)
+		}
+		conversation.Usage = addUsage(conversation.Usage, result.TotalUsage)
+
+		userPrompt = append(userPrompt, textMessage(fantasy.MessageRoleAssistant, message), fantasy.NewUserMessage(result.Response.Content.Text()))
+		message = ""
+	}
+	return conversation, nil
+}
+
+func (s Scenario) systemPrompt() string {
+	var script string
+	if len(s.Script) > 0 {
+		var b strings.Builder
+		b.WriteString("\nBring these up in order, when they fit the conversation:\n")
+		for i, item := range s.Script {
+			fmt.Fprintf(&b, "%d. %s\n", i+1, item)
+		}
+		script = b.String()
+	}
+	return fmt.Sprintf(systemPrompt, s.Persona, s.Goal, script)
+}
+
+func textMessage(role fantasy.MessageRole, text string) fantasy.Message {
+	return fantasy.Message{Role: role, Content: []fantasy.MessagePart{fantasy.TextPart{Text: text}}}
+}
+
+func addUsage(a, b fantasy.Usage) fantasy.Usage {
+	return fantasy.Usage{
+		InputTokens:         a.InputTokens + b.InputTokens,
+		OutputTokens:        a.OutputTokens + b.OutputTokens,
+		TotalTokens:         a.TotalTokens + b.TotalTokens,
+		ReasoningTokens:     a.ReasoningTokens + b.ReasoningTokens,
+		CacheCreationTokens: a.CacheCreationTokens + b.CacheCreationTokens,
+		CacheReadTokens:     a.CacheReadTokens + b.CacheReadTokens,
+	}
+}
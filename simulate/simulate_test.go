@@ -0,0 +1,168 @@
+package simulate
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/require"
+)
+
+type mockModel struct {
+	generate       func(ctx context.Context, call fantasy.Call) (*fantasy.Response, error)
+	generateObject func(ctx context.Context, call fantasy.ObjectCall) (*fantasy.ObjectResponse, error)
+}
+
+func (m *mockModel) Generate(ctx context.Context, call fantasy.Call) (*fantasy.Response, error) {
+	return m.generate(ctx, call)
+}
+
+func (m *mockModel) Stream(context.Context, fantasy.Call) (fantasy.StreamResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockModel) GenerateObject(ctx context.Context, call fantasy.ObjectCall) (*fantasy.ObjectResponse, error) {
+	return m.generateObject(ctx, call)
+}
+
+func (m *mockModel) StreamObject(context.Context, fantasy.ObjectCall) (fantasy.ObjectStreamResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockModel) Provider() string { return "mock" }
+
+func (m *mockModel) Model() string { return "mock" }
+
+// scriptedModel replies with the responses in order and records the
+// prompts it was called with.
+func scriptedModel(responses ...fantasy.ResponseContent) (*mockModel, *[]fantasy.Prompt) {
+	var prompts []fantasy.Prompt
+	return &mockModel{
+		generate: func(_ context.Context, call fantasy.Call) (*fantasy.Response, error) {
+			prompts = append(prompts, call.Prompt)
+			content := responses[0]
+			responses = responses[1:]
+			finishReason := fantasy.FinishReasonStop
+			if len(content.ToolCalls()) > 0 {
+				finishReason = fantasy.FinishReasonToolCalls
+			}
+			return &fantasy.Response{Content: content, FinishReason: finishReason, Usage: fantasy.Usage{InputTokens: 1, OutputTokens: 1}}, nil
+		},
+	}, &prompts
+}
+
+func text(s string) fantasy.ResponseContent {
+	return fantasy.ResponseContent{fantasy.TextContent{Text: s}}
+}
+
+func refundAgent() (fantasy.Agent, *[]fantasy.Prompt) {
+	model, prompts := scriptedModel(
+		text("Which order was it?"),
+		fantasy.ResponseContent{fantasy.ToolCallContent{ToolCallID: "call-1", ToolName: "issue_refund", Input: `{"order":"1234"}`}},
+		text("Your refund for order 1234 is on its way."),
+	)
+	refund := fantasy.NewAgentTool("issue_refund", "Refunds an order.", func(ctx context.Context, input struct {
+		Order string `json:"order"`
+	}, _ fantasy.ToolCall) (fantasy.ToolResponse, error) {
+		return fantasy.NewTextResponse("refunded " + input.Order), nil
+	})
+	return fantasy.NewAgent(model, fantasy.WithTools(refund)), prompts
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	user, userPrompts := scriptedModel(
+		text("I was charged twice."),
+		text("Order 1234."),
+		text("Thanks! "+StopToken),
+	)
+	agent, agentPrompts := refundAgent()
+	conversation, err := Run(t.Context(), user, agent, Scenario{
+		Name:    "double charge",
+		Persona: "A customer who was charged twice.",
+		Goal:    "Get a refund.",
+		Script:  []string{"Mention order 1234 when asked."},
+	})
+	require.NoError(t, err)
+
+	require.True(t, conversation.Finished)
+	require.Len(t, conversation.Turns, 2)
+	require.Equal(t, "I was charged twice.", conversation.Turns[0].User)
+	require.Equal(t, "Your refund for order 1234 is on its way.", conversation.FinalAnswer())
+	require.Len(t, conversation.ToolCalls(), 1)
+	require.Equal(t, int64(3), conversation.Usage.InputTokens)
+	require.Equal(t, `User: I was charged twice.
+
+Assistant: Which order was it?
+
+User: Order 1234.
+
+Assistant called issue_refund with {"order":"1234"}
+
+Assistant: Your refund for order 1234 is on its way.`, conversation.Transcript())
+
+	// The user model sees its own messages as the assistant's.
+	prompts := *userPrompts
+	require.Len(t, prompts, 3)
+	system := prompts[0][0].Content[0].(fantasy.TextPart).Text
+	require.Contains(t, system, "A customer who was charged twice.")
+	require.Contains(t, system, "1. Mention order 1234 when asked.")
+	require.Len(t, prompts[2], 6)
+	require.Equal(t, fantasy.MessageRoleAssistant, prompts[2][2].Role)
+	require.Equal(t, fantasy.MessageRoleUser, prompts[2][3].Role)
+	require.Equal(t, "Which order was it?", prompts[2][3].Content[0].(fantasy.TextPart).Text)
+
+	// The agent keeps the history of the conversation.
+	require.Len(t, (*agentPrompts)[1], 3)
+
+	require.NoError(t, Check(t.Context(), conversation,
+		Finished(),
+		ToolUsed("issue_refund"),
+		ToolNotUsed("delete_account"),
+		FinalAnswerMatches(regexp.MustCompile(`(?i)refund`)),
+	))
+	err = Check(t.Context(), conversation, ToolUsed("delete_account"), FinalAnswerMatches(regexp.MustCompile(`sorry`)))
+	require.ErrorContains(t, err, "tool delete_account was not used")
+	require.ErrorContains(t, err, `does not match sorry`)
+}
+
+func TestRun_MaxTurns(t *testing.T) {
+	t.Parallel()
+
+	user, userPrompts := scriptedModel(text("And another thing."))
+	agent, _ := refundAgent()
+	conversation, err := Run(t.Context(), user, agent, Scenario{Opening: "Hi.", MaxTurns: 2})
+	require.NoError(t, err)
+	require.False(t, conversation.Finished)
+	require.Len(t, conversation.Turns, 2)
+	require.Equal(t, "Hi.", conversation.Turns[0].User)
+	require.Len(t, *userPrompts, 1)
+	require.ErrorContains(t, Check(t.Context(), conversation, Finished()), "didn't finish within 2 turns")
+}
+
+func TestJudge(t *testing.T) {
+	t.Parallel()
+
+	judge := &mockModel{
+		generateObject: func(_ context.Context, call fantasy.ObjectCall) (*fantasy.ObjectResponse, error) {
+			prompt := call.Prompt[1].Content[0].(fantasy.TextPart).Text
+			require.Contains(t, prompt, "Question:\nGet a refund.")
+			require.Contains(t, prompt, "User: I want a refund.")
+			return &fantasy.ObjectResponse{
+				Object: map[string]any{"pass": false, "score": 0, "reason": "no refund was issued"},
+			}, nil
+		},
+	}
+	conversation := &Conversation{
+		Scenario: Scenario{Goal: "Get a refund."},
+		Turns: []Turn{{
+			User:   "I want a refund.",
+			Result: &fantasy.AgentResult{Response: fantasy.Response{Content: text("No.")}},
+		}},
+	}
+	err := Check(t.Context(), conversation, Judge(judge, "The user gets a refund."))
+	require.EqualError(t, err, "judge: no refund was issued")
+}
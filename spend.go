@@ -0,0 +1,266 @@
+package fantasy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Pricing is the price of a model in dollars per million tokens.
+type Pricing struct {
+	Input      float64 `json:"input"`
+	Output     float64 `json:"output"`
+	CacheWrite float64 `json:"cache_write"`
+	CacheRead  float64 `json:"cache_read"`
+}
+
+// Cost returns the price of the usage in dollars.
+func (p Pricing) Cost(usage Usage) float64 {
+	return (float64(usage.InputTokens)*p.Input +
+		float64(usage.OutputTokens)*p.Output +
+		float64(usage.CacheCreationTokens)*p.CacheWrite +
+		float64(usage.CacheReadTokens)*p.CacheRead) / 1e6
+}
+
+// CostFunction returns the cost of the usage of one call to the model.
+type CostFunction func(model LanguageModel, usage Usage) float64
+
+// PricingTable returns a CostFunction pricing calls by the model ID, looking
+// up "provider/model" before "model". Models without a price cost nothing.
+func PricingTable(prices map[string]Pricing) CostFunction {
+	return func(model LanguageModel, usage Usage) float64 {
+		pricing, ok := prices[model.Provider()+"/"+model.Model()]
+		if !ok {
+			pricing = prices[model.Model()]
+		}
+		return pricing.Cost(usage)
+	}
+}
+
+// SpendStore persists spend counters, so limits hold across agents and
+// processes that share it. Counters are identified by a key, the tenant ID,
+// and the start of their window.
+type SpendStore interface {
+	// Spent returns the amount recorded for the key in the window.
+	Spent(ctx context.Context, key string, window time.Time) (float64, error)
+	// AddSpend adds amount to the key's counter for the window and returns
+	// the new total.
+	AddSpend(ctx context.Context, key string, window time.Time, amount float64) (float64, error)
+}
+
+// NewMemorySpendStore creates a SpendStore keeping the counters in memory.
+// Counters of past windows are dropped as new windows start.
+func NewMemorySpendStore() SpendStore {
+	return &memorySpendStore{counters: map[string]spendCounter{}}
+}
+
+type spendCounter struct {
+	window time.Time
+	spent  float64
+}
+
+type memorySpendStore struct {
+	mu       sync.Mutex
+	counters map[string]spendCounter
+}
+
+// Spent implements SpendStore.
+func (s *memorySpendStore) Spent(_ context.Context, key string, window time.Time) (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counter := s.counters[key]
+	if !counter.window.Equal(window) {
+		return 0, nil
+	}
+	return counter.spent, nil
+}
+
+// AddSpend implements SpendStore.
+func (s *memorySpendStore) AddSpend(_ context.Context, key string, window time.Time, amount float64) (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counter := s.counters[key]
+	if !counter.window.Equal(window) {
+		counter = spendCounter{window: window}
+	}
+	counter.spent += amount
+	s.counters[key] = counter
+	return counter.spent, nil
+}
+
+// SpendAlert reports that spending in a window crossed a threshold.
+type SpendAlert struct {
+	// Key is the tenant ID the spending is counted for, empty without one.
+	Key string
+	// Threshold is the crossed fraction of the limit; 1 means the limit was
+	// exceeded and further calls are blocked until the window ends.
+	Threshold float64
+	Spent     float64
+	Limit     float64
+	// WindowEnd is when the counter resets, zero if it never does.
+	WindowEnd time.Time
+}
+
+// SpendAlertFunc is called when spending crosses a threshold.
+type SpendAlertFunc func(ctx context.Context, alert SpendAlert)
+
+// SpendLimitError is returned instead of calling the model once spending
+// reached the limit of the current window.
+type SpendLimitError struct {
+	Key       string
+	Spent     float64
+	Limit     float64
+	WindowEnd time.Time
+}
+
+func (e *SpendLimitError) Error() string {
+	msg := fmt.Sprintf("spend limit of %.2f reached (spent %.2f)", e.Limit, e.Spent)
+	if !e.WindowEnd.IsZero() {
+		msg += ", calls resume at " + e.WindowEnd.Format(time.RFC3339)
+	}
+	return msg
+}
+
+type spendLimit struct {
+	limit      float64
+	window     time.Duration
+	onAlert    SpendAlertFunc
+	thresholds []float64
+}
+
+// WithSpendLimit blocks the agent's model calls with a *SpendLimitError once
+// the cost of the calls made in the current window reaches limit. Windows
+// are aligned to multiples of window since the zero time, so a 24 hour
+// window resets at midnight UTC; a zero window never resets. Spending is
+// counted per tenant ID of the call's context (see WithTenantID) in the
+// SpendStore, by default one of the agent's own, and priced with the
+// CostFunction set with WithCostFunction, without which calls fail. Agents
+// only count towards each other's limits if they share a store set with
+// WithSpendStore. onExceeded, if not nil, is called
+// when a call's cost makes spending reach the limit, and when it crosses a
+// threshold set with WithSpendWarnings.
+func WithSpendLimit(limit float64, window time.Duration, onExceeded SpendAlertFunc) AgentOption {
+	return func(s *agentSettings) {
+		if s.spendLimit == nil {
+			s.spendLimit = &spendLimit{}
+		}
+		s.spendLimit.limit = limit
+		s.spendLimit.window = window
+		s.spendLimit.onAlert = onExceeded
+	}
+}
+
+// WithSpendWarnings sets soft thresholds, as fractions of the spend limit,
+// at which the spend limit's callback is called without blocking calls,
+// e.g. 0.5 and 0.8 to warn at half and 80% of the limit.
+func WithSpendWarnings(thresholds ...float64) AgentOption {
+	return func(s *agentSettings) {
+		if s.spendLimit == nil {
+			s.spendLimit = &spendLimit{}
+		}
+		s.spendLimit.thresholds = slices.Sorted(slices.Values(thresholds))
+	}
+}
+
+// WithSpendStore sets where the spend limit's counters are persisted. Agents
+// sharing a store share their counters.
+func WithSpendStore(store SpendStore) AgentOption {
+	return func(s *agentSettings) {
+		s.spendStore = store
+	}
+}
+
+// WithCostFunction sets how the cost of the agent's calls is calculated for
+// its spend limit.
+func WithCostFunction(fn CostFunction) AgentOption {
+	return func(s *agentSettings) {
+		s.costFunction = fn
+	}
+}
+
+// spendWindow returns the key and the window of the call's spend counter.
+func (a *agent) spendWindow(ctx context.Context) (string, time.Time) {
+	key, _ := TenantID(ctx)
+	if a.settings.spendLimit.window <= 0 {
+		return key, time.Time{}
+	}
+	return key, time.Now().UTC().Truncate(a.settings.spendLimit.window)
+}
+
+// checkSpend returns a *SpendLimitError if the spend limit was reached.
+func (a *agent) checkSpend(ctx context.Context) error {
+	limit := a.settings.spendLimit
+	if limit == nil || limit.limit <= 0 {
+		return nil
+	}
+	if a.settings.costFunction == nil {
+		return errors.New("spend limit set without a cost function, see WithCostFunction")
+	}
+	key, window := a.spendWindow(ctx)
+	spent, err := a.settings.spendStore.Spent(ctx, key, window)
+	if err != nil {
+		return fmt.Errorf("reading spend: %w", err)
+	}
+	if spent >= limit.limit {
+		return &SpendLimitError{Key: key, Spent: spent, Limit: limit.limit, WindowEnd: window.Add(limit.window)}
+	}
+	return nil
+}
+
+// recordSpend adds the cost of a call to the spend counter and alerts about
+// the thresholds it crossed.
+func (a *agent) recordSpend(ctx context.Context, model LanguageModel, usage Usage) error {
+	limit := a.settings.spendLimit
+	if limit == nil || limit.limit <= 0 || a.settings.costFunction == nil {
+		return nil
+	}
+	cost := a.settings.costFunction(model, usage)
+	if cost <= 0 {
+		return nil
+	}
+	key, window := a.spendWindow(ctx)
+	spent, err := a.settings.spendStore.AddSpend(ctx, key, window, cost)
+	if err != nil {
+		return fmt.Errorf("recording spend: %w", err)
+	}
+	if limit.onAlert == nil {
+		return nil
+	}
+	before := spent - cost
+	for _, threshold := range append(slices.Clone(limit.thresholds), 1) {
+		at := threshold * limit.limit
+		if before < at && spent >= at {
+			limit.onAlert(ctx, SpendAlert{
+				Key:       key,
+				Threshold: threshold,
+				Spent:     spent,
+				Limit:     limit.limit,
+				WindowEnd: window.Add(limit.window),
+			})
+		}
+	}
+	return nil
+}
+
+// spendStream records the cost of the stream when it finishes.
+func (a *agent) spendStream(ctx context.Context, model LanguageModel, stream StreamResponse) StreamResponse {
+	if a.settings.spendLimit == nil {
+		return stream
+	}
+	return func(yield func(StreamPart) bool) {
+		for part := range stream {
+			if part.Type == StreamPartTypeFinish {
+				if err := a.recordSpend(ctx, model, part.Usage); err != nil {
+					yield(StreamPart{Type: StreamPartTypeError, Error: err})
+					return
+				}
+			}
+			if !yield(part) {
+				return
+			}
+		}
+	}
+}
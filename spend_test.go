@@ -0,0 +1,146 @@
+package fantasy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPricingTable(t *testing.T) {
+	t.Parallel()
+
+	cost := PricingTable(map[string]Pricing{
+		"mock-model":               {Input: 1, Output: 2},
+		"mock-provider/mock-model": {Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.3},
+		"other-model":              {Input: 1},
+	})
+	usage := Usage{InputTokens: 1_000_000, OutputTokens: 100_000, CacheCreationTokens: 200_000, CacheReadTokens: 1_000_000}
+	require.InDelta(t, 3+1.5+0.75+0.3, cost(&mockLanguageModel{}, usage), 1e-9)
+}
+
+func TestWithSpendLimit(t *testing.T) {
+	t.Parallel()
+
+	var calls int
+	model := &mockLanguageModel{
+		generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+			calls++
+			return &Response{
+				Content:      []Content{TextContent{Text: "done"}},
+				FinishReason: FinishReasonStop,
+				Usage:        Usage{InputTokens: 100_000, OutputTokens: 10_000},
+			}, nil
+		},
+	}
+	var alerts []SpendAlert
+	store := NewMemorySpendStore()
+	agent := NewAgent(model,
+		WithSpendLimit(1, time.Hour, func(ctx context.Context, alert SpendAlert) {
+			alerts = append(alerts, alert)
+		}),
+		WithSpendWarnings(0.5),
+		WithSpendStore(store),
+		// Each call costs 0.3.
+		WithCostFunction(PricingTable(map[string]Pricing{"mock-model": {Input: 2, Output: 10}})),
+	)
+
+	ctx := WithTenantID(t.Context(), "acme")
+	for range 4 {
+		_, err := agent.Generate(ctx, AgentCall{Prompt: "hello"})
+		require.NoError(t, err)
+	}
+	_, err := agent.Generate(ctx, AgentCall{Prompt: "hello"})
+	var limitErr *SpendLimitError
+	require.ErrorAs(t, err, &limitErr)
+	require.Equal(t, "acme", limitErr.Key)
+	require.InDelta(t, 1.2, limitErr.Spent, 1e-9)
+	require.Equal(t, 4, calls)
+
+	require.Len(t, alerts, 2)
+	require.Equal(t, 0.5, alerts[0].Threshold)
+	require.InDelta(t, 0.6, alerts[0].Spent, 1e-9)
+	require.Equal(t, 1.0, alerts[1].Threshold)
+	require.InDelta(t, 1.2, alerts[1].Spent, 1e-9)
+	require.Equal(t, limitErr.WindowEnd, alerts[1].WindowEnd)
+
+	// Other tenants have their own counter.
+	_, err = agent.Generate(WithTenantID(t.Context(), "globex"), AgentCall{Prompt: "hello"})
+	require.NoError(t, err)
+
+	// So do other windows.
+	window := time.Now().UTC().Truncate(time.Hour)
+	spent, err := store.Spent(t.Context(), "acme", window.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Zero(t, spent)
+}
+
+func TestWithSpendLimit_Stream(t *testing.T) {
+	t.Parallel()
+
+	model := &mockLanguageModel{
+		streamFunc: func(ctx context.Context, call Call) (StreamResponse, error) {
+			return func(yield func(StreamPart) bool) {
+				_ = yield(StreamPart{Type: StreamPartTypeTextStart, ID: "1"}) &&
+					yield(StreamPart{Type: StreamPartTypeTextDelta, ID: "1", Delta: "done"}) &&
+					yield(StreamPart{Type: StreamPartTypeTextEnd, ID: "1"}) &&
+					yield(StreamPart{Type: StreamPartTypeFinish, FinishReason: FinishReasonStop, Usage: Usage{OutputTokens: 1_000_000}})
+			}, nil
+		},
+	}
+	agent := NewAgent(model,
+		WithSpendLimit(1, 0, nil),
+		WithSpendStore(NewMemorySpendStore()),
+		WithCostFunction(PricingTable(map[string]Pricing{"mock-model": {Output: 1}})),
+	)
+	_, err := agent.Stream(t.Context(), AgentStreamCall{Prompt: "hello"})
+	require.NoError(t, err)
+	_, err = agent.Stream(t.Context(), AgentStreamCall{Prompt: "hello"})
+	var limitErr *SpendLimitError
+	require.ErrorAs(t, err, &limitErr)
+	require.True(t, limitErr.WindowEnd.IsZero())
+	require.Equal(t, "spend limit of 1.00 reached (spent 1.00)", limitErr.Error())
+}
+
+func TestWithSpendLimit_PerAgent(t *testing.T) {
+	t.Parallel()
+
+	model := &mockLanguageModel{
+		generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+			return &Response{
+				Content:      []Content{TextContent{Text: "done"}},
+				FinishReason: FinishReasonStop,
+				Usage:        Usage{OutputTokens: 1_000_000},
+			}, nil
+		},
+	}
+	cost := WithCostFunction(PricingTable(map[string]Pricing{"mock-model": {Output: 1}}))
+	small := NewAgent(model, WithSpendLimit(1, 0, nil), cost)
+	large := NewAgent(model, WithSpendLimit(10, 0, nil), cost)
+
+	_, err := small.Generate(t.Context(), AgentCall{Prompt: "hello"})
+	require.NoError(t, err)
+	_, err = small.Generate(t.Context(), AgentCall{Prompt: "hello"})
+	var limitErr *SpendLimitError
+	require.ErrorAs(t, err, &limitErr)
+
+	// The other agent has its own counter.
+	_, err = large.Generate(t.Context(), AgentCall{Prompt: "hello"})
+	require.NoError(t, err)
+}
+
+func TestWithSpendLimit_WithoutCostFunction(t *testing.T) {
+	t.Parallel()
+
+	var calls int
+	model := &mockLanguageModel{
+		generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+			calls++
+			return &Response{Content: []Content{TextContent{Text: "done"}}, FinishReason: FinishReasonStop}, nil
+		},
+	}
+	_, err := NewAgent(model, WithSpendLimit(1, 0, nil)).Generate(t.Context(), AgentCall{Prompt: "hello"})
+	require.ErrorContains(t, err, "without a cost function")
+	require.Zero(t, calls)
+}
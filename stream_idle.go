@@ -0,0 +1,152 @@
+package fantasy
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+// maxStreamResumes is how many times a stalled stream is resumed from its
+// partial text before the stall is returned as an error.
+const maxStreamResumes = 2
+
+// WithStreamIdleTimeout aborts a stream when no part arrives for the
+// duration, since some providers silently hang connections. If the model
+// implements PrefillModel and the stream has only produced text so far, the
+// agent resumes it by sending the partial text back for the model to
+// continue, and the callbacks see one uninterrupted response. Otherwise the
+// step fails with a *StreamStalledError, which is retried like other
+// transient errors.
+func WithStreamIdleTimeout(timeout time.Duration) AgentOption {
+	return func(s *agentSettings) {
+		s.streamIdleTimeout = timeout
+	}
+}
+
+func (a *agent) idleStream(ctx context.Context, model LanguageModel, call Call) (StreamResponse, error) {
+	timeout := a.settings.streamIdleTimeout
+	if timeout <= 0 {
+		return a.safeStream(ctx, model, call)
+	}
+	streamCtx, cancel := context.WithCancel(ctx)
+	stream, err := a.safeStream(streamCtx, model, call)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	canResume := supportsPrefill(model)
+
+	return func(yield func(StreamPart) bool) {
+		defer func() { cancel() }()
+		var (
+			partial    strings.Builder
+			openTextID string
+			// continueID is the ID of the text block the resumed stream
+			// continues, and remap maps the resumed stream's IDs for it.
+			continueID string
+			remap      = map[string]string{}
+		)
+		for resumes := 0; ; resumes++ {
+			resume := false
+			for part := range withIdleTimeout(stream, timeout, cancel) {
+				var stalled *StreamStalledError
+				if part.Type == StreamPartTypeError && errors.As(part.Error, &stalled) &&
+					canResume && partial.Len() > 0 && resumes < maxStreamResumes {
+					resume = true
+					break
+				}
+				if id, ok := remap[part.ID]; ok {
+					part.ID = id
+				}
+				switch part.Type {
+				case StreamPartTypeTextStart:
+					if continueID != "" {
+						remap[part.ID] = continueID
+						continueID = ""
+						continue
+					}
+					openTextID = part.ID
+				case StreamPartTypeTextDelta:
+					partial.WriteString(part.Delta)
+				case StreamPartTypeTextEnd:
+					openTextID = ""
+				case StreamPartTypeWarnings, StreamPartTypeFinish, StreamPartTypeError:
+				default:
+					canResume = false
+				}
+				if !yield(part) {
+					return
+				}
+			}
+			if !resume {
+				return
+			}
+
+			continueID = openTextID
+			clear(remap)
+			resumeCall := call
+			resumeCall.Prompt = append(slices.Clone(call.Prompt), Message{
+				Role:    MessageRoleAssistant,
+				Content: []MessagePart{TextPart{Text: strings.TrimRight(partial.String(), " \t\r\n")}},
+			})
+			var streamCtx context.Context
+			streamCtx, cancel = context.WithCancel(ctx)
+			var err error
+			stream, err = a.safeStream(streamCtx, model, resumeCall)
+			if err != nil {
+				yield(StreamPart{Type: StreamPartTypeError, Error: err})
+				return
+			}
+		}
+	}, nil
+}
+
+func supportsPrefill(model LanguageModel) bool {
+	prefill, ok := model.(PrefillModel)
+	return ok && prefill.SupportsPrefill()
+}
+
+// withIdleTimeout reads the stream in a goroutine and ends it with a
+// *StreamStalledError part if no part arrives for the timeout. cancel
+// aborts the request behind the stream, and is called when the returned
+// stream ends.
+func withIdleTimeout(stream StreamResponse, timeout time.Duration, cancel context.CancelFunc) StreamResponse {
+	return func(yield func(StreamPart) bool) {
+		parts := make(chan StreamPart)
+		done := make(chan struct{})
+		defer func() {
+			close(done)
+			cancel()
+		}()
+		go func() {
+			defer close(parts)
+			for part := range stream {
+				select {
+				case parts <- part:
+				case <-done:
+					return
+				}
+			}
+		}()
+
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		for {
+			select {
+			case part, ok := <-parts:
+				if !ok {
+					return
+				}
+				if !yield(part) {
+					return
+				}
+				timer.Reset(timeout)
+			case <-timer.C:
+				yield(StreamPart{Type: StreamPartTypeError, Error: &StreamStalledError{Timeout: timeout}})
+				return
+			}
+		}
+	}
+}
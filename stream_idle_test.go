@@ -0,0 +1,137 @@
+package fantasy
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type prefillMockModel struct {
+	*mockLanguageModel
+}
+
+func (prefillMockModel) SupportsPrefill() bool { return true }
+
+// hangingStream yields the parts and then hangs until the request is
+// cancelled.
+func hangingStream(ctx context.Context, parts ...StreamPart) StreamResponse {
+	return func(yield func(StreamPart) bool) {
+		for _, part := range parts {
+			if !yield(part) {
+				return
+			}
+		}
+		<-ctx.Done()
+	}
+}
+
+func TestStreamIdleTimeout(t *testing.T) {
+	t.Parallel()
+
+	t.Run("stalled stream fails", func(t *testing.T) {
+		t.Parallel()
+		model := &mockLanguageModel{
+			streamFunc: func(ctx context.Context, call Call) (StreamResponse, error) {
+				return hangingStream(ctx, StreamPart{Type: StreamPartTypeTextStart, ID: "0"}), nil
+			},
+		}
+		agent := NewAgent(model, WithStreamIdleTimeout(20*time.Millisecond), WithMaxRetries(0))
+		_, err := agent.Stream(t.Context(), AgentStreamCall{Prompt: "hi"})
+
+		var stalledErr *StreamStalledError
+		require.ErrorAs(t, err, &stalledErr)
+		require.Equal(t, 20*time.Millisecond, stalledErr.Timeout)
+		require.True(t, isRetryableError(err))
+	})
+
+	t.Run("slow but steady stream succeeds", func(t *testing.T) {
+		t.Parallel()
+		model := &mockLanguageModel{
+			streamFunc: func(ctx context.Context, call Call) (StreamResponse, error) {
+				return func(yield func(StreamPart) bool) {
+					for _, delta := range []string{"a", "b", "c"} {
+						time.Sleep(10 * time.Millisecond)
+						if !yield(StreamPart{Type: StreamPartTypeTextDelta, ID: "0", Delta: delta}) {
+							return
+						}
+					}
+					yield(StreamPart{Type: StreamPartTypeFinish, FinishReason: FinishReasonStop})
+				}, nil
+			},
+		}
+		agent := NewAgent(model, WithStreamIdleTimeout(100*time.Millisecond))
+		_, err := agent.Stream(t.Context(), AgentStreamCall{Prompt: "hi"})
+		require.NoError(t, err)
+	})
+
+	t.Run("resumes from partial text", func(t *testing.T) {
+		t.Parallel()
+		var calls atomic.Int32
+		model := prefillMockModel{&mockLanguageModel{
+			streamFunc: func(ctx context.Context, call Call) (StreamResponse, error) {
+				if calls.Add(1) == 1 {
+					return hangingStream(ctx,
+						StreamPart{Type: StreamPartTypeTextStart, ID: "a"},
+						StreamPart{Type: StreamPartTypeTextDelta, ID: "a", Delta: "Hello, "},
+					), nil
+				}
+				last := call.Prompt[len(call.Prompt)-1]
+				require.Equal(t, MessageRoleAssistant, last.Role)
+				require.Equal(t, TextPart{Text: "Hello,"}, last.Content[0])
+				return func(yield func(StreamPart) bool) {
+					for _, part := range []StreamPart{
+						{Type: StreamPartTypeTextStart, ID: "b"},
+						{Type: StreamPartTypeTextDelta, ID: "b", Delta: "world!"},
+						{Type: StreamPartTypeTextEnd, ID: "b"},
+						{Type: StreamPartTypeFinish, FinishReason: FinishReasonStop},
+					} {
+						if !yield(part) {
+							return
+						}
+					}
+				}, nil
+			},
+		}}
+
+		var starts, ids []string
+		agent := NewAgent(model, WithStreamIdleTimeout(20*time.Millisecond), WithMaxRetries(0))
+		result, err := agent.Stream(t.Context(), AgentStreamCall{
+			Prompt: "hi",
+			OnTextStart: func(id string) error {
+				starts = append(starts, id)
+				return nil
+			},
+			OnTextDelta: func(id, text string) error {
+				ids = append(ids, id)
+				return nil
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, int32(2), calls.Load())
+		require.Equal(t, "Hello, world!", result.Response.Content.Text())
+		require.Equal(t, []string{"a"}, starts)
+		require.Equal(t, []string{"a", "a"}, ids)
+	})
+
+	t.Run("does not resume after tool input", func(t *testing.T) {
+		t.Parallel()
+		var calls atomic.Int32
+		model := prefillMockModel{&mockLanguageModel{
+			streamFunc: func(ctx context.Context, call Call) (StreamResponse, error) {
+				calls.Add(1)
+				return hangingStream(ctx,
+					StreamPart{Type: StreamPartTypeTextDelta, ID: "a", Delta: "Let me check."},
+					StreamPart{Type: StreamPartTypeToolInputStart, ID: "tool-1", ToolCallName: "lookup"},
+				), nil
+			},
+		}}
+		agent := NewAgent(model, WithStreamIdleTimeout(20*time.Millisecond), WithMaxRetries(0))
+		_, err := agent.Stream(t.Context(), AgentStreamCall{Prompt: "hi"})
+		var stalledErr *StreamStalledError
+		require.ErrorAs(t, err, &stalledErr)
+		require.Equal(t, int32(1), calls.Load())
+	})
+}
@@ -0,0 +1,47 @@
+package fantasy
+
+import "strings"
+
+// WithStrictPromptConversion makes the agent's calls fail with a
+// *DroppedContentError before they're sent when the provider would leave
+// content of the prompt out, like files of a media type it doesn't support,
+// empty messages or reasoning it can't send back. See
+// CallWarningTypeDroppedContent.
+func WithStrictPromptConversion() AgentOption {
+	return func(s *agentSettings) {
+		s.strictPromptConversion = true
+	}
+}
+
+// DroppedContentError is returned for calls with StrictPromptConversion set
+// whose prompt the provider couldn't convert without leaving content out.
+type DroppedContentError struct {
+	Warnings []CallWarning
+}
+
+func (e *DroppedContentError) Error() string {
+	messages := make([]string, len(e.Warnings))
+	for i, w := range e.Warnings {
+		messages[i] = w.String()
+	}
+	return "prompt content would be dropped: " + strings.Join(messages, "; ")
+}
+
+// CheckDroppedContent returns a *DroppedContentError if call is strict and
+// warnings, from converting its prompt, report dropped content. Providers
+// call it before sending the request.
+func CheckDroppedContent(call Call, warnings []CallWarning) error {
+	if !call.StrictPromptConversion {
+		return nil
+	}
+	var dropped []CallWarning
+	for _, w := range warnings {
+		if w.Type == CallWarningTypeDroppedContent {
+			dropped = append(dropped, w)
+		}
+	}
+	if len(dropped) == 0 {
+		return nil
+	}
+	return &DroppedContentError{Warnings: dropped}
+}
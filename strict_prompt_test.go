@@ -0,0 +1,40 @@
+package fantasy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckDroppedContent(t *testing.T) {
+	t.Parallel()
+
+	dropped := CallWarning{Type: CallWarningTypeDroppedContent, Message: "file part media type application/zip not supported"}
+	warnings := []CallWarning{
+		{Type: CallWarningTypeUnsupportedSetting, Setting: "top_k"},
+		dropped,
+	}
+
+	require.NoError(t, CheckDroppedContent(Call{}, warnings))
+	require.NoError(t, CheckDroppedContent(Call{StrictPromptConversion: true}, warnings[:1]))
+
+	err := CheckDroppedContent(Call{StrictPromptConversion: true}, warnings)
+	var droppedErr *DroppedContentError
+	require.ErrorAs(t, err, &droppedErr)
+	require.Equal(t, []CallWarning{dropped}, droppedErr.Warnings)
+	require.EqualError(t, err, "prompt content would be dropped: file part media type application/zip not supported")
+}
+
+func TestWithStrictPromptConversion(t *testing.T) {
+	t.Parallel()
+
+	var strict bool
+	model := &mockLanguageModel{generateFunc: func(_ context.Context, call Call) (*Response, error) {
+		strict = call.StrictPromptConversion
+		return &Response{Content: ResponseContent{TextContent{Text: "ok"}}, FinishReason: FinishReasonStop}, nil
+	}}
+	_, err := NewAgent(model, WithStrictPromptConversion()).Generate(t.Context(), AgentCall{Prompt: "hi"})
+	require.NoError(t, err)
+	require.True(t, strict)
+}
@@ -0,0 +1,256 @@
+package fantasy
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// Tenant holds the settings a multi-tenant application uses for the calls
+// of one customer.
+type Tenant struct {
+	ID string
+	// APIKey and BaseURL configure the provider the tenant's calls are sent
+	// with. Empty values leave the provider's defaults.
+	APIKey  string
+	BaseURL string
+	// RequestsPerSecond limits how fast the tenant's calls start; calls over
+	// the limit wait for their turn. Zero means unlimited. Burst is how many
+	// calls may start at once, by default RequestsPerSecond rounded up.
+	RequestsPerSecond float64
+	Burst             int
+}
+
+// TenantResolver returns the settings of a tenant. id is the tenant ID of
+// the call's context, or empty if it has none; resolvers may fall back to a
+// default tenant or return an error.
+type TenantResolver interface {
+	ResolveTenant(ctx context.Context, id string) (Tenant, error)
+}
+
+// TenantResolverFunc is a function that implements TenantResolver.
+type TenantResolverFunc func(ctx context.Context, id string) (Tenant, error)
+
+// ResolveTenant implements TenantResolver.
+func (f TenantResolverFunc) ResolveTenant(ctx context.Context, id string) (Tenant, error) {
+	return f(ctx, id)
+}
+
+type tenantContextKey struct{}
+
+// WithTenantID returns a context whose calls through a tenant provider are
+// made for the tenant.
+func WithTenantID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, id)
+}
+
+// TenantID returns the tenant ID of ctx, and whether it has one.
+func TenantID(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(tenantContextKey{}).(string)
+	return id, ok
+}
+
+// NewTenantProvider returns a provider whose models resolve the tenant of
+// each call from its context, so one provider serves every customer of a
+// multi-tenant application. newProvider creates the provider a tenant's
+// calls are sent with from its API key and base URL, e.g.:
+//
+//	provider := fantasy.NewTenantProvider(anthropic.Name, resolver, func(t fantasy.Tenant) (fantasy.Provider, error) {
+//	    return anthropic.New(anthropic.WithAPIKey(t.APIKey), anthropic.WithBaseURL(t.BaseURL))
+//	})
+//
+// Providers and models are created once per API key and base URL and
+// reused. name is the name of the providers newProvider creates.
+func NewTenantProvider(name string, resolver TenantResolver, newProvider func(Tenant) (Provider, error)) Provider {
+	return &tenantProvider{
+		name:        name,
+		resolver:    resolver,
+		newProvider: newProvider,
+		providers:   map[tenantProviderKey]Provider{},
+		models:      map[tenantModelKey]LanguageModel{},
+		limiters:    map[string]*rate.Limiter{},
+	}
+}
+
+type tenantProviderKey struct {
+	apiKey  string
+	baseURL string
+}
+
+type tenantModelKey struct {
+	tenantProviderKey
+	modelID string
+}
+
+type tenantProvider struct {
+	name        string
+	resolver    TenantResolver
+	newProvider func(Tenant) (Provider, error)
+
+	mu        sync.Mutex
+	providers map[tenantProviderKey]Provider
+	models    map[tenantModelKey]LanguageModel
+	limiters  map[string]*rate.Limiter
+}
+
+// Name implements Provider.
+func (p *tenantProvider) Name() string {
+	return p.name
+}
+
+// LanguageModel implements Provider. The tenant's model is only created
+// when the returned model is first called for the tenant. The returned
+// model has the optional capabilities of the tenants' models, such as
+// PDFModel; it always implements TokenCounter, which fails for models that
+// can't count tokens.
+func (p *tenantProvider) LanguageModel(_ context.Context, modelID string) (LanguageModel, error) {
+	model := &tenantModel{provider: p, modelID: modelID}
+	return tokenCountingModel{capabilityModel{LanguageModel: model, capabilities: model}}, nil
+}
+
+// model returns the model of the tenant of ctx, once the tenant's rate limit
+// lets the call start.
+func (p *tenantProvider) model(ctx context.Context, modelID string) (LanguageModel, error) {
+	id, _ := TenantID(ctx)
+	tenant, err := p.resolver.ResolveTenant(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("resolving tenant %q: %w", id, err)
+	}
+	if tenant.ID == "" {
+		tenant.ID = id
+	}
+	if limiter := p.limiter(tenant); limiter != nil {
+		if err := limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.cachedModel(ctx, tenant, modelID)
+}
+
+// cachedModel returns the model of the tenant, creating it and its provider
+// if needed. Callers must hold the lock.
+func (p *tenantProvider) cachedModel(ctx context.Context, tenant Tenant, modelID string) (LanguageModel, error) {
+	key := tenantModelKey{tenantProviderKey{tenant.APIKey, tenant.BaseURL}, modelID}
+	if model, ok := p.models[key]; ok {
+		return model, nil
+	}
+	provider, ok := p.providers[key.tenantProviderKey]
+	if !ok {
+		var err error
+		if provider, err = p.newProvider(tenant); err != nil {
+			return nil, fmt.Errorf("creating provider for tenant %q: %w", tenant.ID, err)
+		}
+		p.providers[key.tenantProviderKey] = provider
+	}
+	model, err := provider.LanguageModel(ctx, modelID)
+	if err != nil {
+		return nil, err
+	}
+	p.models[key] = model
+	return model, nil
+}
+
+// capableModel returns a model with the ID to detect the capabilities of
+// the tenants' models: one already created for a tenant, or else the model
+// of a tenant without an API key or base URL.
+func (p *tenantProvider) capableModel(modelID string) (LanguageModel, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for key, model := range p.models {
+		if key.modelID == modelID {
+			return model, true
+		}
+	}
+	model, err := p.cachedModel(context.Background(), Tenant{}, modelID)
+	return model, err == nil
+}
+
+// limiter returns the rate limiter of the tenant, updated to its current
+// limit, or nil if it has none.
+func (p *tenantProvider) limiter(tenant Tenant) *rate.Limiter {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if tenant.RequestsPerSecond <= 0 {
+		delete(p.limiters, tenant.ID)
+		return nil
+	}
+	limit := rate.Limit(tenant.RequestsPerSecond)
+	burst := tenant.Burst
+	if burst <= 0 {
+		burst = max(1, int(math.Ceil(tenant.RequestsPerSecond)))
+	}
+	limiter, ok := p.limiters[tenant.ID]
+	if !ok {
+		limiter = rate.NewLimiter(limit, burst)
+		p.limiters[tenant.ID] = limiter
+	} else if limiter.Limit() != limit || limiter.Burst() != burst {
+		limiter.SetLimit(limit)
+		limiter.SetBurst(burst)
+	}
+	return limiter
+}
+
+type tenantModel struct {
+	provider *tenantProvider
+	modelID  string
+}
+
+// Generate implements LanguageModel.
+func (m *tenantModel) Generate(ctx context.Context, call Call) (*Response, error) {
+	model, err := m.provider.model(ctx, m.modelID)
+	if err != nil {
+		return nil, err
+	}
+	return model.Generate(ctx, call)
+}
+
+// Stream implements LanguageModel.
+func (m *tenantModel) Stream(ctx context.Context, call Call) (StreamResponse, error) {
+	model, err := m.provider.model(ctx, m.modelID)
+	if err != nil {
+		return nil, err
+	}
+	return model.Stream(ctx, call)
+}
+
+// GenerateObject implements LanguageModel.
+func (m *tenantModel) GenerateObject(ctx context.Context, call ObjectCall) (*ObjectResponse, error) {
+	model, err := m.provider.model(ctx, m.modelID)
+	if err != nil {
+		return nil, err
+	}
+	return model.GenerateObject(ctx, call)
+}
+
+// StreamObject implements LanguageModel.
+func (m *tenantModel) StreamObject(ctx context.Context, call ObjectCall) (ObjectStreamResponse, error) {
+	model, err := m.provider.model(ctx, m.modelID)
+	if err != nil {
+		return nil, err
+	}
+	return model.StreamObject(ctx, call)
+}
+
+// Provider implements LanguageModel.
+func (m *tenantModel) Provider() string {
+	return m.provider.name
+}
+
+// Model implements LanguageModel.
+func (m *tenantModel) Model() string {
+	return m.modelID
+}
+
+func (m *tenantModel) capable() (LanguageModel, bool) {
+	return m.provider.capableModel(m.modelID)
+}
+
+func (m *tenantModel) counter(ctx context.Context) (LanguageModel, error) {
+	return m.provider.model(ctx, m.modelID)
+}
@@ -0,0 +1,110 @@
+package fantasy
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type tenantTestProvider struct {
+	tenant Tenant
+}
+
+func (p *tenantTestProvider) Name() string { return "mock-provider" }
+
+func (p *tenantTestProvider) LanguageModel(_ context.Context, modelID string) (LanguageModel, error) {
+	return &mockLanguageModel{
+		generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+			return &Response{Content: []Content{TextContent{Text: p.tenant.APIKey + "@" + p.tenant.BaseURL + "/" + modelID}}}, nil
+		},
+	}, nil
+}
+
+func TestTenantProvider(t *testing.T) {
+	t.Parallel()
+
+	tenants := map[string]Tenant{
+		"acme":   {APIKey: "acme-key", BaseURL: "https://acme.example"},
+		"globex": {APIKey: "globex-key", BaseURL: "https://globex.example", RequestsPerSecond: 20, Burst: 1},
+	}
+	resolver := TenantResolverFunc(func(ctx context.Context, id string) (Tenant, error) {
+		tenant, ok := tenants[id]
+		if !ok {
+			return Tenant{}, errors.New("unknown tenant")
+		}
+		return tenant, nil
+	})
+	var mu sync.Mutex
+	var created int
+	provider := NewTenantProvider("mock-provider", resolver, func(tenant Tenant) (Provider, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		created++
+		return &tenantTestProvider{tenant: tenant}, nil
+	})
+	model, err := provider.LanguageModel(t.Context(), "model-1")
+	require.NoError(t, err)
+	require.Equal(t, "mock-provider", model.Provider())
+	require.Equal(t, "model-1", model.Model())
+
+	generate := func(ctx context.Context) (string, error) {
+		resp, err := model.Generate(ctx, Call{Prompt: Prompt{NewUserMessage("hello")}})
+		if err != nil {
+			return "", err
+		}
+		return resp.Content.Text(), nil
+	}
+
+	text, err := generate(WithTenantID(t.Context(), "acme"))
+	require.NoError(t, err)
+	require.Equal(t, "acme-key@https://acme.example/model-1", text)
+	text, err = generate(WithTenantID(t.Context(), "globex"))
+	require.NoError(t, err)
+	require.Equal(t, "globex-key@https://globex.example/model-1", text)
+	_, err = generate(WithTenantID(t.Context(), "acme"))
+	require.NoError(t, err)
+	require.Equal(t, 2, created, "providers are reused")
+
+	_, err = generate(t.Context())
+	require.ErrorContains(t, err, `resolving tenant "": unknown tenant`)
+
+	t.Run("rate limit", func(t *testing.T) {
+		start := time.Now()
+		for range 3 {
+			_, err := generate(WithTenantID(t.Context(), "globex"))
+			require.NoError(t, err)
+		}
+		require.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+
+		ctx, cancel := context.WithCancel(WithTenantID(t.Context(), "globex"))
+		cancel()
+		_, err := generate(ctx)
+		require.Error(t, err)
+	})
+}
+
+func TestTenantProvider_ModelCapabilities(t *testing.T) {
+	t.Parallel()
+
+	resolver := TenantResolverFunc(func(ctx context.Context, id string) (Tenant, error) {
+		return Tenant{ID: id, APIKey: id + "-key"}, nil
+	})
+	provider := NewTenantProvider("mock-provider", resolver, func(Tenant) (Provider, error) {
+		return capableTestProvider{}, nil
+	})
+	model, err := provider.LanguageModel(t.Context(), "model-1")
+	require.NoError(t, err)
+	requireCapabilities(t, model)
+}
+
+type capableTestProvider struct{}
+
+func (capableTestProvider) Name() string { return "mock-provider" }
+
+func (capableTestProvider) LanguageModel(context.Context, string) (LanguageModel, error) {
+	return &capableMockModel{}, nil
+}
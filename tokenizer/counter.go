@@ -0,0 +1,25 @@
+package tokenizer
+
+import (
+	"context"
+
+	"charm.land/fantasy"
+)
+
+// messageOverhead is the tokens chat formats add around each message, such
+// as the role and separators.
+const messageOverhead = 4
+
+// Counter counts the input tokens of calls with a tokenizer instead of the
+// provider. The count is an estimate of what the provider reports, since
+// chat templates and tool definitions are rendered differently by each of
+// them.
+type Counter struct {
+	Tokenizer fantasy.Tokenizer
+}
+
+// CountTokens implements fantasy.TokenCounter.
+func (c Counter) CountTokens(_ context.Context, call fantasy.Call) (int64, error) {
+	breakdown := fantasy.CountInputBreakdown(call.Prompt, call.Tools, 0, c.Tokenizer)
+	return breakdown.Total() + int64(messageOverhead*len(call.Prompt)), nil
+}
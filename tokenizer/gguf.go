@@ -0,0 +1,238 @@
+package tokenizer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// GGUF metadata value types.
+const (
+	ggufUint8 uint32 = iota
+	ggufInt8
+	ggufUint16
+	ggufInt16
+	ggufUint32
+	ggufInt32
+	ggufFloat32
+	ggufBool
+	ggufString
+	ggufArray
+	ggufUint64
+	ggufInt64
+	ggufFloat64
+)
+
+// LoadGGUF returns the tokenizer described by the metadata of a GGUF model
+// file. Only SentencePiece vocabularies, tokenizer.ggml.model "llama", are
+// supported.
+func LoadGGUF(name string) (Tokenizer, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadGGUF(bufio.NewReader(f))
+}
+
+// ReadGGUF is like LoadGGUF but reads the file from r. Only the metadata at
+// the start of the file is read.
+func ReadGGUF(r io.Reader) (Tokenizer, error) {
+	metadata, err := readGGUFMetadata(r)
+	if err != nil {
+		return nil, fmt.Errorf("reading GGUF metadata: %w", err)
+	}
+	model, _ := metadata["tokenizer.ggml.model"].(string)
+	if model != "llama" {
+		return nil, fmt.Errorf("unsupported GGUF tokenizer model %q", model)
+	}
+	tokens, _ := metadata["tokenizer.ggml.tokens"].([]any)
+	if len(tokens) == 0 {
+		return nil, errors.New("GGUF file has no tokenizer vocabulary")
+	}
+	vocab := make([]Piece, len(tokens))
+	scores, _ := metadata["tokenizer.ggml.scores"].([]any)
+	types, _ := metadata["tokenizer.ggml.token_type"].([]any)
+	for i, token := range tokens {
+		vocab[i].Text, _ = token.(string)
+		vocab[i].Type = PieceNormal
+		if i < len(scores) {
+			score, _ := scores[i].(float32)
+			vocab[i].Score = score
+		}
+		if i < len(types) {
+			t, _ := types[i].(int32)
+			vocab[i].Type = PieceType(t)
+		}
+	}
+	addSpacePrefix := true
+	if add, ok := metadata["tokenizer.ggml.add_space_prefix"].(bool); ok {
+		addSpacePrefix = add
+	}
+	return NewSentencePiece(vocab, addSpacePrefix), nil
+}
+
+// readGGUFMetadata reads the metadata key-value pairs of a GGUF file. Arrays
+// are returned as []any; only the tokenizer keys are kept.
+func readGGUFMetadata(r io.Reader) (map[string]any, error) {
+	var header struct {
+		Magic   [4]byte
+		Version uint32
+	}
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, err
+	}
+	if string(header.Magic[:]) != "GGUF" {
+		return nil, errors.New("not a GGUF file")
+	}
+	if header.Version < 2 {
+		return nil, fmt.Errorf("unsupported GGUF version %d", header.Version)
+	}
+	var counts struct {
+		Tensors  uint64
+		Metadata uint64
+	}
+	if err := binary.Read(r, binary.LittleEndian, &counts); err != nil {
+		return nil, err
+	}
+	metadata := map[string]any{}
+	for range counts.Metadata {
+		key, err := readGGUFString(r)
+		if err != nil {
+			return nil, err
+		}
+		var valueType uint32
+		if err := binary.Read(r, binary.LittleEndian, &valueType); err != nil {
+			return nil, err
+		}
+		keep := strings.HasPrefix(key, "tokenizer.")
+		value, err := readGGUFValue(r, valueType, keep)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		if keep {
+			metadata[key] = value
+		}
+	}
+	return metadata, nil
+}
+
+// readGGUFValue reads a value of the type. When keep is false the value is
+// skipped and nil is returned.
+func readGGUFValue(r io.Reader, valueType uint32, keep bool) (any, error) {
+	switch valueType {
+	case ggufString:
+		if !keep {
+			return nil, skipGGUFString(r)
+		}
+		return readGGUFString(r)
+	case ggufArray:
+		var header struct {
+			Type  uint32
+			Count uint64
+		}
+		if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+			return nil, err
+		}
+		if header.Type == ggufArray {
+			return nil, errors.New("nested arrays are not supported")
+		}
+		if !keep {
+			if size := ggufSize(header.Type); size > 0 {
+				_, err := io.CopyN(io.Discard, r, int64(size)*int64(header.Count))
+				return nil, err
+			}
+		}
+		var values []any
+		if keep {
+			values = make([]any, 0, min(header.Count, 1<<20))
+		}
+		for range header.Count {
+			value, err := readGGUFValue(r, header.Type, keep)
+			if err != nil {
+				return nil, err
+			}
+			if keep {
+				values = append(values, value)
+			}
+		}
+		return values, nil
+	}
+	size := ggufSize(valueType)
+	if size == 0 {
+		return nil, fmt.Errorf("unknown value type %d", valueType)
+	}
+	var buf [8]byte
+	if _, err := io.ReadFull(r, buf[:size]); err != nil {
+		return nil, err
+	}
+	le := binary.LittleEndian
+	switch valueType {
+	case ggufUint8:
+		return buf[0], nil
+	case ggufInt8:
+		return int8(buf[0]), nil
+	case ggufUint16:
+		return le.Uint16(buf[:]), nil
+	case ggufInt16:
+		return int16(le.Uint16(buf[:])), nil
+	case ggufUint32:
+		return le.Uint32(buf[:]), nil
+	case ggufInt32:
+		return int32(le.Uint32(buf[:])), nil
+	case ggufFloat32:
+		return math.Float32frombits(le.Uint32(buf[:])), nil
+	case ggufBool:
+		return buf[0] != 0, nil
+	case ggufUint64:
+		return le.Uint64(buf[:]), nil
+	case ggufInt64:
+		return int64(le.Uint64(buf[:])), nil
+	default:
+		return math.Float64frombits(le.Uint64(buf[:])), nil
+	}
+}
+
+// ggufSize returns the size of a fixed size value type, or zero.
+func ggufSize(valueType uint32) int {
+	switch valueType {
+	case ggufUint8, ggufInt8, ggufBool:
+		return 1
+	case ggufUint16, ggufInt16:
+		return 2
+	case ggufUint32, ggufInt32, ggufFloat32:
+		return 4
+	case ggufUint64, ggufInt64, ggufFloat64:
+		return 8
+	}
+	return 0
+}
+
+func readGGUFString(r io.Reader) (string, error) {
+	var n uint64
+	if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+		return "", err
+	}
+	if n > 1<<30 {
+		return "", fmt.Errorf("string of %d bytes is too long", n)
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+func skipGGUFString(r io.Reader) error {
+	var n uint64
+	if err := binary.Read(r, binary.LittleEndian, &n); err != nil {
+		return err
+	}
+	_, err := io.CopyN(io.Discard, r, int64(n))
+	return err
+}
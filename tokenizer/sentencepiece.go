@@ -0,0 +1,199 @@
+package tokenizer
+
+import (
+	"container/heap"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// PieceType is the type of a SentencePiece vocabulary entry, numbered as in
+// GGUF files.
+type PieceType int32
+
+// Piece types.
+const (
+	PieceNormal      PieceType = 1
+	PieceUnknown     PieceType = 2
+	PieceControl     PieceType = 3
+	PieceUserDefined PieceType = 4
+	PieceUnused      PieceType = 5
+	PieceByte        PieceType = 6
+)
+
+// Piece is an entry of a SentencePiece vocabulary. Its ID is its index.
+type Piece struct {
+	Text  string
+	Score float32
+	Type  PieceType
+}
+
+// spaceMarker replaces spaces in SentencePiece pieces.
+const spaceMarker = "▁"
+
+type sentencePiece struct {
+	vocab          []Piece
+	ids            map[string]int
+	bytes          [256]int
+	unknown        int
+	addSpacePrefix bool
+}
+
+// NewSentencePiece returns a tokenizer merging pieces of the vocabulary by
+// score, as the BPE SentencePiece models of Llama and Mistral do. Text the
+// vocabulary doesn't cover falls back to byte pieces such as <0x41>. When
+// addSpacePrefix is set a space is added before the text, as SentencePiece
+// does by default. Control pieces are never produced.
+func NewSentencePiece(vocab []Piece, addSpacePrefix bool) Tokenizer {
+	sp := &sentencePiece{
+		vocab:          vocab,
+		ids:            make(map[string]int, len(vocab)),
+		unknown:        -1,
+		addSpacePrefix: addSpacePrefix,
+	}
+	for i := range sp.bytes {
+		sp.bytes[i] = -1
+	}
+	for id, piece := range vocab {
+		switch piece.Type {
+		case PieceControl:
+		case PieceUnknown:
+			if sp.unknown < 0 {
+				sp.unknown = id
+			}
+		case PieceByte:
+			var b int
+			if _, err := fmt.Sscanf(piece.Text, "<0x%02X>", &b); err == nil && b < 256 {
+				sp.bytes[b] = id
+			}
+		default:
+			if _, ok := sp.ids[piece.Text]; !ok {
+				sp.ids[piece.Text] = id
+			}
+		}
+	}
+	return sp
+}
+
+type spSymbol struct {
+	text       string
+	prev, next int
+}
+
+type spBigram struct {
+	left, right int
+	score       float32
+	text        string
+}
+
+type spBigrams []spBigram
+
+func (q spBigrams) Len() int { return len(q) }
+func (q spBigrams) Less(i, j int) bool {
+	if q[i].score != q[j].score {
+		return q[i].score > q[j].score
+	}
+	return q[i].left < q[j].left
+}
+func (q spBigrams) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+func (q *spBigrams) Push(x any)   { *q = append(*q, x.(spBigram)) }
+func (q *spBigrams) Pop() any {
+	old := *q
+	last := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return last
+}
+
+func (sp *sentencePiece) Encode(text string) []int {
+	if text == "" {
+		return nil
+	}
+	if sp.addSpacePrefix {
+		text = " " + text
+	}
+	text = strings.ReplaceAll(text, " ", spaceMarker)
+
+	symbols := make([]spSymbol, 0, utf8.RuneCountInString(text))
+	for i := range text {
+		_, size := utf8.DecodeRuneInString(text[i:])
+		symbols = append(symbols, spSymbol{text: text[i : i+size], prev: len(symbols) - 1, next: len(symbols) + 1})
+	}
+	symbols[len(symbols)-1].next = -1
+
+	queue := &spBigrams{}
+	tryBigram := func(left, right int) {
+		if left < 0 || right < 0 {
+			return
+		}
+		merged := symbols[left].text + symbols[right].text
+		if id, ok := sp.ids[merged]; ok {
+			heap.Push(queue, spBigram{left: left, right: right, score: sp.vocab[id].Score, text: merged})
+		}
+	}
+	for i := 1; i < len(symbols); i++ {
+		tryBigram(i-1, i)
+	}
+	for queue.Len() > 0 {
+		bigram := heap.Pop(queue).(spBigram)
+		left, right := &symbols[bigram.left], &symbols[bigram.right]
+		// Skip bigrams whose symbols were merged since they were queued.
+		if left.next != bigram.right || left.text+right.text != bigram.text {
+			continue
+		}
+		left.text = bigram.text
+		right.text = ""
+		left.next = right.next
+		if right.next >= 0 {
+			symbols[right.next].prev = bigram.left
+		}
+		tryBigram(left.prev, bigram.left)
+		tryBigram(bigram.left, left.next)
+	}
+
+	var tokens []int
+	for i := 0; i >= 0; i = symbols[i].next {
+		symbol := symbols[i].text
+		if id, ok := sp.ids[symbol]; ok {
+			tokens = append(tokens, id)
+			continue
+		}
+		for j := 0; j < len(symbol); j++ {
+			if id := sp.bytes[symbol[j]]; id >= 0 {
+				tokens = append(tokens, id)
+			} else if sp.unknown >= 0 {
+				tokens = append(tokens, sp.unknown)
+				break
+			}
+		}
+	}
+	return tokens
+}
+
+func (sp *sentencePiece) Decode(tokens []int) string {
+	var b strings.Builder
+	for _, id := range tokens {
+		if id < 0 || id >= len(sp.vocab) {
+			continue
+		}
+		piece := sp.vocab[id]
+		switch piece.Type {
+		case PieceControl:
+		case PieceByte:
+			var c int
+			if _, err := fmt.Sscanf(piece.Text, "<0x%02X>", &c); err == nil {
+				b.WriteByte(byte(c))
+			}
+		default:
+			b.WriteString(strings.ReplaceAll(piece.Text, spaceMarker, " "))
+		}
+	}
+	text := b.String()
+	if sp.addSpacePrefix {
+		text = strings.TrimPrefix(text, " ")
+	}
+	return text
+}
+
+func (sp *sentencePiece) Count(text string) int {
+	return len(sp.Encode(text))
+}
@@ -0,0 +1,141 @@
+package tokenizer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkoukk/tiktoken-go"
+)
+
+// Tiktoken encodings.
+const (
+	// O200KBase is the encoding of GPT-4o, GPT-4.1, GPT-5 and the o-series.
+	O200KBase = "o200k_base"
+	// CL100KBase is the encoding of GPT-4, GPT-3.5 and the third generation
+	// of embedding models.
+	CL100KBase = "cl100k_base"
+)
+
+type tiktokenEncoding struct {
+	pattern string
+	special map[string]int
+}
+
+var tiktokenEncodings = map[string]tiktokenEncoding{
+	O200KBase: {
+		pattern: strings.Join([]string{
+			`[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?`,
+			`[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?`,
+			`\p{N}{1,3}`,
+			` ?[^\s\p{L}\p{N}]+[\r\n/]*`,
+			`\s*[\r\n]+`,
+			`\s+(?!\S)`,
+			`\s+`,
+		}, "|"),
+		special: map[string]int{
+			"<|endoftext|>":   199999,
+			"<|endofprompt|>": 200018,
+		},
+	},
+	CL100KBase: {
+		pattern: `(?i:'s|'t|'re|'ve|'m|'ll|'d)|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+(?!\S)|\s+`,
+		special: map[string]int{
+			"<|endoftext|>":   100257,
+			"<|fim_prefix|>":  100258,
+			"<|fim_middle|>":  100259,
+			"<|fim_suffix|>":  100260,
+			"<|endofprompt|>": 100276,
+		},
+	},
+}
+
+type tiktokenTokenizer struct {
+	encoding *tiktoken.Tiktoken
+}
+
+// Tiktoken returns the tokenizer of a tiktoken encoding, such as O200KBase.
+// The encoding's ranks are downloaded from OpenAI the first time and cached
+// in TIKTOKEN_CACHE_DIR, or a temporary directory when it's unset.
+func Tiktoken(encoding string) (Tokenizer, error) {
+	enc, err := tiktoken.GetEncoding(encoding)
+	if err != nil {
+		return nil, err
+	}
+	return &tiktokenTokenizer{encoding: enc}, nil
+}
+
+// NewTiktoken returns the tokenizer of a tiktoken encoding with the ranks
+// read from a .tiktoken file, for environments that can't download them.
+func NewTiktoken(encoding string, ranks io.Reader) (Tokenizer, error) {
+	enc, ok := tiktokenEncodings[encoding]
+	if !ok {
+		return nil, fmt.Errorf("unknown tiktoken encoding %q", encoding)
+	}
+	mergeable, err := readRanks(ranks)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s ranks: %w", encoding, err)
+	}
+	bpe, err := tiktoken.NewCoreBPE(mergeable, enc.special, enc.pattern)
+	if err != nil {
+		return nil, err
+	}
+	specialSet := make(map[string]any, len(enc.special))
+	for token := range enc.special {
+		specialSet[token] = true
+	}
+	return &tiktokenTokenizer{
+		encoding: tiktoken.NewTiktoken(bpe, &tiktoken.Encoding{
+			Name:           encoding,
+			PatStr:         enc.pattern,
+			MergeableRanks: mergeable,
+			SpecialTokens:  enc.special,
+		}, specialSet),
+	}, nil
+}
+
+// readRanks reads a .tiktoken file: a base64 token and its rank per line.
+func readRanks(r io.Reader) (map[string]int, error) {
+	ranks := map[string]int{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		encoded, rank, ok := strings.Cut(line, " ")
+		if !ok {
+			return nil, fmt.Errorf("malformed line %q", line)
+		}
+		token, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, err
+		}
+		n, err := strconv.Atoi(rank)
+		if err != nil {
+			return nil, err
+		}
+		ranks[string(token)] = n
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ranks, nil
+}
+
+// Encode encodes text, treating special tokens such as <|endoftext|> as
+// plain text.
+func (t *tiktokenTokenizer) Encode(text string) []int {
+	return t.encoding.EncodeOrdinary(text)
+}
+
+func (t *tiktokenTokenizer) Decode(tokens []int) string {
+	return t.encoding.Decode(tokens)
+}
+
+func (t *tiktokenTokenizer) Count(text string) int {
+	return len(t.Encode(text))
+}
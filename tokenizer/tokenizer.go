@@ -0,0 +1,131 @@
+// Package tokenizer counts tokens locally, the way the tokenizer of a model
+// family does, so token counting, tool result limits and chunking agree
+// without calling the provider.
+//
+// Tokenizers are registered per model family and looked up by model ID:
+//
+//	tok, err := tokenizer.ForModel("gpt-4o-mini")
+//	...
+//	agent := fantasy.NewAgent(model, fantasy.WithTokenizer(tok), fantasy.WithToolResultLimit(2000, fantasy.TruncateMiddle))
+//	splitter := chunk.Markdown(chunk.Options{Size: 500, Length: chunk.TokenizedTokens(tok)})
+//
+// The OpenAI families use tiktoken, whose encodings are downloaded on first
+// use and cached in TIKTOKEN_CACHE_DIR. Models run locally from GGUF files,
+// such as with the kronk provider, use the SentencePiece vocabulary stored in
+// the file; see LoadGGUF.
+package tokenizer
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"charm.land/fantasy"
+)
+
+// Tokenizer encodes text into the tokens of a model family.
+type Tokenizer interface {
+	fantasy.Tokenizer
+	Encode(text string) []int
+	Decode(tokens []int) string
+}
+
+// Family is a group of models sharing a tokenizer.
+type Family struct {
+	Name string
+	// Prefixes are the model ID prefixes of the family. The family with the
+	// longest matching prefix wins.
+	Prefixes []string
+	// Load creates the tokenizer the first time a model of the family is
+	// looked up.
+	Load func() (Tokenizer, error)
+}
+
+// NotFoundError is returned when no family is registered for a model.
+type NotFoundError struct {
+	Model string
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("no tokenizer registered for model %q", e.Model)
+}
+
+type family struct {
+	Family
+	mu        sync.Mutex
+	tokenizer Tokenizer
+}
+
+var (
+	familiesMu sync.RWMutex
+	families   = map[string]*family{}
+)
+
+// Register registers the family, replacing any family previously registered
+// under its name.
+func Register(f Family) {
+	familiesMu.Lock()
+	defer familiesMu.Unlock()
+	families[f.Name] = &family{Family: f}
+}
+
+// ForModel returns the tokenizer of the family model belongs to, loading it
+// on first use, or a *NotFoundError. Model IDs are matched without their
+// provider prefix, so "openai/gpt-4o" matches "gpt-4o".
+func ForModel(model string) (Tokenizer, error) {
+	f := lookup(model)
+	if f == nil {
+		return nil, &NotFoundError{Model: model}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.tokenizer != nil {
+		return f.tokenizer, nil
+	}
+	tok, err := f.Load()
+	if err != nil {
+		return nil, fmt.Errorf("loading %s tokenizer: %w", f.Name, err)
+	}
+	f.tokenizer = tok
+	return tok, nil
+}
+
+// FamilyOf returns the name of the family model belongs to, and whether one
+// is registered.
+func FamilyOf(model string) (string, bool) {
+	f := lookup(model)
+	if f == nil {
+		return "", false
+	}
+	return f.Name, true
+}
+
+func lookup(model string) *family {
+	id := strings.ToLower(path.Base(model))
+	familiesMu.RLock()
+	defer familiesMu.RUnlock()
+	var best *family
+	var bestLen int
+	for _, f := range families {
+		for _, prefix := range f.Prefixes {
+			if len(prefix) > bestLen && strings.HasPrefix(id, prefix) {
+				best, bestLen = f, len(prefix)
+			}
+		}
+	}
+	return best
+}
+
+func init() {
+	Register(Family{
+		Name:     O200KBase,
+		Prefixes: []string{"gpt-4o", "chatgpt-4o", "gpt-4.1", "gpt-4.5", "gpt-5", "o1", "o3", "o4"},
+		Load:     func() (Tokenizer, error) { return Tiktoken(O200KBase) },
+	})
+	Register(Family{
+		Name:     CL100KBase,
+		Prefixes: []string{"gpt-4", "gpt-3.5-turbo", "text-embedding-3", "text-embedding-ada-002"},
+		Load:     func() (Tokenizer, error) { return Tiktoken(CL100KBase) },
+	})
+}
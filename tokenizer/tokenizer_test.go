@@ -0,0 +1,201 @@
+package tokenizer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/require"
+)
+
+// testRanks returns a .tiktoken file with every byte and a few merges.
+func testRanks() string {
+	var b strings.Builder
+	for i := range 256 {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(i)}), i)
+	}
+	for i, merge := range []string{"he", "ll", "hell", "hello", " w", "or", " wor", "ld", " world"} {
+		fmt.Fprintf(&b, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(merge)), 256+i)
+	}
+	return b.String()
+}
+
+func TestTiktoken(t *testing.T) {
+	t.Parallel()
+
+	tok, err := NewTiktoken(CL100KBase, strings.NewReader(testRanks()))
+	require.NoError(t, err)
+
+	tokens := tok.Encode("hello world!")
+	require.Equal(t, []int{259, 264, '!'}, tokens)
+	require.Equal(t, "hello world!", tok.Decode(tokens))
+	require.Equal(t, 3, tok.Count("hello world!"))
+
+	// Special tokens are counted as plain text.
+	require.Greater(t, tok.Count("<|endoftext|>"), 1)
+
+	_, err = NewTiktoken("p50k_base", strings.NewReader(testRanks()))
+	require.Error(t, err)
+}
+
+type ggufKV struct {
+	key   string
+	value any
+}
+
+// writeGGUF writes a GGUF file with the metadata and no tensors.
+func writeGGUF(t *testing.T, kvs []ggufKV) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	le := binary.LittleEndian
+	writeString := func(s string) {
+		require.NoError(t, binary.Write(&buf, le, uint64(len(s))))
+		buf.WriteString(s)
+	}
+	buf.WriteString("GGUF")
+	require.NoError(t, binary.Write(&buf, le, uint32(3)))
+	require.NoError(t, binary.Write(&buf, le, uint64(0)))
+	require.NoError(t, binary.Write(&buf, le, uint64(len(kvs))))
+	for _, kv := range kvs {
+		writeString(kv.key)
+		switch v := kv.value.(type) {
+		case string:
+			require.NoError(t, binary.Write(&buf, le, ggufString))
+			writeString(v)
+		case bool:
+			require.NoError(t, binary.Write(&buf, le, ggufBool))
+			require.NoError(t, binary.Write(&buf, le, v))
+		case uint32:
+			require.NoError(t, binary.Write(&buf, le, ggufUint32))
+			require.NoError(t, binary.Write(&buf, le, v))
+		case []string:
+			require.NoError(t, binary.Write(&buf, le, ggufArray))
+			require.NoError(t, binary.Write(&buf, le, ggufString))
+			require.NoError(t, binary.Write(&buf, le, uint64(len(v))))
+			for _, s := range v {
+				writeString(s)
+			}
+		case []float32:
+			require.NoError(t, binary.Write(&buf, le, ggufArray))
+			require.NoError(t, binary.Write(&buf, le, ggufFloat32))
+			require.NoError(t, binary.Write(&buf, le, uint64(len(v))))
+			for _, f := range v {
+				require.NoError(t, binary.Write(&buf, le, math.Float32bits(f)))
+			}
+		case []int32:
+			require.NoError(t, binary.Write(&buf, le, ggufArray))
+			require.NoError(t, binary.Write(&buf, le, ggufInt32))
+			require.NoError(t, binary.Write(&buf, le, uint64(len(v))))
+			for _, i := range v {
+				require.NoError(t, binary.Write(&buf, le, i))
+			}
+		default:
+			t.Fatalf("unsupported value %T", v)
+		}
+	}
+	return buf.Bytes()
+}
+
+func testGGUF(t *testing.T) []byte {
+	t.Helper()
+	tokens := []string{"<unk>", "<s>", "</s>"}
+	types := []int32{int32(PieceUnknown), int32(PieceControl), int32(PieceControl)}
+	for i := range 256 {
+		tokens = append(tokens, fmt.Sprintf("<0x%02X>", i))
+		types = append(types, int32(PieceByte))
+	}
+	pieces := []string{"▁", "h", "e", "l", "o", "w", "r", "d", "▁h", "▁he", "ll", "▁hell", "▁hello", "or", "▁w", "▁wor", "▁worl", "▁world"}
+	tokens = append(tokens, pieces...)
+	scores := make([]float32, len(tokens))
+	for i := range pieces {
+		types = append(types, int32(PieceNormal))
+		scores[len(tokens)-len(pieces)+i] = float32(i)
+	}
+	return writeGGUF(t, []ggufKV{
+		{"general.architecture", "llama"},
+		{"general.alignment", uint32(32)},
+		{"tokenizer.ggml.model", "llama"},
+		{"tokenizer.ggml.tokens", tokens},
+		{"tokenizer.ggml.scores", scores},
+		{"tokenizer.ggml.token_type", types},
+		{"tokenizer.ggml.merges", []string{"▁ h", "h e"}},
+	})
+}
+
+func TestReadGGUF(t *testing.T) {
+	t.Parallel()
+
+	tok, err := ReadGGUF(bytes.NewReader(testGGUF(t)))
+	require.NoError(t, err)
+
+	vocabSize := 3 + 256
+	tokens := tok.Encode("hello world")
+	require.Equal(t, []int{vocabSize + 12, vocabSize + 17}, tokens)
+	require.Equal(t, "hello world", tok.Decode(tokens))
+
+	// Characters outside of the vocabulary fall back to bytes.
+	tokens = tok.Encode("hé")
+	require.Equal(t, []int{vocabSize + 8, 3 + 0xC3, 3 + 0xA9}, tokens)
+	require.Equal(t, "hé", tok.Decode(tokens))
+
+	t.Run("unsupported model", func(t *testing.T) {
+		t.Parallel()
+		data := writeGGUF(t, []ggufKV{{"tokenizer.ggml.model", "gpt2"}})
+		_, err := ReadGGUF(bytes.NewReader(data))
+		require.ErrorContains(t, err, `unsupported GGUF tokenizer model "gpt2"`)
+	})
+
+	t.Run("not a GGUF file", func(t *testing.T) {
+		t.Parallel()
+		_, err := ReadGGUF(strings.NewReader("GGML and some more bytes"))
+		require.Error(t, err)
+	})
+}
+
+func TestForModel(t *testing.T) {
+	t.Parallel()
+
+	Register(Family{
+		Name:     "test-family",
+		Prefixes: []string{"test-model", "test-model-large"},
+		Load: func() (Tokenizer, error) {
+			return NewTiktoken(CL100KBase, strings.NewReader(testRanks()))
+		},
+	})
+
+	tok, err := ForModel("provider/Test-Model-Large-2")
+	require.NoError(t, err)
+	again, err := ForModel("test-model")
+	require.NoError(t, err)
+	require.Same(t, tok, again)
+
+	family, ok := FamilyOf("gpt-4o-mini")
+	require.True(t, ok)
+	require.Equal(t, O200KBase, family)
+	family, ok = FamilyOf("gpt-4-turbo")
+	require.True(t, ok)
+	require.Equal(t, CL100KBase, family)
+
+	_, err = ForModel("claude-sonnet-4")
+	var notFound *NotFoundError
+	require.ErrorAs(t, err, &notFound)
+}
+
+func TestCounter(t *testing.T) {
+	t.Parallel()
+
+	tok, err := NewTiktoken(CL100KBase, strings.NewReader(testRanks()))
+	require.NoError(t, err)
+
+	var counter fantasy.TokenCounter = Counter{Tokenizer: tok}
+	n, err := counter.CountTokens(t.Context(), fantasy.Call{
+		Prompt: fantasy.Prompt{fantasy.NewUserMessage("hello world")},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(2+messageOverhead), n)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"reflect"
 
 	"charm.land/fantasy/schema"
@@ -26,6 +27,11 @@ type ToolCall struct {
 	ID    string `json:"id"`
 	Name  string `json:"name"`
 	Input string `json:"input"`
+	// Output streams intermediate output of the tool, such as the stdout
+	// of a command, to the OnToolExecutionDelta callback of Agent.Stream.
+	// Writes are dropped when nothing listens. It is never nil when the
+	// agent runs the tool.
+	Output io.Writer `json:"-"`
 }
 
 // ToolResponse represents the response from a tool execution, matching the existing pattern.
@@ -39,6 +45,18 @@ type ToolResponse struct {
 	Metadata  string `json:"metadata,omitempty"`
 	IsError   bool   `json:"is_error"`
 	StopTurn  bool   `json:"stop_turn,omitempty"`
+	// Sensitive marks the response as one transcripts redact. See
+	// SensitiveToolResult.
+	Sensitive bool `json:"sensitive,omitempty"`
+	// Fatal marks an error response that aborts the agent run. See
+	// NewFatalErrorResponse.
+	Fatal bool `json:"fatal,omitempty"`
+	// Err is the error of an error response created from an error, kept in
+	// the tool result for callbacks. The model only sees Content.
+	Err error `json:"-"`
+	// Deferred is the job of a response whose result comes later. See
+	// Deferred.
+	Deferred DeferredHandle `json:"-"`
 }
 
 // NewTextResponse creates a text response.
@@ -58,6 +76,31 @@ func NewTextErrorResponse(content string) ToolResponse {
 	}
 }
 
+// NewRetryableErrorResponse creates an error response telling the model
+// the tool failed, so it can adapt, for example by fixing its input or
+// trying another tool. The agent run goes on.
+func NewRetryableErrorResponse(err error) ToolResponse {
+	return ToolResponse{
+		Type:    "text",
+		Content: err.Error(),
+		IsError: true,
+		Err:     err,
+	}
+}
+
+// NewFatalErrorResponse creates an error response that aborts the agent
+// run: Generate and Stream return a *ToolFatalError wrapping err, without
+// calling the model again.
+func NewFatalErrorResponse(err error) ToolResponse {
+	return ToolResponse{
+		Type:    "text",
+		Content: err.Error(),
+		IsError: true,
+		Fatal:   true,
+		Err:     err,
+	}
+}
+
 // NewImageResponse creates an image response with binary data.
 func NewImageResponse(data []byte, mediaType string) ToolResponse {
 	return ToolResponse{
@@ -76,6 +119,15 @@ func NewMediaResponse(data []byte, mediaType string) ToolResponse {
 	}
 }
 
+// SensitiveToolResult marks a response as sensitive, such as one holding
+// personal data or secrets. The model still receives it, but the transcript
+// package replaces it with a placeholder when the conversation is exported
+// with transcript.WithRedactedToolResults.
+func SensitiveToolResult(response ToolResponse) ToolResponse {
+	response.Sensitive = true
+	return response
+}
+
 // WithResponseMetadata adds metadata to a response.
 func WithResponseMetadata(response ToolResponse, metadata any) ToolResponse {
 	if metadata != nil {
@@ -88,6 +140,46 @@ func WithResponseMetadata(response ToolResponse, metadata any) ToolResponse {
 	return response
 }
 
+type toolOutputContextKey struct{}
+
+type toolOutputReporter struct {
+	id string
+	fn OnToolExecutionDeltaFunc
+}
+
+// ReportToolOutput reports output of a running tool, such as the lines a
+// command prints, so that UIs can show it live. It's the same as writing to
+// ToolCall.Output: when the tool runs in Agent.Stream the delta goes to the
+// OnToolExecutionDelta and OnChunk callbacks; otherwise it is dropped.
+// Reported output is not added to the conversation, which gets the tool's
+// ToolResponse as usual. Parallel tools may report concurrently.
+func ReportToolOutput(ctx context.Context, delta string) error {
+	reporter, ok := ctx.Value(toolOutputContextKey{}).(toolOutputReporter)
+	if !ok || delta == "" {
+		return nil
+	}
+	return reporter.fn(reporter.id, delta)
+}
+
+func withToolOutputReporter(ctx context.Context, toolCallID string, fn OnToolExecutionDeltaFunc) context.Context {
+	if fn == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, toolOutputContextKey{}, toolOutputReporter{id: toolCallID, fn: fn})
+}
+
+// toolOutputWriter is the ToolCall.Output of a tool run with ctx.
+type toolOutputWriter struct {
+	ctx context.Context
+}
+
+func (w toolOutputWriter) Write(p []byte) (int, error) {
+	if err := ReportToolOutput(w.ctx, string(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
 // AgentTool represents a tool that can be called by a language model.
 // This matches the existing BaseTool interface pattern.
 type AgentTool interface {
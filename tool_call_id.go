@@ -0,0 +1,198 @@
+package fantasy
+
+import "sync"
+
+// toolCallIDs makes the IDs of the tool calls of an agent run unique, as
+// some providers reuse IDs across responses or omit them, which breaks
+// matching tool results to their calls. A tool call whose ID is empty or
+// already used in the conversation gets a new ID from NewID, which the agent
+// uses for the rest of the run: in the step content, the messages, the
+// callbacks and the tool execution.
+//
+// The original IDs are restored in the prompts sent to the model, so the
+// provider gets back the IDs it returned, unless that would make two calls
+// in the prompt share an ID, or the original ID is empty.
+// Provider-executed tool calls are left to the provider.
+type toolCallIDs struct {
+	mu sync.Mutex
+	// used are the IDs in the conversation.
+	used map[string]bool
+	// original maps the new IDs to the IDs the provider returned.
+	original map[string]string
+	// streaming maps the IDs of the tool calls being streamed to their new
+	// IDs.
+	streaming map[string]string
+}
+
+// newToolCallIDs returns the tool call IDs of a run continuing the prompt.
+func newToolCallIDs(prompt Prompt) *toolCallIDs {
+	ids := &toolCallIDs{
+		used:      map[string]bool{},
+		original:  map[string]string{},
+		streaming: map[string]string{},
+	}
+	for _, msg := range prompt {
+		for _, part := range msg.Content {
+			if call, ok := AsMessagePart[ToolCallPart](part); ok {
+				ids.used[call.ToolCallID] = true
+			}
+		}
+	}
+	return ids
+}
+
+// unique returns the ID to use for a tool call with the provider's ID.
+// Callers must hold the lock.
+func (ids *toolCallIDs) unique(id string) string {
+	if id != "" && !ids.used[id] {
+		ids.used[id] = true
+		return id
+	}
+	newID := NewID()
+	for ids.used[newID] {
+		newID = NewID()
+	}
+	ids.used[newID] = true
+	ids.original[newID] = id
+	return newID
+}
+
+// uniqueContent gives the tool calls of the content unique IDs.
+func (ids *toolCallIDs) uniqueContent(content ResponseContent) ResponseContent {
+	ids.mu.Lock()
+	defer ids.mu.Unlock()
+	var unique ResponseContent
+	for i, c := range content {
+		call, ok := AsContentType[ToolCallContent](c)
+		if !ok || call.ProviderExecuted {
+			continue
+		}
+		id := ids.unique(call.ToolCallID)
+		if id == call.ToolCallID {
+			continue
+		}
+		if unique == nil {
+			unique = make(ResponseContent, len(content))
+			copy(unique, content)
+		}
+		call.ToolCallID = id
+		unique[i] = call
+	}
+	if unique == nil {
+		return content
+	}
+	return unique
+}
+
+// uniqueStream gives the tool calls of the stream unique IDs.
+func (ids *toolCallIDs) uniqueStream(stream StreamResponse) StreamResponse {
+	return func(yield func(StreamPart) bool) {
+		for part := range stream {
+			if !part.ProviderExecuted {
+				part.ID = ids.uniqueStreamPart(part)
+			}
+			if !yield(part) {
+				return
+			}
+		}
+	}
+}
+
+func (ids *toolCallIDs) uniqueStreamPart(part StreamPart) string {
+	ids.mu.Lock()
+	defer ids.mu.Unlock()
+	switch part.Type {
+	case StreamPartTypeToolInputStart:
+		id := ids.unique(part.ID)
+		ids.streaming[part.ID] = id
+		return id
+	case StreamPartTypeToolInputDelta, StreamPartTypeToolInputEnd:
+		if id, ok := ids.streaming[part.ID]; ok {
+			return id
+		}
+	case StreamPartTypeToolCall:
+		if id, ok := ids.streaming[part.ID]; ok {
+			delete(ids.streaming, part.ID)
+			return id
+		}
+		return ids.unique(part.ID)
+	}
+	return part.ID
+}
+
+// restore returns the prompt with the original IDs of the tool calls and
+// results. The prompt is left untouched.
+func (ids *toolCallIDs) restore(prompt Prompt) Prompt {
+	ids.mu.Lock()
+	defer ids.mu.Unlock()
+	if len(ids.original) == 0 {
+		return prompt
+	}
+
+	var calls []string
+	count := map[string]int{}
+	for _, msg := range prompt {
+		if msg.Role != MessageRoleAssistant {
+			continue
+		}
+		for _, part := range msg.Content {
+			if call, ok := AsMessagePart[ToolCallPart](part); ok {
+				calls = append(calls, call.ToolCallID)
+				count[ids.restoredID(call.ToolCallID)]++
+			}
+		}
+	}
+	restored := map[string]string{}
+	for _, id := range calls {
+		if original, ok := ids.original[id]; ok && original != "" && count[original] == 1 {
+			restored[id] = original
+		}
+	}
+	if len(restored) == 0 {
+		return prompt
+	}
+
+	result := make(Prompt, len(prompt))
+	for i, msg := range prompt {
+		var content []MessagePart
+		for j, part := range msg.Content {
+			switch part := part.(type) {
+			case ToolCallPart:
+				if original, ok := restored[part.ToolCallID]; ok {
+					part.ToolCallID = original
+					content = restoredContent(content, msg.Content)
+					content[j] = part
+				}
+			case ToolResultPart:
+				if original, ok := restored[part.ToolCallID]; ok {
+					part.ToolCallID = original
+					content = restoredContent(content, msg.Content)
+					content[j] = part
+				}
+			}
+		}
+		if content != nil {
+			msg.Content = content
+		}
+		result[i] = msg
+	}
+	return result
+}
+
+// restoredID returns the ID a tool call is sent with if its original ID is
+// restored. Callers must hold the lock.
+func (ids *toolCallIDs) restoredID(id string) string {
+	if original, ok := ids.original[id]; ok && original != "" {
+		return original
+	}
+	return id
+}
+
+// restoredContent returns content, or a copy of parts to restore the IDs
+// in if it's nil.
+func restoredContent(content, parts []MessagePart) []MessagePart {
+	if content != nil {
+		return content
+	}
+	return append([]MessagePart(nil), parts...)
+}
@@ -0,0 +1,183 @@
+package fantasy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAgent_UniqueToolCallIDs(t *testing.T) {
+	t.Parallel()
+
+	// The provider numbers tool calls per response and omits some IDs.
+	var prompts []Prompt
+	model := &mockLanguageModel{
+		generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+			prompts = append(prompts, call.Prompt)
+			switch len(prompts) {
+			case 1:
+				return &Response{Content: ResponseContent{
+					ToolCallContent{ToolCallID: "call_0", ToolName: "lookup", Input: `{}`},
+					ToolCallContent{ToolCallID: "", ToolName: "lookup", Input: `{}`},
+				}, FinishReason: FinishReasonToolCalls}, nil
+			case 2:
+				return &Response{Content: ResponseContent{
+					ToolCallContent{ToolCallID: "call_0", ToolName: "lookup", Input: `{}`},
+					ToolCallContent{ToolCallID: "call_0", ToolName: "lookup", Input: `{}`},
+				}, FinishReason: FinishReasonToolCalls}, nil
+			}
+			return &Response{Content: ResponseContent{TextContent{Text: "done"}}, FinishReason: FinishReasonStop}, nil
+		},
+	}
+	var executed []string
+	tool := &mockTool{
+		name: "lookup",
+		executeFunc: func(ctx context.Context, call ToolCall) (ToolResponse, error) {
+			executed = append(executed, call.ID)
+			return NewTextResponse("found " + call.ID), nil
+		},
+	}
+	agent := NewAgent(model, WithTools(tool))
+	result, err := agent.Generate(t.Context(), AgentCall{Prompt: "hello"})
+	require.NoError(t, err)
+
+	require.Len(t, executed, 4)
+	require.Equal(t, "call_0", executed[0])
+	seen := map[string]bool{}
+	for _, id := range executed {
+		require.NotEmpty(t, id)
+		require.False(t, seen[id], "duplicate ID %s", id)
+		seen[id] = true
+	}
+	var calls []string
+	for _, step := range result.Steps {
+		for _, call := range step.Content.ToolCalls() {
+			calls = append(calls, call.ToolCallID)
+		}
+	}
+	require.Equal(t, executed, calls)
+
+	// The provider gets its own IDs back, except the empty one and the ones
+	// duplicated in the prompt.
+	last := prompts[2]
+	require.Len(t, last, 5)
+	require.Equal(t, []string{"call_0", executed[1]}, toolCallIDsOf(last[1]))
+	require.Equal(t, []string{"call_0", executed[1]}, toolCallIDsOf(last[2]))
+	require.Equal(t, executed[2:], toolCallIDsOf(last[3]))
+	require.Equal(t, executed[2:], toolCallIDsOf(last[4]))
+	// The agent's messages keep the unique IDs.
+	require.Equal(t, executed[2:], toolCallIDsOf(result.Steps[1].Messages[0]))
+}
+
+func TestAgent_UniqueToolCallIDs_AcrossTurns(t *testing.T) {
+	t.Parallel()
+
+	// The provider numbers tool calls per response.
+	var prompts []Prompt
+	model := &mockLanguageModel{
+		generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+			prompts = append(prompts, call.Prompt)
+			if len(prompts) <= 2 {
+				return &Response{Content: ResponseContent{
+					ToolCallContent{ToolCallID: "call_0", ToolName: "lookup", Input: `{}`},
+				}, FinishReason: FinishReasonToolCalls}, nil
+			}
+			return &Response{Content: ResponseContent{TextContent{Text: "done"}}, FinishReason: FinishReasonStop}, nil
+		},
+	}
+	tool := &mockTool{
+		name: "lookup",
+		executeFunc: func(ctx context.Context, call ToolCall) (ToolResponse, error) {
+			return NewTextResponse("found"), nil
+		},
+	}
+	result, err := NewAgent(model, WithTools(tool)).Generate(t.Context(), AgentCall{Prompt: "hello"})
+	require.NoError(t, err)
+	second := result.Steps[1].Content.ToolCalls()[0].ToolCallID
+	require.NotEqual(t, "call_0", second)
+
+	// The second call keeps its new ID, or the prompt would send call_0
+	// twice.
+	last := prompts[2]
+	require.Len(t, last, 5)
+	require.Equal(t, []string{"call_0"}, toolCallIDsOf(last[1]))
+	require.Equal(t, []string{"call_0"}, toolCallIDsOf(last[2]))
+	require.Equal(t, []string{second}, toolCallIDsOf(last[3]))
+	require.Equal(t, []string{second}, toolCallIDsOf(last[4]))
+}
+
+func TestAgent_UniqueToolCallIDs_Stream(t *testing.T) {
+	t.Parallel()
+
+	steps := 0
+	model := &mockLanguageModel{
+		streamFunc: func(ctx context.Context, call Call) (StreamResponse, error) {
+			steps++
+			return func(yield func(StreamPart) bool) {
+				if steps > 2 {
+					yield(StreamPart{Type: StreamPartTypeTextStart, ID: "text"})
+					yield(StreamPart{Type: StreamPartTypeTextDelta, ID: "text", Delta: "done"})
+					yield(StreamPart{Type: StreamPartTypeTextEnd, ID: "text"})
+					yield(StreamPart{Type: StreamPartTypeFinish, FinishReason: FinishReasonStop})
+					return
+				}
+				for _, part := range []StreamPart{
+					{Type: StreamPartTypeToolInputStart, ID: "tool", ToolCallName: "lookup"},
+					{Type: StreamPartTypeToolInputDelta, ID: "tool", Delta: `{}`},
+					{Type: StreamPartTypeToolInputEnd, ID: "tool"},
+					{Type: StreamPartTypeToolCall, ID: "tool", ToolCallName: "lookup", ToolCallInput: `{}`},
+					{Type: StreamPartTypeFinish, FinishReason: FinishReasonToolCalls},
+				} {
+					if !yield(part) {
+						return
+					}
+				}
+			}, nil
+		},
+	}
+	tool := &mockTool{
+		name: "lookup",
+		executeFunc: func(ctx context.Context, call ToolCall) (ToolResponse, error) {
+			return NewTextResponse("found"), nil
+		},
+	}
+	var started, deltas, called []string
+	agent := NewAgent(model, WithTools(tool))
+	result, err := agent.Stream(t.Context(), AgentStreamCall{
+		Prompt: "hello",
+		OnToolInputStart: func(id, toolName string) error {
+			started = append(started, id)
+			return nil
+		},
+		OnToolInputDelta: func(id, delta string) error {
+			deltas = append(deltas, id)
+			return nil
+		},
+		OnToolCall: func(toolCall ToolCallContent) error {
+			called = append(called, toolCall.ToolCallID)
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Steps, 3)
+	require.Equal(t, "tool", called[0])
+	require.Len(t, called, 2)
+	require.NotEqual(t, called[0], called[1])
+	require.Equal(t, called, started)
+	require.Equal(t, called, deltas)
+	require.Equal(t, called[1:], toolCallIDsOf(result.Steps[1].Messages[0]))
+}
+
+func toolCallIDsOf(msg Message) []string {
+	var ids []string
+	for _, part := range msg.Content {
+		switch part := part.(type) {
+		case ToolCallPart:
+			ids = append(ids, part.ToolCallID)
+		case ToolResultPart:
+			ids = append(ids, part.ToolCallID)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,66 @@
+package fantasy
+
+import "context"
+
+// ToolErrorAction is what the agent does with an error returned by a tool,
+// as decided by an OnToolErrorFunc.
+type ToolErrorAction int
+
+const (
+	// ToolErrorAbort aborts the run: Generate and Stream return the error
+	// wrapped in a *ToolFatalError.
+	ToolErrorAbort ToolErrorAction = iota
+	// ToolErrorReport sends the error to the model as the tool's result, as
+	// with NewRetryableErrorResponse, and the run goes on.
+	ToolErrorReport
+	// ToolErrorRetry runs the tool again. If it fails again, OnToolError is
+	// called again, so callbacks retrying should count the attempts of each
+	// ToolCall.ID.
+	ToolErrorRetry
+)
+
+// OnToolErrorFunc is called when a tool returns an error or panics, after
+// the retries of WithToolRetry, and decides what the agent does with the
+// error. Without it, the agent ends the run after the step, as with
+// ToolErrorAbort, but Generate doesn't return the error.
+type OnToolErrorFunc func(toolCall ToolCall, err error) ToolErrorAction
+
+type toolErrorContextKey struct{}
+
+// withToolErrorHandler makes the tools run with ctx report their errors to
+// fn.
+func withToolErrorHandler(ctx context.Context, fn OnToolErrorFunc) context.Context {
+	if fn == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, toolErrorContextKey{}, fn)
+}
+
+// handleToolError runs the tool again while OnToolError asks to retry, and
+// returns the response, the number of retries and the error once the
+// callback decided what to do with it. Reported errors become an error
+// response and aborting errors a *ToolFatalError.
+func (a *agent) handleToolError(ctx context.Context, run func(context.Context, ToolCall) (ToolResponse, error), call ToolCall, resp ToolResponse, err error) (ToolResponse, int, error) {
+	onToolError, ok := ctx.Value(toolErrorContextKey{}).(OnToolErrorFunc)
+	if !ok {
+		return resp, 0, err
+	}
+	retries := 0
+	for err != nil {
+		switch onToolError(call, err) {
+		case ToolErrorRetry:
+			var n int
+			resp, n, err = a.runTool(ctx, run, call)
+			retries += 1 + n
+		case ToolErrorReport:
+			resp.Type = "text"
+			resp.Content = err.Error()
+			resp.IsError = true
+			resp.Err = err
+			return resp, retries, nil
+		default:
+			return resp, retries, &ToolFatalError{ToolCallID: call.ID, ToolName: call.Name, Err: err}
+		}
+	}
+	return resp, retries, nil
+}
@@ -0,0 +1,85 @@
+package fantasy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnToolError(t *testing.T) {
+	t.Parallel()
+
+	errUnavailable := errors.New("service unavailable")
+	// run runs an agent whose tool fails on its first run, panicking if
+	// panics is set, and returns the result and the errors OnToolError got.
+	run := func(t *testing.T, action ToolErrorAction, panics bool) (*AgentResult, []error, error) {
+		runs := 0
+		tool := &mockTool{
+			name: "lookup",
+			executeFunc: func(ctx context.Context, call ToolCall) (ToolResponse, error) {
+				runs++
+				if runs > 1 {
+					return NewTextResponse("found"), nil
+				}
+				if panics {
+					panic("boom")
+				}
+				return ToolResponse{}, errUnavailable
+			},
+		}
+		var errs []error
+		agent := NewAgent(
+			toolCallingModel(ToolCallContent{ToolCallID: "call-1", ToolName: "lookup", Input: `{}`}),
+			WithTools(tool),
+		)
+		result, err := agent.Generate(t.Context(), AgentCall{
+			Prompt: "hello",
+			OnToolError: func(toolCall ToolCall, err error) ToolErrorAction {
+				require.Equal(t, "call-1", toolCall.ID)
+				errs = append(errs, err)
+				return action
+			},
+		})
+		return result, errs, err
+	}
+
+	t.Run("retry", func(t *testing.T) {
+		t.Parallel()
+		result, errs, err := run(t, ToolErrorRetry, false)
+		require.NoError(t, err)
+		require.Equal(t, []error{errUnavailable}, errs)
+		toolResult := result.Steps[0].Content.ToolResults()[0]
+		require.Equal(t, ToolResultOutputContentText{Text: "found"}, toolResult.Result)
+		require.Equal(t, 1, toolResult.Retries)
+	})
+
+	t.Run("report", func(t *testing.T) {
+		t.Parallel()
+		result, _, err := run(t, ToolErrorReport, false)
+		require.NoError(t, err)
+		require.Len(t, result.Steps, 2)
+		toolResult := result.Steps[0].Content.ToolResults()[0]
+		require.ErrorIs(t, toolResult.Result.(ToolResultOutputContentError).Error, errUnavailable)
+	})
+
+	t.Run("abort", func(t *testing.T) {
+		t.Parallel()
+		_, _, err := run(t, ToolErrorAbort, false)
+		require.ErrorIs(t, err, errUnavailable)
+		var fatalErr *ToolFatalError
+		require.ErrorAs(t, err, &fatalErr)
+		require.Equal(t, "lookup", fatalErr.ToolName)
+	})
+
+	t.Run("panic", func(t *testing.T) {
+		t.Parallel()
+		result, errs, err := run(t, ToolErrorReport, true)
+		require.NoError(t, err)
+		require.Len(t, errs, 1)
+		var panicErr *PanicError
+		require.ErrorAs(t, errs[0], &panicErr)
+		require.Len(t, result.Steps, 2)
+	})
+}
@@ -0,0 +1,148 @@
+package fantasy
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// TruncationStrategy decides how WithToolResultLimit shortens a tool result
+// that is over the limit.
+type TruncationStrategy string
+
+const (
+	// TruncateKeepHead keeps the beginning of the output.
+	TruncateKeepHead TruncationStrategy = "head"
+	// TruncateKeepTail keeps the end of the output, such as the last lines
+	// of a log.
+	TruncateKeepTail TruncationStrategy = "tail"
+	// TruncateMiddle keeps the beginning and the end of the output and
+	// removes the middle.
+	TruncateMiddle TruncationStrategy = "middle"
+	// TruncateSummarize asks the step's model to summarize the output. It
+	// falls back to TruncateMiddle if summarizing fails or the summary is
+	// still over the limit.
+	TruncateSummarize TruncationStrategy = "summarize"
+)
+
+// charsPerToken is the estimate used to turn token limits into text lengths.
+const charsPerToken = 4
+
+const toolResultSummaryPrompt = "Summarize the following tool output for an AI agent that requested it. " +
+	"Keep the details the agent is likely to need, such as names, numbers, paths, errors and exact matches. " +
+	"Reply with the summary only."
+
+type toolResultLimit struct {
+	maxTokens int64
+	strategy  TruncationStrategy
+}
+
+// WithToolResultLimit limits the text of tool results added to the
+// conversation to about maxTokens tokens, shortening longer ones with the
+// strategy. Only the messages sent back to the model are shortened;
+// StepResult.Content and the OnToolResult callback still receive the full
+// output.
+func WithToolResultLimit(maxTokens int64, strategy TruncationStrategy) AgentOption {
+	return func(s *agentSettings) {
+		s.toolResultLimit = &toolResultLimit{maxTokens: maxTokens, strategy: strategy}
+	}
+}
+
+// WithTokenizer makes the agent count tokens with tokenizer where it
+// otherwise estimates them at about four characters per token, such as when
+// applying WithToolResultLimit.
+func WithTokenizer(tokenizer Tokenizer) AgentOption {
+	return func(s *agentSettings) {
+		s.tokenizer = tokenizer
+	}
+}
+
+// limitToolResults returns the messages with oversized tool results
+// shortened according to the agent's tool result limit.
+func (a *agent) limitToolResults(ctx context.Context, model LanguageModel, messages []Message) []Message {
+	limit := a.settings.toolResultLimit
+	if limit == nil || limit.maxTokens <= 0 {
+		return messages
+	}
+	maxChars := int(limit.maxTokens * charsPerToken)
+
+	result := make([]Message, len(messages))
+	for i, msg := range messages {
+		result[i] = msg
+		if msg.Role != MessageRoleTool {
+			continue
+		}
+		result[i].Content = make([]MessagePart, len(msg.Content))
+		for j, part := range msg.Content {
+			result[i].Content[j] = part
+			toolResult, ok := AsMessagePart[ToolResultPart](part)
+			if !ok {
+				continue
+			}
+			text, ok := AsToolResultOutputType[ToolResultOutputContentText](toolResult.Output)
+			if !ok {
+				continue
+			}
+			textChars := maxChars
+			if tokenizer := a.settings.tokenizer; tokenizer != nil {
+				tokens := int64(tokenizer.Count(text.Text))
+				if tokens <= limit.maxTokens {
+					continue
+				}
+				// Keep the share of the text the limit allows.
+				textChars = int(int64(len([]rune(text.Text))) * limit.maxTokens / tokens)
+			}
+			if len([]rune(text.Text)) <= textChars {
+				continue
+			}
+			if limit.strategy == TruncateSummarize {
+				text.Text = summarizeToolResult(ctx, model, text.Text, limit.maxTokens, textChars)
+			} else {
+				text.Text = truncateText(text.Text, textChars, limit.strategy)
+			}
+			toolResult.Output = text
+			result[i].Content[j] = toolResult
+		}
+	}
+	return result
+}
+
+// summarizeToolResult summarizes a tool output with the model, falling back
+// to truncating its middle.
+func summarizeToolResult(ctx context.Context, model LanguageModel, text string, maxTokens int64, maxChars int) string {
+	resp, err := model.Generate(ctx, Call{
+		Prompt: Prompt{
+			NewSystemMessage(toolResultSummaryPrompt),
+			NewUserMessage(text),
+		},
+		MaxOutputTokens: &maxTokens,
+	})
+	if err != nil {
+		return truncateText(text, maxChars, TruncateMiddle)
+	}
+	summary := strings.TrimSpace(resp.Content.Text())
+	if summary == "" || len([]rune(summary)) > maxChars {
+		return truncateText(text, maxChars, TruncateMiddle)
+	}
+	return fmt.Sprintf("[Summary of a %d character tool output]\n%s", len([]rune(text)), summary)
+}
+
+// truncateText shortens text to maxChars characters, marking where and how
+// much was removed.
+func truncateText(text string, maxChars int, strategy TruncationStrategy) string {
+	runes := []rune(text)
+	if len(runes) <= maxChars {
+		return text
+	}
+	removed := len(runes) - maxChars
+	switch strategy {
+	case TruncateKeepHead:
+		return fmt.Sprintf("%s\n[... %d characters truncated]", string(runes[:maxChars]), removed)
+	case TruncateKeepTail:
+		return fmt.Sprintf("[%d characters truncated ...]\n%s", removed, string(runes[len(runes)-maxChars:]))
+	default:
+		head := maxChars / 2
+		tail := maxChars - head
+		return fmt.Sprintf("%s\n[... %d characters truncated ...]\n%s", string(runes[:head]), removed, string(runes[len(runes)-tail:]))
+	}
+}
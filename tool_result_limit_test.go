@@ -0,0 +1,105 @@
+package fantasy
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncateText(t *testing.T) {
+	t.Parallel()
+
+	text := "abcdefghij"
+	require.Equal(t, text, truncateText(text, 10, TruncateKeepHead))
+	require.Equal(t, "abcd\n[... 6 characters truncated]", truncateText(text, 4, TruncateKeepHead))
+	require.Equal(t, "[6 characters truncated ...]\nghij", truncateText(text, 4, TruncateKeepTail))
+	require.Equal(t, "ab\n[... 6 characters truncated ...]\nij", truncateText(text, 4, TruncateMiddle))
+}
+
+func TestWithToolResultLimit(t *testing.T) {
+	t.Parallel()
+
+	output := strings.Repeat("x", 100) + strings.Repeat("y", 100)
+	grep := &mockTool{
+		name: "grep",
+		executeFunc: func(ctx context.Context, call ToolCall) (ToolResponse, error) {
+			return NewTextResponse(output), nil
+		},
+	}
+
+	run := func(t *testing.T, strategy TruncationStrategy, summarize func(Call) (*Response, error), opts ...AgentOption) (*AgentResult, string) {
+		var toolResultText string
+		model := &mockLanguageModel{
+			generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+				if call.Prompt[0].Content[0].(TextPart).Text == toolResultSummaryPrompt {
+					return summarize(call)
+				}
+				if len(call.Prompt) == 1 {
+					return &Response{
+						Content:      []Content{ToolCallContent{ToolCallID: "call-1", ToolName: "grep", Input: `{}`}},
+						FinishReason: FinishReasonToolCalls,
+					}, nil
+				}
+				result, ok := AsMessagePart[ToolResultPart](call.Prompt[len(call.Prompt)-1].Content[0])
+				require.True(t, ok)
+				text, ok := AsToolResultOutputType[ToolResultOutputContentText](result.Output)
+				require.True(t, ok)
+				toolResultText = text.Text
+				return &Response{Content: []Content{TextContent{Text: "done"}}, FinishReason: FinishReasonStop}, nil
+			},
+		}
+		agent := NewAgent(model, append([]AgentOption{WithTools(grep), WithToolResultLimit(10, strategy)}, opts...)...)
+		result, err := agent.Generate(t.Context(), AgentCall{Prompt: "search"})
+		require.NoError(t, err)
+		return result, toolResultText
+	}
+
+	t.Run("truncate", func(t *testing.T) {
+		t.Parallel()
+
+		result, sent := run(t, TruncateKeepTail, nil)
+		require.Equal(t, "[160 characters truncated ...]\n"+strings.Repeat("y", 40), sent)
+
+		// The full output is kept in the step content.
+		toolResults := result.Steps[0].Content.ToolResults()
+		full, ok := AsToolResultOutputType[ToolResultOutputContentText](toolResults[0].Result)
+		require.True(t, ok)
+		require.Equal(t, output, full.Text)
+	})
+
+	t.Run("tokenizer", func(t *testing.T) {
+		t.Parallel()
+
+		// Two characters per token: the 100 tokens are cut to 10.
+		_, sent := run(t, TruncateKeepTail, nil, WithTokenizer(tokenizerFunc(func(text string) int {
+			return len(text) / 2
+		})))
+		require.Equal(t, "[180 characters truncated ...]\n"+strings.Repeat("y", 20), sent)
+	})
+
+	t.Run("summarize", func(t *testing.T) {
+		t.Parallel()
+
+		_, sent := run(t, TruncateSummarize, func(call Call) (*Response, error) {
+			require.Equal(t, int64(10), *call.MaxOutputTokens)
+			return &Response{Content: []Content{TextContent{Text: "100 x then 100 y"}}}, nil
+		})
+		require.Equal(t, "[Summary of a 200 character tool output]\n100 x then 100 y", sent)
+	})
+
+	t.Run("summarize failure", func(t *testing.T) {
+		t.Parallel()
+
+		_, sent := run(t, TruncateSummarize, func(Call) (*Response, error) {
+			return nil, errors.New("overloaded")
+		})
+		require.Equal(t, truncateText(output, 40, TruncateMiddle), sent)
+	})
+}
+
+type tokenizerFunc func(text string) int
+
+func (f tokenizerFunc) Count(text string) int { return f(text) }
@@ -0,0 +1,100 @@
+package fantasy
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ToolRetryPolicy configures how the agent retries tool executions that
+// fail with a transient error. See WithToolRetry.
+type ToolRetryPolicy struct {
+	// MaxRetries is how many times a failed execution is retried.
+	MaxRetries int
+	// InitialDelay is the wait before the first retry.
+	InitialDelay time.Duration
+	// BackoffFactor multiplies the delay after each retry.
+	BackoffFactor float64
+	// Retryable reports whether the error of an execution is worth
+	// retrying. Defaults to IsTransientToolError.
+	Retryable func(call ToolCall, err error) bool
+	// OnRetry is called before each retry, with the number of the retry,
+	// starting at 1, the error that triggered it and the delay before it.
+	OnRetry func(call ToolCall, retry int, err error, delay time.Duration)
+}
+
+// DefaultToolRetryPolicy returns a policy retrying transient errors three
+// times, waiting one, two and four seconds.
+func DefaultToolRetryPolicy() ToolRetryPolicy {
+	return ToolRetryPolicy{
+		MaxRetries:    3,
+		InitialDelay:  time.Second,
+		BackoffFactor: 2,
+	}
+}
+
+// WithToolRetry retries tool executions that fail with a transient error,
+// such as a network error or a 429 response, with exponential backoff. The
+// model only sees the error once the retries are exhausted. The number of
+// retries is reported in ToolResultContent.Retries, and the policy's
+// OnRetry is called before each one.
+//
+// Only errors returned by the tool are retried, not error responses like
+// NewTextErrorResponse, which are meant for the model.
+func WithToolRetry(policy ToolRetryPolicy) AgentOption {
+	return func(s *agentSettings) {
+		s.toolRetry = &policy
+	}
+}
+
+// IsTransientToolError reports whether a tool error is likely to go away
+// when retried: a network or HTTP/2 transport error, a *ProviderError that
+// is retryable, such as a 429 or 5xx response, or an error with a
+// Temporary method returning true. Tools calling HTTP APIs can wrap failed
+// responses in a *ProviderError with the status code to have them
+// classified.
+func IsTransientToolError(err error) bool {
+	if isRetryableError(err) {
+		return true
+	}
+	var temporary interface{ Temporary() bool }
+	return errors.As(err, &temporary) && temporary.Temporary()
+}
+
+// runTool runs the tool, resolving deferred results and retrying transient
+// errors according to the agent's ToolRetryPolicy, and returns the number
+// of retries.
+func (a *agent) runTool(ctx context.Context, run func(context.Context, ToolCall) (ToolResponse, error), call ToolCall) (ToolResponse, int, error) {
+	attempt := func() (ToolResponse, error) {
+		resp, err := a.callTool(ctx, run, call)
+		if err == nil && resp.Deferred != nil {
+			return a.resolveDeferred(ctx, call, resp)
+		}
+		return resp, err
+	}
+	resp, err := attempt()
+	policy := a.settings.toolRetry
+	if policy == nil {
+		return resp, 0, err
+	}
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = func(_ ToolCall, err error) bool { return IsTransientToolError(err) }
+	}
+	delay := policy.InitialDelay
+	retries := 0
+	for err != nil && retries < policy.MaxRetries && !isAbortError(err) && retryable(call, err) {
+		retries++
+		if policy.OnRetry != nil {
+			policy.OnRetry(call, retries, err, delay)
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return resp, retries, ctx.Err()
+		}
+		delay = time.Duration(float64(delay) * policy.BackoffFactor)
+		resp, err = attempt()
+	}
+	return resp, retries, err
+}
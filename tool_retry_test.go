@@ -0,0 +1,104 @@
+package fantasy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithToolRetry(t *testing.T) {
+	t.Parallel()
+
+	runs := 0
+	tool := &mockTool{
+		name: "lookup",
+		executeFunc: func(ctx context.Context, call ToolCall) (ToolResponse, error) {
+			runs++
+			if runs < 3 {
+				return ToolResponse{}, &ProviderError{StatusCode: 429}
+			}
+			return NewTextResponse("found"), nil
+		},
+	}
+	type retry struct {
+		n     int
+		delay time.Duration
+	}
+	var retries []retry
+	policy := ToolRetryPolicy{
+		MaxRetries:    3,
+		InitialDelay:  time.Millisecond,
+		BackoffFactor: 2,
+		OnRetry: func(call ToolCall, n int, err error, delay time.Duration) {
+			require.Equal(t, "call-1", call.ID)
+			retries = append(retries, retry{n, delay})
+		},
+	}
+	agent := NewAgent(
+		toolCallingModel(ToolCallContent{ToolCallID: "call-1", ToolName: "lookup", Input: `{}`}),
+		WithTools(tool),
+		WithToolRetry(policy),
+	)
+	result, err := agent.Generate(t.Context(), AgentCall{Prompt: "hello"})
+	require.NoError(t, err)
+	require.Equal(t, 3, runs)
+	require.Equal(t, []retry{{1, time.Millisecond}, {2, 2 * time.Millisecond}}, retries)
+
+	toolResults := result.Steps[0].Content.ToolResults()
+	require.Len(t, toolResults, 1)
+	require.Equal(t, 2, toolResults[0].Retries)
+	require.Equal(t, ToolResultOutputContentText{Text: "found"}, toolResults[0].Result)
+}
+
+func TestWithToolRetry_GivesUp(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		err  error
+		runs int
+	}{
+		"exhausted":     {err: &net.DNSError{Err: "no such host", IsTimeout: true}, runs: 3},
+		"not transient": {err: errors.New("invalid input"), runs: 1},
+		"canceled":      {err: context.Canceled, runs: 1},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+			runs := 0
+			tool := &mockTool{
+				name: "lookup",
+				executeFunc: func(ctx context.Context, call ToolCall) (ToolResponse, error) {
+					runs++
+					return ToolResponse{}, tc.err
+				},
+			}
+			agent := NewAgent(
+				toolCallingModel(ToolCallContent{ToolCallID: "call-1", ToolName: "lookup", Input: `{}`}),
+				WithTools(tool),
+				WithToolRetry(ToolRetryPolicy{MaxRetries: 2, InitialDelay: time.Millisecond, BackoffFactor: 2}),
+			)
+			_, err := agent.Generate(t.Context(), AgentCall{Prompt: "hello"})
+			require.NoError(t, err)
+			require.Equal(t, tc.runs, runs)
+		})
+	}
+}
+
+type temporaryError struct{}
+
+func (temporaryError) Error() string   { return "try again" }
+func (temporaryError) Temporary() bool { return true }
+
+func TestIsTransientToolError(t *testing.T) {
+	t.Parallel()
+
+	require.True(t, IsTransientToolError(&ProviderError{StatusCode: 503}))
+	require.True(t, IsTransientToolError(&net.OpError{Op: "dial", Err: errors.New("connection refused")}))
+	require.True(t, IsTransientToolError(temporaryError{}))
+	require.False(t, IsTransientToolError(&ProviderError{StatusCode: 400}))
+	require.False(t, IsTransientToolError(errors.New("invalid input")))
+	require.False(t, IsTransientToolError(context.Canceled))
+}
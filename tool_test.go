@@ -2,6 +2,7 @@ package fantasy
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
 
@@ -109,3 +110,60 @@ func TestNewMediaResponse(t *testing.T) {
 	require.False(t, resp.IsError)
 	require.Empty(t, resp.Content)
 }
+
+func TestErrorResponses(t *testing.T) {
+	t.Parallel()
+
+	errNotFound := errors.New("city not found")
+	// run returns the result of a run whose tool responds with resp, and
+	// how many times the model was called.
+	run := func(t *testing.T, resp ToolResponse) (*AgentResult, int, error) {
+		model := toolCallingModel(ToolCallContent{ToolCallID: "call-1", ToolName: "weather", Input: `{}`})
+		var calls int
+		generate := model.generateFunc
+		model.generateFunc = func(ctx context.Context, call Call) (*Response, error) {
+			calls++
+			return generate(ctx, call)
+		}
+		tool := &mockTool{
+			name: "weather",
+			executeFunc: func(ctx context.Context, call ToolCall) (ToolResponse, error) {
+				return resp, nil
+			},
+		}
+		result, err := NewAgent(model, WithTools(tool)).Generate(t.Context(), AgentCall{Prompt: "weather?"})
+		return result, calls, err
+	}
+
+	t.Run("retryable", func(t *testing.T) {
+		t.Parallel()
+		resp := NewRetryableErrorResponse(errNotFound)
+		require.True(t, resp.IsError)
+		require.False(t, resp.Fatal)
+		require.Equal(t, "city not found", resp.Content)
+
+		result, calls, err := run(t, resp)
+		require.NoError(t, err)
+		require.Equal(t, 2, calls)
+		require.Len(t, result.Steps, 2)
+		toolResult := result.Steps[0].Content.ToolResults()[0]
+		require.ErrorIs(t, toolResult.Result.(ToolResultOutputContentError).Error, errNotFound)
+	})
+
+	t.Run("fatal", func(t *testing.T) {
+		t.Parallel()
+		resp := NewFatalErrorResponse(errNotFound)
+		require.True(t, resp.IsError)
+		require.True(t, resp.Fatal)
+
+		result, calls, err := run(t, resp)
+		require.Nil(t, result)
+		require.Equal(t, 1, calls)
+		require.ErrorIs(t, err, errNotFound)
+		var fatalErr *ToolFatalError
+		require.ErrorAs(t, err, &fatalErr)
+		require.Equal(t, "weather", fatalErr.ToolName)
+		require.Equal(t, "call-1", fatalErr.ToolCallID)
+		require.EqualError(t, err, "tool weather: city not found")
+	})
+}
@@ -0,0 +1,268 @@
+// Package fetch provides a tool that retrieves web pages and other URLs for
+// agents. HTML pages are converted to markdown with the extract package,
+// other text is returned as is, and images, audio, video and PDFs are
+// returned as media unless an extractor is registered for them.
+//
+// The tool protects against server-side request forgery: it only connects
+// to public addresses unless AllowPrivateNetworks is set, checks every
+// address it dials, including after redirects and DNS changes, and can be
+// limited to a list of domains.
+//
+// Example:
+//
+//	tool := fetch.New(fetch.Config{AllowDomains: []string{"go.dev", "pkg.go.dev"}})
+//	agent := fantasy.NewAgent(model, fantasy.WithTools(tool))
+package fetch
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/extract"
+)
+
+// ToolName is the name of the fetch tool.
+const ToolName = "fetch"
+
+// Defaults for the zero values of Config.
+const (
+	DefaultMaxBodyBytes = 5 * 1024 * 1024
+	DefaultTimeout      = 30 * time.Second
+	DefaultUserAgent    = "fantasy-fetch/1.0"
+)
+
+const maxRedirects = 10
+
+// ErrBlocked is returned when a URL or the address it resolves to is not
+// allowed.
+var ErrBlocked = errors.New("blocked by fetch policy")
+
+// Config configures the fetch tool.
+type Config struct {
+	// AllowDomains lists the domains that may be fetched, including their
+	// subdomains. When it is empty any domain not denied may be fetched.
+	AllowDomains []string
+	// DenyDomains lists the domains that may not be fetched, including
+	// their subdomains. It takes precedence over AllowDomains.
+	DenyDomains []string
+	// AllowPrivateNetworks allows loopback, private, link-local and other
+	// non-public addresses, which are blocked by default.
+	AllowPrivateNetworks bool
+	// MaxBodyBytes caps the size of the response body. Longer text is
+	// truncated and longer media is rejected. Defaults to
+	// DefaultMaxBodyBytes.
+	MaxBodyBytes int64
+	// Timeout is how long a fetch may take. Defaults to DefaultTimeout.
+	Timeout time.Duration
+	// UserAgent is sent with each request. Defaults to DefaultUserAgent.
+	UserAgent string
+	// Extractors converts responses to text. Register an extractor for
+	// application/pdf, for example, to return PDFs as text instead of
+	// media. Defaults to extract.NewRegistry().
+	Extractors *extract.Registry
+}
+
+// New creates the fetch tool.
+func New(cfg Config) fantasy.AgentTool {
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.UserAgent == "" {
+		cfg.UserAgent = DefaultUserAgent
+	}
+	if cfg.Extractors == nil {
+		cfg.Extractors = extract.NewRegistry()
+	}
+	f := &fetcher{cfg: cfg}
+	dialer := &net.Dialer{Timeout: cfg.Timeout, Control: f.checkDial}
+	f.client = &http.Client{
+		Timeout: cfg.Timeout,
+		Transport: &http.Transport{
+			// A proxy would dial on our behalf, so addresses couldn't be
+			// checked.
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 10 * time.Second,
+			ForceAttemptHTTP2:   true,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return f.checkURL(req.URL)
+		},
+	}
+
+	description := "Fetch a URL over HTTP or HTTPS. HTML pages are returned as markdown, other text as is, and images and PDFs as media."
+	if len(cfg.AllowDomains) > 0 {
+		description += " Only these domains may be fetched: " + strings.Join(cfg.AllowDomains, ", ") + "."
+	}
+	return fantasy.NewParallelAgentTool(ToolName, description, f.fetch)
+}
+
+type fetcher struct {
+	cfg    Config
+	client *http.Client
+}
+
+type input struct {
+	URL string `json:"url" description:"The URL to fetch"`
+}
+
+func (f *fetcher) fetch(ctx context.Context, in input, _ fantasy.ToolCall) (fantasy.ToolResponse, error) {
+	u, err := url.Parse(strings.TrimSpace(in.URL))
+	if err != nil {
+		return fantasy.NewTextErrorResponse(fmt.Sprintf("invalid URL: %v", err)), nil
+	}
+	if err := f.checkURL(u); err != nil {
+		return fantasy.NewTextErrorResponse(err.Error()), nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return fantasy.NewTextErrorResponse(err.Error()), nil
+	}
+	req.Header.Set("User-Agent", f.cfg.UserAgent)
+	req.Header.Set("Accept", "text/html, text/markdown, text/plain, application/json, */*;q=0.8")
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return fantasy.NewTextErrorResponse(err.Error()), nil
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, f.cfg.MaxBodyBytes+1))
+	if err != nil {
+		return fantasy.NewTextErrorResponse(fmt.Sprintf("reading response: %v", err)), nil
+	}
+	truncated := int64(len(body)) > f.cfg.MaxBodyBytes
+	if truncated {
+		body = body[:f.cfg.MaxBodyBytes]
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if mediaType == "" {
+		mediaType = http.DetectContentType(body)
+		mediaType, _, _ = mime.ParseMediaType(mediaType)
+	}
+	if resp.StatusCode >= 400 {
+		return fantasy.NewTextErrorResponse(fmt.Sprintf("%s returned %s\n\n%s", resp.Request.URL, resp.Status, truncate(string(body), 1000))), nil
+	}
+
+	switch {
+	case f.cfg.Extractors.Supports(mediaType):
+		doc, err := f.cfg.Extractors.Extract(ctx, bytes.NewReader(body), cmp.Or(contentType, mediaType), resp.Request.URL)
+		if err != nil {
+			return fantasy.NewTextErrorResponse(fmt.Sprintf("extracting text: %v", err)), nil
+		}
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "URL: %s\n", resp.Request.URL)
+		if doc.Title != "" {
+			fmt.Fprintf(&sb, "Title: %s\n", doc.Title)
+		}
+		sb.WriteString("\n" + doc.Text)
+		if truncated {
+			fmt.Fprintf(&sb, "\n\n[Response truncated at %d bytes]", f.cfg.MaxBodyBytes)
+		}
+		return fantasy.NewTextResponse(sb.String()), nil
+	case isMedia(mediaType):
+		if truncated {
+			return fantasy.NewTextErrorResponse(fmt.Sprintf("%s is larger than %d bytes", mediaType, f.cfg.MaxBodyBytes)), nil
+		}
+		if strings.HasPrefix(mediaType, "image/") {
+			return fantasy.NewImageResponse(body, mediaType), nil
+		}
+		return fantasy.NewMediaResponse(body, mediaType), nil
+	}
+	return fantasy.NewTextErrorResponse(fmt.Sprintf("unsupported content type %q", mediaType)), nil
+}
+
+// checkURL returns an error if the URL may not be fetched.
+func (f *fetcher) checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: unsupported scheme %q", ErrBlocked, u.Scheme)
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	if host == "" {
+		return fmt.Errorf("%w: missing host", ErrBlocked)
+	}
+	if matchesDomain(host, f.cfg.DenyDomains) {
+		return fmt.Errorf("%w: domain %s is denied", ErrBlocked, host)
+	}
+	if len(f.cfg.AllowDomains) > 0 && !matchesDomain(host, f.cfg.AllowDomains) {
+		return fmt.Errorf("%w: domain %s is not allowed", ErrBlocked, host)
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !f.allowedAddr(addr) {
+		return fmt.Errorf("%w: address %s is not public", ErrBlocked, addr)
+	}
+	return nil
+}
+
+// checkDial rejects connections to addresses that are not allowed. It runs
+// after DNS resolution, so hostnames that resolve to private addresses are
+// blocked too.
+func (f *fetcher) checkDial(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !f.allowedAddr(addr) {
+		return fmt.Errorf("%w: address %s is not public", ErrBlocked, addr)
+	}
+	return nil
+}
+
+// carrierGradeNAT is the shared address space of RFC 6598, which is not
+// covered by netip.Addr.IsPrivate.
+var carrierGradeNAT = netip.MustParsePrefix("100.64.0.0/10")
+
+func (f *fetcher) allowedAddr(addr netip.Addr) bool {
+	if f.cfg.AllowPrivateNetworks {
+		return true
+	}
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !carrierGradeNAT.Contains(addr)
+}
+
+func matchesDomain(host string, domains []string) bool {
+	return slices.ContainsFunc(domains, func(domain string) bool {
+		domain = strings.TrimPrefix(strings.ToLower(domain), ".")
+		return host == domain || strings.HasSuffix(host, "."+domain)
+	})
+}
+
+func isMedia(mediaType string) bool {
+	return strings.HasPrefix(mediaType, "image/") ||
+		strings.HasPrefix(mediaType, "audio/") ||
+		strings.HasPrefix(mediaType, "video/") ||
+		mediaType == "application/pdf"
+}
+
+func truncate(text string, maxChars int) string {
+	runes := []rune(strings.TrimSpace(text))
+	if len(runes) <= maxChars {
+		return string(runes)
+	}
+	return string(runes[:maxChars]) + "..."
+}
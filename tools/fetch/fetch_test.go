@@ -0,0 +1,162 @@
+package fetch
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/extract"
+	"github.com/stretchr/testify/require"
+)
+
+const testPage = `<html>
+<head><title>Fantasy docs</title></head>
+<body><nav>Home</nav><main><h1>Getting started</h1><p>Fantasy builds agents.</p></main></body>
+</html>`
+
+func run(t *testing.T, tool fantasy.AgentTool, rawURL string) fantasy.ToolResponse {
+	t.Helper()
+	input, err := json.Marshal(input{URL: rawURL})
+	require.NoError(t, err)
+	resp, err := tool.Run(t.Context(), fantasy.ToolCall{ID: "1", Name: ToolName, Input: string(input)})
+	require.NoError(t, err)
+	return resp
+}
+
+func TestFetch(t *testing.T) {
+	t.Parallel()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/page", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte(testPage))
+	})
+	mux.HandleFunc("/image.png", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		_, _ = w.Write([]byte{0x89, 'P', 'N', 'G'})
+	})
+	mux.HandleFunc("/large.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(strings.Repeat("x", 100)))
+	})
+	mux.HandleFunc("/doc.pdf", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		_, _ = w.Write([]byte("%PDF-1.7 hello"))
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nothing here", http.StatusNotFound)
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data", http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	tool := New(Config{AllowPrivateNetworks: true, MaxBodyBytes: 50})
+	large := New(Config{AllowPrivateNetworks: true})
+
+	t.Run("html", func(t *testing.T) {
+		t.Parallel()
+
+		resp := run(t, large, server.URL+"/page")
+		require.False(t, resp.IsError, resp.Content)
+		require.Equal(t, "URL: "+server.URL+"/page\nTitle: Fantasy docs\n\n# Getting started\n\nFantasy builds agents.", resp.Content)
+	})
+
+	t.Run("image", func(t *testing.T) {
+		t.Parallel()
+
+		resp := run(t, tool, server.URL+"/image.png")
+		require.Equal(t, "image", resp.Type)
+		require.Equal(t, "image/png", resp.MediaType)
+	})
+
+	t.Run("pdf", func(t *testing.T) {
+		t.Parallel()
+
+		resp := run(t, tool, server.URL+"/doc.pdf")
+		require.Equal(t, "media", resp.Type)
+
+		extractors := extract.NewRegistry()
+		extractors.Register("application/pdf", extract.ExtractorFunc(func(ctx context.Context, r io.Reader) (extract.Document, error) {
+			data, err := io.ReadAll(r)
+			return extract.Document{Text: strings.TrimPrefix(string(data), "%PDF-1.7 ")}, err
+		}))
+		resp = run(t, New(Config{AllowPrivateNetworks: true, Extractors: extractors}), server.URL+"/doc.pdf")
+		require.Equal(t, "URL: "+server.URL+"/doc.pdf\n\nhello", resp.Content)
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		t.Parallel()
+
+		resp := run(t, tool, server.URL+"/large.txt")
+		require.Contains(t, resp.Content, strings.Repeat("x", 50)+"\n\n[Response truncated at 50 bytes]")
+	})
+
+	t.Run("status", func(t *testing.T) {
+		t.Parallel()
+
+		resp := run(t, tool, server.URL+"/missing")
+		require.True(t, resp.IsError)
+		require.Contains(t, resp.Content, "404 Not Found")
+	})
+
+	t.Run("private networks", func(t *testing.T) {
+		t.Parallel()
+
+		strict := New(Config{})
+		require.Contains(t, run(t, strict, server.URL+"/page").Content, ErrBlocked.Error())
+		// Hostnames are checked when dialing, after they are resolved.
+		localhost := strings.Replace(server.URL, "127.0.0.1", "localhost", 1)
+		require.Contains(t, run(t, strict, localhost+"/page").Content, ErrBlocked.Error())
+	})
+
+	t.Run("redirect", func(t *testing.T) {
+		t.Parallel()
+
+		resp := run(t, New(Config{AllowPrivateNetworks: true, DenyDomains: []string{"169.254.169.254"}}), server.URL+"/redirect")
+		require.True(t, resp.IsError)
+		require.Contains(t, resp.Content, ErrBlocked.Error())
+	})
+}
+
+func TestCheckURL(t *testing.T) {
+	t.Parallel()
+
+	f := &fetcher{cfg: Config{AllowDomains: []string{"go.dev"}, DenyDomains: []string{"private.go.dev"}}}
+	for rawURL, allowed := range map[string]bool{
+		"https://go.dev/doc":         true,
+		"https://pkg.go.dev/fmt":     true,
+		"https://GO.DEV./":           true,
+		"https://notgo.dev/":         false,
+		"https://private.go.dev/":    false,
+		"https://a.private.go.dev/":  false,
+		"file:///etc/passwd":         false,
+		"ftp://go.dev/":              false,
+		"http://[::1]/":              false,
+		"http://10.0.0.1/":           false,
+		"http://[::ffff:127.0.0.1]/": false,
+		"http://100.64.0.1/":         false,
+	} {
+		u, err := url.Parse(rawURL)
+		require.NoError(t, err)
+		if allowed {
+			require.NoError(t, f.checkURL(u), rawURL)
+		} else {
+			require.ErrorIs(t, f.checkURL(u), ErrBlocked, rawURL)
+		}
+	}
+
+	// Without an allow list, only non-public addresses are blocked.
+	open := &fetcher{}
+	u, _ := url.Parse("http://10.0.0.1/")
+	require.ErrorIs(t, open.checkURL(u), ErrBlocked)
+	u, _ = url.Parse("https://example.com/")
+	require.NoError(t, open.checkURL(u))
+}
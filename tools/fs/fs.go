@@ -0,0 +1,323 @@
+// Package fs provides filesystem tools for agents: read_file, write_file,
+// list_dir and grep. All access goes through a Policy that limits the tools
+// to a set of root directories, caps the size of reads and writes and can
+// make the tools read-only. Paths that escape a root, including through
+// symlinks, are rejected.
+//
+// Example:
+//
+//	tools, err := fs.Tools(fs.Policy{Roots: []string{workdir}, ReadOnly: true})
+//	...
+//	agent := fantasy.NewAgent(model, fantasy.WithTools(tools...))
+package fs
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	iofs "io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"charm.land/fantasy"
+)
+
+// Names of the tools.
+const (
+	ReadFileToolName  = "read_file"
+	WriteFileToolName = "write_file"
+	ListDirToolName   = "list_dir"
+	GrepToolName      = "grep"
+)
+
+// Defaults for the zero values of Policy.
+const (
+	DefaultMaxReadBytes  = 256 * 1024
+	DefaultMaxWriteBytes = 1024 * 1024
+	DefaultMaxMatches    = 100
+)
+
+// ErrOutsideRoots is returned when a path is not inside any of the policy's
+// roots.
+var ErrOutsideRoots = errors.New("path is outside the allowed directories")
+
+// Policy controls what the filesystem tools may access.
+type Policy struct {
+	// Roots are the directories the tools may access. Relative paths are
+	// resolved against the first one. At least one root is required.
+	Roots []string
+	// MaxReadBytes caps how much read_file returns in one call and the size
+	// of the files grep searches. Defaults to DefaultMaxReadBytes.
+	MaxReadBytes int64
+	// MaxWriteBytes caps the size of the content write_file accepts.
+	// Defaults to DefaultMaxWriteBytes.
+	MaxWriteBytes int64
+	// MaxMatches caps the matches grep returns. Defaults to
+	// DefaultMaxMatches.
+	MaxMatches int
+	// ReadOnly leaves out write_file.
+	ReadOnly bool
+}
+
+// Tools returns the filesystem tools for the policy. It fails if the policy
+// has no roots or a root is not a directory.
+func Tools(policy Policy) ([]fantasy.AgentTool, error) {
+	if len(policy.Roots) == 0 {
+		return nil, errors.New("fs: policy needs at least one root")
+	}
+	roots := make([]string, len(policy.Roots))
+	for i, root := range policy.Roots {
+		abs, err := filepath.Abs(root)
+		if err != nil {
+			return nil, fmt.Errorf("fs: root %s: %w", root, err)
+		}
+		info, err := os.Stat(abs)
+		if err != nil {
+			return nil, fmt.Errorf("fs: root %s: %w", root, err)
+		}
+		if !info.IsDir() {
+			return nil, fmt.Errorf("fs: root %s is not a directory", root)
+		}
+		roots[i] = abs
+	}
+	policy.Roots = roots
+	if policy.MaxReadBytes <= 0 {
+		policy.MaxReadBytes = DefaultMaxReadBytes
+	}
+	if policy.MaxWriteBytes <= 0 {
+		policy.MaxWriteBytes = DefaultMaxWriteBytes
+	}
+	if policy.MaxMatches <= 0 {
+		policy.MaxMatches = DefaultMaxMatches
+	}
+
+	t := &toolset{policy: policy}
+	tools := []fantasy.AgentTool{
+		fantasy.NewParallelAgentTool(ReadFileToolName,
+			"Read a text file. Long files can be read in parts with offset and limit, counted in lines.",
+			t.readFile),
+		fantasy.NewParallelAgentTool(ListDirToolName,
+			"List the files and directories in a directory. Directories end with a slash.",
+			t.listDir),
+		fantasy.NewParallelAgentTool(GrepToolName,
+			"Search files for lines matching a regular expression. Returns matches as path:line: text.",
+			t.grep),
+	}
+	if !policy.ReadOnly {
+		tools = append(tools, fantasy.NewAgentTool(WriteFileToolName,
+			"Write a text file, replacing it if it exists. Missing parent directories are created.",
+			t.writeFile))
+	}
+	return tools, nil
+}
+
+type toolset struct {
+	policy Policy
+}
+
+// open resolves the path against the roots and opens the root containing
+// it. It also returns the path relative to that root and the root's
+// directory.
+func (t *toolset) open(path string) (*os.Root, string, string, error) {
+	if path == "" {
+		path = "."
+	}
+	for i, dir := range t.policy.Roots {
+		abs := path
+		if !filepath.IsAbs(abs) {
+			if i > 0 {
+				break
+			}
+			abs = filepath.Join(dir, abs)
+		}
+		rel, err := filepath.Rel(dir, filepath.Clean(abs))
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		root, err := os.OpenRoot(dir)
+		if err != nil {
+			return nil, "", "", err
+		}
+		return root, rel, dir, nil
+	}
+	return nil, "", "", fmt.Errorf("%s: %w", path, ErrOutsideRoots)
+}
+
+type readFileInput struct {
+	Path   string `json:"path" description:"The file path"`
+	Offset int    `json:"offset,omitempty" description:"The first line to read, starting at 0"`
+	Limit  int    `json:"limit,omitempty" description:"The maximum number of lines to read"`
+}
+
+func (t *toolset) readFile(_ context.Context, input readFileInput, _ fantasy.ToolCall) (fantasy.ToolResponse, error) {
+	root, rel, _, err := t.open(input.Path)
+	if err != nil {
+		return fantasy.NewTextErrorResponse(err.Error()), nil
+	}
+	defer root.Close()
+	f, err := root.Open(rel)
+	if err != nil {
+		return fantasy.NewTextErrorResponse(err.Error()), nil
+	}
+	defer f.Close()
+
+	var sb strings.Builder
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, int(t.policy.MaxReadBytes)+1)
+	line := 0
+	for ; scanner.Scan(); line++ {
+		if line < input.Offset {
+			continue
+		}
+		if input.Limit > 0 && line >= input.Offset+input.Limit {
+			break
+		}
+		if int64(sb.Len()+len(scanner.Bytes())+1) > t.policy.MaxReadBytes {
+			fmt.Fprintf(&sb, "[Output truncated at %d bytes. Continue reading from offset %d.]", t.policy.MaxReadBytes, line)
+			break
+		}
+		sb.Write(scanner.Bytes())
+		sb.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return fantasy.NewTextErrorResponse(fmt.Sprintf("%s: line %d: %v", input.Path, line, err)), nil
+	}
+	return fantasy.NewTextResponse(sb.String()), nil
+}
+
+type writeFileInput struct {
+	Path    string `json:"path" description:"The file path"`
+	Content string `json:"content" description:"The content to write"`
+}
+
+func (t *toolset) writeFile(_ context.Context, input writeFileInput, _ fantasy.ToolCall) (fantasy.ToolResponse, error) {
+	if int64(len(input.Content)) > t.policy.MaxWriteBytes {
+		return fantasy.NewTextErrorResponse(fmt.Sprintf("content is %d bytes, over the %d byte limit", len(input.Content), t.policy.MaxWriteBytes)), nil
+	}
+	root, rel, dir, err := t.open(input.Path)
+	if err != nil {
+		return fantasy.NewTextErrorResponse(err.Error()), nil
+	}
+	defer root.Close()
+	if parent := filepath.Dir(rel); parent != "." {
+		if err := root.MkdirAll(parent, 0o755); err != nil {
+			return fantasy.NewTextErrorResponse(err.Error()), nil
+		}
+	}
+	if err := root.WriteFile(rel, []byte(input.Content), 0o644); err != nil {
+		return fantasy.NewTextErrorResponse(err.Error()), nil
+	}
+	return fantasy.NewTextResponse(fmt.Sprintf("Wrote %d bytes to %s.", len(input.Content), filepath.Join(dir, rel))), nil
+}
+
+type listDirInput struct {
+	Path string `json:"path,omitempty" description:"The directory path, the first allowed directory by default"`
+}
+
+func (t *toolset) listDir(_ context.Context, input listDirInput, _ fantasy.ToolCall) (fantasy.ToolResponse, error) {
+	root, rel, _, err := t.open(input.Path)
+	if err != nil {
+		return fantasy.NewTextErrorResponse(err.Error()), nil
+	}
+	defer root.Close()
+	entries, err := iofs.ReadDir(root.FS(), filepath.ToSlash(rel))
+	if err != nil {
+		return fantasy.NewTextErrorResponse(err.Error()), nil
+	}
+	if len(entries) == 0 {
+		return fantasy.NewTextResponse("The directory is empty."), nil
+	}
+	var sb strings.Builder
+	for _, entry := range entries {
+		if entry.IsDir() {
+			sb.WriteString(entry.Name() + "/\n")
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			sb.WriteString(entry.Name() + "\n")
+			continue
+		}
+		fmt.Fprintf(&sb, "%s (%d bytes)\n", entry.Name(), info.Size())
+	}
+	return fantasy.NewTextResponse(sb.String()), nil
+}
+
+type grepInput struct {
+	Pattern string `json:"pattern" description:"The regular expression to search for, in Go syntax"`
+	Path    string `json:"path,omitempty" description:"The file or directory to search, the first allowed directory by default"`
+	Glob    string `json:"glob,omitempty" description:"Only search files whose name matches this pattern, such as *.go"`
+}
+
+func (t *toolset) grep(ctx context.Context, input grepInput, _ fantasy.ToolCall) (fantasy.ToolResponse, error) {
+	re, err := regexp.Compile(input.Pattern)
+	if err != nil {
+		return fantasy.NewTextErrorResponse(fmt.Sprintf("invalid pattern: %v", err)), nil
+	}
+	if input.Glob != "" {
+		if _, err := filepath.Match(input.Glob, ""); err != nil {
+			return fantasy.NewTextErrorResponse(fmt.Sprintf("invalid glob: %v", err)), nil
+		}
+	}
+	root, rel, dir, err := t.open(input.Path)
+	if err != nil {
+		return fantasy.NewTextErrorResponse(err.Error()), nil
+	}
+	defer root.Close()
+
+	var sb strings.Builder
+	matches := 0
+	errLimit := errors.New("match limit reached")
+	err = iofs.WalkDir(root.FS(), filepath.ToSlash(rel), func(name string, entry iofs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if entry.IsDir() {
+			if entry.Name() == ".git" {
+				return iofs.SkipDir
+			}
+			return nil
+		}
+		if input.Glob != "" {
+			if ok, _ := filepath.Match(input.Glob, entry.Name()); !ok {
+				return nil
+			}
+		}
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Size() > t.policy.MaxReadBytes {
+			return nil
+		}
+		data, err := iofs.ReadFile(root.FS(), name)
+		if err != nil || bytes.IndexByte(data[:min(len(data), 512)], 0) >= 0 {
+			return nil
+		}
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		for i, line := range strings.Split(string(data), "\n") {
+			if !re.MatchString(line) {
+				continue
+			}
+			if matches == t.policy.MaxMatches {
+				return errLimit
+			}
+			matches++
+			fmt.Fprintf(&sb, "%s:%d: %s\n", path, i+1, line)
+		}
+		return nil
+	})
+	if errors.Is(err, errLimit) {
+		fmt.Fprintf(&sb, "[Stopped after %d matches.]", t.policy.MaxMatches)
+	} else if err != nil {
+		return fantasy.ToolResponse{}, err
+	}
+	if matches == 0 {
+		return fantasy.NewTextResponse("No matches found."), nil
+	}
+	return fantasy.NewTextResponse(sb.String()), nil
+}
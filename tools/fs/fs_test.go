@@ -0,0 +1,129 @@
+package fs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/require"
+)
+
+func newTools(t *testing.T, policy Policy) map[string]fantasy.AgentTool {
+	t.Helper()
+	tools, err := Tools(policy)
+	require.NoError(t, err)
+	byName := map[string]fantasy.AgentTool{}
+	for _, tool := range tools {
+		byName[tool.Info().Name] = tool
+	}
+	return byName
+}
+
+func run(t *testing.T, tool fantasy.AgentTool, input string) fantasy.ToolResponse {
+	t.Helper()
+	resp, err := tool.Run(t.Context(), fantasy.ToolCall{ID: "1", Name: tool.Info().Name, Input: input})
+	require.NoError(t, err)
+	return resp
+}
+
+func TestTools(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "docs"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "docs", "notes.md"), []byte("func is a keyword\n"), 0o644))
+	tools := newTools(t, Policy{Roots: []string{dir}})
+
+	t.Run("read_file", func(t *testing.T) {
+		t.Parallel()
+
+		require.Equal(t, "package main\n\nfunc main() {}\n", run(t, tools[ReadFileToolName], `{"path":"main.go"}`).Content)
+		require.Equal(t, "\n", run(t, tools[ReadFileToolName], `{"path":"main.go","offset":1,"limit":1}`).Content)
+		require.True(t, run(t, tools[ReadFileToolName], `{"path":"missing.go"}`).IsError)
+	})
+
+	t.Run("list_dir", func(t *testing.T) {
+		t.Parallel()
+
+		require.Equal(t, "docs/\nmain.go (29 bytes)\n", run(t, tools[ListDirToolName], `{}`).Content)
+	})
+
+	t.Run("grep", func(t *testing.T) {
+		t.Parallel()
+
+		resp := run(t, tools[GrepToolName], `{"pattern":"^func"}`)
+		require.Equal(t, filepath.Join(dir, "docs", "notes.md")+":1: func is a keyword\n"+
+			filepath.Join(dir, "main.go")+":3: func main() {}\n", resp.Content)
+
+		resp = run(t, tools[GrepToolName], `{"pattern":"^func","glob":"*.go"}`)
+		require.Equal(t, filepath.Join(dir, "main.go")+":3: func main() {}\n", resp.Content)
+
+		resp = run(t, tools[GrepToolName], `{"pattern":"nothing here"}`)
+		require.Equal(t, "No matches found.", resp.Content)
+	})
+
+	t.Run("write_file", func(t *testing.T) {
+		t.Parallel()
+
+		dir := t.TempDir()
+		tools := newTools(t, Policy{Roots: []string{dir}})
+		resp := run(t, tools[WriteFileToolName], `{"path":"out/result.txt","content":"ok"}`)
+		require.False(t, resp.IsError, resp.Content)
+		data, err := os.ReadFile(filepath.Join(dir, "out", "result.txt"))
+		require.NoError(t, err)
+		require.Equal(t, "ok", string(data))
+	})
+}
+
+func TestPolicy(t *testing.T) {
+	t.Parallel()
+
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "root")
+	require.NoError(t, os.Mkdir(dir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(parent, "secret.txt"), []byte("secret"), 0o644))
+	require.NoError(t, os.Symlink(filepath.Join(parent, "secret.txt"), filepath.Join(dir, "link.txt")))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "big.txt"), []byte(strings.Repeat("line\n", 10)), 0o644))
+
+	t.Run("outside roots", func(t *testing.T) {
+		t.Parallel()
+
+		tools := newTools(t, Policy{Roots: []string{dir}})
+		for _, path := range []string{"../secret.txt", filepath.Join(parent, "secret.txt"), "link.txt"} {
+			resp := run(t, tools[ReadFileToolName], `{"path":"`+path+`"}`)
+			require.True(t, resp.IsError, path)
+			require.NotContains(t, resp.Content, "secret\n")
+		}
+		require.True(t, run(t, tools[WriteFileToolName], `{"path":"../new.txt","content":"x"}`).IsError)
+		require.NoFileExists(t, filepath.Join(parent, "new.txt"))
+	})
+
+	t.Run("read only", func(t *testing.T) {
+		t.Parallel()
+
+		tools := newTools(t, Policy{Roots: []string{dir}, ReadOnly: true})
+		require.NotContains(t, tools, WriteFileToolName)
+		require.Len(t, tools, 3)
+	})
+
+	t.Run("size limits", func(t *testing.T) {
+		t.Parallel()
+
+		tools := newTools(t, Policy{Roots: []string{dir}, MaxReadBytes: 12, MaxWriteBytes: 3})
+		require.Equal(t, "line\nline\n[Output truncated at 12 bytes. Continue reading from offset 2.]",
+			run(t, tools[ReadFileToolName], `{"path":"big.txt"}`).Content)
+		require.True(t, run(t, tools[WriteFileToolName], `{"path":"a.txt","content":"abcd"}`).IsError)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+
+		_, err := Tools(Policy{})
+		require.Error(t, err)
+		_, err = Tools(Policy{Roots: []string{filepath.Join(dir, "big.txt")}})
+		require.Error(t, err)
+	})
+}
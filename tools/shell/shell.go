@@ -0,0 +1,248 @@
+// Package shell provides a tool that runs shell commands for agents. The
+// commands it accepts are limited by allow and deny rules, they run with a
+// timeout and a scrubbed environment, and their output is capped before it
+// is returned to the model. While a command runs, its output is written to
+// the call's fantasy.ToolCall.Output so UIs can show it live.
+//
+// Example:
+//
+//	tool := shell.New(shell.Config{
+//	    Dir:   workdir,
+//	    Allow: []string{"go test", "go vet", "git status", "git diff", "ls"},
+//	    Deny:  []string{"git push"},
+//	})
+//	agent := fantasy.NewAgent(model, fantasy.WithTools(tool))
+package shell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"charm.land/fantasy"
+)
+
+// ToolName is the name of the shell tool.
+const ToolName = "shell"
+
+// Defaults for the zero values of Config.
+const (
+	DefaultTimeout        = 2 * time.Minute
+	DefaultMaxOutputBytes = 32 * 1024
+)
+
+// DefaultPassEnv are the environment variables passed to commands when
+// Config.PassEnv is nil.
+var DefaultPassEnv = []string{"PATH", "HOME", "USER", "LANG", "LC_ALL", "TERM", "TMPDIR"}
+
+// Config configures the shell tool.
+type Config struct {
+	// Shell is the shell that runs the commands, with -c. Defaults to sh.
+	Shell string
+	// Dir is the working directory of the commands. Defaults to the
+	// current directory.
+	Dir string
+	// Allow lists the commands that may run, as leading words such as "ls"
+	// or "git status". When it is empty any command not denied may run.
+	// When it is set, commands that assign variables, such as
+	// "PATH=/tmp ls", or redirect input or output are rejected too, since
+	// they change what an allowed command does.
+	Allow []string
+	// Deny lists the commands that may not run, in the same form as Allow.
+	// Deny takes precedence over Allow. Deny alone is best-effort: a shell
+	// has too many ways to run a program, such as scripts, aliases or
+	// interpreters, to rule them all out, so use Allow where it matters.
+	Deny []string
+	// Timeout is how long a command may run. Defaults to DefaultTimeout.
+	Timeout time.Duration
+	// MaxOutputBytes caps the combined output returned to the model. The
+	// beginning and the end of longer output are kept. Defaults to
+	// DefaultMaxOutputBytes.
+	MaxOutputBytes int
+	// PassEnv lists the environment variables passed through from the
+	// process. Defaults to DefaultPassEnv; use an empty slice to pass none.
+	PassEnv []string
+	// Env holds extra environment variables, as KEY=VALUE.
+	Env []string
+}
+
+// New creates the shell tool.
+func New(cfg Config) fantasy.AgentTool {
+	if cfg.Shell == "" {
+		cfg.Shell = "sh"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.MaxOutputBytes <= 0 {
+		cfg.MaxOutputBytes = DefaultMaxOutputBytes
+	}
+	if cfg.PassEnv == nil {
+		cfg.PassEnv = DefaultPassEnv
+	}
+	return fantasy.NewAgentTool(ToolName, description(cfg), cfg.run)
+}
+
+func description(cfg Config) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Run a command with %s and return its combined output and exit code. Commands time out after %s.", cfg.Shell, cfg.Timeout)
+	if len(cfg.Allow) > 0 {
+		fmt.Fprintf(&sb, " Only these commands are allowed: %s.", strings.Join(cfg.Allow, ", "))
+	}
+	if len(cfg.Deny) > 0 {
+		fmt.Fprintf(&sb, " These commands are not allowed: %s.", strings.Join(cfg.Deny, ", "))
+	}
+	return sb.String()
+}
+
+type input struct {
+	Command string `json:"command" description:"The command to run"`
+}
+
+func (cfg Config) run(ctx context.Context, in input, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+	if err := cfg.check(in.Command); err != nil {
+		return fantasy.NewTextErrorResponse(err.Error()), nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, cfg.Shell, "-c", in.Command)
+	cmd.Dir = cfg.Dir
+	cmd.Env = cfg.environ()
+	// Don't wait forever for children that keep the output open.
+	cmd.WaitDelay = time.Second
+	out := &output{live: call.Output, max: cfg.MaxOutputBytes}
+	cmd.Stdout = out
+	cmd.Stderr = out
+
+	err := cmd.Run()
+	text := out.String()
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fantasy.NewTextErrorResponse(text + fmt.Sprintf("\n[Command timed out after %s]", cfg.Timeout)), nil
+	case errors.As(err, &exitErr):
+		return fantasy.NewTextErrorResponse(text + fmt.Sprintf("\n[Exit code %d]", exitErr.ExitCode())), nil
+	case err != nil:
+		return fantasy.NewTextErrorResponse(fmt.Sprintf("running command: %v", err)), nil
+	}
+	if text == "" {
+		text = "[No output]"
+	}
+	return fantasy.NewTextResponse(text), nil
+}
+
+// shellOperators separate the commands of a command line. Quoting is not
+// parsed, so quoted operators split too, which can only make the rules
+// stricter.
+var shellOperators = regexp.MustCompile(`&&|\|\||[;|&\n()]`)
+
+// check returns an error if the command line runs a command the rules don't
+// allow. Command and process substitution are always rejected, since the
+// nested commands can't be checked.
+func (cfg Config) check(command string) error {
+	if strings.TrimSpace(command) == "" {
+		return errors.New("command is required")
+	}
+	if strings.Contains(command, "$(") || strings.Contains(command, "`") ||
+		strings.Contains(command, "<(") || strings.Contains(command, ">(") {
+		return errors.New("command substitution is not allowed")
+	}
+	if len(cfg.Allow) > 0 && strings.ContainsAny(command, "<>") {
+		return errors.New("redirections are not allowed")
+	}
+	for _, segment := range shellOperators.Split(command, -1) {
+		words, assigns := commandWords(segment)
+		if len(cfg.Allow) > 0 && assigns {
+			return errors.New("variable assignments are not allowed")
+		}
+		if len(words) == 0 {
+			continue
+		}
+		if slices.ContainsFunc(cfg.Deny, func(rule string) bool { return matches(words, rule) }) {
+			return fmt.Errorf("command not allowed: %s", strings.Join(words, " "))
+		}
+		if len(cfg.Allow) > 0 && !slices.ContainsFunc(cfg.Allow, func(rule string) bool { return matches(words, rule) }) {
+			return fmt.Errorf("command not allowed: %s", strings.Join(words, " "))
+		}
+	}
+	return nil
+}
+
+// commandWords splits a command into words, without quotes and leading
+// variable assignments, and reports whether it had any.
+func commandWords(command string) (words []string, assigns bool) {
+	unquote := strings.NewReplacer(`"`, "", `'`, "", `\`, "")
+	for _, word := range strings.Fields(command) {
+		if len(words) == 0 && strings.Contains(word, "=") {
+			assigns = true
+			continue
+		}
+		words = append(words, unquote.Replace(word))
+	}
+	return words, assigns
+}
+
+// matches reports whether the words start with the words of the rule.
+func matches(words []string, rule string) bool {
+	ruleWords := strings.Fields(rule)
+	return len(ruleWords) > 0 && len(ruleWords) <= len(words) && slices.Equal(words[:len(ruleWords)], ruleWords)
+}
+
+func (cfg Config) environ() []string {
+	var env []string
+	for _, name := range cfg.PassEnv {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	return append(env, cfg.Env...)
+}
+
+// output collects the output of a command, keeping its beginning and end
+// when it is over max bytes, and writes it to live as it is written.
+type output struct {
+	live    io.Writer
+	max     int
+	mu      sync.Mutex
+	head    []byte
+	tail    []byte
+	dropped int
+}
+
+func (o *output) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.live != nil {
+		_, _ = o.live.Write(p)
+	}
+
+	rest := p
+	if n := min(o.max/2-len(o.head), len(rest)); n > 0 {
+		o.head = append(o.head, rest[:n]...)
+		rest = rest[n:]
+	}
+	o.tail = append(o.tail, rest...)
+	if keep := o.max - o.max/2; len(o.tail) > keep {
+		o.dropped += len(o.tail) - keep
+		o.tail = append(o.tail[:0], o.tail[len(o.tail)-keep:]...)
+	}
+	return len(p), nil
+}
+
+func (o *output) String() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.dropped == 0 {
+		return string(o.head) + string(o.tail)
+	}
+	return fmt.Sprintf("%s\n[... %d bytes of output truncated ...]\n%s", o.head, o.dropped, o.tail)
+}
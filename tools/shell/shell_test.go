@@ -0,0 +1,121 @@
+package shell
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/require"
+)
+
+func run(t *testing.T, tool fantasy.AgentTool, command string) fantasy.ToolResponse {
+	t.Helper()
+	input, err := json.Marshal(map[string]string{"command": command})
+	require.NoError(t, err)
+	resp, err := tool.Run(t.Context(), fantasy.ToolCall{ID: "1", Name: ToolName, Input: string(input)})
+	require.NoError(t, err)
+	return resp
+}
+
+func TestCheck(t *testing.T) {
+	t.Parallel()
+
+	cfg := Config{
+		Allow: []string{"ls", "git status", "git log", "echo"},
+		Deny:  []string{"git log -p"},
+	}
+	for command, allowed := range map[string]bool{
+		"ls -la":                      true,
+		"git status && echo done":     true,
+		"FOO=1 git status":            false,
+		"LD_PRELOAD=/tmp/x.so ls":     false,
+		"PATH=/tmp ls":                false,
+		"ls > ~/.bashrc":              false,
+		"ls < /etc/shadow":            false,
+		"ls 2>&1":                     false,
+		"git log --oneline | head":    false,
+		"git log -p":                  false,
+		`git "log" -p`:                false,
+		"rm -rf /":                    false,
+		"ls; rm -rf /":                false,
+		"echo $(rm -rf /)":            false,
+		"echo `rm -rf /`":             false,
+		"git push":                    false,
+		"(rm x)":                      false,
+		`echo "safe; rm -rf /"`:       false,
+		"  ":                          false,
+		"ls\nrm x":                    false,
+		"git status --short & wait x": false,
+	} {
+		err := cfg.check(command)
+		if allowed {
+			require.NoError(t, err, command)
+		} else {
+			require.Error(t, err, command)
+		}
+	}
+
+	// Without an allow list any command not denied may run, but
+	// substitutions are still rejected.
+	deny := Config{Deny: []string{"rm"}}
+	require.NoError(t, deny.check("FOO=1 echo hi > out.txt"))
+	require.Error(t, deny.check("echo hi && rm x"))
+	require.Error(t, deny.check("FOO=1 rm x"))
+	require.Error(t, deny.check("echo $(rm -rf /)"))
+	require.Error(t, deny.check("echo `rm -rf /`"))
+	require.Error(t, deny.check("cat <(rm -rf /)"))
+}
+
+func TestShell(t *testing.T) {
+	t.Parallel()
+
+	t.Run("output", func(t *testing.T) {
+		t.Parallel()
+
+		resp := run(t, New(Config{}), "echo out; echo err >&2")
+		require.False(t, resp.IsError)
+		require.Equal(t, "out\nerr\n", resp.Content)
+	})
+
+	t.Run("exit code", func(t *testing.T) {
+		t.Parallel()
+
+		resp := run(t, New(Config{}), "echo failing; exit 3")
+		require.True(t, resp.IsError)
+		require.Equal(t, "failing\n\n[Exit code 3]", resp.Content)
+	})
+
+	t.Run("not allowed", func(t *testing.T) {
+		t.Parallel()
+
+		resp := run(t, New(Config{Allow: []string{"echo"}}), "cat /etc/passwd")
+		require.True(t, resp.IsError)
+		require.Equal(t, "command not allowed: cat /etc/passwd", resp.Content)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		t.Parallel()
+
+		resp := run(t, New(Config{Timeout: 100 * time.Millisecond}), "sleep 5")
+		require.True(t, resp.IsError)
+		require.Contains(t, resp.Content, "[Command timed out after 100ms]")
+	})
+
+	t.Run("output cap", func(t *testing.T) {
+		t.Parallel()
+
+		resp := run(t, New(Config{MaxOutputBytes: 8}), "printf 'aaaa0123456789bbbb'")
+		require.Equal(t, "aaaa\n[... 10 bytes of output truncated ...]\nbbbb", resp.Content)
+	})
+}
+
+func TestShellEnvironment(t *testing.T) {
+	t.Setenv("FANTASY_SHELL_SECRET", "hunter2")
+
+	resp := run(t, New(Config{Env: []string{"GREETING=hi"}}), `echo "$GREETING $FANTASY_SHELL_SECRET"`)
+	require.Equal(t, "hi \n", resp.Content)
+
+	resp = run(t, New(Config{PassEnv: []string{"FANTASY_SHELL_SECRET"}}), `echo "$FANTASY_SHELL_SECRET"`)
+	require.Equal(t, "hunter2\n", resp.Content)
+}
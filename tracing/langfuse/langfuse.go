@@ -0,0 +1,259 @@
+// Package langfuse exports traces recorded with the tracing package to
+// Langfuse through its ingestion API.
+//
+// Example:
+//
+//	exporter := langfuse.New(os.Getenv("LANGFUSE_PUBLIC_KEY"), os.Getenv("LANGFUSE_SECRET_KEY"))
+//	err := exporter.Export(ctx, trace)
+package langfuse
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/tracing"
+	"charm.land/fantasy/transcript"
+	"github.com/google/uuid"
+)
+
+// DefaultBaseURL is the URL of Langfuse Cloud.
+const DefaultBaseURL = "https://cloud.langfuse.com"
+
+// Exporter pushes traces to Langfuse.
+type Exporter struct {
+	publicKey  string
+	secretKey  string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// Option configures an Exporter.
+type Option func(*Exporter)
+
+// WithBaseURL sets the URL of a self-hosted or regional Langfuse instance.
+func WithBaseURL(baseURL string) Option {
+	return func(e *Exporter) {
+		e.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithHTTPClient sets the HTTP client requests are made with.
+func WithHTTPClient(client *http.Client) Option {
+	return func(e *Exporter) {
+		e.httpClient = client
+	}
+}
+
+// New creates an exporter authenticating with the project's API keys.
+func New(publicKey, secretKey string, opts ...Option) *Exporter {
+	e := &Exporter{
+		publicKey:  publicKey,
+		secretKey:  secretKey,
+		baseURL:    DefaultBaseURL,
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+var _ tracing.Exporter = (*Exporter)(nil)
+
+// event is an event of the ingestion API.
+type event struct {
+	ID        string `json:"id"`
+	Timestamp string `json:"timestamp"`
+	Type      string `json:"type"`
+	Body      any    `json:"body"`
+}
+
+type traceBody struct {
+	ID        string            `json:"id"`
+	Timestamp string            `json:"timestamp"`
+	Name      string            `json:"name,omitempty"`
+	UserID    string            `json:"userId,omitempty"`
+	SessionID string            `json:"sessionId,omitempty"`
+	Input     any               `json:"input,omitempty"`
+	Output    any               `json:"output,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+}
+
+type observationBody struct {
+	ID                  string         `json:"id"`
+	TraceID             string         `json:"traceId"`
+	ParentObservationID string         `json:"parentObservationId,omitempty"`
+	Name                string         `json:"name"`
+	StartTime           string         `json:"startTime"`
+	EndTime             string         `json:"endTime,omitempty"`
+	Input               any            `json:"input,omitempty"`
+	Output              any            `json:"output,omitempty"`
+	Level               string         `json:"level,omitempty"`
+	StatusMessage       string         `json:"statusMessage,omitempty"`
+	Metadata            map[string]any `json:"metadata,omitempty"`
+
+	// Generations only.
+	Model           string         `json:"model,omitempty"`
+	ModelParameters map[string]any `json:"modelParameters,omitempty"`
+	UsageDetails    map[string]int `json:"usageDetails,omitempty"`
+}
+
+type scoreBody struct {
+	ID       string  `json:"id"`
+	TraceID  string  `json:"traceId"`
+	Name     string  `json:"name"`
+	Value    float64 `json:"value"`
+	DataType string  `json:"dataType"`
+	Comment  string  `json:"comment,omitempty"`
+}
+
+// Export implements tracing.Exporter. The trace's tenant becomes the
+// Langfuse user, or the MetadataUserID of its metadata if set, and the
+// "session_id" metadata its session.
+func (e *Exporter) Export(ctx context.Context, trace tracing.Trace) error {
+	userID := trace.TenantID
+	if id := trace.Metadata[fantasy.MetadataUserID]; id != "" {
+		userID = id
+	}
+	body := traceBody{
+		ID:        trace.ID,
+		Timestamp: timestamp(trace.Start),
+		Name:      trace.Name,
+		UserID:    userID,
+		SessionID: trace.Metadata["session_id"],
+		Input:     trace.Input,
+		Output:    trace.Output,
+		Metadata:  trace.Metadata,
+	}
+	batch := []event{newEvent("trace-create", body)}
+	for _, span := range trace.Spans {
+		batch = append(batch, observationEvent(trace.ID, span))
+	}
+	for _, score := range trace.Scores {
+		batch = append(batch, scoreEvent(trace.ID, score))
+	}
+	return e.ingest(ctx, batch)
+}
+
+// Score implements tracing.Exporter.
+func (e *Exporter) Score(ctx context.Context, traceID string, score tracing.Score) error {
+	return e.ingest(ctx, []event{scoreEvent(traceID, score)})
+}
+
+func observationEvent(traceID string, span tracing.Span) event {
+	body := observationBody{
+		ID:                  span.ID,
+		TraceID:             traceID,
+		ParentObservationID: span.ParentID,
+		Name:                span.Name,
+		StartTime:           timestamp(span.Start),
+		EndTime:             timestamp(span.End),
+	}
+	if span.Error != "" {
+		body.Level = "ERROR"
+		body.StatusMessage = span.Error
+	}
+	if span.Kind == tracing.SpanTool {
+		body.Input = json.RawMessage(toolInput(span.ToolInput))
+		body.Output = span.ToolOutput
+		body.Metadata = map[string]any{"tool_call_id": span.ToolCallID}
+		return newEvent("span-create", body)
+	}
+	body.Input = transcript.ToOpenAI(span.Prompt)
+	body.Output = transcript.ToOpenAI(fantasy.Prompt{span.Completion()})
+	body.Model = span.Model
+	body.ModelParameters = span.Parameters
+	body.Metadata = map[string]any{"provider": span.Provider}
+	if span.FinishReason != "" {
+		body.Metadata["finish_reason"] = string(span.FinishReason)
+	}
+	body.UsageDetails = map[string]int{
+		"input":  int(span.Usage.InputTokens),
+		"output": int(span.Usage.OutputTokens),
+		"total":  int(span.Usage.InputTokens + span.Usage.OutputTokens + span.Usage.CacheCreationTokens + span.Usage.CacheReadTokens),
+	}
+	if span.Usage.CacheReadTokens > 0 {
+		body.UsageDetails["cache_read_input_tokens"] = int(span.Usage.CacheReadTokens)
+	}
+	if span.Usage.CacheCreationTokens > 0 {
+		body.UsageDetails["cache_creation_input_tokens"] = int(span.Usage.CacheCreationTokens)
+	}
+	return newEvent("generation-create", body)
+}
+
+func scoreEvent(traceID string, score tracing.Score) event {
+	return newEvent("score-create", scoreBody{
+		ID:       uuid.NewString(),
+		TraceID:  traceID,
+		Name:     score.Name,
+		Value:    score.Value,
+		DataType: "NUMERIC",
+		Comment:  score.Comment,
+	})
+}
+
+func newEvent(eventType string, body any) event {
+	return event{
+		ID:        uuid.NewString(),
+		Timestamp: timestamp(time.Now()),
+		Type:      eventType,
+		Body:      body,
+	}
+}
+
+// ingestionResponse is the multi-status response of the ingestion API.
+type ingestionResponse struct {
+	Errors []struct {
+		ID      string `json:"id"`
+		Status  int    `json:"status"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func (e *Exporter) ingest(ctx context.Context, batch []event) error {
+	data, err := json.Marshal(map[string]any{"batch": batch})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.baseURL+"/api/public/ingestion", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(e.publicKey, e.secretKey)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("langfuse: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("langfuse: ingestion failed with status %d: %s", resp.StatusCode, respBody)
+	}
+	var result ingestionResponse
+	if err := json.Unmarshal(respBody, &result); err == nil && len(result.Errors) > 0 {
+		first := result.Errors[0]
+		return fmt.Errorf("langfuse: %d of %d events rejected, first with status %d: %s",
+			len(result.Errors), len(batch), first.Status, first.Message)
+	}
+	return nil
+}
+
+func timestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func toolInput(input string) string {
+	if !json.Valid([]byte(input)) {
+		data, _ := json.Marshal(input)
+		return string(data)
+	}
+	return input
+}
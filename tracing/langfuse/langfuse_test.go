@@ -0,0 +1,145 @@
+package langfuse
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/tracing"
+	"github.com/stretchr/testify/require"
+)
+
+func testTrace() tracing.Trace {
+	start := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	return tracing.Trace{
+		ID:       "trace-1",
+		Name:     "support",
+		Start:    start,
+		End:      start.Add(time.Second),
+		Input:    "hello",
+		Output:   "done",
+		TenantID: "acme",
+		Metadata: map[string]string{fantasy.MetadataUserID: "user-1"},
+		Spans: []tracing.Span{
+			{
+				ID:         "gen-1",
+				Kind:       tracing.SpanGeneration,
+				Name:       "mock/model",
+				Start:      start,
+				End:        start.Add(100 * time.Millisecond),
+				Provider:   "mock",
+				Model:      "model",
+				Parameters: map[string]any{"temperature": 0.5},
+				Prompt:     fantasy.Prompt{fantasy.NewUserMessage("hello")},
+				Content: fantasy.ResponseContent{
+					fantasy.ToolCallContent{ToolCallID: "call-1", ToolName: "lookup", Input: `{}`},
+				},
+				Usage:        fantasy.Usage{InputTokens: 10, OutputTokens: 5, CacheReadTokens: 3},
+				FinishReason: fantasy.FinishReasonToolCalls,
+			},
+			{
+				ID:         "tool-1",
+				ParentID:   "gen-1",
+				Kind:       tracing.SpanTool,
+				Name:       "lookup",
+				Start:      start.Add(100 * time.Millisecond),
+				End:        start.Add(200 * time.Millisecond),
+				ToolCallID: "call-1",
+				ToolInput:  `{}`,
+				Error:      "not found",
+			},
+		},
+		Scores: []tracing.Score{{Name: "helpful", Value: 1, Comment: "good"}},
+	}
+}
+
+type ingestion struct {
+	Batch []struct {
+		ID   string         `json:"id"`
+		Type string         `json:"type"`
+		Body map[string]any `json:"body"`
+	} `json:"batch"`
+}
+
+func TestExport(t *testing.T) {
+	t.Parallel()
+
+	var got ingestion
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/public/ingestion", r.URL.Path)
+		user, pass, ok := r.BasicAuth()
+		require.True(t, ok)
+		require.Equal(t, "pk", user)
+		require.Equal(t, "sk", pass)
+		body, _ := io.ReadAll(r.Body)
+		require.NoError(t, json.Unmarshal(body, &got))
+		w.WriteHeader(http.StatusMultiStatus)
+		_, _ = w.Write([]byte(`{"successes":[],"errors":[]}`))
+	}))
+	defer server.Close()
+
+	exporter := New("pk", "sk", WithBaseURL(server.URL+"/"))
+	require.NoError(t, exporter.Export(t.Context(), testTrace()))
+
+	require.Len(t, got.Batch, 4)
+	types := make([]string, len(got.Batch))
+	for i, event := range got.Batch {
+		require.NotEmpty(t, event.ID)
+		types[i] = event.Type
+	}
+	require.Equal(t, []string{"trace-create", "generation-create", "span-create", "score-create"}, types)
+
+	trace := got.Batch[0].Body
+	require.Equal(t, "trace-1", trace["id"])
+	require.Equal(t, "user-1", trace["userId"])
+	require.Equal(t, "hello", trace["input"])
+
+	generation := got.Batch[1].Body
+	require.Equal(t, "trace-1", generation["traceId"])
+	require.Nil(t, generation["parentObservationId"])
+	require.Equal(t, "model", generation["model"])
+	require.Equal(t, map[string]any{"temperature": 0.5}, generation["modelParameters"])
+	require.Equal(t, []any{map[string]any{"role": "user", "content": "hello"}}, generation["input"])
+	require.Equal(t, map[string]any{"input": 10.0, "output": 5.0, "total": 18.0, "cache_read_input_tokens": 3.0}, generation["usageDetails"])
+
+	span := got.Batch[2].Body
+	require.Equal(t, "gen-1", span["parentObservationId"])
+	require.Equal(t, "ERROR", span["level"])
+	require.Equal(t, "not found", span["statusMessage"])
+	require.Equal(t, map[string]any{}, span["input"])
+
+	score := got.Batch[3].Body
+	require.Equal(t, "trace-1", score["traceId"])
+	require.Equal(t, "helpful", score["name"])
+	require.Equal(t, 1.0, score["value"])
+}
+
+func TestExport_Errors(t *testing.T) {
+	t.Parallel()
+
+	t.Run("status", func(t *testing.T) {
+		t.Parallel()
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+		}))
+		defer server.Close()
+		err := New("pk", "sk", WithBaseURL(server.URL)).Export(t.Context(), testTrace())
+		require.ErrorContains(t, err, "status 401")
+	})
+
+	t.Run("rejected events", func(t *testing.T) {
+		t.Parallel()
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusMultiStatus)
+			_, _ = w.Write([]byte(`{"errors":[{"id":"1","status":400,"message":"invalid body"}]}`))
+		}))
+		defer server.Close()
+		err := New("pk", "sk", WithBaseURL(server.URL)).Score(t.Context(), "trace-1", tracing.Score{Name: "helpful"})
+		require.ErrorContains(t, err, "1 of 1 events rejected")
+		require.ErrorContains(t, err, "invalid body")
+	})
+}
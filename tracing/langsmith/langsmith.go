@@ -0,0 +1,238 @@
+// Package langsmith exports traces recorded with the tracing package to
+// LangSmith through its run ingestion API.
+//
+// Example:
+//
+//	exporter := langsmith.New(os.Getenv("LANGSMITH_API_KEY"), langsmith.WithProject("support-agent"))
+//	err := exporter.Export(ctx, trace)
+package langsmith
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/tracing"
+	"charm.land/fantasy/transcript"
+	"github.com/google/uuid"
+)
+
+// DefaultEndpoint is the URL of the LangSmith API.
+const DefaultEndpoint = "https://api.smith.langchain.com"
+
+// Exporter pushes traces to LangSmith.
+type Exporter struct {
+	apiKey     string
+	endpoint   string
+	project    string
+	httpClient *http.Client
+}
+
+// Option configures an Exporter.
+type Option func(*Exporter)
+
+// WithEndpoint sets the URL of a self-hosted or regional LangSmith API.
+func WithEndpoint(endpoint string) Option {
+	return func(e *Exporter) {
+		e.endpoint = strings.TrimSuffix(endpoint, "/")
+	}
+}
+
+// WithProject sets the project runs are logged to. LangSmith uses its
+// default project if unset.
+func WithProject(project string) Option {
+	return func(e *Exporter) {
+		e.project = project
+	}
+}
+
+// WithHTTPClient sets the HTTP client requests are made with.
+func WithHTTPClient(client *http.Client) Option {
+	return func(e *Exporter) {
+		e.httpClient = client
+	}
+}
+
+// New creates an exporter authenticating with the API key.
+func New(apiKey string, opts ...Option) *Exporter {
+	e := &Exporter{
+		apiKey:     apiKey,
+		endpoint:   DefaultEndpoint,
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+var _ tracing.Exporter = (*Exporter)(nil)
+
+type run struct {
+	ID          string         `json:"id"`
+	TraceID     string         `json:"trace_id"`
+	ParentRunID string         `json:"parent_run_id,omitempty"`
+	DottedOrder string         `json:"dotted_order"`
+	Name        string         `json:"name"`
+	RunType     string         `json:"run_type"`
+	StartTime   string         `json:"start_time"`
+	EndTime     string         `json:"end_time,omitempty"`
+	Inputs      map[string]any `json:"inputs"`
+	Outputs     map[string]any `json:"outputs,omitempty"`
+	Error       string         `json:"error,omitempty"`
+	SessionName string         `json:"session_name,omitempty"`
+	Extra       map[string]any `json:"extra,omitempty"`
+}
+
+type feedback struct {
+	ID      string  `json:"id"`
+	RunID   string  `json:"run_id"`
+	Key     string  `json:"key"`
+	Score   float64 `json:"score"`
+	Comment string  `json:"comment,omitempty"`
+}
+
+// Export implements tracing.Exporter. The trace becomes a chain run with a
+// child llm run per generation and tool runs under the generation that
+// called them. Scores are sent as feedback on the root run.
+func (e *Exporter) Export(ctx context.Context, trace tracing.Trace) error {
+	metadata := map[string]any{}
+	for k, v := range trace.Metadata {
+		metadata[k] = v
+	}
+	if trace.TenantID != "" {
+		metadata["tenant_id"] = trace.TenantID
+	}
+	root := run{
+		ID:          trace.ID,
+		TraceID:     trace.ID,
+		DottedOrder: dottedOrder(trace.Start, trace.ID),
+		Name:        trace.Name,
+		RunType:     "chain",
+		StartTime:   timestamp(trace.Start),
+		EndTime:     timestamp(trace.End),
+		Inputs:      map[string]any{"input": trace.Input},
+		Outputs:     map[string]any{"output": trace.Output},
+		Error:       trace.Error,
+		SessionName: e.project,
+		Extra:       map[string]any{"metadata": metadata},
+	}
+	runs := []run{root}
+	orders := map[string]string{trace.ID: root.DottedOrder}
+	for _, span := range trace.Spans {
+		parentID := span.ParentID
+		if parentID == "" {
+			parentID = trace.ID
+		}
+		r := e.spanRun(trace.ID, parentID, span)
+		r.DottedOrder = orders[parentID] + "." + dottedOrder(span.Start, span.ID)
+		orders[span.ID] = r.DottedOrder
+		runs = append(runs, r)
+	}
+	if err := e.post(ctx, "/runs/batch", map[string]any{"post": runs}); err != nil {
+		return err
+	}
+	for _, score := range trace.Scores {
+		if err := e.Score(ctx, trace.ID, score); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Score implements tracing.Exporter.
+func (e *Exporter) Score(ctx context.Context, traceID string, score tracing.Score) error {
+	return e.post(ctx, "/feedback", feedback{
+		ID:      uuid.NewString(),
+		RunID:   traceID,
+		Key:     score.Name,
+		Score:   score.Value,
+		Comment: score.Comment,
+	})
+}
+
+func (e *Exporter) spanRun(traceID, parentID string, span tracing.Span) run {
+	r := run{
+		ID:          span.ID,
+		TraceID:     traceID,
+		ParentRunID: parentID,
+		Name:        span.Name,
+		StartTime:   timestamp(span.Start),
+		EndTime:     timestamp(span.End),
+		Error:       span.Error,
+		SessionName: e.project,
+	}
+	if span.Kind == tracing.SpanTool {
+		r.RunType = "tool"
+		r.Inputs = map[string]any{"input": span.ToolInput}
+		r.Outputs = map[string]any{"output": span.ToolOutput}
+		r.Extra = map[string]any{"metadata": map[string]any{"tool_call_id": span.ToolCallID}}
+		return r
+	}
+	r.RunType = "llm"
+	r.Inputs = map[string]any{"messages": transcript.ToOpenAI(span.Prompt)}
+	r.Outputs = map[string]any{
+		"messages": transcript.ToOpenAI(fantasy.Prompt{span.Completion()}),
+		"usage_metadata": map[string]any{
+			"input_tokens":  span.Usage.InputTokens + span.Usage.CacheCreationTokens + span.Usage.CacheReadTokens,
+			"output_tokens": span.Usage.OutputTokens,
+			"total_tokens":  span.Usage.InputTokens + span.Usage.CacheCreationTokens + span.Usage.CacheReadTokens + span.Usage.OutputTokens,
+			"input_token_details": map[string]any{
+				"cache_read":     span.Usage.CacheReadTokens,
+				"cache_creation": span.Usage.CacheCreationTokens,
+			},
+		},
+	}
+	if span.FinishReason != "" {
+		r.Outputs["finish_reason"] = string(span.FinishReason)
+	}
+	metadata := map[string]any{
+		"ls_provider":   span.Provider,
+		"ls_model_name": span.Model,
+	}
+	for k, v := range span.Parameters {
+		metadata["ls_"+k] = v
+	}
+	r.Extra = map[string]any{"metadata": metadata, "invocation_params": span.Parameters}
+	return r
+}
+
+func (e *Exporter) post(ctx context.Context, path string, body any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("x-api-key", e.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("langsmith: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("langsmith: %s failed with status %d: %s", path, resp.StatusCode, respBody)
+	}
+	return nil
+}
+
+// dottedOrder returns the segment of a run in the dotted order LangSmith
+// sorts and nests runs by.
+func dottedOrder(start time.Time, id string) string {
+	start = start.UTC()
+	return fmt.Sprintf("%s%06dZ%s", start.Format("20060102T150405"), start.Nanosecond()/1000, id)
+}
+
+func timestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,142 @@
+package langsmith
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/tracing"
+	"github.com/stretchr/testify/require"
+)
+
+func testTrace() tracing.Trace {
+	start := time.Date(2025, 1, 2, 3, 4, 5, 123456789, time.UTC)
+	return tracing.Trace{
+		ID:       "trace-1",
+		Name:     "support",
+		Start:    start,
+		End:      start.Add(time.Second),
+		Input:    "hello",
+		Output:   "done",
+		TenantID: "acme",
+		Spans: []tracing.Span{
+			{
+				ID:         "gen-1",
+				Kind:       tracing.SpanGeneration,
+				Name:       "mock/model",
+				Start:      start,
+				End:        start.Add(100 * time.Millisecond),
+				Provider:   "mock",
+				Model:      "model",
+				Parameters: map[string]any{"temperature": 0.5},
+				Prompt:     fantasy.Prompt{fantasy.NewUserMessage("hello")},
+				Content: fantasy.ResponseContent{
+					fantasy.ToolCallContent{ToolCallID: "call-1", ToolName: "lookup", Input: `{}`},
+				},
+				Usage:        fantasy.Usage{InputTokens: 10, OutputTokens: 5},
+				FinishReason: fantasy.FinishReasonToolCalls,
+			},
+			{
+				ID:         "tool-1",
+				ParentID:   "gen-1",
+				Kind:       tracing.SpanTool,
+				Name:       "lookup",
+				Start:      start.Add(100 * time.Millisecond),
+				End:        start.Add(200 * time.Millisecond),
+				ToolCallID: "call-1",
+				ToolInput:  `{}`,
+				ToolOutput: "found",
+			},
+		},
+		Scores: []tracing.Score{{Name: "helpful", Value: 1, Comment: "good"}},
+	}
+}
+
+type request struct {
+	path string
+	body map[string]any
+}
+
+func newServer(t *testing.T) (*httptest.Server, func() []request) {
+	var (
+		mu       sync.Mutex
+		requests []request
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "key", r.Header.Get("x-api-key"))
+		data, _ := io.ReadAll(r.Body)
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(data, &body))
+		mu.Lock()
+		requests = append(requests, request{path: r.URL.Path, body: body})
+		mu.Unlock()
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []request {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+}
+
+func TestExport(t *testing.T) {
+	t.Parallel()
+
+	server, requests := newServer(t)
+	exporter := New("key", WithEndpoint(server.URL), WithProject("support-agent"))
+	require.NoError(t, exporter.Export(t.Context(), testTrace()))
+
+	reqs := requests()
+	require.Len(t, reqs, 2)
+	require.Equal(t, "/runs/batch", reqs[0].path)
+	runs := reqs[0].body["post"].([]any)
+	require.Len(t, runs, 3)
+	root, llm, tool := runs[0].(map[string]any), runs[1].(map[string]any), runs[2].(map[string]any)
+
+	require.Equal(t, "chain", root["run_type"])
+	require.Equal(t, "trace-1", root["trace_id"])
+	require.Nil(t, root["parent_run_id"])
+	require.Equal(t, "20250102T030405123456Ztrace-1", root["dotted_order"])
+	require.Equal(t, "support-agent", root["session_name"])
+	require.Equal(t, map[string]any{"input": "hello"}, root["inputs"])
+	require.Equal(t, "acme", root["extra"].(map[string]any)["metadata"].(map[string]any)["tenant_id"])
+
+	require.Equal(t, "llm", llm["run_type"])
+	require.Equal(t, "trace-1", llm["parent_run_id"])
+	require.Equal(t, "20250102T030405123456Ztrace-1.20250102T030405123456Zgen-1", llm["dotted_order"])
+	outputs := llm["outputs"].(map[string]any)
+	require.Equal(t, 15.0, outputs["usage_metadata"].(map[string]any)["total_tokens"])
+	metadata := llm["extra"].(map[string]any)["metadata"].(map[string]any)
+	require.Equal(t, "mock", metadata["ls_provider"])
+	require.Equal(t, "model", metadata["ls_model_name"])
+	require.Equal(t, 0.5, metadata["ls_temperature"])
+
+	require.Equal(t, "tool", tool["run_type"])
+	require.Equal(t, "gen-1", tool["parent_run_id"])
+	require.True(t, strings.HasPrefix(tool["dotted_order"].(string), llm["dotted_order"].(string)+"."))
+	require.Equal(t, map[string]any{"output": "found"}, tool["outputs"])
+
+	require.Equal(t, "/feedback", reqs[1].path)
+	require.Equal(t, "trace-1", reqs[1].body["run_id"])
+	require.Equal(t, "helpful", reqs[1].body["key"])
+	require.Equal(t, 1.0, reqs[1].body["score"])
+	require.Equal(t, "good", reqs[1].body["comment"])
+}
+
+func TestExport_Error(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}))
+	defer server.Close()
+	err := New("key", WithEndpoint(server.URL)).Export(t.Context(), testTrace())
+	require.ErrorContains(t, err, "/runs/batch failed with status 403")
+}
@@ -0,0 +1,165 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	"charm.land/fantasy"
+)
+
+// Model returns a model recording a generation span for each call made in
+// a run. It has the optional capabilities of model.
+func Model(model fantasy.LanguageModel) fantasy.LanguageModel {
+	return fantasy.WithCapabilities(&tracedModel{LanguageModel: model}, model)
+}
+
+type tracedModel struct {
+	fantasy.LanguageModel
+}
+
+func (m *tracedModel) startGeneration(run *Run, call fantasy.Call) int {
+	return run.startSpan(Span{
+		Kind:       SpanGeneration,
+		Name:       m.Provider() + "/" + m.Model(),
+		Provider:   m.Provider(),
+		Model:      m.Model(),
+		Parameters: parameters(call),
+		Prompt:     call.Prompt,
+	})
+}
+
+// Generate implements fantasy.LanguageModel.
+func (m *tracedModel) Generate(ctx context.Context, call fantasy.Call) (*fantasy.Response, error) {
+	run := FromContext(ctx)
+	if run == nil {
+		return m.LanguageModel.Generate(ctx, call)
+	}
+	i := m.startGeneration(run, call)
+	resp, err := m.LanguageModel.Generate(ctx, call)
+	run.endSpan(i, func(span *Span) {
+		if err != nil {
+			span.Error = err.Error()
+			return
+		}
+		span.Content = resp.Content
+		span.Usage = resp.Usage
+		span.FinishReason = resp.FinishReason
+	})
+	return resp, err
+}
+
+// Stream implements fantasy.LanguageModel. The generation ends with the
+// stream.
+func (m *tracedModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.StreamResponse, error) {
+	run := FromContext(ctx)
+	if run == nil {
+		return m.LanguageModel.Stream(ctx, call)
+	}
+	i := m.startGeneration(run, call)
+	stream, err := m.LanguageModel.Stream(ctx, call)
+	if err != nil {
+		run.endSpan(i, func(span *Span) { span.Error = err.Error() })
+		return nil, err
+	}
+	return func(yield func(fantasy.StreamPart) bool) {
+		var (
+			content      fantasy.ResponseContent
+			text         strings.Builder
+			reasoning    strings.Builder
+			usage        fantasy.Usage
+			finishReason fantasy.FinishReason
+			streamErr    error
+		)
+		defer func() {
+			if reasoning.Len() > 0 {
+				content = append(fantasy.ResponseContent{fantasy.ReasoningContent{Text: reasoning.String()}}, content...)
+			}
+			if text.Len() > 0 {
+				content = append(content, fantasy.TextContent{Text: text.String()})
+			}
+			run.endSpan(i, func(span *Span) {
+				span.Content = content
+				span.Usage = usage
+				span.FinishReason = finishReason
+				if streamErr != nil {
+					span.Error = streamErr.Error()
+				}
+			})
+		}()
+		for part := range stream {
+			switch part.Type {
+			case fantasy.StreamPartTypeTextDelta:
+				text.WriteString(part.Delta)
+			case fantasy.StreamPartTypeReasoningDelta:
+				reasoning.WriteString(part.Delta)
+			case fantasy.StreamPartTypeToolCall:
+				content = append(content, fantasy.ToolCallContent{
+					ToolCallID:       part.ID,
+					ToolName:         part.ToolCallName,
+					Input:            part.ToolCallInput,
+					ProviderExecuted: part.ProviderExecuted,
+				})
+			case fantasy.StreamPartTypeFinish:
+				usage, finishReason = part.Usage, part.FinishReason
+			case fantasy.StreamPartTypeError:
+				streamErr = part.Error
+			}
+			if !yield(part) {
+				return
+			}
+		}
+	}, nil
+}
+
+// GenerateObject implements fantasy.LanguageModel.
+func (m *tracedModel) GenerateObject(ctx context.Context, call fantasy.ObjectCall) (*fantasy.ObjectResponse, error) {
+	run := FromContext(ctx)
+	if run == nil {
+		return m.LanguageModel.GenerateObject(ctx, call)
+	}
+	i := run.startSpan(Span{
+		Kind:     SpanGeneration,
+		Name:     m.Provider() + "/" + m.Model(),
+		Provider: m.Provider(),
+		Model:    m.Model(),
+		Prompt:   call.Prompt,
+	})
+	resp, err := m.LanguageModel.GenerateObject(ctx, call)
+	run.endSpan(i, func(span *Span) {
+		if err != nil {
+			span.Error = err.Error()
+			return
+		}
+		span.Content = fantasy.ResponseContent{fantasy.TextContent{Text: resp.RawText}}
+		span.Usage = resp.Usage
+		span.FinishReason = resp.FinishReason
+	})
+	return resp, err
+}
+
+// parameters returns the sampling parameters set on the call.
+func parameters(call fantasy.Call) map[string]any {
+	params := map[string]any{}
+	if call.MaxOutputTokens != nil {
+		params["max_output_tokens"] = *call.MaxOutputTokens
+	}
+	if call.Temperature != nil {
+		params["temperature"] = *call.Temperature
+	}
+	if call.TopP != nil {
+		params["top_p"] = *call.TopP
+	}
+	if call.TopK != nil {
+		params["top_k"] = *call.TopK
+	}
+	if call.PresencePenalty != nil {
+		params["presence_penalty"] = *call.PresencePenalty
+	}
+	if call.FrequencyPenalty != nil {
+		params["frequency_penalty"] = *call.FrequencyPenalty
+	}
+	if call.ToolChoice != nil {
+		params["tool_choice"] = string(*call.ToolChoice)
+	}
+	return params
+}
@@ -0,0 +1,47 @@
+package tracing
+
+import (
+	"context"
+
+	"charm.land/fantasy"
+)
+
+// Tools returns the tools recording a tool span for each execution in a
+// run.
+func Tools(tools ...fantasy.AgentTool) []fantasy.AgentTool {
+	traced := make([]fantasy.AgentTool, len(tools))
+	for i, tool := range tools {
+		traced[i] = &tracedTool{AgentTool: tool}
+	}
+	return traced
+}
+
+type tracedTool struct {
+	fantasy.AgentTool
+}
+
+// Run implements fantasy.AgentTool.
+func (t *tracedTool) Run(ctx context.Context, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+	run := FromContext(ctx)
+	if run == nil {
+		return t.AgentTool.Run(ctx, call)
+	}
+	i := run.startSpan(Span{
+		Kind:       SpanTool,
+		Name:       call.Name,
+		ToolCallID: call.ID,
+		ToolInput:  call.Input,
+	})
+	resp, err := t.AgentTool.Run(ctx, call)
+	run.endSpan(i, func(span *Span) {
+		switch {
+		case err != nil:
+			span.Error = err.Error()
+		case resp.IsError:
+			span.Error = resp.Content
+		default:
+			span.ToolOutput = resp.Content
+		}
+	})
+	return resp, err
+}
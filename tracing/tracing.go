@@ -0,0 +1,209 @@
+// Package tracing records agent runs as traces of spans, one per model call
+// and tool execution, for export to observability tools. The langfuse and
+// langsmith sub-packages push traces to Langfuse and LangSmith; other tools
+// can implement Exporter.
+//
+// Wrap the model and the tools of the agent, and start a run in the context
+// of each call:
+//
+//	agent := fantasy.NewAgent(tracing.Model(model), fantasy.WithTools(tracing.Tools(tools...)...))
+//
+//	ctx, run := tracing.Start(ctx, "support-agent", prompt)
+//	result, err := agent.Generate(ctx, fantasy.AgentCall{Prompt: prompt})
+//	trace := run.End(result, err)
+//	err = exporter.Export(ctx, trace)
+//
+// The wrappers don't record anything for calls made outside of a run.
+package tracing
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/google/uuid"
+)
+
+// SpanKind is the kind of operation a span records.
+type SpanKind string
+
+const (
+	// SpanGeneration is a call to a language model.
+	SpanGeneration SpanKind = "generation"
+	// SpanTool is the execution of a tool.
+	SpanTool SpanKind = "tool"
+)
+
+// Span is an operation of a traced run.
+type Span struct {
+	ID string
+	// ParentID is the ID of the enclosing span, empty for spans directly
+	// under the trace. Tool spans are children of the generation that called
+	// the tool.
+	ParentID string
+	Kind     SpanKind
+	Name     string
+	Start    time.Time
+	End      time.Time
+	// Error is the error the operation failed with, if any.
+	Error string
+
+	// Provider, Model, Parameters, Prompt, Content, Usage and FinishReason
+	// describe generations.
+	Provider     string
+	Model        string
+	Parameters   map[string]any
+	Prompt       fantasy.Prompt
+	Content      fantasy.ResponseContent
+	Usage        fantasy.Usage
+	FinishReason fantasy.FinishReason
+
+	// ToolCallID, ToolInput and ToolOutput describe tool executions.
+	ToolCallID string
+	ToolInput  string
+	ToolOutput string
+}
+
+// Score is an evaluation of a trace, such as a grader result or user
+// feedback.
+type Score struct {
+	Name    string
+	Value   float64
+	Comment string
+}
+
+// Trace is a recorded agent run.
+type Trace struct {
+	// ID is a random UUID, as required by some tools.
+	ID    string
+	Name  string
+	Start time.Time
+	End   time.Time
+	// Input is the prompt the run was started with and Output the text of
+	// its final response.
+	Input  string
+	Output string
+	Error  string
+	// TenantID and Metadata are taken from the context the run was started
+	// in; see fantasy.WithTenantID and fantasy.WithCallMetadata.
+	TenantID string
+	Metadata map[string]string
+	Usage    fantasy.Usage
+	// Spans are in the order the operations started.
+	Spans []Span
+	// Scores are exported with the trace.
+	Scores []Score
+}
+
+// Exporter pushes traces to an observability tool.
+type Exporter interface {
+	Export(ctx context.Context, trace Trace) error
+	// Score adds a score to a trace already exported.
+	Score(ctx context.Context, traceID string, score Score) error
+}
+
+// Run records a trace as the agent runs.
+type Run struct {
+	mu    sync.Mutex
+	trace Trace
+	// parents maps tool call IDs to the generation that made the call.
+	parents map[string]string
+}
+
+type runContextKey struct{}
+
+// Start starts recording a run and returns a context the agent must be
+// called with.
+func Start(ctx context.Context, name, input string) (context.Context, *Run) {
+	tenantID, _ := fantasy.TenantID(ctx)
+	run := &Run{
+		trace: Trace{
+			ID:       uuid.NewString(),
+			Name:     name,
+			Start:    time.Now(),
+			Input:    input,
+			TenantID: tenantID,
+			Metadata: fantasy.CallMetadata(ctx),
+		},
+		parents: map[string]string{},
+	}
+	return context.WithValue(ctx, runContextKey{}, run), run
+}
+
+// FromContext returns the run recorded in ctx, or nil.
+func FromContext(ctx context.Context) *Run {
+	run, _ := ctx.Value(runContextKey{}).(*Run)
+	return run
+}
+
+// ID returns the ID of the trace.
+func (r *Run) ID() string {
+	return r.trace.ID
+}
+
+// Score adds a score to the trace.
+func (r *Run) Score(score Score) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.trace.Scores = append(r.trace.Scores, score)
+}
+
+// End ends the run with the result of the agent and returns its trace.
+func (r *Run) End(result *fantasy.AgentResult, err error) Trace {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.trace.End = time.Now()
+	if result != nil {
+		r.trace.Output = result.Response.Content.Text()
+		r.trace.Usage = result.TotalUsage
+	}
+	if err != nil {
+		r.trace.Error = err.Error()
+	}
+	trace := r.trace
+	trace.Spans = append([]Span(nil), r.trace.Spans...)
+	trace.Scores = append([]Score(nil), r.trace.Scores...)
+	return trace
+}
+
+// startSpan adds the span and returns its index.
+func (r *Run) startSpan(span Span) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	span.ID = uuid.NewString()
+	span.Start = time.Now()
+	if span.Kind == SpanTool {
+		span.ParentID = r.parents[span.ToolCallID]
+	}
+	r.trace.Spans = append(r.trace.Spans, span)
+	return len(r.trace.Spans) - 1
+}
+
+// endSpan ends the span at index i after update sets its results.
+func (r *Run) endSpan(i int, update func(span *Span)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	span := &r.trace.Spans[i]
+	span.End = time.Now()
+	update(span)
+	for _, call := range span.Content.ToolCalls() {
+		r.parents[call.ToolCallID] = span.ID
+	}
+}
+
+// Completion returns the content of a generation as an assistant message.
+func (s Span) Completion() fantasy.Message {
+	msg := fantasy.Message{Role: fantasy.MessageRoleAssistant}
+	if text := s.Content.Text(); text != "" {
+		msg.Content = append(msg.Content, fantasy.TextPart{Text: text})
+	}
+	for _, call := range s.Content.ToolCalls() {
+		msg.Content = append(msg.Content, fantasy.ToolCallPart{
+			ToolCallID: call.ToolCallID,
+			ToolName:   call.ToolName,
+			Input:      call.Input,
+		})
+	}
+	return msg
+}
@@ -0,0 +1,159 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/require"
+)
+
+type mockModel struct {
+	calls int
+}
+
+func (m *mockModel) Generate(ctx context.Context, call fantasy.Call) (*fantasy.Response, error) {
+	m.calls++
+	if m.calls == 1 {
+		return &fantasy.Response{
+			Content: fantasy.ResponseContent{
+				fantasy.ToolCallContent{ToolCallID: "call-1", ToolName: "lookup", Input: `{"q":"fantasy"}`},
+			},
+			FinishReason: fantasy.FinishReasonToolCalls,
+			Usage:        fantasy.Usage{InputTokens: 10, OutputTokens: 5},
+		}, nil
+	}
+	return &fantasy.Response{
+		Content:      fantasy.ResponseContent{fantasy.TextContent{Text: "done"}},
+		FinishReason: fantasy.FinishReasonStop,
+		Usage:        fantasy.Usage{InputTokens: 20, OutputTokens: 2},
+	}, nil
+}
+
+func (m *mockModel) Stream(ctx context.Context, call fantasy.Call) (fantasy.StreamResponse, error) {
+	return func(yield func(fantasy.StreamPart) bool) {
+		_ = yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeTextStart, ID: "1"}) &&
+			yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeTextDelta, ID: "1", Delta: "do"}) &&
+			yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeTextDelta, ID: "1", Delta: "ne"}) &&
+			yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeTextEnd, ID: "1"}) &&
+			yield(fantasy.StreamPart{Type: fantasy.StreamPartTypeFinish, FinishReason: fantasy.FinishReasonStop, Usage: fantasy.Usage{OutputTokens: 2}})
+	}, nil
+}
+
+func (m *mockModel) GenerateObject(ctx context.Context, call fantasy.ObjectCall) (*fantasy.ObjectResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockModel) StreamObject(ctx context.Context, call fantasy.ObjectCall) (fantasy.ObjectStreamResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockModel) Provider() string { return "mock-provider" }
+func (m *mockModel) Model() string    { return "mock-model" }
+
+type lookupInput struct {
+	Q string `json:"q"`
+}
+
+func lookupTool() fantasy.AgentTool {
+	return fantasy.NewAgentTool("lookup", "Looks things up", func(ctx context.Context, input lookupInput, call fantasy.ToolCall) (fantasy.ToolResponse, error) {
+		return fantasy.NewTextResponse("found " + input.Q), nil
+	})
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+
+	agent := fantasy.NewAgent(Model(&mockModel{}), fantasy.WithTools(Tools(lookupTool())...))
+	ctx := fantasy.WithCallMetadata(fantasy.WithTenantID(t.Context(), "acme"), map[string]string{fantasy.MetadataUserID: "user-1"})
+	ctx, run := Start(ctx, "support", "hello")
+	temperature := 0.5
+	result, err := agent.Generate(ctx, fantasy.AgentCall{Prompt: "hello", Temperature: &temperature})
+	require.NoError(t, err)
+	run.Score(Score{Name: "helpful", Value: 1})
+	trace := run.End(result, err)
+
+	require.Equal(t, run.ID(), trace.ID)
+	require.Equal(t, "support", trace.Name)
+	require.Equal(t, "hello", trace.Input)
+	require.Equal(t, "done", trace.Output)
+	require.Equal(t, "acme", trace.TenantID)
+	require.Equal(t, "user-1", trace.Metadata[fantasy.MetadataUserID])
+	require.Equal(t, int64(30), trace.Usage.InputTokens)
+	require.Equal(t, []Score{{Name: "helpful", Value: 1}}, trace.Scores)
+	require.False(t, trace.End.Before(trace.Start))
+
+	require.Len(t, trace.Spans, 3)
+	first, tool, second := trace.Spans[0], trace.Spans[1], trace.Spans[2]
+
+	require.Equal(t, SpanGeneration, first.Kind)
+	require.Empty(t, first.ParentID)
+	require.Equal(t, "mock-provider/mock-model", first.Name)
+	require.Equal(t, 0.5, first.Parameters["temperature"])
+	require.Equal(t, fantasy.FinishReasonToolCalls, first.FinishReason)
+	require.Len(t, first.Content.ToolCalls(), 1)
+
+	require.Equal(t, SpanTool, tool.Kind)
+	require.Equal(t, first.ID, tool.ParentID)
+	require.Equal(t, "lookup", tool.Name)
+	require.Equal(t, "call-1", tool.ToolCallID)
+	require.Equal(t, `{"q":"fantasy"}`, tool.ToolInput)
+	require.Equal(t, "found fantasy", tool.ToolOutput)
+
+	require.Equal(t, SpanGeneration, second.Kind)
+	require.Empty(t, second.ParentID)
+	require.Equal(t, "done", second.Content.Text())
+	require.Len(t, second.Prompt, 3)
+}
+
+func TestRun_Stream(t *testing.T) {
+	t.Parallel()
+
+	agent := fantasy.NewAgent(Model(&mockModel{}))
+	ctx, run := Start(t.Context(), "support", "hello")
+	result, err := agent.Stream(ctx, fantasy.AgentStreamCall{Prompt: "hello"})
+	require.NoError(t, err)
+	trace := run.End(result, err)
+
+	require.Len(t, trace.Spans, 1)
+	require.Equal(t, "done", trace.Spans[0].Content.Text())
+	require.Equal(t, fantasy.FinishReasonStop, trace.Spans[0].FinishReason)
+	require.Equal(t, int64(2), trace.Spans[0].Usage.OutputTokens)
+	require.False(t, trace.Spans[0].End.IsZero())
+}
+
+func TestOutsideRun(t *testing.T) {
+	t.Parallel()
+
+	agent := fantasy.NewAgent(Model(&mockModel{}), fantasy.WithTools(Tools(lookupTool())...))
+	result, err := agent.Generate(t.Context(), fantasy.AgentCall{Prompt: "hello"})
+	require.NoError(t, err)
+	require.Equal(t, "done", result.Response.Content.Text())
+	require.Nil(t, FromContext(t.Context()))
+}
+
+type pdfMockModel struct {
+	mockModel
+}
+
+func (*pdfMockModel) SupportsPDF() bool { return true }
+
+func (*pdfMockModel) CountTokens(context.Context, fantasy.Call) (int64, error) { return 42, nil }
+
+func TestModel_Capabilities(t *testing.T) {
+	t.Parallel()
+
+	model := Model(&pdfMockModel{})
+	pdf, ok := model.(fantasy.PDFModel)
+	require.True(t, ok)
+	require.True(t, pdf.SupportsPDF())
+	counter, ok := model.(fantasy.TokenCounter)
+	require.True(t, ok)
+	count, err := counter.CountTokens(t.Context(), fantasy.Call{})
+	require.NoError(t, err)
+	require.Equal(t, int64(42), count)
+
+	_, ok = Model(&mockModel{}).(fantasy.TokenCounter)
+	require.False(t, ok)
+}
@@ -0,0 +1,181 @@
+package transcript
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"charm.land/fantasy"
+)
+
+// anthropicProvider is the provider options key used by the Anthropic
+// provider for reasoning signatures.
+const anthropicProvider = "anthropic"
+
+// AnthropicTranscript is a conversation in the Anthropic messages format,
+// where system prompts are separate from the messages.
+type AnthropicTranscript struct {
+	System   string             `json:"system,omitempty"`
+	Messages []AnthropicMessage `json:"messages"`
+}
+
+// AnthropicMessage is a user or assistant message.
+type AnthropicMessage struct {
+	Role    string           `json:"role"`
+	Content []AnthropicBlock `json:"content"`
+}
+
+// AnthropicBlock is a content block. Only the fields of its type are set.
+type AnthropicBlock struct {
+	Type string `json:"type"`
+
+	// text
+	Text string `json:"text,omitempty"`
+
+	// thinking and redacted_thinking
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Data      string `json:"data,omitempty"`
+
+	// image and document
+	Source *AnthropicSource `json:"source,omitempty"`
+
+	// tool_use
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// tool_result
+	ToolUseID string `json:"tool_use_id,omitempty"`
+	Content   string `json:"content,omitempty"`
+	IsError   bool   `json:"is_error,omitempty"`
+}
+
+// AnthropicSource is base64 encoded image or document data.
+type AnthropicSource struct {
+	Type      string `json:"type"`
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+// ToAnthropic converts the conversation to the Anthropic messages format.
+// System messages are joined into the system prompt, tool results are sent
+// as user messages and consecutive messages with the same role are merged,
+// as the API requires. Reasoning keeps the signature the Anthropic provider
+// stored in its provider options; provider-executed tools are dropped.
+func ToAnthropic(conversation fantasy.Prompt, opts ...Option) AnthropicTranscript {
+	var transcript AnthropicTranscript
+	var system []string
+	for _, msg := range newOptions(opts).redactConversation(conversation) {
+		var role string
+		var blocks []AnthropicBlock
+		switch msg.Role {
+		case fantasy.MessageRoleSystem:
+			if text := messageText(msg); text != "" {
+				system = append(system, text)
+			}
+			continue
+		case fantasy.MessageRoleUser, fantasy.MessageRoleTool:
+			role = string(fantasy.MessageRoleUser)
+		case fantasy.MessageRoleAssistant:
+			role = string(fantasy.MessageRoleAssistant)
+		default:
+			continue
+		}
+		for _, part := range msg.Content {
+			if block, ok := anthropicBlock(part); ok {
+				blocks = append(blocks, block)
+			}
+		}
+		if len(blocks) == 0 {
+			continue
+		}
+		if n := len(transcript.Messages); n > 0 && transcript.Messages[n-1].Role == role {
+			transcript.Messages[n-1].Content = append(transcript.Messages[n-1].Content, blocks...)
+			continue
+		}
+		transcript.Messages = append(transcript.Messages, AnthropicMessage{Role: role, Content: blocks})
+	}
+	transcript.System = strings.Join(system, "\n\n")
+	return transcript
+}
+
+func anthropicBlock(part fantasy.MessagePart) (AnthropicBlock, bool) {
+	switch part.GetType() {
+	case fantasy.ContentTypeText:
+		text, _ := fantasy.AsMessagePart[fantasy.TextPart](part)
+		if text.Text == "" {
+			return AnthropicBlock{}, false
+		}
+		return AnthropicBlock{Type: "text", Text: text.Text}, true
+	case fantasy.ContentTypeReasoning:
+		reasoning, _ := fantasy.AsMessagePart[fantasy.ReasoningPart](part)
+		signature, redacted := reasoningSignature(reasoning.ProviderOptions)
+		if redacted != "" {
+			return AnthropicBlock{Type: "redacted_thinking", Data: redacted}, true
+		}
+		return AnthropicBlock{Type: "thinking", Thinking: reasoning.Text, Signature: signature}, true
+	case fantasy.ContentTypeFile:
+		file, _ := fantasy.AsMessagePart[fantasy.FilePart](part)
+		blockType := "document"
+		if strings.HasPrefix(file.MediaType, "image/") {
+			blockType = "image"
+		}
+		return AnthropicBlock{
+			Type: blockType,
+			Source: &AnthropicSource{
+				Type:      "base64",
+				MediaType: file.MediaType,
+				Data:      base64.StdEncoding.EncodeToString(file.Data),
+			},
+		}, true
+	case fantasy.ContentTypeToolCall:
+		call, _ := fantasy.AsMessagePart[fantasy.ToolCallPart](part)
+		if call.ProviderExecuted {
+			return AnthropicBlock{}, false
+		}
+		return AnthropicBlock{
+			Type:  "tool_use",
+			ID:    call.ToolCallID,
+			Name:  call.ToolName,
+			Input: toolInput(call.Input),
+		}, true
+	case fantasy.ContentTypeToolResult:
+		result, _ := fantasy.AsMessagePart[fantasy.ToolResultPart](part)
+		if result.ProviderExecuted {
+			return AnthropicBlock{}, false
+		}
+		text, isError := toolResultText(result.Output)
+		return AnthropicBlock{
+			Type:      "tool_result",
+			ToolUseID: result.ToolCallID,
+			Content:   text,
+			IsError:   isError,
+		}, true
+	}
+	return AnthropicBlock{}, false
+}
+
+// reasoningSignature reads the reasoning signature and redacted data from the
+// serialized Anthropic provider options, so this package doesn't depend on
+// the provider.
+func reasoningSignature(options fantasy.ProviderOptions) (signature, redacted string) {
+	data, ok := options[anthropicProvider]
+	if !ok || data == nil {
+		return "", ""
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return "", ""
+	}
+	var typed struct {
+		Data struct {
+			Signature    string `json:"signature"`
+			RedactedData string `json:"redacted_data"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &typed); err != nil {
+		return "", ""
+	}
+	return typed.Data.Signature, typed.Data.RedactedData
+}
@@ -0,0 +1,158 @@
+package transcript
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"charm.land/fantasy"
+)
+
+// OpenAIMessage is a message in the OpenAI chat completions format.
+type OpenAIMessage struct {
+	Role string `json:"role"`
+	// Content is a string, a list of OpenAIContentPart or nil for assistant
+	// messages that only call tools.
+	Content    any              `json:"content"`
+	ToolCalls  []OpenAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+// OpenAIContentPart is a part of a multimodal user message.
+type OpenAIContentPart struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	ImageURL *OpenAIImageURL `json:"image_url,omitempty"`
+	File     *OpenAIFile     `json:"file,omitempty"`
+}
+
+// OpenAIImageURL is an image given as a URL or a data URL.
+type OpenAIImageURL struct {
+	URL string `json:"url"`
+}
+
+// OpenAIFile is a file given as a data URL.
+type OpenAIFile struct {
+	Filename string `json:"filename,omitempty"`
+	FileData string `json:"file_data"`
+}
+
+// OpenAIToolCall is a function call made by the assistant.
+type OpenAIToolCall struct {
+	ID       string             `json:"id"`
+	Type     string             `json:"type"`
+	Function OpenAIFunctionCall `json:"function"`
+}
+
+// OpenAIFunctionCall is the name and JSON arguments of a function call.
+type OpenAIFunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// ToOpenAI converts the conversation to OpenAI chat completions messages.
+// Reasoning and provider-executed tool calls have no equivalent and are
+// dropped; each tool result becomes its own tool message.
+func ToOpenAI(conversation fantasy.Prompt, opts ...Option) []OpenAIMessage {
+	var messages []OpenAIMessage
+	for _, msg := range newOptions(opts).redactConversation(conversation) {
+		switch msg.Role {
+		case fantasy.MessageRoleSystem:
+			messages = append(messages, OpenAIMessage{
+				Role:    string(msg.Role),
+				Content: messageText(msg),
+			})
+		case fantasy.MessageRoleUser:
+			messages = append(messages, openAIUserMessage(msg))
+		case fantasy.MessageRoleAssistant:
+			if m, ok := openAIAssistantMessage(msg); ok {
+				messages = append(messages, m)
+			}
+		case fantasy.MessageRoleTool:
+			for _, part := range msg.Content {
+				result, ok := fantasy.AsMessagePart[fantasy.ToolResultPart](part)
+				if !ok || result.ProviderExecuted {
+					continue
+				}
+				text, _ := toolResultText(result.Output)
+				messages = append(messages, OpenAIMessage{
+					Role:       string(fantasy.MessageRoleTool),
+					Content:    text,
+					ToolCallID: result.ToolCallID,
+				})
+			}
+		}
+	}
+	return messages
+}
+
+// WriteOpenAIJSONL writes the conversation as a single JSON Lines record,
+// {"messages": [...]}, the format used for OpenAI fine-tuning datasets.
+// Call it once per conversation to build a dataset.
+func WriteOpenAIJSONL(w io.Writer, conversation fantasy.Prompt, opts ...Option) error {
+	return json.NewEncoder(w).Encode(struct {
+		Messages []OpenAIMessage `json:"messages"`
+	}{
+		Messages: ToOpenAI(conversation, opts...),
+	})
+}
+
+func openAIUserMessage(msg fantasy.Message) OpenAIMessage {
+	var parts []OpenAIContentPart
+	hasFiles := false
+	for _, part := range msg.Content {
+		switch part.GetType() {
+		case fantasy.ContentTypeText:
+			text, _ := fantasy.AsMessagePart[fantasy.TextPart](part)
+			parts = append(parts, OpenAIContentPart{Type: "text", Text: text.Text})
+		case fantasy.ContentTypeFile:
+			file, _ := fantasy.AsMessagePart[fantasy.FilePart](part)
+			hasFiles = true
+			dataURL := "data:" + file.MediaType + ";base64," + base64.StdEncoding.EncodeToString(file.Data)
+			if strings.HasPrefix(file.MediaType, "image/") {
+				parts = append(parts, OpenAIContentPart{Type: "image_url", ImageURL: &OpenAIImageURL{URL: dataURL}})
+			} else {
+				parts = append(parts, OpenAIContentPart{Type: "file", File: &OpenAIFile{Filename: file.Filename, FileData: dataURL}})
+			}
+		}
+	}
+	if !hasFiles {
+		return OpenAIMessage{Role: string(msg.Role), Content: messageText(msg)}
+	}
+	return OpenAIMessage{Role: string(msg.Role), Content: parts}
+}
+
+func openAIAssistantMessage(msg fantasy.Message) (OpenAIMessage, bool) {
+	m := OpenAIMessage{Role: string(msg.Role)}
+	text := messageText(msg)
+	for _, part := range msg.Content {
+		call, ok := fantasy.AsMessagePart[fantasy.ToolCallPart](part)
+		if !ok || call.ProviderExecuted {
+			continue
+		}
+		m.ToolCalls = append(m.ToolCalls, OpenAIToolCall{
+			ID:   call.ToolCallID,
+			Type: "function",
+			Function: OpenAIFunctionCall{
+				Name:      call.ToolName,
+				Arguments: string(toolInput(call.Input)),
+			},
+		})
+	}
+	if text != "" {
+		m.Content = text
+	}
+	return m, text != "" || len(m.ToolCalls) > 0
+}
+
+// messageText joins the text parts of a message.
+func messageText(msg fantasy.Message) string {
+	var texts []string
+	for _, part := range msg.Content {
+		if text, ok := fantasy.AsMessagePart[fantasy.TextPart](part); ok {
+			texts = append(texts, text.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
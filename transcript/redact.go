@@ -0,0 +1,117 @@
+package transcript
+
+import (
+	"errors"
+	"slices"
+
+	"charm.land/fantasy"
+)
+
+// DefaultRedactionPlaceholder replaces sensitive tool results when
+// WithRedactedToolResults is given no placeholder.
+const DefaultRedactionPlaceholder = "[redacted]"
+
+// Option configures how a transcript is exported.
+type Option func(*options)
+
+type options struct {
+	redact      bool
+	placeholder string
+}
+
+// WithRedactedToolResults replaces the output of the tool results marked
+// with fantasy.SensitiveToolResult with placeholder, or
+// DefaultRedactionPlaceholder when it's empty. Failed results stay errors.
+func WithRedactedToolResults(placeholder string) Option {
+	return func(o *options) {
+		o.redact = true
+		o.placeholder = placeholder
+	}
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// Redact returns the conversation with the output of its sensitive tool
+// results replaced with placeholder, or DefaultRedactionPlaceholder when it's
+// empty, for persisting it in the fantasy JSON format. The conversation is
+// left untouched, so it can still be sent to the model.
+func Redact(conversation fantasy.Prompt, placeholder string) fantasy.Prompt {
+	if placeholder == "" {
+		placeholder = DefaultRedactionPlaceholder
+	}
+	var redacted fantasy.Prompt
+	for i, msg := range conversation {
+		var content []fantasy.MessagePart
+		for j, part := range msg.Content {
+			result, ok := fantasy.AsMessagePart[fantasy.ToolResultPart](part)
+			if !ok || !result.Sensitive {
+				continue
+			}
+			if content == nil {
+				content = slices.Clone(msg.Content)
+			}
+			result.Output = redactedOutput(result.Output, placeholder)
+			content[j] = result
+		}
+		if content == nil {
+			continue
+		}
+		if redacted == nil {
+			redacted = slices.Clone(conversation)
+		}
+		redacted[i].Content = content
+	}
+	if redacted == nil {
+		return conversation
+	}
+	return redacted
+}
+
+// redactConversation applies the redaction options to the conversation.
+func (o options) redactConversation(conversation fantasy.Prompt) fantasy.Prompt {
+	if !o.redact {
+		return conversation
+	}
+	return Redact(conversation, o.placeholder)
+}
+
+// redactContent applies the redaction options to the content of a step.
+func (o options) redactContent(content fantasy.ResponseContent) fantasy.ResponseContent {
+	if !o.redact {
+		return content
+	}
+	placeholder := o.placeholder
+	if placeholder == "" {
+		placeholder = DefaultRedactionPlaceholder
+	}
+	var redacted fantasy.ResponseContent
+	for i, c := range content {
+		result, ok := fantasy.AsContentType[fantasy.ToolResultContent](c)
+		if !ok || !result.Sensitive {
+			continue
+		}
+		if redacted == nil {
+			redacted = slices.Clone(content)
+		}
+		result.Result = redactedOutput(result.Result, placeholder)
+		result.ClientMetadata = ""
+		redacted[i] = result
+	}
+	if redacted == nil {
+		return content
+	}
+	return redacted
+}
+
+func redactedOutput(output fantasy.ToolResultOutputContent, placeholder string) fantasy.ToolResultOutputContent {
+	if output != nil && output.GetType() == fantasy.ToolResultContentTypeError {
+		return fantasy.ToolResultOutputContentError{Error: errors.New(placeholder)}
+	}
+	return fantasy.ToolResultOutputContentText{Text: placeholder}
+}
@@ -0,0 +1,131 @@
+// Package transcript exports agent runs to formats other tooling understands:
+// the OpenAI chat completions message format, the Anthropic messages format
+// and a generic JSON Lines trace. They are useful for building fine-tuning
+// datasets and for sharing transcripts.
+//
+// Example:
+//
+//	result, err := agent.Generate(ctx, fantasy.AgentCall{Prompt: prompt})
+//	...
+//	conversation := transcript.Conversation(
+//	    fantasy.Prompt{fantasy.NewSystemMessage(system), fantasy.NewUserMessage(prompt)},
+//	    result,
+//	)
+//	err = transcript.WriteOpenAIJSONL(w, conversation)
+//
+// Tool results marked with fantasy.SensitiveToolResult are exported as is
+// unless WithRedactedToolResults is given; Redact does the same for
+// conversations persisted in the fantasy JSON format.
+package transcript
+
+import (
+	"encoding/json"
+	"io"
+
+	"charm.land/fantasy"
+)
+
+// Conversation returns the input messages followed by the messages produced
+// by each step of the result.
+func Conversation(input fantasy.Prompt, result *fantasy.AgentResult) fantasy.Prompt {
+	conversation := append(fantasy.Prompt{}, input...)
+	if result == nil {
+		return conversation
+	}
+	for _, step := range result.Steps {
+		conversation = append(conversation, step.Messages...)
+	}
+	return conversation
+}
+
+// TraceRecordType identifies the kind of a trace record.
+type TraceRecordType string
+
+const (
+	// TraceRecordMessage is an input message.
+	TraceRecordMessage TraceRecordType = "message"
+	// TraceRecordStep is a step of the agent run.
+	TraceRecordStep TraceRecordType = "step"
+	// TraceRecordResult closes the trace with the final text and total usage.
+	TraceRecordResult TraceRecordType = "result"
+)
+
+// TraceRecord is a line of the JSON Lines trace written by WriteTrace.
+type TraceRecord struct {
+	Type         TraceRecordType         `json:"type"`
+	Step         int                     `json:"step,omitempty"`
+	Message      *fantasy.Message        `json:"message,omitempty"`
+	Content      fantasy.ResponseContent `json:"content,omitempty"`
+	FinishReason fantasy.FinishReason    `json:"finish_reason,omitempty"`
+	Warnings     []fantasy.CallWarning   `json:"warnings,omitempty"`
+	Text         string                  `json:"text,omitempty"`
+	Usage        *fantasy.Usage          `json:"usage,omitempty"`
+}
+
+// WriteTrace writes the run as JSON Lines: a record per input message, a
+// record per step, numbered from 1, and a final result record. Content keeps
+// the fantasy JSON format, so nothing the provider returned is lost.
+func WriteTrace(w io.Writer, input fantasy.Prompt, result *fantasy.AgentResult, opts ...Option) error {
+	o := newOptions(opts)
+	input = o.redactConversation(input)
+	enc := json.NewEncoder(w)
+	for i := range input {
+		if err := enc.Encode(TraceRecord{Type: TraceRecordMessage, Message: &input[i]}); err != nil {
+			return err
+		}
+	}
+	if result == nil {
+		return nil
+	}
+	for i, step := range result.Steps {
+		usage := step.Usage
+		record := TraceRecord{
+			Type:         TraceRecordStep,
+			Step:         i + 1,
+			Content:      o.redactContent(step.Content),
+			FinishReason: step.FinishReason,
+			Warnings:     step.Warnings,
+			Usage:        &usage,
+		}
+		if err := enc.Encode(record); err != nil {
+			return err
+		}
+	}
+	return enc.Encode(TraceRecord{
+		Type:  TraceRecordResult,
+		Text:  result.Response.Content.Text(),
+		Usage: &result.TotalUsage,
+	})
+}
+
+// toolResultText returns the text of a tool result output and whether it is
+// an error.
+func toolResultText(output fantasy.ToolResultOutputContent) (string, bool) {
+	if output == nil {
+		return "", false
+	}
+	switch output.GetType() {
+	case fantasy.ToolResultContentTypeText:
+		text, _ := fantasy.AsToolResultOutputType[fantasy.ToolResultOutputContentText](output)
+		return text.Text, false
+	case fantasy.ToolResultContentTypeError:
+		e, _ := fantasy.AsToolResultOutputType[fantasy.ToolResultOutputContentError](output)
+		if e.Error == nil {
+			return "", true
+		}
+		return e.Error.Error(), true
+	case fantasy.ToolResultContentTypeMedia:
+		media, _ := fantasy.AsToolResultOutputType[fantasy.ToolResultOutputContentMedia](output)
+		return media.Text, false
+	}
+	return "", false
+}
+
+// toolInput returns the tool call input as JSON, falling back to an empty
+// object when the model produced invalid JSON.
+func toolInput(input string) json.RawMessage {
+	if !json.Valid([]byte(input)) {
+		return json.RawMessage("{}")
+	}
+	return json.RawMessage(input)
+}
@@ -0,0 +1,271 @@
+package transcript
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/anthropic"
+	"github.com/stretchr/testify/require"
+)
+
+func testRun() (fantasy.Prompt, *fantasy.AgentResult) {
+	input := fantasy.Prompt{
+		fantasy.NewSystemMessage("You are helpful."),
+		fantasy.NewUserMessage("What is the weather in Paris?"),
+	}
+	result := &fantasy.AgentResult{
+		Steps: []fantasy.StepResult{
+			{
+				Response: fantasy.Response{
+					Content: fantasy.ResponseContent{
+						fantasy.ToolCallContent{ToolCallID: "call-1", ToolName: "weather", Input: `{"city":"Paris"}`},
+					},
+					FinishReason: fantasy.FinishReasonToolCalls,
+					Usage:        fantasy.Usage{InputTokens: 10, OutputTokens: 5},
+				},
+				Messages: []fantasy.Message{
+					{
+						Role: fantasy.MessageRoleAssistant,
+						Content: []fantasy.MessagePart{
+							fantasy.ReasoningPart{
+								Text: "I should check the weather.",
+								ProviderOptions: fantasy.ProviderOptions{
+									anthropic.Name: &anthropic.ReasoningOptionMetadata{Signature: "sig-1"},
+								},
+							},
+							fantasy.ToolCallPart{ToolCallID: "call-1", ToolName: "weather", Input: `{"city":"Paris"}`},
+						},
+					},
+					{
+						Role: fantasy.MessageRoleTool,
+						Content: []fantasy.MessagePart{
+							fantasy.ToolResultPart{ToolCallID: "call-1", Output: fantasy.ToolResultOutputContentText{Text: "Sunny"}},
+						},
+					},
+				},
+			},
+			{
+				Response: fantasy.Response{
+					Content:      fantasy.ResponseContent{fantasy.TextContent{Text: "It is sunny."}},
+					FinishReason: fantasy.FinishReasonStop,
+					Usage:        fantasy.Usage{InputTokens: 20, OutputTokens: 4},
+				},
+				Messages: []fantasy.Message{
+					{
+						Role:    fantasy.MessageRoleAssistant,
+						Content: []fantasy.MessagePart{fantasy.TextPart{Text: "It is sunny."}},
+					},
+				},
+			},
+		},
+		Response:   fantasy.Response{Content: fantasy.ResponseContent{fantasy.TextContent{Text: "It is sunny."}}},
+		TotalUsage: fantasy.Usage{InputTokens: 30, OutputTokens: 9},
+	}
+	return input, result
+}
+
+func TestConversation(t *testing.T) {
+	t.Parallel()
+
+	input, result := testRun()
+	conversation := Conversation(input, result)
+	require.Len(t, conversation, 5)
+	require.Equal(t, fantasy.MessageRoleTool, conversation[3].Role)
+	require.Len(t, input, 2, "input must not be modified")
+}
+
+func TestToOpenAI(t *testing.T) {
+	t.Parallel()
+
+	input, result := testRun()
+	messages := ToOpenAI(Conversation(input, result))
+	require.Len(t, messages, 5)
+
+	require.Equal(t, OpenAIMessage{Role: "system", Content: "You are helpful."}, messages[0])
+	require.Equal(t, OpenAIMessage{Role: "user", Content: "What is the weather in Paris?"}, messages[1])
+	require.Equal(t, OpenAIMessage{
+		Role: "assistant",
+		ToolCalls: []OpenAIToolCall{{
+			ID:       "call-1",
+			Type:     "function",
+			Function: OpenAIFunctionCall{Name: "weather", Arguments: `{"city":"Paris"}`},
+		}},
+	}, messages[2])
+	require.Equal(t, OpenAIMessage{Role: "tool", Content: "Sunny", ToolCallID: "call-1"}, messages[3])
+	require.Equal(t, OpenAIMessage{Role: "assistant", Content: "It is sunny."}, messages[4])
+
+	t.Run("images", func(t *testing.T) {
+		t.Parallel()
+
+		messages := ToOpenAI(fantasy.Prompt{
+			fantasy.NewUserMessage("Describe", fantasy.FilePart{Data: []byte("png"), MediaType: "image/png"}),
+		})
+		parts, ok := messages[0].Content.([]OpenAIContentPart)
+		require.True(t, ok)
+		require.Len(t, parts, 2)
+		require.Equal(t, "data:image/png;base64,cG5n", parts[1].ImageURL.URL)
+	})
+
+	t.Run("jsonl", func(t *testing.T) {
+		t.Parallel()
+
+		var buf bytes.Buffer
+		require.NoError(t, WriteOpenAIJSONL(&buf, Conversation(input, result)))
+		require.JSONEq(t, `{"messages": [
+			{"role": "system", "content": "You are helpful."},
+			{"role": "user", "content": "What is the weather in Paris?"},
+			{"role": "assistant", "content": null, "tool_calls": [{"id": "call-1", "type": "function", "function": {"name": "weather", "arguments": "{\"city\":\"Paris\"}"}}]},
+			{"role": "tool", "content": "Sunny", "tool_call_id": "call-1"},
+			{"role": "assistant", "content": "It is sunny."}
+		]}`, buf.String())
+	})
+}
+
+func TestToAnthropic(t *testing.T) {
+	t.Parallel()
+
+	input, result := testRun()
+	conversation := append(Conversation(input, result), fantasy.Message{
+		Role: fantasy.MessageRoleTool,
+		Content: []fantasy.MessagePart{
+			fantasy.ToolResultPart{ToolCallID: "call-2", Output: fantasy.ToolResultOutputContentError{Error: errors.New("boom")}},
+		},
+	}, fantasy.NewUserMessage("Thanks"))
+
+	transcript := ToAnthropic(conversation)
+	require.Equal(t, "You are helpful.", transcript.System)
+	require.Len(t, transcript.Messages, 5)
+
+	assistant := transcript.Messages[1]
+	require.Equal(t, "assistant", assistant.Role)
+	require.Equal(t, AnthropicBlock{Type: "thinking", Thinking: "I should check the weather.", Signature: "sig-1"}, assistant.Content[0])
+	require.Equal(t, "tool_use", assistant.Content[1].Type)
+	require.JSONEq(t, `{"city":"Paris"}`, string(assistant.Content[1].Input))
+
+	require.Equal(t, "user", transcript.Messages[2].Role)
+	require.Equal(t, AnthropicBlock{Type: "tool_result", ToolUseID: "call-1", Content: "Sunny"}, transcript.Messages[2].Content[0])
+
+	// The failed tool result and the next user message are merged.
+	last := transcript.Messages[4]
+	require.Equal(t, "user", last.Role)
+	require.Len(t, last.Content, 2)
+	require.True(t, last.Content[0].IsError)
+	require.Equal(t, "Thanks", last.Content[1].Text)
+}
+
+func TestWriteTrace(t *testing.T) {
+	t.Parallel()
+
+	input, result := testRun()
+	var buf bytes.Buffer
+	require.NoError(t, WriteTrace(&buf, input, result))
+
+	var types []TraceRecordType
+	var records []map[string]any
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+		types = append(types, TraceRecordType(record["type"].(string)))
+	}
+	require.Equal(t, []TraceRecordType{
+		TraceRecordMessage, TraceRecordMessage, TraceRecordStep, TraceRecordStep, TraceRecordResult,
+	}, types)
+	require.Equal(t, float64(1), records[2]["step"])
+	require.Equal(t, "tool-calls", records[2]["finish_reason"])
+	require.Equal(t, "It is sunny.", records[4]["text"])
+	require.Equal(t, float64(30), records[4]["usage"].(map[string]any)["input_tokens"])
+
+	var message fantasy.Message
+	raw, err := json.Marshal(records[1]["message"])
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(raw, &message))
+	require.Equal(t, input[1], message)
+}
+
+func TestRedactedToolResults(t *testing.T) {
+	t.Parallel()
+
+	conversation := fantasy.Prompt{
+		fantasy.NewUserMessage("Look up the customer"),
+		{
+			Role: fantasy.MessageRoleAssistant,
+			Content: []fantasy.MessagePart{
+				fantasy.ToolCallPart{ToolCallID: "call-1", ToolName: "customer", Input: `{}`},
+				fantasy.ToolCallPart{ToolCallID: "call-2", ToolName: "orders", Input: `{}`},
+			},
+		},
+		{
+			Role: fantasy.MessageRoleTool,
+			Content: []fantasy.MessagePart{
+				fantasy.ToolResultPart{ToolCallID: "call-1", Output: fantasy.ToolResultOutputContentText{Text: "ssn: 123-45-6789"}, Sensitive: true},
+				fantasy.ToolResultPart{ToolCallID: "call-2", Output: fantasy.ToolResultOutputContentText{Text: "2 orders"}},
+			},
+		},
+	}
+
+	t.Run("exported as is by default", func(t *testing.T) {
+		t.Parallel()
+		messages := ToOpenAI(conversation)
+		require.Equal(t, "ssn: 123-45-6789", messages[2].Content)
+	})
+
+	t.Run("openai", func(t *testing.T) {
+		t.Parallel()
+		messages := ToOpenAI(conversation, WithRedactedToolResults(""))
+		require.Equal(t, DefaultRedactionPlaceholder, messages[2].Content)
+		require.Equal(t, "2 orders", messages[3].Content)
+	})
+
+	t.Run("anthropic", func(t *testing.T) {
+		t.Parallel()
+		transcript := ToAnthropic(conversation, WithRedactedToolResults("<removed>"))
+		require.Equal(t, "<removed>", transcript.Messages[2].Content[0].Content)
+		require.Equal(t, "2 orders", transcript.Messages[2].Content[1].Content)
+	})
+
+	t.Run("fantasy format", func(t *testing.T) {
+		t.Parallel()
+		redacted := Redact(conversation, "")
+		raw, err := json.Marshal(redacted)
+		require.NoError(t, err)
+		require.NotContains(t, string(raw), "123-45-6789")
+
+		var persisted fantasy.Prompt
+		require.NoError(t, json.Unmarshal(raw, &persisted))
+		result, ok := fantasy.AsMessagePart[fantasy.ToolResultPart](persisted[2].Content[0])
+		require.True(t, ok)
+		require.True(t, result.Sensitive)
+
+		// The live conversation is left untouched.
+		live, _ := fantasy.AsMessagePart[fantasy.ToolResultPart](conversation[2].Content[0])
+		require.Equal(t, fantasy.ToolResultOutputContentText{Text: "ssn: 123-45-6789"}, live.Output)
+	})
+
+	t.Run("trace", func(t *testing.T) {
+		t.Parallel()
+		result := &fantasy.AgentResult{
+			Steps: []fantasy.StepResult{{
+				Response: fantasy.Response{
+					Content: fantasy.ResponseContent{
+						fantasy.ToolResultContent{
+							ToolCallID: "call-1",
+							ToolName:   "customer",
+							Result:     fantasy.ToolResultOutputContentError{Error: errors.New("no access to 123-45-6789")},
+							Sensitive:  true,
+						},
+					},
+				},
+			}},
+		}
+		var buf bytes.Buffer
+		require.NoError(t, WriteTrace(&buf, conversation, result, WithRedactedToolResults("")))
+		require.NotContains(t, buf.String(), "123-45-6789")
+		require.Contains(t, buf.String(), DefaultRedactionPlaceholder)
+	})
+}
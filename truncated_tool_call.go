@@ -0,0 +1,65 @@
+package fantasy
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"charm.land/fantasy/jsonrepair"
+)
+
+// TruncatedToolCallMode is what the agent does with a tool call whose JSON
+// input was cut off because the response hit the output token limit.
+type TruncatedToolCallMode int
+
+const (
+	// TruncatedToolCallRepair closes the cut off JSON with jsonrepair and
+	// executes the call if the result is valid for the tool. Otherwise the
+	// call is rejected as with TruncatedToolCallReemit. This is the default.
+	TruncatedToolCallRepair TruncatedToolCallMode = iota
+	// TruncatedToolCallReemit rejects the call with an error result asking
+	// the model to make it again with a shorter input.
+	TruncatedToolCallReemit
+)
+
+// WithTruncatedToolCalls sets how the agent recovers tool calls cut off by
+// the output token limit. Either way the agent goes on to the next step, so
+// the model sees the result of the call.
+func WithTruncatedToolCalls(mode TruncatedToolCallMode) AgentOption {
+	return func(s *agentSettings) {
+		s.truncatedToolCalls = mode
+	}
+}
+
+// TruncatedToolCallError is the validation error of a tool call whose
+// input was cut off by the output token limit. It's sent to the model as
+// the call's result.
+type TruncatedToolCallError struct {
+	ToolName string
+}
+
+func (e *TruncatedToolCallError) Error() string {
+	return fmt.Sprintf("the input of the %s tool call was cut off by the output token limit; call the tool again with a shorter input, splitting the work across several calls if needed", e.ToolName)
+}
+
+// isTruncatedToolCall reports whether a tool call in a response that ended
+// with finishReason was cut off in its input.
+func isTruncatedToolCall(toolCall ToolCallContent, finishReason FinishReason) bool {
+	return finishReason == FinishReasonLength && !toolCall.ProviderExecuted && !json.Valid([]byte(toolCall.Input))
+}
+
+// recoverTruncatedToolCall repairs a truncated tool call or marks it
+// invalid, depending on the agent's TruncatedToolCallMode.
+func (a *agent) recoverTruncatedToolCall(toolCall ToolCallContent, availableTools []AgentTool, execProviderTools []ExecutableProviderTool) ToolCallContent {
+	if a.settings.truncatedToolCalls == TruncatedToolCallRepair {
+		if repaired, err := jsonrepair.RepairJSON(toolCall.Input); err == nil {
+			repairedCall := toolCall
+			repairedCall.Input = repaired
+			if a.validateToolCall(repairedCall, availableTools, execProviderTools) == nil {
+				return repairedCall
+			}
+		}
+	}
+	toolCall.Invalid = true
+	toolCall.ValidationError = &TruncatedToolCallError{ToolName: toolCall.ToolName}
+	return toolCall
+}
@@ -0,0 +1,123 @@
+package fantasy
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncatedToolCalls(t *testing.T) {
+	t.Parallel()
+
+	truncatedResponse := &Response{
+		Content: ResponseContent{ToolCallContent{
+			ToolCallID: "call-1",
+			ToolName:   "write_file",
+			Input:      `{"path":"a.txt","content":"hel`,
+		}},
+		FinishReason: FinishReasonLength,
+	}
+	newTool := func(required ...string) (*mockTool, *atomic.Pointer[string]) {
+		var input atomic.Pointer[string]
+		return &mockTool{
+			name:     "write_file",
+			required: required,
+			executeFunc: func(ctx context.Context, call ToolCall) (ToolResponse, error) {
+				input.Store(&call.Input)
+				return NewTextResponse("ok"), nil
+			},
+		}, &input
+	}
+	newModel := func(first *Response) (*mockLanguageModel, *[]Call) {
+		var calls []Call
+		return &mockLanguageModel{
+			generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+				calls = append(calls, call)
+				if len(calls) == 1 {
+					return first, nil
+				}
+				return &Response{Content: ResponseContent{TextContent{Text: "done"}}, FinishReason: FinishReasonStop}, nil
+			},
+		}, &calls
+	}
+
+	t.Run("repairs and executes", func(t *testing.T) {
+		t.Parallel()
+		tool, input := newTool("path", "content")
+		model, calls := newModel(truncatedResponse)
+		result, err := NewAgent(model, WithTools(tool)).Generate(t.Context(), AgentCall{Prompt: "write"})
+		require.NoError(t, err)
+		require.JSONEq(t, `{"path":"a.txt","content":"hel"}`, *input.Load())
+		require.Len(t, *calls, 2)
+		require.Len(t, result.Steps, 2)
+	})
+
+	t.Run("asks to re-emit", func(t *testing.T) {
+		t.Parallel()
+		tool, input := newTool()
+		model, calls := newModel(truncatedResponse)
+		result, err := NewAgent(model, WithTools(tool), WithTruncatedToolCalls(TruncatedToolCallReemit)).Generate(t.Context(), AgentCall{Prompt: "write"})
+		require.NoError(t, err)
+		require.Nil(t, input.Load())
+		require.Len(t, *calls, 2)
+
+		toolResults := result.Steps[0].Content.ToolResults()
+		require.Len(t, toolResults, 1)
+		errResult, ok := toolResults[0].Result.(ToolResultOutputContentError)
+		require.True(t, ok)
+		var truncatedErr *TruncatedToolCallError
+		require.ErrorAs(t, errResult.Error, &truncatedErr)
+		require.Equal(t, "write_file", truncatedErr.ToolName)
+	})
+
+	t.Run("re-emits when the repaired input is invalid", func(t *testing.T) {
+		t.Parallel()
+		tool, input := newTool("path", "content")
+		model, _ := newModel(&Response{
+			Content: ResponseContent{ToolCallContent{
+				ToolCallID: "call-1",
+				ToolName:   "write_file",
+				Input:      `{"path":"a.t`,
+			}},
+			FinishReason: FinishReasonLength,
+		})
+		result, err := NewAgent(model, WithTools(tool)).Generate(t.Context(), AgentCall{Prompt: "write"})
+		require.NoError(t, err)
+		require.Nil(t, input.Load())
+		toolCalls := result.Steps[0].Content.ToolCalls()
+		require.True(t, toolCalls[0].Invalid)
+		require.IsType(t, &TruncatedToolCallError{}, toolCalls[0].ValidationError)
+	})
+
+	t.Run("stream cut off before the tool call", func(t *testing.T) {
+		t.Parallel()
+		tool, input := newTool("path", "content")
+		var calls atomic.Int32
+		model := &mockLanguageModel{
+			streamFunc: func(ctx context.Context, call Call) (StreamResponse, error) {
+				if calls.Add(1) == 1 {
+					return partsStream(
+						StreamPart{Type: StreamPartTypeToolInputStart, ID: "call-1", ToolCallName: "write_file"},
+						StreamPart{Type: StreamPartTypeToolInputDelta, ID: "call-1", Delta: `{"path":"a.txt","content":"hel`},
+						StreamPart{Type: StreamPartTypeFinish, FinishReason: FinishReasonLength},
+					), nil
+				}
+				return partsStream(StreamPart{Type: StreamPartTypeFinish, FinishReason: FinishReasonStop}), nil
+			},
+		}
+		var onToolCall []ToolCallContent
+		_, err := NewAgent(model, WithTools(tool)).Stream(t.Context(), AgentStreamCall{
+			Prompt: "write",
+			OnToolCall: func(toolCall ToolCallContent) error {
+				onToolCall = append(onToolCall, toolCall)
+				return nil
+			},
+		})
+		require.NoError(t, err)
+		require.JSONEq(t, `{"path":"a.txt","content":"hel"}`, *input.Load())
+		require.Len(t, onToolCall, 1)
+		require.Equal(t, int32(2), calls.Load())
+	})
+}
@@ -0,0 +1,146 @@
+package fantasy
+
+import (
+	"encoding/json"
+	"time"
+	"unicode/utf8"
+)
+
+// TokenBreakdown estimates how the input tokens of a call split between the
+// parts of the prompt, to find out what fills the context window.
+//
+// Text is estimated at about four characters per token. Other holds the
+// input tokens reported by the provider that the estimates don't account
+// for, such as images, files and message formatting.
+type TokenBreakdown struct {
+	// System is the system prompt.
+	System int64 `json:"system"`
+	// User is the text of user messages.
+	User int64 `json:"user"`
+	// Assistant is the text and reasoning of previous assistant messages.
+	Assistant int64 `json:"assistant"`
+	// ToolCalls is the input of previous tool calls.
+	ToolCalls int64 `json:"tool_calls"`
+	// ToolResults is the output of previous tool calls, by tool name.
+	ToolResults map[string]int64 `json:"tool_results,omitempty"`
+	// ToolDefinitions is the names, descriptions and schemas of the tools.
+	ToolDefinitions int64 `json:"tool_definitions"`
+	// Other is the rest of the reported input tokens.
+	Other int64 `json:"other"`
+}
+
+// ToolResultsTotal returns the tokens of all tool results.
+func (b TokenBreakdown) ToolResultsTotal() int64 {
+	var total int64
+	for _, tokens := range b.ToolResults {
+		total += tokens
+	}
+	return total
+}
+
+// Total returns the sum of all the parts.
+func (b TokenBreakdown) Total() int64 {
+	return b.System + b.User + b.Assistant + b.ToolCalls + b.ToolResultsTotal() + b.ToolDefinitions + b.Other
+}
+
+// EstimateInputBreakdown estimates how the input tokens of a call with the
+// prompt and tools split between their parts. inputTokens is the count the
+// provider reported, or zero if unknown.
+func EstimateInputBreakdown(prompt Prompt, tools []Tool, inputTokens int64) TokenBreakdown {
+	return CountInputBreakdown(prompt, tools, inputTokens, nil)
+}
+
+// CountInputBreakdown is like EstimateInputBreakdown but counts the tokens
+// of each part with tokenizer. A nil tokenizer estimates them.
+func CountInputBreakdown(prompt Prompt, tools []Tool, inputTokens int64, tokenizer Tokenizer) TokenBreakdown {
+	count := func(text string) int64 {
+		return countTokens(tokenizer, text)
+	}
+	var b TokenBreakdown
+	toolNames := map[string]string{}
+	for _, msg := range prompt {
+		for _, part := range msg.Content {
+			switch part.GetType() {
+			case ContentTypeText:
+				text, _ := AsMessagePart[TextPart](part)
+				switch msg.Role {
+				case MessageRoleSystem:
+					b.System += count(text.Text)
+				case MessageRoleAssistant:
+					b.Assistant += count(text.Text)
+				default:
+					b.User += count(text.Text)
+				}
+			case ContentTypeReasoning:
+				reasoning, _ := AsMessagePart[ReasoningPart](part)
+				b.Assistant += count(reasoning.Text)
+			case ContentTypeToolCall:
+				call, _ := AsMessagePart[ToolCallPart](part)
+				toolNames[call.ToolCallID] = call.ToolName
+				b.ToolCalls += count(call.ToolName) + count(call.Input)
+			case ContentTypeToolResult:
+				result, _ := AsMessagePart[ToolResultPart](part)
+				if b.ToolResults == nil {
+					b.ToolResults = map[string]int64{}
+				}
+				b.ToolResults[toolNames[result.ToolCallID]] += count(toolResultOutputText(result.Output))
+			}
+		}
+	}
+	for _, tool := range tools {
+		if data, err := json.Marshal(tool); err == nil {
+			b.ToolDefinitions += count(string(data))
+		}
+	}
+	b.Other = max(inputTokens-b.Total(), 0)
+	return b
+}
+
+// ToolDurations returns the total time spent running each tool, by name,
+// across all steps.
+func (r *AgentResult) ToolDurations() map[string]time.Duration {
+	durations := map[string]time.Duration{}
+	for _, step := range r.Steps {
+		for _, result := range step.Content.ToolResults() {
+			if !result.ProviderExecuted {
+				durations[result.ToolName] += result.Duration
+			}
+		}
+	}
+	return durations
+}
+
+// EstimateTokens estimates the tokens of a text at about four characters per
+// token, for when a TokenCounter is unavailable or too slow.
+func EstimateTokens(text string) int64 {
+	return int64(utf8.RuneCountInString(text)+3) / 4
+}
+
+// countTokens counts the tokens of text with tokenizer, or estimates them
+// when it's nil.
+func countTokens(tokenizer Tokenizer, text string) int64 {
+	if tokenizer == nil {
+		return EstimateTokens(text)
+	}
+	return int64(tokenizer.Count(text))
+}
+
+func toolResultOutputText(output ToolResultOutputContent) string {
+	if output == nil {
+		return ""
+	}
+	switch output.GetType() {
+	case ToolResultContentTypeText:
+		text, _ := AsToolResultOutputType[ToolResultOutputContentText](output)
+		return text.Text
+	case ToolResultContentTypeError:
+		e, _ := AsToolResultOutputType[ToolResultOutputContentError](output)
+		if e.Error != nil {
+			return e.Error.Error()
+		}
+	case ToolResultContentTypeMedia:
+		media, _ := AsToolResultOutputType[ToolResultOutputContentMedia](output)
+		return media.Text
+	}
+	return ""
+}
@@ -0,0 +1,92 @@
+package fantasy
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateInputBreakdown(t *testing.T) {
+	t.Parallel()
+
+	prompt := Prompt{
+		NewSystemMessage(strings.Repeat("s", 40)),
+		NewUserMessage(strings.Repeat("u", 20)),
+		{
+			Role: MessageRoleAssistant,
+			Content: []MessagePart{
+				TextPart{Text: strings.Repeat("a", 8)},
+				ToolCallPart{ToolCallID: "1", ToolName: "grep", Input: `{"q":"x"}`},
+				ToolCallPart{ToolCallID: "2", ToolName: "read", Input: `{}`},
+			},
+		},
+		{
+			Role: MessageRoleTool,
+			Content: []MessagePart{
+				ToolResultPart{ToolCallID: "1", Output: ToolResultOutputContentText{Text: strings.Repeat("g", 400)}},
+				ToolResultPart{ToolCallID: "2", Output: ToolResultOutputContentError{Error: errors.New("missing")}},
+			},
+		},
+	}
+
+	b := EstimateInputBreakdown(prompt, nil, 500)
+	require.Equal(t, int64(10), b.System)
+	require.Equal(t, int64(5), b.User)
+	require.Equal(t, int64(2), b.Assistant)
+	require.Equal(t, int64(100), b.ToolResults["grep"])
+	require.Equal(t, int64(2), b.ToolResults["read"])
+	require.Equal(t, int64(500), b.Total())
+
+	b = EstimateInputBreakdown(prompt, nil, 0)
+	require.Zero(t, b.Other)
+}
+
+func TestAgentUsageBreakdown(t *testing.T) {
+	t.Parallel()
+
+	slow := &mockTool{
+		name: "slow",
+		executeFunc: func(ctx context.Context, call ToolCall) (ToolResponse, error) {
+			time.Sleep(10 * time.Millisecond)
+			return NewTextResponse(strings.Repeat("x", 400)), nil
+		},
+	}
+
+	step := 0
+	model := &mockLanguageModel{
+		generateFunc: func(ctx context.Context, call Call) (*Response, error) {
+			step++
+			if step == 1 {
+				return &Response{
+					Content:      []Content{ToolCallContent{ToolCallID: "call-1", ToolName: "slow", Input: `{}`}},
+					FinishReason: FinishReasonToolCalls,
+					Usage:        Usage{InputTokens: 50},
+				}, nil
+			}
+			return &Response{
+				Content:      []Content{TextContent{Text: "done"}},
+				FinishReason: FinishReasonStop,
+				Usage:        Usage{InputTokens: 200},
+			}, nil
+		},
+	}
+
+	agent := NewAgent(model, WithTools(slow))
+	result, err := agent.Generate(t.Context(), AgentCall{Prompt: "go"})
+	require.NoError(t, err)
+	require.Len(t, result.Steps, 2)
+
+	toolResults := result.Steps[0].Content.ToolResults()
+	require.Len(t, toolResults, 1)
+	require.GreaterOrEqual(t, toolResults[0].Duration, 10*time.Millisecond)
+	require.Equal(t, toolResults[0].Duration, result.ToolDurations()["slow"])
+
+	breakdown := result.Steps[1].InputBreakdown
+	require.Equal(t, int64(100), breakdown.ToolResults["slow"])
+	require.Positive(t, breakdown.ToolDefinitions)
+	require.Equal(t, int64(200), breakdown.Total())
+}
@@ -0,0 +1,184 @@
+package fantasy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrValidationUnsupported is returned by Validate for providers that have
+// no cheap request to check their configuration with.
+var ErrValidationUnsupported = errors.New("provider does not support validation")
+
+// Validator is implemented by providers that can check their configuration
+// with a cheap authenticated request, such as listing models, before the
+// first generation.
+type Validator interface {
+	Validate(ctx context.Context) error
+}
+
+// Validate checks the provider's credentials and endpoint. It returns nil
+// when the provider is usable, a *ValidationError describing the problem
+// otherwise, and ErrValidationUnsupported when the provider doesn't
+// implement Validator.
+func Validate(ctx context.Context, provider Provider) error {
+	validator, ok := provider.(Validator)
+	if !ok {
+		return ErrValidationUnsupported
+	}
+	return validator.Validate(ctx)
+}
+
+// ValidationProblem classifies why a provider failed validation.
+type ValidationProblem string
+
+const (
+	// ValidationProblemInvalidCredentials means the API key or token was rejected.
+	ValidationProblemInvalidCredentials ValidationProblem = "invalid-credentials"
+	// ValidationProblemPermissionDenied means the credentials are valid but lack access.
+	ValidationProblemPermissionDenied ValidationProblem = "permission-denied"
+	// ValidationProblemWrongBaseURL means the endpoint isn't the provider's API.
+	ValidationProblemWrongBaseURL ValidationProblem = "wrong-base-url"
+	// ValidationProblemClockSkew means a signed request was rejected because
+	// the local clock is off.
+	ValidationProblemClockSkew ValidationProblem = "clock-skew"
+	// ValidationProblemUnreachable means the endpoint could not be reached.
+	ValidationProblemUnreachable ValidationProblem = "unreachable"
+	// ValidationProblemRateLimited means the provider is throttling requests.
+	ValidationProblemRateLimited ValidationProblem = "rate-limited"
+	// ValidationProblemUnknown means the failure couldn't be classified.
+	ValidationProblemUnknown ValidationProblem = "unknown"
+)
+
+// maxClockSkew is how far the provider's Date header may drift from the
+// local clock before an auth failure is attributed to clock skew.
+const maxClockSkew = 5 * time.Minute
+
+var clockSkewFragments = []string{
+	"clock skew",
+	"signature expired",
+	"requesttimetooskewed",
+	"request time too skewed",
+	"signature not yet current",
+}
+
+// ValidationError describes a failed provider validation with an
+// actionable hint.
+type ValidationError struct {
+	Provider string
+	Problem  ValidationProblem
+	Hint     string
+	Cause    error
+}
+
+func (e *ValidationError) Error() string {
+	if e.Cause == nil {
+		return fmt.Sprintf("%s: %s", e.Provider, e.Hint)
+	}
+	return fmt.Sprintf("%s: %s: %v", e.Provider, e.Hint, e.Cause)
+}
+
+// Unwrap returns the underlying cause.
+func (e *ValidationError) Unwrap() error {
+	return e.Cause
+}
+
+// NewValidationError classifies the error returned by a provider's
+// validation request. It returns nil for a nil error.
+func NewValidationError(provider string, err error) error {
+	if err == nil {
+		return nil
+	}
+	problem := classifyValidationError(err)
+	return &ValidationError{
+		Provider: provider,
+		Problem:  problem,
+		Hint:     validationHint(problem),
+		Cause:    err,
+	}
+}
+
+func classifyValidationError(err error) ValidationProblem {
+	message := strings.ToLower(err.Error())
+	for _, fragment := range clockSkewFragments {
+		if strings.Contains(message, fragment) {
+			return ValidationProblemClockSkew
+		}
+	}
+
+	var providerErr *ProviderError
+	if errors.As(err, &providerErr) {
+		switch providerErr.StatusCode {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			if isClockSkewed(providerErr.ResponseHeaders) {
+				return ValidationProblemClockSkew
+			}
+			if providerErr.StatusCode == http.StatusForbidden {
+				return ValidationProblemPermissionDenied
+			}
+			return ValidationProblemInvalidCredentials
+		case http.StatusNotFound, http.StatusMethodNotAllowed:
+			return ValidationProblemWrongBaseURL
+		case http.StatusTooManyRequests:
+			return ValidationProblemRateLimited
+		}
+		if providerErr.AuthError {
+			return ValidationProblemInvalidCredentials
+		}
+	}
+
+	var netErr net.Error
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	switch {
+	case errors.As(err, &dnsErr):
+		return ValidationProblemWrongBaseURL
+	case errors.As(err, &opErr), errors.As(err, &netErr):
+		return ValidationProblemUnreachable
+	}
+
+	// An HTML page where JSON was expected usually means the base URL
+	// points at a website rather than the API.
+	if strings.Contains(message, "invalid character '<'") {
+		return ValidationProblemWrongBaseURL
+	}
+	return ValidationProblemUnknown
+}
+
+func isClockSkewed(headers map[string]string) bool {
+	for k, v := range headers {
+		if !strings.EqualFold(k, "Date") {
+			continue
+		}
+		date, err := http.ParseTime(v)
+		if err != nil {
+			return false
+		}
+		skew := time.Since(date)
+		return skew > maxClockSkew || skew < -maxClockSkew
+	}
+	return false
+}
+
+func validationHint(problem ValidationProblem) string {
+	switch problem {
+	case ValidationProblemInvalidCredentials:
+		return "the API key was rejected, check that it is set and not expired"
+	case ValidationProblemPermissionDenied:
+		return "the credentials are valid but lack access, check the key's permissions"
+	case ValidationProblemWrongBaseURL:
+		return "the endpoint is not the provider's API, check the base URL"
+	case ValidationProblemClockSkew:
+		return "the request signature was rejected, check that the system clock is correct"
+	case ValidationProblemUnreachable:
+		return "the endpoint could not be reached, check the network and the base URL"
+	case ValidationProblemRateLimited:
+		return "the provider is rate limiting requests, try again later"
+	default:
+		return "validation request failed"
+	}
+}
@@ -0,0 +1,103 @@
+package fantasy
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewValidationError(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, NewValidationError("test", nil))
+
+	tests := []struct {
+		name string
+		err  error
+		want ValidationProblem
+	}{
+		{
+			name: "unauthorized",
+			err:  &ProviderError{StatusCode: http.StatusUnauthorized},
+			want: ValidationProblemInvalidCredentials,
+		},
+		{
+			name: "forbidden",
+			err:  &ProviderError{StatusCode: http.StatusForbidden},
+			want: ValidationProblemPermissionDenied,
+		},
+		{
+			name: "not found",
+			err:  &ProviderError{StatusCode: http.StatusNotFound},
+			want: ValidationProblemWrongBaseURL,
+		},
+		{
+			name: "rate limited",
+			err:  &ProviderError{StatusCode: http.StatusTooManyRequests},
+			want: ValidationProblemRateLimited,
+		},
+		{
+			name: "signature expired",
+			err:  &ProviderError{StatusCode: http.StatusForbidden, Message: "Signature expired: 20260101T000000Z is now earlier than 20260101T001500Z"},
+			want: ValidationProblemClockSkew,
+		},
+		{
+			name: "skewed date header",
+			err: &ProviderError{
+				StatusCode:      http.StatusUnauthorized,
+				ResponseHeaders: map[string]string{"Date": time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)},
+			},
+			want: ValidationProblemClockSkew,
+		},
+		{
+			name: "dns failure",
+			err:  &net.DNSError{Err: "no such host", Name: "api.example.invalid", IsNotFound: true},
+			want: ValidationProblemWrongBaseURL,
+		},
+		{
+			name: "connection refused",
+			err:  &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")},
+			want: ValidationProblemUnreachable,
+		},
+		{
+			name: "html response",
+			err:  errors.New("invalid character '<' looking for beginning of value"),
+			want: ValidationProblemWrongBaseURL,
+		},
+		{
+			name: "other",
+			err:  errors.New("boom"),
+			want: ValidationProblemUnknown,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			err := NewValidationError("test", tt.err)
+			var validationErr *ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			require.Equal(t, tt.want, validationErr.Problem)
+			require.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+type unvalidatedProvider struct{}
+
+func (unvalidatedProvider) Name() string { return "unvalidated" }
+
+func (unvalidatedProvider) LanguageModel(context.Context, string) (LanguageModel, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestValidate_Unsupported(t *testing.T) {
+	t.Parallel()
+
+	err := Validate(t.Context(), unvalidatedProvider{})
+	require.ErrorIs(t, err, ErrValidationUnsupported)
+}
@@ -0,0 +1,78 @@
+package vectorstore
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+
+	"charm.land/fantasy"
+)
+
+// MemoryStore is a Store that keeps documents in memory and searches them
+// exhaustively. It suits up to tens of thousands of documents. It is safe
+// for concurrent use.
+type MemoryStore struct {
+	mu   sync.RWMutex
+	docs map[string]Document
+	// order keeps the insertion order, so ties and results are stable.
+	order []string
+}
+
+// NewMemoryStore creates an empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{docs: map[string]Document{}}
+}
+
+// Upsert implements Store.
+func (s *MemoryStore) Upsert(_ context.Context, docs ...Document) error {
+	if err := validate(docs); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, doc := range docs {
+		if _, ok := s.docs[doc.ID]; !ok {
+			s.order = append(s.order, doc.ID)
+		}
+		doc.Metadata = maps.Clone(doc.Metadata)
+		doc.Embedding = slices.Clone(doc.Embedding)
+		s.docs[doc.ID] = doc
+	}
+	return nil
+}
+
+// Query implements Store.
+func (s *MemoryStore) Query(_ context.Context, embedding fantasy.Embedding, k int, filter Filter) ([]Result, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	top := topK{k: k}
+	for _, id := range s.order {
+		doc := s.docs[id]
+		if filter.Matches(doc.Metadata) {
+			top.add(doc, CosineSimilarity(embedding, doc.Embedding))
+		}
+	}
+	return top.results, nil
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(_ context.Context, ids ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, id := range ids {
+		delete(s.docs, id)
+	}
+	s.order = slices.DeleteFunc(s.order, func(id string) bool {
+		_, ok := s.docs[id]
+		return !ok
+	})
+	return nil
+}
+
+// Len returns the number of documents in the store.
+func (s *MemoryStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.docs)
+}
@@ -0,0 +1,136 @@
+package vectorstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"charm.land/fantasy"
+)
+
+var tableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// SQLiteStore is a Store that keeps documents in a SQLite table, so small
+// RAG apps can persist their index in a single file. Queries scan the table
+// and compute similarities in Go, which suits up to tens of thousands of
+// documents.
+//
+// The store works with any database/sql SQLite driver, and this package
+// imports none; the application opens the database with the driver of its
+// choice, such as the pure Go modernc.org/sqlite.
+type SQLiteStore struct {
+	db    *sql.DB
+	table string
+}
+
+// NewSQLiteStore creates a store in the table of the database, creating the
+// table if needed.
+func NewSQLiteStore(ctx context.Context, db *sql.DB, table string) (*SQLiteStore, error) {
+	if !tableName.MatchString(table) {
+		return nil, fmt.Errorf("vectorstore: invalid table name %q", table)
+	}
+	_, err := db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	id TEXT PRIMARY KEY,
+	text TEXT NOT NULL,
+	metadata TEXT NOT NULL,
+	embedding BLOB NOT NULL
+)`, table))
+	if err != nil {
+		return nil, fmt.Errorf("vectorstore: creating table: %w", err)
+	}
+	return &SQLiteStore{db: db, table: table}, nil
+}
+
+// Upsert implements Store.
+func (s *SQLiteStore) Upsert(ctx context.Context, docs ...Document) error {
+	if err := validate(docs); err != nil {
+		return err
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf(`INSERT INTO %s (id, text, metadata, embedding) VALUES (?, ?, ?, ?)
+ON CONFLICT(id) DO UPDATE SET text = excluded.text, metadata = excluded.metadata, embedding = excluded.embedding`, s.table))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, doc := range docs {
+		metadata, err := json.Marshal(doc.Metadata)
+		if err != nil {
+			return err
+		}
+		if _, err := stmt.ExecContext(ctx, doc.ID, doc.Text, string(metadata), encodeEmbedding(doc.Embedding)); err != nil {
+			return fmt.Errorf("vectorstore: upserting %q: %w", doc.ID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+// Query implements Store.
+func (s *SQLiteStore) Query(ctx context.Context, embedding fantasy.Embedding, k int, filter Filter) ([]Result, error) {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`SELECT id, text, metadata, embedding FROM %s ORDER BY rowid`, s.table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	top := topK{k: k}
+	for rows.Next() {
+		var (
+			doc      Document
+			metadata string
+			vector   []byte
+		)
+		if err := rows.Scan(&doc.ID, &doc.Text, &metadata, &vector); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(metadata), &doc.Metadata); err != nil {
+			return nil, fmt.Errorf("vectorstore: document %q: %w", doc.ID, err)
+		}
+		if !filter.Matches(doc.Metadata) {
+			continue
+		}
+		doc.Embedding = decodeEmbedding(vector)
+		top.add(doc, CosineSimilarity(embedding, doc.Embedding))
+	}
+	return top.results, rows.Err()
+}
+
+// Delete implements Store.
+func (s *SQLiteStore) Delete(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE id IN (%s)`, s.table, placeholders), args...)
+	return err
+}
+
+// encodeEmbedding stores an embedding as little-endian float32s.
+func encodeEmbedding(embedding fantasy.Embedding) []byte {
+	data := make([]byte, 4*len(embedding))
+	for i, v := range embedding {
+		binary.LittleEndian.PutUint32(data[4*i:], math.Float32bits(v))
+	}
+	return data
+}
+
+func decodeEmbedding(data []byte) fantasy.Embedding {
+	embedding := make(fantasy.Embedding, len(data)/4)
+	for i := range embedding {
+		embedding[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[4*i:]))
+	}
+	return embedding
+}
@@ -0,0 +1,218 @@
+// Package vectorstore stores text chunks with their embeddings and finds
+// the ones closest to a query, for retrieval-augmented generation. It comes
+// with an in-memory store and a SQLite store, and Index embeds documents
+// and queries with a fantasy.EmbeddingModel.
+//
+// Example:
+//
+//	index := vectorstore.NewIndex(vectorstore.NewMemoryStore(), embeddingModel)
+//	err := index.Add(ctx,
+//	    vectorstore.Document{ID: "faq-1", Text: "Refunds take 5 days.", Metadata: map[string]string{"source": "faq"}},
+//	)
+//	...
+//	results, err := index.Search(ctx, "how long do refunds take?", 3, nil)
+package vectorstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+
+	"charm.land/fantasy"
+)
+
+// Document is a chunk of text stored with its embedding.
+type Document struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+	// Metadata holds attributes to filter on, such as the source of the
+	// text.
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	Embedding fantasy.Embedding `json:"embedding,omitempty"`
+}
+
+// Result is a document returned by a query.
+type Result struct {
+	Document
+	// Score is the cosine similarity between the document and the query,
+	// from -1 to 1. Higher is closer.
+	Score float32 `json:"score"`
+}
+
+// Filter restricts a query to the documents whose metadata has all of the
+// filter's keys with the same values. A nil filter matches every document.
+type Filter map[string]string
+
+// Matches reports whether the metadata matches the filter.
+func (f Filter) Matches(metadata map[string]string) bool {
+	for key, value := range f {
+		if v, ok := metadata[key]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Store stores documents and queries them by embedding.
+type Store interface {
+	// Upsert adds the documents, replacing any with the same ID. Documents
+	// need an ID and an embedding.
+	Upsert(ctx context.Context, docs ...Document) error
+	// Query returns the k documents most similar to the embedding that
+	// match the filter, most similar first.
+	Query(ctx context.Context, embedding fantasy.Embedding, k int, filter Filter) ([]Result, error)
+	// Delete removes the documents with the IDs. Unknown IDs are ignored.
+	Delete(ctx context.Context, ids ...string) error
+}
+
+// DefaultBatchSize is the number of texts Index embeds per call when
+// BatchSize is not set.
+const DefaultBatchSize = 64
+
+// Index stores and searches documents by text, embedding them with Model.
+type Index struct {
+	Store Store
+	Model fantasy.EmbeddingModel
+	// BatchSize is the number of texts embedded per call. Defaults to
+	// DefaultBatchSize.
+	BatchSize int
+}
+
+// NewIndex creates an index over the store that embeds with the model.
+func NewIndex(store Store, model fantasy.EmbeddingModel) *Index {
+	return &Index{Store: store, Model: model}
+}
+
+// Add embeds the documents that have no embedding and upserts them.
+func (x *Index) Add(ctx context.Context, docs ...Document) error {
+	docs = slices.Clone(docs)
+	var missing []int
+	for i, doc := range docs {
+		if len(doc.Embedding) == 0 {
+			missing = append(missing, i)
+		}
+	}
+	batchSize := x.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	for batch := range slices.Chunk(missing, batchSize) {
+		values := make([]string, len(batch))
+		for i, idx := range batch {
+			values[i] = docs[idx].Text
+		}
+		embeddings, err := x.embed(ctx, values)
+		if err != nil {
+			return err
+		}
+		for i, idx := range batch {
+			docs[idx].Embedding = embeddings[i]
+		}
+	}
+	return x.Store.Upsert(ctx, docs...)
+}
+
+// Search returns the k documents most similar to the query that match the
+// filter.
+func (x *Index) Search(ctx context.Context, query string, k int, filter Filter) ([]Result, error) {
+	embeddings, err := x.embed(ctx, []string{query})
+	if err != nil {
+		return nil, err
+	}
+	return x.Store.Query(ctx, embeddings[0], k, filter)
+}
+
+// Retrieve implements fantasy.Retriever, so the index can back
+// fantasy.RetrievalTool and fantasy.WithRetrievedContext. The "title" and
+// "url" metadata of the documents are used as their title and URL.
+func (x *Index) Retrieve(ctx context.Context, query string, k int) ([]fantasy.RetrievedDocument, error) {
+	results, err := x.Search(ctx, query, k, nil)
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]fantasy.RetrievedDocument, len(results))
+	for i, result := range results {
+		docs[i] = fantasy.RetrievedDocument{
+			ID:    result.ID,
+			Title: result.Metadata["title"],
+			URL:   result.Metadata["url"],
+			Text:  result.Text,
+			Score: result.Score,
+		}
+	}
+	return docs, nil
+}
+
+// Delete removes the documents with the IDs.
+func (x *Index) Delete(ctx context.Context, ids ...string) error {
+	return x.Store.Delete(ctx, ids...)
+}
+
+func (x *Index) embed(ctx context.Context, values []string) ([]fantasy.Embedding, error) {
+	resp, err := x.Model.Embed(ctx, fantasy.EmbeddingCall{Values: values})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Embeddings) != len(values) {
+		return nil, fmt.Errorf("vectorstore: embedding model returned %d embeddings for %d values", len(resp.Embeddings), len(values))
+	}
+	return resp.Embeddings, nil
+}
+
+// CosineSimilarity returns the cosine similarity of two embeddings, or 0 if
+// their lengths differ or either is zero.
+func CosineSimilarity(a, b fantasy.Embedding) float32 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		normA += float64(a[i]) * float64(a[i])
+		normB += float64(b[i]) * float64(b[i])
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return float32(dot / (math.Sqrt(normA) * math.Sqrt(normB)))
+}
+
+func validate(docs []Document) error {
+	for _, doc := range docs {
+		if doc.ID == "" {
+			return errors.New("vectorstore: document has no ID")
+		}
+		if len(doc.Embedding) == 0 {
+			return fmt.Errorf("vectorstore: document %q has no embedding", doc.ID)
+		}
+	}
+	return nil
+}
+
+// topK keeps the k best results seen, most similar first.
+type topK struct {
+	k       int
+	results []Result
+}
+
+func (t *topK) add(doc Document, score float32) {
+	if t.k <= 0 {
+		return
+	}
+	if len(t.results) == t.k && score <= t.results[len(t.results)-1].Score {
+		return
+	}
+	// Ties keep the order the documents were added in.
+	i, _ := slices.BinarySearchFunc(t.results, score, func(r Result, score float32) int {
+		if r.Score >= score {
+			return -1
+		}
+		return 1
+	})
+	t.results = slices.Insert(t.results, i, Result{Document: doc, Score: score})
+	if len(t.results) > t.k {
+		t.results = t.results[:t.k]
+	}
+}
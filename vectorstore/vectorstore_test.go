@@ -0,0 +1,132 @@
+package vectorstore
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+// wordModel embeds texts by counting a few known words.
+type wordModel struct {
+	calls int
+}
+
+var vocabulary = []string{"cat", "dog", "fish", "car"}
+
+func (m *wordModel) Embed(_ context.Context, call fantasy.EmbeddingCall) (*fantasy.EmbeddingResponse, error) {
+	m.calls++
+	resp := &fantasy.EmbeddingResponse{}
+	for _, value := range call.Values {
+		embedding := make(fantasy.Embedding, len(vocabulary))
+		for i, word := range vocabulary {
+			embedding[i] = float32(strings.Count(value, word))
+		}
+		resp.Embeddings = append(resp.Embeddings, embedding)
+	}
+	return resp, nil
+}
+
+func (m *wordModel) Provider() string { return "test" }
+func (m *wordModel) Model() string    { return "words" }
+
+func newSQLiteStore(t *testing.T) Store {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "vectors.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+	if err := db.PingContext(t.Context()); err != nil {
+		t.Skipf("sqlite unavailable: %v", err)
+	}
+	store, err := NewSQLiteStore(t.Context(), db, "chunks")
+	require.NoError(t, err)
+	return store
+}
+
+func TestStores(t *testing.T) {
+	t.Parallel()
+
+	stores := map[string]func(t *testing.T) Store{
+		"memory": func(*testing.T) Store { return NewMemoryStore() },
+		"sqlite": newSQLiteStore,
+	}
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			model := &wordModel{}
+			index := NewIndex(newStore(t), model)
+			index.BatchSize = 2
+			require.NoError(t, index.Add(t.Context(),
+				Document{ID: "1", Text: "a cat and a cat", Metadata: map[string]string{"kind": "pet"}},
+				Document{ID: "2", Text: "a dog", Metadata: map[string]string{"kind": "pet"}},
+				Document{ID: "3", Text: "a fish and a cat", Metadata: map[string]string{"kind": "pet"}},
+				Document{ID: "4", Text: "a car", Metadata: map[string]string{"kind": "vehicle"}},
+				Document{ID: "5", Text: "precomputed", Embedding: fantasy.Embedding{0, 0, 0, 1}},
+			))
+			require.Equal(t, 2, model.calls)
+
+			results, err := index.Search(t.Context(), "cat", 2, nil)
+			require.NoError(t, err)
+			require.Len(t, results, 2)
+			require.Equal(t, "1", results[0].ID)
+			require.InDelta(t, 1, results[0].Score, 1e-6)
+			require.Equal(t, "3", results[1].ID)
+			require.Equal(t, map[string]string{"kind": "pet"}, results[0].Metadata)
+
+			results, err = index.Search(t.Context(), "car", 5, Filter{"kind": "vehicle"})
+			require.NoError(t, err)
+			require.Len(t, results, 1)
+			require.Equal(t, "4", results[0].ID)
+
+			// Upserting replaces the document.
+			require.NoError(t, index.Add(t.Context(), Document{ID: "2", Text: "a car"}))
+			require.NoError(t, index.Delete(t.Context(), "4", "5", "missing"))
+			results, err = index.Search(t.Context(), "car", 5, nil)
+			require.NoError(t, err)
+			require.Equal(t, "2", results[0].ID)
+			require.Equal(t, "a car", results[0].Text)
+			require.Len(t, results, 3)
+
+			require.Error(t, index.Store.Upsert(t.Context(), Document{ID: "6", Text: "no embedding"}))
+		})
+	}
+}
+
+func TestCosineSimilarity(t *testing.T) {
+	t.Parallel()
+
+	require.InDelta(t, 1, CosineSimilarity(fantasy.Embedding{1, 2}, fantasy.Embedding{2, 4}), 1e-6)
+	require.InDelta(t, -1, CosineSimilarity(fantasy.Embedding{1, 0}, fantasy.Embedding{-1, 0}), 1e-6)
+	require.Zero(t, CosineSimilarity(fantasy.Embedding{1, 0}, fantasy.Embedding{0, 1}))
+	require.Zero(t, CosineSimilarity(fantasy.Embedding{1}, fantasy.Embedding{1, 0}))
+	require.Zero(t, CosineSimilarity(fantasy.Embedding{0, 0}, fantasy.Embedding{1, 0}))
+}
+
+func TestNewSQLiteStoreInvalidTable(t *testing.T) {
+	t.Parallel()
+
+	_, err := NewSQLiteStore(t.Context(), nil, "chunks; DROP TABLE x")
+	require.Error(t, err)
+}
+
+func TestIndexRetrieve(t *testing.T) {
+	t.Parallel()
+
+	index := NewIndex(NewMemoryStore(), &wordModel{})
+	require.NoError(t, index.Add(t.Context(),
+		Document{ID: "1", Text: "cats purr", Metadata: map[string]string{"title": "Cats", "url": "https://example.com/cats"}},
+		Document{ID: "2", Text: "dogs bark"},
+	))
+
+	var retriever fantasy.Retriever = index
+	docs, err := retriever.Retrieve(t.Context(), "cat", 1)
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	require.Equal(t, fantasy.RetrievedDocument{ID: "1", Title: "Cats", URL: "https://example.com/cats", Text: "cats purr", Score: docs[0].Score}, docs[0])
+}
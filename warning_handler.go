@@ -0,0 +1,71 @@
+package fantasy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// WithWarningHandler makes the agent call handler with each warning of its
+// steps, such as files a provider dropped or settings it ignored, so they
+// can be logged instead of going unnoticed.
+func WithWarningHandler(handler func(CallWarning)) AgentOption {
+	return func(s *agentSettings) {
+		s.warningHandler = handler
+	}
+}
+
+// WithWarningsAsErrors makes the agent fail with a *WarningsError when a
+// step has warnings, after passing them to the warning handler. It's meant
+// for tests and CI, where silently dropped content should break the build.
+func WithWarningsAsErrors() AgentOption {
+	return func(s *agentSettings) {
+		s.warningsAsErrors = true
+	}
+}
+
+// WarningsError is returned by agents set up with WithWarningsAsErrors
+// when a step has warnings.
+type WarningsError struct {
+	Warnings []CallWarning
+}
+
+func (e *WarningsError) Error() string {
+	messages := make([]string, len(e.Warnings))
+	for i, w := range e.Warnings {
+		messages[i] = w.String()
+	}
+	return fmt.Sprintf("call warnings: %s", strings.Join(messages, "; "))
+}
+
+// String describes the warning.
+func (w CallWarning) String() string {
+	var text string
+	switch {
+	case w.Message != "":
+		text = w.Message
+	case w.Setting != "":
+		text = "unsupported setting " + w.Setting
+	case w.Tool != nil:
+		text = "unsupported tool " + w.Tool.GetName()
+	default:
+		text = string(w.Type)
+	}
+	if w.Details != "" {
+		text += ": " + w.Details
+	}
+	return text
+}
+
+// handleWarnings passes the warnings of a step to the warning handler,
+// returning a *WarningsError if the agent escalates them.
+func (a *agent) handleWarnings(warnings []CallWarning) error {
+	if a.settings.warningHandler != nil {
+		for _, w := range warnings {
+			a.settings.warningHandler(w)
+		}
+	}
+	if a.settings.warningsAsErrors && len(warnings) > 0 {
+		return &WarningsError{Warnings: warnings}
+	}
+	return nil
+}
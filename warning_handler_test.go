@@ -0,0 +1,57 @@
+package fantasy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWarningHandler(t *testing.T) {
+	t.Parallel()
+
+	warnings := []CallWarning{
+		{Type: CallWarningTypeUnsupportedSetting, Setting: "top_k"},
+		{Type: CallWarningTypeOther, Message: "file part media type audio/ogg not supported"},
+	}
+	model := &mockLanguageModel{
+		generateFunc: func(context.Context, Call) (*Response, error) {
+			return &Response{
+				Content:      ResponseContent{TextContent{Text: "ok"}},
+				FinishReason: FinishReasonStop,
+				Warnings:     warnings,
+			}, nil
+		},
+		streamFunc: func(context.Context, Call) (StreamResponse, error) {
+			return func(yield func(StreamPart) bool) {
+				_ = yield(StreamPart{Type: StreamPartTypeWarnings, Warnings: warnings}) &&
+					yield(StreamPart{Type: StreamPartTypeTextDelta, ID: "1", Delta: "ok"}) &&
+					yield(StreamPart{Type: StreamPartTypeFinish, FinishReason: FinishReasonStop})
+			}, nil
+		},
+	}
+
+	t.Run("handler", func(t *testing.T) {
+		t.Parallel()
+		var handled []CallWarning
+		agent := NewAgent(model, WithWarningHandler(func(w CallWarning) {
+			handled = append(handled, w)
+		}))
+		_, err := agent.Generate(t.Context(), AgentCall{Prompt: "hi"})
+		require.NoError(t, err)
+		require.Equal(t, warnings, handled)
+	})
+
+	t.Run("as errors", func(t *testing.T) {
+		t.Parallel()
+		agent := NewAgent(model, WithWarningsAsErrors())
+		_, err := agent.Generate(t.Context(), AgentCall{Prompt: "hi"})
+		var warningsErr *WarningsError
+		require.ErrorAs(t, err, &warningsErr)
+		require.Equal(t, warnings, warningsErr.Warnings)
+		require.EqualError(t, err, "call warnings: unsupported setting top_k; file part media type audio/ogg not supported")
+
+		_, err = agent.Stream(t.Context(), AgentStreamCall{Prompt: "hi"})
+		require.ErrorAs(t, err, &warningsErr)
+	})
+}